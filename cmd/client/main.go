@@ -6,6 +6,8 @@ import (
 	"github.com/bbt-t/lets-go-keep/internal/config"
 	"github.com/bbt-t/lets-go-keep/internal/controller/handlers"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
@@ -30,11 +32,73 @@ func init() {
 }
 
 func main() {
-	cfg := config.NewClientConfig()
-	c := handlers.NewClientConnection(cfg.ServerAddress)
-	h := handlers.NewClientHandlers(c)
+	cfg, args, err := config.NewClientConfig(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(client.ExitUsage)
+	}
+
+	if len(args) > 0 && args[0] == "agent" {
+		runAgent(cfg)
+		return
+	}
+
+	h := newHandlers(cfg)
+
+	if len(args) > 0 {
+		os.Exit(client.NewCLI(h, cfg.Output, os.Stdin, os.Stdout, os.Stderr).Run(args))
+	}
 
 	tui := client.NewTUI(h)
 
 	log.Fatalln(tui.Run())
 }
+
+// newHandlers returns handlers of running agent or, if it isn't running, handlers connected to server.
+func newHandlers(cfg config.ClientConfig) handlers.ClientHandlers {
+	if !cfg.Agent.Disabled {
+		if agent, err := handlers.NewAgentClient(cfg.Agent.Socket); err == nil {
+			return agent
+		}
+	}
+
+	return newServerHandlers(cfg)
+}
+
+// newServerHandlers returns handlers connected to server.
+func newServerHandlers(cfg config.ClientConfig) handlers.ClientHandlers {
+	creds, err := handlers.NewClientCredentials(cfg.TLS, cfg.ServerAddress)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	c := handlers.NewClientConnection(cfg.ServerAddress, creds, cfg.Timeout)
+
+	return handlers.NewClientHandlers(handlers.NewOfflineConnection(c, cfg.CacheDirectory), cfg.EncryptMetadata)
+}
+
+// runAgent runs agent, which keeps vault unlocked, until it's stopped by signal.
+func runAgent(cfg config.ClientConfig) {
+	listener, err := handlers.ListenAgentSocket(cfg.Agent.Socket)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(client.ExitUsage)
+	}
+
+	agent := handlers.NewAgentServer(newServerHandlers(cfg), cfg.Agent.IdleTimeout)
+
+	go func() {
+		if err := agent.Serve(listener); err != nil {
+			log.Fatalln(err)
+		}
+	}()
+	fmt.Println("Agent listens", cfg.Agent.Socket)
+
+	sigint := make(chan os.Signal, 1)
+	signal.Notify(sigint, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	<-sigint
+
+	if err = agent.Close(); err != nil {
+		log.Warnf("%s :: %v", "close agent fault", err)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bbt-t/lets-go-keep/internal/config"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+)
+
+// Exit codes of commands.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const commandsUsage = `usage: server [backup [--allow-missing]|verify|restore <path>]
+
+  backup <path>   dump DB and files of file records into archive, then verify it;
+                  fails, if files of some records are missing, unless --allow-missing is set
+  verify <path>   check that archive is complete and not corrupted
+  restore <path>  restore archive into empty DB and file storage
+`
+
+// errMissingFiles means that backup wasn't saved, because files of some records are missing.
+var errMissingFiles = errors.New("files of records are missing, backup isn't saved, use --allow-missing to save it")
+
+// runCommand runs command of server instead of server itself.
+func runCommand(cfg config.ServerConfig, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, commandsUsage)
+		return exitUsage
+	}
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	allowMissing := flags.Bool("allow-missing", false, "")
+
+	if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 1 || (*allowMissing && args[0] != "backup") {
+		fmt.Fprint(stderr, commandsUsage)
+		return exitUsage
+	}
+
+	ctx := context.Background()
+	command, path := args[0], flags.Arg(0)
+
+	var (
+		manifest storage.BackupManifest
+		err      error
+	)
+
+	switch command {
+	case "backup", "restore":
+		files, errFiles := storage.NewFileStorageByURL(cfg.FileStorage())
+		if errFiles != nil {
+			err = errFiles
+			break
+		}
+
+		db := storage.NewDBStorage(cfg.DBConnectionURL)
+		if command == "backup" {
+			manifest, err = backupTo(ctx, db, files, path, *allowMissing)
+		} else {
+			db.MigrateUP()
+			manifest, err = restoreFrom(ctx, db, files, path)
+		}
+	case "verify":
+		manifest, err = verifyFile(path)
+	default:
+		fmt.Fprint(stderr, commandsUsage)
+		return exitUsage
+	}
+
+	for _, recordID := range manifest.Missing {
+		fmt.Fprintf(stderr, "missing file of record %s\n", recordID)
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", command, err)
+		return exitError
+	}
+
+	rows := 0
+	for _, table := range manifest.Tables {
+		rows += table.Rows
+	}
+
+	fmt.Fprintf(stdout, "%s: %s: schema %d, %d tables, %d rows, %d files\n",
+		command, path, manifest.Schema, len(manifest.Tables), rows, len(manifest.Files))
+
+	return exitOK
+}
+
+// backupTo writes backup into temporary file, verifies it and renames it to path,
+// so path has only complete backups. Backup with missing files is saved only if they are allowed,
+// manifest is returned anyway, so missing files can be reported.
+func backupTo(
+	ctx context.Context, db storage.BackupStorager, files storage.FileStorager, path string, allowMissing bool,
+) (storage.BackupManifest, error) {
+	tmp := path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return storage.BackupManifest{}, err
+	}
+
+	manifest, err := storage.Backup(ctx, db, files, f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if errClose := f.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil && len(manifest.Missing) != 0 && !allowMissing {
+		err = errMissingFiles
+	}
+	if err == nil {
+		_, err = verifyFile(tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return manifest, err
+	}
+
+	return manifest, nil
+}
+
+// verifyFile verifies backup in file.
+func verifyFile(path string) (storage.BackupManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return storage.BackupManifest{}, err
+	}
+	defer f.Close()
+
+	return storage.VerifyBackup(f)
+}
+
+// restoreFrom restores backup from file.
+func restoreFrom(ctx context.Context, db storage.BackupStorager, files storage.FileStorager, path string) (storage.BackupManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return storage.BackupManifest{}, err
+	}
+	defer f.Close()
+
+	return storage.RestoreBackup(ctx, db, files, f)
+}
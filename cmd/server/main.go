@@ -11,6 +11,7 @@ import (
 	"github.com/bbt-t/lets-go-keep/internal/config"
 	"github.com/bbt-t/lets-go-keep/internal/controller/handlers"
 	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	log "github.com/sirupsen/logrus"
@@ -36,15 +37,32 @@ func init() {
 func main() {
 	cfg := config.NewServerConfig()
 
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(cfg, os.Args[1:], os.Stdout, os.Stderr))
+	}
+
 	db := storage.NewDBStorage(cfg.DBConnectionURL)
 	db.MigrateUP()
 
-	files := storage.NewFileStorage(cfg.FilesDirectory)
+	files, err := storage.NewFileStorageByURL(cfg.FileStorage())
+	if err != nil {
+		log.Fatalln(err)
+	}
 	s := storage.NewStorage(db, files)
 
-	jwtAuth := handlers.NewAuthenticatorJWT([]byte(cfg.Auth.SecretJWT), cfg.Auth.ExpirationTime)
-	h := handlers.NewServerHandlers(s, jwtAuth)
-	server := handlers.NewServerConn(h)
+	hasher, err := pkg.NewPasswordHasher(cfg.PasswordHasher)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	jwtAuth := handlers.NewAuthenticatorJWT([]byte(cfg.Auth.SecretJWT), cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+	h := handlers.NewServerHandlers(s, jwtAuth, hasher)
+	creds, err := handlers.NewServerCredentials(cfg.TLS, cfg.RunAddress)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	server := handlers.NewServerConn(h, jwtAuth, creds)
 
 	go server.Run(context.Background(), cfg.RunAddress)
 
@@ -0,0 +1,1274 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/backup"
+	"github.com/bbt-t/lets-go-keep/internal/config"
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/controller/handlers"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/exchange"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Exit codes of CLI. Every error class has its own code, so scripts can react to it.
+const (
+	ExitOK = iota
+	ExitUnknown
+	ExitUsage
+	ExitWrongCredentials
+	ExitUnauthenticated
+	ExitNotFound
+	ExitLoginExists
+	ExitConflict
+	ExitWrongMasterKey
+	ExitServerUnavailable
+	ExitNotSupported
+)
+
+// Environment variables with credentials, so they don't have to be passed by flags.
+const (
+	envLogin     = "GOPHKEEPER_LOGIN"
+	envPassword  = "GOPHKEEPER_PASSWORD"
+	envMasterKey = "GOPHKEEPER_MASTER_KEY"
+	// envExportPassword is password of imported or exported KeePass database.
+	envExportPassword = "GOPHKEEPER_EXPORT_PASSWORD"
+	// envBackupPassphrase is passphrase of backups.
+	envBackupPassphrase = "GOPHKEEPER_BACKUP_PASSPHRASE"
+)
+
+// cliUsage is help of CLI.
+const cliUsage = `Usage: client [global flags] <command> [flags] [arguments]
+
+Global flags: -profile, -config, -server, -output (json or text), -timeout, -cache-dir,
+  -tls-ca, -tls-cert, -tls-key, -tls-server-name, -tls-insecure, -agent-socket, -agent-idle-timeout,
+  -no-agent, -encrypt-metadata. They override settings
+  of profile from config file and environment. Without command client runs TUI.
+
+Commands:
+  login                           check credentials and fill offline cache
+  register                        create new user
+  ls [-search S] [-tag T]... [-folder F] [-type T]... [-sort name|updated|type] [-desc] [-limit N] [-offset N]
+                                  list records, which match all filters; all pages are listed, if -limit isn't set
+  get <id>                        get decrypted record (file records are saved to current directory)
+  add text [-text T] [-metadata M]          add text record, text is read from stdin if flag isn't set
+  add login -username U [-secret S | generator flags] [-metadata M]
+                                  password is generated, if -secret isn't set
+  add card -number N -expiration MM/YY -cvc C [-metadata M]
+  add file <path>
+  Every add command takes -name N, -tag T (can be repeated) and -folder path/to/folder.
+  add otp -uri otpauth://... [-metadata M]  add TOTP or HOTP key
+  otp <id>                        print current code of one-time password record (HOTP counter is advanced)
+  rm <id>                         delete record
+  conflicts                       list offline changes, which conflicted with other devices (shown once)
+                                  or which server rejected (kept and sent again after login)
+  migrate                         encrypt labels saved in clear before -encrypt-metadata was on and save
+                                  records of old clients in current payload format
+  import [-format kdbx|bitwarden|1pux|csv] [-export-password P] [-folder F] [-dry-run] <path>
+                                  import export of KeePass (KDBX 4), Bitwarden (JSON), 1Password (1PUX)
+                                  or CSV; format is detected by extension, -dry-run only counts records
+  export [-format kdbx|bitwarden|csv] [-export-password P] [-folder F] <path>
+                                  export records (of folder) to KeePass (KDBX 4), Bitwarden (JSON) or CSV;
+                                  files are kept only in KeePass database, which is encrypted by -export-password
+  backup [-passphrase P] <path>   write all records to archive encrypted by passphrase
+  restore [-passphrase P] [-dry-run] <path>
+                                  create records of backup, which don't exist yet; -dry-run only checks backup
+  generate [-length N] [-no-lower] [-no-upper] [-no-digits] [-no-symbols] [-no-ambiguous]
+  generate -passphrase [-words N] [-separator S] [-capitalize]
+                                  generate password or diceware passphrase with entropy estimate
+  agent                           run agent, which keeps vault unlocked for other commands and TUI
+  lock                            lock vault in agent
+
+Credentials are taken from flags -login, -password, -master-key or from environment variables
+GOPHKEEPER_LOGIN, GOPHKEEPER_PASSWORD, GOPHKEEPER_MASTER_KEY (password of KeePass database is
+-export-password or GOPHKEEPER_EXPORT_PASSWORD, passphrase of backup is -passphrase
+or GOPHKEEPER_BACKUP_PASSPHRASE). If agent is unlocked, commands
+can be run without credentials. Results are written to stdout as JSON
+or as text (see -output), errors are written to stderr with non-zero exit code.
+`
+
+// CLI is non-interactive command line interface for client. Every command logs in by itself,
+// unless session of agent is reused.
+type CLI struct {
+	client handlers.ClientHandlers
+	output string
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// cliResult is result of command, which can be printed as JSON or as text.
+type cliResult interface {
+	text() string
+}
+
+// cliStatus is result of command, which doesn't return data.
+type cliStatus struct {
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+}
+
+// cliGenerated is generated password with its entropy in bits.
+type cliGenerated struct {
+	Password string  `json:"password"`
+	Entropy  float64 `json:"entropy"`
+}
+
+// cliCreated is status of created record with generated password.
+type cliCreated struct {
+	cliStatus
+	cliGenerated
+}
+
+// cliOTP is current code of one-time password record.
+type cliOTP struct {
+	ID        string `json:"id"`
+	Code      string `json:"code"`
+	ExpiresIn int    `json:"expires_in,omitempty"` // In seconds, only for TOTP.
+	Counter   uint64 `json:"counter,omitempty"`    // Counter of returned code, only for HOTP.
+}
+
+// cliMigrated is result of migration: IDs of migrated records, of records, which data is malformed,
+// and of records, which labels were encrypted.
+type cliMigrated struct {
+	Migrated []string `json:"migrated"`
+	Skipped  []string `json:"skipped,omitempty"`
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// cliImported is result of import: numbers of records by type, number of created records
+// and entries, which aren't imported or created, with reasons.
+type cliImported struct {
+	DryRun  bool           `json:"dry_run,omitempty"`
+	Records int            `json:"records"`
+	Types   map[string]int `json:"types"`
+	Created int            `json:"created"`
+	Skipped []cliSkipped   `json:"skipped,omitempty"`
+	Failed  []cliSkipped   `json:"failed,omitempty"`
+}
+
+// cliExported is result of export: path of written file, number of exported records
+// and records, which aren't exported, with reasons.
+type cliExported struct {
+	Path     string       `json:"path"`
+	Exported int          `json:"exported"`
+	Skipped  []cliSkipped `json:"skipped,omitempty"`
+}
+
+// cliBackedUp is result of backup: path of archive, number of records in it
+// and records, which can't be got, with reasons.
+type cliBackedUp struct {
+	Path    string       `json:"path"`
+	Records int          `json:"records"`
+	Skipped []cliSkipped `json:"skipped,omitempty"`
+}
+
+// cliRestored is result of restore: numbers of created records and of records, which already exist,
+// records, which aren't created, and new IDs of records of backup.
+type cliRestored struct {
+	DryRun     bool              `json:"dry_run,omitempty"`
+	Restored   int               `json:"restored"`
+	Duplicates int               `json:"duplicates"`
+	Failed     []cliSkipped      `json:"failed,omitempty"`
+	IDs        map[string]string `json:"ids,omitempty"`
+}
+
+// cliConflicts is list of offline changes, which weren't applied on server as they were made.
+type cliConflicts []cliConflict
+
+// cliConflict is JSON view of offline change: conflicting update is saved as record Copy,
+// change with Error is rejected by server and stays queued.
+type cliConflict struct {
+	Kind     string    `json:"kind"`
+	ID       string    `json:"id"`
+	Copy     string    `json:"copy,omitempty"`
+	Metadata string    `json:"metadata"`
+	Name     string    `json:"name,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// cliSkipped is entry, which isn't imported or exported, with reason.
+type cliSkipped struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// cliRecords is list of records.
+type cliRecords []cliRecord
+
+// cliRecord is JSON view of record.
+type cliRecord struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Metadata  string      `json:"metadata"`
+	Name      string      `json:"name,omitempty"`
+	Tags      []string    `json:"tags,omitempty"`
+	Folder    string      `json:"folder,omitempty"`
+	Data      string      `json:"data,omitempty"`
+	Payload   interface{} `json:"payload,omitempty"`
+	Revision  int64       `json:"revision"`
+	UpdatedAt *time.Time  `json:"updated_at,omitempty"`
+}
+
+// cliRecordTypes are names of record types in CLI.
+var cliRecordTypes = map[entity.RecordType]string{
+	entity.TypeText:             "text",
+	entity.TypeLoginAndPassword: "login",
+	entity.TypeCreditCard:       "card",
+	entity.TypeFile:             "file",
+	entity.TypeOTP:              "otp",
+}
+
+// cliSorts are names of records sorting in CLI.
+var cliSorts = map[string]entity.RecordsSort{
+	"name":    entity.SortByName,
+	"updated": entity.SortByUpdatedAt,
+	"type":    entity.SortByType,
+}
+
+// cliError is JSON view of error.
+type cliError struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+}
+
+// NewCLI gets new command line interface for client, which prints results in output format.
+func NewCLI(client handlers.ClientHandlers, output string, stdin io.Reader, stdout, stderr io.Writer) *CLI {
+	return &CLI{
+		client: client,
+		output: output,
+		stdin:  stdin,
+		stdout: stdout,
+		stderr: stderr,
+	}
+}
+
+// Run runs command from arguments (without program name) and returns exit code.
+func (c *CLI) Run(args []string) int {
+	if len(args) == 0 {
+		return c.usage(errors.New("command isn't set"))
+	}
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	credentials := entity.UserCredentials{}
+	var masterKey string
+
+	flags.StringVar(&credentials.Login, "login", os.Getenv(envLogin), "login of user")
+	flags.StringVar(&credentials.Password, "password", os.Getenv(envPassword), "password of user")
+	flags.StringVar(&masterKey, "master-key", os.Getenv(envMasterKey), "master key of user")
+
+	switch command {
+	case "login":
+		return c.withAuth(c.client.Login, flags, args, &credentials, &masterKey, 0, c.status("ok"))
+	case "register":
+		return c.withAuth(c.client.Register, flags, args, &credentials, &masterKey, 0, c.status("ok"))
+	case "ls":
+		query := queryFlags(flags)
+
+		return c.withAuth(c.login, flags, args, &credentials, &masterKey, 0, func(_ []string) int {
+			return c.list(query)
+		})
+	case "get":
+		return c.withAuth(c.login, flags, args, &credentials, &masterKey, 1, c.get)
+	case "otp":
+		return c.withAuth(c.login, flags, args, &credentials, &masterKey, 1, c.otp)
+	case "migrate":
+		return c.withAuth(c.login, flags, args, &credentials, &masterKey, 0, c.migrate)
+	case "rm":
+		return c.withAuth(c.login, flags, args, &credentials, &masterKey, 1, c.remove)
+	case "conflicts":
+		return c.withAuth(c.login, flags, args, &credentials, &masterKey, 0, c.conflicts)
+	case "import":
+		var (
+			format  string
+			options exchange.Options
+			dryRun  bool
+		)
+
+		flags.StringVar(&format, "format", "", "format of export: kdbx, bitwarden, 1pux or csv")
+		flags.StringVar(&options.Password, "export-password", os.Getenv(envExportPassword), "password of KeePass database")
+		flags.StringVar(&options.Folder, "folder", "", "parent folder of imported records")
+		flags.BoolVar(&dryRun, "dry-run", false, "only count records, which would be imported")
+
+		// Dry run doesn't create records, so it needs no login.
+		auth := func(credentials entity.UserCredentials) error {
+			if dryRun {
+				return nil
+			}
+
+			return c.login(credentials)
+		}
+
+		return c.withAuth(auth, flags, args, &credentials, &masterKey, 1, func(args []string) int {
+			return c.importExport(args[0], format, options, dryRun)
+		})
+	case "export":
+		var (
+			format  string
+			options exchange.Options
+		)
+
+		flags.StringVar(&format, "format", "", "format of export: kdbx, bitwarden or csv")
+		flags.StringVar(&options.Password, "export-password", os.Getenv(envExportPassword), "password of KeePass database")
+		flags.StringVar(&options.Folder, "folder", "", "export only records of folder")
+
+		return c.withAuth(c.login, flags, args, &credentials, &masterKey, 1, func(args []string) int {
+			return c.exportRecords(args[0], format, options)
+		})
+	case "backup":
+		var passphrase string
+
+		flags.StringVar(&passphrase, "passphrase", os.Getenv(envBackupPassphrase), "passphrase of backup")
+
+		return c.withAuth(c.login, flags, args, &credentials, &masterKey, 1, func(args []string) int {
+			return c.backup(args[0], passphrase)
+		})
+	case "restore":
+		var (
+			passphrase string
+			dryRun     bool
+		)
+
+		flags.StringVar(&passphrase, "passphrase", os.Getenv(envBackupPassphrase), "passphrase of backup")
+		flags.BoolVar(&dryRun, "dry-run", false, "only check backup and count records, which would be restored")
+
+		return c.withAuth(c.login, flags, args, &credentials, &masterKey, 1, func(args []string) int {
+			return c.restore(args[0], passphrase, dryRun)
+		})
+	case "add":
+		if len(args) == 0 {
+			return c.usage(errors.New("record type isn't set"))
+		}
+
+		return c.add(flags, args[0], args[1:], &credentials, &masterKey)
+	case "generate":
+		generate := generatorFlags(flags)
+		if err := flags.Parse(args); err != nil {
+			return c.usage(err)
+		}
+		if flags.NArg() != 0 {
+			return c.usage(errors.New("generate needs 0 argument(s)"))
+		}
+
+		password, entropy, err := generate()
+		if err != nil {
+			return c.usage(err)
+		}
+
+		return c.print(cliGenerated{Password: password, Entropy: entropy})
+	case "lock":
+		locker, ok := c.client.(interface{ Lock() error })
+		if !ok {
+			return c.usage(errors.New("lock works only with agent"))
+		}
+		if err := locker.Lock(); err != nil {
+			return c.fail(err)
+		}
+
+		return c.status("locked")(nil)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(c.stdout, cliUsage)
+
+		return ExitOK
+	default:
+		return c.usage(fmt.Errorf("unknown command %q", command))
+	}
+}
+
+// withAuth parses flags and positional arguments of command, authenticates (logs in or registers)
+// and runs command.
+func (c *CLI) withAuth(
+	auth func(credentials entity.UserCredentials) error,
+	flags *flag.FlagSet,
+	args []string,
+	credentials *entity.UserCredentials,
+	masterKey *string,
+	argsCount int,
+	command func(args []string) int,
+) int {
+	if err := flags.Parse(args); err != nil {
+		return c.usage(err)
+	}
+	if flags.NArg() != argsCount {
+		return c.usage(fmt.Errorf("%s needs %d argument(s)", flags.Name(), argsCount))
+	}
+
+	credentials.MasterKey = []byte(*masterKey)
+	if err := auth(*credentials); err != nil {
+		return c.fail(err)
+	}
+
+	return command(flags.Args())
+}
+
+// login logs in by credentials. Without credentials unlocked session (of agent) is reused.
+func (c *CLI) login(credentials entity.UserCredentials) error {
+	if credentials.Login == "" && credentials.Password == "" && len(credentials.MasterKey) == 0 && c.client.Unlocked() {
+		return nil
+	}
+
+	return c.client.Login(credentials)
+}
+
+// status returns command, which only prints status.
+func (c *CLI) status(status string) func(args []string) int {
+	return func(_ []string) int {
+		return c.print(cliStatus{Status: status})
+	}
+}
+
+// list prints records, which match query. Without limit all pages are got one by one.
+func (c *CLI) list(getQuery func() (entity.RecordsQuery, error)) int {
+	query, err := getQuery()
+	if err != nil {
+		return c.usage(err)
+	}
+
+	all := query.Limit == 0
+	if all {
+		query.Limit = entity.RecordsMaxLimit
+	}
+
+	result := make(cliRecords, 0)
+	for {
+		page, err := c.client.ListRecords(query)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		for _, record := range page.Records {
+			result = append(result, toCLIRecord(record))
+		}
+
+		query.Offset += int32(len(page.Records))
+		if !all || len(page.Records) == 0 || query.Offset >= page.Total {
+			break
+		}
+	}
+
+	return c.print(result)
+}
+
+// get prints decrypted record.
+func (c *CLI) get(args []string) int {
+	record, err := c.client.GetRecord(args[0])
+	if err != nil {
+		return c.fail(err)
+	}
+
+	result := toCLIRecord(record)
+	result.Data = string(record.Data)
+
+	if record.Type != entity.TypeFile {
+		payload, err := entity.DecodePayload(record)
+		if err != nil {
+			return c.fail(err)
+		}
+
+		result.Data, result.Payload = payload.String(), payload
+	}
+
+	return c.print(result)
+}
+
+// otp prints current code of one-time password record. HOTP record is saved with next counter,
+// so the same code isn't returned twice.
+func (c *CLI) otp(args []string) int {
+	record, err := c.client.GetRecord(args[0])
+	if err != nil {
+		return c.fail(err)
+	}
+	if record.Type != entity.TypeOTP {
+		return c.usage(fmt.Errorf("record %s isn't one-time password", record.ID))
+	}
+
+	otp := entity.OTPData{}
+	if err = otp.Decode(record.Data); err != nil {
+		return c.fail(err)
+	}
+
+	key, err := pkg.ParseOTPURI(otp.URI)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	code, remaining := key.Code(time.Now())
+	result := cliOTP{ID: record.ID, Code: code, ExpiresIn: int(remaining.Round(time.Second) / time.Second)}
+
+	if key.Type == pkg.OTPTypeHOTP {
+		result.Counter = key.Counter
+
+		key.Counter++
+		record.Data, _ = (&entity.OTPData{URI: key.URI()}).Bytes()
+		if err = c.client.UpdateRecord(record); err != nil {
+			return c.fail(err)
+		}
+	}
+
+	return c.print(result)
+}
+
+// migrate encrypts labels, which were saved in clear before metadata encryption was on, and saves records,
+// which were saved before payloads were versioned, in current payload format.
+// Migration can be run again after failure, already migrated records are left as is.
+func (c *CLI) migrate(_ []string) int {
+	// Records with labels in clear can't be read with metadata encryption on, so labels are migrated first.
+	labels, err := c.client.MigrateLabels()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	records, err := c.client.GetRecordsInfo()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	result := cliMigrated{Migrated: []string{}, Labels: labels}
+	for _, info := range records {
+		if info.Type == entity.TypeFile {
+			continue
+		}
+
+		record, err := c.client.GetRecord(info.ID)
+		if err != nil {
+			return c.fail(err)
+		}
+		if !entity.IsLegacyPayload(record.Data) {
+			continue
+		}
+
+		payload, err := entity.DecodePayload(record)
+		if err != nil {
+			log.Infoln(err)
+
+			result.Skipped = append(result.Skipped, record.ID)
+			continue
+		}
+
+		record.Data, _ = payload.Bytes()
+		if err = c.client.UpdateRecord(record); err != nil {
+			return c.fail(err)
+		}
+
+		result.Migrated = append(result.Migrated, record.ID)
+	}
+
+	return c.print(result)
+}
+
+// conflicts lists offline changes, which weren't applied on server as they were made.
+func (c *CLI) conflicts(_ []string) int {
+	conflicts, err := c.client.Conflicts()
+	if err != nil {
+		return c.fail(err)
+	}
+
+	result := make(cliConflicts, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		result = append(result, cliConflict{
+			Kind:     conflict.Kind,
+			ID:       conflict.RecordID,
+			Copy:     conflict.CopyID,
+			Metadata: conflict.Metadata,
+			Name:     conflict.Name,
+			Error:    conflict.Error,
+			At:       conflict.At,
+		})
+	}
+
+	return c.print(result)
+}
+
+// importExport imports records of export of other password manager. Entries, which can't be converted
+// or created, are reported and import goes on.
+func (c *CLI) importExport(path, formatName string, options exchange.Options, dryRun bool) int {
+	format, err := exchange.DetectFormat(path)
+	if formatName != "" {
+		format, err = exchange.ParseFormat(formatName)
+	}
+	if err != nil {
+		return c.usage(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c.usage(err)
+	}
+
+	parsed, err := exchange.Parse(format, data, options)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	result := cliImported{DryRun: dryRun, Records: len(parsed.Records), Types: make(map[string]int)}
+	for recordType, count := range parsed.Summary() {
+		result.Types[cliRecordTypes[recordType]] = count
+	}
+	for _, skipped := range parsed.Skipped {
+		result.Skipped = append(result.Skipped, cliSkipped(skipped))
+	}
+
+	if dryRun {
+		return c.print(result)
+	}
+
+	report, err := exchange.Import(c.client, parsed.Records)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	result.Created = report.Created
+	for _, failed := range report.Failed {
+		result.Failed = append(result.Failed, cliSkipped(failed))
+	}
+
+	return c.print(result)
+}
+
+// exportRecords writes decrypted records to file in format of other password manager. File is written
+// only if all records are got, records, which the format can't keep, are reported.
+func (c *CLI) exportRecords(path, formatName string, options exchange.Options) int {
+	format, err := exchange.DetectFormat(path)
+	if formatName != "" {
+		format, err = exchange.ParseFormat(formatName)
+	}
+	if err != nil {
+		return c.usage(err)
+	}
+	if format == exchange.FormatKDBX && options.Password == "" {
+		return c.usage(exchange.ErrNoPassword)
+	}
+
+	collected, err := exchange.Collect(c.client, options.Folder)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	var buf bytes.Buffer
+	skipped, err := exchange.Export(&buf, format, collected.Records, options)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	if err = os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return c.fail(err)
+	}
+
+	result := cliExported{Path: path, Exported: len(collected.Records) - len(skipped)}
+	for _, entry := range append(collected.Skipped, skipped...) {
+		result.Skipped = append(result.Skipped, cliSkipped(entry))
+	}
+
+	return c.print(result)
+}
+
+// backup writes all records of user to archive encrypted by passphrase. Archive is written record by record
+// to temporary file, which replaces archive, only if all records are got or skipped.
+func (c *CLI) backup(path, passphrase string) int {
+	if passphrase == "" {
+		return c.usage(backup.ErrNoPassphrase)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return c.fail(err)
+	}
+	defer os.Remove(file.Name())
+
+	manifest, skipped, err := backup.Write(file, c.client, passphrase)
+	if err == nil {
+		err = file.Sync()
+	}
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		return c.fail(err)
+	}
+
+	result := cliBackedUp{Path: path, Records: len(manifest.Records)}
+	for _, entry := range skipped {
+		result.Skipped = append(result.Skipped, cliSkipped(entry))
+	}
+
+	return c.print(result)
+}
+
+// restore creates records of backup, records, which already exist, are skipped.
+// Backup is read record by record.
+func (c *CLI) restore(path, passphrase string, dryRun bool) int {
+	file, err := os.Open(path)
+	if err != nil {
+		return c.usage(err)
+	}
+	defer file.Close()
+
+	archive, err := backup.NewReader(file, passphrase)
+	if err != nil {
+		return c.fail(err)
+	}
+	defer archive.Close()
+
+	report, err := backup.Restore(c.client, archive, dryRun)
+	if err != nil {
+		return c.fail(err)
+	}
+
+	result := cliRestored{DryRun: dryRun, Restored: report.Restored, Duplicates: report.Duplicates, IDs: report.IDs}
+	for _, failed := range report.Failed {
+		result.Failed = append(result.Failed, cliSkipped(failed))
+	}
+
+	return c.print(result)
+}
+
+// remove deletes record, even if it was changed on another device.
+func (c *CLI) remove(args []string) int {
+	if err := c.client.DeleteRecord(args[0], 0); err != nil {
+		return c.fail(err)
+	}
+
+	return c.print(cliStatus{Status: "deleted", ID: args[0]})
+}
+
+// add creates record of type from flags.
+func (c *CLI) add(
+	flags *flag.FlagSet,
+	recordType string,
+	args []string,
+	credentials *entity.UserCredentials,
+	masterKey *string,
+) int {
+	var (
+		record                  entity.Record
+		text, username, secret  string
+		number, expiration, cvc string
+		uri, notes, url, holder string
+		fields                  []entity.CustomField
+		argsCount               int
+		generate                func() (string, float64, error)
+	)
+
+	flags.StringVar(&record.Metadata, "metadata", "", "metadata of record")
+	flags.StringVar(&record.Name, "name", "", "name of record")
+	flags.Var((*stringsFlag)(&record.Tags), "tag", "tag of record, can be repeated")
+	flags.StringVar(&record.Folder, "folder", "", "folder of record, e.g. work/banks")
+
+	switch recordType {
+	case "text":
+		record.Type = entity.TypeText
+		flags.StringVar(&text, "text", "", "text, stdin is read if it isn't set")
+	case "login":
+		record.Type = entity.TypeLoginAndPassword
+		flags.StringVar(&username, "username", "", "saved login")
+		flags.StringVar(&secret, "secret", "", "saved password, it's generated if isn't set")
+		flags.StringVar(&url, "url", "", "URL of site")
+		flags.StringVar(&notes, "notes", "", "notes")
+		flags.Var(&customFieldsFlag{fields: &fields}, "field", "custom field name=value, can be repeated")
+		flags.Var(&customFieldsFlag{fields: &fields, hidden: true}, "hidden-field", "secret custom field name=value")
+		generate = generatorFlags(flags)
+	case "card":
+		record.Type = entity.TypeCreditCard
+		flags.StringVar(&number, "number", "", "card number")
+		flags.StringVar(&expiration, "expiration", "", "card expiration date")
+		flags.StringVar(&cvc, "cvc", "", "card CVC code")
+		flags.StringVar(&holder, "holder", "", "card holder")
+		flags.StringVar(&notes, "notes", "", "notes")
+	case "file":
+		record.Type = entity.TypeFile
+		argsCount = 1
+	case "otp":
+		record.Type = entity.TypeOTP
+		flags.StringVar(&uri, "uri", "", "otpauth:// URI of key")
+	default:
+		return c.usage(fmt.Errorf("unknown record type %q", recordType))
+	}
+
+	return c.withAuth(c.login, flags, args, credentials, masterKey, argsCount, func(args []string) int {
+		var generated *cliGenerated
+
+		switch record.Type {
+		case entity.TypeText:
+			if text == "" {
+				data, err := io.ReadAll(c.stdin)
+				if err != nil {
+					return c.usage(err)
+				}
+				text = string(data)
+			}
+
+			record.Data, _ = (&entity.TextData{Text: text}).Bytes()
+		case entity.TypeLoginAndPassword:
+			if secret == "" {
+				password, entropy, err := generate()
+				if err != nil {
+					return c.usage(err)
+				}
+
+				secret, generated = password, &cliGenerated{Password: password, Entropy: entropy}
+			}
+
+			record.Data, _ = (&entity.LoginAndPassword{
+				Login:    username,
+				Password: secret,
+				URL:      url,
+				Notes:    notes,
+				Fields:   fields,
+			}).Bytes()
+		case entity.TypeCreditCard:
+			card := entity.CreditCard{
+				CardNumber:     number,
+				ExpirationDate: expiration,
+				CVCCode:        cvc,
+				Holder:         holder,
+				Notes:          notes,
+			}
+			if err := checkCreditCard(card); err != nil {
+				return c.usage(err)
+			}
+
+			record.Data, _ = card.Bytes()
+		case entity.TypeOTP:
+			key, err := pkg.ParseOTPURI(uri)
+			if err != nil {
+				return c.usage(err)
+			}
+			if record.Metadata == "" {
+				record.Metadata = strings.TrimSpace(key.Issuer + " " + key.Account)
+			}
+
+			record.Data, _ = (&entity.OTPData{URI: uri}).Bytes()
+		case entity.TypeFile:
+			filePath := args[0]
+			if record.Metadata == "" {
+				record.Metadata = path.Base(filePath)
+			}
+
+			reader, err := (&entity.BinaryFile{FilePath: filePath}).Reader()
+			if err != nil {
+				return c.usage(err)
+			}
+			defer reader.Close()
+
+			record.Body = reader
+		}
+
+		if _, err := c.client.CreateRecord(record); err != nil {
+			return c.fail(err)
+		}
+
+		if generated != nil {
+			return c.print(cliCreated{cliStatus: cliStatus{Status: "created"}, cliGenerated: *generated})
+		}
+
+		return c.status("created")(nil)
+	})
+}
+
+// customFieldsFlag is repeatable flag of login custom fields in form name=value.
+type customFieldsFlag struct {
+	fields *[]entity.CustomField
+	hidden bool
+}
+
+// String gets fields as flag value.
+func (f *customFieldsFlag) String() string {
+	if f.fields == nil {
+		return ""
+	}
+
+	return formatCustomFields(*f.fields)
+}
+
+// Set adds field.
+func (f *customFieldsFlag) Set(value string) error {
+	name, value, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return errors.New("custom field should be name=value")
+	}
+
+	*f.fields = append(*f.fields, entity.CustomField{Name: name, Value: value, Hidden: f.hidden})
+
+	return nil
+}
+
+// stringsFlag is repeatable flag of strings.
+type stringsFlag []string
+
+// String gets strings as flag value.
+func (f *stringsFlag) String() string {
+	if f == nil {
+		return ""
+	}
+
+	return strings.Join(*f, ",")
+}
+
+// Set adds string.
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+
+	return nil
+}
+
+// queryFlags defines flags of records query and returns query by parsed flags.
+func queryFlags(flags *flag.FlagSet) func() (entity.RecordsQuery, error) {
+	var (
+		query         entity.RecordsQuery
+		types         stringsFlag
+		sort          string
+		offset, limit int
+	)
+
+	flags.StringVar(&query.Search, "search", "", "substring of name or metadata")
+	flags.Var((*stringsFlag)(&query.Tags), "tag", "tag, which records must have, can be repeated")
+	flags.StringVar(&query.Folder, "folder", "", "folder, records of subfolders are listed too")
+	flags.Var(&types, "type", "type of records (text, login, card, file, otp), can be repeated")
+	flags.StringVar(&sort, "sort", "name", "sort by name, updated or type")
+	flags.BoolVar(&query.Descending, "desc", false, "sort in descending order")
+	flags.IntVar(&limit, "limit", 0, "max number of records")
+	flags.IntVar(&offset, "offset", 0, "number of skipped records")
+
+	return func() (entity.RecordsQuery, error) {
+		for _, name := range types {
+			recordType, ok := cliRecordType(name)
+			if !ok {
+				return query, fmt.Errorf("unknown record type %q", name)
+			}
+
+			query.Types = append(query.Types, recordType)
+		}
+
+		var ok bool
+		if query.Sort, ok = cliSorts[sort]; !ok {
+			return query, fmt.Errorf("unknown sorting %q", sort)
+		}
+		if offset < 0 || limit < 0 || limit > entity.RecordsMaxLimit {
+			return query, fmt.Errorf("limit should be in 0..%d and offset shouldn't be negative", entity.RecordsMaxLimit)
+		}
+
+		query.Offset, query.Limit = int32(offset), int32(limit)
+
+		return query, nil
+	}
+}
+
+// cliRecordType gets record type by its name in CLI.
+func cliRecordType(name string) (entity.RecordType, bool) {
+	for recordType, typeName := range cliRecordTypes {
+		if typeName == name {
+			return recordType, true
+		}
+	}
+
+	return 0, false
+}
+
+// generatorFlags defines flags of password generator and returns generator by parsed flags.
+func generatorFlags(flags *flag.FlagSet) func() (string, float64, error) {
+	var (
+		password   = pkg.DefaultPasswordPolicy
+		passphrase = pkg.DefaultPassphrasePolicy
+
+		isPassphrase                      bool
+		noLower, noUpper, noDigits, noSym bool
+	)
+
+	flags.IntVar(&password.Length, "length", password.Length, "length of password")
+	flags.BoolVar(&noLower, "no-lower", false, "password without lowercase letters")
+	flags.BoolVar(&noUpper, "no-upper", false, "password without uppercase letters")
+	flags.BoolVar(&noDigits, "no-digits", false, "password without digits")
+	flags.BoolVar(&noSym, "no-symbols", false, "password without symbols")
+	flags.BoolVar(&password.ExcludeAmbiguous, "no-ambiguous", false, "password without similar characters")
+	flags.BoolVar(&isPassphrase, "passphrase", false, "generate diceware passphrase")
+	flags.IntVar(&passphrase.Words, "words", passphrase.Words, "number of words in passphrase")
+	flags.StringVar(&passphrase.Separator, "separator", passphrase.Separator, "separator of words in passphrase")
+	flags.BoolVar(&passphrase.Capitalize, "capitalize", false, "capitalize words of passphrase")
+
+	return func() (string, float64, error) {
+		if isPassphrase {
+			generated, err := pkg.GeneratePassphrase(passphrase)
+
+			return generated, passphrase.Entropy(), err
+		}
+
+		password.Lower, password.Upper = !noLower, !noUpper
+		password.Digits, password.Symbols = !noDigits, !noSym
+		generated, err := pkg.GeneratePassword(password)
+
+		return generated, password.Entropy(), err
+	}
+}
+
+// print writes result to stdout as JSON or as text.
+func (c *CLI) print(result cliResult) int {
+	if c.output == config.OutputText {
+		fmt.Fprintln(c.stdout, result.text())
+
+		return ExitOK
+	}
+
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(result); err != nil {
+		log.Warnf("%s :: %v", "encode result fault", err)
+
+		return ExitUnknown
+	}
+
+	return ExitOK
+}
+
+// fail writes error to stderr as JSON or as text and returns exit code of its class.
+func (c *CLI) fail(err error) int {
+	code := exitCode(err)
+
+	if c.output == config.OutputText {
+		fmt.Fprintf(c.stderr, "error: %v\n", err)
+
+		return code
+	}
+
+	if errEncode := json.NewEncoder(c.stderr).Encode(cliError{Error: err.Error(), Code: code}); errEncode != nil {
+		log.Warnf("%s :: %v", "encode error fault", errEncode)
+	}
+
+	return code
+}
+
+// usage writes usage error to stderr.
+func (c *CLI) usage(err error) int {
+	c.fail(fmt.Errorf("%w: %v", errUsage, err))
+	fmt.Fprint(c.stderr, cliUsage)
+
+	return ExitUsage
+}
+
+// errUsage means that command is called with wrong arguments.
+var errUsage = errors.New("wrong usage")
+
+// exitCode gets exit code of error class.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, errUsage), errors.Is(err, controller.ErrFieldIsEmpty), errors.Is(err, exchange.ErrBadExport),
+		errors.Is(err, backup.ErrNoPassphrase), errors.Is(err, backup.ErrNotBackup):
+		return ExitUsage
+	case errors.Is(err, storage.ErrWrongCredentials), errors.Is(err, exchange.ErrWrongPassword),
+		errors.Is(err, backup.ErrWrongPassphrase):
+		return ExitWrongCredentials
+	case errors.Is(err, storage.ErrUnauthenticated), errors.Is(err, controller.ErrLocked),
+		errors.Is(err, controller.ErrLegacyKey):
+		return ExitUnauthenticated
+	case errors.Is(err, storage.ErrNotFound):
+		return ExitNotFound
+	case errors.Is(err, storage.ErrLoginExists):
+		return ExitLoginExists
+	case errors.Is(err, storage.ErrConflict):
+		return ExitConflict
+	case errors.Is(err, controller.ErrWrongMasterKey), errors.Is(err, controller.ErrDataCorrupted),
+		errors.Is(err, backup.ErrCorrupted):
+		return ExitWrongMasterKey
+	case errors.Is(err, controller.ErrServerUnavailable), errors.Is(err, controller.ErrOffline):
+		return ExitServerUnavailable
+	case errors.Is(err, storage.ErrNotSupported), errors.Is(err, exchange.ErrUnsupported),
+		errors.Is(err, exchange.ErrEncryptedExport), errors.Is(err, backup.ErrVersion):
+		return ExitNotSupported
+	default:
+		return ExitUnknown
+	}
+}
+
+// toCLIRecord converts record to its JSON view without data.
+func toCLIRecord(record entity.Record) cliRecord {
+	result := cliRecord{
+		ID:       record.ID,
+		Type:     cliRecordTypes[record.Type],
+		Metadata: record.Metadata,
+		Name:     record.Name,
+		Tags:     record.Tags,
+		Folder:   record.Folder,
+		Revision: record.Revision,
+	}
+
+	if !record.UpdatedAt.IsZero() {
+		updatedAt := record.UpdatedAt
+		result.UpdatedAt = &updatedAt
+	}
+
+	return result
+}
+
+// text prints status and ID of record, if it's set.
+func (s cliStatus) text() string {
+	return strings.TrimSpace(s.Status + " " + s.ID)
+}
+
+// text prints generated password.
+func (g cliGenerated) text() string {
+	return g.Password
+}
+
+// text prints generated password, so it can be used in scripts as is.
+func (c cliCreated) text() string {
+	return c.Password
+}
+
+// text prints migrated records per line, skipped records are marked.
+func (m cliMigrated) text() string {
+	lines := make([]string, 0, len(m.Labels)+len(m.Migrated)+len(m.Skipped))
+	for _, id := range m.Labels {
+		lines = append(lines, id+"\tlabels encrypted")
+	}
+	for _, id := range m.Migrated {
+		lines = append(lines, id+"\tmigrated")
+	}
+	for _, id := range m.Skipped {
+		lines = append(lines, id+"\tskipped")
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// text prints one conflict per line: what happened with offline change of record.
+func (cs cliConflicts) text() string {
+	lines := make([]string, 0, len(cs))
+	for _, conflict := range cs {
+		var status string
+
+		switch {
+		case conflict.Error != "":
+			status = "rejected by server, will be sent again: " + conflict.Error
+		case conflict.Copy != "":
+			status = "record was changed on another device, offline " + conflict.Kind + " is saved as " + conflict.Copy
+		default:
+			status = "record was changed on another device, offline " + conflict.Kind + " is skipped"
+		}
+
+		lines = append(lines, conflict.ID+"\t"+conflict.Kind+"\t"+conflict.Metadata+"\t"+status)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// text prints number of records of every type, entries, which aren't imported, and number of created records.
+func (i cliImported) text() string {
+	types := make([]string, 0, len(i.Types))
+	for recordType := range i.Types {
+		types = append(types, recordType)
+	}
+	sort.Strings(types)
+
+	lines := make([]string, 0, len(types)+len(i.Skipped)+len(i.Failed)+1)
+	for _, recordType := range types {
+		lines = append(lines, fmt.Sprintf("%s\t%d", recordType, i.Types[recordType]))
+	}
+	for _, skipped := range i.Skipped {
+		lines = append(lines, "skipped\t"+skipped.Name+"\t"+skipped.Reason)
+	}
+	for _, failed := range i.Failed {
+		lines = append(lines, "failed\t"+failed.Name+"\t"+failed.Reason)
+	}
+
+	if i.DryRun {
+		lines = append(lines, fmt.Sprintf("dry run\t%d", i.Records))
+	} else {
+		lines = append(lines, fmt.Sprintf("created\t%d", i.Created))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// text prints records, which aren't exported, and number of exported records.
+func (e cliExported) text() string {
+	lines := make([]string, 0, len(e.Skipped)+1)
+	for _, skipped := range e.Skipped {
+		lines = append(lines, "skipped\t"+skipped.Name+"\t"+skipped.Reason)
+	}
+	lines = append(lines, fmt.Sprintf("exported\t%d\t%s", e.Exported, e.Path))
+
+	return strings.Join(lines, "\n")
+}
+
+// text prints records, which can't be got, and number of records in backup.
+func (b cliBackedUp) text() string {
+	lines := make([]string, 0, len(b.Skipped)+1)
+	for _, skipped := range b.Skipped {
+		lines = append(lines, "skipped\t"+skipped.Name+"\t"+skipped.Reason)
+	}
+	lines = append(lines, fmt.Sprintf("backed up\t%d\t%s", b.Records, b.Path))
+
+	return strings.Join(lines, "\n")
+}
+
+// text prints new IDs of records of backup, records, which aren't created, and numbers of records.
+func (r cliRestored) text() string {
+	ids := make([]string, 0, len(r.IDs))
+	for id := range r.IDs {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	lines := make([]string, 0, len(ids)+len(r.Failed)+2)
+	for _, id := range ids {
+		lines = append(lines, id+"\t"+r.IDs[id])
+	}
+	for _, failed := range r.Failed {
+		lines = append(lines, "failed\t"+failed.Name+"\t"+failed.Reason)
+	}
+
+	if r.DryRun {
+		lines = append(lines, fmt.Sprintf("dry run\t%d", r.Restored))
+	} else {
+		lines = append(lines, fmt.Sprintf("restored\t%d", r.Restored))
+	}
+	lines = append(lines, fmt.Sprintf("duplicates\t%d", r.Duplicates))
+
+	return strings.Join(lines, "\n")
+}
+
+// text prints code, so it can be used in scripts as is.
+func (o cliOTP) text() string {
+	return o.Code
+}
+
+// text prints record data, so it can be used in scripts as is.
+func (r cliRecord) text() string {
+	return r.Data
+}
+
+// text prints record per line: ID, type, metadata, name and folder separated by tabs.
+func (r cliRecords) text() string {
+	lines := make([]string, 0, len(r))
+	for _, record := range r {
+		lines = append(lines, strings.TrimRight(
+			record.ID+"\t"+record.Type+"\t"+record.Metadata+"\t"+record.Name+"\t"+record.Folder, "\t",
+		))
+	}
+
+	return strings.Join(lines, "\n")
+}
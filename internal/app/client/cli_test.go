@@ -0,0 +1,583 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/config"
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// encode encodes payload to record data.
+func encode(payload entity.Payload) []byte {
+	data, _ := payload.Bytes()
+
+	return data
+}
+
+func TestCLI(t *testing.T) {
+	credentials := entity.UserCredentials{Login: "login", Password: "password", MasterKey: []byte("master")}
+	auth := []string{"-login", "login", "-password", "password", "-master-key", "master"}
+
+	// Secret is "12345678901234567890" of RFC 4226.
+	hotpURI := "otpauth://hotp/Example:bob?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=1"
+
+	file := filepath.Join(t.TempDir(), "file.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("data"), 0o600))
+
+	export := filepath.Join(t.TempDir(), "export.csv")
+	assert.NoError(t, os.WriteFile(export, []byte("name,username,password,notes\nMail,user,secret,\nNote,,,text\n,,,\n"), 0o600))
+
+	exported := filepath.Join(t.TempDir(), "exported.csv")
+	archive := filepath.Join(t.TempDir(), "backup.gkb")
+	note, err := (&entity.TextData{Text: "text"}).Bytes()
+	assert.NoError(t, err)
+
+	tc := []struct {
+		name   string
+		args   []string
+		stdin  string
+		mock   func(client *mocks.ClientHandlers)
+		code   int
+		stdout string
+	}{
+		{
+			name: "Login",
+			args: append([]string{"login"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `{"status": "ok"}`,
+		},
+		{
+			name: "Login with wrong credentials",
+			args: append([]string{"login"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(storage.ErrWrongCredentials).Once()
+			},
+			code: ExitWrongCredentials,
+		},
+		{
+			name: "Register, but login exists",
+			args: append([]string{"register"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Register", credentials).Return(storage.ErrLoginExists).Once()
+			},
+			code: ExitLoginExists,
+		},
+		{
+			name: "List records",
+			args: append([]string{"ls"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("ListRecords", entity.RecordsQuery{Limit: entity.RecordsMaxLimit}).Return(entity.RecordsPage{
+					Records: []entity.Record{
+						{ID: "recordID", Metadata: "site", Type: entity.TypeLoginAndPassword, Revision: 2},
+					},
+					Total: 1,
+				}, nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `[{"id": "recordID", "type": "login", "metadata": "site", "revision": 2}]`,
+		},
+		{
+			name: "List records with filters, all pages are listed",
+			args: append([]string{"ls", "-search", "bank", "-tag", "work", "-tag", "finance", "-folder", "work",
+				"-type", "login", "-type", "card", "-sort", "updated", "-desc"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				query := entity.RecordsQuery{
+					Search:     "bank",
+					Tags:       []string{"work", "finance"},
+					Folder:     "work",
+					Types:      []entity.RecordType{entity.TypeLoginAndPassword, entity.TypeCreditCard},
+					Sort:       entity.SortByUpdatedAt,
+					Descending: true,
+					Limit:      entity.RecordsMaxLimit,
+				}
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("ListRecords", query).Return(entity.RecordsPage{
+					Records: []entity.Record{{ID: "1", Type: entity.TypeLoginAndPassword, Name: "Bank", Tags: []string{"work"}}},
+					Total:   2,
+				}, nil).Once()
+
+				query.Offset = 1
+				client.On("ListRecords", query).Return(entity.RecordsPage{
+					Records: []entity.Record{{ID: "2", Type: entity.TypeCreditCard, Folder: "work"}},
+					Total:   2,
+				}, nil).Once()
+			},
+			code: ExitOK,
+			stdout: `[{"id": "1", "type": "login", "metadata": "", "name": "Bank", "tags": ["work"], "revision": 0},
+				{"id": "2", "type": "card", "metadata": "", "folder": "work", "revision": 0}]`,
+		},
+		{
+			name: "List page of records",
+			args: append([]string{"ls", "-limit", "10", "-offset", "20", "-sort", "type"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("ListRecords", entity.RecordsQuery{Sort: entity.SortByType, Offset: 20, Limit: 10}).
+					Return(entity.RecordsPage{Records: []entity.Record{{ID: "1"}}, Total: 30}, nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `[{"id": "1", "type": "login", "metadata": "", "revision": 0}]`,
+		},
+		{
+			name: "List records with unknown type",
+			args: append([]string{"ls", "-type", "photo"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+			},
+			code: ExitUsage,
+		},
+		{
+			name: "Get record",
+			args: append(append([]string{"get"}, auth...), "recordID"),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("GetRecord", "recordID").Return(entity.Record{
+					ID: "recordID", Type: entity.TypeText, Data: []byte("secret"), Revision: 1,
+				}, nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `{"id": "recordID", "type": "text", "metadata": "", "data": "secret", "payload": {"text": "secret"}, "revision": 1}`,
+		},
+		{
+			name: "Get record, but not found",
+			args: append(append([]string{"get"}, auth...), "recordID"),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("GetRecord", "recordID").Return(entity.Record{}, storage.ErrNotFound).Once()
+			},
+			code: ExitNotFound,
+		},
+		{
+			name: "Get record without ID",
+			args: append([]string{"get"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {},
+			code: ExitUsage,
+		},
+		{
+			name: "Add text record from stdin",
+			args: append([]string{
+				"add", "text", "-metadata", "note", "-name", "Note", "-tag", "personal", "-tag", "ideas", "-folder", "notes",
+			}, auth...),
+			stdin: "text from stdin",
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("CreateRecord", entity.Record{
+					Type: entity.TypeText, Metadata: "note", Data: encode(&entity.TextData{Text: "text from stdin"}),
+					Name: "Note", Tags: []string{"personal", "ideas"}, Folder: "notes",
+				}).Return("", nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `{"status": "created"}`,
+		},
+		{
+			name: "Add login record",
+			args: append([]string{
+				"add", "login", "-username", "user", "-secret", "pass", "-url", "https://example.com",
+				"-field", "pin=1234", "-hidden-field", "answer=42",
+			}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("CreateRecord", entity.Record{
+					Type: entity.TypeLoginAndPassword,
+					Data: encode(&entity.LoginAndPassword{
+						Login:    "user",
+						Password: "pass",
+						URL:      "https://example.com",
+						Fields:   []entity.CustomField{{Name: "pin", Value: "1234"}, {Name: "answer", Value: "42", Hidden: true}},
+					}),
+				}).Return("", nil).Once()
+			},
+			code: ExitOK,
+		},
+		{
+			name: "Add login record with generated passphrase",
+			args: append([]string{"add", "login", "-username", "user", "-passphrase", "-separator", "_"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("CreateRecord", mock.MatchedBy(func(record entity.Record) bool {
+					login := entity.LoginAndPassword{}
+					return login.Decode(record.Data) == nil && login.Login == "user" &&
+						strings.Count(login.Password, "_") == 5
+				})).Return("", nil).Once()
+			},
+			code: ExitOK,
+		},
+		{
+			name: "Add login record with too short generated password",
+			args: append([]string{"add", "login", "-username", "user", "-length", "4"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+			},
+			code: ExitUsage,
+		},
+		{
+			name: "Generate password without classes",
+			args: []string{"generate", "-no-lower", "-no-upper", "-no-digits", "-no-symbols"},
+			mock: func(client *mocks.ClientHandlers) {},
+			code: ExitUsage,
+		},
+		{
+			name: "Add one-time password record",
+			args: append([]string{"add", "otp", "-uri", hotpURI}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("CreateRecord", entity.Record{
+					Type: entity.TypeOTP, Metadata: "Example bob", Data: encode(&entity.OTPData{URI: hotpURI}),
+				}).Return("", nil).Once()
+			},
+			code: ExitOK,
+		},
+		{
+			name: "Add one-time password record with bad URI",
+			args: append([]string{"add", "otp", "-uri", "otpauth://totp/bob"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+			},
+			code: ExitUsage,
+		},
+		{
+			name: "HOTP code advances counter",
+			args: append(append([]string{"otp"}, auth...), "recordID"),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("GetRecord", "recordID").Return(entity.Record{
+					ID: "recordID", Type: entity.TypeOTP, Data: []byte(hotpURI), Revision: 4,
+				}, nil).Once()
+				client.On("UpdateRecord", mock.MatchedBy(func(record entity.Record) bool {
+					otp := entity.OTPData{}
+					assert.NoError(t, otp.Decode(record.Data))
+
+					key, err := pkg.ParseOTPURI(otp.URI)
+					return err == nil && key.Counter == 2 && record.Revision == 4
+				})).Return(nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `{"id": "recordID", "code": "287082", "counter": 1}`,
+		},
+		{
+			name: "One-time password code of text record",
+			args: append(append([]string{"otp"}, auth...), "recordID"),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("GetRecord", "recordID").Return(entity.Record{
+					ID: "recordID", Type: entity.TypeText, Data: []byte("text"),
+				}, nil).Once()
+			},
+			code: ExitUsage,
+		},
+		{
+			name: "Migrate legacy records",
+			args: append([]string{"migrate"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("MigrateLabels").Return([]string{"plain"}, nil).Once()
+				client.On("GetRecordsInfo").Return([]entity.Record{
+					{ID: "legacy", Type: entity.TypeLoginAndPassword},
+					{ID: "current", Type: entity.TypeText},
+					{ID: "broken", Type: entity.TypeCreditCard},
+					{ID: "file", Type: entity.TypeFile},
+				}, nil).Once()
+				client.On("GetRecord", "legacy").Return(entity.Record{
+					ID: "legacy", Type: entity.TypeLoginAndPassword, Data: []byte("user:pa:ss"), Revision: 3,
+				}, nil).Once()
+				client.On("GetRecord", "current").Return(entity.Record{
+					ID: "current", Type: entity.TypeText, Data: encode(&entity.TextData{Text: "text"}),
+				}, nil).Once()
+				client.On("GetRecord", "broken").Return(entity.Record{
+					ID: "broken", Type: entity.TypeCreditCard, Data: []byte("1234"),
+				}, nil).Once()
+				client.On("UpdateRecord", entity.Record{
+					ID:       "legacy",
+					Type:     entity.TypeLoginAndPassword,
+					Data:     encode(&entity.LoginAndPassword{Login: "user", Password: "pa:ss"}),
+					Revision: 3,
+				}).Return(nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `{"migrated": ["legacy"], "skipped": ["broken"], "labels": ["plain"]}`,
+		},
+		{
+			name: "Conflicts of offline changes",
+			args: append([]string{"conflicts"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("Conflicts").Return([]entity.SyncConflict{
+					{Kind: "update", RecordID: "1", CopyID: "5", Metadata: "bank", At: time.Unix(0, 0).UTC()},
+					{Kind: "create", RecordID: "local-1", Metadata: "note", Error: "forbidden", At: time.Unix(0, 0).UTC()},
+				}, nil).Once()
+			},
+			code: ExitOK,
+			stdout: `[
+				{"kind": "update", "id": "1", "copy": "5", "metadata": "bank", "at": "1970-01-01T00:00:00Z"},
+				{"kind": "create", "id": "local-1", "metadata": "note", "error": "forbidden", "at": "1970-01-01T00:00:00Z"}
+			]`,
+		},
+		{
+			name: "Add card record with wrong number",
+			args: append([]string{"add", "card", "-number", "1", "-expiration", "12/30", "-cvc", "123"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+			},
+			code: ExitUsage,
+		},
+		{
+			name: "Add file record, but server is unavailable",
+			args: append(append([]string{"add", "file"}, auth...), file),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("CreateRecord", mock.MatchedBy(func(record entity.Record) bool {
+					return record.Type == entity.TypeFile && record.Metadata == "file.txt" && record.Body != nil
+				})).Return("", controller.ErrOffline).Once()
+			},
+			code: ExitServerUnavailable,
+		},
+		{
+			name: "Add record of unknown type",
+			args: append([]string{"add", "photo"}, auth...),
+			mock: func(client *mocks.ClientHandlers) {},
+			code: ExitUsage,
+		},
+		{
+			name: "Delete record, but master key is wrong",
+			args: append(append([]string{"rm"}, auth...), "recordID"),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(controller.ErrWrongMasterKey).Once()
+			},
+			code: ExitWrongMasterKey,
+		},
+		{
+			name: "Delete record",
+			args: append(append([]string{"rm"}, auth...), "recordID"),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("DeleteRecord", "recordID", int64(0)).Return(nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `{"status": "deleted", "id": "recordID"}`,
+		},
+		{
+			name: "Import dry run doesn't log in",
+			args: []string{"import", "-dry-run", export},
+			mock: func(client *mocks.ClientHandlers) {},
+			code: ExitOK,
+			stdout: `{"dry_run": true, "records": 2, "types": {"login": 1, "text": 1}, "created": 0,
+				"skipped": [{"name": "row 4", "reason": "empty entry"}]}`,
+		},
+		{
+			name: "Import, record is rejected",
+			args: append(append([]string{"import", "-folder", "imported"}, auth...), export),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("CreateRecord", mock.MatchedBy(func(record entity.Record) bool {
+					return record.Type == entity.TypeLoginAndPassword && record.Name == "Mail" && record.Folder == "imported"
+				})).Return("", nil).Once()
+				client.On("CreateRecord", mock.MatchedBy(func(record entity.Record) bool {
+					return record.Type == entity.TypeText
+				})).Return("", storage.ErrUnknown).Once()
+			},
+			code: ExitOK,
+			stdout: `{"records": 2, "types": {"login": 1, "text": 1}, "created": 1,
+				"skipped": [{"name": "row 4", "reason": "empty entry"}],
+				"failed": [{"name": "Note", "reason": "internal server error"}]}`,
+		},
+		{
+			name: "Import with unknown format",
+			args: []string{"import", "-dry-run", "-format", "lastpass", export},
+			mock: func(client *mocks.ClientHandlers) {},
+			code: ExitUsage,
+		},
+		{
+			name: "Import malformed export",
+			args: []string{"import", "-dry-run", "-format", "bitwarden", export},
+			mock: func(client *mocks.ClientHandlers) {},
+			code: ExitUsage,
+		},
+		{
+			name: "Export records of folder to CSV",
+			args: append(append([]string{"export", "-folder", "Work"}, auth...), exported),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("GetRecordsInfo").Return([]entity.Record{
+					{ID: "1", Type: entity.TypeText, Name: "Note", Folder: "Work"},
+					{ID: "2", Type: entity.TypeFile, Name: "Key", Metadata: "id_rsa", Folder: "Work"},
+					{ID: "3", Type: entity.TypeText, Name: "Other"},
+				}, nil).Once()
+				client.On("GetRecord", "1").Return(entity.Record{
+					ID: "1", Type: entity.TypeText, Name: "Note", Folder: "Work", Data: note,
+				}, nil).Once()
+				client.On("GetFile", "2", mock.Anything).Return(entity.Record{
+					ID: "2", Type: entity.TypeFile, Name: "Key", Metadata: "id_rsa", Folder: "Work",
+				}, nil).Once()
+			},
+			code: ExitOK,
+			stdout: `{"path": "` + exported + `", "exported": 1,
+				"skipped": [{"name": "Key (id_rsa)", "reason": "files can't be exported to CSV"}]}`,
+		},
+		{
+			name: "Export to KeePass without password",
+			args: append(append([]string{"export", "-export-password", ""}, auth...), "export.kdbx"),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+			},
+			code: ExitUsage,
+		},
+		{
+			name: "Backup records",
+			args: append(append([]string{"backup", "-passphrase", "passphrase"}, auth...), archive),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("GetRecordsInfo").Return([]entity.Record{
+					{ID: "1", Type: entity.TypeText, Name: "Note"},
+					{ID: "2", Type: entity.TypeText, Name: "Broken"},
+				}, nil).Once()
+				client.On("GetRecord", "1").Return(entity.Record{
+					ID: "1", Type: entity.TypeText, Name: "Note", Data: note,
+				}, nil).Once()
+				client.On("GetRecord", "2").Return(entity.Record{}, storage.ErrNotFound).Once()
+			},
+			code: ExitOK,
+			stdout: `{"path": "` + archive + `", "records": 1,
+				"skipped": [{"name": "Broken", "reason": "not found record with such id"}]}`,
+		},
+		{
+			name: "Restore backup",
+			args: append(append([]string{"restore", "-passphrase", "passphrase"}, auth...), archive),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+				client.On("GetRecordsInfo").Return([]entity.Record{}, nil).Once()
+				client.On("CreateRecord", entity.Record{Type: entity.TypeText, Name: "Note", Data: note}).Return("new", nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `{"restored": 1, "duplicates": 0, "ids": {"1": "new"}}`,
+		},
+		{
+			name: "Restore backup with wrong passphrase",
+			args: append(append([]string{"restore", "-passphrase", "wrong"}, auth...), archive),
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Login", credentials).Return(nil).Once()
+			},
+			code: ExitWrongCredentials,
+		},
+		{
+			name: "List records with unlocked session of agent",
+			args: []string{"ls"},
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Unlocked").Return(true).Once()
+				client.On("ListRecords", entity.RecordsQuery{Limit: entity.RecordsMaxLimit}).
+					Return(entity.RecordsPage{Records: []entity.Record{}}, nil).Once()
+			},
+			code:   ExitOK,
+			stdout: `[]`,
+		},
+		{
+			name: "List records without credentials and locked agent",
+			args: []string{"ls"},
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("Unlocked").Return(false).Once()
+				client.On("Login", entity.UserCredentials{MasterKey: []byte{}}).Return(controller.ErrFieldIsEmpty).Once()
+			},
+			code: ExitUsage,
+		},
+		{
+			name: "Lock without agent",
+			args: []string{"lock"},
+			mock: func(client *mocks.ClientHandlers) {},
+			code: ExitUsage,
+		},
+		{
+			name: "Unknown command",
+			args: []string{"sync"},
+			mock: func(client *mocks.ClientHandlers) {},
+			code: ExitUsage,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			client := mocks.NewClientHandlers(t)
+			tt.mock(client)
+
+			var stdout, stderr bytes.Buffer
+			code := NewCLI(client, config.OutputJSON, strings.NewReader(tt.stdin), &stdout, &stderr).Run(tt.args)
+
+			assert.Equal(t, tt.code, code)
+
+			if tt.stdout != "" {
+				assert.JSONEq(t, tt.stdout, stdout.String())
+			}
+
+			if code != ExitOK {
+				var result cliError
+				assert.NoError(t, json.NewDecoder(&stderr).Decode(&result))
+				assert.Equal(t, tt.code, result.Code)
+			}
+		})
+	}
+}
+
+func TestCLI_TextOutput(t *testing.T) {
+	client := mocks.NewClientHandlers(t)
+	credentials := entity.UserCredentials{Login: "login", Password: "password", MasterKey: []byte("master")}
+	auth := []string{"-login", "login", "-password", "password", "-master-key", "master"}
+
+	client.On("Login", credentials).Return(nil).Times(3)
+	client.On("ListRecords", entity.RecordsQuery{Limit: entity.RecordsMaxLimit}).Return(entity.RecordsPage{
+		Records: []entity.Record{
+			{ID: "first", Metadata: "site", Type: entity.TypeLoginAndPassword, Name: "Site"},
+			{ID: "second", Metadata: "note", Type: entity.TypeText},
+		},
+		Total: 2,
+	}, nil).Once()
+	client.On("GetRecord", "first").Return(entity.Record{
+		ID: "first", Data: encode(&entity.LoginAndPassword{Login: "user", Password: "pass"}),
+	}, nil).Once()
+	client.On("GetRecord", "third").Return(entity.Record{}, storage.ErrNotFound).Once()
+
+	var stdout, stderr bytes.Buffer
+	cli := NewCLI(client, config.OutputText, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, ExitOK, cli.Run(append([]string{"ls"}, auth...)))
+	assert.Equal(t, "first\tlogin\tsite\tSite\nsecond\ttext\tnote\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, ExitOK, cli.Run(append(append([]string{"get"}, auth...), "first")))
+	assert.Equal(t, "Login: user\nPassword: pass\n", stdout.String())
+
+	assert.Equal(t, ExitNotFound, cli.Run(append(append([]string{"get"}, auth...), "third")))
+	assert.Equal(t, "error: "+storage.ErrNotFound.Error()+"\n", stderr.String())
+}
+
+func TestCLI_Generate(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	cli := NewCLI(mocks.NewClientHandlers(t), config.OutputJSON, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, ExitOK, cli.Run([]string{"generate", "-length", "32", "-no-symbols"}))
+
+	var result cliGenerated
+	assert.NoError(t, json.NewDecoder(&stdout).Decode(&result))
+	assert.Len(t, result.Password, 32)
+	assert.False(t, strings.ContainsAny(result.Password, pkg.PasswordSymbols))
+	assert.InDelta(t, 190.5, result.Entropy, 0.1)
+
+	cli.output = config.OutputText
+	stdout.Reset()
+
+	assert.Equal(t, ExitOK, cli.Run([]string{"generate", "-passphrase", "-words", "4", "-separator", " "}))
+	assert.Len(t, strings.Fields(stdout.String()), 4)
+}
@@ -2,13 +2,18 @@ package client
 
 import (
 	"errors"
+	"fmt"
 	"path"
 	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/controller"
 	"github.com/bbt-t/lets-go-keep/internal/controller/handlers"
 	"github.com/bbt-t/lets-go-keep/internal/entity"
 	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -21,6 +26,8 @@ type TUI struct {
 	*tview.Application
 	pages  *tview.Pages
 	client handlers.ClientHandlers //*handlers.client
+	// query filters records list. It's kept, when user returns to the list.
+	query entity.RecordsQuery
 }
 
 // NewTUI gets new terminal user interface for client.
@@ -39,7 +46,11 @@ func NewTUI(client handlers.ClientHandlers) *TUI {
 		pages:       pages,
 	}
 
-	tui.authPage("")
+	if client.Unlocked() {
+		tui.recordsInfoPage("Unlocked by agent.")
+	} else {
+		tui.authPage("")
+	}
 
 	return tui
 }
@@ -73,6 +84,20 @@ func (app *TUI) authPage(message string) {
 			app.authPage("Some fields are empty.")
 			return
 		}
+		if errors.Is(err, pkg.ErrBadKDFParams) {
+			log.Infoln(pkg.ErrBadKDFParams)
+
+			app.authPage("Server sent unsafe key derivation parameters.")
+			return
+		}
+		if errors.Is(err, controller.ErrServerUnavailable) {
+			app.authPage("Server is unavailable, no offline data for this login.")
+			return
+		}
+		if errors.Is(err, controller.ErrWrongMasterKey) {
+			app.authPage("Wrong master key. Please try again.")
+			return
+		}
 		if errors.Is(err, storage.ErrUnknown) || err != nil {
 			log.Infoln(storage.ErrUnknown)
 
@@ -92,12 +117,22 @@ func (app *TUI) authPage(message string) {
 			app.authPage("Such login exists. Please try again.")
 			return
 		}
+		if errors.Is(err, controller.ErrOffline) {
+			app.authPage("Server is unavailable, registration works only online.")
+			return
+		}
 		if errors.Is(err, controller.ErrFieldIsEmpty) {
 			log.Infoln(controller.ErrFieldIsEmpty)
 
 			app.authPage("Some fields are empty.")
 			return
 		}
+		if errors.Is(err, pkg.ErrBadKDFParams) || errors.Is(err, controller.ErrBadPublicKey) {
+			log.Infoln(err)
+
+			app.authPage("Server rejected keys of client. Please update the client.")
+			return
+		}
 		if errors.Is(err, storage.ErrUnknown) || err != nil {
 			log.Infoln(storage.ErrUnknown)
 
@@ -126,8 +161,10 @@ func (app *TUI) authPage(message string) {
 	app.pages.SwitchToPage("authentication")
 }
 
-// recordInfoPage switches to page, where are all records shown. You can choose one.
+// recordInfoPage switches to page, where records are shown. Records are searched by name and metadata
+// and filtered by folder chosen in folder tree. You can choose one.
 func (app *TUI) recordsInfoPage(message string) {
+	// All records are got to build folder tree, list shows only records matching query.
 	records, err := app.client.GetRecordsInfo()
 
 	if errors.Is(err, storage.ErrUnauthenticated) {
@@ -143,32 +180,92 @@ func (app *TUI) recordsInfoPage(message string) {
 		return
 	}
 
+	// Conflicts are shown once, rejected changes are reported, until they are sent.
+	conflicts, err := app.client.Conflicts()
+	if err != nil {
+		log.Infoln(err)
+	}
+
+	var resolved []entity.SyncConflict
+	rejected := 0
+	for _, conflict := range conflicts {
+		if conflict.Error != "" {
+			rejected++
+			continue
+		}
+		resolved = append(resolved, conflict)
+	}
+	if rejected > 0 && message == "" {
+		message = fmt.Sprintf("%d offline changes are rejected by server, they are sent again after login.", rejected)
+	}
+
 	list := tview.NewList()
+	fill := func() {
+		list.Clear()
 
-	for _, record := range records {
+		page, err := app.client.ListRecords(app.query)
+		if err != nil {
+			log.Infoln(err)
 
-		f := func(record entity.Record) func() {
-			return func() {
-				app.recordPage(record.ID, "")
-			}
-		}(record)
+			list.AddItem("Failed get records.", "", 0, nil)
+			return
+		}
 
-		if record.Metadata == "" {
-			record.Metadata = "no metadata"
+		for _, record := range page.Records {
+			f := func(record entity.Record) func() {
+				return func() {
+					app.recordPage(record.ID, "")
+				}
+			}(record)
+
+			list.AddItem(recordTitle(record), record.Type.String()+" | "+recordLabels(record), '*', f)
 		}
 
-		list.AddItem(record.ID, record.Type.String()+" | "+record.Metadata, '*', f)
+		if more := page.Total - int32(len(page.Records)); more > 0 {
+			list.AddItem(fmt.Sprintf("%d more records", more), "Refine search to find them.", 0, nil)
+		}
 	}
+	fill()
+
+	search := tview.NewInputField().SetLabel("Search ").SetText(app.query.Search)
+	search.SetChangedFunc(func(text string) {
+		app.query.Search = text
+		fill()
+	})
+
+	root, nodes := folderTree(records)
+	tree := tview.NewTreeView().SetRoot(root).SetCurrentNode(root)
+	if node, ok := nodes[app.query.Folder]; ok {
+		tree.SetCurrentNode(node)
+	} else {
+		app.query.Folder = ""
+	}
+	tree.SetChangedFunc(func(node *tview.TreeNode) {
+		app.query.Folder, _ = node.GetReference().(string)
+		fill()
+	})
 
-	listFrame := tview.NewFrame(list).SetBorders(0, 0, 0, 1, 4, 4).
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(search, 1, 0, false).
+		AddItem(tview.NewFlex().
+			AddItem(tree, 0, 1, false).
+			AddItem(list, 0, 3, true), 0, 1, true)
+
+	listFrame := tview.NewFrame(layout).SetBorders(0, 0, 0, 1, 4, 4).
 		AddText(
-			"Up/Down - switch between records | Enter - choose this option",
+			"Up/Down - switch between records | Enter - choose this option | TAB - search, folders, records",
 			false,
 			tview.AlignLeft,
 			tcell.ColorWhite,
 		).
 		AddText(
-			"Ctrl+N - create new record | Ctrl+U - refresh",
+			"Ctrl+N - create new record | Ctrl+U - refresh | Ctrl+V - shared vaults | Ctrl+O - import | Ctrl+E - export",
+			false,
+			tview.AlignLeft,
+			tcell.ColorWhite,
+		).
+		AddText(
+			"Ctrl+L - logout | Ctrl+X - logout on all devices",
 			false,
 			tview.AlignLeft,
 			tcell.ColorWhite,
@@ -179,18 +276,193 @@ func (app *TUI) recordsInfoPage(message string) {
 			tcell.ColorWhite,
 		)
 
+	focus := []tview.Primitive{search, tree, list}
 	listFrame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyTab {
+			for i, item := range focus {
+				if item.HasFocus() {
+					app.SetFocus(focus[(i+1)%len(focus)])
+					return nil
+				}
+			}
+		}
 		if event.Key() == tcell.KeyCtrlN {
 			app.createRecordPage("")
 		}
 		if event.Key() == tcell.KeyCtrlU {
 			app.recordsInfoPage("Refreshed.")
 		}
+		if event.Key() == tcell.KeyCtrlV {
+			app.vaultsPage("")
+		}
+		if event.Key() == tcell.KeyCtrlO {
+			app.importPage("")
+		}
+		if event.Key() == tcell.KeyCtrlE {
+			app.exportPage("")
+		}
+		if event.Key() == tcell.KeyCtrlL {
+			app.logout()
+		}
+		if event.Key() == tcell.KeyCtrlX {
+			app.revokeSessionsModal()
+		}
 		return event
 	})
 
 	app.pages.AddPage("records", listFrame, true, true)
 	app.pages.SwitchToPage("records")
+
+	if len(resolved) > 0 {
+		app.syncConflictsModal(resolved)
+	}
+}
+
+// syncConflictsModal shows offline changes, which conflicted with changes made on another device.
+func (app *TUI) syncConflictsModal(conflicts []entity.SyncConflict) {
+	lines := []string{"Records were changed on another device, while you were offline:"}
+	for _, conflict := range conflicts {
+		title := recordTitle(entity.Record{ID: conflict.RecordID, Metadata: conflict.Metadata, Name: conflict.Name})
+		if conflict.CopyID != "" {
+			lines = append(lines, fmt.Sprintf("%s: your changes are saved as new record %s.", title, conflict.CopyID))
+		} else {
+			lines = append(lines, title+": your deletion is skipped, record is kept.")
+		}
+	}
+
+	modal := tview.NewModal().
+		SetText(strings.Join(lines, "\n")).
+		AddButtons([]string{"OK"}).
+		SetDoneFunc(func(_ int, _ string) {
+			app.pages.RemovePage("syncConflicts")
+		})
+
+	app.pages.AddPage("syncConflicts", modal, true, true)
+}
+
+// folderTree builds tree of folders of records. Reference of node is folder path, root is all records.
+func folderTree(records []entity.Record) (*tview.TreeNode, map[string]*tview.TreeNode) {
+	folders := make([]string, 0, len(records))
+	for _, record := range records {
+		if record.Folder != "" {
+			folders = append(folders, record.Folder)
+		}
+	}
+	sort.Strings(folders)
+
+	root := tview.NewTreeNode("All records").SetReference("").SetColor(tcell.ColorGreen)
+	nodes := map[string]*tview.TreeNode{"": root}
+
+	for _, folder := range folders {
+		parts := strings.Split(folder, "/")
+		for i := range parts {
+			folderPath := strings.Join(parts[:i+1], "/")
+			if _, ok := nodes[folderPath]; ok {
+				continue
+			}
+
+			node := tview.NewTreeNode(parts[i]).SetReference(folderPath)
+			nodes[strings.Join(parts[:i], "/")].AddChild(node)
+			nodes[folderPath] = node
+		}
+	}
+
+	return root, nodes
+}
+
+// recordTitle gets name of record. Records without name are shown by ID.
+func recordTitle(record entity.Record) string {
+	if record.Name == "" {
+		return record.ID
+	}
+
+	return record.Name
+}
+
+// recordLabels gets metadata, folder and tags of record to show them in one line.
+func recordLabels(record entity.Record) string {
+	labels := record.Metadata
+	if labels == "" {
+		labels = "no metadata"
+	}
+	if record.Folder != "" {
+		labels += " | /" + record.Folder
+	}
+	for _, tag := range record.Tags {
+		labels += " #" + tag
+	}
+
+	return labels
+}
+
+// addLabelsFields adds name, tags and folder of record to form. New records are put to folder chosen in records list.
+func (app *TUI) addLabelsFields(form *tview.Form, record *entity.Record) {
+	if record.ID == "" {
+		record.Folder = app.query.Folder
+	}
+
+	form.AddInputField("Name", record.Name, 30, nil, func(text string) {
+		record.Name = text
+	})
+	form.AddInputField("Tags", strings.Join(record.Tags, ", "), 30, nil, func(text string) {
+		record.Tags = strings.Split(text, ",")
+	})
+	form.AddInputField("Folder", record.Folder, 30, nil, func(text string) {
+		record.Folder = text
+	})
+}
+
+// logout ends session and switches to authentication page.
+func (app *TUI) logout() {
+	err := app.client.Logout()
+
+	if errors.Is(err, controller.ErrServerUnavailable) {
+		app.authPage("Logged out on this device. Server is unavailable, session will expire itself.")
+		return
+	}
+	if err != nil && !errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		app.authPage("Logged out on this device. Something is wrong with server session.")
+		return
+	}
+
+	app.authPage("Logged out.")
+}
+
+// revokeSessionsModal asks to confirm ending sessions on all devices.
+func (app *TUI) revokeSessionsModal() {
+	modal := tview.NewModal().
+		SetText("Logout on all devices? They will have to login again.").
+		AddButtons([]string{"Logout everywhere", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			if label != "Logout everywhere" {
+				app.recordsInfoPage("")
+				return
+			}
+
+			err := app.client.RevokeSessions()
+
+			if errors.Is(err, storage.ErrUnauthenticated) {
+				app.authPage("Session expired. Please login again.")
+				return
+			}
+			if errors.Is(err, controller.ErrOffline) || errors.Is(err, controller.ErrServerUnavailable) {
+				app.recordsInfoPage("Server is unavailable, try later.")
+				return
+			}
+			if err != nil {
+				log.Infoln(err)
+
+				app.recordsInfoPage("Something is wrong. Please try later.")
+				return
+			}
+
+			app.authPage("Logged out on all devices.")
+		})
+
+	app.pages.AddPage("revokeSessions", modal, true, true)
+	app.pages.SwitchToPage("revokeSessions")
 }
 
 // recordPage switches to record page, where you can see decrypted record data, copy this data, or delete record.
@@ -209,6 +481,10 @@ func (app *TUI) recordPage(recordID string, message string) {
 		app.recordsInfoPage("Not found this record.")
 		return
 	}
+	if errors.Is(err, controller.ErrOffline) {
+		app.recordsInfoPage("This record isn't available offline.")
+		return
+	}
 
 	if err != nil {
 		log.Infoln("Failed get record.")
@@ -217,24 +493,38 @@ func (app *TUI) recordPage(recordID string, message string) {
 		return
 	}
 
-	if record.Metadata == "" {
-		record.Metadata = "no metadata"
+	if record.Type == entity.TypeOTP {
+		app.otpRecordPage(record, message)
+		return
+	}
+
+	// File record has no payload, its data is already saved to file.
+	text, secret := string(record.Data), record.Data
+	if record.Type != entity.TypeFile {
+		payload, err := entity.DecodePayload(record)
+		if err != nil {
+			log.Infoln(err)
+
+			message = "Record data is malformed."
+		} else {
+			text, secret = payload.String(), []byte(payload.Secret())
+		}
 	}
 
 	frame := tview.NewFrame(
 		tview.NewTextView().
-			SetText(string(record.Data)).
+			SetText(text).
 			SetTextColor(tcell.ColorYellow).
 			SetDisabled(true)).
 		SetBorders(0, 0, 0, 1, 4, 4).
 		AddText(
-			record.Metadata+" | "+record.Type.String(),
+			recordTitle(record)+" | "+record.Type.String()+" | "+recordLabels(record),
 			true,
 			tview.AlignCenter,
 			tcell.ColorGreen,
 		).
 		AddText(
-			"Ctrl + K - copy | Ctrl+U - delete | ESC - return to the menu",
+			"Ctrl + K - copy | Ctrl+E - edit | Ctrl+R - history | Ctrl+U - delete | ESC - return to the menu",
 			false,
 			tview.AlignLeft,
 			tcell.ColorWhite,
@@ -252,36 +542,128 @@ func (app *TUI) recordPage(recordID string, message string) {
 			app.recordsInfoPage("Returned to menu.")
 		case tcell.KeyCtrlK:
 			app.recordPage(recordID, "Copied successfully.")
-			clipboard.Write(clipboard.FmtText, record.Data)
+			clipboard.Write(clipboard.FmtText, secret)
+		case tcell.KeyCtrlE:
+			if record.Type == entity.TypeFile {
+				app.recordPage(recordID, "File records can't be edited.")
+				return event
+			}
+
+			app.editRecordPage(record)
+		case tcell.KeyCtrlR:
+			app.recordVersionsPage(recordID, record.Revision, "")
 		case tcell.KeyCtrlU:
-			err := app.client.DeleteRecord(recordID)
+			app.deleteRecord(recordID, record.Revision)
+		}
 
-			if errors.Is(err, storage.ErrUnauthenticated) {
-				log.Infoln(storage.ErrUnauthenticated)
+		return event
+	})
 
-				app.authPage("Session expired. Please login again.")
-				return event
-			}
-			if errors.Is(err, controller.ErrWrongMasterKey) {
-				log.Infoln(controller.ErrWrongMasterKey)
+	app.pages.AddPage("record", frame, true, true)
+	app.pages.SwitchToPage("record")
+}
 
-				app.authPage("Wrong master key. Please login again.")
-				return event
+// otpRecordPage switches to page of one-time password record, where current code is shown with countdown.
+// HOTP counter is advanced, when code is copied.
+func (app *TUI) otpRecordPage(record entity.Record, message string) {
+	otp := entity.OTPData{}
+	if err := otp.Decode(record.Data); err != nil {
+		log.Infoln(err)
+	}
+
+	key, err := pkg.ParseOTPURI(otp.URI)
+	if err != nil {
+		log.Infoln(err)
+
+		message = "Bad otpauth URI, edit it please."
+	}
+
+	view := tview.NewTextView().SetTextColor(tcell.ColorYellow)
+	view.SetDisabled(true)
+	render := func() {
+		if key.Secret == nil {
+			view.SetText(otp.URI)
+			return
+		}
+
+		code, remaining := key.Code(time.Now())
+		if key.Type == pkg.OTPTypeHOTP {
+			view.SetText(fmt.Sprintf("%s\n\ncounter %d", code, key.Counter))
+			return
+		}
+
+		view.SetText(fmt.Sprintf("%s\n\nexpires in %ds", code, int(remaining.Round(time.Second)/time.Second)))
+	}
+	render()
+
+	// Code is redrawn every second, until page is left.
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				app.QueueUpdateDraw(render)
 			}
-			if errors.Is(err, storage.ErrNotFound) {
-				log.Infoln(storage.ErrNotFound)
+		}
+	}()
+
+	frame := tview.NewFrame(view).
+		SetBorders(0, 0, 0, 1, 4, 4).
+		AddText(
+			recordTitle(record)+" | "+key.Issuer+" "+key.Account+" | "+recordLabels(record),
+			true,
+			tview.AlignCenter,
+			tcell.ColorGreen,
+		).
+		AddText(
+			"Ctrl + K - copy code | Ctrl+E - edit | Ctrl+R - history | Ctrl+U - delete | ESC - return to the menu",
+			false,
+			tview.AlignLeft,
+			tcell.ColorWhite,
+		).
+		AddText(
+			message,
+			false,
+			tview.AlignRight,
+			tcell.ColorWhite,
+		)
 
-				app.recordsInfoPage("Failed to delete. Not found record.")
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyESC:
+			close(done)
+			app.recordsInfoPage("Returned to menu.")
+		case tcell.KeyCtrlK:
+			if key.Secret == nil {
 				return event
 			}
-			if errors.Is(err, storage.ErrUnknown) || err != nil {
-				log.Infoln(storage.ErrUnknown)
 
-				app.recordPage(recordID, "Something is wrong. Please try later.")
+			close(done)
+			code, _ := key.Code(time.Now())
+			clipboard.Write(clipboard.FmtText, []byte(code))
+
+			if key.Type == pkg.OTPTypeHOTP {
+				key.Counter++
+				record.Data, _ = (&entity.OTPData{URI: key.URI()}).Bytes()
+				app.updateRecord(record)
 				return event
 			}
 
-			app.recordsInfoPage("Deleted successfully.")
+			app.recordPage(record.ID, "Copied successfully.")
+		case tcell.KeyCtrlE:
+			close(done)
+			app.editRecordPage(record)
+		case tcell.KeyCtrlR:
+			close(done)
+			app.recordVersionsPage(record.ID, record.Revision, "")
+		case tcell.KeyCtrlU:
+			close(done)
+			app.deleteRecord(record.ID, record.Revision)
 		}
 
 		return event
@@ -291,20 +673,380 @@ func (app *TUI) recordPage(recordID string, message string) {
 	app.pages.SwitchToPage("record")
 }
 
+// deleteRecord deletes record, if it wasn't changed on another device after revision. Zero revision deletes it anyway.
+func (app *TUI) deleteRecord(recordID string, revision int64) {
+	err := app.client.DeleteRecord(recordID, revision)
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(storage.ErrUnauthenticated)
+
+		app.authPage("Session expired. Please login again.")
+		return
+	}
+	if errors.Is(err, controller.ErrWrongMasterKey) {
+		log.Infoln(controller.ErrWrongMasterKey)
+
+		app.authPage("Wrong master key. Please login again.")
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Infoln(storage.ErrNotFound)
+
+		app.recordsInfoPage("Failed to delete. Not found record.")
+		return
+	}
+	if errors.Is(err, storage.ErrConflict) {
+		app.conflictModal(
+			"Record was changed on another device. Delete it anyway?",
+			"Delete anyway",
+			func() { app.deleteRecord(recordID, 0) },
+			func() { app.recordPage(recordID, "Record was reloaded.") },
+		)
+		return
+	}
+	if errors.Is(err, storage.ErrUnknown) || err != nil {
+		log.Infoln(storage.ErrUnknown)
+
+		app.recordPage(recordID, "Something is wrong. Please try later.")
+		return
+	}
+
+	app.recordsInfoPage("Deleted successfully.")
+}
+
+// editRecordPage switches to page, where you can change data and metadata of record.
+func (app *TUI) editRecordPage(record entity.Record) {
+	form := tview.NewForm()
+
+	payload, err := entity.DecodePayload(record)
+	if err != nil {
+		log.Infoln(err)
+
+		app.recordPage(record.ID, "Record data is malformed, it can't be edited.")
+		return
+	}
+
+	// Legacy record is saved in current format after editing.
+	switch payload := payload.(type) {
+	case *entity.TextData:
+		form.AddTextArea("Text", payload.Text, 30, 5, 0, func(text string) {
+			payload.Text = text
+		})
+	case *entity.LoginAndPassword:
+		form.AddInputField("Login", payload.Login, 20, nil, func(text string) {
+			payload.Login = text
+		})
+		form.AddInputField("Password", payload.Password, 30, nil, func(text string) {
+			payload.Password = text
+		})
+		form.AddInputField("URL", payload.URL, 30, nil, func(text string) {
+			payload.URL = text
+		})
+		fields := payload.Fields
+		form.AddTextArea("Custom fields", formatCustomFields(fields), 30, 3, 0, func(text string) {
+			payload.Fields = parseCustomFields(text, fields)
+		})
+		form.AddTextArea("Notes", payload.Notes, 30, 3, 0, func(text string) {
+			payload.Notes = text
+		})
+	case *entity.CreditCard:
+		form.AddInputField("Number", payload.CardNumber, 20, nil, func(text string) {
+			payload.CardNumber = text
+		})
+		form.AddInputField("Expiration", payload.ExpirationDate, 20, nil, func(text string) {
+			payload.ExpirationDate = text
+		})
+		form.AddInputField("CVC", payload.CVCCode, 3, nil, func(text string) {
+			payload.CVCCode = text
+		})
+		form.AddInputField("Holder", payload.Holder, 20, nil, func(text string) {
+			payload.Holder = text
+		})
+		form.AddTextArea("Notes", payload.Notes, 30, 3, 0, func(text string) {
+			payload.Notes = text
+		})
+	case *entity.OTPData:
+		form.AddInputField("otpauth URI", payload.URI, 40, nil, func(text string) {
+			payload.URI = text
+		})
+	}
+
+	app.addLabelsFields(form, &record)
+	form.AddInputField("Metadata", record.Metadata, 20, nil, func(text string) {
+		record.Metadata = text
+	})
+	form.AddButton("OK", func() {
+		if card, ok := payload.(*entity.CreditCard); ok {
+			if err := checkCreditCard(*card); err != nil {
+				app.recordPage(record.ID, fmt.Sprintf("Wrong credit card: %v.", err))
+				return
+			}
+		}
+		if otp, ok := payload.(*entity.OTPData); ok {
+			if _, err := pkg.ParseOTPURI(otp.URI); err != nil {
+				app.recordPage(record.ID, fmt.Sprintf("Wrong one-time password key: %v.", err))
+				return
+			}
+		}
+
+		record.Data, _ = payload.Bytes()
+		app.updateRecord(record)
+	})
+
+	frame := tview.NewFrame(form).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText(
+			"TAB - switch between fields | Enter - choose this option",
+			false,
+			tview.AlignLeft,
+			tcell.ColorWhite,
+		).
+		AddText("ESC - return to the record.", false, tview.AlignLeft, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyESC {
+			app.recordPage(record.ID, "")
+		}
+		return event
+	})
+
+	app.pages.AddPage("editRecord", frame, true, true)
+	app.pages.SwitchToPage("editRecord")
+}
+
+// formatCustomFields formats custom fields of login as "name=value" per line.
+func formatCustomFields(fields []entity.CustomField) string {
+	lines := make([]string, 0, len(fields))
+	for _, field := range fields {
+		lines = append(lines, field.Name+"="+field.Value)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// parseCustomFields parses custom fields of login from "name=value" lines.
+// Fields keep being hidden, if they were hidden before.
+func parseCustomFields(text string, previous []entity.CustomField) []entity.CustomField {
+	hidden := make(map[string]bool, len(previous))
+	for _, field := range previous {
+		hidden[field.Name] = field.Hidden
+	}
+
+	var fields []entity.CustomField
+	for _, line := range strings.Split(text, "\n") {
+		name, value, ok := strings.Cut(line, "=")
+		if name = strings.TrimSpace(name); !ok || name == "" {
+			continue
+		}
+
+		fields = append(fields, entity.CustomField{Name: name, Value: value, Hidden: hidden[name]})
+	}
+
+	return fields
+}
+
+// updateRecord saves changed record, if it wasn't changed on another device after record revision.
+// Zero revision saves it anyway.
+func (app *TUI) updateRecord(record entity.Record) {
+	err := app.client.UpdateRecord(record)
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		app.authPage("Session expired. Please login again.")
+		return
+	}
+	if errors.Is(err, controller.ErrWrongMasterKey) {
+		app.authPage("Wrong master key. Please login again.")
+		return
+	}
+	if errors.Is(err, controller.ErrLegacyKey) {
+		app.authPage("Records are encrypted by legacy key. Please login again to upgrade it.")
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		app.recordsInfoPage("Failed to update. Not found record.")
+		return
+	}
+	if errors.Is(err, storage.ErrConflict) {
+		app.conflictModal(
+			"Record was changed on another device. Overwrite it with your version?",
+			"Overwrite",
+			func() {
+				record.Revision = 0
+				app.updateRecord(record)
+			},
+			func() { app.recordPage(record.ID, "Your changes were discarded.") },
+		)
+		return
+	}
+	if err != nil {
+		log.Infoln(err)
+
+		app.recordPage(record.ID, "Something is wrong. Please try later.")
+		return
+	}
+
+	app.recordPage(record.ID, "Updated successfully.")
+}
+
+// conflictModal asks, how to resolve conflict with changes made on another device:
+// to force own change or to take changes from server.
+func (app *TUI) conflictModal(text, force string, onForce, onReload func()) {
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons([]string{force, "Reload"}).
+		SetDoneFunc(func(_ int, label string) {
+			if label == force {
+				onForce()
+				return
+			}
+
+			onReload()
+		})
+
+	app.pages.AddPage("conflict", modal, true, true)
+	app.pages.SwitchToPage("conflict")
+}
+
+// recordVersionsPage switches to page with previous versions of record. Chosen version can be restored,
+// if record wasn't changed on another device after revision.
+func (app *TUI) recordVersionsPage(recordID string, revision int64, message string) {
+	versions, err := app.client.GetRecordVersions(recordID)
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		app.authPage("Session expired. Please login again.")
+		return
+	}
+	if errors.Is(err, controller.ErrOffline) {
+		app.recordPage(recordID, "Record history isn't available offline.")
+		return
+	}
+	if err != nil {
+		log.Infoln(err)
+
+		app.recordPage(recordID, "Failed get record history.")
+		return
+	}
+
+	if len(versions) == 0 {
+		app.recordPage(recordID, "Record has no previous versions.")
+		return
+	}
+
+	list := tview.NewList()
+
+	for _, version := range versions {
+		f := func(version entity.RecordVersion) func() {
+			return func() {
+				app.restoreVersionModal(version, revision)
+			}
+		}(version)
+
+		if version.Metadata == "" {
+			version.Metadata = "no metadata"
+		}
+
+		list.AddItem(
+			fmt.Sprintf("Version %d", version.Version),
+			version.ReplacedAt.Local().Format("02.01.2006 15:04")+" | "+version.Metadata,
+			'*',
+			f,
+		)
+	}
+
+	frame := tview.NewFrame(list).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText(
+			"Up/Down - switch between versions | Enter - restore this version",
+			false,
+			tview.AlignLeft,
+			tcell.ColorWhite,
+		).
+		AddText("ESC - return to the record.", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText(message, false, tview.AlignRight, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyESC {
+			app.recordPage(recordID, "")
+		}
+		return event
+	})
+
+	app.pages.AddPage("recordVersions", frame, true, true)
+	app.pages.SwitchToPage("recordVersions")
+}
+
+// restoreVersionModal asks to confirm restoring of record version.
+func (app *TUI) restoreVersionModal(version entity.RecordVersion, revision int64) {
+	modal := tview.NewModal().
+		SetText(fmt.Sprintf("Restore version %d? Current version will be kept in history.", version.Version)).
+		AddButtons([]string{"Restore", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			if label != "Restore" {
+				app.recordVersionsPage(version.RecordID, revision, "")
+				return
+			}
+
+			app.restoreVersion(version, revision)
+		})
+
+	app.pages.AddPage("restoreVersion", modal, true, true)
+	app.pages.SwitchToPage("restoreVersion")
+}
+
+// restoreVersion restores record version, if record wasn't changed on another device after revision.
+// Zero revision restores it anyway.
+func (app *TUI) restoreVersion(version entity.RecordVersion, revision int64) {
+	err := app.client.RestoreRecordVersion(version.RecordID, version.Version, revision)
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		app.authPage("Session expired. Please login again.")
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		app.recordVersionsPage(version.RecordID, revision, "Not found this version.")
+		return
+	}
+	if errors.Is(err, storage.ErrConflict) {
+		app.conflictModal(
+			"Record was changed on another device. Restore this version anyway?",
+			"Restore anyway",
+			func() { app.restoreVersion(version, 0) },
+			func() { app.recordPage(version.RecordID, "Record was reloaded.") },
+		)
+		return
+	}
+	if errors.Is(err, controller.ErrOffline) {
+		app.recordPage(version.RecordID, "Restoring isn't available offline.")
+		return
+	}
+	if err != nil {
+		log.Infoln(err)
+
+		app.recordVersionsPage(version.RecordID, revision, "Something is wrong. Please try later.")
+		return
+	}
+
+	app.recordPage(version.RecordID, "Restored successfully.")
+}
+
 // createTextRecord creates new text record.
 func (app *TUI) createTextRecord() {
-	record := entity.Record{Type: entity.TypeText}
+	record, textData := entity.Record{Type: entity.TypeText}, entity.TextData{}
 	form := tview.NewForm()
 
 	form.AddTextArea("Text", "", 30, 5, 0, func(text string) {
-		record.Data = []byte(text)
+		textData.Text = text
 	})
+	app.addLabelsFields(form, &record)
 	form.AddInputField("Metadata", "", 20, nil, func(text string) {
 		record.Metadata = text
 	})
 	form.AddButton("OK", func() {
-		err := app.client.CreateRecord(record)
+		record.Data, _ = textData.Bytes()
+		_, err := app.client.CreateRecord(record)
 
+		if errors.Is(err, controller.ErrLegacyKey) {
+			app.authPage("Records are encrypted by legacy key. Please login again to upgrade it.")
+			return
+		}
 		if errors.Is(err, storage.ErrUnauthenticated) {
 			log.Infoln(storage.ErrUnauthenticated)
 
@@ -355,16 +1097,48 @@ func (app *TUI) createCredentialsRecord() {
 	form.AddInputField("Login", "", 20, nil, func(text string) {
 		loginAndPassword.Login = text
 	})
-	form.AddInputField("Password", "", 20, nil, func(text string) {
+	passwordField := tview.NewInputField().SetFieldWidth(30).SetChangedFunc(func(text string) {
 		loginAndPassword.Password = text
 	})
+	form.AddFormItem(passwordField)
+	form.AddInputField("URL", "", 30, nil, func(text string) {
+		loginAndPassword.URL = text
+	})
+	form.AddTextArea("Notes", "", 30, 3, 0, func(text string) {
+		loginAndPassword.Notes = text
+	})
+	app.addLabelsFields(form, &record)
 	form.AddInputField("Metadata", "", 20, nil, func(text string) {
 		record.Metadata = text
 	})
+
+	// Password is generated beforehand, so user has to change it deliberately.
+	generate := func(passphrase bool) {
+		secret, err := pkg.GeneratePassword(pkg.DefaultPasswordPolicy)
+		entropy := pkg.DefaultPasswordPolicy.Entropy()
+		if passphrase {
+			secret, err = pkg.GeneratePassphrase(pkg.DefaultPassphrasePolicy)
+			entropy = pkg.DefaultPassphrasePolicy.Entropy()
+		}
+		if err != nil {
+			log.Warnf("%s :: %v", "generate password fault", err)
+			return
+		}
+
+		passwordField.SetLabel(fmt.Sprintf("Password (%.0f bits)", entropy)).SetText(secret)
+	}
+	generate(false)
+
+	form.AddButton("Password", func() { generate(false) })
+	form.AddButton("Passphrase", func() { generate(true) })
 	form.AddButton("OK", func() {
 		record.Data, _ = loginAndPassword.Bytes()
-		err := app.client.CreateRecord(record)
+		_, err := app.client.CreateRecord(record)
 
+		if errors.Is(err, controller.ErrLegacyKey) {
+			app.authPage("Records are encrypted by legacy key. Please login again to upgrade it.")
+			return
+		}
 		if errors.Is(err, storage.ErrUnauthenticated) {
 			app.authPage("Session expired. Please login again.")
 			return
@@ -383,6 +1157,7 @@ func (app *TUI) createCredentialsRecord() {
 
 	frame := tview.NewFrame(form).SetBorders(0, 0, 0, 1, 4, 4).
 		AddText("TAB - switch between fields | Enter - choose this option", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("Password | Passphrase - generate new password", false, tview.AlignLeft, tcell.ColorWhite).
 		AddText("ESC - exit to all records.", false, tview.AlignLeft, tcell.ColorWhite)
 
 	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
@@ -413,48 +1188,27 @@ func (app *TUI) createCardRecord() {
 	form.AddInputField("CVC", "", 3, nil, func(text string) {
 		creditCard.CVCCode = text
 	})
+	form.AddInputField("Holder", "", 20, nil, func(text string) {
+		creditCard.Holder = text
+	})
+	app.addLabelsFields(form, &record)
 	form.AddInputField("Metadata", "", 20, nil, func(text string) {
 		record.Metadata = text
 	})
 
 	form.AddButton("OK", func() {
-		result, err := regexp.Match(
-			`^(0[1-9]|1[0-2])[|/]?([0-9]{4}|[0-9]{2})$`,
-			[]byte(creditCard.ExpirationDate),
-		)
-		if err != nil {
-			log.Fatalln("Failed parse regex for check card expiration date.")
-		}
-		if !result {
-			app.recordsInfoPage("Incorrect expiration date.")
+		if err := checkCreditCard(creditCard); err != nil {
+			app.recordsInfoPage(fmt.Sprintf("Wrong credit card: %v.", err))
 			return
 		}
 
-		result, err = regexp.Match(
-			`^(?:4[0-9]{12}(?:[0-9]{3})?|[25][1-7][0-9]{14}|6(?:011|5[0-9][0-9])[0-9]{12}|3[47][0-9]{13}|3(?:0[0-5]|[68][0-9])[0-9]{11}|(?:2131|1800|35\d{3})\d{11})$`,
-			[]byte(creditCard.CardNumber),
-		)
-		if err != nil {
-			log.Fatalln("Failed parse regex for check card expiration date.")
-			return
-		}
-		if !result {
-			app.recordsInfoPage("Incorrect card number.")
-			return
-		}
+		record.Data, _ = creditCard.Bytes()
+		_, err := app.client.CreateRecord(record)
 
-		result, err = regexp.Match(`\d{3}`, []byte(creditCard.CVCCode))
-		if err != nil {
-			log.Fatalln("Failed parse regex for check card expiration date.")
-		}
-		if !result {
-			app.recordsInfoPage("Incorrect CVC code.")
+		if errors.Is(err, controller.ErrLegacyKey) {
+			app.authPage("Records are encrypted by legacy key. Please login again to upgrade it.")
 			return
 		}
-
-		record.Data, _ = creditCard.Bytes()
-		err = app.client.CreateRecord(record)
-
 		if errors.Is(err, storage.ErrUnauthenticated) {
 			app.authPage("Session expired. Please login again.")
 			return
@@ -496,6 +1250,28 @@ func (app *TUI) createCardRecord() {
 	app.pages.SwitchToPage("createCardRecord")
 }
 
+// Credit card fields patterns.
+var (
+	cardExpirationPattern = regexp.MustCompile(`^(0[1-9]|1[0-2])[|/]?([0-9]{4}|[0-9]{2})$`)
+	cardNumberPattern     = regexp.MustCompile(`^(?:4[0-9]{12}(?:[0-9]{3})?|[25][1-7][0-9]{14}|6(?:011|5[0-9][0-9])[0-9]{12}|3[47][0-9]{13}|3(?:0[0-5]|[68][0-9])[0-9]{11}|(?:2131|1800|35\d{3})\d{11})$`)
+	cardCVCPattern        = regexp.MustCompile(`\d{3}`)
+)
+
+// checkCreditCard checks card number, expiration date and CVC code.
+func checkCreditCard(card entity.CreditCard) error {
+	if !cardExpirationPattern.MatchString(card.ExpirationDate) {
+		return errors.New("incorrect expiration date")
+	}
+	if !cardNumberPattern.MatchString(card.CardNumber) {
+		return errors.New("incorrect card number")
+	}
+	if !cardCVCPattern.MatchString(card.CVCCode) {
+		return errors.New("incorrect CVC code")
+	}
+
+	return nil
+}
+
 // createFileRecord creates file record. You can choose any file to save.
 func (app *TUI) createFileRecord() {
 	record := entity.Record{Type: entity.TypeFile}
@@ -504,21 +1280,27 @@ func (app *TUI) createFileRecord() {
 	form.AddInputField("Filepath", "", 20, nil, func(text string) {
 		file.FilePath = text
 	})
+	app.addLabelsFields(form, &record)
 
 	form.AddButton("OK", func() {
 		filename := path.Base(file.FilePath)
 		record.Metadata = filename
 
-		data, err := file.Bytes()
+		reader, err := file.Reader()
 
 		if err != nil {
 			app.recordsInfoPage("Failed opened file.")
 			return
 		}
 
-		record.Data = data
-		err = app.client.CreateRecord(record)
+		record.Body = reader
+		_, err = app.client.CreateRecord(record)
+		reader.Close()
 
+		if errors.Is(err, controller.ErrOffline) {
+			app.recordsInfoPage("Files can be saved only online.")
+			return
+		}
 		if errors.Is(err, storage.ErrUnauthenticated) {
 			app.authPage("Session expired. Please login again.")
 			return
@@ -560,13 +1342,75 @@ func (app *TUI) createFileRecord() {
 	app.pages.SwitchToPage("createFileRecord")
 }
 
+// createOTPRecord creates one-time password record from otpauth:// URI.
+func (app *TUI) createOTPRecord() {
+	record := entity.Record{
+		Type: entity.TypeOTP,
+	}
+	form, otp := tview.NewForm(), entity.OTPData{}
+
+	form.AddInputField("otpauth URI", "", 40, nil, func(text string) {
+		otp.URI = text
+	})
+	app.addLabelsFields(form, &record)
+	form.AddInputField("Metadata", "", 20, nil, func(text string) {
+		record.Metadata = text
+	})
+	form.AddButton("OK", func() {
+		key, err := pkg.ParseOTPURI(otp.URI)
+		if err != nil {
+			app.recordsInfoPage(fmt.Sprintf("Wrong one-time password key: %v.", err))
+			return
+		}
+		if record.Metadata == "" {
+			record.Metadata = strings.TrimSpace(key.Issuer + " " + key.Account)
+		}
+
+		record.Data, _ = otp.Bytes()
+		_, err = app.client.CreateRecord(record)
+
+		if errors.Is(err, controller.ErrLegacyKey) {
+			app.authPage("Records are encrypted by legacy key. Please login again to upgrade it.")
+			return
+		}
+		if errors.Is(err, storage.ErrUnauthenticated) {
+			app.authPage("Session expired. Please login again.")
+			return
+		}
+		if errors.Is(err, storage.ErrUnknown) {
+			app.recordsInfoPage("Something is wrong. Please try later.")
+			return
+		}
+		if errors.Is(err, controller.ErrWrongMasterKey) {
+			app.authPage("Wrong master key. Please login again.")
+			return
+		}
+
+		app.recordsInfoPage("Created record successfully.")
+	})
+
+	frame := tview.NewFrame(form).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText("TAB - switch between fields | Enter - choose this option", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("ESC - exit to all records.", false, tview.AlignLeft, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyESC {
+			app.recordsInfoPage("Returned to menu.")
+		}
+		return event
+	})
+
+	app.pages.AddPage("createOTPRecord", frame, true, true)
+	app.pages.SwitchToPage("createOTPRecord")
+}
+
 // createRecordPage creates page, where you can choose record type, then create it.
 func (app *TUI) createRecordPage(message string) {
 	form := tview.NewForm()
 
 	form.AddDropDown(
 		"Type",
-		[]string{"Text", "Login + password", "Credit card", "Binary file"},
+		[]string{"Text", "Login + password", "Credit card", "Binary file", "One-time password"},
 		-1,
 		func(option string, optionIndex int) {
 			switch option {
@@ -578,6 +1422,8 @@ func (app *TUI) createRecordPage(message string) {
 				app.createCardRecord()
 			case "Binary file":
 				app.createFileRecord()
+			case "One-time password":
+				app.createOTPRecord()
 			}
 		})
 
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/exchange"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// exportFormats are formats in dropdown of export page.
+var exportFormats = []exchange.Format{exchange.FormatKDBX, exchange.FormatBitwarden, exchange.FormatCSV}
+
+// exportPage writes records to file in format of other password manager.
+func (app *TUI) exportPage(message string) {
+	var (
+		filePath string
+		format   exchange.Format
+		options  exchange.Options
+	)
+
+	names := make([]string, len(exportFormats))
+	for i, f := range exportFormats {
+		names[i] = string(f)
+	}
+
+	form := tview.NewForm()
+	form.AddInputField("Filepath", "", 30, nil, func(text string) {
+		filePath = text
+	})
+	form.AddDropDown("Format", names, 0, func(_ string, index int) {
+		format = exportFormats[index]
+	})
+	form.AddPasswordField("KeePass password", "", 30, '*', func(text string) {
+		options.Password = text
+	})
+	form.AddInputField("Folder", "", 30, nil, func(text string) {
+		options.Folder = text
+	})
+
+	form.AddButton("Export", func() {
+		if filePath == "" {
+			app.exportPage("Filepath is empty.")
+			return
+		}
+
+		exported, skipped, err := app.exportRecords(filePath, format, options)
+
+		switch {
+		case errors.Is(err, storage.ErrUnauthenticated):
+			app.authPage("Session expired. Please login again.")
+		case errors.Is(err, controller.ErrWrongMasterKey):
+			app.authPage("Wrong master key. Please login again.")
+		case errors.Is(err, exchange.ErrNoPassword):
+			app.exportPage("Enter password of KeePass database.")
+		case err != nil:
+			log.Infoln(err)
+
+			app.exportPage("Failed to export records.")
+		default:
+			for _, entry := range skipped {
+				log.Infof("export %q :: %s", entry.Name, entry.Reason)
+			}
+
+			app.recordsInfoPage(fmt.Sprintf("Exported %d record(s), %d skipped.", exported, len(skipped)))
+		}
+	})
+
+	frame := tview.NewFrame(form).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText("Export to KeePass (KDBX 4), Bitwarden (JSON) or CSV, files are kept only in KeePass database",
+			true, tview.AlignCenter, tcell.ColorGreen).
+		AddText("TAB - switch between fields | Enter - choose this option", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("ESC - return to the menu.", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText(message, false, tview.AlignRight, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyESC {
+			app.recordsInfoPage("Returned to menu.")
+		}
+		return event
+	})
+
+	app.pages.AddPage("export", frame, true, true)
+	app.pages.SwitchToPage("export")
+}
+
+// exportRecords writes records of folder to file. Returns number of exported records and skipped ones.
+func (app *TUI) exportRecords(filePath string, format exchange.Format, options exchange.Options) (int, []exchange.Skipped, error) {
+	if format == "" {
+		format = exportFormats[0]
+	}
+	if format == exchange.FormatKDBX && options.Password == "" {
+		return 0, nil, exchange.ErrNoPassword
+	}
+
+	collected, err := exchange.Collect(app.client, options.Folder)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var buf bytes.Buffer
+	skipped, err := exchange.Export(&buf, format, collected.Records, options)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err = os.WriteFile(filePath, buf.Bytes(), 0o600); err != nil {
+		return 0, nil, err
+	}
+
+	return len(collected.Records) - len(skipped), append(collected.Skipped, skipped...), nil
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/exchange"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+)
+
+// importFormats are formats in dropdown of import page, first one is detected by extension.
+var importFormats = []string{"By extension", string(exchange.FormatKDBX), string(exchange.FormatBitwarden),
+	string(exchange.Format1PUX), string(exchange.FormatCSV)}
+
+// importPage reads export of other password manager and shows what will be imported.
+func (app *TUI) importPage(message string) {
+	var (
+		filePath string
+		format   int
+		options  exchange.Options
+	)
+
+	form := tview.NewForm()
+	form.AddInputField("Filepath", "", 30, nil, func(text string) {
+		filePath = text
+	})
+	form.AddDropDown("Format", importFormats, 0, func(_ string, index int) {
+		format = index
+	})
+	form.AddPasswordField("KeePass password", "", 30, '*', func(text string) {
+		options.Password = text
+	})
+	form.AddInputField("Folder", "", 30, nil, func(text string) {
+		options.Folder = text
+	})
+
+	form.AddButton("Preview", func() {
+		parsed, err := readExport(filePath, format, options)
+		if err != nil {
+			app.importPage(importError(err))
+			return
+		}
+
+		app.importModal(parsed)
+	})
+
+	frame := tview.NewFrame(form).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText("Import from KeePass (KDBX 4), Bitwarden (JSON), 1Password (1PUX) or CSV", true,
+			tview.AlignCenter, tcell.ColorGreen).
+		AddText("TAB - switch between fields | Enter - choose this option", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("ESC - return to the menu.", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText(message, false, tview.AlignRight, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyESC {
+			app.recordsInfoPage("Returned to menu.")
+		}
+		return event
+	})
+
+	app.pages.AddPage("import", frame, true, true)
+	app.pages.SwitchToPage("import")
+}
+
+// importModal shows summary of export and creates records, if import is confirmed.
+func (app *TUI) importModal(parsed exchange.Result) {
+	modal := tview.NewModal().
+		SetText(importSummary(parsed)).
+		AddButtons([]string{"Import", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			if label != "Import" {
+				app.importPage("Import is canceled.")
+				return
+			}
+
+			report, err := exchange.Import(app.client, parsed.Records)
+
+			switch {
+			case errors.Is(err, storage.ErrUnauthenticated):
+				app.authPage("Session expired. Please login again.")
+			case errors.Is(err, controller.ErrWrongMasterKey):
+				app.authPage("Wrong master key. Please login again.")
+			case err != nil:
+				log.Infoln(err)
+
+				app.recordsInfoPage(fmt.Sprintf("Import is stopped, %d record(s) are created.", report.Created))
+			default:
+				for _, failed := range report.Failed {
+					log.Infof("import %q :: %s", failed.Name, failed.Reason)
+				}
+
+				app.recordsInfoPage(fmt.Sprintf("Imported %d record(s), %d failed.", report.Created, len(report.Failed)))
+			}
+		})
+
+	app.pages.AddPage("importSummary", modal, true, true)
+	app.pages.SwitchToPage("importSummary")
+}
+
+// readExport reads and converts export. Format is index of importFormats.
+func readExport(filePath string, format int, options exchange.Options) (exchange.Result, error) {
+	detected, err := exchange.DetectFormat(filePath)
+	if format > 0 {
+		detected, err = exchange.ParseFormat(importFormats[format])
+	}
+	if err != nil {
+		return exchange.Result{}, err
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return exchange.Result{}, err
+	}
+
+	return exchange.Parse(detected, data, options)
+}
+
+// importSummary makes text of modal: numbers of records by type and entries, which aren't imported.
+func importSummary(parsed exchange.Result) string {
+	summary := parsed.Summary()
+
+	types := make([]entity.RecordType, 0, len(summary))
+	for recordType := range summary {
+		types = append(types, recordType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	lines := []string{fmt.Sprintf("%d record(s) will be imported:", len(parsed.Records))}
+	for _, recordType := range types {
+		lines = append(lines, fmt.Sprintf("%s: %d", recordType, summary[recordType]))
+	}
+
+	if len(parsed.Skipped) > 0 {
+		lines = append(lines, fmt.Sprintf("%d entry(ies) are skipped:", len(parsed.Skipped)))
+		for _, skipped := range parsed.Skipped {
+			lines = append(lines, skipped.Name+" - "+skipped.Reason)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// importError gets message of import error.
+func importError(err error) string {
+	switch {
+	case errors.Is(err, exchange.ErrUnknownFormat):
+		return "Unknown format, choose it."
+	case errors.Is(err, exchange.ErrWrongPassword):
+		return "Wrong KeePass password."
+	case errors.Is(err, exchange.ErrEncryptedExport):
+		return "Export is encrypted, export it without password."
+	case errors.Is(err, exchange.ErrUnsupported), errors.Is(err, exchange.ErrBadExport):
+		return err.Error()
+	default:
+		log.Infoln(err)
+
+		return "Failed to read file."
+	}
+}
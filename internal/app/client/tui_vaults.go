@@ -0,0 +1,423 @@
+package client
+
+import (
+	"errors"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	log "github.com/sirupsen/logrus"
+	"golang.design/x/clipboard"
+)
+
+// vaultsPage switches to page with shared vaults of user.
+func (app *TUI) vaultsPage(message string) {
+	vaults, err := app.client.GetVaults()
+	if err != nil {
+		app.vaultFailed(err, app.recordsInfoPage)
+		return
+	}
+
+	list := tview.NewList()
+	for _, vault := range vaults {
+		f := func(vault entity.Vault) func() {
+			return func() {
+				app.vaultPage(vault, "")
+			}
+		}(vault)
+
+		list.AddItem(vault.Name, vault.Role.String(), '*', f)
+	}
+	if len(vaults) == 0 {
+		list.AddItem("No shared vaults.", "Create one or ask owner to invite you.", 0, nil)
+	}
+
+	// Owner checks public key of user by fingerprint, which user tells by another channel.
+	fingerprint, err := app.client.Fingerprint()
+	if err != nil {
+		log.Infoln(err)
+	}
+
+	frame := tview.NewFrame(list).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText("Shared vaults", true, tview.AlignCenter, tcell.ColorGreen).
+		AddText("Your key fingerprint: "+fingerprint, true, tview.AlignCenter, tcell.ColorWhite).
+		AddText("Up/Down - switch between vaults | Enter - open vault", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("Ctrl+N - create new vault | ESC - return to the menu", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText(message, false, tview.AlignRight, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyESC:
+			app.recordsInfoPage("Returned to menu.")
+		case tcell.KeyCtrlN:
+			app.createVaultPage()
+		}
+		return event
+	})
+
+	app.pages.AddPage("vaults", frame, true, true)
+	app.pages.SwitchToPage("vaults")
+}
+
+// createVaultPage creates shared vault, user becomes its owner.
+func (app *TUI) createVaultPage() {
+	var name string
+	form := tview.NewForm()
+
+	form.AddInputField("Name", "", 30, nil, func(text string) {
+		name = text
+	})
+	form.AddButton("OK", func() {
+		if _, err := app.client.CreateVault(name); err != nil {
+			app.vaultFailed(err, app.vaultsPage)
+			return
+		}
+
+		app.vaultsPage("Created vault successfully.")
+	})
+
+	frame := tview.NewFrame(form).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText("TAB - switch between fields | Enter - choose this option", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("ESC - return to vaults.", false, tview.AlignLeft, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyESC {
+			app.vaultsPage("")
+		}
+		return event
+	})
+
+	app.pages.AddPage("createVault", frame, true, true)
+	app.pages.SwitchToPage("createVault")
+}
+
+// vaultPage switches to page with records of vault.
+func (app *TUI) vaultPage(vault entity.Vault, message string) {
+	records, err := app.client.GetVaultRecords(vault.ID)
+	if err != nil {
+		app.vaultFailed(err, app.vaultsPage)
+		return
+	}
+
+	list := tview.NewList()
+	for _, record := range records {
+		f := func(record entity.Record) func() {
+			return func() {
+				app.vaultRecordPage(vault, record.ID, "")
+			}
+		}(record)
+
+		list.AddItem(recordTitle(record), record.Type.String()+" | "+recordLabels(record), '*', f)
+	}
+	if len(records) == 0 {
+		list.AddItem("No records in this vault.", "", 0, nil)
+	}
+
+	frame := tview.NewFrame(list).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText(vault.Name+" | "+vault.Role.String(), true, tview.AlignCenter, tcell.ColorGreen).
+		AddText("Up/Down - switch between records | Enter - open record", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("Ctrl+N - create new record | Ctrl+P - members | ESC - return to vaults", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText(message, false, tview.AlignRight, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyESC:
+			app.vaultsPage("")
+		case tcell.KeyCtrlN:
+			if vault.Role == entity.RoleRead {
+				app.vaultPage(vault, "You can only read records of this vault.")
+				return event
+			}
+
+			app.createVaultRecordPage(vault, entity.TypeText)
+		case tcell.KeyCtrlP:
+			app.vaultMembersPage(vault, "")
+		}
+		return event
+	})
+
+	app.pages.AddPage("vault", frame, true, true)
+	app.pages.SwitchToPage("vault")
+}
+
+// vaultRecordPage switches to page with decrypted record of vault, where it can be copied or deleted.
+func (app *TUI) vaultRecordPage(vault entity.Vault, recordID string, message string) {
+	back := func(message string) { app.vaultPage(vault, message) }
+
+	record, err := app.client.GetVaultRecord(vault.ID, recordID)
+	if err != nil {
+		app.vaultFailed(err, back)
+		return
+	}
+
+	text, secret := "", []byte(nil)
+	if payload, err := entity.DecodePayload(record); err != nil {
+		log.Infoln(err)
+
+		message = "Record data is malformed."
+	} else {
+		text, secret = payload.String(), []byte(payload.Secret())
+	}
+
+	frame := tview.NewFrame(
+		tview.NewTextView().
+			SetText(text).
+			SetTextColor(tcell.ColorYellow).
+			SetDisabled(true)).
+		SetBorders(0, 0, 0, 1, 4, 4).
+		AddText(
+			vault.Name+" | "+recordTitle(record)+" | "+record.Type.String()+" | "+recordLabels(record),
+			true,
+			tview.AlignCenter,
+			tcell.ColorGreen,
+		).
+		AddText("Ctrl + K - copy | Ctrl+U - delete | ESC - return to vault", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText(message, false, tview.AlignRight, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyESC:
+			back("")
+		case tcell.KeyCtrlK:
+			app.vaultRecordPage(vault, recordID, "Copied successfully.")
+			clipboard.Write(clipboard.FmtText, secret)
+		case tcell.KeyCtrlU:
+			if err := app.client.DeleteVaultRecord(vault.ID, recordID); err != nil {
+				app.vaultFailed(err, back)
+				return event
+			}
+
+			back("Deleted record successfully.")
+		}
+		return event
+	})
+
+	app.pages.AddPage("vaultRecord", frame, true, true)
+	app.pages.SwitchToPage("vaultRecord")
+}
+
+// createVaultRecordPage creates text or credentials record in vault.
+func (app *TUI) createVaultRecordPage(vault entity.Vault, recordType entity.RecordType) {
+	record := entity.Record{VaultID: vault.ID, Type: recordType}
+	textData, loginAndPassword := entity.TextData{}, entity.LoginAndPassword{}
+	form := tview.NewForm()
+
+	types := []entity.RecordType{entity.TypeText, entity.TypeLoginAndPassword}
+	current := 0
+	if recordType == entity.TypeLoginAndPassword {
+		current = 1
+	}
+	form.AddDropDown("Type", []string{"Text", "Login + password"}, current, func(_ string, index int) {
+		if index >= 0 && types[index] != recordType {
+			app.createVaultRecordPage(vault, types[index])
+		}
+	})
+
+	if recordType == entity.TypeText {
+		form.AddTextArea("Text", "", 30, 5, 0, func(text string) {
+			textData.Text = text
+		})
+	} else {
+		form.AddInputField("Login", "", 20, nil, func(text string) {
+			loginAndPassword.Login = text
+		})
+		form.AddPasswordField("Password", "", 30, '*', func(text string) {
+			loginAndPassword.Password = text
+		})
+		form.AddInputField("URL", "", 30, nil, func(text string) {
+			loginAndPassword.URL = text
+		})
+	}
+	app.addLabelsFields(form, &record)
+	form.AddInputField("Metadata", "", 20, nil, func(text string) {
+		record.Metadata = text
+	})
+	form.AddButton("OK", func() {
+		if recordType == entity.TypeText {
+			record.Data, _ = textData.Bytes()
+		} else {
+			record.Data, _ = loginAndPassword.Bytes()
+		}
+
+		if _, err := app.client.CreateVaultRecord(record); err != nil {
+			app.vaultFailed(err, func(message string) { app.vaultPage(vault, message) })
+			return
+		}
+
+		app.vaultPage(vault, "Created record successfully.")
+	})
+
+	frame := tview.NewFrame(form).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText("Record is encrypted by vault key, all members can read it.", true, tview.AlignCenter, tcell.ColorGreen).
+		AddText("TAB - switch between fields | Enter - choose this option", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("ESC - return to vault.", false, tview.AlignLeft, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyESC {
+			app.vaultPage(vault, "")
+		}
+		return event
+	})
+
+	app.pages.AddPage("createVaultRecord", frame, true, true)
+	app.pages.SwitchToPage("createVaultRecord")
+}
+
+// vaultMembersPage switches to page with members of vault. Owner can invite and revoke members here.
+func (app *TUI) vaultMembersPage(vault entity.Vault, message string) {
+	back := func(message string) { app.vaultPage(vault, message) }
+
+	members, err := app.client.GetVaultMembers(vault.ID)
+	if err != nil {
+		app.vaultFailed(err, back)
+		return
+	}
+
+	list := tview.NewList()
+	for _, member := range members {
+		f := func(member entity.VaultMember) func() {
+			return func() {
+				if vault.Role != entity.RoleOwner || member.Role == entity.RoleOwner {
+					return
+				}
+
+				app.revokeMemberModal(vault, member)
+			}
+		}(member)
+
+		list.AddItem(member.Login, member.Role.String(), '*', f)
+	}
+
+	frame := tview.NewFrame(list).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText(vault.Name+" | members", true, tview.AlignCenter, tcell.ColorGreen).
+		AddText("Up/Down - switch between members | Enter - revoke member", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("Ctrl+N - invite member | ESC - return to vault", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText(message, false, tview.AlignRight, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyESC:
+			back("")
+		case tcell.KeyCtrlN:
+			if vault.Role != entity.RoleOwner {
+				app.vaultMembersPage(vault, "Only owner can invite members.")
+				return event
+			}
+
+			app.inviteMemberPage(vault)
+		}
+		return event
+	})
+
+	app.pages.AddPage("vaultMembers", frame, true, true)
+	app.pages.SwitchToPage("vaultMembers")
+}
+
+// inviteMemberPage invites user to vault with read or read-write role.
+func (app *TUI) inviteMemberPage(vault entity.Vault) {
+	back := func(message string) { app.vaultMembersPage(vault, message) }
+	login, fingerprint, role := "", "", entity.RoleRead
+	form := tview.NewForm()
+
+	form.AddInputField("Login", "", 20, nil, func(text string) {
+		login = text
+	})
+	form.AddInputField("Key fingerprint", "", 40, nil, func(text string) {
+		fingerprint = text
+	})
+	form.AddDropDown("Role", []string{entity.RoleRead.String(), entity.RoleReadWrite.String()}, 0,
+		func(_ string, index int) {
+			if index == 1 {
+				role = entity.RoleReadWrite
+			} else {
+				role = entity.RoleRead
+			}
+		})
+	form.AddButton("OK", func() {
+		if err := app.client.InviteMember(vault.ID, login, fingerprint, role); err != nil {
+			app.vaultFailed(err, back)
+			return
+		}
+
+		back("Invited " + login + " successfully.")
+	})
+
+	frame := tview.NewFrame(form).SetBorders(0, 0, 0, 1, 4, 4).
+		AddText("Vault key is encrypted by public key of invited user.", true, tview.AlignCenter, tcell.ColorGreen).
+		AddText("Ask user for key fingerprint by another channel, it's shown on vaults page.", true, tview.AlignCenter, tcell.ColorWhite).
+		AddText("TAB - switch between fields | Enter - choose this option", false, tview.AlignLeft, tcell.ColorWhite).
+		AddText("ESC - return to members.", false, tview.AlignLeft, tcell.ColorWhite)
+
+	frame.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyESC {
+			back("")
+		}
+		return event
+	})
+
+	app.pages.AddPage("inviteMember", frame, true, true)
+	app.pages.SwitchToPage("inviteMember")
+}
+
+// revokeMemberModal asks to confirm revoking member of vault.
+func (app *TUI) revokeMemberModal(vault entity.Vault, member entity.VaultMember) {
+	back := func(message string) { app.vaultMembersPage(vault, message) }
+
+	modal := tview.NewModal().
+		SetText("Revoke " + member.Login + "? Vault key is replaced and records are encrypted again. " +
+			"Records, which were already read, stay known to this user.").
+		AddButtons([]string{"Revoke", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			if label != "Revoke" {
+				back("")
+				return
+			}
+
+			if err := app.client.RevokeMember(vault.ID, member.Login); err != nil {
+				app.vaultFailed(err, back)
+				return
+			}
+
+			back("Revoked " + member.Login + ".")
+		})
+
+	app.pages.AddPage("revokeMember", modal, true, true)
+	app.pages.SwitchToPage("revokeMember")
+}
+
+// vaultFailed shows error of vault request on page, which back switches to. Expired session returns to login.
+func (app *TUI) vaultFailed(err error, back func(message string)) {
+	switch {
+	case errors.Is(err, storage.ErrUnauthenticated):
+		app.authPage("Session expired. Please login again.")
+	case errors.Is(err, controller.ErrOffline), errors.Is(err, controller.ErrServerUnavailable):
+		back("Server is unavailable, shared vaults work only online.")
+	case errors.Is(err, storage.ErrForbidden):
+		back("Not enough rights for this vault.")
+	case errors.Is(err, storage.ErrNotFound):
+		back("Not found vault, record or user.")
+	case errors.Is(err, storage.ErrNoPublicKey):
+		back("User has no public key yet, ask the user to login once.")
+	case errors.Is(err, controller.ErrBadFingerprint):
+		back("Public key of user doesn't match fingerprint, check it with the user.")
+	case errors.Is(err, controller.ErrKeyNotVerified):
+		back("Public key of some member isn't verified, invite the member again with fingerprint.")
+	case errors.Is(err, storage.ErrConflict):
+		back("Vault was changed meanwhile. Please try again.")
+	case errors.Is(err, controller.ErrPlainLabels):
+		back("Tags of records aren't encrypted, run migrate to encrypt them.")
+	case errors.Is(err, controller.ErrLegacyKey):
+		app.authPage("Records are encrypted by legacy key. Please login again to upgrade it.")
+	case errors.Is(err, controller.ErrFieldIsEmpty):
+		back("Fill all fields.")
+	case errors.Is(err, controller.ErrDataCorrupted):
+		back("Vault key or record can't be decrypted.")
+	default:
+		log.Infoln(err)
+
+		back("Something is wrong. Please try later.")
+	}
+}
@@ -0,0 +1,606 @@
+// Package backup writes and reads encrypted archives with all records of user, which are kept
+// offline independently of server, and restores records of archives.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/exchange"
+	"github.com/bbt-t/lets-go-keep/pkg"
+)
+
+// Archive: header line with format, version and KDF parameters in JSON, then envelope (see pkg.EncryptBytes)
+// of tar. Key of envelope is derived from passphrase by Argon2id, header has HMAC of format name,
+// so wrong passphrase is told from corrupted archive.
+//
+// Every record is two files of tar: its entry in JSON and its data, so archive is written and read
+// record by record. Manifest with all entries is the last file. Archives of version 1 have manifest first,
+// then data of records in order of manifest.
+const (
+	Format  = "gophkeeper-backup"
+	Version = 2
+
+	manifestName = "manifest.json"
+	recordsDir   = "records"
+	entrySuffix  = ".json"
+
+	// maxMetaSize limits manifest and entries, which are read to memory.
+	maxMetaSize = 64 * 1024 * 1024
+)
+
+// Errors of backups.
+var (
+	ErrNoPassphrase    = errors.New("passphrase of backup is empty")
+	ErrNotBackup       = errors.New("file isn't backup of GophKeeper")
+	ErrVersion         = errors.New("unsupported version of backup")
+	ErrWrongPassphrase = errors.New("wrong passphrase of backup")
+	ErrCorrupted       = errors.New("backup is corrupted")
+)
+
+// Manifest describes records of archive.
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Records   []Entry   `json:"records"`
+}
+
+// Entry is record of archive: labels, path of data in archive, its size and SHA-256 checksum.
+type Entry struct {
+	ID        string            `json:"id"`
+	Type      entity.RecordType `json:"type"`
+	Name      string            `json:"name,omitempty"`
+	Metadata  string            `json:"metadata,omitempty"`
+	Folder    string            `json:"folder,omitempty"`
+	Tags      []string          `json:"tags,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Path      string            `json:"path"`
+	Size      int64             `json:"size"`
+	SHA256    string            `json:"sha256"`
+}
+
+// header is plain first line of archive.
+type header struct {
+	Format  string    `json:"format"`
+	Version int       `json:"version"`
+	KDF     kdfParams `json:"kdf"`
+	Check   string    `json:"check"`
+}
+
+// kdfParams are Argon2id parameters of passphrase.
+type kdfParams struct {
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// newKDFParams is used for key derivation of new archives, tests make it cheaper.
+var newKDFParams = pkg.NewKDFParams
+
+// Write writes archive of all records of user encrypted by passphrase. Records are got one by one,
+// data of file records are contents of files. Records, which can't be got, are skipped,
+// but it stops, if session is expired, server is unavailable or key is wrong. Returns manifest of archive.
+func Write(w io.Writer, reader exchange.RecordReader, passphrase string) (Manifest, []exchange.Skipped, error) {
+	if passphrase == "" {
+		return Manifest{}, nil, ErrNoPassphrase
+	}
+
+	params, err := newKDFParams()
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+
+	key, err := pkg.DeriveKey([]byte(passphrase), params)
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+
+	line, err := json.Marshal(header{
+		Format:  Format,
+		Version: Version,
+		KDF:     kdfParams{Salt: params.Salt, Time: params.Time, Memory: params.Memory, Threads: params.Threads},
+		Check:   check(key),
+	})
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+
+	if _, err = w.Write(append(line, '\n')); err != nil {
+		return Manifest{}, nil, err
+	}
+
+	var (
+		manifest = Manifest{Version: Version, CreatedAt: time.Now().UTC(), Records: []Entry{}}
+		skipped  []exchange.Skipped
+		done     = make(chan struct{})
+	)
+
+	// Tar is encrypted, while it's written.
+	pr, pw := io.Pipe()
+	go func() {
+		defer close(done)
+		pw.CloseWithError(writeTar(pw, reader, &manifest, &skipped))
+	}()
+
+	encrypted, err := pkg.NewEncryptReader(pr, key)
+	if err == nil {
+		_, err = io.Copy(w, encrypted)
+	}
+	pr.CloseWithError(err)
+	<-done
+
+	if err != nil {
+		return Manifest{}, nil, err
+	}
+
+	return manifest, skipped, nil
+}
+
+// writeTar writes entries and data of records, which are got one by one, and manifest after them.
+func writeTar(w io.Writer, reader exchange.RecordReader, manifest *Manifest, skipped *[]exchange.Skipped) error {
+	infos, err := reader.GetRecordsInfo()
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+
+	for _, info := range infos {
+		entry, data, err := getRecord(reader, info)
+		if exchange.Fatal(err) {
+			return err
+		}
+		if err != nil {
+			*skipped = append(*skipped, exchange.Skipped{Name: exchange.RecordName(info), Reason: err.Error()})
+			continue
+		}
+
+		entry.Path = path.Join(recordsDir, strconv.Itoa(len(manifest.Records)))
+		err = writeEntry(tw, entry, manifest.CreatedAt, data)
+		data.Close()
+		if err != nil {
+			return err
+		}
+
+		manifest.Records = append(manifest.Records, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = writeTarFile(tw, manifestName, manifest.CreatedAt, int64(len(data)), bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// getRecord gets record with its data, size and checksum of data. File is spooled, because its size
+// and checksum are written before it. Caller must close data.
+func getRecord(reader exchange.RecordReader, info entity.Record) (Entry, io.ReadCloser, error) {
+	if info.Type == entity.TypeFile {
+		return spoolFile(reader, info.ID)
+	}
+
+	record, err := reader.GetRecord(info.ID)
+	if err != nil {
+		return Entry{}, nil, err
+	}
+
+	checksum := sha256.Sum256(record.Data)
+	entry := entryOf(record)
+	entry.Size, entry.SHA256 = int64(len(record.Data)), hex.EncodeToString(checksum[:])
+
+	return entry, io.NopCloser(bytes.NewReader(record.Data)), nil
+}
+
+// spoolFile downloads file record to temporary file, which is encrypted by one-time key, so file
+// isn't kept in memory or in clear on disk. Temporary file is removed, when data is closed.
+func spoolFile(reader exchange.RecordReader, recordID string) (Entry, io.ReadCloser, error) {
+	key, err := pkg.GenerateRandom(pkg.KDFKeySize)
+	if err != nil {
+		return Entry{}, nil, err
+	}
+
+	file, err := os.CreateTemp("", "backup-*")
+	if err != nil {
+		return Entry{}, nil, err
+	}
+	spooled := &spooledFile{file: file}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		encrypted, err := pkg.NewEncryptReader(pr, key)
+		if err == nil {
+			_, err = io.Copy(file, encrypted)
+		}
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	var (
+		hash = sha256.New()
+		size counter
+	)
+
+	record, err := reader.GetFile(recordID, io.MultiWriter(pw, hash, &size))
+	pw.CloseWithError(err)
+	if errSpool := <-done; err == nil {
+		err = errSpool
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		spooled.Reader, err = decryptReader(file, key)
+	}
+	if err != nil {
+		spooled.Close()
+
+		return Entry{}, nil, err
+	}
+
+	entry := entryOf(record)
+	entry.Size, entry.SHA256 = int64(size), hex.EncodeToString(hash.Sum(nil))
+
+	return entry, spooled, nil
+}
+
+// spooledFile is decrypted reader of spooled file, which removes file on close.
+type spooledFile struct {
+	io.Reader
+	file *os.File
+}
+
+// Close closes and removes spooled file.
+func (s *spooledFile) Close() error {
+	if closer, ok := s.Reader.(io.Closer); ok {
+		closer.Close()
+	}
+	s.file.Close()
+
+	return os.Remove(s.file.Name())
+}
+
+// counter counts written bytes.
+type counter int64
+
+func (c *counter) Write(p []byte) (int, error) {
+	*c += counter(len(p))
+
+	return len(p), nil
+}
+
+// entryOf gets entry of record without path, size and checksum of data.
+func entryOf(record entity.Record) Entry {
+	return Entry{
+		ID:        record.ID,
+		Type:      record.Type,
+		Name:      record.Name,
+		Metadata:  record.Metadata,
+		Folder:    record.Folder,
+		Tags:      record.Tags,
+		UpdatedAt: record.UpdatedAt,
+	}
+}
+
+// record gets record of entry without data.
+func (e Entry) record() entity.Record {
+	return entity.Record{
+		ID:        e.ID,
+		Type:      e.Type,
+		Name:      e.Name,
+		Metadata:  e.Metadata,
+		Folder:    e.Folder,
+		Tags:      e.Tags,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+// writeEntry writes entry of record and its data.
+func writeEntry(tw *tar.Writer, entry Entry, modified time.Time, data io.Reader) error {
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err = writeTarFile(tw, entry.Path+entrySuffix, modified, int64(len(meta)), bytes.NewReader(meta)); err != nil {
+		return err
+	}
+
+	return writeTarFile(tw, entry.Path, modified, entry.Size, data)
+}
+
+// writeTarFile writes file of tar with size from r.
+func writeTarFile(tw *tar.Writer, name string, modified time.Time, size int64, r io.Reader) error {
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o600,
+		Size:     size,
+		ModTime:  modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, r)
+
+	return err
+}
+
+// Reader reads archive record by record, so records aren't kept in memory.
+type Reader struct {
+	plain    io.ReadCloser
+	tr       *tar.Reader
+	version  int
+	manifest Manifest
+	entries  []Entry
+	done     bool
+}
+
+// NewReader reads header of archive encrypted by passphrase and checks passphrase. Caller must close reader.
+func NewReader(r io.Reader, passphrase string) (*Reader, error) {
+	if passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+
+	br := bufio.NewReader(r)
+
+	line, err := br.ReadBytes('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	var h header
+	if err = json.Unmarshal(line, &h); err != nil || h.Format != Format {
+		return nil, ErrNotBackup
+	}
+	if h.Version < 1 || h.Version > Version {
+		return nil, fmt.Errorf("%w: %d", ErrVersion, h.Version)
+	}
+
+	key, err := pkg.DeriveKey([]byte(passphrase), entity.KDFParams{
+		Salt:    h.KDF.Salt,
+		Time:    h.KDF.Time,
+		Memory:  h.KDF.Memory,
+		Threads: h.KDF.Threads,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+
+	if !hmac.Equal([]byte(check(key)), []byte(h.Check)) {
+		return nil, ErrWrongPassphrase
+	}
+
+	plain, err := decryptReader(br, key)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &Reader{plain: plain, tr: tar.NewReader(plain), version: h.Version}
+	if reader.version == 1 {
+		if err = reader.readManifest(); err != nil {
+			plain.Close()
+
+			return nil, err
+		}
+	}
+
+	return reader, nil
+}
+
+// Next gets entry of next record and reader of its data, which checks size and checksum of data at its end.
+// Returns io.EOF after the last record, when manifest and end of archive are checked.
+func (r *Reader) Next() (Entry, io.Reader, error) {
+	if r.done {
+		return Entry{}, nil, io.EOF
+	}
+
+	var (
+		entry Entry
+		err   error
+	)
+
+	if r.version == 1 {
+		entry, err = r.nextListed()
+	} else {
+		entry, err = r.nextEntry()
+	}
+	if err != nil {
+		return Entry{}, nil, err
+	}
+
+	h, err := r.tr.Next()
+	if err != nil {
+		return Entry{}, nil, corrupted(err)
+	}
+	if h.Name != entry.Path || h.Size != entry.Size {
+		return Entry{}, nil, fmt.Errorf("%w: no data of record %s", ErrCorrupted, entry.ID)
+	}
+
+	r.entries = append(r.entries, entry)
+
+	return entry, &checkedReader{r: r.tr, entry: entry, hash: sha256.New()}, nil
+}
+
+// Close stops decryption of archive.
+func (r *Reader) Close() error {
+	return r.plain.Close()
+}
+
+// nextListed gets next entry of manifest, which is read first in archives of version 1.
+func (r *Reader) nextListed() (Entry, error) {
+	if len(r.entries) == len(r.manifest.Records) {
+		return Entry{}, r.finish()
+	}
+
+	return r.manifest.Records[len(r.entries)], nil
+}
+
+// nextEntry reads next entry, manifest after the last entry must list the same entries.
+func (r *Reader) nextEntry() (Entry, error) {
+	h, err := r.tr.Next()
+	if errors.Is(err, io.EOF) {
+		return Entry{}, fmt.Errorf("%w: no manifest", ErrCorrupted)
+	}
+	if err != nil {
+		return Entry{}, corrupted(err)
+	}
+
+	if h.Name == manifestName {
+		if err = r.decodeMeta(&r.manifest); err != nil {
+			return Entry{}, fmt.Errorf("%w: manifest: %v", ErrCorrupted, err)
+		}
+		if !sameEntries(r.manifest.Records, r.entries) {
+			return Entry{}, fmt.Errorf("%w: manifest doesn't match records", ErrCorrupted)
+		}
+
+		return Entry{}, r.finish()
+	}
+
+	var entry Entry
+	if !strings.HasSuffix(h.Name, entrySuffix) {
+		return Entry{}, fmt.Errorf("%w: unexpected file %s", ErrCorrupted, h.Name)
+	}
+	if err = r.decodeMeta(&entry); err != nil || entry.Path+entrySuffix != h.Name {
+		return Entry{}, fmt.Errorf("%w: entry %s", ErrCorrupted, h.Name)
+	}
+
+	return entry, nil
+}
+
+// readManifest reads manifest, which is the first file of archives of version 1.
+func (r *Reader) readManifest() error {
+	h, err := r.tr.Next()
+	if err != nil || h.Name != manifestName {
+		return fmt.Errorf("%w: no manifest", ErrCorrupted)
+	}
+
+	if err = r.decodeMeta(&r.manifest); err != nil {
+		return fmt.Errorf("%w: manifest: %v", ErrCorrupted, err)
+	}
+
+	return nil
+}
+
+// decodeMeta decodes current file of tar, which is manifest or entry, from JSON.
+func (r *Reader) decodeMeta(v interface{}) error {
+	data, err := io.ReadAll(io.LimitReader(r.tr, maxMetaSize))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// finish checks that there are no files after the last record and that envelope isn't truncated.
+// Returns io.EOF, if archive is whole.
+func (r *Reader) finish() error {
+	if _, err := r.tr.Next(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: unexpected data after manifest", ErrCorrupted)
+	}
+
+	if _, err := io.Copy(io.Discard, r.plain); err != nil {
+		return corrupted(err)
+	}
+
+	r.done = true
+
+	return io.EOF
+}
+
+// checkedReader reads data of record and checks its size and checksum at its end.
+type checkedReader struct {
+	r     io.Reader
+	entry Entry
+	hash  hash.Hash
+	size  int64
+}
+
+func (c *checkedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	c.size += int64(n)
+
+	if errors.Is(err, io.EOF) && (c.size != c.entry.Size || hex.EncodeToString(c.hash.Sum(nil)) != c.entry.SHA256) {
+		return n, fmt.Errorf("%w: checksum of record %s mismatch", ErrCorrupted, c.entry.ID)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, corrupted(err)
+	}
+
+	return n, err
+}
+
+// sameEntries checks that manifest lists entries, which were read.
+func sameEntries(listed, read []Entry) bool {
+	if len(listed) != len(read) {
+		return false
+	}
+
+	for i := range listed {
+		if listed[i].ID != read[i].ID || listed[i].Path != read[i].Path || listed[i].SHA256 != read[i].SHA256 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decryptReader decrypts envelope, while it's read. Closing reader stops decryption.
+func decryptReader(r io.Reader, key []byte) (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+
+	dw, err := pkg.NewDecryptWriter(pw, key)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		_, err := io.Copy(dw, r)
+		if err == nil {
+			err = dw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// corrupted wraps error of reading archive.
+func corrupted(err error) error {
+	if errors.Is(err, ErrCorrupted) {
+		return err
+	}
+
+	return fmt.Errorf("%w: %v", ErrCorrupted, err)
+}
+
+// check makes HMAC of format name by key of archive.
+func check(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(Format))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,245 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/exchange"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
+)
+
+func init() {
+	// Cheap key derivation for tests.
+	newKDFParams = func() (entity.KDFParams, error) {
+		salt, err := pkg.GenerateRandom(pkg.KDFSalt)
+		return entity.KDFParams{Salt: salt, Time: 1, Memory: 16 * 1024, Threads: 1}, err
+	}
+}
+
+// testRecords are records of user with data, as they are got for backup.
+var testRecords = []entity.Record{
+	{ID: "1", Type: entity.TypeText, Name: "Note", Tags: []string{"personal"}, Data: []byte(`{"text":"hello"}`)},
+	{ID: "2", Type: entity.TypeFile, Name: "Key", Metadata: "id_rsa", Folder: "Work", Data: []byte("private key")},
+	{ID: "3", Type: entity.TypeLoginAndPassword, Name: "Mail", Folder: "Work/Mail", Data: []byte(`{"login":"user"}`)},
+}
+
+// recordsClient returns client, which gets records with data: files by GetFile, other records by GetRecord.
+func recordsClient(t *testing.T, records []entity.Record) *mocks.ClientHandlers {
+	client := mocks.NewClientHandlers(t)
+
+	infos := make([]entity.Record, 0, len(records))
+	for _, record := range records {
+		info, data := record, record.Data
+		info.Data = nil
+		infos = append(infos, info)
+
+		if record.Type != entity.TypeFile {
+			client.On("GetRecord", record.ID).Return(record, nil).Once()
+			continue
+		}
+
+		client.On("GetFile", record.ID, mock.Anything).Run(func(args mock.Arguments) {
+			_, _ = args.Get(1).(io.Writer).Write(data)
+		}).Return(info, nil).Once()
+	}
+	client.On("GetRecordsInfo").Return(infos, nil).Once()
+
+	return client
+}
+
+func testBackup(t *testing.T, records []entity.Record) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	_, _, err := Write(&buf, recordsClient(t, records), "passphrase")
+	require.NoError(t, err)
+
+	return buf.Bytes()
+}
+
+// readArchive reads all records of archive with their data.
+func readArchive(data []byte, passphrase string) ([]entity.Record, error) {
+	archive, err := NewReader(bytes.NewReader(data), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	var records []entity.Record
+	for {
+		entry, data, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+
+		record := entry.record()
+		if record.Data, err = io.ReadAll(data); err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+}
+
+// archiveV1 makes archive of version 1: manifest is the first file, then data of records.
+func archiveV1(t *testing.T, manifest Manifest, data [][]byte) []byte {
+	t.Helper()
+
+	params, err := newKDFParams()
+	require.NoError(t, err)
+	key, err := pkg.DeriveKey([]byte("passphrase"), params)
+	require.NoError(t, err)
+
+	var plain bytes.Buffer
+	tw := tar.NewWriter(&plain)
+	meta, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	require.NoError(t, writeTarFile(tw, manifestName, manifest.CreatedAt, int64(len(meta)), bytes.NewReader(meta)))
+	for i := range data {
+		path := manifest.Records[i].Path
+		require.NoError(t, writeTarFile(tw, path, manifest.CreatedAt, int64(len(data[i])), bytes.NewReader(data[i])))
+	}
+	require.NoError(t, tw.Close())
+
+	encrypted, err := pkg.EncryptBytes(key, plain.Bytes())
+	require.NoError(t, err)
+
+	line, err := json.Marshal(header{
+		Format:  Format,
+		Version: 1,
+		KDF:     kdfParams{Salt: params.Salt, Time: params.Time, Memory: params.Memory, Threads: params.Threads},
+		Check:   check(key),
+	})
+	require.NoError(t, err)
+
+	return append(append(line, '\n'), encrypted...)
+}
+
+func TestWriteRead(t *testing.T) {
+	var buf bytes.Buffer
+	manifest, skipped, err := Write(&buf, recordsClient(t, testRecords), "passphrase")
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+
+	checksum := sha256.Sum256([]byte("private key"))
+	assert.Equal(t, Version, manifest.Version)
+	require.Len(t, manifest.Records, 3)
+	assert.Equal(t, Entry{
+		ID:       "2",
+		Type:     entity.TypeFile,
+		Name:     "Key",
+		Metadata: "id_rsa",
+		Folder:   "Work",
+		Path:     "records/1",
+		Size:     11,
+		SHA256:   hex.EncodeToString(checksum[:]),
+	}, manifest.Records[1])
+
+	t.Log("Archive is self-describing, but records are encrypted")
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte(`{"format":"gophkeeper-backup","version":2,`)))
+	assert.NotContains(t, buf.String(), "private key")
+	assert.NotContains(t, buf.String(), "Mail")
+
+	records, err := readArchive(buf.Bytes(), "passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, testRecords, records)
+
+	t.Log("Wrong passphrase")
+	_, err = readArchive(buf.Bytes(), "wrong")
+	assert.ErrorIs(t, err, ErrWrongPassphrase)
+
+	t.Log("Corrupted archive")
+	data := append([]byte(nil), buf.Bytes()...)
+	data[len(data)-20] ^= 0xFF
+	_, err = readArchive(data, "passphrase")
+	assert.ErrorIs(t, err, ErrCorrupted)
+
+	t.Log("Truncated archive")
+	_, err = readArchive(buf.Bytes()[:len(buf.Bytes())-100], "passphrase")
+	assert.ErrorIs(t, err, ErrCorrupted)
+
+	t.Log("Not backup")
+	_, err = readArchive([]byte("name,password\n"), "passphrase")
+	assert.ErrorIs(t, err, ErrNotBackup)
+
+	t.Log("Future version")
+	_, err = readArchive([]byte(`{"format":"gophkeeper-backup","version":3}`+"\n"), "passphrase")
+	assert.ErrorIs(t, err, ErrVersion)
+
+	t.Log("Empty passphrase")
+	_, _, err = Write(&buf, mocks.NewClientHandlers(t), "")
+	assert.ErrorIs(t, err, ErrNoPassphrase)
+}
+
+func TestWrite_Skipped(t *testing.T) {
+	t.Log("Record, which can't be got, is skipped")
+	client := recordsClient(t, testRecords[:1])
+	client.On("GetRecordsInfo").Unset()
+	client.On("GetRecordsInfo").Return([]entity.Record{
+		{ID: "1", Type: entity.TypeText, Name: "Note"},
+		{ID: "4", Type: entity.TypeFile, Name: "Broken", Metadata: "Broken"},
+	}, nil).Once()
+	client.On("GetFile", "4", mock.Anything).Return(entity.Record{}, storage.ErrNotFound).Once()
+
+	var buf bytes.Buffer
+	manifest, skipped, err := Write(&buf, client, "passphrase")
+	require.NoError(t, err)
+	assert.Len(t, manifest.Records, 1)
+	assert.Equal(t, []exchange.Skipped{{Name: "Broken", Reason: storage.ErrNotFound.Error()}}, skipped)
+
+	records, err := readArchive(buf.Bytes(), "passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, testRecords[:1], records)
+
+	t.Log("Unavailable server stops backup")
+	client = mocks.NewClientHandlers(t)
+	client.On("GetRecordsInfo").Return([]entity.Record{{ID: "1", Type: entity.TypeText}}, nil).Once()
+	client.On("GetRecord", "1").Return(entity.Record{}, controller.ErrServerUnavailable).Once()
+
+	_, _, err = Write(&bytes.Buffer{}, client, "passphrase")
+	assert.ErrorIs(t, err, controller.ErrServerUnavailable)
+}
+
+func TestRead_Version1(t *testing.T) {
+	checksum := sha256.Sum256([]byte("private key"))
+	manifest := Manifest{
+		Version:   1,
+		CreatedAt: time.Now().UTC(),
+		Records: []Entry{{
+			ID: "2", Type: entity.TypeFile, Name: "Key", Metadata: "id_rsa",
+			Path: "records/0", Size: 11, SHA256: hex.EncodeToString(checksum[:]),
+		}},
+	}
+
+	t.Log("Archive of version 1 is read")
+	records, err := readArchive(archiveV1(t, manifest, [][]byte{[]byte("private key")}), "passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, []entity.Record{
+		{ID: "2", Type: entity.TypeFile, Name: "Key", Metadata: "id_rsa", Data: []byte("private key")},
+	}, records)
+
+	t.Log("Data, which doesn't match checksum")
+	_, err = readArchive(archiveV1(t, manifest, [][]byte{[]byte("public key!")}), "passphrase")
+	assert.ErrorIs(t, err, ErrCorrupted)
+
+	t.Log("Record of manifest without data")
+	_, err = readArchive(archiveV1(t, manifest, nil), "passphrase")
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
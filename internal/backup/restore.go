@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/exchange"
+)
+
+// Client gets and creates records of user, it's client handlers.
+type Client interface {
+	exchange.RecordReader
+	exchange.RecordCreator
+}
+
+// Report is result of restore. IDs maps IDs of archive to IDs of records on server: new records
+// and records, which already exist. Records, which aren't created, aren't in IDs.
+type Report struct {
+	Restored   int
+	Duplicates int
+	Failed     []exchange.Skipped
+	IDs        map[string]string
+}
+
+// labels identify record for deduplication.
+type labels struct {
+	recordType entity.RecordType
+	name       string
+	metadata   string
+	folder     string
+}
+
+// content identifies record of archive for deduplication.
+type content struct {
+	labels
+	checksum string
+}
+
+// Restore creates records of archive, which is read record by record, files are uploaded, while they are read.
+// Records, which already exist on server (records with the same type, name, metadata, folder and data)
+// or twice in archive, aren't created, so restore can be repeated. Records, which can't be created,
+// are reported and restore goes on, but it stops, if session is expired, server is unavailable, key is wrong
+// or archive is corrupted. If dryRun is set, nothing is created, but data of records are checked.
+func Restore(client Client, archive *Reader, dryRun bool) (Report, error) {
+	report := Report{IDs: make(map[string]string)}
+
+	existing, err := client.GetRecordsInfo()
+	if err != nil {
+		return report, err
+	}
+
+	candidates := make(map[labels][]entity.Record)
+	for _, record := range existing {
+		candidates[labelsOf(record)] = append(candidates[labelsOf(record)], record)
+	}
+
+	var (
+		checksums = make(map[string]string)  // Checksums of existing records, which are got.
+		restored  = make(map[content]string) // IDs of archive of restored records.
+		copies    = make(map[string]string)  // IDs of archive of duplicates in archive and their originals.
+	)
+
+	for {
+		entry, data, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+
+		record, checksum := entry.record(), entry.SHA256
+		key := labelsOf(record)
+
+		id, err := duplicate(client, candidates[key], checksums, checksum)
+		if exchange.Fatal(err) {
+			return report, err
+		}
+		if err != nil {
+			report.Failed = append(report.Failed, exchange.Skipped{Name: exchange.RecordName(record), Reason: err.Error()})
+			continue
+		}
+
+		if id != "" {
+			report.Duplicates++
+			report.IDs[record.ID] = id
+			continue
+		}
+
+		if original, ok := restored[content{key, checksum}]; ok {
+			report.Duplicates++
+			copies[record.ID] = original
+			continue
+		}
+		restored[content{key, checksum}] = record.ID
+
+		if dryRun {
+			if _, err = io.Copy(io.Discard, data); err != nil {
+				return report, err
+			}
+
+			report.Restored++
+			continue
+		}
+
+		oldID := record.ID
+		record.ID = ""
+
+		if record.Type == entity.TypeFile {
+			record.Body = data
+		} else if record.Data, err = io.ReadAll(data); err != nil {
+			return report, err
+		}
+
+		newID, err := client.CreateRecord(record)
+		if exchange.Fatal(err) {
+			return report, err
+		}
+		// File, which isn't read till its end, is checked here: upload of corrupted file fails.
+		if _, errData := io.Copy(io.Discard, data); errData != nil {
+			return report, errData
+		}
+		if err != nil {
+			report.Failed = append(report.Failed, exchange.Skipped{Name: exchange.RecordName(record), Reason: err.Error()})
+			continue
+		}
+
+		report.Restored++
+		report.IDs[oldID] = newID
+	}
+
+	for id, original := range copies {
+		if newID, ok := report.IDs[original]; ok {
+			report.IDs[id] = newID
+		}
+	}
+
+	return report, nil
+}
+
+// duplicate finds record with checksum of data among existing records with the same labels.
+// Returns ID of found record or empty string.
+func duplicate(client Client, candidates []entity.Record, checksums map[string]string, checksum string) (string, error) {
+	for _, candidate := range candidates {
+		sum, ok := checksums[candidate.ID]
+		if !ok {
+			hash := sha256.New()
+
+			var err error
+			if candidate.Type == entity.TypeFile {
+				_, err = client.GetFile(candidate.ID, hash)
+			} else {
+				var record entity.Record
+				if record, err = client.GetRecord(candidate.ID); err == nil {
+					_, err = hash.Write(record.Data)
+				}
+			}
+			if err != nil {
+				return "", err
+			}
+
+			sum = hex.EncodeToString(hash.Sum(nil))
+			checksums[candidate.ID] = sum
+		}
+
+		if sum == checksum {
+			return candidate.ID, nil
+		}
+	}
+
+	return "", nil
+}
+
+// labelsOf gets labels of record.
+func labelsOf(record entity.Record) labels {
+	return labels{
+		recordType: record.Type,
+		name:       record.Name,
+		metadata:   record.Metadata,
+		folder:     entity.NormalizeFolder(record.Folder),
+	}
+}
@@ -0,0 +1,117 @@
+package backup
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/exchange"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+)
+
+func TestRestore(t *testing.T) {
+	// The last record is copy of the first one.
+	records := append(append([]entity.Record(nil), testRecords...), entity.Record{
+		ID: "4", Type: entity.TypeText, Name: "Note", Tags: []string{"personal"}, Data: []byte(`{"text":"hello"}`),
+	})
+
+	data := testBackup(t, records)
+
+	created := func(name string) interface{} {
+		return mock.MatchedBy(func(record entity.Record) bool {
+			return record.Name == name && record.ID == ""
+		})
+	}
+
+	tc := []struct {
+		name   string
+		dryRun bool
+		mock   func(client *mocks.ClientHandlers)
+		valid  func(report Report, err error)
+	}{
+		{
+			name: "Records are created with new IDs",
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("GetRecordsInfo").Return([]entity.Record{{ID: "a", Type: entity.TypeText, Name: "Other"}}, nil).Once()
+				client.On("CreateRecord", created("Note")).Return("c", nil).Once()
+				client.On("CreateRecord", created("Key")).Return("d", nil).Once()
+				client.On("CreateRecord", created("Mail")).Return("b", nil).Once()
+			},
+			valid: func(report Report, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, Report{
+					Restored:   3,
+					Duplicates: 1,
+					IDs:        map[string]string{"1": "c", "2": "d", "3": "b", "4": "c"},
+				}, report)
+			},
+		},
+		{
+			name: "Existing records aren't created again",
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("GetRecordsInfo").Return([]entity.Record{
+					{ID: "c", Type: entity.TypeText, Name: "Note"},
+					{ID: "d", Type: entity.TypeFile, Name: "Key", Metadata: "id_rsa", Folder: "Work"},
+					{ID: "e", Type: entity.TypeLoginAndPassword, Name: "Mail", Folder: "Work/Mail"},
+				}, nil).Once()
+				client.On("GetRecord", "c").Return(entity.Record{ID: "c", Data: []byte(`{"text":"hello"}`)}, nil).Once()
+				client.On("GetFile", "d", mock.Anything).Run(func(args mock.Arguments) {
+					_, _ = args.Get(1).(io.Writer).Write([]byte("private key"))
+				}).Return(entity.Record{ID: "d"}, nil).Once()
+				client.On("GetRecord", "e").Return(entity.Record{ID: "e", Data: []byte(`{"login":"other"}`)}, nil).Once()
+				client.On("CreateRecord", created("Mail")).Return("", storage.ErrUnknown).Once()
+			},
+			valid: func(report Report, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, Report{
+					Duplicates: 3,
+					Failed:     []exchange.Skipped{{Name: "Mail", Reason: storage.ErrUnknown.Error()}},
+					IDs:        map[string]string{"1": "c", "2": "d", "4": "c"},
+				}, report)
+			},
+		},
+		{
+			name:   "Dry run creates nothing",
+			dryRun: true,
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("GetRecordsInfo").Return([]entity.Record{}, nil).Once()
+			},
+			valid: func(report Report, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, Report{Restored: 3, Duplicates: 1, IDs: map[string]string{}}, report)
+			},
+		},
+		{
+			name: "Wrong key stops restore",
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("GetRecordsInfo").Return([]entity.Record{}, nil).Once()
+				client.On("CreateRecord", created("Note")).Return("c", nil).Once()
+				client.On("CreateRecord", created("Key")).Return("", controller.ErrWrongMasterKey).Once()
+			},
+			valid: func(report Report, err error) {
+				assert.ErrorIs(t, err, controller.ErrWrongMasterKey)
+				assert.Equal(t, 1, report.Restored)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		client := mocks.NewClientHandlers(t)
+		test.mock(client)
+
+		archive, err := NewReader(bytes.NewReader(data), "passphrase")
+		require.NoError(t, err)
+
+		test.valid(Restore(client, archive, test.dryRun))
+		archive.Close()
+	}
+}
@@ -1,13 +1,355 @@
 package config
 
-// ClientConfig struct for client config.
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v8"
+	"github.com/pelletier/go-toml/v2"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of client CLI.
+const (
+	OutputJSON = "json"
+	OutputText = "text"
+)
+
+// DefaultProfile is name of profile, which is used if other isn't chosen.
+const DefaultProfile = "default"
+
+// Errors of client config.
+var (
+	ErrUnknownProfile    = errors.New("profile isn't found in config file")
+	ErrUnknownOutput     = errors.New("unknown output format")
+	ErrUnknownConfigType = errors.New("config file must be YAML or TOML")
+)
+
+// configFileNames are names of config file, which is searched in config directory.
+var configFileNames = []string{"config.yaml", "config.yml", "config.toml"}
+
+// ClientConfig struct for client config. Settings are taken from selected profile of config file,
+// then from environment, then from flags.
 type ClientConfig struct {
-	ServerAddress string
+	Profile        string        `env:"CLIENT_PROFILE"`
+	ConfigFile     string        `env:"CLIENT_CONFIG_FILE"`
+	ServerAddress  string        `env:"SERVER_ADDRESS"`
+	CacheDirectory string        `env:"CACHE_DIRECTORY"`
+	Timeout        time.Duration `env:"REQUEST_TIMEOUT"`
+	Output         string        `env:"OUTPUT_FORMAT"`
+	// EncryptMetadata makes client encrypt metadata and names of records, so server can't read them.
+	EncryptMetadata bool `env:"ENCRYPT_METADATA"`
+	TLS             ClientTLSConfig
+	Agent           AgentConfig
+}
+
+// AgentConfig settings of agent, which keeps vault unlocked. Agent locks vault after idle timeout,
+// zero timeout means that it's never locked automatically.
+type AgentConfig struct {
+	Socket      string        `env:"AGENT_SOCKET"`
+	IdleTimeout time.Duration `env:"AGENT_IDLE_TIMEOUT"`
+	Disabled    bool          `env:"AGENT_DISABLED"`
+}
+
+// ClientTLSConfig TLS settings. Server certificate is verified by CA bundle or by system roots,
+// client certificate is needed only if server requires mutual TLS. Environment names have own prefix,
+// so client and server on one host don't share certificates.
+type ClientTLSConfig struct {
+	CAFile     string `env:"CLIENT_TLS_CA_FILE" yaml:"ca_file" toml:"ca_file"`
+	CertFile   string `env:"CLIENT_TLS_CERT_FILE" yaml:"cert_file" toml:"cert_file"`
+	KeyFile    string `env:"CLIENT_TLS_KEY_FILE" yaml:"key_file" toml:"key_file"`
+	ServerName string `env:"CLIENT_TLS_SERVER_NAME" yaml:"server_name" toml:"server_name"`
+	Insecure   bool   `env:"CLIENT_TLS_INSECURE" yaml:"insecure" toml:"insecure"`
+}
+
+// clientConfigFile is content of client config file. Output is default output format of all profiles.
+type clientConfigFile struct {
+	Profile  string                   `yaml:"profile" toml:"profile"`
+	Output   string                   `yaml:"output" toml:"output"`
+	Profiles map[string]clientProfile `yaml:"profiles" toml:"profiles"`
 }
 
-// NewClientConfig gets client config.
-func NewClientConfig() ClientConfig {
-	return ClientConfig{
+// clientProfile is settings of one server in config file.
+type clientProfile struct {
+	ServerAddress   string          `yaml:"server_address" toml:"server_address"`
+	CacheDirectory  string          `yaml:"cache_directory" toml:"cache_directory"`
+	Timeout         string          `yaml:"timeout" toml:"timeout"`
+	Output          string          `yaml:"output" toml:"output"`
+	EncryptMetadata bool            `yaml:"encrypt_metadata" toml:"encrypt_metadata"`
+	TLS             ClientTLSConfig `yaml:"tls" toml:"tls"`
+	Agent           agentProfile    `yaml:"agent" toml:"agent"`
+}
+
+// agentProfile is agent settings of profile in config file.
+type agentProfile struct {
+	Socket      string `yaml:"socket" toml:"socket"`
+	IdleTimeout string `yaml:"idle_timeout" toml:"idle_timeout"`
+	Disabled    bool   `yaml:"disabled" toml:"disabled"`
+}
+
+// NewClientConfig gets client config from config file, environment and flags in args.
+// Returns arguments left after flags (command of CLI).
+func NewClientConfig(args []string) (ClientConfig, []string, error) {
+	cfg := ClientConfig{
 		ServerAddress: ":3200",
+		Timeout:       10 * time.Second,
+		Output:        OutputJSON,
+		Agent:         AgentConfig{IdleTimeout: 15 * time.Minute},
+	}
+
+	flags, apply := clientFlags()
+	if err := flags.Parse(args); err != nil {
+		return cfg, nil, err
+	}
+
+	// Profile and config file are needed before other settings, so they are taken first.
+	if err := env.Parse(&cfg); err != nil {
+		return cfg, nil, err
+	}
+	apply(&cfg, "profile", "config")
+
+	file, err := readClientConfigFile(cfg.ConfigFile)
+	if err != nil {
+		return cfg, nil, err
+	}
+
+	if err = file.applyProfile(&cfg); err != nil {
+		return cfg, nil, err
+	}
+
+	if err = env.Parse(&cfg); err != nil {
+		return cfg, nil, err
+	}
+	apply(&cfg)
+
+	if cfg.CacheDirectory == "" {
+		cfg.CacheDirectory = defaultCacheDirectory(cfg.Profile)
+	}
+	if cfg.Agent.Socket == "" {
+		cfg.Agent.Socket = defaultAgentSocket(cfg.Profile)
+	}
+
+	if cfg.Output != OutputJSON && cfg.Output != OutputText {
+		return cfg, nil, fmt.Errorf("%w: %s", ErrUnknownOutput, cfg.Output)
+	}
+
+	return cfg, flags.Args(), nil
+}
+
+// clientFlags returns flag set of client and function, which applies flags set by user to config.
+// If names are passed, only these flags are applied.
+func clientFlags() (*flag.FlagSet, func(cfg *ClientConfig, names ...string)) {
+	flags := flag.NewFlagSet("client", flag.ContinueOnError)
+	values := ClientConfig{}
+
+	flags.StringVar(&values.Profile, "profile", "", "name of server profile from config file")
+	flags.StringVar(&values.ConfigFile, "config", "", "path to YAML or TOML config file")
+	flags.StringVar(&values.ServerAddress, "server", "", "address of server")
+	flags.StringVar(&values.CacheDirectory, "cache-dir", "", "directory of local encrypted cache")
+	flags.DurationVar(&values.Timeout, "timeout", 0, "timeout of request to server")
+	flags.StringVar(&values.Output, "output", "", "output format of CLI: json or text")
+	flags.BoolVar(&values.EncryptMetadata, "encrypt-metadata", false, "encrypt metadata and names of records")
+	flags.StringVar(&values.TLS.CAFile, "tls-ca", "", "CA bundle, which verifies server certificate")
+	flags.StringVar(&values.TLS.CertFile, "tls-cert", "", "client certificate for mutual TLS")
+	flags.StringVar(&values.TLS.KeyFile, "tls-key", "", "client key for mutual TLS")
+	flags.StringVar(&values.TLS.ServerName, "tls-server-name", "", "server name in certificate")
+	flags.BoolVar(&values.TLS.Insecure, "tls-insecure", false, "connect without TLS")
+	flags.StringVar(&values.Agent.Socket, "agent-socket", "", "Unix socket of agent")
+	flags.DurationVar(&values.Agent.IdleTimeout, "agent-idle-timeout", 0, "idle time, after which agent locks vault")
+	flags.BoolVar(&values.Agent.Disabled, "no-agent", false, "don't use agent")
+
+	setters := map[string]func(cfg *ClientConfig){
+		"profile":          func(cfg *ClientConfig) { cfg.Profile = values.Profile },
+		"config":           func(cfg *ClientConfig) { cfg.ConfigFile = values.ConfigFile },
+		"server":           func(cfg *ClientConfig) { cfg.ServerAddress = values.ServerAddress },
+		"cache-dir":        func(cfg *ClientConfig) { cfg.CacheDirectory = values.CacheDirectory },
+		"timeout":          func(cfg *ClientConfig) { cfg.Timeout = values.Timeout },
+		"output":           func(cfg *ClientConfig) { cfg.Output = values.Output },
+		"encrypt-metadata": func(cfg *ClientConfig) { cfg.EncryptMetadata = values.EncryptMetadata },
+		"tls-ca":           func(cfg *ClientConfig) { cfg.TLS.CAFile = values.TLS.CAFile },
+		"tls-cert":         func(cfg *ClientConfig) { cfg.TLS.CertFile = values.TLS.CertFile },
+		"tls-key":          func(cfg *ClientConfig) { cfg.TLS.KeyFile = values.TLS.KeyFile },
+		"tls-server-name":  func(cfg *ClientConfig) { cfg.TLS.ServerName = values.TLS.ServerName },
+		"tls-insecure":     func(cfg *ClientConfig) { cfg.TLS.Insecure = values.TLS.Insecure },
+
+		"agent-socket":       func(cfg *ClientConfig) { cfg.Agent.Socket = values.Agent.Socket },
+		"agent-idle-timeout": func(cfg *ClientConfig) { cfg.Agent.IdleTimeout = values.Agent.IdleTimeout },
+		"no-agent":           func(cfg *ClientConfig) { cfg.Agent.Disabled = values.Agent.Disabled },
+	}
+
+	apply := func(cfg *ClientConfig, names ...string) {
+		flags.Visit(func(f *flag.Flag) {
+			if len(names) == 0 {
+				setters[f.Name](cfg)
+				return
+			}
+
+			for _, name := range names {
+				if f.Name == name {
+					setters[f.Name](cfg)
+				}
+			}
+		})
+	}
+
+	return flags, apply
+}
+
+// readClientConfigFile reads config file. If file isn't set, it's searched in config directory,
+// where it may be absent.
+func readClientConfigFile(name string) (clientConfigFile, error) {
+	var file clientConfigFile
+
+	if name == "" {
+		name = findClientConfigFile()
+		if name == "" {
+			return file, nil
+		}
+	}
+
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return file, err
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	case ".toml":
+		err = toml.Unmarshal(data, &file)
+	default:
+		return file, fmt.Errorf("%w: %s", ErrUnknownConfigType, name)
+	}
+
+	if err != nil {
+		return file, fmt.Errorf("parse config file %s: %w", name, err)
+	}
+	log.Infoln("Config file loaded:", name)
+
+	return file, nil
+}
+
+// findClientConfigFile searches config file in config directory. Returns empty name, if it isn't found.
+func findClientConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Infoln(err)
+
+		return ""
+	}
+
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, "gophkeeper", name)
+		if _, err = os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// applyProfile applies settings of chosen profile to config. Default profile may be absent.
+func (f clientConfigFile) applyProfile(cfg *ClientConfig) error {
+	if cfg.Profile == "" {
+		cfg.Profile = f.Profile
+	}
+	if cfg.Profile == "" {
+		cfg.Profile = DefaultProfile
+	}
+
+	if f.Output != "" {
+		cfg.Output = f.Output
+	}
+
+	profile, ok := f.Profiles[cfg.Profile]
+	if !ok {
+		if cfg.Profile == DefaultProfile {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %s", ErrUnknownProfile, cfg.Profile)
+	}
+
+	if profile.ServerAddress != "" {
+		cfg.ServerAddress = profile.ServerAddress
 	}
+	if profile.CacheDirectory != "" {
+		cfg.CacheDirectory = profile.CacheDirectory
+	}
+	if profile.Output != "" {
+		cfg.Output = profile.Output
+	}
+	if profile.Timeout != "" {
+		timeout, err := time.ParseDuration(profile.Timeout)
+		if err != nil {
+			return fmt.Errorf("timeout of profile %s: %w", cfg.Profile, err)
+		}
+		cfg.Timeout = timeout
+	}
+	if profile.EncryptMetadata {
+		cfg.EncryptMetadata = true
+	}
+
+	cfg.TLS = profile.TLS
+
+	if profile.Agent.Socket != "" {
+		cfg.Agent.Socket = profile.Agent.Socket
+	}
+	if profile.Agent.IdleTimeout != "" {
+		timeout, err := time.ParseDuration(profile.Agent.IdleTimeout)
+		if err != nil {
+			return fmt.Errorf("agent idle timeout of profile %s: %w", cfg.Profile, err)
+		}
+		cfg.Agent.IdleTimeout = timeout
+	}
+	cfg.Agent.Disabled = profile.Agent.Disabled
+
+	return nil
+}
+
+// defaultCacheDirectory returns directory for local encrypted store of profile in user config directory.
+// Profiles have separate stores, because they are different servers.
+func defaultCacheDirectory(profile string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Infoln(err)
+
+		dir = ".gophkeeper"
+	} else {
+		dir = filepath.Join(dir, "gophkeeper")
+	}
+
+	if profile == DefaultProfile {
+		return filepath.Join(dir, "cache")
+	}
+
+	return filepath.Join(dir, "cache", profile)
+}
+
+// defaultAgentSocket returns socket of profile agent in user runtime directory. Without runtime directory
+// socket is in user config directory, not in shared temporary directory, where other users can create
+// directory with predictable name before agent.
+func defaultAgentSocket(profile string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir != "" {
+		return filepath.Join(dir, "gophkeeper", "agent-"+profile+".sock")
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		log.Infoln(err)
+
+		dir = ".gophkeeper"
+	} else {
+		dir = filepath.Join(dir, "gophkeeper")
+	}
+	dir = filepath.Join(dir, "run")
+
+	return filepath.Join(dir, "agent-"+profile+".sock")
 }
@@ -0,0 +1,176 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testYAML = `
+profile: work
+output: text
+profiles:
+  default:
+    server_address: localhost:3200
+  work:
+    server_address: keeper.example.com:443
+    timeout: 30s
+    encrypt_metadata: true
+    tls:
+      ca_file: /etc/keeper/ca.crt
+      server_name: keeper.example.com
+  home:
+    server_address: 192.168.1.10:3200
+    output: json
+    tls:
+      insecure: true
+    agent:
+      disabled: true
+`
+
+const testTOML = `
+[profiles.default]
+server_address = "toml.example.com:3200"
+timeout = "5s"
+
+[profiles.default.tls]
+cert_file = "client.crt"
+key_file = "client.key"
+`
+
+func TestNewClientConfig(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(dir, "run"))
+
+	yamlFile := filepath.Join(dir, "gophkeeper", "config.yaml")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(yamlFile), 0o700))
+	assert.NoError(t, os.WriteFile(yamlFile, []byte(testYAML), 0o600))
+
+	tomlFile := filepath.Join(dir, "other.toml")
+	assert.NoError(t, os.WriteFile(tomlFile, []byte(testTOML), 0o600))
+
+	tests := []struct {
+		name  string
+		args  []string
+		env   map[string]string
+		valid func(cfg ClientConfig, args []string, err error)
+	}{
+		{
+			name: "Profile from config file",
+			args: []string{"ls"},
+			valid: func(cfg ClientConfig, args []string, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"ls"}, args)
+				assert.Equal(t, "work", cfg.Profile)
+				assert.Equal(t, "keeper.example.com:443", cfg.ServerAddress)
+				assert.Equal(t, 30*time.Second, cfg.Timeout)
+				assert.Equal(t, OutputText, cfg.Output)
+				assert.Equal(t, "/etc/keeper/ca.crt", cfg.TLS.CAFile)
+				assert.Equal(t, filepath.Join(dir, "gophkeeper", "cache", "work"), cfg.CacheDirectory)
+				assert.Equal(t, filepath.Join(dir, "run", "gophkeeper", "agent-work.sock"), cfg.Agent.Socket)
+				assert.Equal(t, 15*time.Minute, cfg.Agent.IdleTimeout)
+				assert.True(t, cfg.EncryptMetadata)
+			},
+		},
+		{
+			name: "Profile from flag",
+			args: []string{"--profile", "home", "-agent-idle-timeout", "1h", "get", "recordID"},
+			valid: func(cfg ClientConfig, args []string, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"get", "recordID"}, args)
+				assert.Equal(t, "192.168.1.10:3200", cfg.ServerAddress)
+				assert.Equal(t, OutputJSON, cfg.Output)
+				assert.True(t, cfg.TLS.Insecure)
+				assert.Equal(t, 10*time.Second, cfg.Timeout)
+				assert.Equal(t, time.Hour, cfg.Agent.IdleTimeout)
+				assert.True(t, cfg.Agent.Disabled)
+				assert.False(t, cfg.EncryptMetadata)
+			},
+		},
+		{
+			name: "Environment overrides profile, flags override environment",
+			args: []string{"-server", "flag:1", "-tls-insecure", "-encrypt-metadata"},
+			env: map[string]string{
+				"CLIENT_PROFILE": "default", "SERVER_ADDRESS": "env:1", "REQUEST_TIMEOUT": "1m",
+				"CLIENT_TLS_CA_FILE": "/env/ca.crt", "TLS_CERT_FILE": "server.crt",
+			},
+			valid: func(cfg ClientConfig, args []string, err error) {
+				assert.NoError(t, err)
+				assert.Empty(t, args)
+				assert.Equal(t, DefaultProfile, cfg.Profile)
+				assert.Equal(t, "flag:1", cfg.ServerAddress)
+				assert.Equal(t, time.Minute, cfg.Timeout)
+				assert.True(t, cfg.TLS.Insecure)
+				assert.Equal(t, "/env/ca.crt", cfg.TLS.CAFile)
+				assert.Empty(t, cfg.TLS.CertFile, "certificate of server isn't used by client")
+				assert.True(t, cfg.EncryptMetadata)
+				assert.Equal(t, filepath.Join(dir, "gophkeeper", "cache"), cfg.CacheDirectory)
+			},
+		},
+		{
+			name: "Agent socket without runtime directory is in config directory",
+			env:  map[string]string{"XDG_RUNTIME_DIR": ""},
+			valid: func(cfg ClientConfig, args []string, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, filepath.Join(dir, "gophkeeper", "run", "agent-work.sock"), cfg.Agent.Socket)
+			},
+		},
+		{
+			name: "TOML config file from flag",
+			args: []string{"-config", tomlFile},
+			valid: func(cfg ClientConfig, args []string, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, "toml.example.com:3200", cfg.ServerAddress)
+				assert.Equal(t, 5*time.Second, cfg.Timeout)
+				assert.Equal(t, "client.crt", cfg.TLS.CertFile)
+				assert.Equal(t, OutputJSON, cfg.Output)
+			},
+		},
+		{
+			name: "Unknown profile",
+			args: []string{"-profile", "missing"},
+			valid: func(cfg ClientConfig, args []string, err error) {
+				assert.ErrorIs(t, err, ErrUnknownProfile)
+			},
+		},
+		{
+			name: "Unknown output format",
+			args: []string{"-output", "xml"},
+			valid: func(cfg ClientConfig, args []string, err error) {
+				assert.ErrorIs(t, err, ErrUnknownOutput)
+			},
+		},
+		{
+			name: "Missing config file from flag",
+			args: []string{"-config", filepath.Join(dir, "missing.yaml")},
+			valid: func(cfg ClientConfig, args []string, err error) {
+				assert.ErrorIs(t, err, os.ErrNotExist)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			cfg, args, err := NewClientConfig(tt.args)
+			tt.valid(cfg, args, err)
+		})
+	}
+}
+
+func TestNewClientConfig_WithoutFile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg, _, err := NewClientConfig(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultProfile, cfg.Profile)
+	assert.Equal(t, ":3200", cfg.ServerAddress)
+	assert.Equal(t, OutputJSON, cfg.Output)
+}
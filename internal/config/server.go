@@ -12,47 +12,63 @@ type ServerConfig struct {
 	RunAddress      string `env:"SERVER_PORT" envDefault:":3200"`
 	DBConnectionURL string `env:"DATABASE_DSN"`
 	FilesDirectory  string `env:"FILE_STORAGE_PATH" envDefault:"files"`
+	FilesURL        string `env:"FILE_STORAGE_URL"`
+	PasswordHasher  string `env:"PASSWORD_HASHER" envDefault:"argon2id"`
 	Auth            AuthConfig
+	TLS             ServerTLSConfig
 }
 
-// AuthConfig auth settings.
+// ServerTLSConfig TLS settings. Without certificate server doesn't start, unless dev mode is on
+// or server is explicitly allowed to run without TLS.
+type ServerTLSConfig struct {
+	CertFile     string `env:"TLS_CERT_FILE"`
+	KeyFile      string `env:"TLS_KEY_FILE"`
+	ClientCAFile string `env:"TLS_CLIENT_CA_FILE"`
+	DevMode      bool   `env:"TLS_DEV_MODE"`
+	DevDirectory string `env:"TLS_DEV_DIRECTORY" envDefault:"certs"`
+	Insecure     bool   `env:"TLS_INSECURE"`
+}
+
+// AuthConfig auth settings. Access token lifetime is counted from its creation,
+// refresh token lifetime is counted from login or last refresh.
 type AuthConfig struct {
-	SecretJWT      string `env:"SECRET_JWT" envDefault:"HERE_MUST_BE_SECRET_KEY"`
-	ExpirationTime int64
+	SecretJWT       string        `env:"SECRET_JWT" envDefault:"HERE_MUST_BE_SECRET_KEY"`
+	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"720h"`
 }
 
 // NewServerConfig gets server config.
 func NewServerConfig() ServerConfig {
-	var (
-		cfg  ServerConfig
-		auth AuthConfig
-	)
+	var cfg ServerConfig
 
 	if err := env.Parse(&cfg); err != nil {
 		log.Printf("%+v\n", err)
 	}
-	if err := env.Parse(&auth); err != nil {
-		log.Printf("%+v\n", err)
-	}
-
-	cfg.Auth = AuthConfig{
-		SecretJWT:      auth.SecretJWT,
-		ExpirationTime: time.Now().Add(1 * time.Hour).Unix(),
-	}
 	log.Infoln("Config loaded")
 
 	return cfg
 }
 
+// FileStorage is URL of file storage: FilesURL, if it's set, otherwise FilesDirectory.
+func (cfg ServerConfig) FileStorage() string {
+	if cfg.FilesURL != "" {
+		return cfg.FilesURL
+	}
+
+	return cfg.FilesDirectory
+}
+
 // NewConfigForTests config fot tests.
 func NewConfigForTests() ServerConfig {
 	return ServerConfig{
 		RunAddress:      ":3200",
 		DBConnectionURL: "",
 		FilesDirectory:  "files",
+		PasswordHasher:  "argon2id",
 		Auth: AuthConfig{
-			"HERE_MUST_BE_SECRET_KEY",
-			time.Now().Add(1 * time.Hour).Unix(),
+			SecretJWT:       "HERE_MUST_BE_SECRET_KEY",
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 720 * time.Hour,
 		},
 	}
 }
@@ -6,4 +6,20 @@ import "errors"
 var (
 	ErrFieldIsEmpty   = errors.New("field is empty")
 	ErrWrongMasterKey = errors.New("wrong master key")
+	ErrDataCorrupted  = errors.New("record data is corrupted")
+	ErrBadPublicKey   = errors.New("bad public key")
+	ErrBadRole        = errors.New("member role must be read or read-write")
+	ErrBadFingerprint = errors.New("public key of user doesn't match fingerprint")
+	ErrKeyNotVerified = errors.New("public key of member isn't verified by fingerprint, invite member again")
+	ErrBadFileName    = errors.New("file name of record is invalid")
+	ErrPlainLabels    = errors.New("labels of record aren't encrypted, run migrate to encrypt them")
+	ErrLegacyKey      = errors.New("records are encrypted by legacy key, login again to upgrade it")
+
+	ErrServerUnavailable = errors.New("server is unavailable")
+	ErrOffline           = errors.New("operation isn't available offline")
+	ErrLocked            = errors.New("vault is locked, login is needed")
+	ErrAgentRunning      = errors.New("agent is already running")
+	ErrAgentSocket       = errors.New("agent socket must be in directory, which only user owns and can access")
+	ErrAgentPeer         = errors.New("agent socket is used by process of another user")
+	ErrTLSNotConfigured  = errors.New("TLS isn't configured: set certificate, dev mode or insecure mode")
 )
@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// agentServiceName is name of agent service in RPC.
+const agentServiceName = "Agent"
+
+// AgentRecordArgs are arguments of agent calls about one record.
+type AgentRecordArgs struct {
+	RecordID string
+	Revision int64
+	Version  int32
+}
+
+// AgentVaultArgs are arguments of agent calls about shared vault, its member or record.
+type AgentVaultArgs struct {
+	VaultID, RecordID, Login, Fingerprint string
+	Role                                  entity.VaultRole
+}
+
+// AgentServer keeps client handlers unlocked in memory and serves them on Unix socket,
+// so CLI and TUI reuse one session. Handlers are locked (logged out) after idle timeout.
+type AgentServer struct {
+	handlers    ClientHandlers
+	idleTimeout time.Duration
+	listener    net.Listener
+	lastUsed    time.Time
+	streams     map[uint64]*agentStream
+	nextStream  uint64
+	done        chan struct{}
+	*sync.Mutex
+}
+
+// NewAgentServer returns agent, which serves client handlers. Zero idle timeout means no auto-lock.
+func NewAgentServer(h ClientHandlers, idleTimeout time.Duration) *AgentServer {
+	return &AgentServer{
+		handlers:    h,
+		idleTimeout: idleTimeout,
+		streams:     make(map[uint64]*agentStream),
+		done:        make(chan struct{}),
+		Mutex:       &sync.Mutex{},
+	}
+}
+
+// ListenAgentSocket listens Unix socket, which only owner can use. Directory of socket must be owned
+// and accessible only by user, directory, which is created by someone else, isn't used.
+// Stale socket is removed, but socket of running agent and files, which aren't sockets, aren't.
+func ListenAgentSocket(socket string) (net.Listener, error) {
+	dir := filepath.Dir(socket)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	if err := checkSocketDirectory(dir); err != nil {
+		return nil, err
+	}
+
+	if conn, err := net.Dial("unix", socket); err == nil {
+		conn.Close()
+
+		return nil, controller.ErrAgentRunning
+	}
+
+	info, err := os.Lstat(socket)
+	if err == nil && info.Mode().Type() != os.ModeSocket {
+		return nil, controller.ErrAgentSocket
+	}
+	if err == nil {
+		err = os.Remove(socket)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return listenSocket(socket)
+}
+
+// Serve serves agent calls on listener until agent is closed.
+func (a *AgentServer) Serve(listener net.Listener) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(agentServiceName, &agentService{agent: a}); err != nil {
+		return err
+	}
+
+	a.Lock()
+	a.listener, a.lastUsed = listener, time.Now()
+	a.Unlock()
+
+	if a.idleTimeout > 0 {
+		go a.lockWhenIdle()
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-a.done:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		if err = checkPeer(conn); err != nil {
+			log.Warnf("%s :: %v", "agent peer fault", err)
+			conn.Close()
+
+			continue
+		}
+
+		go server.ServeConn(conn)
+	}
+}
+
+// Close stops agent, aborts streams of files and locks handlers.
+func (a *AgentServer) Close() error {
+	a.Lock()
+	select {
+	case <-a.done:
+		a.Unlock()
+
+		return nil
+	default:
+		close(a.done)
+	}
+	listener := a.listener
+	a.Unlock()
+
+	a.abortStreams()
+	a.lock()
+
+	if listener == nil {
+		return nil
+	}
+
+	return listener.Close()
+}
+
+// touch marks agent as used now.
+func (a *AgentServer) touch() {
+	a.Lock()
+	defer a.Unlock()
+
+	a.lastUsed = time.Now()
+}
+
+// lockWhenIdle locks handlers, if agent isn't used during idle timeout.
+func (a *AgentServer) lockWhenIdle() {
+	ticker := time.NewTicker(a.idleTimeout / 10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case <-ticker.C:
+			if a.idle() {
+				a.lock()
+			}
+		}
+	}
+}
+
+// idle reports if agent isn't used during idle timeout.
+func (a *AgentServer) idle() bool {
+	a.Lock()
+	defer a.Unlock()
+
+	return time.Since(a.lastUsed) >= a.idleTimeout
+}
+
+// lock forgets session and encryption key, if handlers are unlocked. It's called without mutex of agent,
+// because logout calls server and other calls shouldn't wait for it, handlers have their own mutex.
+func (a *AgentServer) lock() {
+	if !a.handlers.Unlocked() {
+		return
+	}
+
+	if err := a.handlers.Logout(); err != nil {
+		log.Infoln(err)
+	}
+	log.Infoln("Agent is locked.")
+}
+
+// unlocked marks agent as used and checks that handlers are unlocked.
+func (a *AgentServer) unlocked() error {
+	a.touch()
+
+	if !a.handlers.Unlocked() {
+		return controller.ErrLocked
+	}
+
+	return nil
+}
+
+// agentService is RPC service of agent. Its methods call client handlers.
+type agentService struct {
+	agent *AgentServer
+}
+
+// Login logins user and unlocks agent.
+func (s *agentService) Login(credentials entity.UserCredentials, _ *struct{}) error {
+	s.agent.touch()
+
+	return s.agent.handlers.Login(credentials)
+}
+
+// Register creates new user and unlocks agent.
+func (s *agentService) Register(credentials entity.UserCredentials, _ *struct{}) error {
+	s.agent.touch()
+
+	return s.agent.handlers.Register(credentials)
+}
+
+// Lock locks agent.
+func (s *agentService) Lock(_ struct{}, _ *struct{}) error {
+	s.agent.lock()
+
+	return nil
+}
+
+// Logout ends session and locks agent.
+func (s *agentService) Logout(_ struct{}, _ *struct{}) error {
+	s.agent.touch()
+
+	return s.agent.handlers.Logout()
+}
+
+// RevokeSessions ends all sessions of user and locks agent.
+func (s *agentService) RevokeSessions(_ struct{}, _ *struct{}) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	return s.agent.handlers.RevokeSessions()
+}
+
+// Unlocked reports if agent is unlocked.
+func (s *agentService) Unlocked(_ struct{}, unlocked *bool) error {
+	*unlocked = s.agent.handlers.Unlocked()
+
+	return nil
+}
+
+// GetRecordsInfo gets all records.
+func (s *agentService) GetRecordsInfo(_ struct{}, records *[]entity.Record) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.GetRecordsInfo()
+	*records = result
+
+	return err
+}
+
+// ListRecords gets page of records, which match query.
+func (s *agentService) ListRecords(query entity.RecordsQuery, page *entity.RecordsPage) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.ListRecords(query)
+	*page = result
+
+	return err
+}
+
+// GetRecord gets decrypted record. File record is replied without data, caller downloads it by stream
+// and saves to its working directory, not agent to working directory of agent.
+func (s *agentService) GetRecord(args AgentRecordArgs, record *entity.Record) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.GetRecordInfo(args.RecordID)
+	if err != nil || result.Type == entity.TypeFile {
+		*record = result
+
+		return err
+	}
+
+	result, err = s.agent.handlers.GetRecord(args.RecordID)
+	*record = result
+
+	return err
+}
+
+// GetRecordInfo gets record with decrypted labels without data.
+func (s *agentService) GetRecordInfo(args AgentRecordArgs, record *entity.Record) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.GetRecordInfo(args.RecordID)
+	*record = result
+
+	return err
+}
+
+// CreateRecord creates record and replies with its ID.
+func (s *agentService) CreateRecord(record entity.Record, recordID *string) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	id, err := s.agent.handlers.CreateRecord(record)
+	*recordID = id
+
+	return err
+}
+
+// UpdateRecord updates record.
+func (s *agentService) UpdateRecord(record entity.Record, _ *struct{}) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	return s.agent.handlers.UpdateRecord(record)
+}
+
+// DeleteRecord deletes record.
+func (s *agentService) DeleteRecord(args AgentRecordArgs, _ *struct{}) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	return s.agent.handlers.DeleteRecord(args.RecordID, args.Revision)
+}
+
+// GetRecordVersions gets previous versions of record.
+func (s *agentService) GetRecordVersions(args AgentRecordArgs, versions *[]entity.RecordVersion) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.GetRecordVersions(args.RecordID)
+	*versions = result
+
+	return err
+}
+
+// RestoreRecordVersion makes previous version of record current.
+func (s *agentService) RestoreRecordVersion(args AgentRecordArgs, _ *struct{}) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	return s.agent.handlers.RestoreRecordVersion(args.RecordID, args.Version, args.Revision)
+}
+
+// MigrateLabels encrypts labels of records, which are in clear.
+func (s *agentService) MigrateLabels(_ struct{}, migrated *[]string) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.MigrateLabels()
+	*migrated = result
+
+	return err
+}
+
+// Conflicts gets offline changes, which weren't applied on server as they were made.
+func (s *agentService) Conflicts(_ struct{}, conflicts *[]entity.SyncConflict) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.Conflicts()
+	*conflicts = result
+
+	return err
+}
+
+// CreateVault creates shared vault.
+func (s *agentService) CreateVault(name string, vaultID *string) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.CreateVault(name)
+	*vaultID = result
+
+	return err
+}
+
+// GetVaults gets vaults of user.
+func (s *agentService) GetVaults(_ struct{}, vaults *[]entity.Vault) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.GetVaults()
+	*vaults = result
+
+	return err
+}
+
+// GetVaultMembers gets members of vault.
+func (s *agentService) GetVaultMembers(args AgentVaultArgs, members *[]entity.VaultMember) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.GetVaultMembers(args.VaultID)
+	*members = result
+
+	return err
+}
+
+// Fingerprint gets fingerprint of public key of user.
+func (s *agentService) Fingerprint(_ struct{}, fingerprint *string) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.Fingerprint()
+	*fingerprint = result
+
+	return err
+}
+
+// InviteMember adds member to vault.
+func (s *agentService) InviteMember(args AgentVaultArgs, _ *struct{}) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	return s.agent.handlers.InviteMember(args.VaultID, args.Login, args.Fingerprint, args.Role)
+}
+
+// RevokeMember removes member from vault.
+func (s *agentService) RevokeMember(args AgentVaultArgs, _ *struct{}) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	return s.agent.handlers.RevokeMember(args.VaultID, args.Login)
+}
+
+// GetVaultRecords gets records of vault.
+func (s *agentService) GetVaultRecords(args AgentVaultArgs, records *[]entity.Record) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.GetVaultRecords(args.VaultID)
+	*records = result
+
+	return err
+}
+
+// GetVaultRecord gets decrypted record of vault.
+func (s *agentService) GetVaultRecord(args AgentVaultArgs, record *entity.Record) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.GetVaultRecord(args.VaultID, args.RecordID)
+	*record = result
+
+	return err
+}
+
+// CreateVaultRecord creates record in vault.
+func (s *agentService) CreateVaultRecord(record entity.Record, recordID *string) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	result, err := s.agent.handlers.CreateVaultRecord(record)
+	*recordID = result
+
+	return err
+}
+
+// UpdateVaultRecord updates record in vault.
+func (s *agentService) UpdateVaultRecord(record entity.Record, _ *struct{}) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	return s.agent.handlers.UpdateVaultRecord(record)
+}
+
+// DeleteVaultRecord deletes record from vault.
+func (s *agentService) DeleteVaultRecord(args AgentVaultArgs, _ *struct{}) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	return s.agent.handlers.DeleteVaultRecord(args.VaultID, args.RecordID)
+}
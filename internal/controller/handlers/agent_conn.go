@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// agentErrors are errors, which are restored from agent response, so callers can check them.
+var agentErrors = []error{
+	controller.ErrFieldIsEmpty,
+	controller.ErrWrongMasterKey,
+	controller.ErrDataCorrupted,
+	pkg.ErrBadKDFParams,
+	controller.ErrBadPublicKey,
+	controller.ErrBadRole,
+	controller.ErrBadFingerprint,
+	controller.ErrKeyNotVerified,
+	controller.ErrBadFileName,
+	controller.ErrPlainLabels,
+	controller.ErrLegacyKey,
+	controller.ErrServerUnavailable,
+	controller.ErrOffline,
+	controller.ErrLocked,
+	storage.ErrUnauthenticated,
+	storage.ErrWrongCredentials,
+	storage.ErrLoginExists,
+	storage.ErrNotFound,
+	storage.ErrNotSupported,
+	storage.ErrConflict,
+	storage.ErrForbidden,
+	storage.ErrNoPublicKey,
+	storage.ErrUnknown,
+}
+
+// agentClient is client handlers, which are called in agent by Unix socket.
+type agentClient struct {
+	rpc *rpc.Client
+}
+
+// newAgentClient connects to agent on Unix socket. Agent must run as the same user,
+// so master key isn't sent to agent of another user.
+func newAgentClient(socket string) (*agentClient, error) {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkPeer(conn); err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return &agentClient{rpc: rpc.NewClient(conn)}, nil
+}
+
+// call calls agent method and restores handlers error from response.
+func (a *agentClient) call(method string, args, reply interface{}) error {
+	err := a.rpc.Call(agentServiceName+"."+method, args, reply)
+
+	var serverError rpc.ServerError
+	if !errors.As(err, &serverError) {
+		if err != nil {
+			log.Warnf("%s :: %v", "call agent fault", err)
+
+			return controller.ErrServerUnavailable
+		}
+
+		return nil
+	}
+
+	for _, known := range agentErrors {
+		if string(serverError) == known.Error() {
+			return known
+		}
+	}
+
+	return err
+}
+
+// Lock locks agent: it forgets session and encryption key.
+func (a *agentClient) Lock() error {
+	return a.call("Lock", struct{}{}, &struct{}{})
+}
+
+// Close closes connection to agent.
+func (a *agentClient) Close() error {
+	return a.rpc.Close()
+}
+
+// Login logins user in agent and unlocks it.
+func (a *agentClient) Login(credentials entity.UserCredentials) error {
+	return a.call("Login", credentials, &struct{}{})
+}
+
+// Register creates new user in agent and unlocks it.
+func (a *agentClient) Register(credentials entity.UserCredentials) error {
+	return a.call("Register", credentials, &struct{}{})
+}
+
+// Logout ends session and locks agent.
+func (a *agentClient) Logout() error {
+	return a.call("Logout", struct{}{}, &struct{}{})
+}
+
+// RevokeSessions ends all sessions of user and locks agent.
+func (a *agentClient) RevokeSessions() error {
+	return a.call("RevokeSessions", struct{}{}, &struct{}{})
+}
+
+// Unlocked reports if agent is unlocked.
+func (a *agentClient) Unlocked() bool {
+	var unlocked bool
+
+	if err := a.call("Unlocked", struct{}{}, &unlocked); err != nil {
+		return false
+	}
+
+	return unlocked
+}
+
+// GetRecordsInfo gets all records.
+func (a *agentClient) GetRecordsInfo() ([]entity.Record, error) {
+	var records []entity.Record
+	err := a.call("GetRecordsInfo", struct{}{}, &records)
+
+	return records, err
+}
+
+// ListRecords gets page of records, which match query.
+func (a *agentClient) ListRecords(query entity.RecordsQuery) (entity.RecordsPage, error) {
+	var page entity.RecordsPage
+	err := a.call("ListRecords", query, &page)
+
+	return page, err
+}
+
+// GetRecord gets decrypted record. File record is downloaded by stream and saved to working directory of caller.
+func (a *agentClient) GetRecord(recordID string) (entity.Record, error) {
+	var record entity.Record
+	if err := a.call("GetRecord", AgentRecordArgs{RecordID: recordID}, &record); err != nil || record.Type != entity.TypeFile {
+		return record, err
+	}
+
+	return saveFile(record, func(w io.Writer) error {
+		_, err := a.GetFile(recordID, w)
+
+		return err
+	})
+}
+
+// GetRecordInfo gets record with decrypted labels without data.
+func (a *agentClient) GetRecordInfo(recordID string) (entity.Record, error) {
+	var record entity.Record
+	err := a.call("GetRecordInfo", AgentRecordArgs{RecordID: recordID}, &record)
+
+	return record, err
+}
+
+// GetFile gets file record and writes decrypted file to w. File is streamed by chunks,
+// so neither agent nor caller keeps it in memory.
+func (a *agentClient) GetFile(recordID string, w io.Writer) (entity.Record, error) {
+	var stream uint64
+	if err := a.call("OpenFile", AgentRecordArgs{RecordID: recordID}, &stream); err != nil {
+		return entity.Record{}, err
+	}
+
+	for {
+		var chunk AgentChunk
+		if err := a.call("ReadFile", stream, &chunk); err != nil {
+			return chunk.Record, err
+		}
+
+		if _, err := w.Write(chunk.Data); err != nil {
+			log.Infoln(err)
+			a.closeStream(stream)
+
+			return chunk.Record, storage.ErrUnknown
+		}
+
+		if chunk.Done {
+			return chunk.Record, nil
+		}
+	}
+}
+
+// CreateRecord creates record. Body of file record is streamed to agent by chunks.
+func (a *agentClient) CreateRecord(record entity.Record) (string, error) {
+	if record.Body != nil {
+		return a.createFile(record)
+	}
+
+	var recordID string
+	err := a.call("CreateRecord", record, &recordID)
+
+	return recordID, err
+}
+
+// createFile streams body of file record to agent, record is created after last chunk.
+func (a *agentClient) createFile(record entity.Record) (string, error) {
+	body := record.Body
+	record.Body = nil
+
+	var stream uint64
+	if err := a.call("CreateFile", record, &stream); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, agentChunkSize)
+
+	for {
+		n, err := io.ReadFull(body, buf)
+		done := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !done {
+			log.Infoln(err)
+			a.closeStream(stream)
+
+			return "", storage.ErrUnknown
+		}
+
+		var recordID string
+		if err = a.call("WriteFile", AgentChunk{Stream: stream, Data: buf[:n], Done: done}, &recordID); err != nil || done {
+			return recordID, err
+		}
+	}
+}
+
+// closeStream aborts stream, which isn't used till its end.
+func (a *agentClient) closeStream(stream uint64) {
+	if err := a.call("CloseFile", stream, &struct{}{}); err != nil {
+		log.Infoln(err)
+	}
+}
+
+// UpdateRecord updates record.
+func (a *agentClient) UpdateRecord(record entity.Record) error {
+	record.Body = nil
+
+	return a.call("UpdateRecord", record, &struct{}{})
+}
+
+// DeleteRecord deletes record.
+func (a *agentClient) DeleteRecord(recordID string, revision int64) error {
+	return a.call("DeleteRecord", AgentRecordArgs{RecordID: recordID, Revision: revision}, &struct{}{})
+}
+
+// GetRecordVersions gets previous versions of record.
+func (a *agentClient) GetRecordVersions(recordID string) ([]entity.RecordVersion, error) {
+	var versions []entity.RecordVersion
+	err := a.call("GetRecordVersions", AgentRecordArgs{RecordID: recordID}, &versions)
+
+	return versions, err
+}
+
+// RestoreRecordVersion makes previous version of record current.
+func (a *agentClient) RestoreRecordVersion(recordID string, version int32, revision int64) error {
+	return a.call(
+		"RestoreRecordVersion",
+		AgentRecordArgs{RecordID: recordID, Version: version, Revision: revision},
+		&struct{}{},
+	)
+}
+
+// MigrateLabels encrypts labels of records, which are in clear.
+func (a *agentClient) MigrateLabels() ([]string, error) {
+	var migrated []string
+	err := a.call("MigrateLabels", struct{}{}, &migrated)
+
+	return migrated, err
+}
+
+// Conflicts gets offline changes, which weren't applied on server as they were made.
+func (a *agentClient) Conflicts() ([]entity.SyncConflict, error) {
+	var conflicts []entity.SyncConflict
+	err := a.call("Conflicts", struct{}{}, &conflicts)
+
+	return conflicts, err
+}
+
+// CreateVault creates shared vault.
+func (a *agentClient) CreateVault(name string) (string, error) {
+	var vaultID string
+	err := a.call("CreateVault", name, &vaultID)
+
+	return vaultID, err
+}
+
+// GetVaults gets vaults of user.
+func (a *agentClient) GetVaults() ([]entity.Vault, error) {
+	var vaults []entity.Vault
+	err := a.call("GetVaults", struct{}{}, &vaults)
+
+	return vaults, err
+}
+
+// GetVaultMembers gets members of vault.
+func (a *agentClient) GetVaultMembers(vaultID string) ([]entity.VaultMember, error) {
+	var members []entity.VaultMember
+	err := a.call("GetVaultMembers", AgentVaultArgs{VaultID: vaultID}, &members)
+
+	return members, err
+}
+
+// Fingerprint gets fingerprint of public key of user.
+func (a *agentClient) Fingerprint() (string, error) {
+	var fingerprint string
+	err := a.call("Fingerprint", struct{}{}, &fingerprint)
+
+	return fingerprint, err
+}
+
+// InviteMember adds member to vault.
+func (a *agentClient) InviteMember(vaultID, login, fingerprint string, role entity.VaultRole) error {
+	args := AgentVaultArgs{VaultID: vaultID, Login: login, Fingerprint: fingerprint, Role: role}
+
+	return a.call("InviteMember", args, &struct{}{})
+}
+
+// RevokeMember removes member from vault.
+func (a *agentClient) RevokeMember(vaultID, login string) error {
+	return a.call("RevokeMember", AgentVaultArgs{VaultID: vaultID, Login: login}, &struct{}{})
+}
+
+// GetVaultRecords gets records of vault.
+func (a *agentClient) GetVaultRecords(vaultID string) ([]entity.Record, error) {
+	var records []entity.Record
+	err := a.call("GetVaultRecords", AgentVaultArgs{VaultID: vaultID}, &records)
+
+	return records, err
+}
+
+// GetVaultRecord gets decrypted record of vault.
+func (a *agentClient) GetVaultRecord(vaultID, recordID string) (entity.Record, error) {
+	var record entity.Record
+	err := a.call("GetVaultRecord", AgentVaultArgs{VaultID: vaultID, RecordID: recordID}, &record)
+
+	return record, err
+}
+
+// CreateVaultRecord creates record in vault.
+func (a *agentClient) CreateVaultRecord(record entity.Record) (string, error) {
+	var recordID string
+	record.Body = nil
+	err := a.call("CreateVaultRecord", record, &recordID)
+
+	return recordID, err
+}
+
+// UpdateVaultRecord updates record in vault.
+func (a *agentClient) UpdateVaultRecord(record entity.Record) error {
+	record.Body = nil
+
+	return a.call("UpdateVaultRecord", record, &struct{}{})
+}
+
+// DeleteVaultRecord deletes record from vault.
+func (a *agentClient) DeleteVaultRecord(vaultID, recordID string) error {
+	return a.call("DeleteVaultRecord", AgentVaultArgs{VaultID: vaultID, RecordID: recordID}, &struct{}{})
+}
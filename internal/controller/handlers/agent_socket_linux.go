@@ -0,0 +1,69 @@
+//go:build linux
+
+package handlers
+
+import (
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+)
+
+// checkSocketDirectory checks that directory of agent socket isn't symlink, is owned by user
+// and only user can access it, so other users can't replace socket or connect to it.
+func checkSocketDirectory(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || !info.IsDir() || info.Mode().Perm() != 0o700 || int(stat.Uid) != os.Getuid() {
+		return controller.ErrAgentSocket
+	}
+
+	return nil
+}
+
+// listenSocket listens Unix socket, which is created with mode 0600 under umask,
+// so there is no moment, when socket is created, but others can connect to it.
+func listenSocket(socket string) (net.Listener, error) {
+	mask := syscall.Umask(0o177)
+	defer syscall.Umask(mask)
+
+	return net.Listen("unix", socket)
+}
+
+// checkPeer checks by SO_PEERCRED that process on the other side of Unix socket runs as the same user.
+func checkPeer(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return controller.ErrAgentPeer
+	}
+
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var (
+		cred    *syscall.Ucred
+		errCred error
+	)
+
+	if err = raw.Control(func(fd uintptr) {
+		cred, errCred = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil {
+		return err
+	}
+	if errCred != nil {
+		return errCred
+	}
+
+	if int(cred.Uid) != os.Getuid() {
+		return controller.ErrAgentPeer
+	}
+
+	return nil
+}
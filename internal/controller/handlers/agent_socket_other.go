@@ -0,0 +1,48 @@
+//go:build !linux
+
+package handlers
+
+import (
+	"net"
+	"os"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+)
+
+// checkSocketDirectory checks that directory of agent socket isn't symlink and only owner can access it.
+// Owner of directory isn't checked on this system.
+func checkSocketDirectory(dir string) error {
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() || info.Mode().Perm() != 0o700 {
+		return controller.ErrAgentSocket
+	}
+
+	return nil
+}
+
+// listenSocket listens Unix socket and makes it accessible only by owner.
+// Socket is protected by its directory, until mode is changed.
+func listenSocket(socket string) (net.Listener, error) {
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.Chmod(socket, 0o600); err != nil {
+		listener.Close()
+
+		return nil, err
+	}
+
+	return listener, nil
+}
+
+// checkPeer doesn't check peer on this system: credentials of peer aren't available without cgo,
+// socket is protected by its directory.
+func checkPeer(net.Conn) error {
+	return nil
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+)
+
+const (
+	// agentChunkSize is size of chunks, which files are sent by between agent and its clients.
+	agentChunkSize = 64 * 1024
+	// agentStreamTimeout is time, after which stream, which client stopped using, is aborted,
+	// so handlers, which read or write its file, are released.
+	agentStreamTimeout = time.Minute
+)
+
+// errStreamAborted is error of reads and writes of file, which stream was aborted.
+var errStreamAborted = errors.New("agent stream is aborted")
+
+// AgentChunk is chunk of file, which is streamed between agent and its client. Done marks last chunk:
+// downloaded file is replied with its record, uploaded file is created after it.
+type AgentChunk struct {
+	Stream uint64
+	Data   []byte
+	Done   bool
+	Record entity.Record
+}
+
+// agentStream is file, which handlers read or write in background, while client sends or gets its chunks.
+type agentStream struct {
+	reader *io.PipeReader
+	writer *io.PipeWriter
+	result chan agentStreamResult
+	timer  *time.Timer
+}
+
+// agentStreamResult is result of handlers, which read or wrote file of stream.
+type agentStreamResult struct {
+	record   entity.Record
+	recordID string
+	err      error
+}
+
+// openStream registers new stream, which is aborted, if it isn't used during stream timeout.
+func (a *AgentServer) openStream() (uint64, *agentStream) {
+	reader, writer := io.Pipe()
+	stream := &agentStream{reader: reader, writer: writer, result: make(chan agentStreamResult, 1)}
+
+	a.Lock()
+	defer a.Unlock()
+
+	a.nextStream++
+	id := a.nextStream
+	a.streams[id] = stream
+	stream.timer = time.AfterFunc(agentStreamTimeout, func() { a.abortStream(id) })
+
+	return id, stream
+}
+
+// stream gets stream by ID and marks agent as used now. Stream isn't aborted during call of client,
+// which waits for handlers, until release.
+func (a *AgentServer) stream(id uint64) (*agentStream, error) {
+	a.Lock()
+	defer a.Unlock()
+
+	a.lastUsed = time.Now()
+
+	stream, ok := a.streams[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	stream.timer.Stop()
+
+	return stream, nil
+}
+
+// release restarts timeout of stream, which is still open after call of client.
+func (a *AgentServer) release(id uint64, stream *agentStream) {
+	a.Lock()
+	defer a.Unlock()
+
+	if a.streams[id] == stream {
+		stream.timer.Reset(agentStreamTimeout)
+	}
+}
+
+// finishStream waits for result of handlers and forgets stream.
+func (a *AgentServer) finishStream(id uint64, stream *agentStream) agentStreamResult {
+	result := <-stream.result
+
+	a.Lock()
+	delete(a.streams, id)
+	a.Unlock()
+
+	stream.timer.Stop()
+
+	return result
+}
+
+// abortStream forgets stream and breaks its file, so handlers, which read or write it, return.
+func (a *AgentServer) abortStream(id uint64) {
+	a.Lock()
+	stream, ok := a.streams[id]
+	delete(a.streams, id)
+	a.Unlock()
+
+	if !ok {
+		return
+	}
+
+	stream.timer.Stop()
+	stream.reader.CloseWithError(errStreamAborted)
+	stream.writer.CloseWithError(errStreamAborted)
+}
+
+// abortStreams aborts all streams.
+func (a *AgentServer) abortStreams() {
+	a.Lock()
+	ids := make([]uint64, 0, len(a.streams))
+	for id := range a.streams {
+		ids = append(ids, id)
+	}
+	a.Unlock()
+
+	for _, id := range ids {
+		a.abortStream(id)
+	}
+}
+
+// OpenFile starts download of file record and replies with stream, which chunks of decrypted file are read from.
+func (s *agentService) OpenFile(args AgentRecordArgs, streamID *uint64) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	id, stream := s.agent.openStream()
+
+	go func() {
+		record, err := s.agent.handlers.GetFile(args.RecordID, stream.writer)
+		stream.result <- agentStreamResult{record: record, err: err}
+		stream.writer.Close()
+	}()
+
+	*streamID = id
+
+	return nil
+}
+
+// ReadFile replies with next chunk of downloaded file. Last chunk is replied with record of file.
+// Handlers aren't called here: they are busy with download.
+func (s *agentService) ReadFile(streamID uint64, chunk *AgentChunk) error {
+	stream, err := s.agent.stream(streamID)
+	if err != nil {
+		return err
+	}
+	defer s.agent.release(streamID, stream)
+
+	buf := make([]byte, agentChunkSize)
+
+	n, err := io.ReadFull(stream.reader, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return storage.ErrUnknown
+	}
+
+	*chunk = AgentChunk{Stream: streamID, Data: buf[:n]}
+	if err == nil {
+		return nil
+	}
+
+	result := s.agent.finishStream(streamID, stream)
+	chunk.Done, chunk.Record = true, result.record
+
+	return result.err
+}
+
+// CreateFile starts upload of file record and replies with stream, which chunks of file are written to.
+func (s *agentService) CreateFile(record entity.Record, streamID *uint64) error {
+	if err := s.agent.unlocked(); err != nil {
+		return err
+	}
+
+	id, stream := s.agent.openStream()
+
+	go func() {
+		record.Body = stream.reader
+		recordID, err := s.agent.handlers.CreateRecord(record)
+		stream.result <- agentStreamResult{recordID: recordID, err: err}
+		stream.reader.Close()
+	}()
+
+	*streamID = id
+
+	return nil
+}
+
+// WriteFile writes chunk of uploaded file. After last chunk record is created and replied with its ID.
+// If handlers stop reading file before its end, their error is replied.
+func (s *agentService) WriteFile(chunk AgentChunk, recordID *string) error {
+	stream, err := s.agent.stream(chunk.Stream)
+	if err != nil {
+		return err
+	}
+	defer s.agent.release(chunk.Stream, stream)
+
+	if len(chunk.Data) != 0 {
+		_, err = stream.writer.Write(chunk.Data)
+	}
+	if err == nil && !chunk.Done {
+		return nil
+	}
+
+	stream.writer.Close()
+	result := s.agent.finishStream(chunk.Stream, stream)
+	*recordID = result.recordID
+
+	if result.err == nil && err != nil {
+		return storage.ErrUnknown
+	}
+
+	return result.err
+}
+
+// CloseFile aborts stream, which client stops using before its end.
+func (s *agentService) CloseFile(streamID uint64, _ *struct{}) error {
+	s.agent.abortStream(streamID)
+
+	return nil
+}
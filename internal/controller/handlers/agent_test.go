@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// failingWriter is writer, which always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk is full")
+}
+
+// runAgent runs agent with handlers on socket in temporary directory and connects to it.
+// Directory isn't t.TempDir, because path of Unix socket is limited.
+func runAgent(t *testing.T, h ClientHandlers, idleTimeout time.Duration) (*AgentServer, *agentClient, string) {
+	dir, err := os.MkdirTemp("", "agent")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := ListenAgentSocket(socket)
+	assert.NoError(t, err)
+
+	agent := NewAgentServer(h, idleTimeout)
+	go agent.Serve(listener)
+
+	client, err := newAgentClient(socket)
+	assert.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return agent, client, socket
+}
+
+func TestAgent(t *testing.T) {
+	h, unlocked := mocks.NewClientHandlers(t), false
+	agent, client, socket := runAgent(t, h, 0)
+
+	h.On("Unlocked").Return(func() bool { return unlocked })
+
+	info, err := os.Stat(socket)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	t.Log("Second agent on the same socket")
+	_, err = ListenAgentSocket(socket)
+	assert.ErrorIs(t, err, controller.ErrAgentRunning)
+
+	t.Log("Locked agent doesn't call handlers")
+	assert.False(t, client.Unlocked())
+
+	_, err = client.GetRecordsInfo()
+	assert.Equal(t, controller.ErrLocked, err)
+
+	t.Log("Login with wrong credentials")
+	credentials := entity.UserCredentials{Login: "login", Password: "password", MasterKey: []byte("key")}
+	h.On("Login", credentials).Return(storage.ErrWrongCredentials).Once()
+	assert.Equal(t, storage.ErrWrongCredentials, client.Login(credentials))
+
+	t.Log("Unlocked agent reuses session")
+	h.On("Login", credentials).Return(nil).Run(func(_ mock.Arguments) { unlocked = true }).Once()
+	assert.NoError(t, client.Login(credentials))
+	assert.True(t, client.Unlocked())
+	h.On("GetRecordsInfo").Return([]entity.Record{{ID: "recordID", Metadata: "site"}}, nil).Once()
+
+	records, err := client.GetRecordsInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Record{{ID: "recordID", Metadata: "site"}}, records)
+
+	h.On("GetRecordInfo", "recordID").Return(entity.Record{ID: "recordID", Type: entity.TypeText}, nil).Once()
+	h.On("GetRecord", "recordID").Return(entity.Record{ID: "recordID", Data: []byte("secret")}, nil).Once()
+
+	record, err := client.GetRecord("recordID")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret"), record.Data)
+
+	h.On("GetRecordInfo", "missing").Return(entity.Record{}, storage.ErrNotFound).Once()
+
+	_, err = client.GetRecord("missing")
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	t.Log("File record is streamed by chunks and saved to working directory of caller")
+	dir := chdirTemp(t)
+	file := []byte(strings.Repeat("file data ", agentChunkSize/4))
+	fileRecord := entity.Record{ID: "fileID", Type: entity.TypeFile, Metadata: "docs/report.txt"}
+	h.On("GetRecordInfo", "fileID").Return(fileRecord, nil).Once()
+	h.On("GetFile", "fileID", mock.Anything).
+		Run(func(args mock.Arguments) {
+			args.Get(1).(io.Writer).Write(file)
+		}).
+		Return(fileRecord, nil).Once()
+
+	record, err = client.GetRecord("fileID")
+	assert.NoError(t, err)
+	assert.Equal(t, "Saved file successfully to report.txt.", string(record.Data))
+
+	saved, err := os.ReadFile(filepath.Join(dir, "report.txt"))
+	assert.NoError(t, err)
+	assert.Equal(t, file, saved)
+
+	h.On("GetFile", "recordID", mock.Anything).Return(entity.Record{ID: "recordID"}, storage.ErrNotSupported).Once()
+
+	_, err = client.GetFile("recordID", io.Discard)
+	assert.Equal(t, storage.ErrNotSupported, err)
+
+	t.Log("Body of file record is streamed by chunks")
+	var uploaded []byte
+	h.On("CreateRecord", mock.MatchedBy(func(record entity.Record) bool {
+		return record.Type == entity.TypeFile && record.Metadata == "file" && record.Body != nil
+	})).
+		Run(func(args mock.Arguments) {
+			uploaded, _ = io.ReadAll(args.Get(0).(entity.Record).Body)
+		}).
+		Return("recordID", nil).Once()
+	recordID, err := client.CreateRecord(entity.Record{
+		Type:     entity.TypeFile,
+		Metadata: "file",
+		Body:     bytes.NewReader(file),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "recordID", recordID)
+	assert.Equal(t, file, uploaded)
+
+	t.Log("Upload, which handlers reject before end of file")
+	h.On("CreateRecord", mock.AnythingOfType("entity.Record")).Return("", controller.ErrLegacyKey).Once()
+	_, err = client.CreateRecord(entity.Record{Type: entity.TypeFile, Metadata: "file", Body: bytes.NewReader(file)})
+	assert.Equal(t, controller.ErrLegacyKey, err)
+
+	t.Log("Stream, which caller stops using, is aborted")
+	aborted := make(chan error, 1)
+	h.On("GetFile", "fileID", mock.Anything).
+		Run(func(args mock.Arguments) {
+			_, err := args.Get(1).(io.Writer).Write(file)
+			aborted <- err
+		}).
+		Return(fileRecord, nil).Once()
+
+	_, err = client.GetFile("fileID", failingWriter{})
+	assert.Equal(t, storage.ErrUnknown, err)
+	assert.Error(t, <-aborted)
+
+	h.On("DeleteRecord", "recordID", int64(3)).Return(storage.ErrConflict).Once()
+	assert.Equal(t, storage.ErrConflict, client.DeleteRecord("recordID", 3))
+
+	h.On("RestoreRecordVersion", "recordID", int32(2), int64(7)).Return(nil).Once()
+	assert.NoError(t, client.RestoreRecordVersion("recordID", 2, 7))
+
+	t.Log("Vault calls pass vault arguments")
+	h.On("CreateVault", "team").Return("vaultID", nil).Once()
+
+	vaultID, err := client.CreateVault("team")
+	assert.NoError(t, err)
+	assert.Equal(t, "vaultID", vaultID)
+
+	h.On("Fingerprint").Return("0a0b 0c0d", nil).Once()
+
+	fingerprint, err := client.Fingerprint()
+	assert.NoError(t, err)
+	assert.Equal(t, "0a0b 0c0d", fingerprint)
+
+	h.On("InviteMember", "vaultID", "bob", "0a0b 0c0d", entity.RoleRead).Return(controller.ErrBadFingerprint).Once()
+	assert.Equal(t, controller.ErrBadFingerprint, client.InviteMember("vaultID", "bob", "0a0b 0c0d", entity.RoleRead))
+
+	h.On("GetVaultRecord", "vaultID", "recordID").Return(entity.Record{ID: "recordID", VaultID: "vaultID"}, nil).Once()
+
+	record, err = client.GetVaultRecord("vaultID", "recordID")
+	assert.NoError(t, err)
+	assert.Equal(t, "vaultID", record.VaultID)
+
+	t.Log("Lock agent")
+	h.On("Logout").Return(nil).Run(func(_ mock.Arguments) { unlocked = false }).Once()
+	assert.NoError(t, client.Lock())
+
+	_, err = client.GetRecord("recordID")
+	assert.Equal(t, controller.ErrLocked, err)
+
+	assert.NoError(t, agent.Close())
+}
+
+func TestListenAgentSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "agent")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	t.Log("Directory, which others can access")
+	shared := filepath.Join(dir, "shared")
+	assert.NoError(t, os.Mkdir(shared, 0o755))
+	assert.NoError(t, os.Chmod(shared, 0o755))
+
+	_, err = ListenAgentSocket(filepath.Join(shared, "agent.sock"))
+	assert.ErrorIs(t, err, controller.ErrAgentSocket)
+
+	t.Log("Directory is symlink")
+	private := filepath.Join(dir, "private")
+	assert.NoError(t, os.Mkdir(private, 0o700))
+	assert.NoError(t, os.Symlink(private, filepath.Join(dir, "link")))
+
+	_, err = ListenAgentSocket(filepath.Join(dir, "link", "agent.sock"))
+	assert.ErrorIs(t, err, controller.ErrAgentSocket)
+
+	t.Log("File, which isn't socket, isn't removed")
+	file := filepath.Join(private, "agent.sock")
+	assert.NoError(t, os.WriteFile(file, []byte("data"), 0o600))
+
+	_, err = ListenAgentSocket(file)
+	assert.ErrorIs(t, err, controller.ErrAgentSocket)
+	assert.FileExists(t, file)
+
+	t.Log("Stale socket is replaced, socket is created only for owner")
+	assert.NoError(t, os.Remove(file))
+
+	stale, err := ListenAgentSocket(file)
+	assert.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	assert.NoError(t, stale.Close())
+
+	listener, err := ListenAgentSocket(file)
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	info, err := os.Stat(file)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestAgent_IdleLock(t *testing.T) {
+	h := mocks.NewClientHandlers(t)
+
+	locked, once := make(chan struct{}), sync.Once{}
+	h.On("Unlocked").Return(true)
+	h.On("Logout").Return(nil).Run(func(_ mock.Arguments) {
+		once.Do(func() { close(locked) })
+	})
+
+	agent, _, _ := runAgent(t, h, 50*time.Millisecond)
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Error("Agent wasn't locked after idle timeout")
+	}
+
+	assert.NoError(t, agent.Close())
+}
@@ -1,42 +1,55 @@
 package handlers
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
 	"github.com/bbt-t/lets-go-keep/internal/entity"
 	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
 
 	"github.com/golang-jwt/jwt/v5"
 	log "github.com/sirupsen/logrus"
 )
 
+// refreshTokenSize is size of random refresh token in bytes.
+const refreshTokenSize = 32
+
 // authenticatorJWT is authenticator which uses JWT.
 type authenticatorJWT struct {
-	secretKey      []byte
-	expirationTime int64
+	secretKey  []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
 }
 
 // NewAuthenticatorJWT gets new authenticatorJWT.
-func newAuthenticatorJWT(secretKey []byte, expirationTime int64) *authenticatorJWT {
+func newAuthenticatorJWT(secretKey []byte, accessTTL, refreshTTL time.Duration) *authenticatorJWT {
 	return &authenticatorJWT{
-		secretKey:      secretKey,
-		expirationTime: expirationTime,
+		secretKey:  secretKey,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
 	}
 }
 
-// CreateToken implementation of Authenticator interface. Creates token, which stores userID.
-func (a *authenticatorJWT) CreateToken(userID entity.UserID) (entity.AuthToken, error) {
+// CreateToken implementation of Authenticator interface. Creates token, which stores userID
+// and expires after access lifetime. Returns token and its expiration time.
+func (a *authenticatorJWT) CreateToken(userID entity.UserID) (entity.AuthToken, time.Time, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
+	now := time.Now()
+	expiresAt := now.Add(a.accessTTL)
 
 	claims := token.Claims.(jwt.MapClaims)
-	claims["exp"], claims["userID"] = a.expirationTime, userID
+	claims["iat"], claims["exp"], claims["userID"] = now.Unix(), expiresAt.Unix(), userID
 
 	tokenString, err := token.SignedString(a.secretKey)
 	if err != nil {
 		log.Println("Failed generate token for authentication:", err)
 
-		return "", storage.ErrUnknown
+		return "", time.Time{}, storage.ErrUnknown
 	}
 
-	return entity.AuthToken(tokenString), nil
+	return entity.AuthToken(tokenString), expiresAt, nil
 }
 
 // ValidateToken implementation of Authenticator interface. Validates token, returns userID.
@@ -63,3 +76,21 @@ func (a *authenticatorJWT) ValidateToken(token entity.AuthToken) (entity.UserID,
 
 	return entity.UserID(userID), nil
 }
+
+// CreateRefreshToken implementation of Authenticator interface. Creates random refresh token,
+// which expires after refresh lifetime. Returns token and its expiration time.
+func (a *authenticatorJWT) CreateRefreshToken() (entity.RefreshToken, time.Time, error) {
+	token, err := pkg.GenerateRandom(refreshTokenSize)
+	if err != nil {
+		return "", time.Time{}, storage.ErrUnknown
+	}
+
+	return entity.RefreshToken(hex.EncodeToString(token)), time.Now().Add(a.refreshTTL), nil
+}
+
+// refreshTokenHash returns hash of refresh token, which is stored instead of token.
+func refreshTokenHash(token entity.RefreshToken) string {
+	hash := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(hash[:])
+}
@@ -5,24 +5,55 @@ import (
 	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
 
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewAuthenticatorJWT(t *testing.T) {
-	auth := newAuthenticatorJWT([]byte("secret_key"), time.Now().Add(1*time.Hour).Unix())
+	auth := newAuthenticatorJWT([]byte("secret_key"), time.Hour, 24*time.Hour)
 	assert.NotEmpty(t, auth)
 }
 
 func TestAuthenticatorJWT(t *testing.T) {
-	auth := newAuthenticatorJWT([]byte("secret_key"), time.Now().Add(1*time.Hour).Unix())
+	auth := newAuthenticatorJWT([]byte("secret_key"), time.Hour, 24*time.Hour)
 
 	userID := entity.UserID("user_id_12")
 
-	token, err := auth.CreateToken(userID)
+	token, expiresAt, err := auth.CreateToken(userID)
 	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Second)
 
 	id, errValidate := auth.ValidateToken(token)
 	assert.NoError(t, errValidate)
 	assert.Equal(t, userID, id)
 }
+
+func TestAuthenticatorJWT_RelativeExpiration(t *testing.T) {
+	auth := newAuthenticatorJWT([]byte("secret_key"), -time.Minute, 24*time.Hour)
+
+	token, _, err := auth.CreateToken("user_id_12")
+	assert.NoError(t, err)
+
+	_, err = auth.ValidateToken(token)
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+
+	other := newAuthenticatorJWT([]byte("other_key"), time.Hour, 24*time.Hour)
+	_, err = other.ValidateToken(token)
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+}
+
+func TestAuthenticatorJWT_CreateRefreshToken(t *testing.T) {
+	auth := newAuthenticatorJWT([]byte("secret_key"), time.Hour, 24*time.Hour)
+
+	first, expiresAt, err := auth.CreateRefreshToken()
+	assert.NoError(t, err)
+	assert.Len(t, first, 2*refreshTokenSize)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), expiresAt, time.Second)
+
+	second, _, err := auth.CreateRefreshToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+	assert.NotEqual(t, refreshTokenHash(first), refreshTokenHash(second))
+	assert.Len(t, refreshTokenHash(first), 64)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"io"
+)
+
+// fileChunkSize is max size of data sent in one stream message.
+const fileChunkSize = 64 * 1024
+
+// chunkReader reads data from stream messages as continuous reader.
+type chunkReader struct {
+	buf  []byte
+	recv func() ([]byte, error)
+}
+
+// newChunkReader returns reader, which starts from first chunk and then receives next chunks.
+func newChunkReader(first []byte, recv func() ([]byte, error)) *chunkReader {
+	return &chunkReader{
+		buf:  first,
+		recv: recv,
+	}
+}
+
+// Read implementation of io.Reader interface. Returns io.EOF, when stream is over.
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		chunk, err := r.recv()
+		if err != nil {
+			return 0, err
+		}
+
+		r.buf = chunk
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+}
+
+// chunkWriter splits written data into stream messages not bigger than fileChunkSize.
+type chunkWriter struct {
+	send func([]byte) error
+}
+
+// newChunkWriter returns writer, which sends every chunk using send function.
+func newChunkWriter(send func([]byte) error) *chunkWriter {
+	return &chunkWriter{send: send}
+}
+
+// Write implementation of io.Writer interface.
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+
+	for len(p) > 0 {
+		size := len(p)
+		if size > fileChunkSize {
+			size = fileChunkSize
+		}
+
+		chunk := make([]byte, size)
+		copy(chunk, p[:size])
+
+		if err := w.send(chunk); err != nil {
+			return written, err
+		}
+
+		written += size
+		p = p[size:]
+	}
+
+	return written, nil
+}
+
+// copyChunks copies data from reader to chunk writer using buffer of chunk size.
+func copyChunks(w io.Writer, r io.Reader) (int64, error) {
+	return io.CopyBuffer(w, r, make([]byte, fileChunkSize))
+}
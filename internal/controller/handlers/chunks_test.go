@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkWriter(t *testing.T) {
+	var chunks [][]byte
+
+	writer := newChunkWriter(func(chunk []byte) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+
+	data := bytes.Repeat([]byte("a"), 2*fileChunkSize+10)
+
+	n, err := writer.Write(data)
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Len(t, chunks, 3)
+	assert.Len(t, chunks[0], fileChunkSize)
+	assert.Len(t, chunks[2], 10)
+
+	failing := newChunkWriter(func(chunk []byte) error {
+		return errors.New("stream closed")
+	})
+
+	n, err = failing.Write(data)
+	assert.Error(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestChunkReader(t *testing.T) {
+	chunks := [][]byte{[]byte("second "), {}, []byte("third")}
+
+	reader := newChunkReader([]byte("first "), func() ([]byte, error) {
+		if len(chunks) == 0 {
+			return nil, io.EOF
+		}
+
+		chunk := chunks[0]
+		chunks = chunks[1:]
+
+		return chunk, nil
+	})
+
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "first second third", string(data))
+}
+
+func TestCopyChunks(t *testing.T) {
+	var received bytes.Buffer
+
+	writer := newChunkWriter(func(chunk []byte) error {
+		assert.LessOrEqual(t, len(chunk), fileChunkSize)
+		received.Write(chunk)
+		return nil
+	})
+
+	data := bytes.Repeat([]byte("data"), fileChunkSize)
+
+	n, err := copyChunks(writer, bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	assert.Equal(t, data, received.Bytes())
+}
@@ -1,11 +1,17 @@
 package handlers
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/sha256"
+	"errors"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/controller"
 	"github.com/bbt-t/lets-go-keep/internal/entity"
@@ -15,184 +21,1230 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// sessionRefreshMargin is time before session token expiration, when session is refreshed.
+const sessionRefreshMargin = time.Minute
+
 // client struct for client handlers.
 type client struct {
-	conn      ClientConnection
-	authToken entity.AuthToken
-	masterKey []byte
+	conn         ClientConnection
+	authToken    entity.AuthToken
+	refreshToken entity.RefreshToken
+	expiresAt    time.Time
+	masterKey    []byte
+	// legacy is set, while records are encrypted by legacy key, writes are refused until key is upgraded.
+	legacy bool
+	// encryptMetadata makes client encrypt metadata and name of new and updated records,
+	// so server sees only opaque text and blind index of their words.
+	encryptMetadata bool
+	// vaultKeys are unwrapped keys of shared vaults by vault ID.
+	vaultKeys map[string][]byte
 	*sync.Mutex
 }
 
 // newClientHandlers returns new client handlers.
-func newClientHandlers(connection ClientConnection) *client {
+func newClientHandlers(connection ClientConnection, encryptMetadata bool) *client {
 	return &client{
-		conn:  connection,
-		Mutex: &sync.Mutex{},
+		conn:            connection,
+		encryptMetadata: encryptMetadata,
+		vaultKeys:       make(map[string][]byte),
+		Mutex:           &sync.Mutex{},
 	}
 }
 
-// Login logins user by login and password.
+// Login logins user by login and password. Derives encryption key from master key
+// using key derivation parameters, which server stores for user. Legacy key of user is upgraded.
 func (c *client) Login(credentials entity.UserCredentials) error {
 	if credentials.Login == "" || credentials.Password == "" || len(credentials.MasterKey) == 0 {
 		return controller.ErrFieldIsEmpty
 	}
-	authToken, err := c.conn.Login(credentials)
+	session, err := c.conn.Login(credentials)
 	if err != nil {
 		log.Warnf("%s :: %v", "auth token fault", err)
 
 		return err
 	}
 
+	if session.KDF.Legacy {
+		return c.loginLegacy(credentials.MasterKey, session)
+	}
+
+	key, err := deriveKey(credentials.MasterKey, session.KDF)
+	if err != nil {
+		return err
+	}
+
+	if err = c.setConnKey(key); err != nil {
+		return err
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
-	c.authToken = entity.AuthToken(authToken)
-	sha := sha256.New()
+	c.setSession(session)
+	c.masterKey = key
+	c.provisionPublicKey()
 
-	if _, err = sha.Write(c.masterKey); err != nil {
-		return storage.ErrUnknown
+	return nil
+}
+
+// loginLegacy logins user, whose records are encrypted by legacy key, and upgrades key: records are encrypted
+// by key derived from master key with new parameters, then server stores parameters and forgets legacy mark.
+// If upgrade fails, user stays logged with legacy key: records can be read, but writes are refused
+// until next login upgrades key. Master key, which doesn't match records upgraded before, isn't accepted.
+func (c *client) loginLegacy(masterKey []byte, session entity.Session) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.setSession(session)
+	c.masterKey, c.legacy = legacyKey(), true
+
+	key, err := c.upgradeKey(masterKey, session.KDF)
+	if errors.Is(err, controller.ErrWrongMasterKey) {
+		c.forget()
+
+		return err
 	}
+	if err != nil {
+		log.Warnf("%s :: %v", "upgrade legacy key fault", err)
 
-	key := sha.Sum(nil)
-	c.masterKey = key
+		return nil
+	}
+
+	if err = c.setConnKey(key); err != nil {
+		c.forget()
+
+		return err
+	}
+
+	c.masterKey, c.legacy = key, false
+	c.provisionPublicKey()
 
 	return nil
 }
 
-// Register creates new user by login and password.
+// provisionPublicKey registers public key of user, who was registered before keys, so vaults can be shared
+// with user. Server keeps public key, which is already set, so it's sent on every login. Errors are only logged,
+// login doesn't depend on them. Public key derived from legacy key isn't registered: anyone can compute it.
+func (c *client) provisionPublicKey() {
+	if c.legacy {
+		return
+	}
+
+	publicKey, err := pkg.PublicKey(c.masterKey)
+	if err != nil {
+		log.Warnf("%s :: %v", "derive public key fault", err)
+
+		return
+	}
+
+	if err = c.conn.SetPublicKey(c.authToken, publicKey); err != nil {
+		log.Warnf("%s :: %v", "set public key fault", err)
+	}
+}
+
+// Register creates new user by login and password. Generates new key derivation parameters
+// with random salt, which server stores for user, and registers public key derived from encryption key,
+// so vaults can be shared with user.
 func (c *client) Register(credentials entity.UserCredentials) error {
 	if credentials.Login == "" || credentials.Password == "" || len(credentials.MasterKey) == 0 {
 		return controller.ErrFieldIsEmpty
 	}
-	authToken, err := c.conn.Register(credentials)
+
+	kdf, err := pkg.NewKDFParams()
+	if err != nil {
+		log.Warnf("%s :: %v", "generate kdf params fault", err)
+
+		return storage.ErrUnknown
+	}
+	credentials.KDF = kdf
+
+	key, err := deriveKey(credentials.MasterKey, kdf)
+	if err != nil {
+		return err
+	}
+
+	if credentials.PublicKey, err = pkg.PublicKey(key); err != nil {
+		log.Warnf("%s :: %v", "derive public key fault", err)
+
+		return storage.ErrUnknown
+	}
+
+	session, err := c.conn.Register(credentials)
 	if err != nil {
 		log.Warnf("%s :: %v", "register fault", err)
 
 		return err
 	}
 
+	if err = c.setConnKey(key); err != nil {
+		return err
+	}
+
 	c.Lock()
 	defer c.Unlock()
 
-	c.authToken = entity.AuthToken(authToken)
+	c.setSession(session)
+	c.masterKey = key
+
+	return nil
+}
 
-	sha := sha256.New()
-	_, err = sha.Write(c.masterKey)
-	if err != nil {
-		log.Warnf("%s :: %v", "write master key (sha) fault", err)
+// Logout ends session on server and forgets session and encryption key.
+// They are forgotten even if server returns error.
+func (c *client) Logout() error {
+	c.Lock()
+	defer c.Unlock()
 
-		return storage.ErrUnknown
+	err := c.conn.Logout(c.refreshToken)
+	c.forget()
+
+	return err
+}
+
+// RevokeSessions ends all sessions of user on all devices, then forgets session and encryption key.
+func (c *client) RevokeSessions() error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	if err := c.conn.RevokeSessions(c.authToken); err != nil {
+		log.Warnf("%s :: %v", "revoke sessions fault", err)
+
+		return err
 	}
 
-	key := sha.Sum(nil)
-	c.masterKey = key
+	c.forget()
 
 	return nil
 }
 
-// GetRecordsInfo gets all records.
-func (c *client) GetRecordsInfo() ([]entity.Record, error) {
+// Unlocked reports if encryption key is derived, so records can be read without login.
+func (c *client) Unlocked() bool {
 	c.Lock()
 	defer c.Unlock()
 
-	return c.conn.GetRecordsInfo(c.authToken)
+	return len(c.masterKey) != 0
 }
 
-// GetRecord gets record by recordID and decodes it.
-func (c *client) GetRecord(recordID string) (entity.Record, error) {
+// setSession keeps tokens of session.
+func (c *client) setSession(session entity.Session) {
+	c.authToken, c.refreshToken, c.expiresAt = session.Token, session.RefreshToken, session.ExpiresAt
+}
+
+// forget forgets session, encryption key and keys of vaults.
+func (c *client) forget() {
+	c.setSession(entity.Session{})
+	c.masterKey, c.legacy = nil, false
+	c.vaultKeys = make(map[string][]byte)
+}
+
+// writable checks that records aren't encrypted by legacy key, which anyone can compute,
+// so nothing new is encrypted by it.
+func (c *client) writable() error {
+	if c.legacy {
+		return controller.ErrLegacyKey
+	}
+
+	return nil
+}
+
+// renew refreshes session, if its token expires soon. Errors are only logged:
+// request with expired token fails itself and user has to login again.
+func (c *client) renew() {
+	if c.refreshToken == "" || !expiring(c.expiresAt) {
+		return
+	}
+
+	session, err := c.conn.RefreshSession(c.refreshToken)
+	if err != nil {
+		log.Infoln(err)
+
+		return
+	}
+
+	c.setSession(session)
+}
+
+// expiring checks if session token expires soon. Unknown expiration time never comes.
+func expiring(expiresAt time.Time) bool {
+	return !expiresAt.IsZero() && time.Until(expiresAt) < sessionRefreshMargin
+}
+
+// Conflicts gets offline changes, which conflicted with changes of other devices or were rejected by server,
+// with decrypted labels. Connection, which doesn't sync offline changes, has no conflicts.
+func (c *client) Conflicts() ([]entity.SyncConflict, error) {
 	c.Lock()
 	defer c.Unlock()
 
-	record, errGetRecord := c.conn.GetRecord(c.authToken, recordID)
-	if errGetRecord != nil {
-		log.Infoln(errGetRecord)
+	if len(c.masterKey) == 0 {
+		return nil, controller.ErrLocked
+	}
 
-		return record, errGetRecord
+	synced, ok := c.conn.(SyncedConnection)
+	if !ok {
+		return nil, nil
 	}
 
-	aesBlock, errNewCipher := aes.NewCipher(c.masterKey)
-	if errNewCipher != nil {
-		log.Infoln(errNewCipher)
+	conflicts := synced.Conflicts()
+	for i, conflict := range conflicts {
+		// Labels in clear are shown as is, they may be saved before metadata encryption was on.
+		record, err := openRecordLabels(c.masterKey, entity.Record{Metadata: conflict.Metadata, Name: conflict.Name}, true)
+		if err != nil {
+			return nil, err
+		}
 
-		return record, controller.ErrWrongMasterKey
+		conflicts[i].Metadata, conflicts[i].Name = record.Metadata, record.Name
 	}
 
-	aesGCM, errNewGCM := cipher.NewGCM(aesBlock)
-	if errNewGCM != nil {
-		log.Infoln(errNewGCM)
+	return conflicts, nil
+}
 
-		return record, storage.ErrUnknown
+// setConnKey passes record key to connection, if it keeps local encrypted data.
+func (c *client) setConnKey(key []byte) error {
+	keyed, ok := c.conn.(KeyedConnection)
+	if !ok {
+		return nil
+	}
+
+	if err := keyed.SetKey(key); err != nil {
+		log.Warnf("%s :: %v", "open local store fault", err)
+
+		return err
 	}
 
-	nonce := record.Data[:aesGCM.NonceSize()]
+	return nil
+}
 
-	decoded, err := aesGCM.Open(nil, nonce, record.Data[aesGCM.NonceSize():], nil)
+// deriveKey derives encryption key from master key. Empty salt is rejected, legacy key is used only for users,
+// which server marks as legacy, so server can't downgrade key of other users.
+func deriveKey(masterKey []byte, params entity.KDFParams) ([]byte, error) {
+	key, err := pkg.DeriveKey(masterKey, params)
 	if err != nil {
-		log.Warnf("%s :: %v", "open aesgcm fault", err)
+		log.Warnf("%s :: %v", "derive key fault", err)
 
-		return record, storage.ErrUnknown
+		return nil, pkg.ErrBadKDFParams
 	}
 
-	record.Data = decoded
+	return key, nil
+}
 
-	if record.Type == entity.TypeFile {
-		file, err := os.Create(record.Metadata)
+// legacyKey returns key of users, who were registered before KDF parameters were introduced.
+// Old clients hashed key field before it was set, so their records are encrypted with SHA-256 of empty data.
+func legacyKey() []byte {
+	key := sha256.Sum256(nil)
+
+	return key[:]
+}
+
+// upgradeKey encrypts records of legacy user by key derived from master key and finishes upgrade on server.
+// New parameters are saved on server before records are encrypted, so upgrade can be run again
+// after failure: records, which are already encrypted by new key, are left as is.
+// Records are encrypted by legacy key, while it's kept as key of client. Returns new key.
+func (c *client) upgradeKey(masterKey []byte, params entity.KDFParams) ([]byte, error) {
+	if len(params.Salt) == 0 {
+		generated, err := pkg.NewKDFParams()
 		if err != nil {
-			log.Warnf("%s :: %v", "create metadata-file fault", err)
+			log.Warnf("%s :: %v", "generate kdf params fault", err)
+
+			return nil, storage.ErrUnknown
+		}
+		generated.Legacy = true
+
+		if err = c.conn.UpgradeKDF(c.authToken, generated); err != nil {
+			return nil, err
+		}
+
+		params = generated
+	}
+
+	key, err := deriveKey(masterKey, params)
+	if err != nil {
+		return nil, err
+	}
 
-			return record, storage.ErrUnknown
+	records, err := c.conn.GetRecordsInfo(c.authToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.Type == entity.TypeFile {
+			err = c.upgradeFile(key, record)
+		} else {
+			err = c.upgradeRecord(key, record.ID)
 		}
 
-		_, err = file.Write(record.Data)
 		if err != nil {
-			log.Warnf("%s :: %v", "write in file fault", err)
+			return nil, err
+		}
+	}
+
+	params.Legacy = false
+	if err = c.conn.UpgradeKDF(c.authToken, params); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// upgradeRecord encrypts data and labels of record by new key, if they are encrypted by legacy key.
+// Data, which can't be decrypted by any of keys, means that master key differs from one,
+// which records were encrypted by before upgrade was interrupted.
+func (c *client) upgradeRecord(key []byte, recordID string) error {
+	record, err := c.conn.GetRecord(c.authToken, recordID)
+	if err != nil {
+		return err
+	}
 
-			return record, storage.ErrUnknown
+	data, err := decryptData(c.masterKey, record.Data)
+	if err != nil {
+		if _, errNew := decryptData(key, record.Data); errNew == nil {
+			return nil
 		}
-		record.Data = []byte("Saved file successfully to " + record.Metadata + ".")
+
+		return controller.ErrWrongMasterKey
 	}
 
-	return record, nil
+	if record, err = c.upgradeLabels(key, record); err != nil {
+		return err
+	}
+
+	if record.Data, err = pkg.EncryptBytes(key, data); err != nil {
+		log.Infoln(err)
+
+		return cryptoError(err)
+	}
+
+	return c.conn.UpdateRecord(c.authToken, record)
+}
+
+// upgradeFile uploads file record again encrypted by new key and deletes old record. File is decrypted
+// by legacy key and encrypted by new key by chunks into temporary file, so only encrypted file is on disk.
+// Files, which are already encrypted by new key, are left as is.
+func (c *client) upgradeFile(key []byte, record entity.Record) error {
+	upgraded, err := c.upgradeLabels(key, record)
+	if err != nil {
+		return err
+	}
+	upgraded.ID, upgraded.Revision = "", 0
+
+	file, err := os.CreateTemp("", "upgrade-*")
+	if err != nil {
+		log.Warnf("%s :: %v", "create temporary file fault", err)
+
+		return storage.ErrUnknown
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	pr, pw := io.Pipe()
+	encrypted := make(chan error, 1)
+
+	go func() {
+		reader, err := pkg.NewEncryptReader(pr, key)
+		if err == nil {
+			_, err = io.Copy(file, reader)
+		}
+
+		pr.CloseWithError(err)
+		encrypted <- err
+	}()
+
+	errDownload := c.download(c.masterKey, record.ID, pw)
+	pw.CloseWithError(errDownload)
+	errEncrypt := <-encrypted
+
+	if errDownload != nil {
+		if c.download(key, record.ID, io.Discard) == nil {
+			return nil
+		}
+
+		return errDownload
+	}
+	if errEncrypt != nil {
+		log.Warnf("%s :: %v", "encrypt file fault", errEncrypt)
+
+		return cryptoError(errEncrypt)
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		log.Warnf("%s :: %v", "rewind temporary file fault", err)
+
+		return storage.ErrUnknown
+	}
+
+	if _, err = c.conn.UploadFile(c.authToken, upgraded, file); err != nil {
+		return err
+	}
+
+	return c.conn.DeleteRecord(c.authToken, record.ID, record.Revision)
+}
+
+// upgradeLabels encrypts labels of record by new key, if they were encrypted by legacy key
+// or metadata encryption is on. Labels in clear are left as is otherwise.
+func (c *client) upgradeLabels(key []byte, record entity.Record) (entity.Record, error) {
+	sealed := hasSealedLabels(record)
+
+	record, err := openRecordLabels(c.masterKey, record, true)
+	if err != nil {
+		return record, err
+	}
+
+	record.Tags, record.Folder = entity.NormalizeTags(record.Tags), entity.NormalizeFolder(record.Folder)
+	if !sealed && !c.encryptMetadata {
+		record.BlindIndex = nil
+
+		return record, nil
+	}
+
+	return sealRecordLabels(key, record, pkg.BlindIndex(key, record.Name+" "+record.Metadata))
 }
 
-// DeleteRecord deletes record by his ID.
-func (c *client) DeleteRecord(recordID string) error {
+// GetRecordsInfo gets all records. Encrypted metadata is decrypted.
+func (c *client) GetRecordsInfo() ([]entity.Record, error) {
 	c.Lock()
 	defer c.Unlock()
 
-	return c.conn.DeleteRecord(c.authToken, recordID)
+	c.renew()
+
+	records, err := c.conn.GetRecordsInfo(c.authToken)
+	if err != nil {
+		return records, err
+	}
+
+	return c.openRecords(records)
 }
 
-// CreateRecord creates new record.
-func (c *client) CreateRecord(record entity.Record) error {
+// ListRecords gets page of records, which match query. Search words are sent to server as blind index too,
+// so records with encrypted metadata are found by whole words.
+// When metadata encryption is on, server can't sort records by name, so all matching records are got
+// and page is made by client.
+func (c *client) ListRecords(query entity.RecordsQuery) (entity.RecordsPage, error) {
 	c.Lock()
 	defer c.Unlock()
 
-	aesBlock, errNewCipher := aes.NewCipher(c.masterKey)
+	c.renew()
 
-	if errNewCipher != nil {
-		log.Infoln(errNewCipher)
+	query = query.Normalize()
+	query.BlindIndex = pkg.BlindIndex(c.masterKey, query.Search)
 
-		return controller.ErrWrongMasterKey
+	if !c.encryptMetadata {
+		page, err := c.conn.ListRecords(c.authToken, query)
+		if err != nil {
+			return page, err
+		}
+
+		page.Records, err = c.openRecords(page.Records)
+
+		return page, err
 	}
 
-	aesGCM, errNewGCM := cipher.NewGCM(aesBlock)
-	if errNewGCM != nil {
-		log.Infoln(errNewGCM)
+	// Server filters by encrypted tags and folder, search and page are applied to decrypted records.
+	all, err := c.sealQuery(query)
+	if err != nil {
+		return entity.RecordsPage{}, err
+	}
+	all.Offset, all.Limit = 0, entity.RecordsMaxLimit
 
-		return storage.ErrUnknown
+	var records []entity.Record
+	for {
+		page, err := c.conn.ListRecords(c.authToken, all)
+		if err != nil {
+			return entity.RecordsPage{}, err
+		}
+
+		records = append(records, page.Records...)
+		all.Offset += int32(len(page.Records))
+
+		if len(page.Records) == 0 || all.Offset >= page.Total {
+			break
+		}
 	}
 
-	nonce, errGenerateRandom := pkg.GenerateRandom(aesGCM.NonceSize())
-	if errGenerateRandom != nil {
-		log.Infoln(errGenerateRandom)
+	records, err = c.openRecords(records)
+	if err != nil {
+		return entity.RecordsPage{}, err
+	}
 
-		return storage.ErrUnknown
+	return query.Apply(records), nil
+}
+
+// sealQuery encrypts tags and folder of query like sealLabels encrypts them in records.
+func (c *client) sealQuery(query entity.RecordsQuery) (entity.RecordsQuery, error) {
+	var tags []string
+	for _, tag := range query.Tags {
+		encrypted, err := pkg.EncryptLabel(c.masterKey, tag)
+		if err != nil {
+			return query, cryptoError(err)
+		}
+		tags = append(tags, encrypted)
+	}
+
+	folder, err := pkg.EncryptFolder(c.masterKey, query.Folder)
+	if err != nil {
+		return query, cryptoError(err)
 	}
-	// Encryption:
-	out := aesGCM.Seal(nil, nonce, record.Data, nil)
 
-	record.Data = append(nonce, out...)
+	query.Tags, query.Folder = tags, folder
 
-	return c.conn.CreateRecord(c.authToken, record)
+	return query, nil
+}
+
+// sealLabels encrypts metadata, name, tags and folder of record and makes blind index of words
+// of metadata and name, if metadata encryption is on. Tags and folder must be normalized.
+func (c *client) sealLabels(record entity.Record) (entity.Record, error) {
+	record.BlindIndex = nil
+	if !c.encryptMetadata {
+		return record, nil
+	}
+
+	return sealRecordLabels(c.masterKey, record, pkg.BlindIndex(c.masterKey, record.Name+" "+record.Metadata))
+}
+
+// sealRecordLabels encrypts metadata, name, tags and folder of record by key and sets blind index.
+func sealRecordLabels(key []byte, record entity.Record, index []string) (entity.Record, error) {
+	metadata, err := pkg.EncryptMetadata(key, record.Metadata)
+	if err != nil {
+		log.Warnf("%s :: %v", "encrypt metadata fault", err)
+
+		return record, cryptoError(err)
+	}
+
+	name, err := pkg.EncryptMetadata(key, record.Name)
+	if err != nil {
+		log.Warnf("%s :: %v", "encrypt name fault", err)
+
+		return record, cryptoError(err)
+	}
+
+	tags := make([]string, 0, len(record.Tags))
+	for _, tag := range record.Tags {
+		encrypted, err := pkg.EncryptLabel(key, tag)
+		if err != nil {
+			log.Warnf("%s :: %v", "encrypt tag fault", err)
+
+			return record, cryptoError(err)
+		}
+		tags = append(tags, encrypted)
+	}
+
+	folder, err := pkg.EncryptFolder(key, record.Folder)
+	if err != nil {
+		log.Warnf("%s :: %v", "encrypt folder fault", err)
+
+		return record, cryptoError(err)
+	}
+
+	record.Metadata, record.Name, record.BlindIndex = metadata, name, index
+	record.Tags, record.Folder = entity.NormalizeTags(tags), folder
+
+	return record, nil
+}
+
+// openLabels decrypts metadata, name, tags and folder of record. Labels in clear are accepted only
+// if metadata encryption is off, otherwise server could substitute them; MigrateLabels encrypts them.
+func (c *client) openLabels(record entity.Record) (entity.Record, error) {
+	return openRecordLabels(c.masterKey, record, !c.encryptMetadata)
+}
+
+// openRecordLabels decrypts labels of record by key. If plain is set, labels in clear are returned as is.
+func openRecordLabels(key []byte, record entity.Record, plain bool) (entity.Record, error) {
+	metadata, err := openLabel(key, record.Metadata, plain, pkg.IsEncryptedMetadata, pkg.DecryptMetadata)
+	if err != nil {
+		log.Warnf("%s :: %v", "decrypt metadata fault", err)
+
+		return record, cryptoError(err)
+	}
+
+	name, err := openLabel(key, record.Name, plain, pkg.IsEncryptedMetadata, pkg.DecryptMetadata)
+	if err != nil {
+		log.Warnf("%s :: %v", "decrypt name fault", err)
+
+		return record, cryptoError(err)
+	}
+
+	var tags []string
+	for _, tag := range record.Tags {
+		decrypted, err := openLabel(key, tag, plain, pkg.IsEncryptedLabel, pkg.DecryptLabel)
+		if err != nil {
+			log.Warnf("%s :: %v", "decrypt tag fault", err)
+
+			return record, cryptoError(err)
+		}
+		tags = append(tags, decrypted)
+	}
+
+	folder, err := openLabel(key, record.Folder, plain, pkg.IsEncryptedLabel, pkg.DecryptFolder)
+	if err != nil {
+		log.Warnf("%s :: %v", "decrypt folder fault", err)
+
+		return record, cryptoError(err)
+	}
+
+	record.Metadata, record.Name = metadata, name
+	record.Tags, record.Folder = entity.NormalizeTags(tags), folder
+
+	return record, nil
+}
+
+// openLabel decrypts label, label in clear is returned as is, if plain is set.
+func openLabel(
+	key []byte, label string, plain bool,
+	encrypted func(label string) bool, decrypt func(key []byte, label string) (string, error),
+) (string, error) {
+	if plain && !encrypted(label) {
+		return label, nil
+	}
+
+	return decrypt(key, label)
+}
+
+// hasSealedLabels checks if some label of record is encrypted.
+func hasSealedLabels(record entity.Record) bool {
+	for _, metadata := range []string{record.Metadata, record.Name} {
+		if pkg.IsEncryptedMetadata(metadata) {
+			return true
+		}
+	}
+
+	for _, label := range append([]string{record.Folder}, record.Tags...) {
+		if pkg.IsEncryptedLabel(label) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasPlainLabels checks if some label of record is in clear.
+func hasPlainLabels(record entity.Record) bool {
+	for _, metadata := range []string{record.Metadata, record.Name} {
+		if metadata != "" && !pkg.IsEncryptedMetadata(metadata) {
+			return true
+		}
+	}
+
+	for _, label := range append([]string{record.Folder}, record.Tags...) {
+		if label != "" && !pkg.IsEncryptedLabel(label) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MigrateLabels encrypts labels of records, which were saved in clear before metadata encryption was on,
+// and tags and folders of vault records, which were shared before they were encrypted. It's the only place,
+// where labels in clear are accepted with encryption on. Returns IDs of migrated records.
+// Migration can be run again after failure, already encrypted records are left as is.
+func (c *client) MigrateLabels() ([]string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	if err := c.writable(); err != nil {
+		return nil, err
+	}
+
+	migrated := make([]string, 0)
+	if !c.encryptMetadata {
+		return c.migrateVaultLabels(migrated)
+	}
+
+	records, err := c.conn.GetRecordsInfo(c.authToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		if !hasPlainLabels(record) {
+			continue
+		}
+
+		if record.Type != entity.TypeFile {
+			// Update replaces data too, so encrypted data is got as is.
+			if record, err = c.conn.GetRecord(c.authToken, record.ID); err != nil {
+				return migrated, err
+			}
+		}
+
+		if record, err = openRecordLabels(c.masterKey, record, true); err != nil {
+			return migrated, err
+		}
+
+		record.Tags, record.Folder = entity.NormalizeTags(record.Tags), entity.NormalizeFolder(record.Folder)
+		if record, err = c.sealLabels(record); err != nil {
+			return migrated, err
+		}
+
+		if err = c.conn.UpdateRecord(c.authToken, record); err != nil {
+			return migrated, err
+		}
+
+		migrated = append(migrated, record.ID)
+	}
+
+	return c.migrateVaultLabels(migrated)
+}
+
+// migrateVaultLabels encrypts tags and folders in clear of records of vaults, which user can write to.
+func (c *client) migrateVaultLabels(migrated []string) ([]string, error) {
+	vaults, err := c.conn.GetVaults(c.authToken)
+	if err != nil {
+		return migrated, err
+	}
+
+	for _, vault := range vaults {
+		if vault.Role < entity.RoleReadWrite {
+			continue
+		}
+
+		vaultKey, err := c.openVaultKey(vault)
+		if err != nil {
+			return migrated, err
+		}
+
+		records, err := c.conn.GetVaultRecords(c.authToken, vault.ID)
+		if err != nil {
+			return migrated, err
+		}
+
+		for _, record := range records {
+			if !hasPlainLabels(record) {
+				continue
+			}
+
+			if record, err = c.conn.GetVaultRecord(c.authToken, vault.ID, record.ID); err != nil {
+				return migrated, err
+			}
+
+			if record, err = openVaultRecord(vaultKey, record, true); err != nil {
+				return migrated, err
+			}
+
+			if record, err = sealVaultRecord(vaultKey, record); err != nil {
+				return migrated, err
+			}
+
+			if err = c.conn.UpdateVaultRecord(c.authToken, record); err != nil {
+				return migrated, err
+			}
+
+			migrated = append(migrated, record.ID)
+		}
+	}
+
+	return migrated, nil
+}
+
+// openRecords decrypts metadata and names of records.
+func (c *client) openRecords(records []entity.Record) ([]entity.Record, error) {
+	for i := range records {
+		record, err := c.openLabels(records[i])
+		if err != nil {
+			return nil, err
+		}
+
+		records[i] = record
+	}
+
+	return records, nil
+}
+
+// GetRecord gets record by recordID and decodes it.
+func (c *client) GetRecord(recordID string) (entity.Record, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	record, errGetRecord := c.conn.GetRecord(c.authToken, recordID)
+	if errGetRecord != nil {
+		log.Infoln(errGetRecord)
+
+		return record, errGetRecord
+	}
+
+	record, err := c.openLabels(record)
+	if err != nil {
+		return record, err
+	}
+
+	if record.Type == entity.TypeFile {
+		// File data isn't sent by GetRecord, it's downloaded and decrypted by chunks.
+		return c.downloadFile(recordID, record)
+	}
+
+	decoded, err := decryptData(c.masterKey, record.Data)
+	if err != nil {
+		return record, err
+	}
+
+	record.Data = decoded
+
+	return record, nil
+}
+
+// GetRecordInfo gets record with decrypted labels, but without data, so caller chooses by type of record,
+// how data is got.
+func (c *client) GetRecordInfo(recordID string) (entity.Record, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	record, err := c.conn.GetRecord(c.authToken, recordID)
+	if err != nil {
+		log.Infoln(err)
+
+		return record, err
+	}
+
+	if record, err = c.openLabels(record); err != nil {
+		return record, err
+	}
+
+	record.Data = nil
+
+	return record, nil
+}
+
+// GetFile downloads file record and writes decrypted file to w. Record is returned without data.
+func (c *client) GetFile(recordID string, w io.Writer) (entity.Record, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	record, err := c.conn.GetRecord(c.authToken, recordID)
+	if err != nil {
+		log.Infoln(err)
+
+		return record, err
+	}
+
+	if record, err = c.openLabels(record); err != nil {
+		return record, err
+	}
+
+	if record.Type != entity.TypeFile {
+		return record, storage.ErrNotSupported
+	}
+
+	if err = c.download(c.masterKey, recordID, w); err != nil {
+		log.Warnf("%s :: %v", "download file fault", err)
+	}
+
+	return record, err
+}
+
+// downloadFile downloads file record, decrypts it by chunks and saves to file named as record metadata.
+func (c *client) downloadFile(recordID string, record entity.Record) (entity.Record, error) {
+	return saveFile(record, func(w io.Writer) error {
+		err := c.download(c.masterKey, recordID, w)
+		if err != nil {
+			log.Warnf("%s :: %v", "download file fault", err)
+		}
+
+		return err
+	})
+}
+
+// saveFile saves file record to working directory by name of file of record, file is written by write.
+// File is removed, if it isn't written.
+func saveFile(record entity.Record, write func(w io.Writer) error) (entity.Record, error) {
+	name, err := fileName(record.Metadata)
+	if err != nil {
+		log.Warnf("%s :: %q", "bad file name of record", record.Metadata)
+
+		return record, err
+	}
+
+	file, err := os.Create(name)
+	if err != nil {
+		log.Warnf("%s :: %v", "create metadata-file fault", err)
+
+		return record, storage.ErrUnknown
+	}
+	defer file.Close()
+
+	if err = write(file); err != nil {
+		if errRemove := os.Remove(name); errRemove != nil {
+			log.Infoln(errRemove)
+		}
+
+		return record, err
+	}
+
+	record.Data = []byte("Saved file successfully to " + name + ".")
+
+	return record, nil
+}
+
+// fileName gets name of file, which file record is saved to: base name of metadata, which is path of uploaded file.
+// Metadata comes from server, so paths with ".." and names, which aren't names of files, are rejected.
+func fileName(metadata string) (string, error) {
+	path := strings.ReplaceAll(metadata, `\`, "/")
+	for _, part := range strings.Split(path, "/") {
+		if part == ".." {
+			return "", controller.ErrBadFileName
+		}
+	}
+
+	name := filepath.Base(path)
+	if name == "." || name == "/" || strings.TrimSpace(name) == "" {
+		return "", controller.ErrBadFileName
+	}
+
+	return name, nil
+}
+
+// download downloads file record and decrypts it by key by chunks to w.
+func (c *client) download(key []byte, recordID string, w io.Writer) error {
+	writer, err := pkg.NewDecryptWriter(w, key)
+	if err != nil {
+		log.Infoln(err)
+
+		return controller.ErrWrongMasterKey
+	}
+
+	_, errDownload := c.conn.DownloadFile(c.authToken, recordID, writer)
+	errDecrypt := writer.Close()
+
+	if errors.Is(errDecrypt, pkg.ErrNotEnvelope) {
+		// File was saved by old client in legacy format, it can be decrypted only as a whole.
+		var buf bytes.Buffer
+
+		_, errDownload = c.conn.DownloadFile(c.authToken, recordID, &buf)
+		if errDownload == nil {
+			var decoded []byte
+
+			decoded, errDecrypt = decryptLegacy(key, buf.Bytes())
+			if errDecrypt == nil {
+				_, errDecrypt = w.Write(decoded)
+			}
+		}
+	}
+
+	return downloadError(errDownload, errDecrypt)
+}
+
+// downloadError chooses error to return after downloading: decryption errors are more important,
+// but truncated envelope is expected, when download was interrupted.
+func downloadError(errDownload, errDecrypt error) error {
+	switch {
+	case errDecrypt != nil && !errors.Is(errDecrypt, pkg.ErrEnvelopeTruncated):
+		return cryptoError(errDecrypt)
+	case errDownload != nil:
+		return errDownload
+	case errDecrypt != nil:
+		return cryptoError(errDecrypt)
+	}
+
+	return nil
+}
+
+// decryptData decrypts record data by key. Data can be in envelope format or in legacy format.
+func decryptData(key, data []byte) ([]byte, error) {
+	if !pkg.IsEnvelope(data) {
+		return decryptLegacy(key, data)
+	}
+
+	decoded, err := pkg.DecryptBytes(key, data)
+	if err != nil {
+		log.Warnf("%s :: %v", "decrypt envelope fault", err)
+
+		return nil, cryptoError(err)
+	}
+
+	return decoded, nil
+}
+
+// decryptLegacy decrypts data sealed by one AES-GCM call with nonce prefix.
+func decryptLegacy(key, data []byte) ([]byte, error) {
+	aesBlock, errNewCipher := aes.NewCipher(key)
+	if errNewCipher != nil {
+		log.Infoln(errNewCipher)
+
+		return nil, controller.ErrWrongMasterKey
+	}
+
+	aesGCM, errNewGCM := cipher.NewGCM(aesBlock)
+	if errNewGCM != nil {
+		log.Infoln(errNewGCM)
+
+		return nil, storage.ErrUnknown
+	}
+
+	if len(data) < aesGCM.NonceSize() {
+		return nil, controller.ErrDataCorrupted
+	}
+
+	nonce := data[:aesGCM.NonceSize()]
+
+	decoded, err := aesGCM.Open(nil, nonce, data[aesGCM.NonceSize():], nil)
+	if err != nil {
+		log.Warnf("%s :: %v", "open aesgcm fault", err)
+
+		return nil, storage.ErrUnknown
+	}
+
+	return decoded, nil
+}
+
+// cryptoError converts encryption errors to handlers errors.
+func cryptoError(err error) error {
+	var keySizeError aes.KeySizeError
+
+	switch {
+	case errors.As(err, &keySizeError):
+		return controller.ErrWrongMasterKey
+	case errors.Is(err, pkg.ErrEnvelopeCorrupted),
+		errors.Is(err, pkg.ErrEnvelopeTruncated),
+		errors.Is(err, pkg.ErrEnvelopeVersion),
+		errors.Is(err, pkg.ErrNotEnvelope),
+		errors.Is(err, pkg.ErrBadEncryptedMetadata),
+		errors.Is(err, pkg.ErrBadWrappedKey):
+		return controller.ErrDataCorrupted
+	case errors.Is(err, pkg.ErrPlainMetadata):
+		return controller.ErrPlainLabels
+	}
+
+	return err
+}
+
+// DeleteRecord deletes record by his ID. If record was changed on another device after revision,
+// ErrConflict is returned, zero revision deletes record anyway.
+func (c *client) DeleteRecord(recordID string, revision int64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	return c.conn.DeleteRecord(c.authToken, recordID, revision)
+}
+
+// CreateRecord creates new record. Data is encrypted in envelope format, file records are encrypted
+// and uploaded by chunks from record body (or data, if body is empty).
+// Metadata and name are encrypted too, if metadata encryption is on. Returns ID of created record.
+func (c *client) CreateRecord(record entity.Record) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	if err := c.writable(); err != nil {
+		return "", err
+	}
+
+	record.Tags, record.Folder = entity.NormalizeTags(record.Tags), entity.NormalizeFolder(record.Folder)
+
+	record, err := c.sealLabels(record)
+	if err != nil {
+		return "", err
+	}
+
+	if record.Type == entity.TypeFile {
+		body := record.Body
+		if body == nil {
+			body = bytes.NewReader(record.Data)
+		}
+
+		reader, err := pkg.NewEncryptReader(body, c.masterKey)
+		if err != nil {
+			log.Infoln(err)
+
+			return "", cryptoError(err)
+		}
+
+		record.Data, record.Body = nil, nil
+
+		return c.conn.UploadFile(c.authToken, record, reader)
+	}
+
+	encrypted, err := pkg.EncryptBytes(c.masterKey, record.Data)
+	if err != nil {
+		log.Infoln(err)
+
+		return "", cryptoError(err)
+	}
+
+	record.Data = encrypted
+
+	return c.conn.CreateRecord(c.authToken, record)
+}
+
+// UpdateRecord encrypts new data of record and replaces it on server. File records can't be updated.
+// If record was changed on another device after record revision, ErrConflict is returned,
+// zero revision replaces record anyway.
+func (c *client) UpdateRecord(record entity.Record) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	if record.Type == entity.TypeFile {
+		return storage.ErrNotSupported
+	}
+
+	if err := c.writable(); err != nil {
+		return err
+	}
+
+	record.Tags, record.Folder = entity.NormalizeTags(record.Tags), entity.NormalizeFolder(record.Folder)
+
+	record, err := c.sealLabels(record)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := pkg.EncryptBytes(c.masterKey, record.Data)
+	if err != nil {
+		log.Infoln(err)
+
+		return cryptoError(err)
+	}
+
+	record.Data = encrypted
+
+	return c.conn.UpdateRecord(c.authToken, record)
+}
+
+// GetRecordVersions gets previous versions of record, newest first. Encrypted metadata is decrypted.
+func (c *client) GetRecordVersions(recordID string) ([]entity.RecordVersion, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	versions, err := c.conn.GetRecordVersions(c.authToken, recordID)
+	if err != nil {
+		return versions, err
+	}
+
+	for i := range versions {
+		metadata, err := openLabel(c.masterKey, versions[i].Metadata, !c.encryptMetadata, pkg.IsEncryptedMetadata, pkg.DecryptMetadata)
+		if err != nil {
+			log.Warnf("%s :: %v", "decrypt metadata fault", err)
+
+			return nil, cryptoError(err)
+		}
+
+		versions[i].Metadata = metadata
+	}
+
+	return versions, nil
+}
+
+// RestoreRecordVersion makes previous version of record current. If record was changed on another device
+// after revision, storage.ErrConflict is returned. Zero revision restores version anyway.
+func (c *client) RestoreRecordVersion(recordID string, version int32, revision int64) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	return c.conn.RestoreRecordVersion(c.authToken, recordID, version, revision)
 }
@@ -2,16 +2,20 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"io"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/controller"
 	"github.com/bbt-t/lets-go-keep/internal/entity"
 	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
 	pb "github.com/bbt-t/lets-go-keep/protocols/grpc"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -22,9 +26,14 @@ type ClientConnGPRC struct {
 	pb.GophkeeperClient
 }
 
-// NewClientConnection connects to server and returning connection.
-func newClientConn(serverAddress string) *ClientConnGPRC {
-	conn, err := grpc.Dial(serverAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewClientConnection connects to server with transport credentials and returning connection.
+// Unary requests are canceled after timeout, zero timeout means no limit.
+func newClientConn(serverAddress string, creds credentials.TransportCredentials, timeout time.Duration) *ClientConnGPRC {
+	conn, err := grpc.Dial(
+		serverAddress,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUnaryInterceptor(timeoutInterceptor(timeout)),
+	)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -34,87 +43,169 @@ func newClientConn(serverAddress string) *ClientConnGPRC {
 	}
 }
 
+// timeoutInterceptor limits time of unary requests. File streams aren't limited, they can be long.
+func timeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
 // Login logins user by login and password.
-func (c *ClientConnGPRC) Login(credentials entity.UserCredentials) (string, error) {
+func (c *ClientConnGPRC) Login(credentials entity.UserCredentials) (entity.Session, error) {
 	session, err := c.GophkeeperClient.Login(context.Background(), &pb.UserCredentials{
 		Login:    credentials.Login,
 		Password: credentials.Password,
 	})
 
 	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return entity.Session{}, controller.ErrServerUnavailable
 	case codes.Unauthenticated:
-		return "", storage.ErrWrongCredentials
+		return entity.Session{}, storage.ErrWrongCredentials
 	case codes.Internal:
-		return "", storage.ErrUnknown
+		return entity.Session{}, storage.ErrUnknown
 	case codes.InvalidArgument:
-		return "", controller.ErrFieldIsEmpty
+		return entity.Session{}, controller.ErrFieldIsEmpty
 	}
 
 	if err != nil {
 		log.Warnf("%s :: %v", "login fault", err)
 
-		return "", err
+		return entity.Session{}, err
 	}
 
-	return session.SessionToken, nil
+	return sessionFromProto(session), nil
 }
 
-// Register creates new user by login and password.
-func (c *ClientConnGPRC) Register(credentials entity.UserCredentials) (string, error) {
+// Register creates new user by login and password. Sends key derivation parameters of user.
+func (c *ClientConnGPRC) Register(credentials entity.UserCredentials) (entity.Session, error) {
 	session, err := c.GophkeeperClient.Register(context.Background(), &pb.UserCredentials{
-		Login:    credentials.Login,
-		Password: credentials.Password,
+		Login:     credentials.Login,
+		Password:  credentials.Password,
+		Kdf:       kdfParamsToProto(credentials.KDF),
+		PublicKey: credentials.PublicKey,
 	})
 
 	code := status.Code(err)
 
 	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return entity.Session{}, controller.ErrServerUnavailable
 	case codes.AlreadyExists:
-		return "", storage.ErrLoginExists
+		return entity.Session{}, storage.ErrLoginExists
 	case codes.Internal:
-		return "", storage.ErrUnknown
+		return entity.Session{}, storage.ErrUnknown
 	case codes.InvalidArgument:
-		return "", controller.ErrFieldIsEmpty
+		return entity.Session{}, registerArgumentError(err)
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "register fault", err)
+
+		return entity.Session{}, err
 	}
 
-	return session.SessionToken, nil
+	return sessionFromProto(session), nil
+}
+
+// registerArgumentError tells by status message, which argument of register server rejected.
+func registerArgumentError(err error) error {
+	switch status.Convert(err).Message() {
+	case statusBadKDFParams:
+		return pkg.ErrBadKDFParams
+	case statusBadPublicKey:
+		return controller.ErrBadPublicKey
+	default:
+		return controller.ErrFieldIsEmpty
+	}
 }
 
 // GetRecordsInfo gets all record.
 func (c *ClientConnGPRC) GetRecordsInfo(token entity.AuthToken) ([]entity.Record, error) {
-	ctx := metadata.AppendToOutgoingContext(context.Background(), "authToken", string(token))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
 	gotRecords, err := c.GophkeeperClient.GetRecordsInfo(ctx, &emptypb.Empty{})
 	code := status.Code(err)
 
 	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return nil, controller.ErrServerUnavailable
 	case codes.Internal:
 		return nil, storage.ErrUnknown
 	case codes.Unauthenticated:
 		return nil, storage.ErrUnauthenticated
 	}
 
+	if err != nil {
+		log.Warnf("%s :: %v", "get records info fault", err)
+
+		return nil, storage.ErrUnknown
+	}
+
 	records := make([]entity.Record, 0, len(gotRecords.Records))
 
 	for _, record := range gotRecords.Records {
 		records = append(records, entity.Record{
-			ID:       record.Id,
-			Metadata: record.Metadata,
-			Type:     entity.RecordType(record.Type),
+			ID:         record.Id,
+			Metadata:   record.Metadata,
+			Type:       entity.RecordType(record.Type),
+			Revision:   record.Revision,
+			UpdatedAt:  timestampFromProto(record.UpdatedAt),
+			Name:       record.Name,
+			Tags:       record.Tags,
+			Folder:     record.Folder,
+			BlindIndex: record.BlindIndex,
 		})
 	}
 
 	return records, nil
 }
 
+// ListRecords gets page of records, which match query, without data.
+func (c *ClientConnGPRC) ListRecords(token entity.AuthToken, query entity.RecordsQuery) (entity.RecordsPage, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	page, err := c.GophkeeperClient.ListRecords(ctx, recordsQueryToProto(query))
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return entity.RecordsPage{}, controller.ErrServerUnavailable
+	case codes.OK:
+	case codes.Unauthenticated:
+		return entity.RecordsPage{}, storage.ErrUnauthenticated
+	case codes.InvalidArgument:
+		return entity.RecordsPage{}, entity.ErrBadQuery
+	default:
+		log.Warnf("%s :: %v", "list records fault", err)
+
+		return entity.RecordsPage{}, storage.ErrUnknown
+	}
+
+	return recordsPageFromProto(page), nil
+}
+
 // GetRecord gets record from server by ID.
 func (c *ClientConnGPRC) GetRecord(token entity.AuthToken, recordID string) (entity.Record, error) {
-	ctx := metadata.AppendToOutgoingContext(context.Background(), "authToken", string(token))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
 	gotRecord, err := c.GophkeeperClient.GetRecord(ctx, &pb.RecordID{
 		Id: recordID,
 	})
 	record, code := entity.Record{}, status.Code(err)
 
 	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return record, controller.ErrServerUnavailable
 	case codes.Internal:
 		return record, storage.ErrUnknown
 	case codes.Unauthenticated:
@@ -123,50 +214,392 @@ func (c *ClientConnGPRC) GetRecord(token entity.AuthToken, recordID string) (ent
 		return record, storage.ErrNotFound
 	}
 
-	record = entity.Record{
-		ID:       gotRecord.Id,
-		Metadata: gotRecord.Metadata,
-		Type:     entity.RecordType(gotRecord.Type),
-		Data:     gotRecord.StoredData,
+	if err != nil {
+		log.Warnf("%s :: %v", "get record fault", err)
+
+		return record, storage.ErrUnknown
 	}
-	return record, nil
+
+	return recordFromProto(gotRecord), nil
 }
 
-// DeleteRecord deletes record from server by ID.
-func (c *ClientConnGPRC) DeleteRecord(token entity.AuthToken, recordID string) error {
-	ctx := metadata.AppendToOutgoingContext(context.Background(), "authToken", string(token))
+// DeleteRecord deletes record from server by ID, if it wasn't changed after revision.
+func (c *ClientConnGPRC) DeleteRecord(token entity.AuthToken, recordID string, revision int64) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
 	_, err := c.GophkeeperClient.DeleteRecord(ctx, &pb.RecordID{
-		Id: recordID,
+		Id:       recordID,
+		Revision: revision,
 	})
 	code := status.Code(err)
 
 	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return controller.ErrServerUnavailable
 	case codes.Internal:
 		return storage.ErrUnknown
 	case codes.Unauthenticated:
 		return storage.ErrUnauthenticated
 	case codes.NotFound:
 		return storage.ErrNotFound
+	case codes.Aborted:
+		return storage.ErrConflict
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "delete record fault", err)
+
+		return storage.ErrUnknown
 	}
 
 	return nil
 }
 
 // CreateRecord creates record and saves to server.
-func (c *ClientConnGPRC) CreateRecord(token entity.AuthToken, record entity.Record) error {
-	ctx := metadata.AppendToOutgoingContext(context.Background(), "authToken", string(token))
-	_, err := c.GophkeeperClient.CreateRecord(ctx, &pb.Record{
+func (c *ClientConnGPRC) CreateRecord(token entity.AuthToken, record entity.Record) (string, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	recordID, err := c.GophkeeperClient.CreateRecord(ctx, &pb.Record{
 		Type:       pb.MessageType(record.Type),
 		Metadata:   record.Metadata,
 		StoredData: record.Data,
+		Name:       record.Name,
+		Tags:       record.Tags,
+		Folder:     record.Folder,
+		BlindIndex: record.BlindIndex,
 	})
 
 	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return "", controller.ErrServerUnavailable
 	case codes.Internal:
+		return "", storage.ErrUnknown
+	case codes.Unauthenticated:
+		return "", storage.ErrUnauthenticated
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "create record fault", err)
+
+		return "", storage.ErrUnknown
+	}
+
+	return recordID.GetId(), nil
+}
+
+// UpdateRecord replaces data and metadata of record on server, if it wasn't changed after record revision.
+func (c *ClientConnGPRC) UpdateRecord(token entity.AuthToken, record entity.Record) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	_, err := c.GophkeeperClient.UpdateRecord(ctx, &pb.Record{
+		Id:         record.ID,
+		Type:       pb.MessageType(record.Type),
+		Metadata:   record.Metadata,
+		StoredData: record.Data,
+		Revision:   record.Revision,
+		Name:       record.Name,
+		Tags:       record.Tags,
+		Folder:     record.Folder,
+		BlindIndex: record.BlindIndex,
+	})
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return controller.ErrServerUnavailable
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return storage.ErrUnauthenticated
+	case codes.NotFound:
+		return storage.ErrNotFound
+	case codes.FailedPrecondition:
+		return storage.ErrNotSupported
+	case codes.Aborted:
+		return storage.ErrConflict
+	default:
 		return storage.ErrUnknown
+	}
+}
+
+// GetRecordVersions gets previous versions of record from server.
+func (c *ClientConnGPRC) GetRecordVersions(token entity.AuthToken, recordID string) ([]entity.RecordVersion, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	versionsList, err := c.GophkeeperClient.GetRecordVersions(ctx, &pb.RecordID{
+		Id: recordID,
+	})
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return nil, controller.ErrServerUnavailable
+	case codes.OK:
+	case codes.Unauthenticated:
+		return nil, storage.ErrUnauthenticated
+	default:
+		return nil, storage.ErrUnknown
+	}
+
+	versions := make([]entity.RecordVersion, 0, len(versionsList.Versions))
+
+	for _, version := range versionsList.Versions {
+		versions = append(versions, entity.RecordVersion{
+			RecordID:   version.RecordId,
+			Version:    version.Version,
+			Metadata:   version.Metadata,
+			ReplacedAt: version.ReplacedAt.AsTime(),
+		})
+	}
+
+	return versions, nil
+}
+
+// RestoreRecordVersion makes previous version of record current on server, if record wasn't changed after revision.
+func (c *ClientConnGPRC) RestoreRecordVersion(token entity.AuthToken, recordID string, version int32, revision int64) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	_, err := c.GophkeeperClient.RestoreRecordVersion(ctx, &pb.RecordVersion{
+		RecordId: recordID,
+		Version:  version,
+		Revision: revision,
+	})
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return controller.ErrServerUnavailable
+	case codes.OK:
+		return nil
 	case codes.Unauthenticated:
 		return storage.ErrUnauthenticated
+	case codes.NotFound:
+		return storage.ErrNotFound
+	case codes.Aborted:
+		return storage.ErrConflict
+	default:
+		return storage.ErrUnknown
 	}
+}
 
-	return nil
+// RefreshSession exchanges refresh token for new session.
+func (c *ClientConnGPRC) RefreshSession(refreshToken entity.RefreshToken) (entity.Session, error) {
+	session, err := c.GophkeeperClient.RefreshSession(context.Background(), &pb.RefreshToken{
+		RefreshToken: string(refreshToken),
+	})
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return entity.Session{}, controller.ErrServerUnavailable
+	case codes.OK:
+		return sessionFromProto(session), nil
+	case codes.Unauthenticated:
+		return entity.Session{}, storage.ErrUnauthenticated
+	default:
+		log.Warnf("%s :: %v", "refresh session fault", err)
+
+		return entity.Session{}, storage.ErrUnknown
+	}
+}
+
+// Logout ends session of refresh token on server.
+func (c *ClientConnGPRC) Logout(refreshToken entity.RefreshToken) error {
+	_, err := c.GophkeeperClient.Logout(context.Background(), &pb.RefreshToken{
+		RefreshToken: string(refreshToken),
+	})
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return controller.ErrServerUnavailable
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return storage.ErrUnauthenticated
+	default:
+		return storage.ErrUnknown
+	}
+}
+
+// RevokeSessions ends all sessions of user on server.
+func (c *ClientConnGPRC) RevokeSessions(token entity.AuthToken) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	_, err := c.GophkeeperClient.RevokeSessions(ctx, &emptypb.Empty{})
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return controller.ErrServerUnavailable
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return storage.ErrUnauthenticated
+	default:
+		return storage.ErrUnknown
+	}
+}
+
+// UpgradeKDF sends key derivation parameters of legacy user to server: parameters with legacy mark start
+// upgrade of legacy key, parameters without it finish upgrade.
+func (c *ClientConnGPRC) UpgradeKDF(token entity.AuthToken, params entity.KDFParams) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	_, err := c.GophkeeperClient.UpgradeKDF(ctx, kdfParamsToProto(params))
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return controller.ErrServerUnavailable
+	case codes.OK:
+		return nil
+	case codes.Unauthenticated:
+		return storage.ErrUnauthenticated
+	case codes.InvalidArgument:
+		return pkg.ErrBadKDFParams
+	case codes.Aborted:
+		return storage.ErrConflict
+	default:
+		return storage.ErrUnknown
+	}
+}
+
+// Sync gets records changed and deleted on server after revision.
+func (c *ClientConnGPRC) Sync(token entity.AuthToken, sinceRevision int64) (entity.RecordChanges, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	changes, err := c.GophkeeperClient.Sync(ctx, &pb.SyncRequest{
+		SinceRevision: sinceRevision,
+	})
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return entity.RecordChanges{}, controller.ErrServerUnavailable
+	case codes.OK:
+	case codes.Unauthenticated:
+		return entity.RecordChanges{}, storage.ErrUnauthenticated
+	default:
+		log.Warnf("%s :: %v", "sync records fault", err)
+
+		return entity.RecordChanges{}, storage.ErrUnknown
+	}
+
+	return recordChangesFromProto(changes), nil
+}
+
+// UploadFile creates file record and sends its data to server by chunks. Returns ID of created record.
+func (c *ClientConnGPRC) UploadFile(token entity.AuthToken, record entity.Record, r io.Reader) (string, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, authTokenKey, string(token))
+
+	stream, err := c.GophkeeperClient.UploadFile(ctx)
+	if status.Code(err) == codes.Unavailable {
+		return "", controller.ErrServerUnavailable
+	}
+	if err != nil {
+		log.Warnf("%s :: %v", "open upload stream fault", err)
+
+		return "", storage.ErrUnknown
+	}
+
+	err = stream.Send(&pb.FileChunk{
+		Info: &pb.Record{
+			Type:       pb.MessageType(record.Type),
+			Metadata:   record.Metadata,
+			Name:       record.Name,
+			Tags:       record.Tags,
+			Folder:     record.Folder,
+			BlindIndex: record.BlindIndex,
+		},
+	})
+
+	if err == nil {
+		writer := newChunkWriter(func(chunk []byte) error {
+			return stream.Send(&pb.FileChunk{ChunkData: chunk})
+		})
+		_, err = copyChunks(writer, r)
+	}
+
+	// io.EOF means that server closed stream, real status will be returned by CloseAndRecv.
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Warnf("%s :: %v", "send file chunks fault", err)
+
+		return "", storage.ErrUnknown
+	}
+
+	recordID, err := stream.CloseAndRecv()
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return "", controller.ErrServerUnavailable
+	case codes.Internal:
+		return "", storage.ErrUnknown
+	case codes.Unauthenticated:
+		return "", storage.ErrUnauthenticated
+	case codes.InvalidArgument:
+		return "", controller.ErrFieldIsEmpty
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "upload file fault", err)
+
+		return "", storage.ErrUnknown
+	}
+
+	return recordID.Id, nil
+}
+
+// DownloadFile gets record from server and writes its data to writer by chunks.
+func (c *ClientConnGPRC) DownloadFile(token entity.AuthToken, recordID string, w io.Writer) (entity.Record, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx = metadata.AppendToOutgoingContext(ctx, authTokenKey, string(token))
+	record := entity.Record{}
+
+	stream, err := c.GophkeeperClient.DownloadFile(ctx, &pb.RecordID{
+		Id: recordID,
+	})
+	if status.Code(err) == codes.Unavailable {
+		return record, controller.ErrServerUnavailable
+	}
+	if err != nil {
+		log.Warnf("%s :: %v", "open download stream fault", err)
+
+		return record, storage.ErrUnknown
+	}
+
+	first, err := stream.Recv()
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return record, controller.ErrServerUnavailable
+	case codes.Internal:
+		return record, storage.ErrUnknown
+	case codes.Unauthenticated:
+		return record, storage.ErrUnauthenticated
+	case codes.NotFound:
+		return record, storage.ErrNotFound
+	}
+
+	if err != nil || first.Info == nil {
+		log.Warnf("%s :: %v", "download file fault", err)
+
+		return record, storage.ErrUnknown
+	}
+
+	reader := newChunkReader(first.ChunkData, func() ([]byte, error) {
+		chunk, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		return chunk.ChunkData, nil
+	})
+
+	if _, err = copyChunks(w, reader); err != nil {
+		log.Warnf("%s :: %v", "receive file chunks fault", err)
+
+		return record, storage.ErrUnknown
+	}
+
+	record = entity.Record{
+		ID:         first.Info.Id,
+		Metadata:   first.Info.Metadata,
+		Type:       entity.RecordType(first.Info.Type),
+		Name:       first.Info.Name,
+		Tags:       first.Info.Tags,
+		Folder:     first.Info.Folder,
+		BlindIndex: first.Info.BlindIndex,
+	}
+
+	return record, nil
 }
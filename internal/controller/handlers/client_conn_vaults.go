@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	pb "github.com/bbt-t/lets-go-keep/protocols/grpc"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// GetPublicKey gets public key of user from server by login.
+func (c *ClientConnGPRC) GetPublicKey(token entity.AuthToken, login string) ([]byte, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	publicKey, err := c.GophkeeperClient.GetPublicKey(ctx, &pb.UserLogin{Login: login})
+
+	if status.Code(err) == codes.FailedPrecondition {
+		return nil, storage.ErrNoPublicKey
+	}
+	if err != nil {
+		return nil, vaultError(err, "get public key fault")
+	}
+
+	return publicKey.PublicKey, nil
+}
+
+// SetPublicKey sets public key of user on server, if user has no one.
+func (c *ClientConnGPRC) SetPublicKey(token entity.AuthToken, publicKey []byte) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	_, err := c.GophkeeperClient.SetPublicKey(ctx, &pb.PublicKey{PublicKey: publicKey})
+
+	return vaultError(err, "set public key fault")
+}
+
+// CreateVault creates vault on server and returns its ID.
+func (c *ClientConnGPRC) CreateVault(token entity.AuthToken, vault entity.Vault) (string, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	created, err := c.GophkeeperClient.CreateVault(ctx, vaultToProto(vault))
+	if err != nil {
+		return "", vaultError(err, "create vault fault")
+	}
+
+	return created.Id, nil
+}
+
+// GetVaults gets vaults of user from server.
+func (c *ClientConnGPRC) GetVaults(token entity.AuthToken) ([]entity.Vault, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	gotVaults, err := c.GophkeeperClient.GetVaults(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil, vaultError(err, "get vaults fault")
+	}
+
+	vaults := make([]entity.Vault, 0, len(gotVaults.Vaults))
+	for _, vault := range gotVaults.Vaults {
+		vaults = append(vaults, vaultFromProto(vault))
+	}
+
+	return vaults, nil
+}
+
+// GetVaultMembers gets members of vault from server.
+func (c *ClientConnGPRC) GetVaultMembers(token entity.AuthToken, vaultID string) ([]entity.VaultMember, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	gotMembers, err := c.GophkeeperClient.GetVaultMembers(ctx, &pb.Vault{Id: vaultID})
+	if err != nil {
+		return nil, vaultError(err, "get vault members fault")
+	}
+
+	members := make([]entity.VaultMember, 0, len(gotMembers.Members))
+	for _, member := range gotMembers.Members {
+		members = append(members, vaultMemberFromProto(member))
+	}
+
+	return members, nil
+}
+
+// InviteMember adds member with wrapped vault key to vault on server.
+func (c *ClientConnGPRC) InviteMember(token entity.AuthToken, member entity.VaultMember) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	_, err := c.GophkeeperClient.InviteMember(ctx, vaultMemberToProto(member))
+
+	return vaultError(err, "invite member fault")
+}
+
+// RevokeMember removes member from vault on server and replaces vault key.
+func (c *ClientConnGPRC) RevokeMember(token entity.AuthToken, rotation entity.VaultRotation) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	_, err := c.GophkeeperClient.RevokeMember(ctx, vaultRotationToProto(rotation))
+
+	return vaultError(err, "revoke member fault")
+}
+
+// GetVaultRecords gets all records of vault from server.
+func (c *ClientConnGPRC) GetVaultRecords(token entity.AuthToken, vaultID string) ([]entity.Record, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	gotRecords, err := c.GophkeeperClient.GetVaultRecords(ctx, &pb.Vault{Id: vaultID})
+	if err != nil {
+		return nil, vaultError(err, "get vault records fault")
+	}
+
+	records := make([]entity.Record, 0, len(gotRecords.Records))
+	for _, record := range gotRecords.Records {
+		records = append(records, recordFromProto(record))
+	}
+
+	return records, nil
+}
+
+// GetVaultRecord gets record of vault from server by ID.
+func (c *ClientConnGPRC) GetVaultRecord(token entity.AuthToken, vaultID, recordID string) (entity.Record, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	gotRecord, err := c.GophkeeperClient.GetVaultRecord(ctx, &pb.VaultRecordID{
+		VaultId:  vaultID,
+		RecordId: recordID,
+	})
+	if err != nil {
+		return entity.Record{}, vaultError(err, "get vault record fault")
+	}
+
+	return recordFromProto(gotRecord), nil
+}
+
+// CreateVaultRecord creates record in vault on server and returns its ID.
+func (c *ClientConnGPRC) CreateVaultRecord(token entity.AuthToken, record entity.Record) (string, error) {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	recordID, err := c.GophkeeperClient.CreateVaultRecord(ctx, recordToProto(record))
+	if err != nil {
+		return "", vaultError(err, "create vault record fault")
+	}
+
+	return recordID.Id, nil
+}
+
+// UpdateVaultRecord updates record in vault on server.
+func (c *ClientConnGPRC) UpdateVaultRecord(token entity.AuthToken, record entity.Record) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	_, err := c.GophkeeperClient.UpdateVaultRecord(ctx, recordToProto(record))
+
+	return vaultError(err, "update vault record fault")
+}
+
+// DeleteVaultRecord deletes record from vault on server.
+func (c *ClientConnGPRC) DeleteVaultRecord(token entity.AuthToken, vaultID, recordID string) error {
+	ctx := metadata.AppendToOutgoingContext(context.Background(), authTokenKey, string(token))
+	_, err := c.GophkeeperClient.DeleteVaultRecord(ctx, &pb.VaultRecordID{
+		VaultId:  vaultID,
+		RecordId: recordID,
+	})
+
+	return vaultError(err, "delete vault record fault")
+}
+
+// vaultError converts gRPC status of vault request to error, unknown errors are logged as fault.
+func vaultError(err error, fault string) error {
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return controller.ErrServerUnavailable
+	case codes.Internal:
+		return storage.ErrUnknown
+	case codes.Unauthenticated:
+		return storage.ErrUnauthenticated
+	case codes.NotFound:
+		return storage.ErrNotFound
+	case codes.PermissionDenied:
+		return storage.ErrForbidden
+	case codes.FailedPrecondition:
+		return storage.ErrNotSupported
+	case codes.Aborted:
+		return storage.ErrConflict
+	case codes.InvalidArgument:
+		return controller.ErrFieldIsEmpty
+	}
+
+	log.Warnf("%s :: %v", fault, err)
+
+	return storage.ErrUnknown
+}
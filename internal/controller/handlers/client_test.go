@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/controller"
 	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
@@ -15,13 +20,18 @@ import (
 
 func TestNewClientHandlers(t *testing.T) {
 	conn := mocks.NewClientConn(t)
-	handlers := newClientHandlers(conn)
+	handlers := newClientHandlers(conn, false)
 	assert.NotEmpty(t, handlers)
 }
 
 func TestClient_Register(t *testing.T) {
 	conn := mocks.NewClientConn(t)
-	handlers := newClientHandlers(conn)
+	handlers := newClientHandlers(conn, false)
+
+	var (
+		kdf       entity.KDFParams
+		publicKey []byte
+	)
 
 	tc := []struct {
 		name  string
@@ -31,11 +41,15 @@ func TestClient_Register(t *testing.T) {
 		{
 			"Register with good credentials",
 			func() {
-				conn.On("Register", entity.UserCredentials{
-					Login:     "Login",
-					Password:  "Password",
-					MasterKey: []byte("hello"),
-				}).Return("token", nil).Once()
+				conn.On("Register", mock.MatchedBy(func(credentials entity.UserCredentials) bool {
+					return credentials.Login == "Login" &&
+						credentials.Password == "Password" &&
+						pkg.ValidateKDFParams(credentials.KDF) == nil &&
+						len(credentials.PublicKey) == pkg.PublicKeySize
+				})).Run(func(args mock.Arguments) {
+					credentials := args.Get(0).(entity.UserCredentials)
+					kdf, publicKey = credentials.KDF, credentials.PublicKey
+				}).Return(entity.Session{Token: "token"}, nil).Once()
 			},
 			func() {
 				err := handlers.Register(entity.UserCredentials{
@@ -45,16 +59,14 @@ func TestClient_Register(t *testing.T) {
 				})
 				assert.NoError(t, err)
 				assert.Equal(t, entity.AuthToken("token"), handlers.authToken)
-				assert.Equal(
-					t,
-					[]byte{
-						0xe3, 0xb0, 0xc4, 0x42, 0x98, 0xfc, 0x1c, 0x14,
-						0x9a, 0xfb, 0xf4, 0xc8, 0x99, 0x6f, 0xb9, 0x24,
-						0x27, 0xae, 0x41, 0xe4, 0x64, 0x9b, 0x93, 0x4c,
-						0xa4, 0x95, 0x99, 0x1b, 0x78, 0x52, 0xb8, 0x55,
-					},
-					handlers.masterKey,
-				)
+
+				key, err := pkg.DeriveKey([]byte("hello"), kdf)
+				assert.NoError(t, err)
+				assert.Equal(t, key, handlers.masterKey)
+
+				expected, err := pkg.PublicKey(key)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, publicKey)
 			},
 		},
 		{
@@ -83,7 +95,19 @@ func TestClient_Register(t *testing.T) {
 
 func TestClient_Login(t *testing.T) {
 	conn := mocks.NewClientConn(t)
-	handlers := newClientHandlers(conn)
+	handlers := newClientHandlers(conn, false)
+
+	kdf := entity.KDFParams{
+		Salt:    bytes.Repeat([]byte{0x01}, pkg.KDFSalt),
+		Time:    1,
+		Memory:  16 * 1024,
+		Threads: 1,
+	}
+
+	key, err := pkg.DeriveKey([]byte("hello"), kdf)
+	assert.NoError(t, err)
+	publicKey, err := pkg.PublicKey(key)
+	assert.NoError(t, err)
 
 	tc := []struct {
 		name  string
@@ -91,13 +115,14 @@ func TestClient_Login(t *testing.T) {
 		valid func()
 	}{
 		{
-			"Login with good credentials",
+			"Login with good credentials, public key is provisioned",
 			func() {
 				conn.On("Login", entity.UserCredentials{
 					Login:     "Login",
 					Password:  "Password",
 					MasterKey: []byte("hello"),
-				}).Return("token", nil).Once()
+				}).Return(entity.Session{Token: "token", KDF: kdf}, nil).Once()
+				conn.On("SetPublicKey", entity.AuthToken("token"), publicKey).Return(nil).Once()
 			},
 			func() {
 				err := handlers.Login(entity.UserCredentials{
@@ -107,16 +132,70 @@ func TestClient_Login(t *testing.T) {
 				})
 				assert.NoError(t, err)
 				assert.Equal(t, entity.AuthToken("token"), handlers.authToken)
-				assert.Equal(
-					t,
-					[]byte{
-						0xe3, 0xb0, 0xc4, 0x42, 0x98, 0xfc, 0x1c, 0x14,
-						0x9a, 0xfb, 0xf4, 0xc8, 0x99, 0x6f, 0xb9, 0x24,
-						0x27, 0xae, 0x41, 0xe4, 0x64, 0x9b, 0x93, 0x4c,
-						0xa4, 0x95, 0x99, 0x1b, 0x78, 0x52, 0xb8, 0x55,
-					},
-					handlers.masterKey,
-				)
+				assert.Equal(t, key, handlers.masterKey)
+			},
+		},
+		{
+			"Login, when server has another public key of user",
+			func() {
+				conn.On("Login", entity.UserCredentials{
+					Login:     "Login",
+					Password:  "Password",
+					MasterKey: []byte("hello"),
+				}).Return(entity.Session{Token: "token", KDF: kdf}, nil).Once()
+				conn.On("SetPublicKey", entity.AuthToken("token"), publicKey).Return(storage.ErrConflict).Once()
+			},
+			func() {
+				err := handlers.Login(entity.UserCredentials{
+					Login:     "Login",
+					Password:  "Password",
+					MasterKey: []byte("hello"),
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, key, handlers.masterKey)
+			},
+		},
+		{
+			"Login, but server returns weak KDF params",
+			func() {
+				conn.On("Login", entity.UserCredentials{
+					Login:     "Login",
+					Password:  "Password",
+					MasterKey: []byte("hello"),
+				}).Return(entity.Session{
+					Token: "token",
+					KDF:   entity.KDFParams{Salt: kdf.Salt, Time: 1, Memory: 8, Threads: 1},
+				}, nil).Once()
+			},
+			func() {
+				handlers.authToken = ""
+				err := handlers.Login(entity.UserCredentials{
+					Login:     "Login",
+					Password:  "Password",
+					MasterKey: []byte("hello"),
+				})
+				assert.Equal(t, pkg.ErrBadKDFParams, err)
+				assert.Empty(t, handlers.authToken)
+			},
+		},
+		{
+			"Login, but server returns no salt for user, who isn't legacy",
+			func() {
+				conn.On("Login", entity.UserCredentials{
+					Login:     "Login",
+					Password:  "Password",
+					MasterKey: []byte("hello"),
+				}).Return(entity.Session{Token: "token"}, nil).Once()
+			},
+			func() {
+				handlers.authToken = ""
+				err := handlers.Login(entity.UserCredentials{
+					Login:     "Login",
+					Password:  "Password",
+					MasterKey: []byte("hello"),
+				})
+				assert.Equal(t, pkg.ErrBadKDFParams, err)
+				assert.Empty(t, handlers.authToken)
 			},
 		},
 		{
@@ -143,9 +222,151 @@ func TestClient_Login(t *testing.T) {
 	}
 }
 
+func TestClient_LoginLegacy(t *testing.T) {
+	credentials := entity.UserCredentials{Login: "Login", Password: "Password", MasterKey: []byte("hello")}
+	kdf := entity.KDFParams{
+		Salt:    bytes.Repeat([]byte{0x01}, pkg.KDFSalt),
+		Time:    1,
+		Memory:  16 * 1024,
+		Threads: 1,
+	}
+	pending := kdf
+	pending.Legacy = true
+
+	key, err := pkg.DeriveKey([]byte("hello"), kdf)
+	assert.NoError(t, err)
+	otherKey, err := pkg.DeriveKey([]byte("other"), kdf)
+	assert.NoError(t, err)
+
+	legacyData, err := pkg.EncryptBytes(legacyKey(), []byte("hello!"))
+	assert.NoError(t, err)
+	legacyName, err := pkg.EncryptMetadata(legacyKey(), "name")
+	assert.NoError(t, err)
+	upgradedData, err := pkg.EncryptBytes(key, []byte("hello!"))
+	assert.NoError(t, err)
+	otherData, err := pkg.EncryptBytes(otherKey, []byte("hello!"))
+	assert.NoError(t, err)
+
+	t.Log("Legacy key is upgraded: records are encrypted by new key, file is uploaded again")
+	{
+		conn := mocks.NewClientConn(t)
+		handlers := newClientHandlers(conn, false)
+
+		var (
+			generated entity.KDFParams
+			upgraded  []byte
+		)
+
+		conn.On("Login", credentials).
+			Return(entity.Session{Token: "token", KDF: entity.KDFParams{Legacy: true}}, nil).Once()
+		conn.On("UpgradeKDF", entity.AuthToken("token"), mock.MatchedBy(func(params entity.KDFParams) bool {
+			return params.Legacy && pkg.ValidateKDFParams(params) == nil
+		})).Run(func(args mock.Arguments) {
+			generated = args.Get(1).(entity.KDFParams)
+			upgraded, err = pkg.DeriveKey([]byte("hello"), generated)
+			assert.NoError(t, err)
+		}).Return(nil).Once()
+		conn.On("GetRecordsInfo", entity.AuthToken("token")).Return([]entity.Record{
+			{ID: "1", Name: legacyName},
+			{ID: "2", Type: entity.TypeFile, Metadata: "file.txt", Revision: 4},
+		}, nil).Once()
+		conn.On("GetRecord", entity.AuthToken("token"), "1").
+			Return(entity.Record{ID: "1", Name: legacyName, Data: legacyData, Revision: 3}, nil).Once()
+		conn.On("UpdateRecord", entity.AuthToken("token"), mock.MatchedBy(func(record entity.Record) bool {
+			data, errData := pkg.DecryptBytes(upgraded, record.Data)
+			name, errName := pkg.DecryptMetadata(upgraded, record.Name)
+
+			return errData == nil && string(data) == "hello!" && errName == nil && name == "name" &&
+				record.Revision == 3 && len(record.BlindIndex) != 0
+		})).Return(nil).Once()
+		conn.On("DownloadFile", entity.AuthToken("token"), "2", mock.Anything).
+			Return(func(_ entity.AuthToken, _ string, w io.Writer) (entity.Record, error) {
+				_, err := w.Write(legacyData)
+				return entity.Record{}, err
+			}).Once()
+		conn.On(
+			"UploadFile",
+			entity.AuthToken("token"),
+			entity.Record{Type: entity.TypeFile, Metadata: "file.txt"},
+			mock.Anything,
+		).Return(func(_ entity.AuthToken, _ entity.Record, r io.Reader) (string, error) {
+			encrypted, err := io.ReadAll(r)
+			assert.NoError(t, err)
+
+			data, err := pkg.DecryptBytes(upgraded, encrypted)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("hello!"), data)
+
+			return "3", nil
+		}).Once()
+		conn.On("DeleteRecord", entity.AuthToken("token"), "2", int64(4)).Return(nil).Once()
+		conn.On("UpgradeKDF", entity.AuthToken("token"), mock.MatchedBy(func(params entity.KDFParams) bool {
+			return !params.Legacy && bytes.Equal(params.Salt, generated.Salt)
+		})).Return(nil).Once()
+		conn.On("SetPublicKey", entity.AuthToken("token"), mock.AnythingOfType("[]uint8")).Return(nil).Once()
+
+		assert.NoError(t, handlers.Login(credentials))
+		assert.Equal(t, upgraded, handlers.masterKey)
+		assert.False(t, handlers.legacy)
+	}
+
+	t.Log("Upgrade fails, user stays logged with legacy key, but writes are refused")
+	{
+		conn := mocks.NewClientConn(t)
+		handlers := newClientHandlers(conn, false)
+
+		conn.On("Login", credentials).
+			Return(entity.Session{Token: "token", KDF: entity.KDFParams{Legacy: true}}, nil).Once()
+		conn.On("UpgradeKDF", entity.AuthToken("token"), mock.AnythingOfType("entity.KDFParams")).
+			Return(controller.ErrServerUnavailable).Once()
+
+		assert.NoError(t, handlers.Login(credentials))
+		assert.Equal(t, legacyKey(), handlers.masterKey)
+
+		_, err := handlers.CreateRecord(entity.Record{Data: []byte("hello!")})
+		assert.Equal(t, controller.ErrLegacyKey, err)
+		assert.Equal(t, controller.ErrLegacyKey, handlers.UpdateRecord(entity.Record{ID: "1"}))
+		_, err = handlers.CreateVault("vault")
+		assert.Equal(t, controller.ErrLegacyKey, err)
+		_, err = handlers.Fingerprint()
+		assert.Equal(t, controller.ErrLegacyKey, err)
+	}
+
+	t.Log("Interrupted upgrade is finished, records encrypted by new key are left as is")
+	{
+		conn := mocks.NewClientConn(t)
+		handlers := newClientHandlers(conn, false)
+
+		conn.On("Login", credentials).Return(entity.Session{Token: "token", KDF: pending}, nil).Once()
+		conn.On("GetRecordsInfo", entity.AuthToken("token")).Return([]entity.Record{{ID: "1"}}, nil).Once()
+		conn.On("GetRecord", entity.AuthToken("token"), "1").
+			Return(entity.Record{ID: "1", Data: upgradedData}, nil).Once()
+		conn.On("UpgradeKDF", entity.AuthToken("token"), kdf).Return(nil).Once()
+		conn.On("SetPublicKey", entity.AuthToken("token"), mock.AnythingOfType("[]uint8")).Return(nil).Once()
+
+		assert.NoError(t, handlers.Login(credentials))
+		assert.Equal(t, key, handlers.masterKey)
+	}
+
+	t.Log("Interrupted upgrade with another master key")
+	{
+		conn := mocks.NewClientConn(t)
+		handlers := newClientHandlers(conn, false)
+
+		conn.On("Login", credentials).Return(entity.Session{Token: "token", KDF: pending}, nil).Once()
+		conn.On("GetRecordsInfo", entity.AuthToken("token")).Return([]entity.Record{{ID: "1"}}, nil).Once()
+		conn.On("GetRecord", entity.AuthToken("token"), "1").
+			Return(entity.Record{ID: "1", Data: otherData}, nil).Once()
+
+		assert.Equal(t, controller.ErrWrongMasterKey, handlers.Login(credentials))
+		assert.Empty(t, handlers.authToken)
+		assert.Empty(t, handlers.masterKey)
+	}
+}
+
 func TestClient_GetRecordsInfo(t *testing.T) {
 	conn := mocks.NewClientConn(t)
-	handlers := newClientHandlers(conn)
+	handlers := newClientHandlers(conn, false)
 	handlers.authToken = "token"
 
 	tc := []struct {
@@ -195,7 +416,7 @@ func TestClient_GetRecordsInfo(t *testing.T) {
 
 func TestClient_GetRecord(t *testing.T) {
 	conn := mocks.NewClientConn(t)
-	handlers := newClientHandlers(conn)
+	handlers := newClientHandlers(conn, false)
 	handlers.authToken = "token"
 	handlers.masterKey = []byte{
 		0xe3, 0xb0, 0xc4, 0x42, 0x98, 0xfc, 0x1c, 0x14,
@@ -203,6 +424,7 @@ func TestClient_GetRecord(t *testing.T) {
 		0x27, 0xae, 0x41, 0xe4, 0x64, 0x9b, 0x93, 0x4c,
 		0xa4, 0x95, 0x99, 0x1b, 0x78, 0x52, 0xb8, 0x55,
 	}
+	filePath := filepath.Join(chdirTemp(t), "file.txt")
 
 	tc := []struct {
 		name  string
@@ -229,6 +451,57 @@ func TestClient_GetRecord(t *testing.T) {
 				}, record)
 			},
 		},
+		{
+			"Get file record",
+			func() {
+				conn.On("GetRecord", entity.AuthToken("token"), "2").Return(entity.Record{
+					ID:       "2",
+					Type:     entity.TypeFile,
+					Metadata: filePath,
+				}, nil).Once()
+				// Legacy file isn't in envelope format, so it's downloaded second time as a whole.
+				conn.On(
+					"DownloadFile",
+					entity.AuthToken("token"),
+					"2",
+					mock.Anything,
+				).Return(func(_ entity.AuthToken, _ string, w io.Writer) (entity.Record, error) {
+					_, err := w.Write([]byte{
+						0xcb, 0x1a, 0x6d, 0xb2, 0x12, 0xe2, 0x34, 0x9d,
+						0xf7, 0xe4, 0x2b, 0x9f, 0xa2, 0x9e, 0xd2, 0x12,
+						0x7, 0x2d, 0xa9, 0xff, 0xa, 0xd5, 0x88, 0x2b, 0x88,
+						0x6d, 0x61, 0x7, 0xf8, 0xd1, 0xc4, 0xf9, 0x17, 0xbc,
+					})
+					return entity.Record{
+						ID:       "2",
+						Type:     entity.TypeFile,
+						Metadata: filePath,
+					}, err
+				}).Twice()
+			},
+			func() {
+				record, err := handlers.GetRecord("2")
+				assert.NoError(t, err)
+
+				data, err := os.ReadFile(record.Metadata)
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("hello!"), data)
+			},
+		},
+		{
+			"Get file record, which name escapes current directory",
+			func() {
+				conn.On("GetRecord", entity.AuthToken("token"), "2").Return(entity.Record{
+					ID:       "2",
+					Type:     entity.TypeFile,
+					Metadata: "../../.bashrc",
+				}, nil).Once()
+			},
+			func() {
+				_, err := handlers.GetRecord("2")
+				assert.Equal(t, controller.ErrBadFileName, err)
+			},
+		},
 		{
 			"Get record, but not found",
 			func() {
@@ -263,9 +536,48 @@ func TestClient_GetRecord(t *testing.T) {
 	}
 }
 
+func TestFileName(t *testing.T) {
+	tc := []struct {
+		metadata string
+		name     string
+		err      error
+	}{
+		{metadata: "/home/bob/passport.pdf", name: "passport.pdf"},
+		{metadata: `C:\Users\bob\passport.pdf`, name: "passport.pdf"},
+		{metadata: "notes..txt", name: "notes..txt"},
+		{metadata: "../passport.pdf", err: controller.ErrBadFileName},
+		{metadata: "docs/../../passport.pdf", err: controller.ErrBadFileName},
+		{metadata: "/", err: controller.ErrBadFileName},
+		{metadata: "", err: controller.ErrBadFileName},
+	}
+
+	for _, test := range tc {
+		t.Log(test.metadata)
+
+		name, err := fileName(test.metadata)
+		assert.Equal(t, test.err, err)
+		assert.Equal(t, test.name, name)
+	}
+}
+
+// chdirTemp changes working directory to temporary one, where file records are saved, until test ends.
+func chdirTemp(t *testing.T) string {
+	dir := t.TempDir()
+
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+
+	t.Cleanup(func() {
+		assert.NoError(t, os.Chdir(wd))
+	})
+
+	return dir
+}
+
 func TestClient_DeleteRecord(t *testing.T) {
 	conn := mocks.NewClientConn(t)
-	handlers := newClientHandlers(conn)
+	handlers := newClientHandlers(conn, false)
 	handlers.authToken = "token"
 	handlers.masterKey = []byte{
 		0xe3, 0xb0, 0xc4, 0x42, 0x98, 0xfc, 0x1c, 0x14,
@@ -282,23 +594,33 @@ func TestClient_DeleteRecord(t *testing.T) {
 		{
 			"Delete record",
 			func() {
-				conn.On("DeleteRecord", entity.AuthToken("token"), "1").Return(nil).Once()
+				conn.On("DeleteRecord", entity.AuthToken("token"), "1", int64(3)).Return(nil).Once()
 			},
 			func() {
-				err := handlers.DeleteRecord("1")
+				err := handlers.DeleteRecord("1", 3)
 				assert.NoError(t, err)
 			},
 		},
 		{
 			"Delete record, but will return error",
 			func() {
-				conn.On("DeleteRecord", entity.AuthToken("token"), "1").Return(storage.ErrNotFound).Once()
+				conn.On("DeleteRecord", entity.AuthToken("token"), "1", int64(3)).Return(storage.ErrNotFound).Once()
 			},
 			func() {
-				err := handlers.DeleteRecord("1")
+				err := handlers.DeleteRecord("1", 3)
 				assert.Equal(t, storage.ErrNotFound, err)
 			},
 		},
+		{
+			"Delete record changed on another device",
+			func() {
+				conn.On("DeleteRecord", entity.AuthToken("token"), "1", int64(3)).Return(storage.ErrConflict).Once()
+			},
+			func() {
+				err := handlers.DeleteRecord("1", 3)
+				assert.Equal(t, storage.ErrConflict, err)
+			},
+		},
 	}
 
 	for _, test := range tc {
@@ -311,7 +633,7 @@ func TestClient_DeleteRecord(t *testing.T) {
 
 func TestClient_CreateRecord(t *testing.T) {
 	conn := mocks.NewClientConn(t)
-	handlers := newClientHandlers(conn)
+	handlers := newClientHandlers(conn, false)
 	handlers.authToken = "token"
 	handlers.masterKey = []byte{
 		0xe3, 0xb0, 0xc4, 0x42, 0x98, 0xfc, 0x1c, 0x14,
@@ -332,10 +654,29 @@ func TestClient_CreateRecord(t *testing.T) {
 					"CreateRecord",
 					entity.AuthToken("token"),
 					mock.AnythingOfType("entity.Record"),
-				).Return(nil).Once()
+				).Return("recordID", nil).Once()
+			},
+			func() {
+				recordID, err := handlers.CreateRecord(entity.Record{
+					Data: []byte("hello!"),
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, "recordID", recordID)
+			},
+		},
+		{
+			"Create file record",
+			func() {
+				conn.On(
+					"UploadFile",
+					entity.AuthToken("token"),
+					entity.Record{Type: entity.TypeFile},
+					mock.AnythingOfType("*pkg.encryptReader"),
+				).Return("1", nil).Once()
 			},
 			func() {
-				err := handlers.CreateRecord(entity.Record{
+				_, err := handlers.CreateRecord(entity.Record{
+					Type: entity.TypeFile,
 					Data: []byte("hello!"),
 				})
 				assert.NoError(t, err)
@@ -348,10 +689,10 @@ func TestClient_CreateRecord(t *testing.T) {
 					"CreateRecord",
 					entity.AuthToken("token"),
 					mock.AnythingOfType("entity.Record"),
-				).Return(storage.ErrUnauthenticated).Once()
+				).Return("", storage.ErrUnauthenticated).Once()
 			},
 			func() {
-				err := handlers.CreateRecord(entity.Record{
+				_, err := handlers.CreateRecord(entity.Record{
 					Data: []byte("hello!"),
 				})
 				assert.Equal(t, storage.ErrUnauthenticated, err)
@@ -364,10 +705,10 @@ func TestClient_CreateRecord(t *testing.T) {
 					"CreateRecord",
 					entity.AuthToken("token"),
 					mock.AnythingOfType("entity.Record"),
-				).Return(storage.ErrUnknown).Once()
+				).Return("", storage.ErrUnknown).Once()
 			},
 			func() {
-				err := handlers.CreateRecord(entity.Record{
+				_, err := handlers.CreateRecord(entity.Record{
 					Data: []byte("hello!"),
 				})
 				assert.Equal(t, storage.ErrUnknown, err)
@@ -383,6 +724,588 @@ func TestClient_CreateRecord(t *testing.T) {
 	}
 }
 
+func TestClient_UpdateRecord(t *testing.T) {
+	conn := mocks.NewClientConn(t)
+	handlers := newClientHandlers(conn, false)
+	handlers.authToken = "token"
+	handlers.masterKey = bytes.Repeat([]byte{0x01}, 32)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Update record, data is encrypted",
+			func() {
+				conn.On(
+					"UpdateRecord",
+					entity.AuthToken("token"),
+					mock.MatchedBy(func(record entity.Record) bool {
+						data, err := pkg.DecryptBytes(handlers.masterKey, record.Data)
+
+						return err == nil && record.ID == "1" && string(data) == "new text"
+					}),
+				).Return(nil).Once()
+			},
+			func() {
+				err := handlers.UpdateRecord(entity.Record{
+					ID:   "1",
+					Type: entity.TypeText,
+					Data: []byte("new text"),
+				})
+				assert.NoError(t, err)
+			},
+		},
+		{
+			"Update record, but not found",
+			func() {
+				conn.On(
+					"UpdateRecord",
+					entity.AuthToken("token"),
+					mock.AnythingOfType("entity.Record"),
+				).Return(storage.ErrNotFound).Once()
+			},
+			func() {
+				err := handlers.UpdateRecord(entity.Record{
+					ID:   "1",
+					Type: entity.TypeText,
+					Data: []byte("new text"),
+				})
+				assert.Equal(t, storage.ErrNotFound, err)
+			},
+		},
+		{
+			"Update file record",
+			func() {},
+			func() {
+				err := handlers.UpdateRecord(entity.Record{
+					ID:   "1",
+					Type: entity.TypeFile,
+					Data: []byte("new file"),
+				})
+				assert.Equal(t, storage.ErrNotSupported, err)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+		conn.AssertExpectations(t)
+	}
+}
+
+func TestClient_RecordVersions(t *testing.T) {
+	conn := mocks.NewClientConn(t)
+	handlers := newClientHandlers(conn, false)
+	handlers.authToken = "token"
+
+	versions := []entity.RecordVersion{{RecordID: "1", Version: 2}, {RecordID: "1", Version: 1}}
+
+	conn.On("GetRecordVersions", entity.AuthToken("token"), "1").Return(versions, nil).Once()
+	conn.On("RestoreRecordVersion", entity.AuthToken("token"), "1", int32(1), int64(3)).Return(nil).Once()
+	conn.On("RestoreRecordVersion", entity.AuthToken("token"), "1", int32(5), int64(3)).Return(storage.ErrNotFound).Once()
+	conn.On("RestoreRecordVersion", entity.AuthToken("token"), "1", int32(2), int64(2)).Return(storage.ErrConflict).Once()
+
+	got, err := handlers.GetRecordVersions("1")
+	assert.NoError(t, err)
+	assert.Equal(t, versions, got)
+
+	assert.NoError(t, handlers.RestoreRecordVersion("1", 1, 3))
+	assert.Equal(t, storage.ErrNotFound, handlers.RestoreRecordVersion("1", 5, 3))
+	assert.Equal(t, storage.ErrConflict, handlers.RestoreRecordVersion("1", 2, 2))
+
+	conn.AssertExpectations(t)
+}
+
+func TestClient_Sessions(t *testing.T) {
+	conn := mocks.NewClientConn(t)
+	handlers := newClientHandlers(conn, false)
+
+	t.Log("Session is refreshed before request, when token expires soon")
+	handlers.setSession(entity.Session{Token: "token", RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Second)})
+	handlers.masterKey = []byte("key")
+	assert.True(t, handlers.Unlocked())
+
+	conn.On("RefreshSession", entity.RefreshToken("refresh")).Return(entity.Session{
+		Token:        "new token",
+		RefreshToken: "new refresh",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}, nil).Once()
+	conn.On("GetRecordsInfo", entity.AuthToken("new token")).Return([]entity.Record{}, nil).Once()
+
+	_, err := handlers.GetRecordsInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, entity.RefreshToken("new refresh"), handlers.refreshToken)
+
+	t.Log("Session isn't refreshed, when token is fresh")
+	conn.On("GetRecordsInfo", entity.AuthToken("new token")).Return([]entity.Record{}, nil).Once()
+
+	_, err = handlers.GetRecordsInfo()
+	assert.NoError(t, err)
+
+	t.Log("Failed refresh is only logged")
+	handlers.expiresAt = time.Now()
+	conn.On("RefreshSession", entity.RefreshToken("new refresh")).Return(entity.Session{}, storage.ErrUnauthenticated).Once()
+	conn.On("GetRecordsInfo", entity.AuthToken("new token")).Return(nil, storage.ErrUnauthenticated).Once()
+
+	_, err = handlers.GetRecordsInfo()
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+
+	t.Log("Logout forgets session, even if server is unavailable")
+	conn.On("Logout", entity.RefreshToken("new refresh")).Return(controller.ErrServerUnavailable).Once()
+
+	assert.Equal(t, controller.ErrServerUnavailable, handlers.Logout())
+	assert.Empty(t, handlers.authToken)
+	assert.Empty(t, handlers.refreshToken)
+	assert.Empty(t, handlers.masterKey)
+	assert.False(t, handlers.Unlocked())
+
+	t.Log("Revoke all sessions")
+	handlers.setSession(entity.Session{Token: "token", RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour)})
+	handlers.masterKey = []byte("key")
+	conn.On("RevokeSessions", entity.AuthToken("token")).Return(nil).Once()
+
+	assert.NoError(t, handlers.RevokeSessions())
+	assert.Empty(t, handlers.authToken)
+	assert.Empty(t, handlers.masterKey)
+
+	t.Log("Revoke all sessions, but server is unavailable")
+	handlers.setSession(entity.Session{Token: "token", RefreshToken: "refresh"})
+	conn.On("RevokeSessions", entity.AuthToken("token")).Return(controller.ErrServerUnavailable).Once()
+
+	assert.Equal(t, controller.ErrServerUnavailable, handlers.RevokeSessions())
+	assert.Equal(t, entity.AuthToken("token"), handlers.authToken)
+
+	conn.AssertExpectations(t)
+}
+
+func TestClient_EnvelopeRecords(t *testing.T) {
+	conn := mocks.NewClientConn(t)
+	handlers := newClientHandlers(conn, false)
+	handlers.authToken = "token"
+	handlers.masterKey = bytes.Repeat([]byte{0x42}, 32)
+
+	filePath := filepath.Join(chdirTemp(t), "file.bin")
+	fileData := bytes.Repeat([]byte("big file "), 1<<15)
+
+	var text, file []byte
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Create and get text record",
+			func() {
+				conn.On("CreateRecord", entity.AuthToken("token"), mock.AnythingOfType("entity.Record")).
+					Run(func(args mock.Arguments) {
+						text = args.Get(1).(entity.Record).Data
+					}).Return("", nil).Once()
+				conn.On("GetRecord", entity.AuthToken("token"), "1").
+					Return(func(entity.AuthToken, string) (entity.Record, error) {
+						return entity.Record{ID: "1", Type: entity.TypeText, Data: text}, nil
+					}).Once()
+			},
+			func() {
+				_, err := handlers.CreateRecord(entity.Record{Type: entity.TypeText, Data: []byte("hello!")})
+				assert.NoError(t, err)
+				assert.True(t, pkg.IsEnvelope(text))
+
+				record, err := handlers.GetRecord("1")
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("hello!"), record.Data)
+			},
+		},
+		{
+			"Create and get file record",
+			func() {
+				conn.On(
+					"UploadFile",
+					entity.AuthToken("token"),
+					entity.Record{Metadata: filePath, Type: entity.TypeFile},
+					mock.AnythingOfType("*pkg.encryptReader"),
+				).Return(func(_ entity.AuthToken, _ entity.Record, r io.Reader) (string, error) {
+					var err error
+					file, err = io.ReadAll(r)
+					return "2", err
+				}).Once()
+				conn.On("GetRecord", entity.AuthToken("token"), "2").
+					Return(entity.Record{ID: "2", Metadata: filePath, Type: entity.TypeFile}, nil).Once()
+				conn.On("DownloadFile", entity.AuthToken("token"), "2", mock.Anything).
+					Return(func(_ entity.AuthToken, _ string, w io.Writer) (entity.Record, error) {
+						_, err := w.Write(file)
+						return entity.Record{ID: "2", Metadata: filePath, Type: entity.TypeFile}, err
+					}).Once()
+			},
+			func() {
+				_, err := handlers.CreateRecord(entity.Record{
+					Metadata: filePath,
+					Type:     entity.TypeFile,
+					Body:     bytes.NewReader(fileData),
+				})
+				assert.NoError(t, err)
+				assert.True(t, pkg.IsEnvelope(file))
+
+				_, err = handlers.GetRecord("2")
+				assert.NoError(t, err)
+
+				data, err := os.ReadFile(filePath)
+				assert.NoError(t, err)
+				assert.Equal(t, fileData, data)
+			},
+		},
+		{
+			"Get content of file record without saving it",
+			func() {
+				assert.NoError(t, os.Remove(filePath))
+
+				conn.On("GetRecord", entity.AuthToken("token"), "2").
+					Return(entity.Record{ID: "2", Metadata: filePath, Type: entity.TypeFile}, nil).Once()
+				conn.On("DownloadFile", entity.AuthToken("token"), "2", mock.Anything).
+					Return(func(_ entity.AuthToken, _ string, w io.Writer) (entity.Record, error) {
+						_, err := w.Write(file)
+						return entity.Record{ID: "2", Metadata: filePath, Type: entity.TypeFile}, err
+					}).Once()
+			},
+			func() {
+				var buf bytes.Buffer
+				record, err := handlers.GetFile("2", &buf)
+				assert.NoError(t, err)
+				assert.Equal(t, filePath, record.Metadata)
+				assert.Equal(t, fileData, buf.Bytes())
+				assert.NoFileExists(t, filePath)
+			},
+		},
+		{
+			"Get truncated file record",
+			func() {
+				conn.On("GetRecord", entity.AuthToken("token"), "2").
+					Return(entity.Record{ID: "2", Metadata: filePath, Type: entity.TypeFile}, nil).Once()
+				conn.On("DownloadFile", entity.AuthToken("token"), "2", mock.Anything).
+					Return(func(_ entity.AuthToken, _ string, w io.Writer) (entity.Record, error) {
+						_, err := w.Write(file[:len(file)-100])
+						return entity.Record{ID: "2", Metadata: filePath, Type: entity.TypeFile}, err
+					}).Once()
+			},
+			func() {
+				_, err := handlers.GetRecord("2")
+				assert.Equal(t, controller.ErrDataCorrupted, err)
+				assert.NoFileExists(t, filePath)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+		conn.AssertExpectations(t)
+	}
+}
+
+func TestClient_EncryptedMetadata(t *testing.T) {
+	conn := mocks.NewClientConn(t)
+	handlers := newClientHandlers(conn, true)
+	handlers.authToken = "token"
+	handlers.masterKey = bytes.Repeat([]byte{0x42}, 32)
+
+	var sent entity.Record
+
+	conn.On("CreateRecord", entity.AuthToken("token"), mock.AnythingOfType("entity.Record")).
+		Run(func(args mock.Arguments) {
+			sent = args.Get(1).(entity.Record)
+		}).Return("", nil).Once()
+
+	_, err := handlers.CreateRecord(entity.Record{
+		Type:     entity.TypeLoginAndPassword,
+		Metadata: "main account",
+		Name:     "Bank",
+		Tags:     []string{"Finance"},
+		Folder:   "Work/Bank",
+		Data:     []byte("secret"),
+	})
+	assert.NoError(t, err)
+	assert.True(t, pkg.IsEncryptedMetadata(sent.Metadata))
+	assert.True(t, pkg.IsEncryptedMetadata(sent.Name))
+
+	tag, err := pkg.EncryptLabel(handlers.masterKey, "finance")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{tag}, sent.Tags)
+
+	folder, err := pkg.EncryptFolder(handlers.masterKey, "Work/Bank")
+	assert.NoError(t, err)
+	assert.Equal(t, folder, sent.Folder)
+	assert.Equal(t, pkg.BlindIndex(handlers.masterKey, "bank main account"), sent.BlindIndex)
+
+	t.Log("Records are decrypted for display")
+	conn.On("GetRecordsInfo", entity.AuthToken("token")).Return([]entity.Record{sent}, nil).Once()
+
+	records, err := handlers.GetRecordsInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "main account", records[0].Metadata)
+	assert.Equal(t, "Bank", records[0].Name)
+	assert.Equal(t, []string{"finance"}, records[0].Tags)
+	assert.Equal(t, "Work/Bank", records[0].Folder)
+
+	t.Log("Labels in clear are rejected, until they are migrated")
+	plain := entity.Record{ID: "2", Metadata: "../../.bashrc", Name: "Bank deposit", Type: entity.TypeText}
+	conn.On("GetRecordsInfo", entity.AuthToken("token")).Return([]entity.Record{sent, plain}, nil).Once()
+
+	_, err = handlers.GetRecordsInfo()
+	assert.Equal(t, controller.ErrPlainLabels, err)
+
+	t.Log("Labels in clear are encrypted by migration, tags of vault records too")
+	publicKey, err := pkg.PublicKey(handlers.masterKey)
+	assert.NoError(t, err)
+	vaultKey, err := pkg.NewVaultKey()
+	assert.NoError(t, err)
+	wrapped, err := pkg.WrapKey(publicKey, vaultKey)
+	assert.NoError(t, err)
+
+	shared, err := sealVaultRecord(vaultKey, entity.Record{ID: "3", VaultID: "vaultID", Type: entity.TypeText, Name: "Wi-Fi"})
+	assert.NoError(t, err)
+	shared.Tags = []string{"network"}
+
+	var migrated, migratedShared entity.Record
+
+	conn.On("GetRecordsInfo", entity.AuthToken("token")).Return([]entity.Record{sent, plain}, nil).Once()
+	conn.On("GetRecord", entity.AuthToken("token"), "2").Return(plain, nil).Once()
+	conn.On("UpdateRecord", entity.AuthToken("token"), mock.AnythingOfType("entity.Record")).
+		Run(func(args mock.Arguments) {
+			migrated = args.Get(1).(entity.Record)
+		}).Return(nil).Once()
+	conn.On("GetVaults", entity.AuthToken("token")).Return([]entity.Vault{
+		{ID: "vaultID", Role: entity.RoleReadWrite, WrappedKey: wrapped},
+		{ID: "readOnlyID", Role: entity.RoleRead},
+	}, nil).Once()
+	conn.On("GetVaultRecords", entity.AuthToken("token"), "vaultID").Return([]entity.Record{shared}, nil).Once()
+	conn.On("GetVaultRecord", entity.AuthToken("token"), "vaultID", "3").Return(shared, nil).Once()
+	conn.On("UpdateVaultRecord", entity.AuthToken("token"), mock.AnythingOfType("entity.Record")).
+		Run(func(args mock.Arguments) {
+			migratedShared = args.Get(1).(entity.Record)
+		}).Return(nil).Once()
+
+	ids, err := handlers.MigrateLabels()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2", "3"}, ids)
+	assert.False(t, hasPlainLabels(migrated))
+	assert.Equal(t, pkg.BlindIndex(handlers.masterKey, "bank deposit ../../.bashrc"), migrated.BlindIndex)
+
+	migratedShared, err = openVaultRecord(vaultKey, migratedShared, false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Wi-Fi", migratedShared.Name)
+	assert.Equal(t, []string{"network"}, migratedShared.Tags)
+
+	t.Log("Search by blind index and encrypted tags, page is made by client")
+	sent.ID = "1"
+	migrated.Tags = []string{tag}
+	conn.On("ListRecords", entity.AuthToken("token"), entity.RecordsQuery{
+		Search:     "BANK",
+		BlindIndex: pkg.BlindIndex(handlers.masterKey, "bank"),
+		Tags:       []string{tag},
+		Limit:      entity.RecordsMaxLimit,
+	}).Return(entity.RecordsPage{Records: []entity.Record{migrated, sent}, Total: 2}, nil).Once()
+
+	page, err := handlers.ListRecords(entity.RecordsQuery{Search: "BANK", Tags: []string{"Finance"}, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), page.Total)
+	assert.Len(t, page.Records, 1)
+	assert.Equal(t, "Bank", page.Records[0].Name)
+
+	t.Log("Metadata of versions is decrypted")
+	conn.On("GetRecordVersions", entity.AuthToken("token"), "1").
+		Return([]entity.RecordVersion{{RecordID: "1", Version: 1, Metadata: sent.Metadata}}, nil).Once()
+
+	versions, err := handlers.GetRecordVersions("1")
+	assert.NoError(t, err)
+	assert.Equal(t, "main account", versions[0].Metadata)
+
+	t.Log("Metadata encrypted by another key")
+	handlers.masterKey = bytes.Repeat([]byte{0x24}, 32)
+	conn.On("GetRecordsInfo", entity.AuthToken("token")).Return([]entity.Record{sent}, nil).Once()
+
+	_, err = handlers.GetRecordsInfo()
+	assert.Equal(t, controller.ErrDataCorrupted, err)
+
+	conn.AssertExpectations(t)
+}
+
+func TestClient_Vaults(t *testing.T) {
+	aliceConn, bobConn := mocks.NewClientConn(t), mocks.NewClientConn(t)
+	alice, bob := newClientHandlers(aliceConn, false), newClientHandlers(bobConn, false)
+	alice.authToken, alice.masterKey = "alice", bytes.Repeat([]byte{0x01}, 32)
+	bob.authToken, bob.masterKey = "bob", bytes.Repeat([]byte{0x02}, 32)
+
+	var vault entity.Vault
+
+	t.Log("Owner creates vault with key wrapped for own public key")
+	aliceConn.On("CreateVault", entity.AuthToken("alice"), mock.AnythingOfType("entity.Vault")).
+		Run(func(args mock.Arguments) {
+			vault = args.Get(1).(entity.Vault)
+		}).Return("vaultID", nil).Once()
+
+	vaultID, err := alice.CreateVault("team")
+	assert.NoError(t, err)
+	assert.Equal(t, "vaultID", vaultID)
+	assert.Equal(t, "team", vault.Name)
+
+	t.Log("Owner invites member, vault key is wrapped by public key of member, which matches fingerprint")
+	bobPublic, err := pkg.PublicKey(bob.masterKey)
+	assert.NoError(t, err)
+
+	bobFingerprint, err := bob.Fingerprint()
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.KeyFingerprint(bobPublic), bobFingerprint)
+
+	var member entity.VaultMember
+
+	aliceConn.On("GetPublicKey", entity.AuthToken("alice"), "bob").Return(bobPublic, nil).Once()
+	aliceConn.On("InviteMember", entity.AuthToken("alice"), mock.AnythingOfType("entity.VaultMember")).
+		Run(func(args mock.Arguments) {
+			member = args.Get(1).(entity.VaultMember)
+		}).Return(nil).Once()
+
+	assert.NoError(t, alice.InviteMember("vaultID", "bob", bobFingerprint, entity.RoleReadWrite))
+	assert.Equal(t, entity.RoleReadWrite, member.Role)
+	assert.True(t, pkg.CheckMemberKeyMAC(alice.masterKey, "vaultID", "bob", bobPublic, member.KeyMAC))
+	assert.Equal(t, controller.ErrBadRole, alice.InviteMember("vaultID", "bob", bobFingerprint, entity.RoleOwner))
+	assert.Equal(t, controller.ErrFieldIsEmpty, alice.InviteMember("vaultID", "bob", "", entity.RoleRead))
+
+	t.Log("Server substitutes public key of member, it doesn't match fingerprint")
+	alicePublic, err := pkg.PublicKey(alice.masterKey)
+	assert.NoError(t, err)
+
+	aliceConn.On("GetPublicKey", entity.AuthToken("alice"), "bob").Return(alicePublic, nil).Once()
+	assert.Equal(t, controller.ErrBadFingerprint, alice.InviteMember("vaultID", "bob", bobFingerprint, entity.RoleRead))
+
+	t.Log("Owner creates record, server gets only ciphertext")
+	var sent entity.Record
+
+	aliceConn.On("CreateVaultRecord", entity.AuthToken("alice"), mock.AnythingOfType("entity.Record")).
+		Run(func(args mock.Arguments) {
+			sent = args.Get(1).(entity.Record)
+		}).Return("recordID", nil).Once()
+
+	recordID, err := alice.CreateVaultRecord(entity.Record{
+		VaultID:  "vaultID",
+		Type:     entity.TypeText,
+		Name:     "Wi-Fi",
+		Metadata: "office",
+		Tags:     []string{"Network"},
+		Folder:   "Office",
+		Data:     []byte("secret"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "recordID", recordID)
+	assert.True(t, pkg.IsEncryptedMetadata(sent.Name))
+	assert.True(t, pkg.IsEncryptedMetadata(sent.Metadata))
+	assert.True(t, pkg.IsEncryptedLabel(sent.Tags[0]))
+	assert.True(t, pkg.IsEncryptedLabel(sent.Folder))
+	assert.NotContains(t, string(sent.Data), "secret")
+
+	t.Log("Member unwraps vault key and reads record")
+	sent.ID = "recordID"
+	bobConn.On("GetVaults", entity.AuthToken("bob")).Return([]entity.Vault{
+		{ID: "vaultID", Name: "team", Role: entity.RoleReadWrite, WrappedKey: member.WrappedKey},
+	}, nil).Once()
+	bobConn.On("GetVaultRecords", entity.AuthToken("bob"), "vaultID").Return([]entity.Record{sent}, nil).Once()
+	bobConn.On("GetVaultRecord", entity.AuthToken("bob"), "vaultID", "recordID").Return(sent, nil).Once()
+
+	records, err := bob.GetVaultRecords("vaultID")
+	assert.NoError(t, err)
+	assert.Equal(t, "Wi-Fi", records[0].Name)
+
+	record, err := bob.GetVaultRecord("vaultID", "recordID")
+	assert.NoError(t, err)
+	assert.Equal(t, "office", record.Metadata)
+	assert.Equal(t, []string{"network"}, record.Tags)
+	assert.Equal(t, "Office", record.Folder)
+	assert.Equal(t, []byte("secret"), record.Data)
+
+	t.Log("Tags in clear are rejected")
+	plain := sent
+	plain.Tags = []string{"network"}
+	bobConn.On("GetVaultRecords", entity.AuthToken("bob"), "vaultID").Return([]entity.Record{plain}, nil).Once()
+
+	_, err = bob.GetVaultRecords("vaultID")
+	assert.Equal(t, controller.ErrPlainLabels, err)
+
+	t.Log("Owner revokes member, vault key is replaced for others, records are encrypted by new key")
+	carolKey := bytes.Repeat([]byte{0x03}, 32)
+	carolPublic, err := pkg.PublicKey(carolKey)
+	assert.NoError(t, err)
+
+	members := []entity.VaultMember{
+		{VaultID: "vaultID", Login: "alice", Role: entity.RoleOwner},
+		{VaultID: "vaultID", Login: "bob", Role: entity.RoleReadWrite, KeyMAC: member.KeyMAC},
+		{
+			VaultID: "vaultID", Login: "carol", Role: entity.RoleRead,
+			KeyMAC: pkg.MemberKeyMAC(alice.masterKey, "vaultID", "carol", carolPublic),
+		},
+	}
+
+	var rotation entity.VaultRotation
+
+	aliceConn.On("GetVaultMembers", entity.AuthToken("alice"), "vaultID").Return(members, nil).Once()
+	aliceConn.On("GetPublicKey", entity.AuthToken("alice"), "carol").Return(carolPublic, nil).Once()
+	aliceConn.On("GetVaultRecords", entity.AuthToken("alice"), "vaultID").Return([]entity.Record{plain}, nil).Once()
+	aliceConn.On("GetVaultRecord", entity.AuthToken("alice"), "vaultID", "recordID").Return(plain, nil).Once()
+	aliceConn.On("RevokeMember", entity.AuthToken("alice"), mock.AnythingOfType("entity.VaultRotation")).
+		Run(func(args mock.Arguments) {
+			rotation = args.Get(1).(entity.VaultRotation)
+		}).Return(nil).Once()
+
+	oldKey := alice.vaultKeys["vaultID"]
+	assert.NoError(t, alice.RevokeMember("vaultID", "bob"))
+	assert.Equal(t, "bob", rotation.Revoked)
+	assert.Len(t, rotation.Members, 2)
+
+	newKey, err := pkg.UnwrapKey(carolKey, rotation.Members[1].WrappedKey)
+	assert.NoError(t, err)
+	assert.NotEqual(t, oldKey, newKey)
+	assert.Equal(t, newKey, alice.vaultKeys["vaultID"])
+
+	reencrypted, err := openVaultRecord(newKey, rotation.Records[0], false)
+	assert.NoError(t, err)
+	assert.Equal(t, "Wi-Fi", reencrypted.Name)
+	assert.Equal(t, []string{"network"}, reencrypted.Tags)
+	assert.Equal(t, []byte("secret"), reencrypted.Data)
+
+	_, err = openVaultRecord(oldKey, rotation.Records[0], false)
+	assert.Equal(t, controller.ErrDataCorrupted, err)
+
+	t.Log("Server substitutes public key of member, who stays, vault key isn't replaced")
+	aliceConn.On("GetVaultMembers", entity.AuthToken("alice"), "vaultID").Return(members, nil).Once()
+	aliceConn.On("GetPublicKey", entity.AuthToken("alice"), "bob").Return(alicePublic, nil).Once()
+
+	assert.Equal(t, controller.ErrKeyNotVerified, alice.RevokeMember("vaultID", "carol"))
+	assert.Equal(t, newKey, alice.vaultKeys["vaultID"])
+
+	t.Log("Revoke user, who isn't member")
+	aliceConn.On("GetVaultMembers", entity.AuthToken("alice"), "vaultID").Return(members[:1], nil).Once()
+	assert.Equal(t, storage.ErrNotFound, alice.RevokeMember("vaultID", "bob"))
+
+	t.Log("Vault key wrapped for another user")
+	bob.vaultKeys = make(map[string][]byte)
+	bobConn.On("GetVaults", entity.AuthToken("bob")).Return([]entity.Vault{
+		{ID: "vaultID", Name: "team", Role: entity.RoleRead, WrappedKey: vault.WrappedKey},
+	}, nil).Once()
+
+	_, err = bob.GetVaultRecord("vaultID", "recordID")
+	assert.Equal(t, controller.ErrDataCorrupted, err)
+
+	t.Log("File records can't be shared")
+	_, err = alice.CreateVaultRecord(entity.Record{VaultID: "vaultID", Type: entity.TypeFile})
+	assert.Equal(t, storage.ErrNotSupported, err)
+
+	aliceConn.AssertExpectations(t)
+	bobConn.AssertExpectations(t)
+}
+
 func Test_GenerateRandom(t *testing.T) {
 	bytes, err := pkg.GenerateRandom(12)
 	assert.NoError(t, err)
@@ -0,0 +1,472 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Records of shared vault are encrypted by vault key: data, metadata, name, tags and folder, so server never
+// sees them. Vault key is wrapped by X25519 public key of every member, public keys are derived from record keys
+// of users and registered on signup or login. Public key of invited user is checked by fingerprint, which user
+// tells owner by another channel, and owner binds it to vault by MAC, so server can't substitute it later.
+// When member is revoked, vault key is replaced and records are encrypted by new one. Records, which member
+// has already got, stay readable for them.
+
+// CreateVault creates shared vault with new random key, which is wrapped for user. Returns ID of vault.
+func (c *client) CreateVault(name string) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	if name == "" {
+		return "", controller.ErrFieldIsEmpty
+	}
+
+	if err := c.writable(); err != nil {
+		return "", err
+	}
+
+	vaultKey, err := pkg.NewVaultKey()
+	if err != nil {
+		log.Warnf("%s :: %v", "generate vault key fault", err)
+
+		return "", storage.ErrUnknown
+	}
+
+	publicKey, err := pkg.PublicKey(c.masterKey)
+	if err != nil {
+		log.Warnf("%s :: %v", "derive public key fault", err)
+
+		return "", controller.ErrWrongMasterKey
+	}
+
+	wrapped, err := pkg.WrapKey(publicKey, vaultKey)
+	if err != nil {
+		log.Warnf("%s :: %v", "wrap vault key fault", err)
+
+		return "", cryptoError(err)
+	}
+
+	vaultID, err := c.conn.CreateVault(c.authToken, entity.Vault{Name: name, WrappedKey: wrapped})
+	if err != nil {
+		return "", err
+	}
+
+	c.vaultKeys[vaultID] = vaultKey
+
+	return vaultID, nil
+}
+
+// GetVaults gets vaults, which user is member of.
+func (c *client) GetVaults() ([]entity.Vault, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	return c.conn.GetVaults(c.authToken)
+}
+
+// GetVaultMembers gets members of vault with their roles.
+func (c *client) GetVaultMembers(vaultID string) ([]entity.VaultMember, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	return c.conn.GetVaultMembers(c.authToken, vaultID)
+}
+
+// Fingerprint gets fingerprint of public key of user, which user tells vault owner to be invited.
+// User on legacy key has no public key, until key is upgraded.
+func (c *client) Fingerprint() (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if len(c.masterKey) == 0 {
+		return "", controller.ErrLocked
+	}
+
+	if err := c.writable(); err != nil {
+		return "", err
+	}
+
+	publicKey, err := pkg.PublicKey(c.masterKey)
+	if err != nil {
+		log.Warnf("%s :: %v", "derive public key fault", err)
+
+		return "", controller.ErrWrongMasterKey
+	}
+
+	return pkg.KeyFingerprint(publicKey), nil
+}
+
+// InviteMember wraps vault key by public key of user with login and adds the user to vault with role.
+// Public key, which server returns, must match fingerprint, which user told. Only owner can invite members.
+func (c *client) InviteMember(vaultID, login, fingerprint string, role entity.VaultRole) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	if vaultID == "" || login == "" || fingerprint == "" {
+		return controller.ErrFieldIsEmpty
+	}
+
+	if role != entity.RoleRead && role != entity.RoleReadWrite {
+		return controller.ErrBadRole
+	}
+
+	vaultKey, err := c.vaultKey(vaultID)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := c.conn.GetPublicKey(c.authToken, login)
+	if err != nil {
+		return err
+	}
+
+	if !pkg.CheckFingerprint(publicKey, fingerprint) {
+		log.Warnf("%s :: %s", "public key of user doesn't match fingerprint", login)
+
+		return controller.ErrBadFingerprint
+	}
+
+	wrapped, err := wrapVaultKey(publicKey, vaultKey)
+	if err != nil {
+		return err
+	}
+
+	return c.conn.InviteMember(c.authToken, entity.VaultMember{
+		VaultID:    vaultID,
+		Login:      login,
+		Role:       role,
+		WrappedKey: wrapped,
+		KeyMAC:     pkg.MemberKeyMAC(c.masterKey, vaultID, login, publicKey),
+	})
+}
+
+// RevokeMember removes member with login from vault and replaces vault key: new key is wrapped for members,
+// who stay, and all records are encrypted by it, server replaces all of them at once.
+// Only owner can revoke members.
+func (c *client) RevokeMember(vaultID, login string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	if vaultID == "" || login == "" {
+		return controller.ErrFieldIsEmpty
+	}
+
+	vaultKey, err := c.vaultKey(vaultID)
+	if err != nil {
+		return err
+	}
+
+	members, err := c.conn.GetVaultMembers(c.authToken, vaultID)
+	if err != nil {
+		return err
+	}
+
+	newKey, err := pkg.NewVaultKey()
+	if err != nil {
+		log.Warnf("%s :: %v", "generate vault key fault", err)
+
+		return storage.ErrUnknown
+	}
+
+	rotation := entity.VaultRotation{VaultID: vaultID, Revoked: login}
+	for _, member := range members {
+		if member.Login == login {
+			continue
+		}
+
+		wrapped, err := c.wrapForMember(member, newKey)
+		if err != nil {
+			return err
+		}
+
+		rotation.Members = append(rotation.Members, entity.VaultMember{
+			VaultID:    vaultID,
+			Login:      member.Login,
+			Role:       member.Role,
+			WrappedKey: wrapped,
+		})
+	}
+
+	if len(rotation.Members) == len(members) {
+		return storage.ErrNotFound
+	}
+
+	records, err := c.conn.GetVaultRecords(c.authToken, vaultID)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range records {
+		record, err := c.conn.GetVaultRecord(c.authToken, vaultID, info.ID)
+		if err != nil {
+			return err
+		}
+
+		// Labels in clear of old records are encrypted here too.
+		if record, err = openVaultRecord(vaultKey, record, true); err != nil {
+			return err
+		}
+
+		if record, err = sealVaultRecord(newKey, record); err != nil {
+			return err
+		}
+
+		rotation.Records = append(rotation.Records, record)
+	}
+
+	if err = c.conn.RevokeMember(c.authToken, rotation); err != nil {
+		return err
+	}
+
+	c.vaultKeys[vaultID] = newKey
+
+	return nil
+}
+
+// wrapForMember wraps vault key for member. Public key of owner is derived from record key,
+// public keys of other members must be bound to vault by MAC, which owner made on invite.
+func (c *client) wrapForMember(member entity.VaultMember, vaultKey []byte) ([]byte, error) {
+	if member.Role == entity.RoleOwner {
+		publicKey, err := pkg.PublicKey(c.masterKey)
+		if err != nil {
+			log.Warnf("%s :: %v", "derive public key fault", err)
+
+			return nil, controller.ErrWrongMasterKey
+		}
+
+		return wrapVaultKey(publicKey, vaultKey)
+	}
+
+	publicKey, err := c.conn.GetPublicKey(c.authToken, member.Login)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pkg.CheckMemberKeyMAC(c.masterKey, member.VaultID, member.Login, publicKey, member.KeyMAC) {
+		log.Warnf("%s :: %s", "public key of member isn't verified", member.Login)
+
+		return nil, controller.ErrKeyNotVerified
+	}
+
+	return wrapVaultKey(publicKey, vaultKey)
+}
+
+// wrapVaultKey wraps vault key by public key.
+func wrapVaultKey(publicKey, vaultKey []byte) ([]byte, error) {
+	wrapped, err := pkg.WrapKey(publicKey, vaultKey)
+	if errors.Is(err, pkg.ErrBadPublicKey) {
+		return nil, controller.ErrBadPublicKey
+	}
+	if err != nil {
+		log.Warnf("%s :: %v", "wrap vault key fault", err)
+
+		return nil, cryptoError(err)
+	}
+
+	return wrapped, nil
+}
+
+// GetVaultRecords gets records of vault without data. Metadata and names are decrypted.
+func (c *client) GetVaultRecords(vaultID string) ([]entity.Record, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	vaultKey, err := c.vaultKey(vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := c.conn.GetVaultRecords(c.authToken, vaultID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if records[i], err = openVaultRecord(vaultKey, records[i], false); err != nil {
+			return nil, err
+		}
+	}
+
+	return records, nil
+}
+
+// GetVaultRecord gets record of vault and decrypts it.
+func (c *client) GetVaultRecord(vaultID, recordID string) (entity.Record, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	vaultKey, err := c.vaultKey(vaultID)
+	if err != nil {
+		return entity.Record{}, err
+	}
+
+	record, err := c.conn.GetVaultRecord(c.authToken, vaultID, recordID)
+	if err != nil {
+		return record, err
+	}
+
+	return openVaultRecord(vaultKey, record, false)
+}
+
+// CreateVaultRecord encrypts record by key of its vault and creates it. File records can't be shared.
+// Returns ID of record.
+func (c *client) CreateVaultRecord(record entity.Record) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	if record.Type == entity.TypeFile {
+		return "", storage.ErrNotSupported
+	}
+
+	vaultKey, err := c.vaultKey(record.VaultID)
+	if err != nil {
+		return "", err
+	}
+
+	record, err = sealVaultRecord(vaultKey, record)
+	if err != nil {
+		return "", err
+	}
+
+	return c.conn.CreateVaultRecord(c.authToken, record)
+}
+
+// UpdateVaultRecord encrypts new data of record by key of its vault and replaces it.
+func (c *client) UpdateVaultRecord(record entity.Record) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	if record.Type == entity.TypeFile {
+		return storage.ErrNotSupported
+	}
+
+	vaultKey, err := c.vaultKey(record.VaultID)
+	if err != nil {
+		return err
+	}
+
+	record, err = sealVaultRecord(vaultKey, record)
+	if err != nil {
+		return err
+	}
+
+	return c.conn.UpdateVaultRecord(c.authToken, record)
+}
+
+// DeleteVaultRecord deletes record from vault.
+func (c *client) DeleteVaultRecord(vaultID, recordID string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	c.renew()
+
+	return c.conn.DeleteVaultRecord(c.authToken, vaultID, recordID)
+}
+
+// vaultKey gets key of vault. Key is unwrapped by record key of user once and kept until logout.
+func (c *client) vaultKey(vaultID string) ([]byte, error) {
+	if vaultKey, ok := c.vaultKeys[vaultID]; ok {
+		return vaultKey, nil
+	}
+
+	vaults, err := c.conn.GetVaults(c.authToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, vault := range vaults {
+		if vault.ID == vaultID {
+			return c.openVaultKey(vault)
+		}
+	}
+
+	return nil, storage.ErrNotFound
+}
+
+// openVaultKey unwraps key of vault by record key of user and keeps it until logout.
+func (c *client) openVaultKey(vault entity.Vault) ([]byte, error) {
+	if vaultKey, ok := c.vaultKeys[vault.ID]; ok {
+		return vaultKey, nil
+	}
+
+	vaultKey, err := pkg.UnwrapKey(c.masterKey, vault.WrappedKey)
+	if err != nil {
+		log.Warnf("%s :: %v", "unwrap vault key fault", err)
+
+		return nil, cryptoError(err)
+	}
+
+	c.vaultKeys[vault.ID] = vaultKey
+
+	return vaultKey, nil
+}
+
+// sealVaultRecord encrypts data, metadata, name, tags and folder of vault record by vault key.
+func sealVaultRecord(vaultKey []byte, record entity.Record) (entity.Record, error) {
+	record.Tags, record.Folder = entity.NormalizeTags(record.Tags), entity.NormalizeFolder(record.Folder)
+
+	record, err := sealRecordLabels(vaultKey, record, nil)
+	if err != nil {
+		return record, err
+	}
+
+	data, err := pkg.EncryptBytes(vaultKey, record.Data)
+	if err != nil {
+		log.Warnf("%s :: %v", "encrypt data fault", err)
+
+		return record, cryptoError(err)
+	}
+
+	record.Data = data
+
+	return record, nil
+}
+
+// openVaultRecord decrypts labels and data (if it's got) of vault record by vault key.
+// Tags and folder in clear of records, which were shared before they were encrypted, are accepted
+// only if plain is set, by migration and replacement of vault key.
+func openVaultRecord(vaultKey []byte, record entity.Record, plain bool) (entity.Record, error) {
+	record, err := openRecordLabels(vaultKey, record, plain)
+	if err != nil {
+		return record, err
+	}
+
+	if len(record.Data) == 0 {
+		return record, nil
+	}
+
+	data, err := pkg.DecryptBytes(vaultKey, record.Data)
+	if err != nil {
+		log.Warnf("%s :: %v", "decrypt data fault", err)
+
+		return record, cryptoError(err)
+	}
+
+	record.Data = data
+
+	return record, nil
+}
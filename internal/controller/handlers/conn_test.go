@@ -1,26 +1,47 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/config"
+	"github.com/bbt-t/lets-go-keep/internal/controller"
 	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
 	"github.com/bbt-t/lets-go-keep/internal/entity"
 	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
+// newTokenAuthenticator returns authenticator mock, which accepts only "token" of "userID".
+func newTokenAuthenticator(t *testing.T) *mocks.Authenticator {
+	auth := mocks.NewAuthenticator(t)
+	auth.On("ValidateToken", entity.AuthToken("token")).Return(entity.UserID("userID"), nil).Maybe()
+	auth.On("ValidateToken", mock.AnythingOfType("entity.AuthToken")).
+		Return(entity.UserID(""), storage.ErrUnauthenticated).Maybe()
+
+	return auth
+}
+
 func TestCreateUser(t *testing.T) {
 	serverCfg, handlers := config.NewServerConfig(), mocks.NewServerHandlers(t)
 
-	server := NewServerConn(handlers)
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
 	server.Run(context.Background(), serverCfg.RunAddress)
 	defer server.Stop()
 
-	client := newClientConn(serverCfg.RunAddress)
+	client := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second)
 
 	tc := []struct {
 		name  string
@@ -33,15 +54,15 @@ func TestCreateUser(t *testing.T) {
 				handlers.On("CreateUser", entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
-				}).Return(entity.AuthToken("token"), nil).Once()
+				}).Return(entity.Session{Token: "token"}, nil).Once()
 			},
 			func() {
-				token, err := client.Register(entity.UserCredentials{
+				session, err := client.Register(entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
 				})
 				assert.NoError(t, err)
-				assert.Equal(t, "token", token)
+				assert.Equal(t, entity.Session{Token: "token"}, session)
 			},
 		},
 		{
@@ -50,15 +71,15 @@ func TestCreateUser(t *testing.T) {
 				handlers.On("CreateUser", entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
-				}).Return(entity.AuthToken(""), storage.ErrLoginExists).Once()
+				}).Return(entity.Session{}, storage.ErrLoginExists).Once()
 			},
 			func() {
-				token, err := client.Register(entity.UserCredentials{
+				session, err := client.Register(entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
 				})
 				assert.Equal(t, storage.ErrLoginExists, err)
-				assert.Empty(t, token)
+				assert.Empty(t, session)
 			},
 		},
 		{
@@ -67,15 +88,60 @@ func TestCreateUser(t *testing.T) {
 				handlers.On("CreateUser", entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
-				}).Return(entity.AuthToken(""), storage.ErrUnknown).Once()
+				}).Return(entity.Session{}, storage.ErrUnknown).Once()
 			},
 			func() {
-				token, err := client.Register(entity.UserCredentials{
+				session, err := client.Register(entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
 				})
 				assert.Equal(t, storage.ErrUnknown, err)
-				assert.Empty(t, token)
+				assert.Empty(t, session)
+			},
+		},
+		{
+			"Create user with empty login",
+			func() {
+				handlers.On("CreateUser", entity.UserCredentials{
+					Password: "Password",
+				}).Return(entity.Session{}, controller.ErrFieldIsEmpty).Once()
+			},
+			func() {
+				session, err := client.Register(entity.UserCredentials{
+					Password: "Password",
+				})
+				assert.Equal(t, controller.ErrFieldIsEmpty, err)
+				assert.Empty(t, session)
+			},
+		},
+		{
+			"Create user with bad key derivation parameters",
+			func() {
+				handlers.On("CreateUser", entity.UserCredentials{
+					Password: "Password",
+				}).Return(entity.Session{}, pkg.ErrBadKDFParams).Once()
+			},
+			func() {
+				session, err := client.Register(entity.UserCredentials{
+					Password: "Password",
+				})
+				assert.Equal(t, pkg.ErrBadKDFParams, err)
+				assert.Empty(t, session)
+			},
+		},
+		{
+			"Create user with bad public key",
+			func() {
+				handlers.On("CreateUser", entity.UserCredentials{
+					Password: "Password",
+				}).Return(entity.Session{}, controller.ErrBadPublicKey).Once()
+			},
+			func() {
+				session, err := client.Register(entity.UserCredentials{
+					Password: "Password",
+				})
+				assert.Equal(t, controller.ErrBadPublicKey, err)
+				assert.Empty(t, session)
 			},
 		},
 	}
@@ -90,9 +156,9 @@ func TestCreateUser(t *testing.T) {
 
 func TestLoginUser(t *testing.T) {
 	serverCfg := config.NewServerConfig()
-	client, handlers := newClientConn(serverCfg.RunAddress), mocks.NewServerHandlers(t)
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
 
-	server := NewServerConn(handlers)
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
 	server.Run(context.Background(), serverCfg.RunAddress)
 	defer server.Stop()
 
@@ -107,15 +173,15 @@ func TestLoginUser(t *testing.T) {
 				handlers.On("LoginUser", entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
-				}).Return(entity.AuthToken("token"), nil).Once()
+				}).Return(entity.Session{Token: "token"}, nil).Once()
 			},
 			func() {
-				token, err := client.Login(entity.UserCredentials{
+				session, err := client.Login(entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
 				})
 				assert.NoError(t, err)
-				assert.Equal(t, "token", token)
+				assert.Equal(t, entity.Session{Token: "token"}, session)
 			},
 		},
 		{
@@ -124,15 +190,15 @@ func TestLoginUser(t *testing.T) {
 				handlers.On("LoginUser", entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
-				}).Return(entity.AuthToken(""), storage.ErrWrongCredentials).Once()
+				}).Return(entity.Session{}, storage.ErrWrongCredentials).Once()
 			},
 			func() {
-				token, err := client.Login(entity.UserCredentials{
+				session, err := client.Login(entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
 				})
 				assert.Equal(t, storage.ErrWrongCredentials, err)
-				assert.Empty(t, token)
+				assert.Empty(t, session)
 			},
 		},
 		{
@@ -141,15 +207,15 @@ func TestLoginUser(t *testing.T) {
 				handlers.On("LoginUser", entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
-				}).Return(entity.AuthToken(""), storage.ErrUnknown).Once()
+				}).Return(entity.Session{}, storage.ErrUnknown).Once()
 			},
 			func() {
-				token, err := client.Login(entity.UserCredentials{
+				session, err := client.Login(entity.UserCredentials{
 					Login:    "Login",
 					Password: "Password",
 				})
 				assert.Equal(t, storage.ErrUnknown, err)
-				assert.Empty(t, token)
+				assert.Empty(t, session)
 			},
 		},
 	}
@@ -164,9 +230,9 @@ func TestLoginUser(t *testing.T) {
 
 func TestGetRecordsInfo(t *testing.T) {
 	serverCfg := config.NewServerConfig()
-	client, handlers := newClientConn(serverCfg.RunAddress), mocks.NewServerHandlers(t)
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
 
-	server := NewServerConn(handlers)
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
 	server.Run(context.Background(), serverCfg.RunAddress)
 	defer server.Stop()
 
@@ -218,11 +284,101 @@ func TestGetRecordsInfo(t *testing.T) {
 	}
 }
 
+func TestListRecords(t *testing.T) {
+	serverCfg := config.NewServerConfig()
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	query := entity.RecordsQuery{
+		Search:     "bank",
+		Tags:       []string{"finance"},
+		Folder:     "work",
+		Types:      []entity.RecordType{entity.TypeLoginAndPassword, entity.TypeOTP},
+		Sort:       entity.SortByUpdatedAt,
+		Descending: true,
+		Offset:     10,
+		Limit:      5,
+	}
+	page := entity.RecordsPage{
+		Records: []entity.Record{{
+			ID:       "1",
+			Type:     entity.TypeLoginAndPassword,
+			Metadata: "login",
+			Name:     "Bank",
+			Tags:     []string{"finance"},
+			Folder:   "work",
+			Revision: 3,
+		}},
+		Total: 11,
+	}
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"List records",
+			func() {
+				handlers.On("ListRecords", mock.AnythingOfType("*context.valueCtx"), query).Return(page, nil).Once()
+			},
+			func() {
+				got, err := client.ListRecords("token", query)
+				assert.NoError(t, err)
+				assert.Equal(t, page, got)
+			},
+		},
+		{
+			"List records, but server will return error",
+			func() {
+				handlers.On("ListRecords", mock.AnythingOfType("*context.valueCtx"), query).
+					Return(entity.RecordsPage{}, storage.ErrUnauthenticated).Once()
+			},
+			func() {
+				_, err := client.ListRecords("token", query)
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+		{
+			"List records, but server will return unknown error",
+			func() {
+				handlers.On("ListRecords", mock.AnythingOfType("*context.valueCtx"), query).
+					Return(entity.RecordsPage{}, storage.ErrUnknown).Once()
+			},
+			func() {
+				_, err := client.ListRecords("token", query)
+				assert.Equal(t, storage.ErrUnknown, err)
+			},
+		},
+		{
+			"List records with unknown sorting",
+			func() {
+				handlers.On("ListRecords", mock.AnythingOfType("*context.valueCtx"), query).
+					Return(entity.RecordsPage{}, entity.ErrBadQuery).Once()
+			},
+			func() {
+				_, err := client.ListRecords("token", query)
+				assert.Equal(t, entity.ErrBadQuery, err)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+		handlers.AssertExpectations(t)
+	}
+}
+
 func TestGetRecord(t *testing.T) {
 	serverCfg := config.NewServerConfig()
-	client, handlers := newClientConn(serverCfg.RunAddress), mocks.NewServerHandlers(t)
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
 
-	server := NewServerConn(handlers)
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
 	server.Run(context.Background(), serverCfg.RunAddress)
 	defer server.Stop()
 
@@ -287,9 +443,9 @@ func TestGetRecord(t *testing.T) {
 
 func TestCreateRecord(t *testing.T) {
 	serverCfg := config.NewServerConfig()
-	client, handlers := newClientConn(serverCfg.RunAddress), mocks.NewServerHandlers(t)
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
 
-	server := NewServerConn(handlers)
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
 	server.Run(context.Background(), serverCfg.RunAddress)
 	defer server.Stop()
 
@@ -305,11 +461,12 @@ func TestCreateRecord(t *testing.T) {
 					"CreateRecord",
 					mock.AnythingOfType("*context.valueCtx"),
 					entity.Record{},
-				).Return(nil).Once()
+				).Return("recordID", nil).Once()
 			},
 			func() {
-				err := client.CreateRecord("token", entity.Record{})
+				recordID, err := client.CreateRecord("token", entity.Record{})
 				assert.NoError(t, err)
+				assert.Equal(t, "recordID", recordID)
 			},
 		},
 		{
@@ -319,10 +476,10 @@ func TestCreateRecord(t *testing.T) {
 					"CreateRecord",
 					mock.AnythingOfType("*context.valueCtx"),
 					entity.Record{},
-				).Return(storage.ErrUnauthenticated).Once()
+				).Return("", storage.ErrUnauthenticated).Once()
 			},
 			func() {
-				err := client.CreateRecord("token", entity.Record{})
+				_, err := client.CreateRecord("token", entity.Record{})
 				assert.Equal(t, storage.ErrUnauthenticated, err)
 			},
 		},
@@ -333,10 +490,10 @@ func TestCreateRecord(t *testing.T) {
 					"CreateRecord",
 					mock.AnythingOfType("*context.valueCtx"),
 					entity.Record{},
-				).Return(storage.ErrUnknown).Once()
+				).Return("", storage.ErrUnknown).Once()
 			},
 			func() {
-				err := client.CreateRecord("token", entity.Record{})
+				_, err := client.CreateRecord("token", entity.Record{})
 				assert.Equal(t, storage.ErrUnknown, err)
 			},
 		},
@@ -352,9 +509,9 @@ func TestCreateRecord(t *testing.T) {
 
 func TestDeleteRecord(t *testing.T) {
 	serverCfg := config.NewServerConfig()
-	client, handlers := newClientConn(serverCfg.RunAddress), mocks.NewServerHandlers(t)
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
 
-	server := NewServerConn(handlers)
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
 	server.Run(context.Background(), serverCfg.RunAddress)
 	defer server.Stop()
 
@@ -370,10 +527,11 @@ func TestDeleteRecord(t *testing.T) {
 					"DeleteRecord",
 					mock.AnythingOfType("*context.valueCtx"),
 					"recordID",
+					int64(3),
 				).Return(nil).Once()
 			},
 			func() {
-				err := client.DeleteRecord("token", "recordID")
+				err := client.DeleteRecord("token", "recordID", 3)
 				assert.NoError(t, err)
 			},
 		},
@@ -384,10 +542,11 @@ func TestDeleteRecord(t *testing.T) {
 					"DeleteRecord",
 					mock.AnythingOfType("*context.valueCtx"),
 					"recordID",
+					int64(3),
 				).Return(storage.ErrUnauthenticated).Once()
 			},
 			func() {
-				err := client.DeleteRecord("token", "recordID")
+				err := client.DeleteRecord("token", "recordID", 3)
 				assert.Equal(t, storage.ErrUnauthenticated, err)
 			},
 		},
@@ -398,13 +557,29 @@ func TestDeleteRecord(t *testing.T) {
 					"DeleteRecord",
 					mock.AnythingOfType("*context.valueCtx"),
 					"recordID",
+					int64(3),
 				).Return(storage.ErrNotFound).Once()
 			},
 			func() {
-				err := client.DeleteRecord("token", "recordID")
+				err := client.DeleteRecord("token", "recordID", 3)
 				assert.Equal(t, storage.ErrNotFound, err)
 			},
 		},
+		{
+			"Delete record, but it was changed on another device.",
+			func() {
+				handlers.On(
+					"DeleteRecord",
+					mock.AnythingOfType("*context.valueCtx"),
+					"recordID",
+					int64(3),
+				).Return(storage.ErrConflict).Once()
+			},
+			func() {
+				err := client.DeleteRecord("token", "recordID", 3)
+				assert.Equal(t, storage.ErrConflict, err)
+			},
+		},
 		{
 			"Delete record, but unknown error.",
 			func() {
@@ -412,10 +587,99 @@ func TestDeleteRecord(t *testing.T) {
 					"DeleteRecord",
 					mock.AnythingOfType("*context.valueCtx"),
 					"recordID",
+					int64(3),
 				).Return(storage.ErrUnknown).Once()
 			},
 			func() {
-				err := client.DeleteRecord("token", "recordID")
+				err := client.DeleteRecord("token", "recordID", 3)
+				assert.Equal(t, storage.ErrUnknown, err)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+		handlers.AssertExpectations(t)
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	serverCfg := config.NewServerConfig()
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	// Bigger than default gRPC message size limit.
+	data := bytes.Repeat([]byte("encrypted"), 1<<19)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Upload file.",
+			func() {
+				handlers.On(
+					"UploadFile",
+					mock.AnythingOfType("*context.valueCtx"),
+					entity.Record{Metadata: "file.bin", Type: entity.TypeFile},
+					mock.AnythingOfType("*handlers.chunkReader"),
+				).Run(func(args mock.Arguments) {
+					received, err := io.ReadAll(args.Get(2).(io.Reader))
+					assert.NoError(t, err)
+					assert.Equal(t, data, received)
+				}).Return("recordID", nil).Once()
+			},
+			func() {
+				id, err := client.UploadFile(
+					"token",
+					entity.Record{Metadata: "file.bin", Type: entity.TypeFile},
+					bytes.NewReader(data),
+				)
+				assert.NoError(t, err)
+				assert.Equal(t, "recordID", id)
+			},
+		},
+		{
+			"Upload file, but not authenticated.",
+			func() {
+				handlers.On(
+					"UploadFile",
+					mock.AnythingOfType("*context.valueCtx"),
+					entity.Record{Metadata: "file.bin", Type: entity.TypeFile},
+					mock.AnythingOfType("*handlers.chunkReader"),
+				).Return("", storage.ErrUnauthenticated).Once()
+			},
+			func() {
+				_, err := client.UploadFile(
+					"token",
+					entity.Record{Metadata: "file.bin", Type: entity.TypeFile},
+					strings.NewReader("encrypted"),
+				)
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+		{
+			"Upload file, but unknown error.",
+			func() {
+				handlers.On(
+					"UploadFile",
+					mock.AnythingOfType("*context.valueCtx"),
+					entity.Record{Metadata: "file.bin", Type: entity.TypeFile},
+					mock.AnythingOfType("*handlers.chunkReader"),
+				).Return("", storage.ErrUnknown).Once()
+			},
+			func() {
+				_, err := client.UploadFile(
+					"token",
+					entity.Record{Metadata: "file.bin", Type: entity.TypeFile},
+					strings.NewReader("encrypted"),
+				)
 				assert.Equal(t, storage.ErrUnknown, err)
 			},
 		},
@@ -428,3 +692,485 @@ func TestDeleteRecord(t *testing.T) {
 		handlers.AssertExpectations(t)
 	}
 }
+
+func TestDownloadFile(t *testing.T) {
+	serverCfg := config.NewServerConfig()
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	// Bigger than default gRPC message size limit.
+	data := bytes.Repeat([]byte("encrypted"), 1<<19)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Download file.",
+			func() {
+				handlers.On("DownloadFile", mock.AnythingOfType("*context.valueCtx"), "recordID").Return(
+					entity.Record{ID: "recordID", Metadata: "file.bin", Type: entity.TypeFile},
+					io.NopCloser(bytes.NewReader(data)),
+					nil,
+				).Once()
+			},
+			func() {
+				var buf bytes.Buffer
+
+				record, err := client.DownloadFile("token", "recordID", &buf)
+				assert.NoError(t, err)
+				assert.Equal(t, entity.Record{ID: "recordID", Metadata: "file.bin", Type: entity.TypeFile}, record)
+				assert.Equal(t, data, buf.Bytes())
+			},
+		},
+		{
+			"Download file, but not found.",
+			func() {
+				handlers.On("DownloadFile", mock.AnythingOfType("*context.valueCtx"), "recordID").
+					Return(entity.Record{}, nil, storage.ErrNotFound).Once()
+			},
+			func() {
+				var buf bytes.Buffer
+
+				_, err := client.DownloadFile("token", "recordID", &buf)
+				assert.Equal(t, storage.ErrNotFound, err)
+				assert.Empty(t, buf.Bytes())
+			},
+		},
+		{
+			"Download file, but not authenticated.",
+			func() {
+				handlers.On("DownloadFile", mock.AnythingOfType("*context.valueCtx"), "recordID").
+					Return(entity.Record{}, nil, storage.ErrUnauthenticated).Once()
+			},
+			func() {
+				var buf bytes.Buffer
+
+				_, err := client.DownloadFile("token", "recordID", &buf)
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+		handlers.AssertExpectations(t)
+	}
+}
+
+func TestUpdateRecord(t *testing.T) {
+	serverCfg := config.NewServerConfig()
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	record := entity.Record{
+		ID:       "recordID",
+		Metadata: "metadata",
+		Type:     entity.TypeText,
+		Data:     []byte("data"),
+		Revision: 3,
+	}
+
+	tc := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"Update record.", nil, nil},
+		{"Update record, but it was changed on another device.", storage.ErrConflict, storage.ErrConflict},
+		{"Update record, but not authenticated.", storage.ErrUnauthenticated, storage.ErrUnauthenticated},
+		{"Update record, but not found.", storage.ErrNotFound, storage.ErrNotFound},
+		{"Update record, but type isn't supported.", storage.ErrNotSupported, storage.ErrNotSupported},
+		{"Update record, but unknown error.", storage.ErrUnknown, storage.ErrUnknown},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		handlers.On(
+			"UpdateRecord",
+			mock.AnythingOfType("*context.valueCtx"),
+			record,
+		).Return(test.err).Once()
+
+		err := client.UpdateRecord("token", record)
+		assert.Equal(t, test.want, err)
+		handlers.AssertExpectations(t)
+	}
+}
+
+func TestRecordVersions(t *testing.T) {
+	serverCfg := config.NewServerConfig()
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	versions := []entity.RecordVersion{
+		{
+			RecordID:   "recordID",
+			Version:    2,
+			Metadata:   "second",
+			ReplacedAt: time.Date(2023, 5, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			RecordID:   "recordID",
+			Version:    1,
+			Metadata:   "first",
+			ReplacedAt: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Get record versions.",
+			func() {
+				handlers.On(
+					"GetRecordVersions",
+					mock.AnythingOfType("*context.valueCtx"),
+					"recordID",
+				).Return(versions, nil).Once()
+			},
+			func() {
+				got, err := client.GetRecordVersions("token", "recordID")
+				assert.NoError(t, err)
+				assert.Equal(t, versions, got)
+			},
+		},
+		{
+			"Get record versions, but not authenticated.",
+			func() {
+				handlers.On(
+					"GetRecordVersions",
+					mock.AnythingOfType("*context.valueCtx"),
+					"recordID",
+				).Return(nil, storage.ErrUnauthenticated).Once()
+			},
+			func() {
+				got, err := client.GetRecordVersions("token", "recordID")
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+				assert.Empty(t, got)
+			},
+		},
+		{
+			"Restore record version.",
+			func() {
+				handlers.On(
+					"RestoreRecordVersion",
+					mock.AnythingOfType("*context.valueCtx"),
+					"recordID",
+					int32(1),
+					int64(3),
+				).Return(nil).Once()
+			},
+			func() {
+				err := client.RestoreRecordVersion("token", "recordID", 1, 3)
+				assert.NoError(t, err)
+			},
+		},
+		{
+			"Restore record version, but not found.",
+			func() {
+				handlers.On(
+					"RestoreRecordVersion",
+					mock.AnythingOfType("*context.valueCtx"),
+					"recordID",
+					int32(5),
+					int64(3),
+				).Return(storage.ErrNotFound).Once()
+			},
+			func() {
+				err := client.RestoreRecordVersion("token", "recordID", 5, 3)
+				assert.Equal(t, storage.ErrNotFound, err)
+			},
+		},
+		{
+			"Restore record version, but record was changed.",
+			func() {
+				handlers.On(
+					"RestoreRecordVersion",
+					mock.AnythingOfType("*context.valueCtx"),
+					"recordID",
+					int32(2),
+					int64(3),
+				).Return(storage.ErrConflict).Once()
+			},
+			func() {
+				err := client.RestoreRecordVersion("token", "recordID", 2, 3)
+				assert.Equal(t, storage.ErrConflict, err)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+		handlers.AssertExpectations(t)
+	}
+}
+
+func TestSync(t *testing.T) {
+	serverCfg := config.NewServerConfig()
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	changedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	changes := entity.RecordChanges{
+		Records: []entity.Record{
+			{ID: "1", Metadata: "text", Type: entity.TypeText, Data: []byte("data"), Revision: 4, UpdatedAt: changedAt},
+		},
+		Deleted:  []entity.Tombstone{{RecordID: "2", Revision: 5, DeletedAt: changedAt}},
+		Revision: 5,
+	}
+
+	t.Log("Sync records")
+	handlers.On("Sync", mock.AnythingOfType("*context.valueCtx"), int64(3)).Return(changes, nil).Once()
+
+	got, err := client.Sync("token", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, changes, got)
+
+	t.Log("Sync records, but not authenticated")
+	handlers.On("Sync", mock.AnythingOfType("*context.valueCtx"), int64(0)).
+		Return(entity.RecordChanges{}, storage.ErrUnauthenticated).Once()
+
+	_, err = client.Sync("token", 0)
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+
+	handlers.AssertExpectations(t)
+}
+
+func TestSessions(t *testing.T) {
+	serverCfg := config.NewServerConfig()
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	t.Log("Refresh session")
+	handlers.On("RefreshSession", entity.RefreshToken("refresh")).Return(entity.Session{
+		Token:        "new token",
+		RefreshToken: "new refresh",
+		ExpiresAt:    time.Now().Add(15 * time.Minute),
+	}, nil).Once()
+
+	session, err := client.RefreshSession("refresh")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.AuthToken("new token"), session.Token)
+	assert.Equal(t, entity.RefreshToken("new refresh"), session.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), session.ExpiresAt, 2*time.Second)
+
+	t.Log("Refresh revoked session")
+	handlers.On("RefreshSession", entity.RefreshToken("revoked")).
+		Return(entity.Session{}, storage.ErrUnauthenticated).Once()
+
+	_, err = client.RefreshSession("revoked")
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+
+	t.Log("Logout")
+	handlers.On("Logout", entity.RefreshToken("refresh")).Return(nil).Once()
+	assert.NoError(t, client.Logout("refresh"))
+
+	t.Log("Logout, but server will return unknown error")
+	handlers.On("Logout", entity.RefreshToken("refresh")).Return(storage.ErrUnknown).Once()
+	assert.Equal(t, storage.ErrUnknown, client.Logout("refresh"))
+
+	t.Log("Revoke all sessions")
+	handlers.On("RevokeSessions", mock.AnythingOfType("*context.valueCtx")).Return(nil).Once()
+	assert.NoError(t, client.RevokeSessions("token"))
+
+	t.Log("Revoke all sessions, but not authenticated")
+	handlers.On("RevokeSessions", mock.AnythingOfType("*context.valueCtx")).Return(storage.ErrUnauthenticated).Once()
+	assert.Equal(t, storage.ErrUnauthenticated, client.RevokeSessions("token"))
+
+	t.Log("Upgrade legacy key")
+	params := entity.KDFParams{Salt: []byte{0x01}, Time: 3, Memory: 65536, Threads: 4, Legacy: true}
+	handlers.On("UpgradeKDF", mock.AnythingOfType("*context.valueCtx"), params).Return(nil).Once()
+	assert.NoError(t, client.UpgradeKDF("token", params))
+
+	t.Log("Upgrade legacy key, but it's already upgraded")
+	handlers.On("UpgradeKDF", mock.AnythingOfType("*context.valueCtx"), params).Return(storage.ErrConflict).Once()
+	assert.Equal(t, storage.ErrConflict, client.UpgradeKDF("token", params))
+
+	t.Log("Upgrade legacy key with weak params")
+	handlers.On("UpgradeKDF", mock.AnythingOfType("*context.valueCtx"), params).Return(pkg.ErrBadKDFParams).Once()
+	assert.Equal(t, pkg.ErrBadKDFParams, client.UpgradeKDF("token", params))
+
+	handlers.AssertExpectations(t)
+}
+
+func TestVaults(t *testing.T) {
+	serverCfg := config.NewServerConfig()
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	ctx := mock.AnythingOfType("*context.valueCtx")
+
+	t.Log("Create vault")
+	handlers.On("CreateVault", ctx, entity.Vault{Name: "team", WrappedKey: []byte("key")}).Return("vaultID", nil).Once()
+
+	vaultID, err := client.CreateVault("token", entity.Vault{Name: "team", WrappedKey: []byte("key")})
+	assert.NoError(t, err)
+	assert.Equal(t, "vaultID", vaultID)
+
+	t.Log("Get vaults")
+	vaults := []entity.Vault{{ID: "vaultID", Name: "team", Role: entity.RoleOwner, WrappedKey: []byte("key")}}
+	handlers.On("GetVaults", ctx).Return(vaults, nil).Once()
+
+	gotVaults, err := client.GetVaults("token")
+	assert.NoError(t, err)
+	assert.Equal(t, vaults, gotVaults)
+
+	t.Log("Get public key of user without it")
+	handlers.On("GetPublicKey", ctx, "bob").Return(nil, storage.ErrNoPublicKey).Once()
+
+	_, err = client.GetPublicKey("token", "bob")
+	assert.Equal(t, storage.ErrNoPublicKey, err)
+
+	t.Log("Set public key, but user has another one")
+	handlers.On("SetPublicKey", ctx, []byte("public")).Return(storage.ErrConflict).Once()
+	assert.Equal(t, storage.ErrConflict, client.SetPublicKey("token", []byte("public")))
+
+	t.Log("Invite member, but user isn't owner")
+	member := entity.VaultMember{
+		VaultID: "vaultID", Login: "bob", Role: entity.RoleRead, WrappedKey: []byte("key"), KeyMAC: []byte("mac"),
+	}
+	handlers.On("InviteMember", ctx, member).Return(storage.ErrForbidden).Once()
+	assert.Equal(t, storage.ErrForbidden, client.InviteMember("token", member))
+
+	t.Log("Get vault members")
+	members := []entity.VaultMember{{Login: "alice", Role: entity.RoleOwner}, {Login: "bob", Role: entity.RoleRead}}
+	handlers.On("GetVaultMembers", ctx, "vaultID").Return(members, nil).Once()
+
+	gotMembers, err := client.GetVaultMembers("token", "vaultID")
+	assert.NoError(t, err)
+	assert.Equal(t, members, gotMembers)
+
+	t.Log("Revoke member of not found vault")
+	rotation := entity.VaultRotation{
+		VaultID: "otherID",
+		Revoked: "bob",
+		Members: []entity.VaultMember{{VaultID: "otherID", Login: "alice", Role: entity.RoleOwner, WrappedKey: []byte("new")}},
+		Records: []entity.Record{{ID: "recordID", VaultID: "otherID", Type: entity.TypeText, Data: []byte("data")}},
+	}
+	handlers.On("RevokeMember", ctx, rotation).Return(storage.ErrNotFound).Once()
+	assert.Equal(t, storage.ErrNotFound, client.RevokeMember("token", rotation))
+
+	t.Log("Revoke member, but vault was changed meanwhile")
+	handlers.On("RevokeMember", ctx, rotation).Return(storage.ErrConflict).Once()
+	assert.Equal(t, storage.ErrConflict, client.RevokeMember("token", rotation))
+
+	t.Log("Create and get vault record")
+	record := entity.Record{VaultID: "vaultID", Type: entity.TypeText, Metadata: "meta", Data: []byte("data")}
+	handlers.On("CreateVaultRecord", ctx, record).Return("recordID", nil).Once()
+	handlers.On("GetVaultRecord", ctx, "vaultID", "recordID").Return(record, nil).Once()
+
+	recordID, err := client.CreateVaultRecord("token", record)
+	assert.NoError(t, err)
+	assert.Equal(t, "recordID", recordID)
+
+	gotRecord, err := client.GetVaultRecord("token", "vaultID", "recordID")
+	assert.NoError(t, err)
+	assert.Equal(t, record, gotRecord)
+
+	t.Log("Update file record of vault")
+	handlers.On("UpdateVaultRecord", ctx, record).Return(storage.ErrNotSupported).Once()
+	assert.Equal(t, storage.ErrNotSupported, client.UpdateVaultRecord("token", record))
+
+	t.Log("Delete vault record, but not authenticated")
+	assert.Equal(t, storage.ErrUnauthenticated, client.DeleteVaultRecord("bad", "vaultID", "recordID"))
+
+	handlers.AssertExpectations(t)
+}
+
+func TestAuthInterceptors(t *testing.T) {
+	serverCfg := config.NewServerConfig()
+	client, handlers := newClientConn(serverCfg.RunAddress, insecure.NewCredentials(), time.Second), mocks.NewServerHandlers(t)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), insecure.NewCredentials())
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	withIdentity := mock.MatchedBy(func(ctx context.Context) bool {
+		identity, ok := entity.IdentityFromContext(ctx)
+		return ok && identity.UserID == "userID"
+	})
+
+	t.Log("Identity of user is in context of unary call")
+	handlers.On("GetRecordsInfo", withIdentity).Return([]entity.Record{}, nil).Once()
+
+	_, err := client.GetRecordsInfo("token")
+	assert.NoError(t, err)
+
+	t.Log("Identity of user is in context of stream call")
+	handlers.On("DownloadFile", withIdentity, "recordID").
+		Return(entity.Record{}, nil, storage.ErrNotFound).Once()
+
+	_, err = client.DownloadFile("token", "recordID", io.Discard)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	t.Log("Call without metadata is rejected")
+	_, err = client.GophkeeperClient.GetRecordsInfo(context.Background(), &emptypb.Empty{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	t.Log("Unary call with bad token is rejected")
+	_, err = client.GetRecordsInfo("bad token")
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+
+	_, err = client.GetRecordVersions("bad token", "recordID")
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+
+	t.Log("Stream call with bad token is rejected")
+	_, err = client.DownloadFile("bad token", "recordID", io.Discard)
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+
+	_, err = client.UploadFile("bad token", entity.Record{Type: entity.TypeFile}, strings.NewReader("data"))
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+
+	t.Log("Login doesn't need token")
+	handlers.On("LoginUser", entity.UserCredentials{Login: "Login", Password: "Password"}).
+		Return(entity.Session{Token: "token"}, nil).Once()
+
+	_, err = client.Login(entity.UserCredentials{Login: "Login", Password: "Password"})
+	assert.NoError(t, err)
+
+	handlers.AssertExpectations(t)
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	invoker := func(deadline bool) grpc.UnaryInvoker {
+		return func(ctx context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+			_, ok := ctx.Deadline()
+			assert.Equal(t, deadline, ok)
+
+			return nil
+		}
+	}
+
+	t.Log("Request with timeout")
+	assert.NoError(t, timeoutInterceptor(time.Second)(context.Background(), "", nil, nil, nil, invoker(true)))
+
+	t.Log("Request without timeout")
+	assert.NoError(t, timeoutInterceptor(0)(context.Background(), "", nil, nil, nil, invoker(false)))
+}
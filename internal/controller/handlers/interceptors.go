@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	pb "github.com/bbt-t/lets-go-keep/protocols/grpc"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// authTokenKey is metadata key of session token.
+const authTokenKey = "authToken"
+
+// publicMethods are methods, which can be called without session token.
+var publicMethods = map[string]bool{
+	pb.Gophkeeper_Register_FullMethodName:       true,
+	pb.Gophkeeper_Login_FullMethodName:          true,
+	pb.Gophkeeper_RefreshSession_FullMethodName: true,
+	pb.Gophkeeper_Logout_FullMethodName:         true,
+}
+
+// authenticate validates session token from metadata and puts identity of user to context.
+func authenticate(ctx context.Context, a Authenticator) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get(authTokenKey)) == 0 {
+		return nil, status.Errorf(codes.Unauthenticated, "Didn't send metadata for authentication.")
+	}
+
+	userID, err := a.ValidateToken(entity.AuthToken(md.Get(authTokenKey)[0]))
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
+	}
+
+	return entity.ContextWithIdentity(ctx, entity.Identity{UserID: userID}), nil
+}
+
+// unaryAuthInterceptor authenticates unary calls, except public methods.
+func unaryAuthInterceptor(a Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		ctx, err := authenticate(ctx, a)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// streamAuthInterceptor authenticates stream calls, except public methods.
+func streamAuthInterceptor(a Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if publicMethods[info.FullMethod] {
+			return handler(srv, stream)
+		}
+
+		ctx, err := authenticate(stream.Context(), a)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authServerStream is server stream with context, which contains identity of user.
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns context with identity of user.
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
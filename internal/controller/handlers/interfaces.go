@@ -2,70 +2,178 @@ package handlers
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
 
 	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	"google.golang.org/grpc/credentials"
 )
 
 // ClientHandlers interface for Client.
+//
+//go:generate mockery --name ClientHandlers
 type ClientHandlers interface {
 	Login(credentials entity.UserCredentials) error
 	Register(credentials entity.UserCredentials) error
+	Logout() error
+	RevokeSessions() error
+	Unlocked() bool
 	GetRecordsInfo() ([]entity.Record, error)
+	ListRecords(query entity.RecordsQuery) (entity.RecordsPage, error)
 	GetRecord(recordID string) (entity.Record, error)
-	CreateRecord(record entity.Record) error
-	DeleteRecord(recordID string) error
+	GetRecordInfo(recordID string) (entity.Record, error)
+	GetFile(recordID string, w io.Writer) (entity.Record, error)
+	CreateRecord(record entity.Record) (string, error)
+	UpdateRecord(record entity.Record) error
+	DeleteRecord(recordID string, revision int64) error
+	GetRecordVersions(recordID string) ([]entity.RecordVersion, error)
+	RestoreRecordVersion(recordID string, version int32, revision int64) error
+	MigrateLabels() ([]string, error)
+	Conflicts() ([]entity.SyncConflict, error)
+	CreateVault(name string) (string, error)
+	GetVaults() ([]entity.Vault, error)
+	GetVaultMembers(vaultID string) ([]entity.VaultMember, error)
+	Fingerprint() (string, error)
+	InviteMember(vaultID, login, fingerprint string, role entity.VaultRole) error
+	RevokeMember(vaultID, login string) error
+	GetVaultRecords(vaultID string) ([]entity.Record, error)
+	GetVaultRecord(vaultID, recordID string) (entity.Record, error)
+	CreateVaultRecord(record entity.Record) (string, error)
+	UpdateVaultRecord(record entity.Record) error
+	DeleteVaultRecord(vaultID, recordID string) error
+}
+
+// NewClientHandlers returns new client handlers (interface). If encryptMetadata is set,
+// metadata and names of records are encrypted before they are sent to server.
+func NewClientHandlers(conn ClientConnection, encryptMetadata bool) ClientHandlers {
+	return newClientHandlers(conn, encryptMetadata)
+}
+
+// AgentClient is client handlers, which are called in agent, where vault stays unlocked.
+type AgentClient interface {
+	ClientHandlers
+	Lock() error
+	Close() error
 }
 
-// NewClientHandlers returns new client handlers (interface).
-func NewClientHandlers(conn ClientConnection) ClientHandlers {
-	return newClientHandlers(conn)
+// NewAgentClient connects to agent on Unix socket and returns its client handlers (interface).
+func NewAgentClient(socket string) (AgentClient, error) {
+	return newAgentClient(socket)
 }
 
 // Authenticator is interface for user authenticating. Should can creates tokens, and gets userIDs from them.
 //
 //go:generate mockery --name Authenticator
 type Authenticator interface {
-	CreateToken(userID entity.UserID) (entity.AuthToken, error)
+	CreateToken(userID entity.UserID) (entity.AuthToken, time.Time, error)
 	ValidateToken(token entity.AuthToken) (entity.UserID, error)
+	CreateRefreshToken() (entity.RefreshToken, time.Time, error)
 }
 
-// NewAuthenticatorJWT gets new authenticatorJWT (interface).
-func NewAuthenticatorJWT(secretKey []byte, expirationTime int64) Authenticator {
-	return newAuthenticatorJWT(secretKey, expirationTime)
+// NewAuthenticatorJWT gets new authenticatorJWT with access and refresh token lifetimes (interface).
+func NewAuthenticatorJWT(secretKey []byte, accessTTL, refreshTTL time.Duration) Authenticator {
+	return newAuthenticatorJWT(secretKey, accessTTL, refreshTTL)
 }
 
 // ClientConnection describes client connection.
 //
 //go:generate mockery --name ClientConn
 type ClientConnection interface {
-	Login(credentials entity.UserCredentials) (string, error)
-	Register(credentials entity.UserCredentials) (string, error)
+	Login(credentials entity.UserCredentials) (entity.Session, error)
+	Register(credentials entity.UserCredentials) (entity.Session, error)
+	RefreshSession(refreshToken entity.RefreshToken) (entity.Session, error)
+	Logout(refreshToken entity.RefreshToken) error
+	RevokeSessions(token entity.AuthToken) error
+	UpgradeKDF(token entity.AuthToken, params entity.KDFParams) error
 	GetRecordsInfo(token entity.AuthToken) ([]entity.Record, error)
+	ListRecords(token entity.AuthToken, query entity.RecordsQuery) (entity.RecordsPage, error)
 	GetRecord(token entity.AuthToken, recordID string) (entity.Record, error)
-	DeleteRecord(token entity.AuthToken, recordID string) error
-	CreateRecord(token entity.AuthToken, record entity.Record) error
+	DeleteRecord(token entity.AuthToken, recordID string, revision int64) error
+	CreateRecord(token entity.AuthToken, record entity.Record) (string, error)
+	UpdateRecord(token entity.AuthToken, record entity.Record) error
+	GetRecordVersions(token entity.AuthToken, recordID string) ([]entity.RecordVersion, error)
+	RestoreRecordVersion(token entity.AuthToken, recordID string, version int32, revision int64) error
+	Sync(token entity.AuthToken, sinceRevision int64) (entity.RecordChanges, error)
+	UploadFile(token entity.AuthToken, record entity.Record, r io.Reader) (string, error)
+	DownloadFile(token entity.AuthToken, recordID string, w io.Writer) (entity.Record, error)
+	GetPublicKey(token entity.AuthToken, login string) ([]byte, error)
+	SetPublicKey(token entity.AuthToken, publicKey []byte) error
+	CreateVault(token entity.AuthToken, vault entity.Vault) (string, error)
+	GetVaults(token entity.AuthToken) ([]entity.Vault, error)
+	GetVaultMembers(token entity.AuthToken, vaultID string) ([]entity.VaultMember, error)
+	InviteMember(token entity.AuthToken, member entity.VaultMember) error
+	RevokeMember(token entity.AuthToken, rotation entity.VaultRotation) error
+	GetVaultRecords(token entity.AuthToken, vaultID string) ([]entity.Record, error)
+	GetVaultRecord(token entity.AuthToken, vaultID, recordID string) (entity.Record, error)
+	CreateVaultRecord(token entity.AuthToken, record entity.Record) (string, error)
+	UpdateVaultRecord(token entity.AuthToken, record entity.Record) error
+	DeleteVaultRecord(token entity.AuthToken, vaultID, recordID string) error
+}
+
+// KeyedConnection is connection, which keeps local data encrypted by record key.
+// Client handlers set key after it's derived on login.
+type KeyedConnection interface {
+	SetKey(key []byte) error
+}
+
+// SyncedConnection is connection, which syncs offline changes. Client handlers report changes,
+// which conflicted with other devices or were rejected by server, to user.
+type SyncedConnection interface {
+	Conflicts() []entity.SyncConflict
+}
+
+// NewOfflineConnection returns connection, which mirrors records to local encrypted store in directory,
+// works with it, when server is unavailable, and sends offline changes, when server is back (interface).
+func NewOfflineConnection(remote ClientConnection, directory string) ClientConnection {
+	return newOfflineConn(remote, directory)
 }
 
-// NewClientConnection connects to server and returning connection (interface).
-func NewClientConnection(serverAddress string) ClientConnection {
-	return newClientConn(serverAddress)
+// NewClientConnection connects to server with transport credentials and returning connection (interface).
+// Unary requests are canceled after timeout, zero timeout means no limit.
+func NewClientConnection(serverAddress string, creds credentials.TransportCredentials, timeout time.Duration) ClientConnection {
+	return newClientConn(serverAddress, creds, timeout)
 }
 
 // ServerHandlers interface for server handlers
 //
 //go:generate mockery --name ServerHandlers
 type ServerHandlers interface {
-	LoginUser(credentials entity.UserCredentials) (entity.AuthToken, error)
-	CreateUser(credentials entity.UserCredentials) (entity.AuthToken, error)
+	LoginUser(credentials entity.UserCredentials) (entity.Session, error)
+	CreateUser(credentials entity.UserCredentials) (entity.Session, error)
+	RefreshSession(refreshToken entity.RefreshToken) (entity.Session, error)
+	Logout(refreshToken entity.RefreshToken) error
+	RevokeSessions(ctx context.Context) error
+	UpgradeKDF(ctx context.Context, params entity.KDFParams) error
 	GetRecordsInfo(ctx context.Context) ([]entity.Record, error)
+	ListRecords(ctx context.Context, query entity.RecordsQuery) (entity.RecordsPage, error)
 	GetRecord(ctx context.Context, recordID string) (entity.Record, error)
-	CreateRecord(ctx context.Context, record entity.Record) error
-	DeleteRecord(ctx context.Context, recordID string) error
+	CreateRecord(ctx context.Context, record entity.Record) (string, error)
+	UpdateRecord(ctx context.Context, record entity.Record) error
+	DeleteRecord(ctx context.Context, recordID string, revision int64) error
+	GetRecordVersions(ctx context.Context, recordID string) ([]entity.RecordVersion, error)
+	RestoreRecordVersion(ctx context.Context, recordID string, version int32, revision int64) error
+	Sync(ctx context.Context, sinceRevision int64) (entity.RecordChanges, error)
+	UploadFile(ctx context.Context, record entity.Record, r io.Reader) (string, error)
+	DownloadFile(ctx context.Context, recordID string) (entity.Record, io.ReadCloser, error)
+	GetPublicKey(ctx context.Context, login string) ([]byte, error)
+	SetPublicKey(ctx context.Context, publicKey []byte) error
+	CreateVault(ctx context.Context, vault entity.Vault) (string, error)
+	GetVaults(ctx context.Context) ([]entity.Vault, error)
+	GetVaultMembers(ctx context.Context, vaultID string) ([]entity.VaultMember, error)
+	InviteMember(ctx context.Context, member entity.VaultMember) error
+	RevokeMember(ctx context.Context, rotation entity.VaultRotation) error
+	GetVaultRecords(ctx context.Context, vaultID string) ([]entity.Record, error)
+	GetVaultRecord(ctx context.Context, vaultID, recordID string) (entity.Record, error)
+	CreateVaultRecord(ctx context.Context, record entity.Record) (string, error)
+	UpdateVaultRecord(ctx context.Context, record entity.Record) error
+	DeleteVaultRecord(ctx context.Context, vaultID, recordID string) error
 }
 
-// NewServerHandlers returns server handlers based on storage and authenticator.
-func NewServerHandlers(s storage.Storager, a Authenticator) ServerHandlers {
-	return newServerHandlers(s, a)
+// NewServerHandlers returns server handlers based on storage, authenticator and password hasher.
+func NewServerHandlers(s storage.Storager, a Authenticator, h pkg.PasswordHasher) ServerHandlers {
+	return newServerHandlers(s, a, h)
 }
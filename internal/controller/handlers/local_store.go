@@ -0,0 +1,494 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Kinds of changes, which are made offline and wait for synchronisation.
+const (
+	changeCreate = "create"
+	changeUpdate = "update"
+	changeDelete = "delete"
+)
+
+// localIDPrefix marks records, which were created offline and aren't saved on server yet.
+const localIDPrefix = "local-"
+
+// keyCheckContext is context of check value of key, so check value can't be confused with other MACs.
+const keyCheckContext = "gophkeeper local store key check"
+
+// localProfile is not secret part of local store, which is needed to derive key before store can be opened.
+// KeyCheck is check value of key, which server confirmed: public key of user on server is derived from it.
+// Without it offline login can't check master key, so it isn't allowed.
+type localProfile struct {
+	Login    string
+	KDF      entity.KDFParams
+	KeyCheck []byte
+}
+
+// localRecord is record mirrored from server. Data is encrypted by record key, as on server.
+// Revision is revision of record on server, when it was mirrored.
+type localRecord struct {
+	ID, Metadata string
+	Name, Folder string
+	Tags         []string
+	BlindIndex   []string
+	Type         entity.RecordType
+	Data         []byte
+	HasData      bool
+	Revision     int64
+	UpdatedAt    time.Time
+}
+
+// localChange is change made offline. Error is why server rejected change, it's sent again on next sync.
+type localChange struct {
+	Kind     string
+	Record   localRecord
+	Error    string
+	FailedAt time.Time
+}
+
+// localConflict is offline change of record, which was changed or deleted on another device.
+// Update is saved as new record CopyID, deletion is skipped. Metadata and Name are labels of offline change.
+type localConflict struct {
+	Kind           string
+	RecordID       string
+	CopyID         string
+	Metadata, Name string
+	ResolvedAt     time.Time
+}
+
+// localState is secret part of local store. Saved to file encrypted by record key.
+// Revision is revision of user data on server, which store is synchronised to.
+// Conflicts are kept, until user sees them.
+type localState struct {
+	Records   []localRecord
+	Changes   []localChange
+	Conflicts []localConflict
+	Revision  int64
+}
+
+// localStore keeps records and offline changes of one user in directory.
+// State is saved only after key is verified, so state is never encrypted by wrong master key.
+type localStore struct {
+	profilePath, statePath string
+	key                    []byte
+	verified               bool
+	state                  localState
+}
+
+// newLocalStore returns store of user, files are named by login hash.
+func newLocalStore(directory, login string) *localStore {
+	sum := sha256.Sum256([]byte(login))
+	name := hex.EncodeToString(sum[:16])
+
+	return &localStore{
+		profilePath: filepath.Join(directory, name+".profile"),
+		statePath:   filepath.Join(directory, name+".cache"),
+	}
+}
+
+// loadProfile reads key derivation parameters of user for offline login. Returns ErrNotFound,
+// if user never logged on this device or server never confirmed key of user, so master key can't be checked.
+// Profile of user on legacy key isn't used: legacy key doesn't depend on master key.
+func (s *localStore) loadProfile() (entity.KDFParams, error) {
+	profile, err := s.readProfile()
+	if err != nil {
+		return entity.KDFParams{}, err
+	}
+
+	if len(profile.KeyCheck) == 0 || profile.KDF.Legacy {
+		return entity.KDFParams{}, storage.ErrNotFound
+	}
+
+	return profile.KDF, nil
+}
+
+// readProfile reads profile of user. Returns ErrNotFound, if user never logged on this device.
+func (s *localStore) readProfile() (localProfile, error) {
+	var profile localProfile
+
+	data, err := os.ReadFile(s.profilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return localProfile{}, storage.ErrNotFound
+	}
+	if err != nil {
+		log.Infoln(err)
+
+		return localProfile{}, storage.ErrUnknown
+	}
+
+	if err = json.Unmarshal(data, &profile); err != nil {
+		log.Infoln(err)
+
+		return localProfile{}, storage.ErrUnknown
+	}
+
+	return profile, nil
+}
+
+// saveProfile saves key derivation parameters of user. Check value of key is kept, while parameters are the same,
+// new parameters mean new key, which must be confirmed again. Profile of user on legacy key isn't saved.
+func (s *localStore) saveProfile(login string, kdf entity.KDFParams) error {
+	if kdf.Legacy {
+		return controller.ErrLegacyKey
+	}
+
+	profile := localProfile{Login: login, KDF: kdf}
+	if previous, err := s.readProfile(); err == nil && sameKDF(previous.KDF, kdf) {
+		profile.KeyCheck = previous.KeyCheck
+	}
+
+	return s.writeProfile(profile)
+}
+
+// writeProfile replaces profile file.
+func (s *localStore) writeProfile(profile localProfile) error {
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.profilePath, data)
+}
+
+// open decrypts state by key. Key must match check value, if key was confirmed. Empty state is used,
+// if there is no state file yet. State, which can't be decrypted by key, which isn't confirmed yet, is ignored:
+// it may be saved by another key, and it isn't replaced, until key is confirmed. Store of profile
+// on legacy key isn't opened.
+func (s *localStore) open(key []byte) error {
+	s.key, s.verified, s.state = nil, false, localState{}
+
+	profile, err := s.readProfile()
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	if profile.KDF.Legacy {
+		return controller.ErrLegacyKey
+	}
+
+	if len(profile.KeyCheck) != 0 {
+		if !hmac.Equal(profile.KeyCheck, keyCheck(key)) {
+			return controller.ErrWrongMasterKey
+		}
+		s.verified = true
+	}
+
+	data, err := os.ReadFile(s.statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		s.key = key
+
+		return nil
+	}
+	if err != nil {
+		log.Infoln(err)
+
+		return storage.ErrUnknown
+	}
+
+	plain, err := pkg.DecryptBytes(key, data)
+	if err != nil && !s.verified {
+		log.Warnf("%s :: %v", "local store of not confirmed key is ignored", err)
+		s.key = key
+
+		return nil
+	}
+	if err != nil {
+		log.Infoln(err)
+
+		return controller.ErrWrongMasterKey
+	}
+
+	if err = json.Unmarshal(plain, &s.state); err != nil {
+		log.Infoln(err)
+		s.state = localState{}
+
+		return controller.ErrDataCorrupted
+	}
+
+	s.key = key
+
+	return nil
+}
+
+// verify saves check value of key, which server confirmed, and state, which wasn't saved before it.
+func (s *localStore) verify() error {
+	profile, err := s.readProfile()
+	if err != nil {
+		return err
+	}
+
+	check := keyCheck(s.key)
+	if !hmac.Equal(profile.KeyCheck, check) {
+		profile.KeyCheck = check
+		if err = s.writeProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	s.verified = true
+
+	return s.save()
+}
+
+// opened checks if state was decrypted.
+func (s *localStore) opened() bool {
+	return s.key != nil
+}
+
+// save encrypts state and replaces state file. State isn't saved, until key is confirmed.
+func (s *localStore) save() error {
+	if !s.verified {
+		return nil
+	}
+
+	plain, err := json.Marshal(s.state)
+	if err != nil {
+		return err
+	}
+
+	data, err := pkg.EncryptBytes(s.key, plain)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.statePath, data)
+}
+
+// find returns index of record in state or -1.
+func (s *localStore) find(recordID string) int {
+	for i, record := range s.state.Records {
+		if record.ID == recordID {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// put adds record to state or replaces existing one.
+func (s *localStore) put(record localRecord) {
+	if i := s.find(record.ID); i >= 0 {
+		s.state.Records[i] = record
+		return
+	}
+
+	s.state.Records = append(s.state.Records, record)
+}
+
+// remove deletes record and all its offline changes from state.
+func (s *localStore) remove(recordID string) {
+	if i := s.find(recordID); i >= 0 {
+		s.state.Records = append(s.state.Records[:i], s.state.Records[i+1:]...)
+	}
+
+	changes := s.state.Changes[:0]
+	for _, change := range s.state.Changes {
+		if change.Record.ID != recordID {
+			changes = append(changes, change)
+		}
+	}
+	s.state.Changes = changes
+}
+
+// merge applies changes made on server after revision. Changes after zero revision contain all records,
+// so mirrored records, which aren't in them, are removed. Records created offline are kept.
+func (s *localStore) merge(changes entity.RecordChanges, sinceRevision int64) {
+	if sinceRevision == 0 {
+		records := s.state.Records[:0]
+		for _, record := range s.state.Records {
+			if isLocalID(record.ID) {
+				records = append(records, record)
+			}
+		}
+		s.state.Records = records
+	}
+
+	for _, record := range changes.Records {
+		s.put(toLocalRecord(record, record.Type != entity.TypeFile))
+	}
+
+	for _, tombstone := range changes.Deleted {
+		if i := s.find(tombstone.RecordID); i >= 0 {
+			s.state.Records = append(s.state.Records[:i], s.state.Records[i+1:]...)
+		}
+	}
+
+	s.state.Revision = changes.Revision
+}
+
+// conflict remembers offline change, which conflicted with change of another device.
+// Update is saved as new record copyID.
+func (s *localStore) conflict(kind string, record entity.Record, copyID string) {
+	s.state.Conflicts = append(s.state.Conflicts, localConflict{
+		Kind:       kind,
+		RecordID:   record.ID,
+		CopyID:     copyID,
+		Metadata:   record.Metadata,
+		Name:       record.Name,
+		ResolvedAt: time.Now().UTC(),
+	})
+}
+
+// conflicts returns conflicts and changes, which server rejected. Conflicts are forgotten,
+// because user has seen them, rejected changes stay queued.
+func (s *localStore) conflicts() []entity.SyncConflict {
+	conflicts := make([]entity.SyncConflict, 0, len(s.state.Conflicts))
+	for _, conflict := range s.state.Conflicts {
+		conflicts = append(conflicts, entity.SyncConflict{
+			Kind:     conflict.Kind,
+			RecordID: conflict.RecordID,
+			CopyID:   conflict.CopyID,
+			Metadata: conflict.Metadata,
+			Name:     conflict.Name,
+			At:       conflict.ResolvedAt,
+		})
+	}
+	s.state.Conflicts = nil
+
+	for _, change := range s.state.Changes {
+		if change.Error == "" {
+			continue
+		}
+
+		conflicts = append(conflicts, entity.SyncConflict{
+			Kind:     change.Kind,
+			RecordID: change.Record.ID,
+			Metadata: change.Record.Metadata,
+			Name:     change.Record.Name,
+			Error:    change.Error,
+			At:       change.FailedAt,
+		})
+	}
+
+	return conflicts
+}
+
+// records returns info of all records in store without data.
+func (s *localStore) records() []entity.Record {
+	records := make([]entity.Record, 0, len(s.state.Records))
+	for _, record := range s.state.Records {
+		info := record.entity()
+		info.Data = nil
+		records = append(records, info)
+	}
+
+	return records
+}
+
+// queue adds offline change. Change of record, which is already queued, replaces queued one,
+// so it's sent with revision, which record had before going offline.
+func (s *localStore) queue(kind string, record localRecord) {
+	for i, change := range s.state.Changes {
+		if change.Record.ID != record.ID {
+			continue
+		}
+
+		if change.Kind == changeCreate || change.Kind == kind {
+			// Changed record is sent again, even if server rejected previous change.
+			record.Revision = change.Record.Revision
+			s.state.Changes[i] = localChange{Kind: change.Kind, Record: record}
+
+			return
+		}
+	}
+
+	s.state.Changes = append(s.state.Changes, localChange{Kind: kind, Record: record})
+}
+
+// keyCheck returns check value of key.
+func keyCheck(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(keyCheckContext))
+
+	return mac.Sum(nil)
+}
+
+// sameKDF checks if key derivation parameters are the same, so they derive the same key.
+func sameKDF(a, b entity.KDFParams) bool {
+	return bytes.Equal(a.Salt, b.Salt) && a.Time == b.Time && a.Memory == b.Memory &&
+		a.Threads == b.Threads && a.Legacy == b.Legacy
+}
+
+// isLocalID checks if record was created offline.
+func isLocalID(recordID string) bool {
+	return len(recordID) > len(localIDPrefix) && recordID[:len(localIDPrefix)] == localIDPrefix
+}
+
+// toLocalRecord converts record with encrypted data to mirrored record.
+func toLocalRecord(record entity.Record, hasData bool) localRecord {
+	return localRecord{
+		ID:         record.ID,
+		Metadata:   record.Metadata,
+		Name:       record.Name,
+		Tags:       record.Tags,
+		Folder:     record.Folder,
+		BlindIndex: record.BlindIndex,
+		Type:       record.Type,
+		Data:       record.Data,
+		HasData:    hasData,
+		Revision:   record.Revision,
+		UpdatedAt:  record.UpdatedAt,
+	}
+}
+
+// entity converts mirrored record to record with encrypted data.
+func (r localRecord) entity() entity.Record {
+	return entity.Record{
+		ID:         r.ID,
+		Metadata:   r.Metadata,
+		Name:       r.Name,
+		Tags:       r.Tags,
+		Folder:     r.Folder,
+		BlindIndex: r.BlindIndex,
+		Type:       r.Type,
+		Data:       r.Data,
+		Revision:   r.Revision,
+		UpdatedAt:  r.UpdatedAt,
+	}
+}
+
+// writeFileAtomic writes data to temporary file and renames it, so file is never left half-written.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -6,6 +6,8 @@ import (
 	entity "github.com/bbt-t/lets-go-keep/internal/entity"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Authenticator is an autogenerated mock type for the Authenticator type
@@ -13,13 +15,45 @@ type Authenticator struct {
 	mock.Mock
 }
 
+// CreateRefreshToken provides a mock function with given fields:
+func (_m *Authenticator) CreateRefreshToken() (entity.RefreshToken, time.Time, error) {
+	ret := _m.Called()
+
+	var r0 entity.RefreshToken
+	var r1 time.Time
+	var r2 error
+	if rf, ok := ret.Get(0).(func() (entity.RefreshToken, time.Time, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() entity.RefreshToken); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(entity.RefreshToken)
+	}
+
+	if rf, ok := ret.Get(1).(func() time.Time); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Get(1).(time.Time)
+	}
+
+	if rf, ok := ret.Get(2).(func() error); ok {
+		r2 = rf()
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // CreateToken provides a mock function with given fields: userID
-func (_m *Authenticator) CreateToken(userID entity.UserID) (entity.AuthToken, error) {
+func (_m *Authenticator) CreateToken(userID entity.UserID) (entity.AuthToken, time.Time, error) {
 	ret := _m.Called(userID)
 
 	var r0 entity.AuthToken
-	var r1 error
-	if rf, ok := ret.Get(0).(func(entity.UserID) (entity.AuthToken, error)); ok {
+	var r1 time.Time
+	var r2 error
+	if rf, ok := ret.Get(0).(func(entity.UserID) (entity.AuthToken, time.Time, error)); ok {
 		return rf(userID)
 	}
 	if rf, ok := ret.Get(0).(func(entity.UserID) entity.AuthToken); ok {
@@ -28,13 +62,19 @@ func (_m *Authenticator) CreateToken(userID entity.UserID) (entity.AuthToken, er
 		r0 = ret.Get(0).(entity.AuthToken)
 	}
 
-	if rf, ok := ret.Get(1).(func(entity.UserID) error); ok {
+	if rf, ok := ret.Get(1).(func(entity.UserID) time.Time); ok {
 		r1 = rf(userID)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(time.Time)
 	}
 
-	return r0, r1
+	if rf, ok := ret.Get(2).(func(entity.UserID) error); ok {
+		r2 = rf(userID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
 }
 
 // ValidateToken provides a mock function with given fields: token
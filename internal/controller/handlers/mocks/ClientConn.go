@@ -5,21 +5,95 @@ package mocks
 import (
 	entity "github.com/bbt-t/lets-go-keep/internal/entity"
 
+	io "io"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
-// ClientConn is an autogenerated mock type for the ClientConn type
+// ClientConn is an autogenerated mock type for the ClientConnection type
 type ClientConn struct {
 	mock.Mock
 }
 
 // CreateRecord provides a mock function with given fields: token, record
-func (_m *ClientConn) CreateRecord(token entity.AuthToken, record entity.Record) error {
+func (_m *ClientConn) CreateRecord(token entity.AuthToken, record entity.Record) (string, error) {
 	ret := _m.Called(token, record)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Record) error); ok {
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Record) (string, error)); ok {
+		return rf(token, record)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Record) string); ok {
+		r0 = rf(token, record)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, entity.Record) error); ok {
+		r1 = rf(token, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateVault provides a mock function with given fields: token, vault
+func (_m *ClientConn) CreateVault(token entity.AuthToken, vault entity.Vault) (string, error) {
+	ret := _m.Called(token, vault)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Vault) (string, error)); ok {
+		return rf(token, vault)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Vault) string); ok {
+		r0 = rf(token, vault)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, entity.Vault) error); ok {
+		r1 = rf(token, vault)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateVaultRecord provides a mock function with given fields: token, record
+func (_m *ClientConn) CreateVaultRecord(token entity.AuthToken, record entity.Record) (string, error) {
+	ret := _m.Called(token, record)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Record) (string, error)); ok {
+		return rf(token, record)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Record) string); ok {
 		r0 = rf(token, record)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, entity.Record) error); ok {
+		r1 = rf(token, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteRecord provides a mock function with given fields: token, recordID, revision
+func (_m *ClientConn) DeleteRecord(token entity.AuthToken, recordID string, revision int64) error {
+	ret := _m.Called(token, recordID, revision)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string, int64) error); ok {
+		r0 = rf(token, recordID, revision)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -27,13 +101,13 @@ func (_m *ClientConn) CreateRecord(token entity.AuthToken, record entity.Record)
 	return r0
 }
 
-// DeleteRecord provides a mock function with given fields: token, recordID
-func (_m *ClientConn) DeleteRecord(token entity.AuthToken, recordID string) error {
-	ret := _m.Called(token, recordID)
+// DeleteVaultRecord provides a mock function with given fields: token, vaultID, recordID
+func (_m *ClientConn) DeleteVaultRecord(token entity.AuthToken, vaultID string, recordID string) error {
+	ret := _m.Called(token, vaultID, recordID)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(entity.AuthToken, string) error); ok {
-		r0 = rf(token, recordID)
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string, string) error); ok {
+		r0 = rf(token, vaultID, recordID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -41,6 +115,56 @@ func (_m *ClientConn) DeleteRecord(token entity.AuthToken, recordID string) erro
 	return r0
 }
 
+// DownloadFile provides a mock function with given fields: token, recordID, w
+func (_m *ClientConn) DownloadFile(token entity.AuthToken, recordID string, w io.Writer) (entity.Record, error) {
+	ret := _m.Called(token, recordID, w)
+
+	var r0 entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string, io.Writer) (entity.Record, error)); ok {
+		return rf(token, recordID, w)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string, io.Writer) entity.Record); ok {
+		r0 = rf(token, recordID, w)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, string, io.Writer) error); ok {
+		r1 = rf(token, recordID, w)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPublicKey provides a mock function with given fields: token, login
+func (_m *ClientConn) GetPublicKey(token entity.AuthToken, login string) ([]byte, error) {
+	ret := _m.Called(token, login)
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string) ([]byte, error)); ok {
+		return rf(token, login)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string) []byte); ok {
+		r0 = rf(token, login)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, string) error); ok {
+		r1 = rf(token, login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRecord provides a mock function with given fields: token, recordID
 func (_m *ClientConn) GetRecord(token entity.AuthToken, recordID string) (entity.Record, error) {
 	ret := _m.Called(token, recordID)
@@ -65,6 +189,32 @@ func (_m *ClientConn) GetRecord(token entity.AuthToken, recordID string) (entity
 	return r0, r1
 }
 
+// GetRecordVersions provides a mock function with given fields: token, recordID
+func (_m *ClientConn) GetRecordVersions(token entity.AuthToken, recordID string) ([]entity.RecordVersion, error) {
+	ret := _m.Called(token, recordID)
+
+	var r0 []entity.RecordVersion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string) ([]entity.RecordVersion, error)); ok {
+		return rf(token, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string) []entity.RecordVersion); ok {
+		r0 = rf(token, recordID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.RecordVersion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, string) error); ok {
+		r1 = rf(token, recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRecordsInfo provides a mock function with given fields: token
 func (_m *ClientConn) GetRecordsInfo(token entity.AuthToken) ([]entity.Record, error) {
 	ret := _m.Called(token)
@@ -91,19 +241,159 @@ func (_m *ClientConn) GetRecordsInfo(token entity.AuthToken) ([]entity.Record, e
 	return r0, r1
 }
 
+// GetVaultMembers provides a mock function with given fields: token, vaultID
+func (_m *ClientConn) GetVaultMembers(token entity.AuthToken, vaultID string) ([]entity.VaultMember, error) {
+	ret := _m.Called(token, vaultID)
+
+	var r0 []entity.VaultMember
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string) ([]entity.VaultMember, error)); ok {
+		return rf(token, vaultID)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string) []entity.VaultMember); ok {
+		r0 = rf(token, vaultID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.VaultMember)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, string) error); ok {
+		r1 = rf(token, vaultID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultRecord provides a mock function with given fields: token, vaultID, recordID
+func (_m *ClientConn) GetVaultRecord(token entity.AuthToken, vaultID string, recordID string) (entity.Record, error) {
+	ret := _m.Called(token, vaultID, recordID)
+
+	var r0 entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string, string) (entity.Record, error)); ok {
+		return rf(token, vaultID, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string, string) entity.Record); ok {
+		r0 = rf(token, vaultID, recordID)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, string, string) error); ok {
+		r1 = rf(token, vaultID, recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultRecords provides a mock function with given fields: token, vaultID
+func (_m *ClientConn) GetVaultRecords(token entity.AuthToken, vaultID string) ([]entity.Record, error) {
+	ret := _m.Called(token, vaultID)
+
+	var r0 []entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string) ([]entity.Record, error)); ok {
+		return rf(token, vaultID)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string) []entity.Record); ok {
+		r0 = rf(token, vaultID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Record)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, string) error); ok {
+		r1 = rf(token, vaultID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaults provides a mock function with given fields: token
+func (_m *ClientConn) GetVaults(token entity.AuthToken) ([]entity.Vault, error) {
+	ret := _m.Called(token)
+
+	var r0 []entity.Vault
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken) ([]entity.Vault, error)); ok {
+		return rf(token)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken) []entity.Vault); ok {
+		r0 = rf(token)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Vault)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken) error); ok {
+		r1 = rf(token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InviteMember provides a mock function with given fields: token, member
+func (_m *ClientConn) InviteMember(token entity.AuthToken, member entity.VaultMember) error {
+	ret := _m.Called(token, member)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.VaultMember) error); ok {
+		r0 = rf(token, member)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListRecords provides a mock function with given fields: token, query
+func (_m *ClientConn) ListRecords(token entity.AuthToken, query entity.RecordsQuery) (entity.RecordsPage, error) {
+	ret := _m.Called(token, query)
+
+	var r0 entity.RecordsPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.RecordsQuery) (entity.RecordsPage, error)); ok {
+		return rf(token, query)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.RecordsQuery) entity.RecordsPage); ok {
+		r0 = rf(token, query)
+	} else {
+		r0 = ret.Get(0).(entity.RecordsPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, entity.RecordsQuery) error); ok {
+		r1 = rf(token, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Login provides a mock function with given fields: credentials
-func (_m *ClientConn) Login(credentials entity.UserCredentials) (string, error) {
+func (_m *ClientConn) Login(credentials entity.UserCredentials) (entity.Session, error) {
 	ret := _m.Called(credentials)
 
-	var r0 string
+	var r0 entity.Session
 	var r1 error
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) (string, error)); ok {
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) (entity.Session, error)); ok {
 		return rf(credentials)
 	}
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) string); ok {
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) entity.Session); ok {
 		r0 = rf(credentials)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(entity.Session)
 	}
 
 	if rf, ok := ret.Get(1).(func(entity.UserCredentials) error); ok {
@@ -115,19 +405,57 @@ func (_m *ClientConn) Login(credentials entity.UserCredentials) (string, error)
 	return r0, r1
 }
 
+// Logout provides a mock function with given fields: refreshToken
+func (_m *ClientConn) Logout(refreshToken entity.RefreshToken) error {
+	ret := _m.Called(refreshToken)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.RefreshToken) error); ok {
+		r0 = rf(refreshToken)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RefreshSession provides a mock function with given fields: refreshToken
+func (_m *ClientConn) RefreshSession(refreshToken entity.RefreshToken) (entity.Session, error) {
+	ret := _m.Called(refreshToken)
+
+	var r0 entity.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.RefreshToken) (entity.Session, error)); ok {
+		return rf(refreshToken)
+	}
+	if rf, ok := ret.Get(0).(func(entity.RefreshToken) entity.Session); ok {
+		r0 = rf(refreshToken)
+	} else {
+		r0 = ret.Get(0).(entity.Session)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.RefreshToken) error); ok {
+		r1 = rf(refreshToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Register provides a mock function with given fields: credentials
-func (_m *ClientConn) Register(credentials entity.UserCredentials) (string, error) {
+func (_m *ClientConn) Register(credentials entity.UserCredentials) (entity.Session, error) {
 	ret := _m.Called(credentials)
 
-	var r0 string
+	var r0 entity.Session
 	var r1 error
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) (string, error)); ok {
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) (entity.Session, error)); ok {
 		return rf(credentials)
 	}
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) string); ok {
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) entity.Session); ok {
 		r0 = rf(credentials)
 	} else {
-		r0 = ret.Get(0).(string)
+		r0 = ret.Get(0).(entity.Session)
 	}
 
 	if rf, ok := ret.Get(1).(func(entity.UserCredentials) error); ok {
@@ -139,6 +467,152 @@ func (_m *ClientConn) Register(credentials entity.UserCredentials) (string, erro
 	return r0, r1
 }
 
+// RestoreRecordVersion provides a mock function with given fields: token, recordID, version, revision
+func (_m *ClientConn) RestoreRecordVersion(token entity.AuthToken, recordID string, version int32, revision int64) error {
+	ret := _m.Called(token, recordID, version, revision)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, string, int32, int64) error); ok {
+		r0 = rf(token, recordID, version, revision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeMember provides a mock function with given fields: token, rotation
+func (_m *ClientConn) RevokeMember(token entity.AuthToken, rotation entity.VaultRotation) error {
+	ret := _m.Called(token, rotation)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.VaultRotation) error); ok {
+		r0 = rf(token, rotation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSessions provides a mock function with given fields: token
+func (_m *ClientConn) RevokeSessions(token entity.AuthToken) error {
+	ret := _m.Called(token)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken) error); ok {
+		r0 = rf(token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPublicKey provides a mock function with given fields: token, publicKey
+func (_m *ClientConn) SetPublicKey(token entity.AuthToken, publicKey []byte) error {
+	ret := _m.Called(token, publicKey)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, []byte) error); ok {
+		r0 = rf(token, publicKey)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Sync provides a mock function with given fields: token, sinceRevision
+func (_m *ClientConn) Sync(token entity.AuthToken, sinceRevision int64) (entity.RecordChanges, error) {
+	ret := _m.Called(token, sinceRevision)
+
+	var r0 entity.RecordChanges
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, int64) (entity.RecordChanges, error)); ok {
+		return rf(token, sinceRevision)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, int64) entity.RecordChanges); ok {
+		r0 = rf(token, sinceRevision)
+	} else {
+		r0 = ret.Get(0).(entity.RecordChanges)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, int64) error); ok {
+		r1 = rf(token, sinceRevision)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateRecord provides a mock function with given fields: token, record
+func (_m *ClientConn) UpdateRecord(token entity.AuthToken, record entity.Record) error {
+	ret := _m.Called(token, record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Record) error); ok {
+		r0 = rf(token, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateVaultRecord provides a mock function with given fields: token, record
+func (_m *ClientConn) UpdateVaultRecord(token entity.AuthToken, record entity.Record) error {
+	ret := _m.Called(token, record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Record) error); ok {
+		r0 = rf(token, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpgradeKDF provides a mock function with given fields: token, params
+func (_m *ClientConn) UpgradeKDF(token entity.AuthToken, params entity.KDFParams) error {
+	ret := _m.Called(token, params)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.KDFParams) error); ok {
+		r0 = rf(token, params)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UploadFile provides a mock function with given fields: token, record, r
+func (_m *ClientConn) UploadFile(token entity.AuthToken, record entity.Record, r io.Reader) (string, error) {
+	ret := _m.Called(token, record, r)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Record, io.Reader) (string, error)); ok {
+		return rf(token, record, r)
+	}
+	if rf, ok := ret.Get(0).(func(entity.AuthToken, entity.Record, io.Reader) string); ok {
+		r0 = rf(token, record, r)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.AuthToken, entity.Record, io.Reader) error); ok {
+		r1 = rf(token, record, r)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewClientConn interface {
 	mock.TestingT
 	Cleanup(func())
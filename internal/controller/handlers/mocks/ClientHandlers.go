@@ -0,0 +1,597 @@
+// Code generated by mockery v2.20.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	entity "github.com/bbt-t/lets-go-keep/internal/entity"
+
+	io "io"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// ClientHandlers is an autogenerated mock type for the ClientHandlers type
+type ClientHandlers struct {
+	mock.Mock
+}
+
+// Conflicts provides a mock function with given fields:
+func (_m *ClientHandlers) Conflicts() ([]entity.SyncConflict, error) {
+	ret := _m.Called()
+
+	var r0 []entity.SyncConflict
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]entity.SyncConflict, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []entity.SyncConflict); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.SyncConflict)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateRecord provides a mock function with given fields: record
+func (_m *ClientHandlers) CreateRecord(record entity.Record) (string, error) {
+	ret := _m.Called(record)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.Record) (string, error)); ok {
+		return rf(record)
+	}
+	if rf, ok := ret.Get(0).(func(entity.Record) string); ok {
+		r0 = rf(record)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.Record) error); ok {
+		r1 = rf(record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateVault provides a mock function with given fields: name
+func (_m *ClientHandlers) CreateVault(name string) (string, error) {
+	ret := _m.Called(name)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return rf(name)
+	}
+	if rf, ok := ret.Get(0).(func(string) string); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateVaultRecord provides a mock function with given fields: record
+func (_m *ClientHandlers) CreateVaultRecord(record entity.Record) (string, error) {
+	ret := _m.Called(record)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.Record) (string, error)); ok {
+		return rf(record)
+	}
+	if rf, ok := ret.Get(0).(func(entity.Record) string); ok {
+		r0 = rf(record)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.Record) error); ok {
+		r1 = rf(record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteRecord provides a mock function with given fields: recordID, revision
+func (_m *ClientHandlers) DeleteRecord(recordID string, revision int64) error {
+	ret := _m.Called(recordID, revision)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int64) error); ok {
+		r0 = rf(recordID, revision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteVaultRecord provides a mock function with given fields: vaultID, recordID
+func (_m *ClientHandlers) DeleteVaultRecord(vaultID string, recordID string) error {
+	ret := _m.Called(vaultID, recordID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(vaultID, recordID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Fingerprint provides a mock function with given fields:
+func (_m *ClientHandlers) Fingerprint() (string, error) {
+	ret := _m.Called()
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() (string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetFile provides a mock function with given fields: recordID, w
+func (_m *ClientHandlers) GetFile(recordID string, w io.Writer) (entity.Record, error) {
+	ret := _m.Called(recordID, w)
+
+	var r0 entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, io.Writer) (entity.Record, error)); ok {
+		return rf(recordID, w)
+	}
+	if rf, ok := ret.Get(0).(func(string, io.Writer) entity.Record); ok {
+		r0 = rf(recordID, w)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, io.Writer) error); ok {
+		r1 = rf(recordID, w)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRecord provides a mock function with given fields: recordID
+func (_m *ClientHandlers) GetRecord(recordID string) (entity.Record, error) {
+	ret := _m.Called(recordID)
+
+	var r0 entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (entity.Record, error)); ok {
+		return rf(recordID)
+	}
+	if rf, ok := ret.Get(0).(func(string) entity.Record); ok {
+		r0 = rf(recordID)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRecordInfo provides a mock function with given fields: recordID
+func (_m *ClientHandlers) GetRecordInfo(recordID string) (entity.Record, error) {
+	ret := _m.Called(recordID)
+
+	var r0 entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (entity.Record, error)); ok {
+		return rf(recordID)
+	}
+	if rf, ok := ret.Get(0).(func(string) entity.Record); ok {
+		r0 = rf(recordID)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRecordVersions provides a mock function with given fields: recordID
+func (_m *ClientHandlers) GetRecordVersions(recordID string) ([]entity.RecordVersion, error) {
+	ret := _m.Called(recordID)
+
+	var r0 []entity.RecordVersion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]entity.RecordVersion, error)); ok {
+		return rf(recordID)
+	}
+	if rf, ok := ret.Get(0).(func(string) []entity.RecordVersion); ok {
+		r0 = rf(recordID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.RecordVersion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRecordsInfo provides a mock function with given fields:
+func (_m *ClientHandlers) GetRecordsInfo() ([]entity.Record, error) {
+	ret := _m.Called()
+
+	var r0 []entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]entity.Record, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []entity.Record); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Record)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultMembers provides a mock function with given fields: vaultID
+func (_m *ClientHandlers) GetVaultMembers(vaultID string) ([]entity.VaultMember, error) {
+	ret := _m.Called(vaultID)
+
+	var r0 []entity.VaultMember
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]entity.VaultMember, error)); ok {
+		return rf(vaultID)
+	}
+	if rf, ok := ret.Get(0).(func(string) []entity.VaultMember); ok {
+		r0 = rf(vaultID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.VaultMember)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(vaultID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultRecord provides a mock function with given fields: vaultID, recordID
+func (_m *ClientHandlers) GetVaultRecord(vaultID string, recordID string) (entity.Record, error) {
+	ret := _m.Called(vaultID, recordID)
+
+	var r0 entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string, string) (entity.Record, error)); ok {
+		return rf(vaultID, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(string, string) entity.Record); ok {
+		r0 = rf(vaultID, recordID)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(string, string) error); ok {
+		r1 = rf(vaultID, recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultRecords provides a mock function with given fields: vaultID
+func (_m *ClientHandlers) GetVaultRecords(vaultID string) ([]entity.Record, error) {
+	ret := _m.Called(vaultID)
+
+	var r0 []entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) ([]entity.Record, error)); ok {
+		return rf(vaultID)
+	}
+	if rf, ok := ret.Get(0).(func(string) []entity.Record); ok {
+		r0 = rf(vaultID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Record)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(vaultID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaults provides a mock function with given fields:
+func (_m *ClientHandlers) GetVaults() ([]entity.Vault, error) {
+	ret := _m.Called()
+
+	var r0 []entity.Vault
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]entity.Vault, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []entity.Vault); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Vault)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InviteMember provides a mock function with given fields: vaultID, login, fingerprint, role
+func (_m *ClientHandlers) InviteMember(vaultID string, login string, fingerprint string, role entity.VaultRole) error {
+	ret := _m.Called(vaultID, login, fingerprint, role)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string, entity.VaultRole) error); ok {
+		r0 = rf(vaultID, login, fingerprint, role)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListRecords provides a mock function with given fields: query
+func (_m *ClientHandlers) ListRecords(query entity.RecordsQuery) (entity.RecordsPage, error) {
+	ret := _m.Called(query)
+
+	var r0 entity.RecordsPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.RecordsQuery) (entity.RecordsPage, error)); ok {
+		return rf(query)
+	}
+	if rf, ok := ret.Get(0).(func(entity.RecordsQuery) entity.RecordsPage); ok {
+		r0 = rf(query)
+	} else {
+		r0 = ret.Get(0).(entity.RecordsPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.RecordsQuery) error); ok {
+		r1 = rf(query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Login provides a mock function with given fields: credentials
+func (_m *ClientHandlers) Login(credentials entity.UserCredentials) error {
+	ret := _m.Called(credentials)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) error); ok {
+		r0 = rf(credentials)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Logout provides a mock function with given fields:
+func (_m *ClientHandlers) Logout() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MigrateLabels provides a mock function with given fields:
+func (_m *ClientHandlers) MigrateLabels() ([]string, error) {
+	ret := _m.Called()
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]string, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Register provides a mock function with given fields: credentials
+func (_m *ClientHandlers) Register(credentials entity.UserCredentials) error {
+	ret := _m.Called(credentials)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) error); ok {
+		r0 = rf(credentials)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreRecordVersion provides a mock function with given fields: recordID, version, revision
+func (_m *ClientHandlers) RestoreRecordVersion(recordID string, version int32, revision int64) error {
+	ret := _m.Called(recordID, version, revision)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int32, int64) error); ok {
+		r0 = rf(recordID, version, revision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeMember provides a mock function with given fields: vaultID, login
+func (_m *ClientHandlers) RevokeMember(vaultID string, login string) error {
+	ret := _m.Called(vaultID, login)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(vaultID, login)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSessions provides a mock function with given fields:
+func (_m *ClientHandlers) RevokeSessions() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Unlocked provides a mock function with given fields:
+func (_m *ClientHandlers) Unlocked() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// UpdateRecord provides a mock function with given fields: record
+func (_m *ClientHandlers) UpdateRecord(record entity.Record) error {
+	ret := _m.Called(record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.Record) error); ok {
+		r0 = rf(record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateVaultRecord provides a mock function with given fields: record
+func (_m *ClientHandlers) UpdateVaultRecord(record entity.Record) error {
+	ret := _m.Called(record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.Record) error); ok {
+		r0 = rf(record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+type mockConstructorTestingTNewClientHandlers interface {
+	mock.TestingT
+	Cleanup(func())
+}
+
+// NewClientHandlers creates a new instance of ClientHandlers. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+func NewClientHandlers(t mockConstructorTestingTNewClientHandlers) *ClientHandlers {
+	mock := &ClientHandlers{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
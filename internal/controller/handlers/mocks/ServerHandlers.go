@@ -7,6 +7,8 @@ import (
 
 	entity "github.com/bbt-t/lets-go-keep/internal/entity"
 
+	io "io"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -16,32 +18,42 @@ type ServerHandlers struct {
 }
 
 // CreateRecord provides a mock function with given fields: ctx, record
-func (_m *ServerHandlers) CreateRecord(ctx context.Context, record entity.Record) error {
+func (_m *ServerHandlers) CreateRecord(ctx context.Context, record entity.Record) (string, error) {
 	ret := _m.Called(ctx, record)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) error); ok {
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) (string, error)); ok {
+		return rf(ctx, record)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) string); ok {
 		r0 = rf(ctx, record)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Record) error); ok {
+		r1 = rf(ctx, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // CreateUser provides a mock function with given fields: credentials
-func (_m *ServerHandlers) CreateUser(credentials entity.UserCredentials) (entity.AuthToken, error) {
+func (_m *ServerHandlers) CreateUser(credentials entity.UserCredentials) (entity.Session, error) {
 	ret := _m.Called(credentials)
 
-	var r0 entity.AuthToken
+	var r0 entity.Session
 	var r1 error
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) (entity.AuthToken, error)); ok {
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) (entity.Session, error)); ok {
 		return rf(credentials)
 	}
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) entity.AuthToken); ok {
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) entity.Session); ok {
 		r0 = rf(credentials)
 	} else {
-		r0 = ret.Get(0).(entity.AuthToken)
+		r0 = ret.Get(0).(entity.Session)
 	}
 
 	if rf, ok := ret.Get(1).(func(entity.UserCredentials) error); ok {
@@ -53,13 +65,75 @@ func (_m *ServerHandlers) CreateUser(credentials entity.UserCredentials) (entity
 	return r0, r1
 }
 
-// DeleteRecord provides a mock function with given fields: ctx, recordID
-func (_m *ServerHandlers) DeleteRecord(ctx context.Context, recordID string) error {
-	ret := _m.Called(ctx, recordID)
+// CreateVault provides a mock function with given fields: ctx, vault
+func (_m *ServerHandlers) CreateVault(ctx context.Context, vault entity.Vault) (string, error) {
+	ret := _m.Called(ctx, vault)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Vault) (string, error)); ok {
+		return rf(ctx, vault)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Vault) string); ok {
+		r0 = rf(ctx, vault)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Vault) error); ok {
+		r1 = rf(ctx, vault)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateVaultRecord provides a mock function with given fields: ctx, record
+func (_m *ServerHandlers) CreateVaultRecord(ctx context.Context, record entity.Record) (string, error) {
+	ret := _m.Called(ctx, record)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) (string, error)); ok {
+		return rf(ctx, record)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) string); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Record) error); ok {
+		r1 = rf(ctx, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteRecord provides a mock function with given fields: ctx, recordID, revision
+func (_m *ServerHandlers) DeleteRecord(ctx context.Context, recordID string, revision int64) error {
+	ret := _m.Called(ctx, recordID, revision)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, recordID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, recordID, revision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteVaultRecord provides a mock function with given fields: ctx, vaultID, recordID
+func (_m *ServerHandlers) DeleteVaultRecord(ctx context.Context, vaultID string, recordID string) error {
+	ret := _m.Called(ctx, vaultID, recordID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, vaultID, recordID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -67,6 +141,65 @@ func (_m *ServerHandlers) DeleteRecord(ctx context.Context, recordID string) err
 	return r0
 }
 
+// DownloadFile provides a mock function with given fields: ctx, recordID
+func (_m *ServerHandlers) DownloadFile(ctx context.Context, recordID string) (entity.Record, io.ReadCloser, error) {
+	ret := _m.Called(ctx, recordID)
+
+	var r0 entity.Record
+	var r1 io.ReadCloser
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.Record, io.ReadCloser, error)); ok {
+		return rf(ctx, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.Record); ok {
+		r0 = rf(ctx, recordID)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) io.ReadCloser); ok {
+		r1 = rf(ctx, recordID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(io.ReadCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, recordID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetPublicKey provides a mock function with given fields: ctx, login
+func (_m *ServerHandlers) GetPublicKey(ctx context.Context, login string) ([]byte, error) {
+	ret := _m.Called(ctx, login)
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]byte, error)); ok {
+		return rf(ctx, login)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, login)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRecord provides a mock function with given fields: ctx, recordID
 func (_m *ServerHandlers) GetRecord(ctx context.Context, recordID string) (entity.Record, error) {
 	ret := _m.Called(ctx, recordID)
@@ -91,6 +224,32 @@ func (_m *ServerHandlers) GetRecord(ctx context.Context, recordID string) (entit
 	return r0, r1
 }
 
+// GetRecordVersions provides a mock function with given fields: ctx, recordID
+func (_m *ServerHandlers) GetRecordVersions(ctx context.Context, recordID string) ([]entity.RecordVersion, error) {
+	ret := _m.Called(ctx, recordID)
+
+	var r0 []entity.RecordVersion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]entity.RecordVersion, error)); ok {
+		return rf(ctx, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []entity.RecordVersion); ok {
+		r0 = rf(ctx, recordID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.RecordVersion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRecordsInfo provides a mock function with given fields: ctx
 func (_m *ServerHandlers) GetRecordsInfo(ctx context.Context) ([]entity.Record, error) {
 	ret := _m.Called(ctx)
@@ -117,19 +276,159 @@ func (_m *ServerHandlers) GetRecordsInfo(ctx context.Context) ([]entity.Record,
 	return r0, r1
 }
 
+// GetVaultMembers provides a mock function with given fields: ctx, vaultID
+func (_m *ServerHandlers) GetVaultMembers(ctx context.Context, vaultID string) ([]entity.VaultMember, error) {
+	ret := _m.Called(ctx, vaultID)
+
+	var r0 []entity.VaultMember
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]entity.VaultMember, error)); ok {
+		return rf(ctx, vaultID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []entity.VaultMember); ok {
+		r0 = rf(ctx, vaultID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.VaultMember)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, vaultID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultRecord provides a mock function with given fields: ctx, vaultID, recordID
+func (_m *ServerHandlers) GetVaultRecord(ctx context.Context, vaultID string, recordID string) (entity.Record, error) {
+	ret := _m.Called(ctx, vaultID, recordID)
+
+	var r0 entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (entity.Record, error)); ok {
+		return rf(ctx, vaultID, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) entity.Record); ok {
+		r0 = rf(ctx, vaultID, recordID)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, vaultID, recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultRecords provides a mock function with given fields: ctx, vaultID
+func (_m *ServerHandlers) GetVaultRecords(ctx context.Context, vaultID string) ([]entity.Record, error) {
+	ret := _m.Called(ctx, vaultID)
+
+	var r0 []entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]entity.Record, error)); ok {
+		return rf(ctx, vaultID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []entity.Record); ok {
+		r0 = rf(ctx, vaultID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Record)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, vaultID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaults provides a mock function with given fields: ctx
+func (_m *ServerHandlers) GetVaults(ctx context.Context) ([]entity.Vault, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []entity.Vault
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]entity.Vault, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []entity.Vault); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Vault)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// InviteMember provides a mock function with given fields: ctx, member
+func (_m *ServerHandlers) InviteMember(ctx context.Context, member entity.VaultMember) error {
+	ret := _m.Called(ctx, member)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.VaultMember) error); ok {
+		r0 = rf(ctx, member)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListRecords provides a mock function with given fields: ctx, query
+func (_m *ServerHandlers) ListRecords(ctx context.Context, query entity.RecordsQuery) (entity.RecordsPage, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 entity.RecordsPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.RecordsQuery) (entity.RecordsPage, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.RecordsQuery) entity.RecordsPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(entity.RecordsPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.RecordsQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // LoginUser provides a mock function with given fields: credentials
-func (_m *ServerHandlers) LoginUser(credentials entity.UserCredentials) (entity.AuthToken, error) {
+func (_m *ServerHandlers) LoginUser(credentials entity.UserCredentials) (entity.Session, error) {
 	ret := _m.Called(credentials)
 
-	var r0 entity.AuthToken
+	var r0 entity.Session
 	var r1 error
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) (entity.AuthToken, error)); ok {
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) (entity.Session, error)); ok {
 		return rf(credentials)
 	}
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) entity.AuthToken); ok {
+	if rf, ok := ret.Get(0).(func(entity.UserCredentials) entity.Session); ok {
 		r0 = rf(credentials)
 	} else {
-		r0 = ret.Get(0).(entity.AuthToken)
+		r0 = ret.Get(0).(entity.Session)
 	}
 
 	if rf, ok := ret.Get(1).(func(entity.UserCredentials) error); ok {
@@ -141,6 +440,190 @@ func (_m *ServerHandlers) LoginUser(credentials entity.UserCredentials) (entity.
 	return r0, r1
 }
 
+// Logout provides a mock function with given fields: refreshToken
+func (_m *ServerHandlers) Logout(refreshToken entity.RefreshToken) error {
+	ret := _m.Called(refreshToken)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.RefreshToken) error); ok {
+		r0 = rf(refreshToken)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RefreshSession provides a mock function with given fields: refreshToken
+func (_m *ServerHandlers) RefreshSession(refreshToken entity.RefreshToken) (entity.Session, error) {
+	ret := _m.Called(refreshToken)
+
+	var r0 entity.Session
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.RefreshToken) (entity.Session, error)); ok {
+		return rf(refreshToken)
+	}
+	if rf, ok := ret.Get(0).(func(entity.RefreshToken) entity.Session); ok {
+		r0 = rf(refreshToken)
+	} else {
+		r0 = ret.Get(0).(entity.Session)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.RefreshToken) error); ok {
+		r1 = rf(refreshToken)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreRecordVersion provides a mock function with given fields: ctx, recordID, version, revision
+func (_m *ServerHandlers) RestoreRecordVersion(ctx context.Context, recordID string, version int32, revision int64) error {
+	ret := _m.Called(ctx, recordID, version, revision)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int32, int64) error); ok {
+		r0 = rf(ctx, recordID, version, revision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeMember provides a mock function with given fields: ctx, rotation
+func (_m *ServerHandlers) RevokeMember(ctx context.Context, rotation entity.VaultRotation) error {
+	ret := _m.Called(ctx, rotation)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.VaultRotation) error); ok {
+		r0 = rf(ctx, rotation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeSessions provides a mock function with given fields: ctx
+func (_m *ServerHandlers) RevokeSessions(ctx context.Context) error {
+	ret := _m.Called(ctx)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(ctx)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPublicKey provides a mock function with given fields: ctx, publicKey
+func (_m *ServerHandlers) SetPublicKey(ctx context.Context, publicKey []byte) error {
+	ret := _m.Called(ctx, publicKey)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte) error); ok {
+		r0 = rf(ctx, publicKey)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Sync provides a mock function with given fields: ctx, sinceRevision
+func (_m *ServerHandlers) Sync(ctx context.Context, sinceRevision int64) (entity.RecordChanges, error) {
+	ret := _m.Called(ctx, sinceRevision)
+
+	var r0 entity.RecordChanges
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (entity.RecordChanges, error)); ok {
+		return rf(ctx, sinceRevision)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) entity.RecordChanges); ok {
+		r0 = rf(ctx, sinceRevision)
+	} else {
+		r0 = ret.Get(0).(entity.RecordChanges)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, sinceRevision)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateRecord provides a mock function with given fields: ctx, record
+func (_m *ServerHandlers) UpdateRecord(ctx context.Context, record entity.Record) error {
+	ret := _m.Called(ctx, record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) error); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateVaultRecord provides a mock function with given fields: ctx, record
+func (_m *ServerHandlers) UpdateVaultRecord(ctx context.Context, record entity.Record) error {
+	ret := _m.Called(ctx, record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) error); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpgradeKDF provides a mock function with given fields: ctx, params
+func (_m *ServerHandlers) UpgradeKDF(ctx context.Context, params entity.KDFParams) error {
+	ret := _m.Called(ctx, params)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.KDFParams) error); ok {
+		r0 = rf(ctx, params)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UploadFile provides a mock function with given fields: ctx, record, r
+func (_m *ServerHandlers) UploadFile(ctx context.Context, record entity.Record, r io.Reader) (string, error) {
+	ret := _m.Called(ctx, record, r)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record, io.Reader) (string, error)); ok {
+		return rf(ctx, record, r)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record, io.Reader) string); ok {
+		r0 = rf(ctx, record, r)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Record, io.Reader) error); ok {
+		r1 = rf(ctx, record, r)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewServerHandlers interface {
 	mock.TestingT
 	Cleanup(func())
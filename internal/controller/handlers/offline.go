@@ -0,0 +1,751 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// offlineConn is client connection, which mirrors records of user to local encrypted store.
+// When server is unavailable, records are read from store and changes are queued.
+// Queued changes are sent to server before any request, when server is available again.
+// Store is synchronised by changes made on server after its revision, so changes of other devices are got too.
+//
+// offlineConn keeps its own session, so tokens passed to its methods are used only before login.
+// Session is refreshed, when its token expires soon, credentials are used only to login after offline login.
+//
+// Key of store is confirmed, when server keeps public key of user, which is derived from it. Until then
+// store isn't saved and offline login isn't allowed, because there is nothing to check master key by.
+type offlineConn struct {
+	sync.Mutex
+	remote       ClientConnection
+	directory    string
+	store        *localStore
+	credentials  entity.UserCredentials
+	token        entity.AuthToken
+	refreshToken entity.RefreshToken
+	expiresAt    time.Time
+	publicKey    []byte
+	retryFailed  bool
+}
+
+// newOfflineConn returns offline connection, which keeps local stores in directory.
+func newOfflineConn(remote ClientConnection, directory string) *offlineConn {
+	return &offlineConn{
+		remote:    remote,
+		directory: directory,
+	}
+}
+
+// Login logins user on server. If server is unavailable, user is logged offline,
+// if this login was already used on this device. Local store is opened by SetKey.
+// Profile of user on legacy key isn't saved, until key is upgraded.
+func (o *offlineConn) Login(credentials entity.UserCredentials) (entity.Session, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	store := newLocalStore(o.directory, credentials.Login)
+
+	session, err := o.remote.Login(credentials)
+	if errors.Is(err, controller.ErrServerUnavailable) {
+		kdf, errProfile := store.loadProfile()
+		if errProfile != nil {
+			log.Infoln(errProfile)
+
+			return entity.Session{}, err
+		}
+
+		o.store, o.credentials = store, sessionCredentials(credentials)
+		o.setSession(entity.Session{})
+
+		return entity.Session{KDF: kdf}, nil
+	}
+	if err != nil {
+		return entity.Session{}, err
+	}
+
+	if !session.KDF.Legacy {
+		if err = store.saveProfile(credentials.Login, session.KDF); err != nil {
+			log.Warnf("%s :: %v", "save local profile fault", err)
+		}
+	}
+
+	o.store, o.credentials = store, sessionCredentials(credentials)
+	o.setSession(session)
+
+	return session, nil
+}
+
+// Register creates user on server. Works only online.
+func (o *offlineConn) Register(credentials entity.UserCredentials) (entity.Session, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	session, err := o.remote.Register(credentials)
+	if errors.Is(err, controller.ErrServerUnavailable) {
+		return entity.Session{}, controller.ErrOffline
+	}
+	if err != nil {
+		return entity.Session{}, err
+	}
+
+	store := newLocalStore(o.directory, credentials.Login)
+	if err = store.saveProfile(credentials.Login, session.KDF); err != nil {
+		log.Warnf("%s :: %v", "save local profile fault", err)
+	}
+
+	o.store, o.credentials = store, sessionCredentials(credentials)
+	o.setSession(session)
+	o.publicKey = credentials.PublicKey
+
+	return session, nil
+}
+
+// RefreshSession returns session of connection, it's refreshed, if its token expires soon.
+func (o *offlineConn) RefreshSession(refreshToken entity.RefreshToken) (entity.Session, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.RefreshSession(refreshToken)
+	}
+
+	if err := o.online(); err != nil {
+		return entity.Session{}, offlineError(err)
+	}
+
+	return entity.Session{Token: o.token, RefreshToken: o.refreshToken, ExpiresAt: o.expiresAt}, nil
+}
+
+// Logout ends session on server and forgets logged user. User is forgotten even if server is unavailable.
+func (o *offlineConn) Logout(refreshToken entity.RefreshToken) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.Logout(refreshToken)
+	}
+
+	var err error
+	if o.refreshToken != "" {
+		err = o.remote.Logout(o.refreshToken)
+	}
+
+	o.forget()
+
+	return err
+}
+
+// RevokeSessions ends all sessions of user on server and forgets logged user. Works only online.
+func (o *offlineConn) RevokeSessions(token entity.AuthToken) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.RevokeSessions(token)
+	}
+
+	if err := o.online(); err != nil {
+		return offlineError(err)
+	}
+
+	if err := o.remote.RevokeSessions(o.token); err != nil {
+		return err
+	}
+
+	o.forget()
+
+	return nil
+}
+
+// UpgradeKDF sends key derivation parameters of legacy user to server. Works only online.
+// When upgrade is finished, profile of local store is saved with new parameters.
+func (o *offlineConn) UpgradeKDF(token entity.AuthToken, params entity.KDFParams) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if err := o.remote.UpgradeKDF(token, params); err != nil {
+		return offlineError(err)
+	}
+
+	if o.store != nil && !params.Legacy {
+		if err := o.store.saveProfile(o.credentials.Login, params); err != nil {
+			log.Warnf("%s :: %v", "save local profile fault", err)
+		}
+	}
+
+	return nil
+}
+
+// SetKey opens local store of logged user by record key. Returns ErrWrongMasterKey, if key doesn't match
+// confirmed key or store can't be decrypted. Changes, which server rejected, are sent again once after login.
+func (o *offlineConn) SetKey(key []byte) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if o.store == nil {
+		return storage.ErrUnauthenticated
+	}
+
+	if err := o.store.open(key); err != nil {
+		return err
+	}
+
+	o.confirmKey()
+	o.retryFailed = true
+
+	if err := o.online(); err != nil && !errors.Is(err, controller.ErrServerUnavailable) {
+		log.Infoln(err)
+	}
+
+	return nil
+}
+
+// GetRecordsInfo synchronises local store with server and gets records from it.
+// Offline records are got from local store as they are.
+func (o *offlineConn) GetRecordsInfo(token entity.AuthToken) ([]entity.Record, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.GetRecordsInfo(token)
+	}
+
+	err := o.online()
+	if err == nil {
+		err = o.pull()
+	}
+
+	if err != nil && !errors.Is(err, controller.ErrServerUnavailable) {
+		return nil, err
+	}
+
+	return o.store.records(), nil
+}
+
+// ListRecords synchronises local store with server and gets page of records from it,
+// so offline records are listed too.
+func (o *offlineConn) ListRecords(token entity.AuthToken, query entity.RecordsQuery) (entity.RecordsPage, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.ListRecords(token, query)
+	}
+
+	err := o.online()
+	if err == nil {
+		err = o.pull()
+	}
+
+	if err != nil && !errors.Is(err, controller.ErrServerUnavailable) {
+		return entity.RecordsPage{}, err
+	}
+
+	return query.Apply(o.store.records()), nil
+}
+
+// GetRecord gets record from server and mirrors it. Offline record is got from local store.
+func (o *offlineConn) GetRecord(token entity.AuthToken, recordID string) (entity.Record, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.GetRecord(token, recordID)
+	}
+
+	err := o.online()
+	if err == nil && !isLocalID(recordID) {
+		var record entity.Record
+
+		record, err = o.remote.GetRecord(o.token, recordID)
+		if err == nil {
+			o.store.put(toLocalRecord(record, record.Type != entity.TypeFile))
+			o.save()
+
+			return record, nil
+		}
+
+		if errors.Is(err, storage.ErrNotFound) {
+			o.store.remove(recordID)
+			o.save()
+		}
+	}
+
+	if err != nil && !errors.Is(err, controller.ErrServerUnavailable) {
+		return entity.Record{}, err
+	}
+
+	i := o.store.find(recordID)
+	if i < 0 {
+		return entity.Record{}, storage.ErrNotFound
+	}
+
+	record := o.store.state.Records[i]
+	if !record.HasData && record.Type != entity.TypeFile {
+		return entity.Record{}, controller.ErrOffline
+	}
+
+	return record.entity(), nil
+}
+
+// CreateRecord creates record on server. Offline record is saved to local store and queued,
+// its ID is local, until record is synced.
+func (o *offlineConn) CreateRecord(token entity.AuthToken, record entity.Record) (string, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.CreateRecord(token, record)
+	}
+
+	err := o.online()
+	if err == nil {
+		recordID, err := o.remote.CreateRecord(o.token, record)
+		if err == nil {
+			o.refresh()
+		}
+
+		return recordID, err
+	}
+
+	if !errors.Is(err, controller.ErrServerUnavailable) {
+		return "", err
+	}
+
+	suffix, err := pkg.GenerateRandom(8)
+	if err != nil {
+		return "", storage.ErrUnknown
+	}
+
+	record.ID, record.Revision = localIDPrefix+hex.EncodeToString(suffix), 0
+	local := toLocalRecord(record, true)
+
+	o.store.put(local)
+	o.store.queue(changeCreate, local)
+
+	return record.ID, o.save()
+}
+
+// UpdateRecord updates record on server. Offline change is saved to local store and queued.
+// Record created offline isn't on server yet, so its queued creation is changed instead.
+func (o *offlineConn) UpdateRecord(token entity.AuthToken, record entity.Record) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.UpdateRecord(token, record)
+	}
+
+	err := o.online()
+	if err == nil {
+		if err = o.remote.UpdateRecord(o.token, record); err == nil {
+			o.refresh()
+		}
+
+		return err
+	}
+
+	if !errors.Is(err, controller.ErrServerUnavailable) {
+		return err
+	}
+
+	if o.store.find(record.ID) < 0 {
+		return storage.ErrNotFound
+	}
+
+	local := toLocalRecord(record, true)
+	o.store.put(local)
+
+	if isLocalID(record.ID) {
+		o.store.queue(changeCreate, local)
+	} else {
+		o.store.queue(changeUpdate, local)
+	}
+
+	return o.save()
+}
+
+// DeleteRecord deletes record on server, if it wasn't changed after revision. Offline deletion is queued.
+func (o *offlineConn) DeleteRecord(token entity.AuthToken, recordID string, revision int64) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.DeleteRecord(token, recordID, revision)
+	}
+
+	err := o.online()
+	if err == nil && !isLocalID(recordID) {
+		err = o.remote.DeleteRecord(o.token, recordID, revision)
+		if err == nil || errors.Is(err, storage.ErrNotFound) {
+			o.store.remove(recordID)
+			o.refresh()
+		}
+
+		return err
+	}
+
+	if err != nil && !errors.Is(err, controller.ErrServerUnavailable) {
+		return err
+	}
+
+	i := o.store.find(recordID)
+	if i < 0 {
+		return storage.ErrNotFound
+	}
+
+	record := o.store.state.Records[i]
+	record.Revision = revision
+	o.store.remove(recordID)
+
+	if !isLocalID(recordID) {
+		o.store.queue(changeDelete, record)
+	}
+
+	return o.save()
+}
+
+// GetRecordVersions gets previous versions of record from server. Works only online.
+func (o *offlineConn) GetRecordVersions(token entity.AuthToken, recordID string) ([]entity.RecordVersion, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.GetRecordVersions(token, recordID)
+	}
+
+	if err := o.online(); err != nil {
+		return nil, offlineError(err)
+	}
+
+	return o.remote.GetRecordVersions(o.token, recordID)
+}
+
+// RestoreRecordVersion restores previous version of record on server, if it wasn't changed after revision.
+// Works only online.
+func (o *offlineConn) RestoreRecordVersion(token entity.AuthToken, recordID string, version int32, revision int64) error {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.RestoreRecordVersion(token, recordID, version, revision)
+	}
+
+	if err := o.online(); err != nil {
+		return offlineError(err)
+	}
+
+	err := o.remote.RestoreRecordVersion(o.token, recordID, version, revision)
+	if err == nil {
+		o.refresh()
+	}
+
+	return err
+}
+
+// Sync gets changes made on server after revision. Works only online,
+// local store is synchronised by GetRecordsInfo.
+func (o *offlineConn) Sync(token entity.AuthToken, sinceRevision int64) (entity.RecordChanges, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.Sync(token, sinceRevision)
+	}
+
+	if err := o.online(); err != nil {
+		return entity.RecordChanges{}, offlineError(err)
+	}
+
+	return o.remote.Sync(o.token, sinceRevision)
+}
+
+// UploadFile uploads file to server. Works only online.
+func (o *offlineConn) UploadFile(token entity.AuthToken, record entity.Record, r io.Reader) (string, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.UploadFile(token, record, r)
+	}
+
+	if err := o.online(); err != nil {
+		return "", offlineError(err)
+	}
+
+	recordID, err := o.remote.UploadFile(o.token, record, r)
+	if err == nil {
+		o.refresh()
+	}
+
+	return recordID, err
+}
+
+// DownloadFile downloads file from server. Works only online.
+func (o *offlineConn) DownloadFile(token entity.AuthToken, recordID string, w io.Writer) (entity.Record, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return o.remote.DownloadFile(token, recordID, w)
+	}
+
+	if err := o.online(); err != nil {
+		return entity.Record{}, offlineError(err)
+	}
+
+	return o.remote.DownloadFile(o.token, recordID, w)
+}
+
+// ready checks if user is logged and his local store is opened.
+func (o *offlineConn) ready() bool {
+	return o.store != nil && o.store.opened()
+}
+
+// online checks that server is available: logins again after offline login, refreshes session,
+// if its token expires soon, and sends queued changes.
+func (o *offlineConn) online() error {
+	if o.token == "" || expiring(o.expiresAt) {
+		if err := o.renew(); err != nil {
+			return err
+		}
+	}
+
+	return o.sync()
+}
+
+// renew gets new session. Session is refreshed by refresh token, so revoked session isn't renewed,
+// only after offline login there is no session and user is logged by credentials.
+func (o *offlineConn) renew() error {
+	var (
+		session entity.Session
+		err     error
+	)
+
+	if o.refreshToken != "" {
+		session, err = o.remote.RefreshSession(o.refreshToken)
+	} else {
+		session, err = o.remote.Login(o.credentials)
+	}
+	if err != nil {
+		return err
+	}
+
+	o.setSession(session)
+
+	return nil
+}
+
+// setSession keeps tokens of session.
+func (o *offlineConn) setSession(session entity.Session) {
+	o.token, o.refreshToken, o.expiresAt = session.Token, session.RefreshToken, session.ExpiresAt
+}
+
+// forget forgets logged user: his session, credentials and opened local store.
+func (o *offlineConn) forget() {
+	o.store, o.credentials, o.publicKey = nil, entity.UserCredentials{}, nil
+	o.setSession(entity.Session{})
+}
+
+// confirmKey saves check value of key of opened store, if server keeps public key derived from it.
+func (o *offlineConn) confirmKey() {
+	if o.publicKey == nil || !o.ready() {
+		return
+	}
+
+	publicKey, err := pkg.PublicKey(o.store.key)
+	if err != nil {
+		log.Warnf("%s :: %v", "derive public key fault", err)
+
+		return
+	}
+	if !bytes.Equal(publicKey, o.publicKey) {
+		log.Warnln("Key of local store isn't confirmed: server keeps another public key.")
+
+		return
+	}
+
+	if err = o.store.verify(); err != nil {
+		log.Warnf("%s :: %v", "save key check of local store fault", err)
+	}
+}
+
+// Conflicts returns offline changes, which conflicted with changes of other devices, and changes,
+// which server rejected. Conflicts are returned once, rejected changes are returned, until they are sent.
+func (o *offlineConn) Conflicts() []entity.SyncConflict {
+	o.Lock()
+	defer o.Unlock()
+
+	if !o.ready() {
+		return nil
+	}
+
+	conflicts := o.store.conflicts()
+	o.save()
+
+	return conflicts
+}
+
+// sync sends queued changes to server in order they were made. Change is dequeued only after server applies it.
+// Change, which server rejects, stays queued with error, so user sees it, and it's sent again after next login
+// or after record is changed again. Records, which were changed on another device, are resolved by apply.
+func (o *offlineConn) sync() error {
+	retry := o.retryFailed
+
+	for i := 0; i < len(o.store.state.Changes); {
+		change := o.store.state.Changes[i]
+		if change.Error != "" && !retry {
+			i++
+			continue
+		}
+
+		err := o.apply(change)
+		if errors.Is(err, controller.ErrServerUnavailable) || errors.Is(err, storage.ErrUnauthenticated) {
+			return err
+		}
+		if err != nil {
+			log.Warnf("%s :: %v", "sync offline change fault, change is kept", err)
+
+			o.store.state.Changes[i].Error, o.store.state.Changes[i].FailedAt = err.Error(), time.Now().UTC()
+			o.save()
+
+			i++
+			continue
+		}
+
+		o.store.state.Changes = append(o.store.state.Changes[:i], o.store.state.Changes[i+1:]...)
+
+		if change.Kind == changeCreate {
+			// Record will be got with server ID on next refresh.
+			if j := o.store.find(change.Record.ID); j >= 0 {
+				o.store.state.Records = append(o.store.state.Records[:j], o.store.state.Records[j+1:]...)
+			}
+		}
+
+		o.save()
+	}
+
+	o.retryFailed = false
+
+	return nil
+}
+
+// apply sends one offline change to server. If record was changed or deleted on another device,
+// offline update is saved as new record, so user can resolve conflict manually,
+// and offline deletion is skipped, so changes of another device aren't lost. Both are remembered as conflicts.
+func (o *offlineConn) apply(change localChange) error {
+	record := change.Record.entity()
+
+	switch change.Kind {
+	case changeCreate:
+		record.ID = ""
+
+		_, err := o.remote.CreateRecord(o.token, record)
+
+		return err
+	case changeUpdate:
+		err := o.remote.UpdateRecord(o.token, record)
+		if !errors.Is(err, storage.ErrConflict) && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+
+		log.Warnf("%s :: %s", "offline update conflicts, it's saved as new record", record.ID)
+
+		// Labels may be encrypted, so copy isn't renamed, conflict tells user, which record is copy.
+		conflicted := record
+		conflicted.ID, conflicted.Revision = "", 0
+
+		copyID, err := o.remote.CreateRecord(o.token, conflicted)
+		if err != nil {
+			return err
+		}
+
+		o.store.conflict(changeUpdate, record, copyID)
+
+		return nil
+	case changeDelete:
+		err := o.remote.DeleteRecord(o.token, record.ID, record.Revision)
+		if errors.Is(err, storage.ErrNotFound) {
+			// Record is already deleted on another device.
+			log.Infoln(err)
+
+			return nil
+		}
+		if errors.Is(err, storage.ErrConflict) {
+			log.Warnf("%s :: %s", "offline deletion conflicts, record is kept", record.ID)
+
+			o.store.conflict(changeDelete, record, "")
+
+			return nil
+		}
+
+		return err
+	default:
+		return storage.ErrUnknown
+	}
+}
+
+// pull gets changes made on server after revision of local store and merges them page by page.
+// Every page is saved, so interrupted pull continues from the last page.
+func (o *offlineConn) pull() error {
+	sinceRevision := o.store.state.Revision
+
+	for {
+		changes, err := o.remote.Sync(o.token, sinceRevision)
+		if err != nil {
+			return err
+		}
+
+		o.store.merge(changes, sinceRevision)
+		o.save()
+
+		if !changes.More || changes.Revision <= sinceRevision {
+			return nil
+		}
+
+		sinceRevision = changes.Revision
+	}
+}
+
+// refresh pulls changes after change was made on server. Change is already made, so error is only logged.
+func (o *offlineConn) refresh() {
+	if err := o.pull(); err != nil {
+		log.Infoln(err)
+	}
+}
+
+// save saves local store. Callers, whose change is already on server, may ignore error.
+func (o *offlineConn) save() error {
+	if err := o.store.save(); err != nil {
+		log.Warnf("%s :: %v", "save local store fault", err)
+
+		return storage.ErrUnknown
+	}
+
+	return nil
+}
+
+// sessionCredentials returns credentials needed to login again. Master key isn't kept.
+func sessionCredentials(credentials entity.UserCredentials) entity.UserCredentials {
+	return entity.UserCredentials{Login: credentials.Login, Password: credentials.Password}
+}
+
+// offlineError converts unavailability of server to error of operation, which doesn't work offline.
+func offlineError(err error) error {
+	if errors.Is(err, controller.ErrServerUnavailable) {
+		return controller.ErrOffline
+	}
+
+	return err
+}
@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	offlineKey         = bytes.Repeat([]byte{0x07}, 32)
+	offlineCredentials = entity.UserCredentials{Login: "Login", Password: "Password"}
+	offlineKDF         = entity.KDFParams{Salt: bytes.Repeat([]byte{0x01}, 16), Time: 3, Memory: 64 * 1024, Threads: 4}
+)
+
+// mirrorRecords logins online, confirms key by public key on server and mirrors records of user to directory.
+func mirrorRecords(t *testing.T, directory string) {
+	remote := mocks.NewClientConn(t)
+	conn := newOfflineConn(remote, directory)
+
+	remote.On("Login", offlineCredentials).Return(entity.Session{Token: "token", KDF: offlineKDF}, nil).Once()
+	remote.On("Sync", entity.AuthToken("token"), int64(0)).Return(entity.RecordChanges{
+		Records: []entity.Record{
+			{
+				ID: "1", Metadata: "text", Type: entity.TypeText, Data: []byte("encrypted text"), Revision: 1,
+				Name: "Note", Tags: []string{"personal"}, Folder: "notes",
+			},
+			{ID: "2", Metadata: "file", Type: entity.TypeFile, Revision: 2},
+		},
+		Revision: 2,
+	}, nil).Once()
+
+	session, err := conn.Login(offlineCredentials)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.AuthToken("token"), session.Token)
+	assert.NoError(t, conn.SetKey(offlineKey))
+
+	publicKey, err := pkg.PublicKey(offlineKey)
+	assert.NoError(t, err)
+	remote.On("SetPublicKey", entity.AuthToken("token"), publicKey).Return(nil).Once()
+	assert.NoError(t, conn.SetPublicKey("", publicKey))
+
+	records, err := conn.GetRecordsInfo("")
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	remote.AssertExpectations(t)
+}
+
+func TestOfflineConn_Mirror(t *testing.T) {
+	directory := t.TempDir()
+	mirrorRecords(t, directory)
+
+	files, err := os.ReadDir(directory)
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(directory, file.Name()))
+		assert.NoError(t, err)
+
+		if strings.HasSuffix(file.Name(), ".cache") {
+			assert.NotContains(t, string(data), "encrypted text")
+			assert.NotContains(t, string(data), "text")
+		}
+	}
+}
+
+func TestOfflineConn_Offline(t *testing.T) {
+	directory := t.TempDir()
+	mirrorRecords(t, directory)
+
+	remote := mocks.NewClientConn(t)
+	conn := newOfflineConn(remote, directory)
+
+	remote.On("Login", offlineCredentials).Return(entity.Session{}, controller.ErrServerUnavailable)
+
+	tc := []struct {
+		name  string
+		valid func()
+	}{
+		{
+			"Login offline with known login",
+			func() {
+				session, err := conn.Login(offlineCredentials)
+				assert.NoError(t, err)
+				assert.Equal(t, entity.Session{KDF: offlineKDF}, session)
+				assert.NoError(t, conn.SetKey(offlineKey))
+			},
+		},
+		{
+			"Get mirrored records",
+			func() {
+				records, err := conn.GetRecordsInfo("")
+				assert.NoError(t, err)
+				assert.Equal(t, []entity.Record{
+					{
+						ID: "1", Metadata: "text", Type: entity.TypeText, Revision: 1,
+						Name: "Note", Tags: []string{"personal"}, Folder: "notes",
+					},
+					{ID: "2", Metadata: "file", Type: entity.TypeFile, Revision: 2},
+				}, records)
+
+				page, err := conn.ListRecords("", entity.RecordsQuery{Tags: []string{"Personal"}, Folder: "notes"})
+				assert.NoError(t, err)
+				assert.Equal(t, int32(1), page.Total)
+				assert.Equal(t, "1", page.Records[0].ID)
+
+				record, err := conn.GetRecord("", "1")
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("encrypted text"), record.Data)
+
+				record, err = conn.GetRecord("", "2")
+				assert.NoError(t, err)
+				assert.Equal(t, "file", record.Metadata)
+
+				_, err = conn.GetRecord("", "3")
+				assert.Equal(t, storage.ErrNotFound, err)
+			},
+		},
+		{
+			"Change records offline",
+			func() {
+				recordID, err := conn.CreateRecord("", entity.Record{
+					Metadata: "new",
+					Type:     entity.TypeText,
+					Data:     []byte("new data"),
+				})
+				assert.NoError(t, err)
+				assert.True(t, strings.HasPrefix(recordID, localIDPrefix))
+				assert.NoError(t, conn.UpdateRecord("", entity.Record{
+					ID:       "1",
+					Metadata: "text",
+					Type:     entity.TypeText,
+					Data:     []byte("first update"),
+					Revision: 1,
+				}))
+				assert.NoError(t, conn.UpdateRecord("", entity.Record{
+					ID:       "1",
+					Metadata: "text",
+					Type:     entity.TypeText,
+					Data:     []byte("updated text"),
+					Revision: 1,
+				}))
+				assert.NoError(t, conn.DeleteRecord("", "2", 2))
+				assert.Len(t, conn.store.state.Changes, 3)
+
+				records, err := conn.GetRecordsInfo("")
+				assert.NoError(t, err)
+				assert.Len(t, records, 2)
+				assert.True(t, isLocalID(records[1].ID))
+
+				record, err := conn.GetRecord("", "1")
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("updated text"), record.Data)
+			},
+		},
+		{
+			"Online only operations",
+			func() {
+				_, err := conn.GetRecordVersions("", "1")
+				assert.Equal(t, controller.ErrOffline, err)
+
+				_, err = conn.DownloadFile("", "2", &bytes.Buffer{})
+				assert.Equal(t, controller.ErrOffline, err)
+
+				_, err = conn.Register(offlineCredentials)
+				assert.Equal(t, controller.ErrOffline, err)
+			},
+		},
+	}
+
+	remote.On("Register", offlineCredentials).Return(entity.Session{}, controller.ErrServerUnavailable)
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.valid()
+	}
+
+	t.Log("Server is back, offline changes are synced in order")
+
+	remote = mocks.NewClientConn(t)
+	conn.remote = remote
+
+	var synced []string
+
+	remote.On("Login", offlineCredentials).Return(entity.Session{Token: "new token", KDF: offlineKDF}, nil).Once()
+	remote.On("CreateRecord", entity.AuthToken("new token"), entity.Record{
+		Metadata: "new",
+		Type:     entity.TypeText,
+		Data:     []byte("new data"),
+	}).Run(func(mock.Arguments) { synced = append(synced, "create") }).Return("", nil).Once()
+	remote.On("UpdateRecord", entity.AuthToken("new token"), entity.Record{
+		ID:       "1",
+		Metadata: "text",
+		Type:     entity.TypeText,
+		Data:     []byte("updated text"),
+		Revision: 1,
+	}).Run(func(mock.Arguments) { synced = append(synced, "update") }).Return(storage.ErrConflict).Once()
+	remote.On("CreateRecord", entity.AuthToken("new token"), entity.Record{
+		Metadata: "text",
+		Type:     entity.TypeText,
+		Data:     []byte("updated text"),
+	}).Run(func(mock.Arguments) { synced = append(synced, "conflicted copy") }).Return("5", nil).Once()
+	remote.On("DeleteRecord", entity.AuthToken("new token"), "2", int64(2)).
+		Run(func(mock.Arguments) { synced = append(synced, "delete") }).Return(storage.ErrConflict).Once()
+	remote.On("Sync", entity.AuthToken("new token"), int64(2)).Return(entity.RecordChanges{
+		Records: []entity.Record{
+			{ID: "1", Metadata: "text", Type: entity.TypeText, Data: []byte("other device"), Revision: 3},
+			{ID: "4", Metadata: "new", Type: entity.TypeText, Data: []byte("new data"), Revision: 4},
+		},
+		Revision: 4,
+		More:     true,
+	}, nil).Once()
+	remote.On("Sync", entity.AuthToken("new token"), int64(4)).Return(entity.RecordChanges{
+		Records: []entity.Record{
+			{ID: "5", Metadata: "text", Type: entity.TypeText, Data: []byte("updated text"), Revision: 5},
+		},
+		Deleted:  []entity.Tombstone{{RecordID: "3", Revision: 6}},
+		Revision: 6,
+	}, nil).Once()
+
+	records, err := conn.GetRecordsInfo("")
+	assert.NoError(t, err)
+	assert.Equal(t, []entity.Record{
+		{ID: "1", Metadata: "text", Type: entity.TypeText, Revision: 3},
+		{ID: "4", Metadata: "new", Type: entity.TypeText, Revision: 4},
+		{ID: "5", Metadata: "text", Type: entity.TypeText, Revision: 5},
+	}, records)
+	assert.Equal(t, []string{"create", "update", "conflicted copy", "delete"}, synced)
+	assert.Empty(t, conn.store.state.Changes)
+	assert.Equal(t, int64(6), conn.store.state.Revision)
+
+	t.Log("Conflicts are shown once")
+	conflicts := conn.Conflicts()
+	assert.Len(t, conflicts, 2)
+	assert.Equal(t, []string{changeUpdate, changeDelete}, []string{conflicts[0].Kind, conflicts[1].Kind})
+	assert.Equal(t, []string{"1", "2"}, []string{conflicts[0].RecordID, conflicts[1].RecordID})
+	assert.Equal(t, "5", conflicts[0].CopyID)
+	assert.Equal(t, "text", conflicts[0].Metadata)
+	assert.Empty(t, conn.Conflicts())
+
+	remote.AssertExpectations(t)
+}
+
+func TestOfflineConn_RejectedChange(t *testing.T) {
+	directory := t.TempDir()
+	mirrorRecords(t, directory)
+
+	remote := mocks.NewClientConn(t)
+	conn := newOfflineConn(remote, directory)
+
+	// Login, opening of store and update are offline.
+	remote.On("Login", offlineCredentials).Return(entity.Session{}, controller.ErrServerUnavailable).Times(3)
+
+	_, err := conn.Login(offlineCredentials)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.SetKey(offlineKey))
+
+	updated := entity.Record{ID: "1", Metadata: "text", Type: entity.TypeText, Data: []byte("updated"), Revision: 1}
+	assert.NoError(t, conn.UpdateRecord("", updated))
+
+	t.Log("Change, which server rejects, stays queued and isn't sent again in the same session")
+	remote.On("Login", offlineCredentials).Return(entity.Session{Token: "token", KDF: offlineKDF}, nil).Once()
+	remote.On("UpdateRecord", entity.AuthToken("token"), updated).Return(storage.ErrForbidden).Once()
+	remote.On("Sync", entity.AuthToken("token"), int64(2)).Return(entity.RecordChanges{Revision: 2}, nil).Twice()
+
+	_, err = conn.GetRecordsInfo("")
+	assert.NoError(t, err)
+	_, err = conn.GetRecordsInfo("")
+	assert.NoError(t, err)
+
+	assert.Len(t, conn.store.state.Changes, 1)
+	assert.Equal(t, []byte("updated"), conn.store.state.Records[conn.store.find("1")].Data, "local record is kept")
+
+	conflicts := conn.Conflicts()
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, changeUpdate, conflicts[0].Kind)
+	assert.Equal(t, storage.ErrForbidden.Error(), conflicts[0].Error)
+	assert.Len(t, conn.Conflicts(), 1, "rejected change is shown, until it's sent")
+
+	t.Log("Rejected change is sent again after login and dequeued, when server applies it")
+	conn = newOfflineConn(remote, directory)
+
+	remote.On("Login", offlineCredentials).Return(entity.Session{Token: "new token", KDF: offlineKDF}, nil).Once()
+	remote.On("UpdateRecord", entity.AuthToken("new token"), updated).Return(nil).Once()
+
+	_, err = conn.Login(offlineCredentials)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.SetKey(offlineKey))
+
+	assert.Empty(t, conn.store.state.Changes)
+	assert.Empty(t, conn.Conflicts())
+
+	remote.AssertExpectations(t)
+}
+
+func TestOfflineConn_LoginOffline(t *testing.T) {
+	directory := t.TempDir()
+	mirrorRecords(t, directory)
+
+	remote := mocks.NewClientConn(t)
+	conn := newOfflineConn(remote, directory)
+
+	remote.On("Login", mock.AnythingOfType("entity.UserCredentials")).
+		Return(entity.Session{}, controller.ErrServerUnavailable)
+
+	t.Log("Login offline with unknown login")
+	_, err := conn.Login(entity.UserCredentials{Login: "Other", Password: "Password"})
+	assert.Equal(t, controller.ErrServerUnavailable, err)
+
+	t.Log("Login offline with wrong master key")
+	_, err = conn.Login(offlineCredentials)
+	assert.NoError(t, err)
+	assert.Equal(t, controller.ErrWrongMasterKey, conn.SetKey(bytes.Repeat([]byte{0x08}, 32)))
+
+	t.Log("Login offline before key is confirmed by server")
+	unconfirmed, onlineRemote := t.TempDir(), mocks.NewClientConn(t)
+	online := newOfflineConn(onlineRemote, unconfirmed)
+	other := entity.UserCredentials{Login: "Unconfirmed", Password: "Password"}
+
+	onlineRemote.On("Login", other).Return(entity.Session{Token: "token", KDF: offlineKDF}, nil).Once()
+
+	_, err = online.Login(other)
+	assert.NoError(t, err)
+	assert.NoError(t, online.SetKey(offlineKey))
+
+	_, err = os.Stat(online.store.statePath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "store isn't saved by key, which isn't confirmed")
+
+	_, err = newOfflineConn(remote, unconfirmed).Login(other)
+	assert.Equal(t, controller.ErrServerUnavailable, err)
+
+	t.Log("Connection isn't used offline until store is opened")
+	remote.On("GetRecordsInfo", entity.AuthToken("")).Return(nil, controller.ErrServerUnavailable).Once()
+	_, err = conn.GetRecordsInfo("")
+	assert.Equal(t, controller.ErrServerUnavailable, err)
+}
+
+func TestOfflineConn_LegacyProfile(t *testing.T) {
+	directory := t.TempDir()
+	remote := mocks.NewClientConn(t)
+	conn := newOfflineConn(remote, directory)
+	legacy := entity.KDFParams{Legacy: true}
+
+	t.Log("Profile of user on legacy key isn't saved")
+	remote.On("Login", offlineCredentials).Return(entity.Session{Token: "token", KDF: legacy}, nil).Once()
+
+	_, err := conn.Login(offlineCredentials)
+	assert.NoError(t, err)
+	_, err = os.Stat(conn.store.profilePath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Equal(t, controller.ErrLegacyKey, conn.store.saveProfile(offlineCredentials.Login, legacy))
+
+	t.Log("Profile on legacy key, which was saved before, isn't used offline")
+	assert.NoError(t, conn.store.writeProfile(localProfile{
+		Login: offlineCredentials.Login, KDF: legacy, KeyCheck: keyCheck(legacyKey()),
+	}))
+	assert.Equal(t, controller.ErrLegacyKey, conn.store.open(legacyKey()))
+
+	offline := mocks.NewClientConn(t)
+	offline.On("Login", offlineCredentials).Return(entity.Session{}, controller.ErrServerUnavailable).Once()
+	_, err = newOfflineConn(offline, directory).Login(offlineCredentials)
+	assert.Equal(t, controller.ErrServerUnavailable, err)
+
+	t.Log("Profile is saved, when key is upgraded")
+	remote.On("UpgradeKDF", entity.AuthToken("token"), offlineKDF).Return(nil).Once()
+	assert.NoError(t, conn.UpgradeKDF("token", offlineKDF))
+
+	profile, err := conn.store.readProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, offlineKDF, profile.KDF)
+	assert.Empty(t, profile.KeyCheck)
+
+	remote.AssertExpectations(t)
+	offline.AssertExpectations(t)
+}
+
+func TestClient_OfflineLogin(t *testing.T) {
+	directory := t.TempDir()
+	remote := mocks.NewClientConn(t)
+	handlers := newClientHandlers(newOfflineConn(remote, directory), false)
+
+	credentials := offlineCredentials
+	credentials.MasterKey = []byte("hello")
+
+	remote.On("Login", credentials).Return(entity.Session{Token: "token", KDF: offlineKDF}, nil).Once()
+	remote.On("Sync", entity.AuthToken("token"), int64(0)).Return(entity.RecordChanges{Revision: 1}, nil).Once()
+	remote.On("SetPublicKey", entity.AuthToken("token"), mock.AnythingOfType("[]uint8")).Return(nil).Once()
+
+	assert.NoError(t, handlers.Login(credentials))
+	assert.Equal(t, offlineCredentials, handlers.conn.(*offlineConn).credentials)
+
+	records, err := handlers.GetRecordsInfo()
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	t.Log("Conflicts are got with decrypted labels")
+	metadata, err := pkg.EncryptMetadata(handlers.masterKey, "bank")
+	assert.NoError(t, err)
+	handlers.conn.(*offlineConn).store.conflict(changeUpdate, entity.Record{ID: "1", Metadata: metadata}, "2")
+
+	conflicts, err := handlers.Conflicts()
+	assert.NoError(t, err)
+	assert.Len(t, conflicts, 1)
+	assert.Equal(t, "bank", conflicts[0].Metadata)
+	assert.Equal(t, "2", conflicts[0].CopyID)
+
+	remote.AssertExpectations(t)
+}
+
+func TestOfflineConn_Sessions(t *testing.T) {
+	directory := t.TempDir()
+	mirrorRecords(t, directory)
+
+	remote := mocks.NewClientConn(t)
+	conn := newOfflineConn(remote, directory)
+
+	remote.On("Login", offlineCredentials).Return(entity.Session{
+		Token:        "token",
+		RefreshToken: "refresh",
+		ExpiresAt:    time.Now().Add(time.Hour),
+		KDF:          offlineKDF,
+	}, nil).Once()
+
+	_, err := conn.Login(offlineCredentials)
+	assert.NoError(t, err)
+	assert.NoError(t, conn.SetKey(offlineKey))
+
+	t.Log("Session is refreshed, when token expires soon")
+	conn.expiresAt = time.Now().Add(time.Second)
+	remote.On("RefreshSession", entity.RefreshToken("refresh")).Return(entity.Session{
+		Token:        "new token",
+		RefreshToken: "new refresh",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}, nil).Once()
+	remote.On("Sync", entity.AuthToken("new token"), int64(2)).Return(entity.RecordChanges{Revision: 2}, nil).Once()
+
+	_, err = conn.GetRecordsInfo("ignored token")
+	assert.NoError(t, err)
+
+	session, err := conn.RefreshSession("ignored refresh")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.AuthToken("new token"), session.Token)
+	assert.Equal(t, entity.RefreshToken("new refresh"), session.RefreshToken)
+
+	t.Log("Revoked session isn't renewed by credentials")
+	conn.expiresAt = time.Now()
+	remote.On("RefreshSession", entity.RefreshToken("new refresh")).
+		Return(entity.Session{}, storage.ErrUnauthenticated).Once()
+
+	_, err = conn.GetRecordVersions("", "1")
+	assert.Equal(t, storage.ErrUnauthenticated, err)
+
+	t.Log("Logout forgets user")
+	remote.On("Logout", entity.RefreshToken("new refresh")).Return(nil).Once()
+
+	assert.NoError(t, conn.Logout(""))
+	assert.Nil(t, conn.store)
+	assert.Empty(t, conn.credentials)
+	assert.Empty(t, conn.token)
+
+	remote.AssertExpectations(t)
+}
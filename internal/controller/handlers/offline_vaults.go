@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+)
+
+// Shared vaults aren't mirrored to local store, so they work only online.
+
+// GetPublicKey gets public key of user from server. Works only online.
+func (o *offlineConn) GetPublicKey(token entity.AuthToken, login string) ([]byte, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.remote.GetPublicKey(token, login)
+}
+
+// SetPublicKey sets public key of user on server and confirms key of local store by it. Works only online.
+func (o *offlineConn) SetPublicKey(token entity.AuthToken, publicKey []byte) error {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return err
+	}
+
+	if err = o.remote.SetPublicKey(token, publicKey); err != nil {
+		return err
+	}
+
+	// Server keeps this public key, so key of local store is confirmed, if public key is derived from it.
+	o.publicKey = publicKey
+	o.confirmKey()
+
+	return nil
+}
+
+// CreateVault creates vault on server. Works only online.
+func (o *offlineConn) CreateVault(token entity.AuthToken, vault entity.Vault) (string, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	return o.remote.CreateVault(token, vault)
+}
+
+// GetVaults gets vaults of user from server. Works only online.
+func (o *offlineConn) GetVaults(token entity.AuthToken) ([]entity.Vault, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.remote.GetVaults(token)
+}
+
+// GetVaultMembers gets members of vault from server. Works only online.
+func (o *offlineConn) GetVaultMembers(token entity.AuthToken, vaultID string) ([]entity.VaultMember, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.remote.GetVaultMembers(token, vaultID)
+}
+
+// InviteMember adds member to vault on server. Works only online.
+func (o *offlineConn) InviteMember(token entity.AuthToken, member entity.VaultMember) error {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return err
+	}
+
+	return o.remote.InviteMember(token, member)
+}
+
+// RevokeMember removes member from vault on server and replaces vault key. Works only online.
+func (o *offlineConn) RevokeMember(token entity.AuthToken, rotation entity.VaultRotation) error {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return err
+	}
+
+	return o.remote.RevokeMember(token, rotation)
+}
+
+// GetVaultRecords gets records of vault from server. Works only online.
+func (o *offlineConn) GetVaultRecords(token entity.AuthToken, vaultID string) ([]entity.Record, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.remote.GetVaultRecords(token, vaultID)
+}
+
+// GetVaultRecord gets record of vault from server. Works only online.
+func (o *offlineConn) GetVaultRecord(token entity.AuthToken, vaultID, recordID string) (entity.Record, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return entity.Record{}, err
+	}
+
+	return o.remote.GetVaultRecord(token, vaultID, recordID)
+}
+
+// CreateVaultRecord creates record in vault on server. Works only online.
+func (o *offlineConn) CreateVaultRecord(token entity.AuthToken, record entity.Record) (string, error) {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	return o.remote.CreateVaultRecord(token, record)
+}
+
+// UpdateVaultRecord updates record in vault on server. Works only online.
+func (o *offlineConn) UpdateVaultRecord(token entity.AuthToken, record entity.Record) error {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return err
+	}
+
+	return o.remote.UpdateVaultRecord(token, record)
+}
+
+// DeleteVaultRecord deletes record from vault on server. Works only online.
+func (o *offlineConn) DeleteVaultRecord(token entity.AuthToken, vaultID, recordID string) error {
+	o.Lock()
+	defer o.Unlock()
+
+	token, err := o.onlineToken(token)
+	if err != nil {
+		return err
+	}
+
+	return o.remote.DeleteVaultRecord(token, vaultID, recordID)
+}
+
+// onlineToken gets token for request, which works only online. Before login passed token is used.
+func (o *offlineConn) onlineToken(token entity.AuthToken) (entity.AuthToken, error) {
+	if !o.ready() {
+		return token, nil
+	}
+
+	if err := o.online(); err != nil {
+		return "", offlineError(err)
+	}
+
+	return o.token, nil
+}
@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	pb "github.com/bbt-t/lets-go-keep/protocols/grpc"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// kdfParamsToProto converts key derivation parameters to gRPC message.
+func kdfParamsToProto(params entity.KDFParams) *pb.KDFParams {
+	if len(params.Salt) == 0 && !params.Legacy {
+		return nil
+	}
+
+	return &pb.KDFParams{
+		Salt:    params.Salt,
+		Time:    params.Time,
+		Memory:  params.Memory,
+		Threads: uint32(params.Threads),
+		Legacy:  params.Legacy,
+	}
+}
+
+// kdfParamsFromProto converts gRPC message to key derivation parameters.
+func kdfParamsFromProto(params *pb.KDFParams) entity.KDFParams {
+	if params == nil {
+		return entity.KDFParams{}
+	}
+
+	return entity.KDFParams{
+		Salt:    params.Salt,
+		Time:    params.Time,
+		Memory:  params.Memory,
+		Threads: uint8(params.Threads),
+		Legacy:  params.Legacy,
+	}
+}
+
+// sessionToProto converts session to gRPC message. Expiration time is sent relative to now,
+// so clocks of client and server don't have to be synchronised.
+func sessionToProto(session entity.Session) *pb.Session {
+	message := &pb.Session{
+		SessionToken: string(session.Token),
+		Kdf:          kdfParamsToProto(session.KDF),
+		RefreshToken: string(session.RefreshToken),
+	}
+
+	if !session.ExpiresAt.IsZero() {
+		message.ExpiresIn = int64(time.Until(session.ExpiresAt) / time.Second)
+	}
+
+	return message
+}
+
+// sessionFromProto converts gRPC message to session. Expiration time is counted by client clock.
+func sessionFromProto(message *pb.Session) entity.Session {
+	session := entity.Session{
+		Token:        entity.AuthToken(message.SessionToken),
+		RefreshToken: entity.RefreshToken(message.RefreshToken),
+		KDF:          kdfParamsFromProto(message.Kdf),
+	}
+
+	if message.ExpiresIn > 0 {
+		session.ExpiresAt = time.Now().Add(time.Duration(message.ExpiresIn) * time.Second)
+	}
+
+	return session
+}
+
+// recordToProto converts record with data to gRPC message.
+func recordToProto(record entity.Record) *pb.Record {
+	return &pb.Record{
+		Id:         record.ID,
+		Type:       pb.MessageType(record.Type),
+		Metadata:   record.Metadata,
+		StoredData: record.Data,
+		Revision:   record.Revision,
+		UpdatedAt:  timestampToProto(record.UpdatedAt),
+		Name:       record.Name,
+		Tags:       record.Tags,
+		Folder:     record.Folder,
+		BlindIndex: record.BlindIndex,
+		VaultId:    record.VaultID,
+	}
+}
+
+// recordFromProto converts gRPC message to record with data.
+func recordFromProto(record *pb.Record) entity.Record {
+	return entity.Record{
+		ID:         record.Id,
+		Metadata:   record.Metadata,
+		Type:       entity.RecordType(record.Type),
+		Data:       record.StoredData,
+		Revision:   record.Revision,
+		UpdatedAt:  timestampFromProto(record.UpdatedAt),
+		Name:       record.Name,
+		Tags:       record.Tags,
+		Folder:     record.Folder,
+		BlindIndex: record.BlindIndex,
+		VaultID:    record.VaultId,
+	}
+}
+
+// recordsQueryToProto converts records query to gRPC message.
+func recordsQueryToProto(query entity.RecordsQuery) *pb.ListRecordsRequest {
+	message := &pb.ListRecordsRequest{
+		Search:     query.Search,
+		Tags:       query.Tags,
+		Folder:     query.Folder,
+		BlindIndex: query.BlindIndex,
+		Types:      make([]pb.MessageType, 0, len(query.Types)),
+		Sort:       pb.RecordsSort(query.Sort),
+		Descending: query.Descending,
+		Offset:     query.Offset,
+		Limit:      query.Limit,
+	}
+
+	for _, recordType := range query.Types {
+		message.Types = append(message.Types, pb.MessageType(recordType))
+	}
+
+	return message
+}
+
+// recordsQueryFromProto converts gRPC message to records query.
+func recordsQueryFromProto(message *pb.ListRecordsRequest) entity.RecordsQuery {
+	query := entity.RecordsQuery{
+		Search:     message.Search,
+		Tags:       message.Tags,
+		Folder:     message.Folder,
+		BlindIndex: message.BlindIndex,
+		Sort:       entity.RecordsSort(message.Sort),
+		Descending: message.Descending,
+		Offset:     message.Offset,
+		Limit:      message.Limit,
+	}
+
+	for _, recordType := range message.Types {
+		query.Types = append(query.Types, entity.RecordType(recordType))
+	}
+
+	return query
+}
+
+// recordsPageToProto converts records page without data to gRPC message.
+func recordsPageToProto(page entity.RecordsPage) *pb.RecordsPage {
+	message := &pb.RecordsPage{
+		Records: make([]*pb.Record, 0, len(page.Records)),
+		Total:   page.Total,
+	}
+
+	for _, record := range page.Records {
+		message.Records = append(message.Records, recordToProto(record))
+	}
+
+	return message
+}
+
+// recordsPageFromProto converts gRPC message to records page.
+func recordsPageFromProto(message *pb.RecordsPage) entity.RecordsPage {
+	page := entity.RecordsPage{
+		Records: make([]entity.Record, 0, len(message.Records)),
+		Total:   message.Total,
+	}
+
+	for _, record := range message.Records {
+		page.Records = append(page.Records, recordFromProto(record))
+	}
+
+	return page
+}
+
+// recordChangesToProto converts changed and deleted records to gRPC message.
+func recordChangesToProto(changes entity.RecordChanges) *pb.RecordChanges {
+	message := &pb.RecordChanges{
+		Records:  make([]*pb.Record, 0, len(changes.Records)),
+		Deleted:  make([]*pb.Tombstone, 0, len(changes.Deleted)),
+		Revision: changes.Revision,
+		More:     changes.More,
+	}
+
+	for _, record := range changes.Records {
+		message.Records = append(message.Records, recordToProto(record))
+	}
+
+	for _, tombstone := range changes.Deleted {
+		message.Deleted = append(message.Deleted, &pb.Tombstone{
+			RecordId:  tombstone.RecordID,
+			Revision:  tombstone.Revision,
+			DeletedAt: timestampToProto(tombstone.DeletedAt),
+		})
+	}
+
+	return message
+}
+
+// recordChangesFromProto converts gRPC message to changed and deleted records.
+func recordChangesFromProto(message *pb.RecordChanges) entity.RecordChanges {
+	changes := entity.RecordChanges{
+		Records:  make([]entity.Record, 0, len(message.Records)),
+		Deleted:  make([]entity.Tombstone, 0, len(message.Deleted)),
+		Revision: message.Revision,
+		More:     message.More,
+	}
+
+	for _, record := range message.Records {
+		changes.Records = append(changes.Records, recordFromProto(record))
+	}
+
+	for _, tombstone := range message.Deleted {
+		changes.Deleted = append(changes.Deleted, entity.Tombstone{
+			RecordID:  tombstone.RecordId,
+			Revision:  tombstone.Revision,
+			DeletedAt: timestampFromProto(tombstone.DeletedAt),
+		})
+	}
+
+	return changes
+}
+
+// vaultToProto converts vault to gRPC message.
+func vaultToProto(vault entity.Vault) *pb.Vault {
+	return &pb.Vault{
+		Id:         vault.ID,
+		Name:       vault.Name,
+		Role:       pb.VaultRole(vault.Role),
+		WrappedKey: vault.WrappedKey,
+	}
+}
+
+// vaultFromProto converts gRPC message to vault.
+func vaultFromProto(vault *pb.Vault) entity.Vault {
+	return entity.Vault{
+		ID:         vault.Id,
+		Name:       vault.Name,
+		Role:       entity.VaultRole(vault.Role),
+		WrappedKey: vault.WrappedKey,
+	}
+}
+
+// vaultMemberToProto converts vault member to gRPC message.
+func vaultMemberToProto(member entity.VaultMember) *pb.VaultMember {
+	return &pb.VaultMember{
+		VaultId:    member.VaultID,
+		Login:      member.Login,
+		Role:       pb.VaultRole(member.Role),
+		WrappedKey: member.WrappedKey,
+		KeyMac:     member.KeyMAC,
+	}
+}
+
+// vaultMemberFromProto converts gRPC message to vault member.
+func vaultMemberFromProto(member *pb.VaultMember) entity.VaultMember {
+	return entity.VaultMember{
+		VaultID:    member.VaultId,
+		Login:      member.Login,
+		Role:       entity.VaultRole(member.Role),
+		WrappedKey: member.WrappedKey,
+		KeyMAC:     member.KeyMac,
+	}
+}
+
+// vaultRotationToProto converts replacement of vault key to gRPC message.
+func vaultRotationToProto(rotation entity.VaultRotation) *pb.VaultRotation {
+	message := &pb.VaultRotation{
+		VaultId: rotation.VaultID,
+		Revoked: rotation.Revoked,
+		Members: make([]*pb.VaultMember, 0, len(rotation.Members)),
+		Records: make([]*pb.Record, 0, len(rotation.Records)),
+	}
+
+	for _, member := range rotation.Members {
+		message.Members = append(message.Members, vaultMemberToProto(member))
+	}
+	for _, record := range rotation.Records {
+		message.Records = append(message.Records, recordToProto(record))
+	}
+
+	return message
+}
+
+// vaultRotationFromProto converts gRPC message to replacement of vault key.
+func vaultRotationFromProto(message *pb.VaultRotation) entity.VaultRotation {
+	rotation := entity.VaultRotation{
+		VaultID: message.VaultId,
+		Revoked: message.Revoked,
+		Members: make([]entity.VaultMember, 0, len(message.Members)),
+		Records: make([]entity.Record, 0, len(message.Records)),
+	}
+
+	for _, member := range message.Members {
+		rotation.Members = append(rotation.Members, vaultMemberFromProto(member))
+	}
+	for _, record := range message.Records {
+		rotation.Records = append(rotation.Records, recordFromProto(record))
+	}
+
+	return rotation
+}
+
+// timestampToProto converts time to gRPC message. Zero time isn't sent.
+func timestampToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+// timestampFromProto converts gRPC message to time. Not sent time is zero.
+func timestampFromProto(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return t.AsTime()
+}
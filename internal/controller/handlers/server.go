@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"io"
 
 	"github.com/bbt-t/lets-go-keep/internal/controller"
 	"github.com/bbt-t/lets-go-keep/internal/entity"
@@ -11,126 +12,479 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// server struct for server handlers.
+// server struct for server handlers. Record handlers expect identity of user in context,
+// which is put there by server connection after token validation.
 type server struct {
 	Storage       storage.Storager
 	Authenticator Authenticator
+	Hasher        pkg.PasswordHasher
 }
 
-// newServerHandlers returns server handlers based on storage and authenticator (interface).
-func newServerHandlers(s storage.Storager, a Authenticator) *server {
+// newServerHandlers returns server handlers based on storage, authenticator and password hasher.
+func newServerHandlers(s storage.Storager, a Authenticator, h pkg.PasswordHasher) *server {
 	return &server{
 		Storage:       s,
 		Authenticator: a,
+		Hasher:        h,
 	}
 }
 
-// LoginUser logins user by login and password.
-func (s *server) LoginUser(credentials entity.UserCredentials) (entity.AuthToken, error) {
+// LoginUser logins user by login and password. Returns session with key derivation parameters of user.
+// Password hash made by old algorithm or with old parameters is replaced after successful login.
+func (s *server) LoginUser(credentials entity.UserCredentials) (entity.Session, error) {
 	if credentials.Login == "" || credentials.Password == "" {
-		return "", controller.ErrFieldIsEmpty
+		return entity.Session{}, controller.ErrFieldIsEmpty
 	}
 
-	credentials.Password = pkg.PasswordHash(credentials)
-
-	userID, err := s.Storage.LoginUser(credentials)
+	user, err := s.Storage.GetUser(credentials.Login)
 	if err != nil {
 		log.Warnf("%s :: %v", "get user login fault", err)
 
-		return "", err
+		return entity.Session{}, err
+	}
+
+	valid, rehash := s.verifyPassword(user.PasswordHash, credentials)
+	if !valid {
+		return entity.Session{}, storage.ErrWrongCredentials
+	}
+
+	if rehash {
+		s.rehashPassword(user.ID, credentials.Password)
+	}
+
+	userID := user.ID
+
+	kdf, err := s.Storage.GetKDFParams(userID)
+	if err != nil {
+		log.Warnf("%s :: %v", "get user kdf params fault", err)
+
+		return entity.Session{}, err
+	}
+
+	session, err := s.newSession(userID)
+	if err != nil {
+		return entity.Session{}, err
+	}
+	session.KDF = kdf
+
+	return session, nil
+}
+
+// RefreshSession exchanges refresh token for new session. Refresh token can be used only once,
+// new refresh token is returned with new session token.
+func (s *server) RefreshSession(refreshToken entity.RefreshToken) (entity.Session, error) {
+	if refreshToken == "" {
+		return entity.Session{}, storage.ErrUnauthenticated
+	}
+
+	newRefreshToken, refreshExpiresAt, err := s.Authenticator.CreateRefreshToken()
+	if err != nil {
+		log.Warnf("%s :: %v", "create refresh token fault", err)
+
+		return entity.Session{}, storage.ErrUnknown
+	}
+
+	userID, err := s.Storage.RotateSession(refreshTokenHash(refreshToken), refreshTokenHash(newRefreshToken), refreshExpiresAt)
+	if err != nil {
+		log.Infoln(err)
+
+		return entity.Session{}, err
 	}
 
-	authToken, errCreateToken := s.Authenticator.CreateToken(userID)
-	if errCreateToken != nil {
+	authToken, expiresAt, err := s.Authenticator.CreateToken(userID)
+	if err != nil {
 		log.Warnf("%s :: %v", "create token fault", err)
 
-		return "", storage.ErrUnknown
+		return entity.Session{}, storage.ErrUnknown
+	}
+
+	return entity.Session{Token: authToken, RefreshToken: newRefreshToken, ExpiresAt: expiresAt}, nil
+}
+
+// Logout ends session of refresh token. Session token stays valid until it expires.
+func (s *server) Logout(refreshToken entity.RefreshToken) error {
+	if refreshToken == "" {
+		return storage.ErrUnauthenticated
+	}
+
+	return s.Storage.DeleteSession(refreshTokenHash(refreshToken))
+}
+
+// RevokeSessions ends all sessions of user, so no device can refresh its session token.
+func (s *server) RevokeSessions(ctx context.Context) error {
+	userID, err := s.userValidate(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.Storage.DeleteSessions(userID)
+}
+
+// UpgradeKDF upgrades legacy key of user. Parameters with legacy mark are saved, when client starts upgrade,
+// parameters without it finish upgrade, when all records are encrypted by key derived with them.
+func (s *server) UpgradeKDF(ctx context.Context, params entity.KDFParams) error {
+	if _, err := s.userValidate(ctx); err != nil {
+		return err
+	}
+
+	if err := pkg.ValidateKDFParams(params); err != nil {
+		log.Infoln(err)
+
+		return pkg.ErrBadKDFParams
 	}
 
-	return authToken, nil
+	if params.Legacy {
+		return s.Storage.SetKDFParams(ctx, params)
+	}
+
+	return s.Storage.FinishKDFUpgrade(ctx, params)
 }
 
-// CreateUser creates new user by login and password.
-func (s *server) CreateUser(credentials entity.UserCredentials) (entity.AuthToken, error) {
+// newSession creates session token and refresh token of user and saves session.
+func (s *server) newSession(userID entity.UserID) (entity.Session, error) {
+	authToken, expiresAt, err := s.Authenticator.CreateToken(userID)
+	if err != nil {
+		log.Warnf("%s :: %v", "create token fault", err)
+
+		return entity.Session{}, storage.ErrUnknown
+	}
+
+	refreshToken, refreshExpiresAt, err := s.Authenticator.CreateRefreshToken()
+	if err != nil {
+		log.Warnf("%s :: %v", "create refresh token fault", err)
+
+		return entity.Session{}, storage.ErrUnknown
+	}
+
+	if err = s.Storage.CreateSession(userID, refreshTokenHash(refreshToken), refreshExpiresAt); err != nil {
+		log.Warnf("%s :: %v", "create session fault", err)
+
+		return entity.Session{}, err
+	}
+
+	return entity.Session{Token: authToken, RefreshToken: refreshToken, ExpiresAt: expiresAt}, nil
+}
+
+// CreateUser creates new user by login and password. Key derivation parameters are generated by client.
+func (s *server) CreateUser(credentials entity.UserCredentials) (entity.Session, error) {
 	if credentials.Login == "" || credentials.Password == "" {
-		return "", controller.ErrFieldIsEmpty
+		return entity.Session{}, controller.ErrFieldIsEmpty
+	}
+
+	if err := pkg.ValidateKDFParams(credentials.KDF); err != nil || credentials.KDF.Legacy {
+		log.Infoln(err)
+
+		return entity.Session{}, pkg.ErrBadKDFParams
+	}
+
+	if len(credentials.PublicKey) != 0 && len(credentials.PublicKey) != pkg.PublicKeySize {
+		return entity.Session{}, controller.ErrBadPublicKey
+	}
+
+	passwordHash, err := s.Hasher.Hash(credentials.Password)
+	if err != nil {
+		log.Warnf("%s :: %v", "hash password fault", err)
+
+		return entity.Session{}, storage.ErrUnknown
 	}
 
-	if err := s.Storage.CreateUser(entity.UserCredentials{
-		Login:    credentials.Login,
-		Password: pkg.PasswordHash(credentials),
+	if err = s.Storage.CreateUser(entity.UserCredentials{
+		Login:     credentials.Login,
+		Password:  passwordHash,
+		KDF:       credentials.KDF,
+		PublicKey: credentials.PublicKey,
 	}); err != nil {
 		log.Warnf("%s :: %v", "create new user fault", err)
 
-		return "", err
+		return entity.Session{}, err
 	}
 
 	return s.LoginUser(credentials)
 }
 
-// GetRecordsInfo gets all records from storage.
-func (s *server) GetRecordsInfo(ctx context.Context) ([]entity.Record, error) {
-	userID, err := s.userValidate(ctx)
+// verifyPassword checks password against stored hash. Reports if hash should be replaced by new one.
+func (s *server) verifyPassword(passwordHash string, credentials entity.UserCredentials) (valid, rehash bool) {
+	if pkg.IsLegacyPasswordHash(passwordHash) {
+		return pkg.VerifyLegacyPasswordHash(passwordHash, credentials), true
+	}
+
+	valid, err := s.Hasher.Verify(passwordHash, credentials.Password)
 	if err != nil {
-		log.Warnf("%s :: %v", "validate user fault", err)
+		log.Warnf("%s :: %v", "verify password fault", err)
 
+		return false, false
+	}
+
+	return valid, valid && s.Hasher.NeedsRehash(passwordHash)
+}
+
+// rehashPassword replaces password hash of user by hash of current algorithm.
+// Login shouldn't fail because of it, so errors are only logged.
+func (s *server) rehashPassword(userID entity.UserID, password string) {
+	passwordHash, err := s.Hasher.Hash(password)
+	if err != nil {
+		log.Warnf("%s :: %v", "rehash password fault", err)
+
+		return
+	}
+
+	if err = s.Storage.UpdatePasswordHash(userID, passwordHash); err != nil {
+		log.Warnf("%s :: %v", "update password hash fault", err)
+	}
+}
+
+// GetRecordsInfo gets all records from storage.
+func (s *server) GetRecordsInfo(ctx context.Context) ([]entity.Record, error) {
+	if _, err := s.userValidate(ctx); err != nil {
 		return nil, err
 	}
 
-	return s.Storage.GetRecordsInfo(context.WithValue(ctx, "userID", userID))
+	return s.Storage.GetRecordsInfo(ctx)
+}
+
+// ListRecords gets page of records, which match query, from storage.
+func (s *server) ListRecords(ctx context.Context, query entity.RecordsQuery) (entity.RecordsPage, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return entity.RecordsPage{}, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return entity.RecordsPage{}, err
+	}
+
+	return s.Storage.ListRecords(ctx, query.Normalize())
 }
 
 // GetRecord get record from storage by ID.
 func (s *server) GetRecord(ctx context.Context, recordID string) (entity.Record, error) {
-	userID, err := s.userValidate(ctx)
-	if err != nil {
-		log.Warnf("%s :: %v", "validate user fault", err)
-
+	if _, err := s.userValidate(ctx); err != nil {
 		return entity.Record{}, err
 	}
 
-	return s.Storage.GetRecord(context.WithValue(ctx, "userID", userID), recordID)
+	return s.Storage.GetRecord(ctx, recordID)
 }
 
-// CreateRecord added record to storage.
-func (s *server) CreateRecord(ctx context.Context, record entity.Record) error {
-	userID, err := s.userValidate(ctx)
-	if err != nil {
-		log.Warnf("%s :: %v", "validate user fault", err)
+// CreateRecord added record to storage and returns its ID.
+func (s *server) CreateRecord(ctx context.Context, record entity.Record) (string, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return "", err
+	}
 
+	return s.Storage.CreateRecord(ctx, record)
+}
+
+// DeleteRecord deletes record from storage, if it wasn't changed after revision.
+func (s *server) DeleteRecord(ctx context.Context, recordID string, revision int64) error {
+	if _, err := s.userValidate(ctx); err != nil {
 		return err
 	}
 
-	_, err = s.Storage.CreateRecord(context.WithValue(ctx, "userID", userID), record)
-	return err
+	return s.Storage.DeleteRecord(ctx, recordID, revision)
 }
 
-// DeleteRecord deletes record from storage.
-func (s *server) DeleteRecord(ctx context.Context, recordID string) error {
-	userID, err := s.userValidate(ctx)
-	if err != nil {
-		log.Warnf("%s :: %v", "validate user fault", err)
+// UpdateRecord updates record in storage, if it wasn't changed after record revision.
+// Previous version is kept in record history.
+func (s *server) UpdateRecord(ctx context.Context, record entity.Record) error {
+	if _, err := s.userValidate(ctx); err != nil {
+		return err
+	}
+
+	return s.Storage.UpdateRecord(ctx, record)
+}
+
+// GetRecordVersions gets previous versions of record from storage.
+func (s *server) GetRecordVersions(ctx context.Context, recordID string) ([]entity.RecordVersion, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.Storage.GetRecordVersions(ctx, recordID)
+}
 
+// RestoreRecordVersion makes previous version of record current, if record wasn't changed after revision.
+func (s *server) RestoreRecordVersion(ctx context.Context, recordID string, version int32, revision int64) error {
+	if _, err := s.userValidate(ctx); err != nil {
 		return err
 	}
 
-	return s.Storage.DeleteRecord(context.WithValue(ctx, "userID", userID), recordID)
+	return s.Storage.RestoreRecordVersion(ctx, recordID, version, revision)
 }
 
-// userValidate validate logic.
-func (s *server) userValidate(ctx context.Context) (entity.UserID, error) {
-	var userID entity.UserID
+// Sync gets records changed and deleted after revision from storage.
+func (s *server) Sync(ctx context.Context, sinceRevision int64) (entity.RecordChanges, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return entity.RecordChanges{}, err
+	}
 
-	token, ok := ctx.Value("authToken").(entity.AuthToken)
-	if !ok {
-		return userID, storage.ErrUnauthenticated
+	return s.Storage.Sync(ctx, sinceRevision)
+}
+
+// UploadFile added file record to storage, reading its data by chunks.
+func (s *server) UploadFile(ctx context.Context, record entity.Record, r io.Reader) (string, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return "", err
 	}
 
-	userIDValid, err := s.Authenticator.ValidateToken(token)
-	if err != nil {
-		log.Warnf("%s :: %v", "validate token fault", err)
+	return s.Storage.CreateFileRecord(ctx, record, r)
+}
+
+// DownloadFile gets record from storage and opens reader with its data. Caller must close reader.
+func (s *server) DownloadFile(ctx context.Context, recordID string) (entity.Record, io.ReadCloser, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return entity.Record{}, nil, err
+	}
+
+	return s.Storage.GetFileRecord(ctx, recordID)
+}
+
+// GetPublicKey gets public key of user by login, so vault key can be wrapped for this user.
+func (s *server) GetPublicKey(ctx context.Context, login string) ([]byte, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return nil, err
+	}
+
+	if login == "" {
+		return nil, controller.ErrFieldIsEmpty
+	}
+
+	return s.Storage.GetPublicKey(ctx, login)
+}
+
+// SetPublicKey sets public key of user, who was registered before keys.
+func (s *server) SetPublicKey(ctx context.Context, publicKey []byte) error {
+	if _, err := s.userValidate(ctx); err != nil {
+		return err
+	}
+
+	if len(publicKey) == 0 {
+		return controller.ErrFieldIsEmpty
+	}
+	if len(publicKey) != pkg.PublicKeySize {
+		return controller.ErrBadPublicKey
+	}
+
+	return s.Storage.SetPublicKey(ctx, publicKey)
+}
+
+// CreateVault creates shared vault, user becomes its owner.
+func (s *server) CreateVault(ctx context.Context, vault entity.Vault) (string, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return "", err
+	}
+
+	if vault.Name == "" || len(vault.WrappedKey) == 0 {
+		return "", controller.ErrFieldIsEmpty
+	}
+
+	return s.Storage.CreateVault(ctx, vault)
+}
+
+// GetVaults gets vaults, which user is member of.
+func (s *server) GetVaults(ctx context.Context) ([]entity.Vault, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.Storage.GetVaults(ctx)
+}
+
+// GetVaultMembers gets members of vault.
+func (s *server) GetVaultMembers(ctx context.Context, vaultID string) ([]entity.VaultMember, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.Storage.GetVaultMembers(ctx, vaultID)
+}
+
+// InviteMember adds user to vault with read or read-write role. Vault key must be wrapped for this user.
+func (s *server) InviteMember(ctx context.Context, member entity.VaultMember) error {
+	if _, err := s.userValidate(ctx); err != nil {
+		return err
+	}
+
+	if member.Login == "" || len(member.WrappedKey) == 0 {
+		return controller.ErrFieldIsEmpty
+	}
+
+	if member.Role != entity.RoleRead && member.Role != entity.RoleReadWrite {
+		return controller.ErrBadRole
+	}
+
+	return s.Storage.AddVaultMember(ctx, member)
+}
+
+// RevokeMember removes user from vault and replaces vault key: key must be wrapped for every member,
+// who stays, and every record of vault must be encrypted by it.
+func (s *server) RevokeMember(ctx context.Context, rotation entity.VaultRotation) error {
+	if _, err := s.userValidate(ctx); err != nil {
+		return err
+	}
+
+	if rotation.VaultID == "" || rotation.Revoked == "" || len(rotation.Members) == 0 {
+		return controller.ErrFieldIsEmpty
+	}
+
+	for _, member := range rotation.Members {
+		if member.Login == "" || len(member.WrappedKey) == 0 {
+			return controller.ErrFieldIsEmpty
+		}
+	}
+
+	return s.Storage.RevokeVaultMember(ctx, rotation)
+}
+
+// GetVaultRecords gets all records of vault from storage.
+func (s *server) GetVaultRecords(ctx context.Context, vaultID string) ([]entity.Record, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return nil, err
+	}
+
+	return s.Storage.GetVaultRecords(ctx, vaultID)
+}
+
+// GetVaultRecord gets record of vault from storage.
+func (s *server) GetVaultRecord(ctx context.Context, vaultID, recordID string) (entity.Record, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return entity.Record{}, err
+	}
+
+	return s.Storage.GetVaultRecord(ctx, vaultID, recordID)
+}
+
+// CreateVaultRecord adds record to vault in storage.
+func (s *server) CreateVaultRecord(ctx context.Context, record entity.Record) (string, error) {
+	if _, err := s.userValidate(ctx); err != nil {
+		return "", err
+	}
+
+	return s.Storage.CreateVaultRecord(ctx, record)
+}
 
-		return userID, err
+// UpdateVaultRecord updates record of vault in storage.
+func (s *server) UpdateVaultRecord(ctx context.Context, record entity.Record) error {
+	if _, err := s.userValidate(ctx); err != nil {
+		return err
 	}
-	return userIDValid, nil
+
+	return s.Storage.UpdateVaultRecord(ctx, record)
+}
+
+// DeleteVaultRecord deletes record of vault from storage.
+func (s *server) DeleteVaultRecord(ctx context.Context, vaultID, recordID string) error {
+	if _, err := s.userValidate(ctx); err != nil {
+		return err
+	}
+
+	return s.Storage.DeleteVaultRecord(ctx, vaultID, recordID)
+}
+
+// userValidate gets ID of user, whose request was authenticated by server connection.
+func (s *server) userValidate(ctx context.Context) (entity.UserID, error) {
+	identity, ok := entity.IdentityFromContext(ctx)
+	if !ok {
+		return "", storage.ErrUnauthenticated
+	}
+
+	return identity.UserID, nil
 }
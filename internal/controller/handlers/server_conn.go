@@ -8,27 +8,40 @@ import (
 	"github.com/bbt-t/lets-go-keep/internal/controller"
 	"github.com/bbt-t/lets-go-keep/internal/entity"
 	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
 	pb "github.com/bbt-t/lets-go-keep/protocols/grpc"
 
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Messages of invalid argument statuses of register, by which client tells rejected argument.
+const (
+	statusBadKDFParams = "Bad key derivation parameters."
+	statusBadPublicKey = "Bad public key."
 )
 
 // ServerConn keeps server endpoints alive.
 type ServerConn struct {
 	pb.UnimplementedGophkeeperServer
-	Handlers ServerHandlers
-	server   *grpc.Server
+	Handlers      ServerHandlers
+	Authenticator Authenticator
+	creds         credentials.TransportCredentials
+	server        *grpc.Server
 }
 
-// NewServerConn returns new server connection.
-func NewServerConn(h ServerHandlers) *ServerConn {
+// NewServerConn returns new server connection, which uses transport credentials (TLS or insecure).
+// Session token of every call, except register, login and session refresh, is validated by authenticator.
+func NewServerConn(h ServerHandlers, a Authenticator, creds credentials.TransportCredentials) *ServerConn {
 	return &ServerConn{
-		Handlers: h,
+		Handlers:      h,
+		Authenticator: a,
+		creds:         creds,
 	}
 }
 
@@ -39,7 +52,11 @@ func (s *ServerConn) Run(_ context.Context, runAddress string) {
 		log.Fatal(err)
 	}
 
-	grpcServ := grpc.NewServer()
+	grpcServ := grpc.NewServer(
+		grpc.Creds(s.creds),
+		grpc.UnaryInterceptor(unaryAuthInterceptor(s.Authenticator)),
+		grpc.StreamInterceptor(streamAuthInterceptor(s.Authenticator)),
+	)
 	pb.RegisterGophkeeperServer(grpcServ, s)
 
 	go func() {
@@ -59,9 +76,11 @@ func (s *ServerConn) Stop() {
 
 // Register process register endpoint.
 func (s *ServerConn) Register(_ context.Context, credentials *pb.UserCredentials) (*pb.Session, error) {
-	token, err := s.Handlers.CreateUser(entity.UserCredentials{
-		Login:    credentials.Login,
-		Password: credentials.Password,
+	session, err := s.Handlers.CreateUser(entity.UserCredentials{
+		Login:     credentials.Login,
+		Password:  credentials.Password,
+		KDF:       kdfParamsFromProto(credentials.Kdf),
+		PublicKey: credentials.PublicKey,
 	})
 
 	if errors.Is(err, controller.ErrFieldIsEmpty) {
@@ -70,6 +89,18 @@ func (s *ServerConn) Register(_ context.Context, credentials *pb.UserCredentials
 		return nil, status.Errorf(codes.InvalidArgument, "Login or password is empty.")
 	}
 
+	if errors.Is(err, pkg.ErrBadKDFParams) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.InvalidArgument, statusBadKDFParams)
+	}
+
+	if errors.Is(err, controller.ErrBadPublicKey) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.InvalidArgument, statusBadPublicKey)
+	}
+
 	if errors.Is(err, storage.ErrLoginExists) {
 		log.Infoln(err)
 
@@ -82,12 +113,12 @@ func (s *ServerConn) Register(_ context.Context, credentials *pb.UserCredentials
 		return nil, status.Errorf(codes.Internal, "Internal server error.")
 	}
 
-	return &pb.Session{SessionToken: string(token)}, nil
+	return sessionToProto(session), nil
 }
 
 // Login process login endpoint.
 func (s *ServerConn) Login(_ context.Context, credentials *pb.UserCredentials) (*pb.Session, error) {
-	token, err := s.Handlers.LoginUser(entity.UserCredentials{
+	session, err := s.Handlers.LoginUser(entity.UserCredentials{
 		Login:    credentials.Login,
 		Password: credentials.Password,
 	})
@@ -110,19 +141,11 @@ func (s *ServerConn) Login(_ context.Context, credentials *pb.UserCredentials) (
 		return nil, status.Errorf(codes.Internal, "Internal server error.")
 	}
 
-	return &pb.Session{SessionToken: string(token)}, nil
+	return sessionToProto(session), nil
 }
 
 // GetRecordsInfo process get all records endpoint.
 func (s *ServerConn) GetRecordsInfo(ctx context.Context, _ *emptypb.Empty) (*pb.RecordsList, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok || len(md.Get("authToken")) == 0 {
-		return nil, status.Errorf(codes.Unauthenticated, "Didn't send metadata for authentication.")
-	}
-
-	token := entity.AuthToken(md.Get("authToken")[0])
-	ctx = context.WithValue(ctx, "authToken", token)
-
 	records, err := s.Handlers.GetRecordsInfo(ctx)
 
 	if errors.Is(err, storage.ErrUnauthenticated) {
@@ -141,25 +164,48 @@ func (s *ServerConn) GetRecordsInfo(ctx context.Context, _ *emptypb.Empty) (*pb.
 
 	for _, record := range records {
 		recordsList = append(recordsList, &pb.Record{
-			Id:       record.ID,
-			Metadata: record.Metadata,
-			Type:     pb.MessageType(record.Type),
+			Id:         record.ID,
+			Metadata:   record.Metadata,
+			Type:       pb.MessageType(record.Type),
+			Revision:   record.Revision,
+			UpdatedAt:  timestampToProto(record.UpdatedAt),
+			Name:       record.Name,
+			Tags:       record.Tags,
+			Folder:     record.Folder,
+			BlindIndex: record.BlindIndex,
 		})
 	}
 
 	return &pb.RecordsList{Records: recordsList}, nil
 }
 
-// GetRecord process get record endpoint.
-func (s *ServerConn) GetRecord(ctx context.Context, recordID *pb.RecordID) (*pb.Record, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok || len(md.Get("authToken")) == 0 {
-		return nil, status.Errorf(codes.Unauthenticated, "Didn't send metadata for authentication.")
+// ListRecords process list records endpoint.
+func (s *ServerConn) ListRecords(ctx context.Context, query *pb.ListRecordsRequest) (*pb.RecordsPage, error) {
+	page, err := s.Handlers.ListRecords(ctx, recordsQueryFromProto(query))
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
 	}
 
-	token := entity.AuthToken(md.Get("authToken")[0])
-	ctx = context.WithValue(ctx, "authToken", token)
+	if errors.Is(err, entity.ErrBadQuery) {
+		log.Infoln(err)
 
+		return nil, status.Errorf(codes.InvalidArgument, "Unknown sorting of records.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "list records fault", err)
+
+		return nil, status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return recordsPageToProto(page), nil
+}
+
+// GetRecord process get record endpoint.
+func (s *ServerConn) GetRecord(ctx context.Context, recordID *pb.RecordID) (*pb.Record, error) {
 	record, err := s.Handlers.GetRecord(ctx, recordID.Id)
 
 	if errors.Is(err, storage.ErrUnauthenticated) {
@@ -180,29 +226,39 @@ func (s *ServerConn) GetRecord(ctx context.Context, recordID *pb.RecordID) (*pb.
 		return nil, status.Errorf(codes.Internal, "Internal server error.")
 	}
 
-	return &pb.Record{
-		Id:         record.ID,
-		Type:       pb.MessageType(record.Type),
-		Metadata:   record.Metadata,
-		StoredData: record.Data,
-	}, nil
+	return recordToProto(record), nil
 }
 
 // CreateRecord process create record endpoint.
-func (s *ServerConn) CreateRecord(ctx context.Context, record *pb.Record) (*emptypb.Empty, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok || len(md.Get("authToken")) == 0 {
-		return nil, status.Errorf(codes.Unauthenticated, "Didn't send metadata for authentication.")
+func (s *ServerConn) CreateRecord(ctx context.Context, record *pb.Record) (*pb.RecordID, error) {
+	id, err := s.Handlers.CreateRecord(ctx, entity.Record{
+		Metadata:   record.Metadata,
+		Type:       entity.RecordType(record.Type),
+		Data:       record.StoredData,
+		Name:       record.Name,
+		Tags:       record.Tags,
+		Folder:     record.Folder,
+		BlindIndex: record.BlindIndex,
+	})
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return &pb.RecordID{}, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
 	}
 
-	token := entity.AuthToken(md.Get("authToken")[0])
-	ctx = context.WithValue(ctx, "authToken", token)
+	if err != nil {
+		log.Warnf("%s :: %v", "create record fault", err)
 
-	err := s.Handlers.CreateRecord(ctx, entity.Record{
-		Metadata: record.Metadata,
-		Type:     entity.RecordType(record.Type),
-		Data:     record.StoredData,
-	})
+		return &pb.RecordID{}, status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return &pb.RecordID{Id: id}, nil
+}
+
+// DeleteRecord process delete record endpoint.
+func (s *ServerConn) DeleteRecord(ctx context.Context, recordID *pb.RecordID) (*emptypb.Empty, error) {
+	err := s.Handlers.DeleteRecord(ctx, recordID.Id, recordID.Revision)
 
 	if errors.Is(err, storage.ErrUnauthenticated) {
 		log.Infoln(err)
@@ -210,8 +266,20 @@ func (s *ServerConn) CreateRecord(ctx context.Context, record *pb.Record) (*empt
 		return &emptypb.Empty{}, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
 	}
 
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.NotFound, "Not found record with such id.")
+	}
+
+	if errors.Is(err, storage.ErrConflict) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Aborted, "Record was changed on another device.")
+	}
+
 	if err != nil {
-		log.Warnf("%s :: %v", "create record fault", err)
+		log.Warnf("%s :: %v", "delete record fault", err)
 
 		return &emptypb.Empty{}, status.Errorf(codes.Internal, "Internal server error.")
 	}
@@ -219,35 +287,312 @@ func (s *ServerConn) CreateRecord(ctx context.Context, record *pb.Record) (*empt
 	return &emptypb.Empty{}, nil
 }
 
-// DeleteRecord process delete record endpoint.
-func (s *ServerConn) DeleteRecord(ctx context.Context, recordID *pb.RecordID) (*emptypb.Empty, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok || len(md.Get("authToken")) == 0 {
-		return nil, status.Errorf(codes.Unauthenticated, "Didn't send metadata for authentication.")
+// UpdateRecord process update record endpoint.
+func (s *ServerConn) UpdateRecord(ctx context.Context, record *pb.Record) (*emptypb.Empty, error) {
+	err := s.Handlers.UpdateRecord(ctx, entity.Record{
+		ID:         record.Id,
+		Metadata:   record.Metadata,
+		Type:       entity.RecordType(record.Type),
+		Data:       record.StoredData,
+		Revision:   record.Revision,
+		Name:       record.Name,
+		Tags:       record.Tags,
+		Folder:     record.Folder,
+		BlindIndex: record.BlindIndex,
+	})
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
 	}
 
-	token := entity.AuthToken(md.Get("authToken")[0])
-	ctx = context.WithValue(ctx, "authToken", token)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.NotFound, "Not found record with such id and type.")
+	}
 
-	err := s.Handlers.DeleteRecord(ctx, recordID.Id)
+	if errors.Is(err, storage.ErrNotSupported) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.FailedPrecondition, "Record of this type can't be updated.")
+	}
+
+	if errors.Is(err, storage.ErrConflict) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Aborted, "Record was changed on another device.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "update record fault", err)
+
+		return nil, status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetRecordVersions process get record versions endpoint.
+func (s *ServerConn) GetRecordVersions(ctx context.Context, recordID *pb.RecordID) (*pb.RecordVersionsList, error) {
+	versions, err := s.Handlers.GetRecordVersions(ctx, recordID.Id)
 
 	if errors.Is(err, storage.ErrUnauthenticated) {
 		log.Infoln(err)
 
-		return &emptypb.Empty{}, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
+		return nil, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "get record versions fault", err)
+
+		return nil, status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	versionsList := make([]*pb.RecordVersion, 0, len(versions))
+
+	for _, version := range versions {
+		versionsList = append(versionsList, &pb.RecordVersion{
+			RecordId:   version.RecordID,
+			Version:    version.Version,
+			Metadata:   version.Metadata,
+			ReplacedAt: timestamppb.New(version.ReplacedAt),
+		})
+	}
+
+	return &pb.RecordVersionsList{Versions: versionsList}, nil
+}
+
+// RestoreRecordVersion process restore record version endpoint.
+func (s *ServerConn) RestoreRecordVersion(ctx context.Context, version *pb.RecordVersion) (*emptypb.Empty, error) {
+	err := s.Handlers.RestoreRecordVersion(ctx, version.RecordId, version.Version, version.Revision)
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
 	}
 
 	if errors.Is(err, storage.ErrNotFound) {
 		log.Infoln(err)
 
-		return nil, status.Errorf(codes.NotFound, "Not found record with such id.")
+		return nil, status.Errorf(codes.NotFound, "Not found record version.")
+	}
+
+	if errors.Is(err, storage.ErrConflict) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Aborted, "Record was changed on another device.")
 	}
 
 	if err != nil {
-		log.Warnf("%s :: %v", "delete record fault", err)
+		log.Warnf("%s :: %v", "restore record version fault", err)
 
-		return &emptypb.Empty{}, status.Errorf(codes.Internal, "Internal server error.")
+		return nil, status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// Sync process sync endpoint. Returns records changed and deleted after revision, which client knows.
+func (s *ServerConn) Sync(ctx context.Context, request *pb.SyncRequest) (*pb.RecordChanges, error) {
+	changes, err := s.Handlers.Sync(ctx, request.SinceRevision)
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "sync records fault", err)
+
+		return nil, status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return recordChangesToProto(changes), nil
+}
+
+// RefreshSession process refresh session endpoint.
+func (s *ServerConn) RefreshSession(_ context.Context, refreshToken *pb.RefreshToken) (*pb.Session, error) {
+	session, err := s.Handlers.RefreshSession(entity.RefreshToken(refreshToken.RefreshToken))
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Unauthenticated, "Session is expired or revoked.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "refresh session fault", err)
+
+		return nil, status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return sessionToProto(session), nil
+}
+
+// Logout process logout endpoint.
+func (s *ServerConn) Logout(_ context.Context, refreshToken *pb.RefreshToken) (*emptypb.Empty, error) {
+	err := s.Handlers.Logout(entity.RefreshToken(refreshToken.RefreshToken))
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Unauthenticated, "Session is expired or revoked.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "logout fault", err)
+
+		return nil, status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RevokeSessions process revoke all sessions endpoint.
+func (s *ServerConn) RevokeSessions(ctx context.Context, _ *emptypb.Empty) (*emptypb.Empty, error) {
+	err := s.Handlers.RevokeSessions(ctx)
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "revoke sessions fault", err)
+
+		return nil, status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// UpgradeKDF process upgrade key derivation parameters endpoint.
+func (s *ServerConn) UpgradeKDF(ctx context.Context, params *pb.KDFParams) (*emptypb.Empty, error) {
+	err := s.Handlers.UpgradeKDF(ctx, kdfParamsFromProto(params))
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Unauthenticated, "Bad authentication token.")
+	}
+
+	if errors.Is(err, pkg.ErrBadKDFParams) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.InvalidArgument, statusBadKDFParams)
+	}
+
+	if errors.Is(err, storage.ErrConflict) {
+		log.Infoln(err)
+
+		return nil, status.Errorf(codes.Aborted, "Key derivation parameters were already changed.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "upgrade kdf params fault", err)
+
+		return nil, status.Errorf(codes.Internal, "Internal server error.")
 	}
 
 	return &emptypb.Empty{}, nil
 }
+
+// UploadFile process upload file endpoint. First message must contain record info, next ones - file chunks.
+func (s *ServerConn) UploadFile(stream pb.Gophkeeper_UploadFileServer) error {
+	first, err := stream.Recv()
+	if err != nil || first.Info == nil {
+		log.Infoln(err)
+
+		return status.Errorf(codes.InvalidArgument, "Didn't send file record info.")
+	}
+
+	reader := newChunkReader(first.ChunkData, func() ([]byte, error) {
+		chunk, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		return chunk.ChunkData, nil
+	})
+
+	recordID, err := s.Handlers.UploadFile(stream.Context(), entity.Record{
+		Metadata:   first.Info.Metadata,
+		Type:       entity.TypeFile,
+		Name:       first.Info.Name,
+		Tags:       first.Info.Tags,
+		Folder:     first.Info.Folder,
+		BlindIndex: first.Info.BlindIndex,
+	}, reader)
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return status.Errorf(codes.Unauthenticated, "Bad authentication token.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "upload file fault", err)
+
+		return status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return stream.SendAndClose(&pb.RecordID{Id: recordID})
+}
+
+// DownloadFile process download file endpoint. First message contains record info, next ones - file chunks.
+func (s *ServerConn) DownloadFile(recordID *pb.RecordID, stream pb.Gophkeeper_DownloadFileServer) error {
+	record, file, err := s.Handlers.DownloadFile(stream.Context(), recordID.Id)
+
+	if errors.Is(err, storage.ErrUnauthenticated) {
+		log.Infoln(err)
+
+		return status.Errorf(codes.Unauthenticated, "Bad authentication token.")
+	}
+
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Infoln(err)
+
+		return status.Errorf(codes.NotFound, "Not found record with such id.")
+	}
+
+	if err != nil {
+		log.Warnf("%s :: %v", "download file fault", err)
+
+		return status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	defer file.Close()
+
+	if err = stream.Send(&pb.FileChunk{
+		Info: &pb.Record{
+			Id:         record.ID,
+			Type:       pb.MessageType(record.Type),
+			Metadata:   record.Metadata,
+			Name:       record.Name,
+			Tags:       record.Tags,
+			Folder:     record.Folder,
+			BlindIndex: record.BlindIndex,
+		},
+	}); err != nil {
+		log.Infoln(err)
+
+		return err
+	}
+
+	writer := newChunkWriter(func(chunk []byte) error {
+		return stream.Send(&pb.FileChunk{ChunkData: chunk})
+	})
+
+	if _, err = copyChunks(writer, file); err != nil {
+		log.Warnf("%s :: %v", "send file chunks fault", err)
+
+		return status.Errorf(codes.Internal, "Internal server error.")
+	}
+
+	return nil
+}
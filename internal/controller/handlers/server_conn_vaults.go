@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	pb "github.com/bbt-t/lets-go-keep/protocols/grpc"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// GetPublicKey process get public key endpoint.
+func (s *ServerConn) GetPublicKey(ctx context.Context, login *pb.UserLogin) (*pb.PublicKey, error) {
+	publicKey, err := s.Handlers.GetPublicKey(ctx, login.Login)
+	if err != nil {
+		return nil, vaultStatus(err, "get public key fault")
+	}
+
+	return &pb.PublicKey{PublicKey: publicKey}, nil
+}
+
+// SetPublicKey process set public key endpoint.
+func (s *ServerConn) SetPublicKey(ctx context.Context, publicKey *pb.PublicKey) (*emptypb.Empty, error) {
+	if err := s.Handlers.SetPublicKey(ctx, publicKey.PublicKey); err != nil {
+		return nil, vaultStatus(err, "set public key fault")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// CreateVault process create vault endpoint.
+func (s *ServerConn) CreateVault(ctx context.Context, vault *pb.Vault) (*pb.Vault, error) {
+	vaultID, err := s.Handlers.CreateVault(ctx, vaultFromProto(vault))
+	if err != nil {
+		return nil, vaultStatus(err, "create vault fault")
+	}
+
+	return &pb.Vault{Id: vaultID}, nil
+}
+
+// GetVaults process get vaults endpoint.
+func (s *ServerConn) GetVaults(ctx context.Context, _ *emptypb.Empty) (*pb.VaultsList, error) {
+	vaults, err := s.Handlers.GetVaults(ctx)
+	if err != nil {
+		return nil, vaultStatus(err, "get vaults fault")
+	}
+
+	message := &pb.VaultsList{Vaults: make([]*pb.Vault, 0, len(vaults))}
+	for _, vault := range vaults {
+		message.Vaults = append(message.Vaults, vaultToProto(vault))
+	}
+
+	return message, nil
+}
+
+// GetVaultMembers process get vault members endpoint.
+func (s *ServerConn) GetVaultMembers(ctx context.Context, vault *pb.Vault) (*pb.VaultMembersList, error) {
+	members, err := s.Handlers.GetVaultMembers(ctx, vault.Id)
+	if err != nil {
+		return nil, vaultStatus(err, "get vault members fault")
+	}
+
+	message := &pb.VaultMembersList{Members: make([]*pb.VaultMember, 0, len(members))}
+	for _, member := range members {
+		message.Members = append(message.Members, vaultMemberToProto(member))
+	}
+
+	return message, nil
+}
+
+// InviteMember process invite member endpoint.
+func (s *ServerConn) InviteMember(ctx context.Context, member *pb.VaultMember) (*emptypb.Empty, error) {
+	if err := s.Handlers.InviteMember(ctx, vaultMemberFromProto(member)); err != nil {
+		return nil, vaultStatus(err, "invite member fault")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// RevokeMember process revoke member endpoint.
+func (s *ServerConn) RevokeMember(ctx context.Context, rotation *pb.VaultRotation) (*emptypb.Empty, error) {
+	if err := s.Handlers.RevokeMember(ctx, vaultRotationFromProto(rotation)); err != nil {
+		return nil, vaultStatus(err, "revoke member fault")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// GetVaultRecords process get vault records endpoint.
+func (s *ServerConn) GetVaultRecords(ctx context.Context, vault *pb.Vault) (*pb.RecordsList, error) {
+	records, err := s.Handlers.GetVaultRecords(ctx, vault.Id)
+	if err != nil {
+		return nil, vaultStatus(err, "get vault records fault")
+	}
+
+	message := &pb.RecordsList{Records: make([]*pb.Record, 0, len(records))}
+	for _, record := range records {
+		message.Records = append(message.Records, recordToProto(record))
+	}
+
+	return message, nil
+}
+
+// GetVaultRecord process get vault record endpoint.
+func (s *ServerConn) GetVaultRecord(ctx context.Context, recordID *pb.VaultRecordID) (*pb.Record, error) {
+	record, err := s.Handlers.GetVaultRecord(ctx, recordID.VaultId, recordID.RecordId)
+	if err != nil {
+		return nil, vaultStatus(err, "get vault record fault")
+	}
+
+	return recordToProto(record), nil
+}
+
+// CreateVaultRecord process create vault record endpoint.
+func (s *ServerConn) CreateVaultRecord(ctx context.Context, record *pb.Record) (*pb.RecordID, error) {
+	recordID, err := s.Handlers.CreateVaultRecord(ctx, recordFromProto(record))
+	if err != nil {
+		return nil, vaultStatus(err, "create vault record fault")
+	}
+
+	return &pb.RecordID{Id: recordID}, nil
+}
+
+// UpdateVaultRecord process update vault record endpoint.
+func (s *ServerConn) UpdateVaultRecord(ctx context.Context, record *pb.Record) (*emptypb.Empty, error) {
+	if err := s.Handlers.UpdateVaultRecord(ctx, recordFromProto(record)); err != nil {
+		return nil, vaultStatus(err, "update vault record fault")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteVaultRecord process delete vault record endpoint.
+func (s *ServerConn) DeleteVaultRecord(ctx context.Context, recordID *pb.VaultRecordID) (*emptypb.Empty, error) {
+	if err := s.Handlers.DeleteVaultRecord(ctx, recordID.VaultId, recordID.RecordId); err != nil {
+		return nil, vaultStatus(err, "delete vault record fault")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// vaultStatus converts error of vault endpoint to gRPC status, unknown errors are logged as fault.
+func vaultStatus(err error, fault string) error {
+	switch {
+	case errors.Is(err, storage.ErrUnauthenticated):
+		log.Infoln(err)
+
+		return status.Errorf(codes.Unauthenticated, "Bad authentication token.")
+	case errors.Is(err, storage.ErrNotFound):
+		log.Infoln(err)
+
+		return status.Errorf(codes.NotFound, "Not found vault, record or user.")
+	case errors.Is(err, storage.ErrForbidden):
+		log.Infoln(err)
+
+		return status.Errorf(codes.PermissionDenied, "Not enough rights for this vault.")
+	case errors.Is(err, storage.ErrNoPublicKey):
+		log.Infoln(err)
+
+		return status.Errorf(codes.FailedPrecondition, "User has no public key.")
+	case errors.Is(err, storage.ErrNotSupported):
+		log.Infoln(err)
+
+		return status.Errorf(codes.FailedPrecondition, "Record of this type can't be shared.")
+	case errors.Is(err, storage.ErrConflict):
+		log.Infoln(err)
+
+		return status.Errorf(codes.Aborted, "Vault or key was changed, try again.")
+	case errors.Is(err, controller.ErrFieldIsEmpty), errors.Is(err, controller.ErrBadRole),
+		errors.Is(err, controller.ErrBadPublicKey):
+		log.Infoln(err)
+
+		return status.Errorf(codes.InvalidArgument, "%s.", err)
+	}
+
+	log.Warnf("%s :: %v", fault, err)
+
+	return status.Errorf(codes.Internal, "Internal server error.")
+}
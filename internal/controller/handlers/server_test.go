@@ -1,29 +1,80 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/controller"
 	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
 	"github.com/bbt-t/lets-go-keep/internal/entity"
 	"github.com/bbt-t/lets-go-keep/internal/storage"
 	storageMocks "github.com/bbt-t/lets-go-keep/internal/storage/mocks"
+	"github.com/bbt-t/lets-go-keep/pkg"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// testHasher is cheap password hasher for tests.
+var testHasher = pkg.NewArgon2idHasher(1, 1024, 1)
+
+// hashOf returns matcher of password hash made for password.
+func hashOf(password string) interface{} {
+	return mock.MatchedBy(func(passwordHash string) bool {
+		valid, err := testHasher.Verify(passwordHash, password)
+
+		return err == nil && valid && !testHasher.NeedsRehash(passwordHash)
+	})
+}
+
+// expectSession expects creating of session for user.
+func expectSession(store *storageMocks.Storager, auth *mocks.Authenticator, userID entity.UserID) {
+	auth.On("CreateToken", userID).Return(entity.AuthToken("token"), time.Now().Add(time.Hour), nil).Once()
+	auth.On("CreateRefreshToken").Return(entity.RefreshToken("refresh"), time.Now().Add(24*time.Hour), nil).Once()
+	store.On("CreateSession", userID, refreshTokenHash("refresh"), mock.AnythingOfType("time.Time")).Return(nil).Once()
+}
+
 func TestNewServerHandlers(t *testing.T) {
 	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
-	handlers := NewServerHandlers(store, auth)
+	handlers := NewServerHandlers(store, auth, testHasher)
 
 	assert.NotEmpty(t, handlers)
 }
 
 func TestServer_CreateUser(t *testing.T) {
 	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
-	handlers := NewServerHandlers(store, auth)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	kdf := entity.KDFParams{
+		Salt:    []byte("0123456789abcdef"),
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+
+	// createUser expects new user and returns him on login.
+	createUser := func(kdf entity.KDFParams) {
+		var passwordHash string
+
+		store.On("CreateUser", mock.MatchedBy(func(credentials entity.UserCredentials) bool {
+			valid, err := testHasher.Verify(credentials.Password, "password")
+
+			return credentials.Login == "admin" && err == nil && valid &&
+				assert.ObjectsAreEqual(kdf, credentials.KDF)
+		})).Run(func(args mock.Arguments) {
+			passwordHash = args.Get(0).(entity.UserCredentials).Password
+		}).Return(nil).Once()
+		store.On("GetUser", "admin").Return(func(string) entity.User {
+			return entity.User{ID: "userID", Login: "admin", PasswordHash: passwordHash}
+		}, nil).Once()
+		store.On("GetKDFParams", entity.UserID("userID")).Return(kdf, nil).Once()
+		expectSession(store, auth, "userID")
+	}
 
 	tc := []struct {
 		name string
@@ -32,24 +83,70 @@ func TestServer_CreateUser(t *testing.T) {
 		want error
 	}{
 		{
-			"Create user with good credentials",
+			"Create user without KDF params",
+			func() {},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "password",
+			},
+			pkg.ErrBadKDFParams,
+		},
+		{
+			"Create legacy user",
+			func() {},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "password",
+				KDF:      entity.KDFParams{Legacy: true},
+			},
+			pkg.ErrBadKDFParams,
+		},
+		{
+			"Create user with KDF params",
 			func() {
-				store.On("CreateUser", entity.UserCredentials{
-					Login:    "admin",
-					Password: "749f09bade8aca755660eeb17792da880218d4fbdc4e25fbec279d7fe9f65d70",
-				}).Return(nil).Once()
-				store.On("LoginUser", entity.UserCredentials{
-					Login:    "admin",
-					Password: "749f09bade8aca755660eeb17792da880218d4fbdc4e25fbec279d7fe9f65d70",
-				}).Return(entity.UserID("userID"), nil).Once()
-				auth.On("CreateToken", entity.UserID("userID")).Return(entity.AuthToken("token"), nil).Once()
+				createUser(kdf)
 			},
 			entity.UserCredentials{
 				Login:    "admin",
 				Password: "password",
+				KDF:      kdf,
 			},
 			nil,
 		},
+		{
+			"Create user with existing login",
+			func() {
+				store.On("CreateUser", mock.AnythingOfType("entity.UserCredentials")).
+					Return(storage.ErrLoginExists).Once()
+			},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "password",
+				KDF:      kdf,
+			},
+			storage.ErrLoginExists,
+		},
+		{
+			"Create user with weak KDF params",
+			func() {},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "password",
+				KDF:      entity.KDFParams{Salt: kdf.Salt, Time: 1, Memory: 8, Threads: 1},
+			},
+			pkg.ErrBadKDFParams,
+		},
+		{
+			"Create user with bad public key",
+			func() {},
+			entity.UserCredentials{
+				Login:     "admin",
+				Password:  "password",
+				KDF:       kdf,
+				PublicKey: []byte("short"),
+			},
+			controller.ErrBadPublicKey,
+		},
 		{
 			"Create user with bad credentials",
 			func() {},
@@ -75,7 +172,34 @@ func TestServer_CreateUser(t *testing.T) {
 
 func TestServer_LoginUser(t *testing.T) {
 	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
-	handlers := NewServerHandlers(store, auth)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	currentHash, err := testHasher.Hash("password")
+	assert.NoError(t, err)
+
+	oldParamsHash, err := pkg.NewArgon2idHasher(1, 2048, 1).Hash("password")
+	assert.NoError(t, err)
+
+	bcryptHash, err := pkg.NewBcryptHasher(bcrypt.MinCost).Hash("password")
+	assert.NoError(t, err)
+
+	// sha256("admin" + "password"), made by PasswordHash.
+	legacyHash := "749f09bade8aca755660eeb17792da880218d4fbdc4e25fbec279d7fe9f65d70"
+
+	// getUser expects loading of user with password hash.
+	getUser := func(passwordHash string) {
+		store.On("GetUser", "admin").Return(entity.User{
+			ID:           "userID",
+			Login:        "admin",
+			PasswordHash: passwordHash,
+		}, nil).Once()
+	}
+
+	// loggedIn expects creating of session.
+	loggedIn := func() {
+		store.On("GetKDFParams", entity.UserID("userID")).Return(entity.KDFParams{}, nil).Once()
+		expectSession(store, auth, "userID")
+	}
 
 	tc := []struct {
 		name string
@@ -86,11 +210,21 @@ func TestServer_LoginUser(t *testing.T) {
 		{
 			"Login user with good credentials",
 			func() {
-				store.On("LoginUser", entity.UserCredentials{
-					Login:    "admin",
-					Password: "749f09bade8aca755660eeb17792da880218d4fbdc4e25fbec279d7fe9f65d70",
-				}).Return(entity.UserID("userID"), nil).Once()
-				auth.On("CreateToken", entity.UserID("userID")).Return(entity.AuthToken("token"), nil).Once()
+				getUser(currentHash)
+				loggedIn()
+			},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "password",
+			},
+			nil,
+		},
+		{
+			"Login user with legacy SHA-256 hash, which is rehashed",
+			func() {
+				getUser(legacyHash)
+				store.On("UpdatePasswordHash", entity.UserID("userID"), hashOf("password")).Return(nil).Once()
+				loggedIn()
 			},
 			entity.UserCredentials{
 				Login:    "admin",
@@ -98,6 +232,77 @@ func TestServer_LoginUser(t *testing.T) {
 			},
 			nil,
 		},
+		{
+			"Login user with hash of old parameters, which is rehashed",
+			func() {
+				getUser(oldParamsHash)
+				store.On("UpdatePasswordHash", entity.UserID("userID"), hashOf("password")).Return(nil).Once()
+				loggedIn()
+			},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "password",
+			},
+			nil,
+		},
+		{
+			"Login user with bcrypt hash, rehash fails, but user is logged",
+			func() {
+				getUser(bcryptHash)
+				store.On("UpdatePasswordHash", entity.UserID("userID"), hashOf("password")).
+					Return(storage.ErrUnknown).Once()
+				loggedIn()
+			},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "password",
+			},
+			nil,
+		},
+		{
+			"Login user with wrong password",
+			func() {
+				getUser(currentHash)
+			},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "wrong",
+			},
+			storage.ErrWrongCredentials,
+		},
+		{
+			"Login user with wrong password and legacy hash",
+			func() {
+				getUser(legacyHash)
+			},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "wrong",
+			},
+			storage.ErrWrongCredentials,
+		},
+		{
+			"Login user with malformed hash",
+			func() {
+				getUser("$argon2id$broken")
+			},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "password",
+			},
+			storage.ErrWrongCredentials,
+		},
+		{
+			"Login not existing user",
+			func() {
+				store.On("GetUser", "admin").Return(entity.User{}, storage.ErrWrongCredentials).Once()
+			},
+			entity.UserCredentials{
+				Login:    "admin",
+				Password: "password",
+			},
+			storage.ErrWrongCredentials,
+		},
 		{
 			"Login user with bad credentials",
 			func() {},
@@ -123,7 +328,7 @@ func TestServer_LoginUser(t *testing.T) {
 
 func TestServer_GetRecordsInfo(t *testing.T) {
 	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
-	handlers := NewServerHandlers(store, auth)
+	handlers := NewServerHandlers(store, auth, testHasher)
 
 	tc := []struct {
 		name  string
@@ -134,10 +339,9 @@ func TestServer_GetRecordsInfo(t *testing.T) {
 			"Get all records with valid context",
 			func() {
 				store.On("GetRecordsInfo", mock.AnythingOfType("*context.valueCtx")).Return([]entity.Record{}, nil).Once()
-				auth.On("ValidateToken", entity.AuthToken("token")).Return(entity.UserID("userID"), nil).Once()
 			},
 			func() {
-				ctx := context.WithValue(context.Background(), "authToken", entity.AuthToken("token"))
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
 				_, err := handlers.GetRecordsInfo(ctx)
 				assert.NoError(t, err)
 			},
@@ -164,9 +368,63 @@ func TestServer_GetRecordsInfo(t *testing.T) {
 
 }
 
+func TestServer_ListRecords(t *testing.T) {
+	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"List records with valid context, query is normalized",
+			func() {
+				store.On("ListRecords", mock.AnythingOfType("*context.valueCtx"), entity.RecordsQuery{
+					Tags:   []string{"work"},
+					Folder: "work/banks",
+					Limit:  entity.RecordsDefaultLimit,
+				}).Return(entity.RecordsPage{Total: 1}, nil).Once()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				page, err := handlers.ListRecords(ctx, entity.RecordsQuery{Tags: []string{" Work"}, Folder: "/work/banks/", Offset: -1})
+				assert.NoError(t, err)
+				assert.Equal(t, int32(1), page.Total)
+			},
+		},
+		{
+			"List records with not valid context",
+			func() {},
+			func() {
+				_, err := handlers.ListRecords(context.Background(), entity.RecordsQuery{})
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+		{
+			"List records with unknown sorting",
+			func() {},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				_, err := handlers.ListRecords(ctx, entity.RecordsQuery{Sort: 42})
+				assert.ErrorIs(t, err, entity.ErrBadQuery)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	}
+}
+
 func TestServer_GetRecord(t *testing.T) {
 	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
-	handlers := NewServerHandlers(store, auth)
+	handlers := NewServerHandlers(store, auth, testHasher)
 
 	tc := []struct {
 		name  string
@@ -177,10 +435,9 @@ func TestServer_GetRecord(t *testing.T) {
 			"Get record with valid context",
 			func() {
 				store.On("GetRecord", mock.AnythingOfType("*context.valueCtx"), "recordID").Return(entity.Record{}, nil).Once()
-				auth.On("ValidateToken", entity.AuthToken("token")).Return(entity.UserID("userID"), nil).Once()
 			},
 			func() {
-				ctx := context.WithValue(context.Background(), "authToken", entity.AuthToken("token"))
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
 				_, err := handlers.GetRecord(ctx, "recordID")
 				assert.NoError(t, err)
 			},
@@ -209,7 +466,7 @@ func TestServer_GetRecord(t *testing.T) {
 
 func TestServer_CreateRecord(t *testing.T) {
 	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
-	handlers := NewServerHandlers(store, auth)
+	handlers := NewServerHandlers(store, auth, testHasher)
 
 	tc := []struct {
 		name  string
@@ -219,13 +476,13 @@ func TestServer_CreateRecord(t *testing.T) {
 		{
 			"Create record with valid context",
 			func() {
-				store.On("CreateRecord", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("entity.Record")).Return("", nil).Once()
-				auth.On("ValidateToken", entity.AuthToken("token")).Return(entity.UserID("userID"), nil).Once()
+				store.On("CreateRecord", mock.AnythingOfType("*context.valueCtx"), mock.AnythingOfType("entity.Record")).Return("recordID", nil).Once()
 			},
 			func() {
-				ctx := context.WithValue(context.Background(), "authToken", entity.AuthToken("token"))
-				err := handlers.CreateRecord(ctx, entity.Record{})
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				recordID, err := handlers.CreateRecord(ctx, entity.Record{})
 				assert.NoError(t, err)
+				assert.Equal(t, "recordID", recordID)
 			},
 		},
 		{
@@ -252,7 +509,7 @@ func TestServer_CreateRecord(t *testing.T) {
 
 func TestServer_DeleteRecord(t *testing.T) {
 	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
-	handlers := NewServerHandlers(store, auth)
+	handlers := NewServerHandlers(store, auth, testHasher)
 
 	tc := []struct {
 		name  string
@@ -262,12 +519,11 @@ func TestServer_DeleteRecord(t *testing.T) {
 		{
 			"Delete record with valid context",
 			func() {
-				store.On("DeleteRecord", mock.AnythingOfType("*context.valueCtx"), "recordID").Return(nil).Once()
-				auth.On("ValidateToken", entity.AuthToken("token")).Return(entity.UserID("userID"), nil).Once()
+				store.On("DeleteRecord", mock.AnythingOfType("*context.valueCtx"), "recordID", int64(3)).Return(nil).Once()
 			},
 			func() {
-				ctx := context.WithValue(context.Background(), "authToken", entity.AuthToken("token"))
-				err := handlers.DeleteRecord(ctx, "recordID")
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				err := handlers.DeleteRecord(ctx, "recordID", 3)
 				assert.NoError(t, err)
 			},
 		},
@@ -276,7 +532,145 @@ func TestServer_DeleteRecord(t *testing.T) {
 			func() {},
 			func() {
 				ctx := context.Background()
-				err := handlers.DeleteRecord(ctx, "recordID")
+				err := handlers.DeleteRecord(ctx, "recordID", 3)
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	}
+
+}
+
+func TestServer_UploadFile(t *testing.T) {
+	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Upload file with valid context",
+			func() {
+				store.On(
+					"CreateFileRecord",
+					mock.AnythingOfType("*context.valueCtx"),
+					entity.Record{Type: entity.TypeFile},
+					mock.AnythingOfType("*strings.Reader"),
+				).Return("recordID", nil).Once()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				id, err := handlers.UploadFile(ctx, entity.Record{Type: entity.TypeFile}, strings.NewReader("file"))
+				assert.NoError(t, err)
+				assert.Equal(t, "recordID", id)
+			},
+		},
+		{
+			"Upload file with not valid context",
+			func() {},
+			func() {
+				ctx := context.Background()
+				_, err := handlers.UploadFile(ctx, entity.Record{Type: entity.TypeFile}, strings.NewReader("file"))
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	}
+}
+
+func TestServer_DownloadFile(t *testing.T) {
+	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Download file with valid context",
+			func() {
+				store.On("GetFileRecord", mock.AnythingOfType("*context.valueCtx"), "recordID").
+					Return(entity.Record{ID: "recordID"}, io.NopCloser(strings.NewReader("file")), nil).Once()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				record, file, err := handlers.DownloadFile(ctx, "recordID")
+				assert.NoError(t, err)
+				assert.Equal(t, entity.Record{ID: "recordID"}, record)
+
+				data, err := io.ReadAll(file)
+				assert.NoError(t, err)
+				assert.Equal(t, "file", string(data))
+			},
+		},
+		{
+			"Download file with not valid context",
+			func() {},
+			func() {
+				ctx := context.Background()
+				_, file, err := handlers.DownloadFile(ctx, "recordID")
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+				assert.Nil(t, file)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	}
+}
+
+func TestServer_UpdateRecord(t *testing.T) {
+	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	record := entity.Record{ID: "recordID", Type: entity.TypeText, Data: []byte("data")}
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Update record with valid context",
+			func() {
+				store.On("UpdateRecord", mock.AnythingOfType("*context.valueCtx"), record).Return(nil).Once()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				err := handlers.UpdateRecord(ctx, record)
+				assert.NoError(t, err)
+			},
+		},
+		{
+			"Update record with not valid context",
+			func() {},
+			func() {
+				err := handlers.UpdateRecord(context.Background(), record)
 				assert.Equal(t, storage.ErrUnauthenticated, err)
 			},
 		},
@@ -290,5 +684,413 @@ func TestServer_DeleteRecord(t *testing.T) {
 		store.AssertExpectations(t)
 		auth.AssertExpectations(t)
 	}
+}
 
+func TestServer_RecordVersions(t *testing.T) {
+	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Get record versions with valid context",
+			func() {
+				store.On("GetRecordVersions", mock.AnythingOfType("*context.valueCtx"), "recordID").
+					Return([]entity.RecordVersion{{RecordID: "recordID", Version: 1}}, nil).Once()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				versions, err := handlers.GetRecordVersions(ctx, "recordID")
+				assert.NoError(t, err)
+				assert.Len(t, versions, 1)
+			},
+		},
+		{
+			"Restore record version with valid context",
+			func() {
+				store.On("RestoreRecordVersion", mock.AnythingOfType("*context.valueCtx"), "recordID", int32(1), int64(4)).
+					Return(nil).Once()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				err := handlers.RestoreRecordVersion(ctx, "recordID", 1, 4)
+				assert.NoError(t, err)
+			},
+		},
+		{
+			"Record versions with not valid context",
+			func() {},
+			func() {
+				_, err := handlers.GetRecordVersions(context.Background(), "recordID")
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+
+				err = handlers.RestoreRecordVersion(context.Background(), "recordID", 1, 4)
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	}
+}
+
+func TestServer_Sync(t *testing.T) {
+	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Sync with valid context",
+			func() {
+				store.On("Sync", mock.AnythingOfType("*context.valueCtx"), int64(3)).
+					Return(entity.RecordChanges{Revision: 5}, nil).Once()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				changes, err := handlers.Sync(ctx, 3)
+				assert.NoError(t, err)
+				assert.Equal(t, int64(5), changes.Revision)
+			},
+		},
+		{
+			"Sync with not valid context",
+			func() {},
+			func() {
+				_, err := handlers.Sync(context.Background(), 3)
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	}
+}
+
+func TestServer_UpgradeKDF(t *testing.T) {
+	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+	params := entity.KDFParams{
+		Salt:    bytes.Repeat([]byte{0x01}, pkg.KDFSalt),
+		Time:    pkg.KDFTime,
+		Memory:  pkg.KDFMemory,
+		Threads: pkg.KDFThreads,
+	}
+	pending := params
+	pending.Legacy = true
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Start upgrade of legacy key",
+			func() {
+				store.On("SetKDFParams", ctx, pending).Return(nil).Once()
+			},
+			func() {
+				assert.NoError(t, handlers.UpgradeKDF(ctx, pending))
+			},
+		},
+		{
+			"Finish upgrade of legacy key",
+			func() {
+				store.On("FinishKDFUpgrade", ctx, params).Return(nil).Once()
+			},
+			func() {
+				assert.NoError(t, handlers.UpgradeKDF(ctx, params))
+			},
+		},
+		{
+			"Upgrade legacy key to weak params",
+			func() {},
+			func() {
+				assert.Equal(t, pkg.ErrBadKDFParams, handlers.UpgradeKDF(ctx, entity.KDFParams{Legacy: true}))
+			},
+		},
+		{
+			"Upgrade legacy key with not valid context",
+			func() {},
+			func() {
+				assert.Equal(t, storage.ErrUnauthenticated, handlers.UpgradeKDF(context.Background(), params))
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+		store.AssertExpectations(t)
+	}
+}
+
+func TestServer_Sessions(t *testing.T) {
+	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
+	handlers := NewServerHandlers(store, auth, testHasher)
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Refresh session",
+			func() {
+				auth.On("CreateRefreshToken").Return(entity.RefreshToken("new refresh"), expiresAt, nil).Once()
+				store.On("RotateSession", refreshTokenHash("refresh"), refreshTokenHash("new refresh"), expiresAt).
+					Return(entity.UserID("userID"), nil).Once()
+				auth.On("CreateToken", entity.UserID("userID")).Return(entity.AuthToken("new token"), expiresAt, nil).Once()
+			},
+			func() {
+				session, err := handlers.RefreshSession("refresh")
+				assert.NoError(t, err)
+				assert.Equal(t, entity.Session{
+					Token:        "new token",
+					RefreshToken: "new refresh",
+					ExpiresAt:    expiresAt,
+				}, session)
+			},
+		},
+		{
+			"Refresh expired or revoked session",
+			func() {
+				auth.On("CreateRefreshToken").Return(entity.RefreshToken("new refresh"), expiresAt, nil).Once()
+				store.On("RotateSession", refreshTokenHash("refresh"), refreshTokenHash("new refresh"), expiresAt).
+					Return(entity.UserID(""), storage.ErrUnauthenticated).Once()
+			},
+			func() {
+				session, err := handlers.RefreshSession("refresh")
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+				assert.Empty(t, session)
+			},
+		},
+		{
+			"Refresh session without refresh token",
+			func() {},
+			func() {
+				_, err := handlers.RefreshSession("")
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+		{
+			"Logout",
+			func() {
+				store.On("DeleteSession", refreshTokenHash("refresh")).Return(nil).Once()
+			},
+			func() {
+				assert.NoError(t, handlers.Logout("refresh"))
+			},
+		},
+		{
+			"Logout without refresh token",
+			func() {},
+			func() {
+				assert.Equal(t, storage.ErrUnauthenticated, handlers.Logout(""))
+			},
+		},
+		{
+			"Revoke all sessions",
+			func() {
+				store.On("DeleteSessions", entity.UserID("userID")).Return(nil).Once()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+				assert.NoError(t, handlers.RevokeSessions(ctx))
+			},
+		},
+		{
+			"Revoke all sessions with not valid context",
+			func() {},
+			func() {
+				assert.Equal(t, storage.ErrUnauthenticated, handlers.RevokeSessions(context.Background()))
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	}
+}
+
+func TestServer_Vaults(t *testing.T) {
+	store, auth := storageMocks.NewStorager(t), mocks.NewAuthenticator(t)
+	handlers := NewServerHandlers(store, auth, testHasher)
+	ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: "userID"})
+	rotation := entity.VaultRotation{
+		VaultID: "vaultID",
+		Revoked: "bob",
+		Members: []entity.VaultMember{{Login: "alice", WrappedKey: []byte("new")}},
+	}
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Create vault",
+			func() {
+				store.On("CreateVault", ctx, entity.Vault{Name: "team", WrappedKey: []byte("key")}).
+					Return("vaultID", nil).Once()
+			},
+			func() {
+				vaultID, err := handlers.CreateVault(ctx, entity.Vault{Name: "team", WrappedKey: []byte("key")})
+				assert.NoError(t, err)
+				assert.Equal(t, "vaultID", vaultID)
+			},
+		},
+		{
+			"Create vault without wrapped key",
+			func() {},
+			func() {
+				_, err := handlers.CreateVault(ctx, entity.Vault{Name: "team"})
+				assert.Equal(t, controller.ErrFieldIsEmpty, err)
+			},
+		},
+		{
+			"Get vaults with not valid context",
+			func() {},
+			func() {
+				_, err := handlers.GetVaults(context.Background())
+				assert.Equal(t, storage.ErrUnauthenticated, err)
+			},
+		},
+		{
+			"Get public key",
+			func() {
+				store.On("GetPublicKey", ctx, "bob").Return([]byte("public"), nil).Once()
+			},
+			func() {
+				publicKey, err := handlers.GetPublicKey(ctx, "bob")
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("public"), publicKey)
+			},
+		},
+		{
+			"Invite member",
+			func() {
+				store.On("AddVaultMember", ctx, entity.VaultMember{
+					VaultID: "vaultID", Login: "bob", Role: entity.RoleReadWrite, WrappedKey: []byte("key"),
+				}).Return(nil).Once()
+			},
+			func() {
+				err := handlers.InviteMember(ctx, entity.VaultMember{
+					VaultID: "vaultID", Login: "bob", Role: entity.RoleReadWrite, WrappedKey: []byte("key"),
+				})
+				assert.NoError(t, err)
+			},
+		},
+		{
+			"Invite member as owner",
+			func() {},
+			func() {
+				err := handlers.InviteMember(ctx, entity.VaultMember{
+					VaultID: "vaultID", Login: "bob", Role: entity.RoleOwner, WrappedKey: []byte("key"),
+				})
+				assert.Equal(t, controller.ErrBadRole, err)
+			},
+		},
+		{
+			"Revoke member without rights",
+			func() {
+				store.On("RevokeVaultMember", ctx, rotation).Return(storage.ErrForbidden).Once()
+			},
+			func() {
+				assert.Equal(t, storage.ErrForbidden, handlers.RevokeMember(ctx, rotation))
+			},
+		},
+		{
+			"Revoke member without new key of other members",
+			func() {},
+			func() {
+				withoutKey := rotation
+				withoutKey.Members = []entity.VaultMember{{Login: "alice"}}
+				assert.Equal(t, controller.ErrFieldIsEmpty, handlers.RevokeMember(ctx, withoutKey))
+			},
+		},
+		{
+			"Set public key of user",
+			func() {
+				store.On("SetPublicKey", ctx, bytes.Repeat([]byte{0x0a}, pkg.PublicKeySize)).Return(nil).Once()
+			},
+			func() {
+				assert.NoError(t, handlers.SetPublicKey(ctx, bytes.Repeat([]byte{0x0a}, pkg.PublicKeySize)))
+				assert.Equal(t, controller.ErrBadPublicKey, handlers.SetPublicKey(ctx, []byte("short")))
+			},
+		},
+		{
+			"Get vault records",
+			func() {
+				store.On("GetVaultRecords", ctx, "vaultID").
+					Return([]entity.Record{{ID: "recordID", VaultID: "vaultID"}}, nil).Once()
+				store.On("GetVaultRecord", ctx, "vaultID", "recordID").
+					Return(entity.Record{ID: "recordID", VaultID: "vaultID", Data: []byte("data")}, nil).Once()
+			},
+			func() {
+				records, err := handlers.GetVaultRecords(ctx, "vaultID")
+				assert.NoError(t, err)
+				assert.Len(t, records, 1)
+
+				record, err := handlers.GetVaultRecord(ctx, "vaultID", "recordID")
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("data"), record.Data)
+			},
+		},
+		{
+			"Change vault records",
+			func() {
+				record := entity.Record{ID: "recordID", VaultID: "vaultID", Data: []byte("data")}
+				store.On("CreateVaultRecord", ctx, record).Return("recordID", nil).Once()
+				store.On("UpdateVaultRecord", ctx, record).Return(nil).Once()
+				store.On("DeleteVaultRecord", ctx, "vaultID", "recordID").Return(nil).Once()
+			},
+			func() {
+				record := entity.Record{ID: "recordID", VaultID: "vaultID", Data: []byte("data")}
+
+				recordID, err := handlers.CreateVaultRecord(ctx, record)
+				assert.NoError(t, err)
+				assert.Equal(t, "recordID", recordID)
+				assert.NoError(t, handlers.UpdateVaultRecord(ctx, record))
+				assert.NoError(t, handlers.DeleteVaultRecord(ctx, "vaultID", "recordID"))
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+
+		store.AssertExpectations(t)
+		auth.AssertExpectations(t)
+	}
 }
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net"
+	"path/filepath"
+
+	"github.com/bbt-t/lets-go-keep/internal/config"
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/pkg"
+
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Names of development certificate files in dev directory.
+const (
+	devCertFile = "server.crt"
+	devKeyFile  = "server.key"
+)
+
+// NewServerCredentials returns server transport credentials by TLS config. In dev mode without certificate
+// self-signed certificate for run address is generated on first start. Without certificate server runs insecure
+// only if it's explicitly allowed, otherwise ErrTLSNotConfigured is returned.
+func NewServerCredentials(cfg config.ServerTLSConfig, runAddress string) (credentials.TransportCredentials, error) {
+	if cfg.CertFile == "" && cfg.DevMode {
+		cfg.CertFile = filepath.Join(cfg.DevDirectory, devCertFile)
+		cfg.KeyFile = filepath.Join(cfg.DevDirectory, devKeyFile)
+
+		created, err := pkg.EnsureSelfSignedCert(cfg.CertFile, cfg.KeyFile, devHosts(runAddress))
+		if err != nil {
+			log.Warnf("%s :: %v", "generate dev certificate fault", err)
+
+			return nil, err
+		}
+		if created {
+			log.Warnf("Generated self-signed certificate %s, use it as client CA bundle.", cfg.CertFile)
+		}
+	}
+
+	if cfg.CertFile == "" && cfg.Insecure {
+		log.Warnln("TLS is disabled, server runs without transport encryption.")
+
+		return insecure.NewCredentials(), nil
+	}
+	if cfg.CertFile == "" {
+		return nil, controller.ErrTLSNotConfigured
+	}
+
+	tlsConfig, err := pkg.NewServerTLSConfig(cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile)
+	if err != nil {
+		log.Warnf("%s :: %v", "load server certificate fault", err)
+
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// NewClientCredentials returns client transport credentials by TLS config. If server name isn't set,
+// it's taken from server address, so certificate of address host is expected.
+func NewClientCredentials(cfg config.ClientTLSConfig, serverAddress string) (credentials.TransportCredentials, error) {
+	if cfg.Insecure {
+		log.Warnln("TLS is disabled, connection to server isn't encrypted.")
+
+		return insecure.NewCredentials(), nil
+	}
+
+	serverName := cfg.ServerName
+	if serverName == "" {
+		serverName = addressHost(serverAddress)
+	}
+
+	tlsConfig, err := pkg.NewClientTLSConfig(cfg.CAFile, cfg.CertFile, cfg.KeyFile, serverName)
+	if err != nil {
+		log.Warnf("%s :: %v", "load client TLS config fault", err)
+
+		return nil, err
+	}
+
+	return credentials.NewTLS(tlsConfig), nil
+}
+
+// devHosts returns hosts of development certificate: local hosts and host of run address.
+func devHosts(runAddress string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+
+	host := addressHost(runAddress)
+	for _, local := range hosts {
+		if host == local {
+			return hosts
+		}
+	}
+
+	return append(hosts, host)
+}
+
+// addressHost returns host of address, address without host (like ":3200") is local.
+func addressHost(address string) string {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+
+	switch host {
+	case "", "0.0.0.0", "::":
+		return "localhost"
+	}
+
+	return host
+}
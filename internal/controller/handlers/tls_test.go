@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/config"
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/pkg"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServerCredentials(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewServerCredentials(config.ServerTLSConfig{}, ":3200")
+	assert.Equal(t, controller.ErrTLSNotConfigured, err)
+
+	creds, err := NewServerCredentials(config.ServerTLSConfig{Insecure: true}, ":3200")
+	assert.NoError(t, err)
+	assert.Equal(t, "insecure", creds.Info().SecurityProtocol)
+
+	creds, err = NewServerCredentials(config.ServerTLSConfig{DevMode: true, DevDirectory: dir}, ":3200")
+	assert.NoError(t, err)
+	assert.Equal(t, "tls", creds.Info().SecurityProtocol)
+	assert.FileExists(t, filepath.Join(dir, devCertFile))
+	assert.FileExists(t, filepath.Join(dir, devKeyFile))
+
+	_, err = NewServerCredentials(config.ServerTLSConfig{CertFile: filepath.Join(dir, "missing.crt")}, ":3200")
+	assert.Error(t, err)
+}
+
+func TestNewClientCredentials(t *testing.T) {
+	creds, err := NewClientCredentials(config.ClientTLSConfig{Insecure: true}, ":3200")
+	assert.NoError(t, err)
+	assert.Equal(t, "insecure", creds.Info().SecurityProtocol)
+
+	creds, err = NewClientCredentials(config.ClientTLSConfig{}, "keeper.example.com:3200")
+	assert.NoError(t, err)
+	assert.Equal(t, "tls", creds.Info().SecurityProtocol)
+
+	_, err = NewClientCredentials(config.ClientTLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.crt")}, ":3200")
+	assert.Error(t, err)
+}
+
+func TestDevHosts(t *testing.T) {
+	assert.Equal(t, []string{"localhost", "127.0.0.1", "::1"}, devHosts(":3200"))
+	assert.Equal(t, []string{"localhost", "127.0.0.1", "::1"}, devHosts("127.0.0.1:3200"))
+	assert.Equal(t, []string{"localhost", "127.0.0.1", "::1", "keeper.example.com"}, devHosts("keeper.example.com:3200"))
+}
+
+func TestTLSConnection(t *testing.T) {
+	dir := t.TempDir()
+	serverCfg, handlers := config.NewServerConfig(), mocks.NewServerHandlers(t)
+
+	clientCert, clientKey := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	_, err := pkg.EnsureSelfSignedCert(clientCert, clientKey, []string{"client"})
+	assert.NoError(t, err)
+
+	serverCreds, err := NewServerCredentials(config.ServerTLSConfig{
+		ClientCAFile: clientCert,
+		DevMode:      true,
+		DevDirectory: dir,
+	}, serverCfg.RunAddress)
+	assert.NoError(t, err)
+
+	server := NewServerConn(handlers, newTokenAuthenticator(t), serverCreds)
+	server.Run(context.Background(), serverCfg.RunAddress)
+	defer server.Stop()
+
+	credentials := entity.UserCredentials{Login: "Login", Password: "Password"}
+
+	tc := []struct {
+		name  string
+		cfg   config.ClientTLSConfig
+		mock  func()
+		valid func(err error)
+	}{
+		{
+			"Mutual TLS",
+			config.ClientTLSConfig{
+				CAFile:   filepath.Join(dir, devCertFile),
+				CertFile: clientCert,
+				KeyFile:  clientKey,
+			},
+			func() {
+				handlers.On("LoginUser", credentials).Return(entity.Session{Token: "token"}, nil).Once()
+			},
+			func(err error) {
+				assert.NoError(t, err)
+			},
+		},
+		{
+			"Without client certificate",
+			config.ClientTLSConfig{
+				CAFile: filepath.Join(dir, devCertFile),
+			},
+			func() {},
+			func(err error) {
+				assert.ErrorIs(t, err, controller.ErrServerUnavailable)
+			},
+		},
+		{
+			"Without CA bundle of self-signed certificate",
+			config.ClientTLSConfig{
+				CertFile: clientCert,
+				KeyFile:  clientKey,
+			},
+			func() {},
+			func(err error) {
+				assert.ErrorIs(t, err, controller.ErrServerUnavailable)
+			},
+		},
+		{
+			"Without TLS",
+			config.ClientTLSConfig{
+				Insecure: true,
+			},
+			func() {},
+			func(err error) {
+				assert.ErrorIs(t, err, controller.ErrServerUnavailable)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		clientCreds, err := NewClientCredentials(test.cfg, serverCfg.RunAddress)
+		assert.NoError(t, err)
+
+		client := newClientConn(serverCfg.RunAddress, clientCreds, time.Second)
+
+		test.mock()
+		_, err = client.Login(credentials)
+		test.valid(err)
+		handlers.AssertExpectations(t)
+	}
+}
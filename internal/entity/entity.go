@@ -3,6 +3,7 @@ package entity
 import (
 	"io"
 	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -12,12 +13,50 @@ const (
 	TypeFile
 	TypeText
 	TypeCreditCard
+	TypeOTP
+)
+
+// Roles of vault members. Readers can only read records, writers can change them, owner manages members too.
+const (
+	RoleRead VaultRole = iota
+	RoleReadWrite
+	RoleOwner
 )
 
 // UserCredentials struct for user authorization.
+// PublicKey is X25519 key of user, which is registered on signup, so vault keys can be shared with user.
 type UserCredentials struct {
 	Login, Password string
 	MasterKey       []byte
+	KDF             KDFParams
+	PublicKey       []byte
+}
+
+// KDFParams are parameters of Argon2id, which derives encryption key from master key.
+// Stored on server with user. Legacy marks users, who were created before KDF was introduced,
+// they have no salt and their key isn't derived from master key.
+type KDFParams struct {
+	Salt    []byte
+	Time    uint32
+	Memory  uint32 // In KiB.
+	Threads uint8
+	Legacy  bool
+}
+
+// Session is result of user authorization. Short-lived token authorizes requests until it expires,
+// then refresh token is exchanged for new session.
+type Session struct {
+	Token        AuthToken
+	RefreshToken RefreshToken
+	ExpiresAt    time.Time
+	KDF          KDFParams
+}
+
+// User is stored user with encoded password hash.
+type User struct {
+	ID           UserID
+	Login        string
+	PasswordHash string
 }
 
 // UserID is unique identificator of user.
@@ -26,15 +65,113 @@ type UserID string
 // AuthToken is authorization token of user. Should store userID.
 type AuthToken string
 
+// RefreshToken is long-lived opaque token, which is exchanged for new session. Server stores only its hash.
+type RefreshToken string
+
 // Record is struct for decrypted or encrypted information.
+// Name, tags and folder organise records, they are stored on server as is, like metadata.
 type Record struct {
 	ID, Metadata string
-	Type         RecordType
-	Data         []byte
+	Name         string
+	Tags         []string
+	// Folder is path of folder separated by slashes, e.g. "work/banks". Empty folder is root.
+	Folder string
+	// BlindIndex is keyed hashes of words of encrypted metadata, so server can search it.
+	BlindIndex []string
+	Type       RecordType
+	Data       []byte
+	// Body is optional reader with record data. Used for big files, which shouldn't be read to memory.
+	Body io.Reader
+	// Revision is revision of user data, when record was changed last time. When record is changed,
+	// it's revision, which client knows, zero revision means that record is changed unconditionally.
+	Revision  int64
+	UpdatedAt time.Time
+	// VaultID is shared vault of record. Empty vault means personal record.
+	VaultID string
 }
 
 type RecordType int32
 
+// RecordVersion is previous version of record, which can be restored.
+type RecordVersion struct {
+	RecordID   string
+	Version    int32
+	Metadata   string
+	ReplacedAt time.Time
+}
+
+// Tombstone is mark of deleted record, so other devices can delete it too.
+type Tombstone struct {
+	RecordID  string
+	Revision  int64
+	DeletedAt time.Time
+}
+
+// RecordChanges are records changed and deleted after some revision of user data.
+// Revision is current revision, which should be used for next synchronisation.
+// More means that changes are cut by page, the rest are got from Revision.
+type RecordChanges struct {
+	Records  []Record
+	Deleted  []Tombstone
+	Revision int64
+	More     bool
+}
+
+// SyncConflict is offline change, which isn't applied on server as it was made. Update of record,
+// which was changed or deleted on another device, is saved as new record CopyID, deletion of such record
+// is skipped. Change, which server rejected, has Error and stays queued, until it's sent or record is deleted.
+// Metadata and Name are labels of changed record.
+type SyncConflict struct {
+	Kind           string
+	RecordID       string
+	CopyID         string
+	Metadata, Name string
+	Error          string
+	At             time.Time
+}
+
+// VaultRole is access of member to shared vault.
+type VaultRole int32
+
+// Vault is shared vault of records. Its records are encrypted by vault key, which is wrapped
+// by public key of every member, so server never knows it. WrappedKey is key wrapped for current user.
+type Vault struct {
+	ID, Name   string
+	Role       VaultRole
+	WrappedKey []byte
+}
+
+// VaultMember is user, who has access to shared vault. WrappedKey is vault key wrapped for this user.
+// KeyMAC binds public key of member, which owner checked by fingerprint on invite, to vault and login,
+// so owner can wrap new vault key for the same public key later.
+type VaultMember struct {
+	VaultID, Login string
+	Role           VaultRole
+	WrappedKey     []byte
+	KeyMAC         []byte
+}
+
+// VaultRotation replaces vault key, when member is revoked: new key is wrapped for members, who stay,
+// and all records of vault are encrypted by it.
+type VaultRotation struct {
+	VaultID, Revoked string
+	Members          []VaultMember
+	Records          []Record
+}
+
+func (r VaultRole) String() string {
+	switch r {
+	case RoleRead:
+		return "read"
+	case RoleReadWrite:
+		return "read-write"
+	case RoleOwner:
+		return "owner"
+	default:
+		return "unknown"
+	}
+}
+
 func (r RecordType) String() string {
 	switch r {
 	case TypeLoginAndPassword:
@@ -45,6 +182,8 @@ func (r RecordType) String() string {
 		return "Text"
 	case TypeCreditCard:
 		return "Credit card"
+	case TypeOTP:
+		return "One-time password"
 	default:
 		return "Unknown"
 	}
@@ -52,22 +191,23 @@ func (r RecordType) String() string {
 
 // LoginAndPassword for encrypted login and password.
 type LoginAndPassword struct {
-	Login, Password string
+	Login    string        `json:"login"`
+	Password string        `json:"password"`
+	URL      string        `json:"url,omitempty"`
+	Notes    string        `json:"notes,omitempty"`
+	Fields   []CustomField `json:"fields,omitempty"`
 }
 
-// Bytes implementation of Data interface.
-func (data *LoginAndPassword) Bytes() ([]byte, error) {
-	return []byte(data.Login + ":" + data.Password), nil
+// CustomField is user-defined field of login, e.g. security question. Hidden field is secret.
+type CustomField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Hidden bool   `json:"hidden,omitempty"`
 }
 
 // TextData for encrypted text data.
 type TextData struct {
-	Text string
-}
-
-// Bytes gets bytes of information.
-func (data *TextData) Bytes() ([]byte, error) {
-	return []byte(data.Text), nil
+	Text string `json:"text"`
 }
 
 // BinaryFile for encrypted file.
@@ -78,6 +218,17 @@ type BinaryFile struct {
 
 // Bytes gets bytes of information.
 func (data *BinaryFile) Bytes() ([]byte, error) {
+	file, err := data.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+// Reader opens file for reading by chunks. Caller must close it.
+func (data *BinaryFile) Reader() (io.ReadCloser, error) {
 	file, err := os.Open(data.FilePath)
 	if err != nil {
 		log.Infoln(err)
@@ -86,15 +237,19 @@ func (data *BinaryFile) Bytes() ([]byte, error) {
 	}
 	data.File = file
 
-	return io.ReadAll(data.File)
+	return file, nil
 }
 
 // CreditCard for encrypted credit card.
 type CreditCard struct {
-	CardNumber, ExpirationDate, CVCCode string
+	CardNumber     string `json:"number"`
+	ExpirationDate string `json:"expiration"`
+	CVCCode        string `json:"cvc"`
+	Holder         string `json:"holder,omitempty"`
+	Notes          string `json:"notes,omitempty"`
 }
 
-// Bytes gets bytes of information.
-func (data *CreditCard) Bytes() ([]byte, error) {
-	return []byte(data.CardNumber + "|" + data.ExpirationDate + "|" + data.CVCCode), nil
+// OTPData for encrypted key of one-time passwords, it's otpauth:// URI.
+type OTPData struct {
+	URI string `json:"uri"`
 }
@@ -0,0 +1,26 @@
+package entity
+
+import "context"
+
+// Identity is authenticated user of request.
+type Identity struct {
+	UserID UserID
+}
+
+// identityKey is context key of identity. Unexported type can't collide with keys of other packages.
+type identityKey struct{}
+
+// ContextWithIdentity returns context, which carries identity of authenticated user.
+func ContextWithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns identity of authenticated user. Reports false, if request isn't authenticated.
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	if !ok || identity.UserID == "" {
+		return Identity{}, false
+	}
+
+	return identity, true
+}
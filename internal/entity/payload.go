@@ -0,0 +1,233 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// PayloadVersion is current version of record payload encoding.
+const PayloadVersion = 1
+
+// payloadMagic starts encoded payload. It has zero byte, so it can't start text of legacy record,
+// which was saved as is.
+var payloadMagic = []byte("\x00gkp")
+
+// Errors of payload decoding.
+var (
+	ErrBadPayload            = errors.New("record payload is malformed")
+	ErrUnknownPayloadVersion = errors.New("record payload has unknown version")
+)
+
+// Payload is decrypted data of record with its own structure, which is encoded to record data.
+// File records aren't payloads, their data is file content.
+type Payload interface {
+	// Bytes encodes payload to record data of current version.
+	Bytes() ([]byte, error)
+	// Decode decodes payload from record data of any known version, including legacy records.
+	Decode(data []byte) error
+	// Secret is the main secret of payload, which is copied to clipboard.
+	Secret() string
+	// String is human-readable view of payload.
+	String() string
+}
+
+// NewPayload gets empty payload of record type.
+func NewPayload(recordType RecordType) (Payload, error) {
+	switch recordType {
+	case TypeLoginAndPassword:
+		return &LoginAndPassword{}, nil
+	case TypeText:
+		return &TextData{}, nil
+	case TypeCreditCard:
+		return &CreditCard{}, nil
+	case TypeOTP:
+		return &OTPData{}, nil
+	default:
+		return nil, fmt.Errorf("%w: record type %q has no payload", ErrBadPayload, recordType)
+	}
+}
+
+// DecodePayload decodes payload of record.
+func DecodePayload(record Record) (Payload, error) {
+	payload, err := NewPayload(record.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, payload.Decode(record.Data)
+}
+
+// IsLegacyPayload reports if record data was saved before payloads were versioned, so it should be migrated.
+func IsLegacyPayload(data []byte) bool {
+	return !bytes.HasPrefix(data, payloadMagic)
+}
+
+// encodePayload encodes payload as magic, version and JSON.
+func encodePayload(payload interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(payloadMagic)+1+len(encoded))
+	data = append(data, payloadMagic...)
+	data = append(data, PayloadVersion)
+
+	return append(data, encoded...), nil
+}
+
+// decodePayload decodes payload of current version. It returns false, if data is legacy, so caller decodes it itself.
+func decodePayload(data []byte, payload interface{}) (bool, error) {
+	if IsLegacyPayload(data) {
+		return false, nil
+	}
+
+	data = data[len(payloadMagic):]
+	if len(data) == 0 {
+		return true, ErrBadPayload
+	}
+	if data[0] != PayloadVersion {
+		return true, fmt.Errorf("%w: %d", ErrUnknownPayloadVersion, data[0])
+	}
+
+	if err := json.Unmarshal(data[1:], payload); err != nil {
+		return true, fmt.Errorf("%w: %v", ErrBadPayload, err)
+	}
+
+	return true, nil
+}
+
+// Bytes gets bytes of information.
+func (data *LoginAndPassword) Bytes() ([]byte, error) {
+	return encodePayload(data)
+}
+
+// Decode decodes login and password. Legacy record is "login:password", login hadn't colons.
+func (data *LoginAndPassword) Decode(b []byte) error {
+	if ok, err := decodePayload(b, data); ok {
+		return err
+	}
+
+	login, password, _ := strings.Cut(string(b), ":")
+	*data = LoginAndPassword{Login: login, Password: password}
+
+	return nil
+}
+
+// Secret is password.
+func (data *LoginAndPassword) Secret() string {
+	return data.Password
+}
+
+// String gets fields per line.
+func (data *LoginAndPassword) String() string {
+	lines := []string{"Login: " + data.Login, "Password: " + data.Password}
+	if data.URL != "" {
+		lines = append(lines, "URL: "+data.URL)
+	}
+	for _, field := range data.Fields {
+		value := field.Value
+		if field.Hidden {
+			value = "********"
+		}
+		lines = append(lines, field.Name+": "+value)
+	}
+	if data.Notes != "" {
+		lines = append(lines, "", data.Notes)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Bytes gets bytes of information.
+func (data *TextData) Bytes() ([]byte, error) {
+	return encodePayload(data)
+}
+
+// Decode decodes text. Legacy record is text as is.
+func (data *TextData) Decode(b []byte) error {
+	if ok, err := decodePayload(b, data); ok {
+		return err
+	}
+
+	*data = TextData{Text: string(b)}
+
+	return nil
+}
+
+// Secret is text.
+func (data *TextData) Secret() string {
+	return data.Text
+}
+
+// String gets text.
+func (data *TextData) String() string {
+	return data.Text
+}
+
+// Bytes gets bytes of information.
+func (data *CreditCard) Bytes() ([]byte, error) {
+	return encodePayload(data)
+}
+
+// Decode decodes credit card. Legacy record is "number|expiration|cvc".
+func (data *CreditCard) Decode(b []byte) error {
+	if ok, err := decodePayload(b, data); ok {
+		return err
+	}
+
+	fields := strings.Split(string(b), "|")
+	if len(fields) != 3 {
+		return ErrBadPayload
+	}
+	*data = CreditCard{CardNumber: fields[0], ExpirationDate: fields[1], CVCCode: fields[2]}
+
+	return nil
+}
+
+// Secret is card number.
+func (data *CreditCard) Secret() string {
+	return data.CardNumber
+}
+
+// String gets fields per line.
+func (data *CreditCard) String() string {
+	lines := []string{"Number: " + data.CardNumber, "Expiration: " + data.ExpirationDate, "CVC: " + data.CVCCode}
+	if data.Holder != "" {
+		lines = append(lines, "Holder: "+data.Holder)
+	}
+	if data.Notes != "" {
+		lines = append(lines, "", data.Notes)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Bytes gets bytes of information.
+func (data *OTPData) Bytes() ([]byte, error) {
+	return encodePayload(data)
+}
+
+// Decode decodes one-time password key. Legacy record is URI as is.
+func (data *OTPData) Decode(b []byte) error {
+	if ok, err := decodePayload(b, data); ok {
+		return err
+	}
+
+	*data = OTPData{URI: string(b)}
+
+	return nil
+}
+
+// Secret is URI, codes are made from it by caller.
+func (data *OTPData) Secret() string {
+	return data.URI
+}
+
+// String gets URI.
+func (data *OTPData) String() string {
+	return data.URI
+}
@@ -0,0 +1,116 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload(t *testing.T) {
+	tc := []struct {
+		name    string
+		record  Record
+		payload Payload
+	}{
+		{
+			name:   "Login with colon in password",
+			record: Record{Type: TypeLoginAndPassword},
+			payload: &LoginAndPassword{
+				Login:    "user",
+				Password: "pa:ss|word",
+				URL:      "https://example.com",
+				Notes:    "notes",
+				Fields:   []CustomField{{Name: "pin", Value: "1234", Hidden: true}},
+			},
+		},
+		{
+			name:    "Text",
+			record:  Record{Type: TypeText},
+			payload: &TextData{Text: "\x00gk text"},
+		},
+		{
+			name:    "Credit card",
+			record:  Record{Type: TypeCreditCard},
+			payload: &CreditCard{CardNumber: "4242424242424242", ExpirationDate: "12/30", CVCCode: "123", Holder: "BOB"},
+		},
+		{
+			name:    "One-time password",
+			record:  Record{Type: TypeOTP},
+			payload: &OTPData{URI: "otpauth://totp/bob?secret=ABC"},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		data, err := test.payload.Bytes()
+		assert.NoError(t, err)
+		assert.False(t, IsLegacyPayload(data))
+
+		test.record.Data = data
+		decoded, err := DecodePayload(test.record)
+		assert.NoError(t, err)
+		assert.Equal(t, test.payload, decoded)
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	tc := []struct {
+		name    string
+		record  Record
+		payload Payload
+		want    error
+	}{
+		{
+			name:    "Legacy login",
+			record:  Record{Type: TypeLoginAndPassword, Data: []byte("user:pass:word")},
+			payload: &LoginAndPassword{Login: "user", Password: "pass:word"},
+		},
+		{
+			name:    "Legacy text",
+			record:  Record{Type: TypeText, Data: []byte("text")},
+			payload: &TextData{Text: "text"},
+		},
+		{
+			name:    "Legacy credit card",
+			record:  Record{Type: TypeCreditCard, Data: []byte("4242424242424242|12/30|123")},
+			payload: &CreditCard{CardNumber: "4242424242424242", ExpirationDate: "12/30", CVCCode: "123"},
+		},
+		{
+			name:    "Legacy one-time password",
+			record:  Record{Type: TypeOTP, Data: []byte("otpauth://totp/bob?secret=ABC")},
+			payload: &OTPData{URI: "otpauth://totp/bob?secret=ABC"},
+		},
+		{
+			name:   "Malformed legacy credit card",
+			record: Record{Type: TypeCreditCard, Data: []byte("4242424242424242")},
+			want:   ErrBadPayload,
+		},
+		{
+			name:   "Unknown version",
+			record: Record{Type: TypeText, Data: []byte("\x00gkp\x02{}")},
+			want:   ErrUnknownPayloadVersion,
+		},
+		{
+			name:   "Malformed JSON",
+			record: Record{Type: TypeText, Data: []byte("\x00gkp\x01{")},
+			want:   ErrBadPayload,
+		},
+		{
+			name:   "File",
+			record: Record{Type: TypeFile, Data: []byte("data")},
+			want:   ErrBadPayload,
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		payload, err := DecodePayload(test.record)
+		assert.ErrorIs(t, err, test.want)
+		if test.want == nil {
+			assert.True(t, IsLegacyPayload(test.record.Data))
+			assert.Equal(t, test.payload, payload)
+		}
+	}
+}
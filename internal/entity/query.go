@@ -0,0 +1,223 @@
+package entity
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// Sorting of records list.
+const (
+	SortByName RecordsSort = iota
+	SortByUpdatedAt
+	SortByType
+)
+
+// Limits of records page.
+const (
+	RecordsDefaultLimit = 100
+	RecordsMaxLimit     = 1000
+)
+
+// ErrBadQuery is query of records list with unknown sorting.
+var ErrBadQuery = errors.New("records query is invalid")
+
+// RecordsSort is field, which records list is sorted by.
+type RecordsSort int32
+
+// Valid checks that records list can be sorted by field.
+func (s RecordsSort) Valid() bool {
+	return s >= SortByName && s <= SortByType
+}
+
+// RecordsQuery is filter, sorting and page of records list. Empty query gets first page of all records sorted by name.
+type RecordsQuery struct {
+	// Search is case-insensitive substring of name or metadata.
+	Search string
+	// BlindIndex is hashes of search words. Record with all of them matches search too, even if its metadata
+	// is encrypted.
+	BlindIndex []string
+	// Tags are tags, which record must have all.
+	Tags []string
+	// Folder gets records of folder and its subfolders. Empty folder means all records.
+	Folder     string
+	Types      []RecordType
+	Sort       RecordsSort
+	Descending bool
+	Offset     int32
+	// Limit is size of page, zero means default size. It's cut to RecordsMaxLimit.
+	Limit int32
+}
+
+// RecordsPage is page of records list. Total is number of records, which match query, in all pages.
+type RecordsPage struct {
+	Records []Record
+	Total   int32
+}
+
+// NormalizeTags makes tags lowercase and trimmed, drops empty and repeated ones and sorts them.
+// Commas are removed, because they separate stored tags. No tags are nil.
+func NormalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	result := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(tag, ",", "")))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		result = append(result, tag)
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// NormalizeFolder makes folder path without empty parts and slashes at ends, e.g. "/work//banks/" is "work/banks".
+func NormalizeFolder(folder string) string {
+	parts := strings.Split(folder, "/")
+	result := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+
+	return strings.Join(result, "/")
+}
+
+// InFolder checks if folder is the same as parent or its subfolder.
+func InFolder(folder, parent string) bool {
+	return parent == "" || folder == parent || strings.HasPrefix(folder, parent+"/")
+}
+
+// Validate checks query, which is got from client.
+func (q RecordsQuery) Validate() error {
+	if !q.Sort.Valid() {
+		return ErrBadQuery
+	}
+
+	return nil
+}
+
+// Normalize normalizes tags and folder of query and limits its page. Unknown sorting is sorting by name.
+func (q RecordsQuery) Normalize() RecordsQuery {
+	q.Tags = NormalizeTags(q.Tags)
+	q.Folder = NormalizeFolder(q.Folder)
+
+	if !q.Sort.Valid() {
+		q.Sort = SortByName
+	}
+
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Limit <= 0 {
+		q.Limit = RecordsDefaultLimit
+	}
+	if q.Limit > RecordsMaxLimit {
+		q.Limit = RecordsMaxLimit
+	}
+
+	return q
+}
+
+// Match checks if record matches filter of query. Query must be normalized.
+func (q RecordsQuery) Match(record Record) bool {
+	if q.Search != "" {
+		search := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(record.Name), search) &&
+			!strings.Contains(strings.ToLower(record.Metadata), search) &&
+			!(len(q.BlindIndex) > 0 && containsAll(record.BlindIndex, q.BlindIndex)) {
+			return false
+		}
+	}
+
+	if !InFolder(record.Folder, q.Folder) {
+		return false
+	}
+
+	if !containsAll(record.Tags, q.Tags) {
+		return false
+	}
+
+	if len(q.Types) == 0 {
+		return true
+	}
+	for _, recordType := range q.Types {
+		if record.Type == recordType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsAll checks if all values are in list.
+func containsAll(list, values []string) bool {
+	set := make(map[string]bool, len(list))
+	for _, item := range list {
+		set[item] = true
+	}
+
+	for _, value := range values {
+		if !set[value] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Apply filters, sorts and pages records in memory, as server does.
+func (q RecordsQuery) Apply(records []Record) RecordsPage {
+	q = q.Normalize()
+
+	matched := make([]Record, 0, len(records))
+	for _, record := range records {
+		if q.Match(record) {
+			matched = append(matched, record)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if q.Descending {
+			a, b = b, a
+		}
+
+		switch {
+		case q.Sort == SortByUpdatedAt && !a.UpdatedAt.Equal(b.UpdatedAt):
+			return a.UpdatedAt.Before(b.UpdatedAt)
+		case q.Sort == SortByType && a.Type != b.Type:
+			return a.Type < b.Type
+		case q.Sort == SortByName && !strings.EqualFold(a.Name, b.Name):
+			return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+		}
+
+		return a.ID < b.ID
+	})
+
+	page := RecordsPage{Records: []Record{}, Total: int32(len(matched))}
+	if int(q.Offset) < len(matched) {
+		end := int(q.Offset + q.Limit)
+		if end > len(matched) {
+			end = len(matched)
+		}
+		page.Records = matched[q.Offset:end]
+	}
+
+	return page
+}
@@ -0,0 +1,73 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	assert.Equal(t, []string{"bank", "work"}, NormalizeTags([]string{" Work", "bank", "WORK", "", "ba,nk"}))
+	assert.Nil(t, NormalizeTags([]string{" ", ","}))
+	assert.Nil(t, NormalizeTags(nil))
+}
+
+func TestNormalizeFolder(t *testing.T) {
+	assert.Equal(t, "work/banks", NormalizeFolder("/work// banks /"))
+	assert.Equal(t, "", NormalizeFolder("/"))
+	assert.True(t, InFolder("work/banks", "work"))
+	assert.True(t, InFolder("work", "work"))
+	assert.True(t, InFolder("work", ""))
+	assert.False(t, InFolder("workshop", "work"))
+}
+
+func TestRecordsQuery_Validate(t *testing.T) {
+	assert.NoError(t, RecordsQuery{Sort: SortByType}.Validate())
+	assert.ErrorIs(t, RecordsQuery{Sort: 42}.Validate(), ErrBadQuery)
+	assert.ErrorIs(t, RecordsQuery{Sort: -1}.Validate(), ErrBadQuery)
+	assert.Equal(t, SortByName, RecordsQuery{Sort: 42}.Normalize().Sort)
+}
+
+func TestRecordsQuery_Apply(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{ID: "1", Name: "bank", Metadata: "main account", Tags: []string{"finance"}, Folder: "work/banks", UpdatedAt: now},
+		{ID: "2", Name: "Amazon", Type: TypeCreditCard, Tags: []string{"finance", "shop"}, UpdatedAt: now.Add(time.Hour)},
+		{ID: "3", Name: "Notes", Type: TypeText, Folder: "work", UpdatedAt: now.Add(-time.Hour)},
+		{ID: "4", Name: "notes", Type: TypeText, Folder: "workshop", UpdatedAt: now},
+	}
+
+	ids := func(page RecordsPage) []string {
+		result := make([]string, 0, len(page.Records))
+		for _, record := range page.Records {
+			result = append(result, record.ID)
+		}
+
+		return result
+	}
+
+	tc := []struct {
+		name  string
+		query RecordsQuery
+		ids   []string
+		total int32
+	}{
+		{name: "All sorted by name", query: RecordsQuery{}, ids: []string{"2", "1", "3", "4"}, total: 4},
+		{name: "Search in metadata", query: RecordsQuery{Search: "ACCOUNT"}, ids: []string{"1"}, total: 1},
+		{name: "All tags", query: RecordsQuery{Tags: []string{"Finance", "shop"}}, ids: []string{"2"}, total: 1},
+		{name: "Folder with subfolders", query: RecordsQuery{Folder: "/work/"}, ids: []string{"1", "3"}, total: 2},
+		{name: "Types", query: RecordsQuery{Types: []RecordType{TypeText}}, ids: []string{"3", "4"}, total: 2},
+		{name: "Newest first", query: RecordsQuery{Sort: SortByUpdatedAt, Descending: true}, ids: []string{"2", "4", "1", "3"}, total: 4},
+		{name: "Page", query: RecordsQuery{Offset: 1, Limit: 2}, ids: []string{"1", "3"}, total: 4},
+		{name: "Page after the last", query: RecordsQuery{Offset: 10}, ids: []string{}, total: 4},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		page := test.query.Apply(records)
+		assert.Equal(t, test.ids, ids(page))
+		assert.Equal(t, test.total, page.Total)
+	}
+}
@@ -0,0 +1,300 @@
+package exchange
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Argon2 (RFC 9106) is implemented here, because KeePass derives keys by Argon2d,
+// which golang.org/x/crypto/argon2 doesn't export. Argon2id is implemented too, so the same code
+// is checked against that package.
+
+// Types of Argon2.
+const (
+	argon2d  = 0
+	argon2id = 2
+)
+
+const (
+	argon2Version    = 0x13
+	argon2SyncPoints = 4
+	argon2BlockWords = 128
+)
+
+// argon2Block is memory block of Argon2, 1 KiB.
+type argon2Block [argon2BlockWords]uint64
+
+// argon2Key derives key of keyLen bytes. Memory is in KiB.
+func argon2Key(mode int, password, salt, secret, data []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	if memory < 8*uint32(threads) {
+		memory = 8 * uint32(threads)
+	}
+
+	h0 := argon2InitHash(mode, password, salt, secret, data, time, memory, uint32(threads), keyLen)
+	memory = memory / (argon2SyncPoints * uint32(threads)) * (argon2SyncPoints * uint32(threads))
+
+	blocks := argon2InitBlocks(&h0, memory, uint32(threads))
+	argon2Fill(mode, blocks, time, memory, uint32(threads))
+
+	return argon2Extract(blocks, memory, uint32(threads), keyLen)
+}
+
+// argon2InitHash makes H0 of parameters and inputs, 8 bytes are left for block and lane numbers.
+func argon2InitHash(mode int, password, salt, secret, data []byte, time, memory, threads, keyLen uint32) [blake2b.Size + 8]byte {
+	var (
+		h0     [blake2b.Size + 8]byte
+		params [24]byte
+	)
+
+	b2, _ := blake2b.New512(nil)
+
+	binary.LittleEndian.PutUint32(params[0:4], threads)
+	binary.LittleEndian.PutUint32(params[4:8], keyLen)
+	binary.LittleEndian.PutUint32(params[8:12], memory)
+	binary.LittleEndian.PutUint32(params[12:16], time)
+	binary.LittleEndian.PutUint32(params[16:20], argon2Version)
+	binary.LittleEndian.PutUint32(params[20:24], uint32(mode))
+	b2.Write(params[:])
+
+	for _, input := range [][]byte{password, salt, secret, data} {
+		var size [4]byte
+		binary.LittleEndian.PutUint32(size[:], uint32(len(input)))
+		b2.Write(size[:])
+		b2.Write(input)
+	}
+
+	b2.Sum(h0[:0])
+
+	return h0
+}
+
+// argon2InitBlocks allocates memory and makes first two blocks of every lane.
+func argon2InitBlocks(h0 *[blake2b.Size + 8]byte, memory, threads uint32) []argon2Block {
+	var raw [1024]byte
+
+	blocks := make([]argon2Block, memory)
+	for lane := uint32(0); lane < threads; lane++ {
+		first := lane * (memory / threads)
+		binary.LittleEndian.PutUint32(h0[blake2b.Size+4:], lane)
+
+		for i := uint32(0); i < 2; i++ {
+			binary.LittleEndian.PutUint32(h0[blake2b.Size:], i)
+			argon2Hash(raw[:], h0[:])
+
+			for j := range blocks[first+i] {
+				blocks[first+i][j] = binary.LittleEndian.Uint64(raw[j*8:])
+			}
+		}
+	}
+
+	return blocks
+}
+
+// argon2Fill fills memory in passes, lanes of every slice are filled in parallel.
+func argon2Fill(mode int, blocks []argon2Block, time, memory, threads uint32) {
+	lanes := memory / threads
+	segments := lanes / argon2SyncPoints
+
+	fillSegment := func(pass, slice, lane uint32, wg *sync.WaitGroup) {
+		defer wg.Done()
+
+		var addresses, in, zero argon2Block
+
+		// Argon2id uses data-independent addressing in first half of first pass, like Argon2i.
+		independent := mode == argon2id && pass == 0 && slice < argon2SyncPoints/2
+		if independent {
+			in[0], in[1], in[2] = uint64(pass), uint64(lane), uint64(slice)
+			in[3], in[4], in[5] = uint64(memory), uint64(time), uint64(mode)
+		}
+
+		index := uint32(0)
+		if pass == 0 && slice == 0 {
+			index = 2
+			if independent {
+				in[6]++
+				argon2Compress(&addresses, &in, &zero, false)
+				argon2Compress(&addresses, &addresses, &zero, false)
+			}
+		}
+
+		offset := lane*lanes + slice*segments + index
+		for index < segments {
+			prev := offset - 1
+			if index == 0 && slice == 0 {
+				prev += lanes
+			}
+
+			random := blocks[prev][0]
+			if independent {
+				if index%argon2BlockWords == 0 {
+					in[6]++
+					argon2Compress(&addresses, &in, &zero, false)
+					argon2Compress(&addresses, &addresses, &zero, false)
+				}
+				random = addresses[index%argon2BlockWords]
+			}
+
+			ref := argon2Index(random, lanes, segments, threads, pass, slice, lane, index)
+			argon2Compress(&blocks[offset], &blocks[prev], &blocks[ref], true)
+
+			index, offset = index+1, offset+1
+		}
+	}
+
+	for pass := uint32(0); pass < time; pass++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			var wg sync.WaitGroup
+			for lane := uint32(0); lane < threads; lane++ {
+				wg.Add(1)
+				go fillSegment(pass, slice, lane, &wg)
+			}
+			wg.Wait()
+		}
+	}
+}
+
+// argon2Extract makes key from XOR of last blocks of all lanes.
+func argon2Extract(blocks []argon2Block, memory, threads, keyLen uint32) []byte {
+	lanes := memory / threads
+	for lane := uint32(0); lane < threads-1; lane++ {
+		for i, v := range blocks[lane*lanes+lanes-1] {
+			blocks[memory-1][i] ^= v
+		}
+	}
+
+	var raw [1024]byte
+	for i, v := range blocks[memory-1] {
+		binary.LittleEndian.PutUint64(raw[i*8:], v)
+	}
+
+	key := make([]byte, keyLen)
+	argon2Hash(key, raw[:])
+
+	return key
+}
+
+// argon2Index gets index of reference block.
+func argon2Index(random uint64, lanes, segments, threads, pass, slice, lane, index uint32) uint32 {
+	refLane := uint32(random>>32) % threads
+	if pass == 0 && slice == 0 {
+		refLane = lane
+	}
+
+	area, start := 3*segments, ((slice+1)%argon2SyncPoints)*segments
+	if lane == refLane {
+		area += index
+	}
+	if pass == 0 {
+		area, start = slice*segments, 0
+		if slice == 0 || lane == refLane {
+			area += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		area--
+	}
+
+	p := random & 0xFFFFFFFF
+	p = (p * p) >> 32
+	p = (p * uint64(area)) >> 32
+
+	return refLane*lanes + uint32((uint64(start)+uint64(area)-(p+1))%uint64(lanes))
+}
+
+// argon2Compress is compression function G of two blocks. Result is XORed to out, if xor is set.
+func argon2Compress(out, a, b *argon2Block, xor bool) {
+	var t argon2Block
+	for i := range t {
+		t[i] = a[i] ^ b[i]
+	}
+	r := t
+
+	for i := 0; i < argon2BlockWords; i += 16 {
+		blamka(&t[i], &t[i+1], &t[i+2], &t[i+3], &t[i+4], &t[i+5], &t[i+6], &t[i+7],
+			&t[i+8], &t[i+9], &t[i+10], &t[i+11], &t[i+12], &t[i+13], &t[i+14], &t[i+15])
+	}
+	for i := 0; i < 16; i += 2 {
+		blamka(&t[i], &t[i+1], &t[16+i], &t[16+i+1], &t[32+i], &t[32+i+1], &t[48+i], &t[48+i+1],
+			&t[64+i], &t[64+i+1], &t[80+i], &t[80+i+1], &t[96+i], &t[96+i+1], &t[112+i], &t[112+i+1])
+	}
+
+	for i := range t {
+		if xor {
+			out[i] ^= r[i] ^ t[i]
+		} else {
+			out[i] = r[i] ^ t[i]
+		}
+	}
+}
+
+// blamka is BLAKE2b round with multiplications, which mixes 16 words.
+func blamka(v0, v1, v2, v3, v4, v5, v6, v7, v8, v9, v10, v11, v12, v13, v14, v15 *uint64) {
+	blamkaMix(v0, v4, v8, v12)
+	blamkaMix(v1, v5, v9, v13)
+	blamkaMix(v2, v6, v10, v14)
+	blamkaMix(v3, v7, v11, v15)
+	blamkaMix(v0, v5, v10, v15)
+	blamkaMix(v1, v6, v11, v12)
+	blamkaMix(v2, v7, v8, v13)
+	blamkaMix(v3, v4, v9, v14)
+}
+
+// blamkaMix is quarter round of blamka.
+func blamkaMix(a, b, c, d *uint64) {
+	*a += *b + 2*uint64(uint32(*a))*uint64(uint32(*b))
+	*d = bits.RotateLeft64(*d^*a, -32)
+	*c += *d + 2*uint64(uint32(*c))*uint64(uint32(*d))
+	*b = bits.RotateLeft64(*b^*c, -24)
+	*a += *b + 2*uint64(uint32(*a))*uint64(uint32(*b))
+	*d = bits.RotateLeft64(*d^*a, -16)
+	*c += *d + 2*uint64(uint32(*c))*uint64(uint32(*d))
+	*b = bits.RotateLeft64(*b^*c, -63)
+}
+
+// argon2Hash is variable-length hash H' of Argon2.
+func argon2Hash(out, in []byte) {
+	var (
+		b2     hash.Hash
+		buffer [blake2b.Size]byte
+	)
+
+	if len(out) < blake2b.Size {
+		b2, _ = blake2b.New(len(out), nil)
+	} else {
+		b2, _ = blake2b.New512(nil)
+	}
+
+	binary.LittleEndian.PutUint32(buffer[:4], uint32(len(out)))
+	b2.Write(buffer[:4])
+	b2.Write(in)
+
+	if len(out) <= blake2b.Size {
+		b2.Sum(out[:0])
+		return
+	}
+
+	outLen := len(out)
+	b2.Sum(buffer[:0])
+	b2.Reset()
+	copy(out, buffer[:32])
+	out = out[32:]
+
+	for len(out) > blake2b.Size {
+		b2.Write(buffer[:])
+		b2.Sum(buffer[:0])
+		copy(out, buffer[:32])
+		out = out[32:]
+		b2.Reset()
+	}
+
+	if outLen%blake2b.Size > 0 {
+		r := ((outLen + 31) / 32) - 2
+		b2, _ = blake2b.New(outLen-32*r, nil)
+	}
+	b2.Write(buffer[:])
+	b2.Sum(out[:0])
+}
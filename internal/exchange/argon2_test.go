@@ -0,0 +1,31 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/argon2"
+)
+
+func TestArgon2Key(t *testing.T) {
+	password, salt := bytes.Repeat([]byte{0x01}, 32), bytes.Repeat([]byte{0x02}, 16)
+	secret, data := bytes.Repeat([]byte{0x03}, 8), bytes.Repeat([]byte{0x04}, 12)
+
+	t.Log("Argon2d test vector of RFC 9106")
+	key := argon2Key(argon2d, password, salt, secret, data, 3, 32, 4, 32)
+	assert.Equal(t, "512b391b6f1162975371d30919734294f868e3be3984f3c1a13a4db9fabe4acb", hex.EncodeToString(key))
+
+	t.Log("Argon2id test vector of RFC 9106")
+	key = argon2Key(argon2id, password, salt, secret, data, 3, 32, 4, 32)
+	assert.Equal(t, "0d640df58d78766c08c037a34a8b53c9d01ef0452d75b65eb52520e96b01e659", hex.EncodeToString(key))
+
+	t.Log("Argon2id is the same as in x/crypto")
+	for _, keyLen := range []uint32{16, 32, 100} {
+		assert.Equal(t,
+			argon2.IDKey([]byte("password"), []byte("somesalt"), 2, 64, 2, keyLen),
+			argon2Key(argon2id, []byte("password"), []byte("somesalt"), nil, nil, 2, 64, 2, keyLen),
+		)
+	}
+}
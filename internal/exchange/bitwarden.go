@@ -0,0 +1,287 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+)
+
+// Types of Bitwarden items and custom fields.
+const (
+	bitwardenLogin    = 1
+	bitwardenNote     = 2
+	bitwardenCard     = 3
+	bitwardenIdentity = 4
+
+	bitwardenHidden = 1
+	bitwardenLinked = 3
+)
+
+// bitwardenExport is unencrypted JSON export of Bitwarden. Organization exports have collections instead of folders.
+type bitwardenExport struct {
+	Encrypted   bool              `json:"encrypted"`
+	Folders     []bitwardenFolder `json:"folders"`
+	Collections []bitwardenFolder `json:"collections"`
+	Items       []bitwardenItem   `json:"items"`
+}
+
+type bitwardenFolder struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type bitwardenItem struct {
+	Type          int              `json:"type"`
+	Name          string           `json:"name"`
+	Notes         string           `json:"notes"`
+	FolderID      string           `json:"folderId"`
+	CollectionIDs []string         `json:"collectionIds"`
+	Fields        []bitwardenField `json:"fields"`
+	Login         *struct {
+		URIs []struct {
+			URI string `json:"uri"`
+		} `json:"uris"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+		TOTP     string `json:"totp"`
+	} `json:"login"`
+	Card *struct {
+		CardholderName string `json:"cardholderName"`
+		Number         string `json:"number"`
+		ExpMonth       string `json:"expMonth"`
+		ExpYear        string `json:"expYear"`
+		Code           string `json:"code"`
+	} `json:"card"`
+	Identity map[string]interface{} `json:"identity"`
+}
+
+type bitwardenField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Type  int    `json:"type"`
+}
+
+// identityFields are fields of Bitwarden identity in order of text record.
+var identityFields = []string{
+	"title", "firstName", "middleName", "lastName", "company", "email", "phone", "username",
+	"address1", "address2", "address3", "city", "state", "postalCode", "country",
+	"ssn", "passportNumber", "licenseNumber",
+}
+
+// parseBitwarden converts unencrypted JSON export of Bitwarden. Identities become text records.
+func parseBitwarden(data []byte) (Result, error) {
+	var export bitwardenExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrBadExport, err)
+	}
+
+	if export.Encrypted {
+		return Result{}, ErrEncryptedExport
+	}
+
+	folders := make(map[string]string, len(export.Folders)+len(export.Collections))
+	for _, folder := range append(export.Folders, export.Collections...) {
+		folders[folder.ID] = folder.Name
+	}
+
+	var result Result
+	for _, bw := range export.Items {
+		it := item{name: bw.Name, folder: folders[bw.FolderID]}
+		if it.folder == "" && len(bw.CollectionIDs) > 0 {
+			it.folder = folders[bw.CollectionIDs[0]]
+		}
+
+		fields := make([]entity.CustomField, 0, len(bw.Fields))
+		for _, field := range bw.Fields {
+			if field.Type != bitwardenLinked {
+				fields = append(fields, entity.CustomField{
+					Name:   field.Name,
+					Value:  field.Value,
+					Hidden: field.Type == bitwardenHidden,
+				})
+			}
+		}
+
+		switch {
+		case bw.Type == bitwardenLogin && bw.Login != nil:
+			login := entity.LoginAndPassword{
+				Login:    bw.Login.Username,
+				Password: bw.Login.Password,
+				Notes:    bw.Notes,
+				Fields:   fields,
+			}
+			if len(bw.Login.URIs) > 0 {
+				login.URL = bw.Login.URIs[0].URI
+			}
+
+			// Login with one-time password only is OTP record.
+			if login.Login != "" || login.Password != "" || login.URL != "" || login.Notes != "" ||
+				len(login.Fields) > 0 || bw.Login.TOTP == "" {
+				result.addLogin(it, login)
+			}
+			result.addOTP(it, bw.Login.TOTP)
+		case bw.Type == bitwardenCard && bw.Card != nil:
+			result.addCard(it, entity.CreditCard{
+				CardNumber:     bw.Card.Number,
+				ExpirationDate: cardExpiration(bw.Card.ExpMonth, bw.Card.ExpYear),
+				CVCCode:        bw.Card.Code,
+				Holder:         bw.Card.CardholderName,
+				Notes:          joinText(bw.Notes, fieldsText(fields)),
+			})
+		case bw.Type == bitwardenIdentity:
+			var identity []entity.CustomField
+			for _, name := range identityFields {
+				if value, ok := bw.Identity[name].(string); ok && value != "" {
+					identity = append(identity, entity.CustomField{Name: name, Value: value})
+				}
+			}
+
+			result.addText(it, joinText(fieldsText(identity), bw.Notes, fieldsText(fields)))
+		case bw.Type == bitwardenNote:
+			result.addText(it, joinText(bw.Notes, fieldsText(fields)))
+		default:
+			result.skip(it.name, fmt.Sprintf("unsupported item type %d", bw.Type))
+		}
+	}
+
+	return result, nil
+}
+
+// bitwardenOutput is JSON export of Bitwarden, which is written by exportBitwarden.
+type bitwardenOutput struct {
+	Encrypted bool              `json:"encrypted"`
+	Folders   []bitwardenFolder `json:"folders"`
+	Items     []bitwardenEntry  `json:"items"`
+}
+
+type bitwardenEntry struct {
+	ID         string              `json:"id"`
+	FolderID   *string             `json:"folderId"`
+	Type       int                 `json:"type"`
+	Reprompt   int                 `json:"reprompt"`
+	Name       string              `json:"name"`
+	Notes      *string             `json:"notes"`
+	Favorite   bool                `json:"favorite"`
+	Fields     []bitwardenField    `json:"fields,omitempty"`
+	Login      *bitwardenLoginData `json:"login,omitempty"`
+	SecureNote *struct {
+		Type int `json:"type"`
+	} `json:"secureNote,omitempty"`
+	Card *bitwardenCardData `json:"card,omitempty"`
+}
+
+type bitwardenLoginData struct {
+	URIs     []bitwardenURI `json:"uris"`
+	Username string         `json:"username"`
+	Password string         `json:"password"`
+	TOTP     *string        `json:"totp"`
+}
+
+type bitwardenURI struct {
+	Match *int   `json:"match"`
+	URI   string `json:"uri"`
+}
+
+type bitwardenCardData struct {
+	CardholderName string `json:"cardholderName"`
+	Number         string `json:"number"`
+	ExpMonth       string `json:"expMonth"`
+	ExpYear        string `json:"expYear"`
+	Code           string `json:"code"`
+}
+
+// exportBitwarden writes records as unencrypted JSON export of Bitwarden. Folders keep paths, Bitwarden
+// shows them nested. OTP records become logins with TOTP only, files are skipped: JSON has no attachments.
+func exportBitwarden(w io.Writer, records []entity.Record) ([]Skipped, error) {
+	var (
+		output  = bitwardenOutput{Folders: []bitwardenFolder{}, Items: []bitwardenEntry{}}
+		folders = make(map[string]string)
+		skipped []Skipped
+	)
+
+	for _, record := range records {
+		entry := decodeExported(record)
+		if entry.skipped != nil {
+			skipped = append(skipped, *entry.skipped)
+			continue
+		}
+
+		if record.Type == entity.TypeFile {
+			skipped = append(skipped, Skipped{Name: RecordName(record), Reason: "files can't be exported to Bitwarden JSON"})
+			continue
+		}
+
+		id, err := newUUID()
+		if err != nil {
+			return nil, err
+		}
+
+		item := bitwardenEntry{ID: id, Name: title(record)}
+
+		if record.Folder != "" {
+			if _, ok := folders[record.Folder]; !ok {
+				if folders[record.Folder], err = newUUID(); err != nil {
+					return nil, err
+				}
+				output.Folders = append(output.Folders, bitwardenFolder{ID: folders[record.Folder], Name: record.Folder})
+			}
+
+			folderID := folders[record.Folder]
+			item.FolderID = &folderID
+		}
+
+		switch {
+		case entry.login != nil:
+			item.Type, item.Notes = bitwardenLogin, optional(entry.login.Notes)
+			item.Login = &bitwardenLoginData{Username: entry.login.Login, Password: entry.login.Password, URIs: []bitwardenURI{}}
+			if entry.login.URL != "" {
+				item.Login.URIs = append(item.Login.URIs, bitwardenURI{URI: entry.login.URL})
+			}
+
+			for _, field := range entry.login.Fields {
+				fieldType := 0
+				if field.Hidden {
+					fieldType = bitwardenHidden
+				}
+				item.Fields = append(item.Fields, bitwardenField{Name: field.Name, Value: field.Value, Type: fieldType})
+			}
+		case entry.text != nil:
+			item.Type, item.Notes = bitwardenNote, optional(entry.text.Text)
+			item.SecureNote = &struct {
+				Type int `json:"type"`
+			}{}
+		case entry.card != nil:
+			month, year := splitExpiration(entry.card.ExpirationDate)
+			item.Type, item.Notes = bitwardenCard, optional(entry.card.Notes)
+			item.Card = &bitwardenCardData{
+				CardholderName: entry.card.Holder,
+				Number:         entry.card.CardNumber,
+				ExpMonth:       strings.TrimPrefix(month, "0"),
+				ExpYear:        year,
+				Code:           entry.card.CVCCode,
+			}
+		case entry.otp != nil:
+			item.Type = bitwardenLogin
+			item.Login = &bitwardenLoginData{URIs: []bitwardenURI{}, TOTP: optional(entry.otp.URI)}
+		}
+
+		output.Items = append(output.Items, item)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return skipped, encoder.Encode(output)
+}
+
+// optional makes null of empty string in JSON.
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
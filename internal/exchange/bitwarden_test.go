@@ -0,0 +1,89 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+)
+
+const testBitwardenExport = `{
+	"encrypted": false,
+	"folders": [{"id": "f1", "name": "Work"}],
+	"items": [
+		{
+			"type": 1, "name": "Mail", "folderId": "f1", "notes": "work mail",
+			"fields": [{"name": "PIN", "value": "1234", "type": 1}, {"name": "linked", "value": null, "type": 3}],
+			"login": {"uris": [{"uri": "https://mail.example.com"}], "username": "user", "password": "secret", "totp": "JBSWY3DPEHPK3PXP"}
+		},
+		{"type": 2, "name": "Note", "notes": "text", "secureNote": {"type": 0}},
+		{
+			"type": 3, "name": "Visa", "folderId": "unknown",
+			"card": {"cardholderName": "John Doe", "number": "4111111111111111", "expMonth": "3", "expYear": "2030", "code": "123"}
+		},
+		{"type": 4, "name": "Passport", "identity": {"firstName": "John", "lastName": "Doe", "passportNumber": "123456"}},
+		{"type": 2, "name": "Empty"},
+		{"type": 5, "name": "SSH key"}
+	]
+}`
+
+func TestParseBitwarden(t *testing.T) {
+	result, err := Parse(FormatBitwarden, []byte(testBitwardenExport), Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Records, 5)
+
+	t.Log("Login with custom fields and folder")
+	payload, err := entity.DecodePayload(result.Records[0])
+	require.NoError(t, err)
+	assert.Equal(t, "Work", result.Records[0].Folder)
+	assert.Equal(t, &entity.LoginAndPassword{
+		Login:    "user",
+		Password: "secret",
+		URL:      "https://mail.example.com",
+		Notes:    "work mail",
+		Fields:   []entity.CustomField{{Name: "PIN", Value: "1234", Hidden: true}},
+	}, payload)
+
+	t.Log("Bare TOTP secret becomes otpauth:// URI")
+	assert.Equal(t, entity.TypeOTP, result.Records[1].Type)
+	payload, err = entity.DecodePayload(result.Records[1])
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth://totp/Mail?secret=JBSWY3DPEHPK3PXP", payload.(*entity.OTPData).URI)
+
+	t.Log("Secure note")
+	payload, err = entity.DecodePayload(result.Records[2])
+	require.NoError(t, err)
+	assert.Equal(t, &entity.TextData{Text: "text"}, payload)
+
+	t.Log("Card")
+	payload, err = entity.DecodePayload(result.Records[3])
+	require.NoError(t, err)
+	assert.Equal(t, "", result.Records[3].Folder)
+	assert.Equal(t, &entity.CreditCard{
+		CardNumber:     "4111111111111111",
+		ExpirationDate: "03/30",
+		CVCCode:        "123",
+		Holder:         "John Doe",
+	}, payload)
+
+	t.Log("Identity is text")
+	payload, err = entity.DecodePayload(result.Records[4])
+	require.NoError(t, err)
+	assert.Equal(t, &entity.TextData{Text: "firstName: John\nlastName: Doe\npassportNumber: 123456"}, payload)
+
+	t.Log("Empty note and unknown type are skipped")
+	assert.Equal(t, []Skipped{
+		{Name: "Empty", Reason: "empty entry"},
+		{Name: "SSH key", Reason: "unsupported item type 5"},
+	}, result.Skipped)
+
+	t.Log("Encrypted export")
+	_, err = Parse(FormatBitwarden, []byte(`{"encrypted": true, "passwordProtected": true, "data": "..."}`), Options{})
+	assert.ErrorIs(t, err, ErrEncryptedExport)
+
+	t.Log("Malformed export")
+	_, err = Parse(FormatBitwarden, []byte(`{"items": [`), Options{})
+	assert.ErrorIs(t, err, ErrBadExport)
+}
@@ -0,0 +1,202 @@
+package exchange
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+)
+
+// csvColumns maps headers of CSV exports (Bitwarden, LastPass, 1Password, browsers) to fields of records.
+// Headers are lowercase with underscores instead of spaces and dashes.
+var csvColumns = map[string]string{
+	"name": "name", "title": "name",
+	"username": "login", "login": "login", "user": "login", "login_username": "login",
+	"password": "password", "login_password": "password",
+	"url": "url", "uri": "url", "website": "url", "login_uri": "url",
+	"notes": "notes", "note": "notes", "extra": "notes", "comments": "notes",
+	"folder": "folder", "group": "folder", "grouping": "folder",
+	"tags": "tags",
+	"totp": "otp", "otp": "otp", "otpauth": "otp", "login_totp": "otp",
+	"type": "type", "fields": "fields",
+	"card_number": "number", "cardnumber": "number", "number": "number",
+	"expiration": "expiration", "expiration_date": "expiration", "expiry": "expiration",
+	"cvc": "cvc", "cvv": "cvc", "security_code": "cvc",
+	"cardholder": "holder", "cardholder_name": "holder", "name_on_card": "holder",
+}
+
+// csvIgnored are service columns of exports, which aren't kept.
+var csvIgnored = map[string]bool{
+	"favorite": true, "fav": true, "reprompt": true, "archived": true, "guid": true, "httprealm": true,
+	"formactionorigin": true, "timecreated": true, "timelastused": true, "timepasswordchanged": true,
+}
+
+// parseCSV converts CSV with header row. Type of record is got from type column or guessed by filled fields,
+// unknown columns become custom fields.
+func parseCSV(data []byte) (Result, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrBadExport, err)
+	}
+	if len(rows) == 0 {
+		return Result{}, fmt.Errorf("%w: no header", ErrBadExport)
+	}
+
+	header, known := make([]string, len(rows[0])), false
+	for i, column := range rows[0] {
+		column = strings.ToLower(strings.TrimSpace(column))
+		column = strings.NewReplacer(" ", "_", "-", "_").Replace(column)
+
+		if field, ok := csvColumns[column]; ok {
+			header[i], known = field, true
+		} else if !csvIgnored[column] {
+			header[i] = "custom:" + strings.TrimSpace(rows[0][i])
+		}
+	}
+	if !known {
+		return Result{}, fmt.Errorf("%w: no known columns", ErrBadExport)
+	}
+
+	var result Result
+	for n, row := range rows[1:] {
+		values := make(map[string]string)
+		var fields []entity.CustomField
+
+		for i, value := range row {
+			if i >= len(header) || header[i] == "" || strings.TrimSpace(value) == "" {
+				continue
+			}
+
+			if name, ok := strings.CutPrefix(header[i], "custom:"); ok {
+				fields = append(fields, entity.CustomField{Name: name, Value: value})
+			} else {
+				values[header[i]] = value
+			}
+		}
+
+		fields = append(fields, parseFieldLines(values["fields"])...)
+		result.addCSVRow(n+2, values, fields)
+	}
+
+	return result, nil
+}
+
+// addCSVRow adds records of row with number n.
+func (r *Result) addCSVRow(n int, values map[string]string, fields []entity.CustomField) {
+	it := item{
+		name:   values["name"],
+		folder: values["folder"],
+		tags:   strings.FieldsFunc(values["tags"], func(r rune) bool { return r == ',' || r == ';' }),
+	}
+	if it.name == "" {
+		it.name = values["url"]
+	}
+	if it.name == "" {
+		it.name = fmt.Sprintf("row %d", n)
+	}
+
+	recordType := strings.ToLower(strings.TrimSpace(values["type"]))
+	switch {
+	case recordType == "note" || recordType == "securenote" || recordType == "text":
+		r.addText(it, joinText(values["notes"], fieldsText(fields)))
+	case recordType == "card" || values["number"] != "":
+		r.addCard(it, entity.CreditCard{
+			CardNumber:     values["number"],
+			ExpirationDate: values["expiration"],
+			CVCCode:        values["cvc"],
+			Holder:         values["holder"],
+			Notes:          joinText(values["notes"], fieldsText(fields)),
+		})
+	case recordType == "login" || values["login"] != "" || values["password"] != "" || values["url"] != "":
+		r.addLogin(it, entity.LoginAndPassword{
+			Login:    values["login"],
+			Password: values["password"],
+			URL:      values["url"],
+			Notes:    values["notes"],
+			Fields:   fields,
+		})
+	case values["notes"] != "" || len(fields) > 0:
+		r.addText(it, joinText(values["notes"], fieldsText(fields)))
+	case values["otp"] == "":
+		r.skip(it.name, "empty entry")
+	}
+
+	r.addOTP(it, values["otp"])
+}
+
+// parseFieldLines parses custom fields of Bitwarden CSV, they are "name: value" lines.
+func parseFieldLines(text string) []entity.CustomField {
+	var fields []entity.CustomField
+
+	for _, line := range strings.Split(text, "\n") {
+		name, value, _ := strings.Cut(line, ":")
+		if name, value = strings.TrimSpace(name), strings.TrimSpace(value); name != "" || value != "" {
+			fields = append(fields, entity.CustomField{Name: name, Value: value})
+		}
+	}
+
+	return fields
+}
+
+// csvHeader is header of exported CSV, parseCSV reads it back.
+var csvHeader = []string{
+	"type", "name", "folder", "tags", "username", "password", "url", "notes", "totp", "fields",
+	"card_number", "expiration", "cvc", "cardholder",
+}
+
+// exportCSV writes records as CSV, files are skipped. Custom fields are "name: value" lines of fields column.
+func exportCSV(w io.Writer, records []entity.Record) ([]Skipped, error) {
+	var skipped []Skipped
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		entry := decodeExported(record)
+		if entry.skipped != nil {
+			skipped = append(skipped, *entry.skipped)
+			continue
+		}
+
+		row := make(map[string]string, len(csvHeader))
+		row["name"], row["folder"], row["tags"] = title(record), record.Folder, strings.Join(record.Tags, ",")
+
+		switch {
+		case entry.login != nil:
+			row["type"], row["username"], row["password"] = "login", entry.login.Login, entry.login.Password
+			row["url"], row["notes"], row["fields"] = entry.login.URL, entry.login.Notes, fieldsText(entry.login.Fields)
+		case entry.text != nil:
+			row["type"], row["notes"] = "text", entry.text.Text
+		case entry.card != nil:
+			row["type"], row["card_number"], row["expiration"] = "card", entry.card.CardNumber, entry.card.ExpirationDate
+			row["cvc"], row["cardholder"], row["notes"] = entry.card.CVCCode, entry.card.Holder, entry.card.Notes
+		case entry.otp != nil:
+			row["type"], row["totp"] = "otp", entry.otp.URI
+		default:
+			skipped = append(skipped, Skipped{Name: RecordName(record), Reason: "files can't be exported to CSV"})
+			continue
+		}
+
+		values := make([]string, len(csvHeader))
+		for i, column := range csvHeader {
+			values[i] = row[column]
+		}
+
+		if err := writer.Write(values); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+
+	return skipped, writer.Error()
+}
@@ -0,0 +1,69 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+)
+
+func TestParseCSV(t *testing.T) {
+	t.Log("Bitwarden CSV")
+	result, err := Parse(FormatCSV, []byte("\xef\xbb\xbf"+
+		"folder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n"+
+		"Work,1,login,Mail,,\"PIN: 1234\",0,https://mail.example.com,user,secret,\n"+
+		",,note,Note,text,,0,,,,\n"+
+		",,login,,,,0,,,,\n"), Options{Folder: "imported"})
+	require.NoError(t, err)
+	require.Len(t, result.Records, 3)
+
+	assert.Equal(t, "imported/Work", result.Records[0].Folder)
+	payload, err := entity.DecodePayload(result.Records[0])
+	require.NoError(t, err)
+	assert.Equal(t, &entity.LoginAndPassword{
+		Login:    "user",
+		Password: "secret",
+		URL:      "https://mail.example.com",
+		Fields:   []entity.CustomField{{Name: "PIN", Value: "1234"}},
+	}, payload)
+
+	payload, err = entity.DecodePayload(result.Records[1])
+	require.NoError(t, err)
+	assert.Equal(t, &entity.TextData{Text: "text"}, payload)
+
+	assert.Equal(t, "row 4", result.Records[2].Name)
+
+	t.Log("Generic CSV with card, OTP and unknown columns")
+	result, err = Parse(FormatCSV, []byte(
+		"Title,Card Number,Expiration,CVV,Cardholder,Tags,Branch,OTPAuth\n"+
+			"Visa,4111111111111111,03/30,123,John Doe,bank;cards,Main,\n"+
+			"2FA,,,,,,,otpauth://totp/Example:user?secret=JBSWY3DPEHPK3PXP\n"+
+			"Broken,,,,,,,not-base32!\n"+
+			",,,,,,,\n"), Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Records, 2)
+
+	assert.Equal(t, []string{"bank", "cards"}, result.Records[0].Tags)
+	payload, err = entity.DecodePayload(result.Records[0])
+	require.NoError(t, err)
+	assert.Equal(t, &entity.CreditCard{
+		CardNumber:     "4111111111111111",
+		ExpirationDate: "03/30",
+		CVCCode:        "123",
+		Holder:         "John Doe",
+		Notes:          "Branch: Main",
+	}, payload)
+
+	assert.Equal(t, entity.TypeOTP, result.Records[1].Type)
+	assert.Equal(t, "Example user", result.Records[1].Metadata)
+
+	require.Len(t, result.Skipped, 2)
+	assert.Equal(t, "Broken", result.Skipped[0].Name)
+	assert.Equal(t, Skipped{Name: "row 5", Reason: "empty entry"}, result.Skipped[1])
+
+	t.Log("No known columns")
+	_, err = Parse(FormatCSV, []byte("a,b\n1,2\n"), Options{})
+	assert.ErrorIs(t, err, ErrBadExport)
+}
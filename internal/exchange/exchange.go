@@ -0,0 +1,340 @@
+// Package exchange converts exports of other password managers to records: KeePass (KDBX 4),
+// Bitwarden (unencrypted JSON), 1Password (1PUX) and generic CSV, and exports records
+// to KeePass, Bitwarden and CSV.
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+	"github.com/bbt-t/lets-go-keep/pkg"
+)
+
+// Format is format of export.
+type Format string
+
+// Supported formats.
+const (
+	FormatKDBX      Format = "kdbx"
+	FormatBitwarden Format = "bitwarden"
+	Format1PUX      Format = "1pux"
+	FormatCSV       Format = "csv"
+)
+
+// Errors of import.
+var (
+	ErrUnknownFormat   = errors.New("unknown export format, use kdbx, bitwarden, 1pux or csv")
+	ErrBadExport       = errors.New("export is malformed")
+	ErrWrongPassword   = errors.New("wrong password of export or export is corrupted")
+	ErrEncryptedExport = errors.New("export is encrypted, export it without encryption")
+	ErrUnsupported     = errors.New("export uses unsupported encryption or compression")
+	ErrTooLarge        = errors.New("export is too large")
+)
+
+// maxUnpackedSize limits decompressed content of export, so small archive can't exhaust memory.
+// Tests lower it.
+var maxUnpackedSize int64 = 1 << 30
+
+// Options are options of import.
+type Options struct {
+	// Password opens KeePass database.
+	Password string
+	// Folder is parent folder of all imported records, e.g. "imported".
+	Folder string
+}
+
+// Skipped is entry of export, which isn't imported, with reason.
+type Skipped struct {
+	Name   string
+	Reason string
+}
+
+// Result is records converted from export. Data of records are encoded payloads,
+// data of file records are contents of attachments, so records can be created as is.
+type Result struct {
+	Records []entity.Record
+	Skipped []Skipped
+}
+
+// Report is result of bulk creation of records.
+type Report struct {
+	Created int
+	Failed  []Skipped
+}
+
+// RecordCreator creates records, it's client handlers.
+type RecordCreator interface {
+	CreateRecord(record entity.Record) (string, error)
+}
+
+// ParseFormat gets format by name.
+func ParseFormat(name string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(name))); format {
+	case FormatKDBX, FormatBitwarden, Format1PUX, FormatCSV:
+		return format, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// DetectFormat gets format by extension of export file.
+func DetectFormat(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".kdbx":
+		return FormatKDBX, nil
+	case ".json":
+		return FormatBitwarden, nil
+	case ".1pux":
+		return Format1PUX, nil
+	case ".csv":
+		return FormatCSV, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// Parse converts export to records. Records are put to options folder, their tags and folders are normalized.
+func Parse(format Format, data []byte, options Options) (Result, error) {
+	var (
+		result Result
+		err    error
+	)
+
+	switch format {
+	case FormatKDBX:
+		result, err = parseKDBX(data, options.Password)
+	case FormatBitwarden:
+		result, err = parseBitwarden(data)
+	case Format1PUX:
+		result, err = parse1PUX(data)
+	case FormatCSV:
+		result, err = parseCSV(data)
+	default:
+		err = ErrUnknownFormat
+	}
+
+	if err != nil {
+		return Result{}, err
+	}
+
+	for i, record := range result.Records {
+		result.Records[i].Folder = entity.NormalizeFolder(options.Folder + "/" + record.Folder)
+		result.Records[i].Tags = entity.NormalizeTags(record.Tags)
+	}
+
+	return result, nil
+}
+
+// Summary counts records by type.
+func (r Result) Summary() map[entity.RecordType]int {
+	summary := make(map[entity.RecordType]int)
+	for _, record := range r.Records {
+		summary[record.Type]++
+	}
+
+	return summary
+}
+
+// Import creates records one by one. Records, which are rejected, are reported as failed and import goes on,
+// but it stops, if session is expired, server is unavailable or key is wrong, because the rest would fail too.
+func Import(creator RecordCreator, records []entity.Record) (Report, error) {
+	var report Report
+
+	for _, record := range records {
+		_, err := creator.CreateRecord(record)
+
+		switch {
+		case err == nil:
+			report.Created++
+		case Fatal(err):
+			return report, err
+		default:
+			report.Failed = append(report.Failed, Skipped{Name: RecordName(record), Reason: err.Error()})
+		}
+	}
+
+	return report, nil
+}
+
+// Fatal checks if error stops import, export or restore, because the rest would fail too.
+func Fatal(err error) bool {
+	return errors.Is(err, storage.ErrUnauthenticated) || errors.Is(err, controller.ErrServerUnavailable) ||
+		errors.Is(err, controller.ErrLocked) || errors.Is(err, controller.ErrWrongMasterKey)
+}
+
+// recordName gets name of record for reports.
+func RecordName(record entity.Record) string {
+	if record.Type == entity.TypeFile && record.Name != record.Metadata {
+		return fmt.Sprintf("%s (%s)", record.Name, record.Metadata)
+	}
+
+	return record.Name
+}
+
+// item is entry of export, which becomes one or more records with the same name, folder and tags.
+type item struct {
+	name   string
+	folder string
+	tags   []string
+}
+
+// add adds record with payload. Returns false, if payload can't be encoded.
+func (r *Result) add(it item, recordType entity.RecordType, payload entity.Payload) bool {
+	data, err := payload.Bytes()
+	if err != nil {
+		r.skip(it.name, err.Error())
+		return false
+	}
+
+	r.Records = append(r.Records, entity.Record{
+		Type:   recordType,
+		Name:   it.name,
+		Folder: it.folder,
+		Tags:   it.tags,
+		Data:   data,
+	})
+
+	return true
+}
+
+// addLogin adds login record.
+func (r *Result) addLogin(it item, login entity.LoginAndPassword) {
+	r.add(it, entity.TypeLoginAndPassword, &login)
+}
+
+// addText adds text record. Entry without text is skipped.
+func (r *Result) addText(it item, text string) {
+	if strings.TrimSpace(text) == "" {
+		r.skip(it.name, "empty entry")
+		return
+	}
+
+	r.add(it, entity.TypeText, &entity.TextData{Text: text})
+}
+
+// addCard adds credit card record.
+func (r *Result) addCard(it item, card entity.CreditCard) {
+	r.add(it, entity.TypeCreditCard, &card)
+}
+
+// addOTP adds OTP record. Value is otpauth:// URI or bare base32 secret of TOTP.
+func (r *Result) addOTP(it item, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+
+	if !strings.HasPrefix(strings.ToLower(value), "otpauth://") {
+		u := url.URL{
+			Scheme:   "otpauth",
+			Host:     pkg.OTPTypeTOTP,
+			Path:     "/" + it.name,
+			RawQuery: url.Values{"secret": {strings.ReplaceAll(value, " ", "")}}.Encode(),
+		}
+		value = u.String()
+	}
+
+	key, err := pkg.ParseOTPURI(value)
+	if err != nil {
+		r.skip(it.name, "one-time password: "+err.Error())
+		return
+	}
+
+	if r.add(it, entity.TypeOTP, &entity.OTPData{URI: value}) {
+		r.Records[len(r.Records)-1].Metadata = strings.TrimSpace(key.Issuer + " " + key.Account)
+	}
+}
+
+// addFile adds file record with attachment. Metadata of file records is file name, files are saved by it,
+// so only base name is kept.
+func (r *Result) addFile(it item, fileName string, content []byte) {
+	fileName = filepath.Base(filepath.Clean("/" + strings.ReplaceAll(fileName, "\\", "/")))
+	if fileName == "/" || fileName == "." {
+		fileName = "attachment"
+	}
+
+	r.Records = append(r.Records, entity.Record{
+		Type:     entity.TypeFile,
+		Name:     it.name,
+		Metadata: fileName,
+		Folder:   it.folder,
+		Tags:     it.tags,
+		Data:     content,
+	})
+}
+
+// skip records entry, which isn't imported.
+func (r *Result) skip(name, reason string) {
+	r.Skipped = append(r.Skipped, Skipped{Name: name, Reason: reason})
+}
+
+// cardExpiration makes expiration date MM/YY of month and year.
+func cardExpiration(month, year string) string {
+	month, year = strings.TrimSpace(month), strings.TrimSpace(year)
+	if month == "" && year == "" {
+		return ""
+	}
+
+	if len(month) == 1 {
+		month = "0" + month
+	}
+	if len(year) == 4 {
+		year = year[2:]
+	}
+
+	return month + "/" + year
+}
+
+// fieldsText makes text of custom fields, one "name: value" per line.
+func fieldsText(fields []entity.CustomField) string {
+	lines := make([]string, 0, len(fields))
+	for _, field := range fields {
+		lines = append(lines, field.Name+": "+field.Value)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// joinText joins non-empty parts of text by empty lines.
+func joinText(parts ...string) string {
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+
+	return strings.Join(result, "\n\n")
+}
+
+// folderName makes name of folder, which can't be split to subfolders.
+func folderName(name string) string {
+	return strings.TrimSpace(strings.ReplaceAll(name, "/", "-"))
+}
+
+// readUnpacked reads decompressed content up to left bytes and subtracts its size from left.
+// If content is larger, left becomes negative, so later reads fail too.
+func readUnpacked(r io.Reader, left *int64) ([]byte, error) {
+	if *left < 0 {
+		return nil, ErrTooLarge
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, *left+1))
+	if err != nil {
+		return nil, err
+	}
+
+	*left -= int64(len(data))
+	if *left < 0 {
+		return nil, ErrTooLarge
+	}
+
+	return data, nil
+}
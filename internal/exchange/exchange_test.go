@@ -0,0 +1,88 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tc := map[string]Format{
+		"passwords.kdbx":  FormatKDBX,
+		"bitwarden.JSON":  FormatBitwarden,
+		"1password.1pux":  Format1PUX,
+		"/tmp/export.csv": FormatCSV,
+	}
+
+	for path, expected := range tc {
+		format, err := DetectFormat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, format)
+	}
+
+	_, err := DetectFormat("export.txt")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	format, err := ParseFormat(" Bitwarden ")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatBitwarden, format)
+
+	_, err = ParseFormat("lastpass")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestImport(t *testing.T) {
+	records := []entity.Record{
+		{Name: "first", Type: entity.TypeText},
+		{Name: "key", Metadata: "key.pem", Type: entity.TypeFile},
+		{Name: "third", Type: entity.TypeText},
+	}
+
+	tc := []struct {
+		name  string
+		mock  func(client *mocks.ClientHandlers)
+		valid func(report Report, err error)
+	}{
+		{
+			name: "Rejected record is reported and import goes on",
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("CreateRecord", records[0]).Return("", nil).Once()
+				client.On("CreateRecord", records[1]).Return("", controller.ErrOffline).Once()
+				client.On("CreateRecord", records[2]).Return("", nil).Once()
+			},
+			valid: func(report Report, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, Report{
+					Created: 2,
+					Failed:  []Skipped{{Name: "key (key.pem)", Reason: controller.ErrOffline.Error()}},
+				}, report)
+			},
+		},
+		{
+			name: "Expired session stops import",
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("CreateRecord", records[0]).Return("", nil).Once()
+				client.On("CreateRecord", mock.Anything).Return("", storage.ErrUnauthenticated).Once()
+			},
+			valid: func(report Report, err error) {
+				assert.ErrorIs(t, err, storage.ErrUnauthenticated)
+				assert.Equal(t, Report{Created: 1}, report)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		client := mocks.NewClientHandlers(t)
+		test.mock(client)
+
+		test.valid(Import(client, records))
+	}
+}
@@ -0,0 +1,162 @@
+package exchange
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/pkg"
+)
+
+// ErrNoPassword means that KeePass database is exported without password.
+var ErrNoPassword = errors.New("password of KeePass database is empty")
+
+// RecordReader gets records with decrypted data, it's client handlers.
+type RecordReader interface {
+	GetRecordsInfo() ([]entity.Record, error)
+	GetRecord(recordID string) (entity.Record, error)
+	GetFile(recordID string, w io.Writer) (entity.Record, error)
+}
+
+// Collect gets records of user in folder (all records, if folder is empty) with decrypted data,
+// data of file records are contents of files. Records, which can't be got, are skipped,
+// but it stops, if session is expired, server is unavailable or key is wrong.
+func Collect(reader RecordReader, folder string) (Result, error) {
+	infos, err := reader.GetRecordsInfo()
+	if err != nil {
+		return Result{}, err
+	}
+
+	folder = entity.NormalizeFolder(folder)
+
+	var result Result
+	for _, info := range infos {
+		if !entity.InFolder(info.Folder, folder) {
+			continue
+		}
+
+		var record entity.Record
+		if info.Type == entity.TypeFile {
+			var buf bytes.Buffer
+
+			record, err = reader.GetFile(info.ID, &buf)
+			record.Data = buf.Bytes()
+		} else {
+			record, err = reader.GetRecord(info.ID)
+		}
+
+		if Fatal(err) {
+			return Result{}, err
+		}
+		if err != nil {
+			result.skip(RecordName(info), err.Error())
+			continue
+		}
+
+		result.Records = append(result.Records, record)
+	}
+
+	return result, nil
+}
+
+// Export writes records in format. KeePass database is encrypted by password of options.
+// Returns records, which the format can't keep, e.g. files in CSV.
+func Export(w io.Writer, format Format, records []entity.Record, options Options) ([]Skipped, error) {
+	switch format {
+	case FormatKDBX:
+		if options.Password == "" {
+			return nil, ErrNoPassword
+		}
+
+		return exportKDBX(w, records, options.Password)
+	case FormatBitwarden:
+		return exportBitwarden(w, records)
+	case FormatCSV:
+		return exportCSV(w, records)
+	case Format1PUX:
+		return nil, fmt.Errorf("%w: export to 1PUX", ErrUnsupported)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// exported is record with decoded payload, which is converted to entry of export.
+type exported struct {
+	record  entity.Record
+	login   *entity.LoginAndPassword
+	text    *entity.TextData
+	card    *entity.CreditCard
+	otp     *entity.OTPData
+	skipped *Skipped
+}
+
+// decodeExported decodes payload of record. Record with malformed data is skipped.
+func decodeExported(record entity.Record) exported {
+	result := exported{record: record}
+	if record.Type == entity.TypeFile {
+		return result
+	}
+
+	payload, err := entity.DecodePayload(record)
+	if err != nil {
+		result.skipped = &Skipped{Name: RecordName(record), Reason: err.Error()}
+		return result
+	}
+
+	switch p := payload.(type) {
+	case *entity.LoginAndPassword:
+		result.login = p
+	case *entity.TextData:
+		result.text = p
+	case *entity.CreditCard:
+		result.card = p
+	case *entity.OTPData:
+		result.otp = p
+	}
+
+	return result
+}
+
+// expirationPattern is expiration date of card: MM/YY, MMYY or MM/YYYY.
+var expirationPattern = regexp.MustCompile(`^(0[1-9]|1[0-2])[|/]?([0-9]{4}|[0-9]{2})$`)
+
+// splitExpiration gets month and four-digit year of card expiration date. Unknown format is month as is.
+func splitExpiration(expiration string) (string, string) {
+	match := expirationPattern.FindStringSubmatch(expiration)
+	if match == nil {
+		return expiration, ""
+	}
+
+	if len(match[2]) == 2 {
+		return match[1], "20" + match[2]
+	}
+
+	return match[1], match[2]
+}
+
+// newUUID generates random UUID (version 4).
+func newUUID() (string, error) {
+	b, err := pkg.GenerateRandom(16)
+	if err != nil {
+		return "", err
+	}
+
+	b[6], b[8] = b[6]&0x0f|0x40, b[8]&0x3f|0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// title gets title of exported entry: name of record, metadata, if there is no name, or ID.
+func title(record entity.Record) string {
+	switch {
+	case record.Name != "":
+		return record.Name
+	case record.Metadata != "":
+		return record.Metadata
+	default:
+		return record.ID
+	}
+}
@@ -0,0 +1,243 @@
+package exchange
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bbt-t/lets-go-keep/internal/controller"
+	"github.com/bbt-t/lets-go-keep/internal/controller/handlers/mocks"
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/internal/storage"
+)
+
+// testExportRecords makes records of all types like client handlers get them. Custom field of login is hidden or not.
+func testExportRecords(hidden bool) []entity.Record {
+	var result Result
+
+	work := item{name: "Mail", folder: "Work/Mail", tags: []string{"mail"}}
+	result.addLogin(work, entity.LoginAndPassword{
+		Login:    "user",
+		Password: "secret",
+		URL:      "https://mail.example.com",
+		Notes:    "work mail",
+		Fields:   []entity.CustomField{{Name: "PIN", Value: "1234", Hidden: hidden}},
+	})
+	result.addOTP(work, "otpauth://totp/Mail:user?secret=JBSWY3DPEHPK3PXP")
+	result.addText(item{name: "Note", tags: []string{"personal"}}, "text of note")
+	result.addCard(item{name: "Visa", folder: "Banks", tags: []string{"bank"}}, entity.CreditCard{
+		CardNumber:     "4111111111111111",
+		ExpirationDate: "03/30",
+		CVCCode:        "123",
+		Holder:         "John Doe",
+		Notes:          "debit",
+	})
+	result.addFile(item{name: "Key", folder: "Work", tags: []string{"ssh"}}, "id_rsa", []byte("private key"))
+
+	return result.Records
+}
+
+func TestExport(t *testing.T) {
+	iterations, memory := kdbxExportIterations, kdbxExportMemory
+	kdbxExportIterations, kdbxExportMemory = 1, 64*1024
+	defer func() {
+		kdbxExportIterations, kdbxExportMemory = iterations, memory
+	}()
+
+	records := testExportRecords(true)
+
+	withoutTags := func(records []entity.Record) []entity.Record {
+		result := make([]entity.Record, len(records))
+		for i, record := range records {
+			record.Tags = nil
+			result[i] = record
+		}
+
+		return result
+	}
+
+	tc := []struct {
+		name     string
+		format   Format
+		expected []entity.Record
+		skipped  []Skipped
+	}{
+		{
+			name:     "KeePass keeps all records",
+			format:   FormatKDBX,
+			expected: records,
+		},
+		{
+			name:     "Bitwarden hasn't files and tags",
+			format:   FormatBitwarden,
+			expected: withoutTags(records[:4]),
+			skipped:  []Skipped{{Name: "Key (id_rsa)", Reason: "files can't be exported to Bitwarden JSON"}},
+		},
+		{
+			name:     "CSV hasn't files and hidden fields",
+			format:   FormatCSV,
+			expected: testExportRecords(false)[:4],
+			skipped:  []Skipped{{Name: "Key (id_rsa)", Reason: "files can't be exported to CSV"}},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		var buf bytes.Buffer
+		skipped, err := Export(&buf, test.format, records, Options{Password: "master"})
+		require.NoError(t, err)
+		assert.Equal(t, test.skipped, skipped)
+
+		result, err := Parse(test.format, buf.Bytes(), Options{Password: "master"})
+		require.NoError(t, err)
+		assert.Empty(t, result.Skipped)
+		assert.ElementsMatch(t, test.expected, result.Records)
+	}
+
+	t.Log("KeePass database is encrypted by password")
+	var buf bytes.Buffer
+	_, err := Export(&buf, FormatKDBX, records, Options{Password: "master"})
+	require.NoError(t, err)
+	assert.NotContains(t, buf.String(), "secret")
+
+	_, err = Parse(FormatKDBX, buf.Bytes(), Options{Password: "wrong"})
+	assert.ErrorIs(t, err, ErrWrongPassword)
+
+	t.Log("KeePass database needs password")
+	_, err = Export(io.Discard, FormatKDBX, records, Options{})
+	assert.ErrorIs(t, err, ErrNoPassword)
+
+	t.Log("1Password exports can't be written")
+	_, err = Export(io.Discard, Format1PUX, records, Options{})
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestExportKDBX(t *testing.T) {
+	iterations, memory := kdbxExportIterations, kdbxExportMemory
+	kdbxExportIterations, kdbxExportMemory = 1, 64*1024
+	defer func() {
+		kdbxExportIterations, kdbxExportMemory = iterations, memory
+	}()
+
+	var buf bytes.Buffer
+	_, err := Export(&buf, FormatKDBX, testExportRecords(true), Options{Password: "master"})
+	require.NoError(t, err)
+
+	t.Log("Exported database is read by reader of tests, not by parser")
+	file := openTestKDBX(t, buf.Bytes(), "master")
+	assert.Equal(t, "GophKeeper", file.root.child("Meta").child("Generator").text)
+	assert.ElementsMatch(t, []testKDBXEntry{
+		{
+			Folder: "Work/Mail",
+			Tags:   "mail",
+			Strings: map[string]string{
+				"Title": "Mail", "UserName": "user", "Password": "secret", "URL": "https://mail.example.com",
+				"Notes": "work mail", "PIN": "1234",
+			},
+			Protected: []string{"Password", "PIN"},
+		},
+		{
+			Folder:    "Work/Mail",
+			Tags:      "mail",
+			Strings:   map[string]string{"Title": "Mail", "otp": "otpauth://totp/Mail:user?secret=JBSWY3DPEHPK3PXP"},
+			Protected: []string{"otp"},
+		},
+		{
+			Tags:    "personal",
+			Strings: map[string]string{"Title": "Note", "Notes": "text of note"},
+		},
+		{
+			Folder: "Banks",
+			Tags:   "bank",
+			Strings: map[string]string{
+				"Title": "Visa", "Card number": "4111111111111111", "Expiration": "03/30", "CVC": "123",
+				"Cardholder": "John Doe", "Notes": "debit",
+			},
+			Protected: []string{"Card number", "CVC"},
+		},
+		{
+			Folder:   "Work",
+			Tags:     "ssh",
+			Strings:  map[string]string{"Title": "Key"},
+			Binaries: map[string]string{"id_rsa": "private key"},
+		},
+	}, file.entries(t))
+}
+
+func TestCollect(t *testing.T) {
+	infos := []entity.Record{
+		{ID: "1", Name: "Mail", Type: entity.TypeLoginAndPassword, Folder: "Work/Mail"},
+		{ID: "2", Name: "Key", Metadata: "id_rsa", Type: entity.TypeFile, Folder: "Work"},
+		{ID: "3", Name: "Note", Type: entity.TypeText},
+		{ID: "4", Name: "Broken", Type: entity.TypeText, Folder: "Work"},
+	}
+
+	tc := []struct {
+		name   string
+		folder string
+		mock   func(client *mocks.ClientHandlers)
+		valid  func(result Result, err error)
+	}{
+		{
+			name:   "Records of folder with contents of files",
+			folder: "Work",
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("GetRecordsInfo").Return(infos, nil).Once()
+				client.On("GetRecord", "1").Return(entity.Record{ID: "1", Data: []byte("login")}, nil).Once()
+				client.On("GetFile", "2", mock.Anything).Run(func(args mock.Arguments) {
+					_, _ = args.Get(1).(io.Writer).Write([]byte("private key"))
+				}).Return(entity.Record{ID: "2", Type: entity.TypeFile}, nil).Once()
+				client.On("GetRecord", "4").Return(entity.Record{}, controller.ErrWrongMasterKey).Once()
+			},
+			valid: func(result Result, err error) {
+				assert.ErrorIs(t, err, controller.ErrWrongMasterKey)
+			},
+		},
+		{
+			name: "Records, which can't be got, are skipped",
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("GetRecordsInfo").Return(infos, nil).Once()
+				client.On("GetRecord", "1").Return(entity.Record{ID: "1", Data: []byte("login")}, nil).Once()
+				client.On("GetFile", "2", mock.Anything).Run(func(args mock.Arguments) {
+					_, _ = args.Get(1).(io.Writer).Write([]byte("private key"))
+				}).Return(entity.Record{ID: "2", Type: entity.TypeFile}, nil).Once()
+				client.On("GetRecord", "3").Return(entity.Record{}, storage.ErrNotFound).Once()
+				client.On("GetRecord", "4").Return(entity.Record{ID: "4"}, nil).Once()
+			},
+			valid: func(result Result, err error) {
+				assert.NoError(t, err)
+				assert.Equal(t, Result{
+					Records: []entity.Record{
+						{ID: "1", Data: []byte("login")},
+						{ID: "2", Type: entity.TypeFile, Data: []byte("private key")},
+						{ID: "4"},
+					},
+					Skipped: []Skipped{{Name: "Note", Reason: storage.ErrNotFound.Error()}},
+				}, result)
+			},
+		},
+		{
+			name: "Expired session",
+			mock: func(client *mocks.ClientHandlers) {
+				client.On("GetRecordsInfo").Return(nil, storage.ErrUnauthenticated).Once()
+			},
+			valid: func(result Result, err error) {
+				assert.ErrorIs(t, err, storage.ErrUnauthenticated)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		client := mocks.NewClientHandlers(t)
+		test.mock(client)
+
+		test.valid(Collect(client, test.folder))
+	}
+}
@@ -0,0 +1,1183 @@
+package exchange
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/twofish"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/pkg"
+)
+
+// KDBX 4 file: signatures and version, header of TLV fields, SHA-256 and HMAC-SHA-256 of header,
+// then encrypted (and maybe gzipped) payload in HMAC blocks. Payload is inner header and XML,
+// protected values of XML are encrypted by inner stream cipher in document order.
+const (
+	kdbxSignature1 = 0x9AA2D903
+	kdbxSignature2 = 0xB54BFB67
+	kdbxVersion4   = 4
+)
+
+// Fields of header.
+const (
+	kdbxEndOfHeader   = 0
+	kdbxCipherID      = 2
+	kdbxCompression   = 3
+	kdbxMasterSeed    = 4
+	kdbxEncryptionIV  = 7
+	kdbxKDFParameters = 11
+)
+
+// Fields of inner header.
+const (
+	kdbxInnerEnd       = 0
+	kdbxInnerStreamID  = 1
+	kdbxInnerStreamKey = 2
+	kdbxInnerBinary    = 3
+
+	kdbxChaCha20Stream = 3
+)
+
+// Types of variant dictionary values, which KDF parameters are.
+const (
+	kdbxVariantVersion = 0x0100
+	kdbxVariantEnd     = 0x00
+	kdbxVariantUint32  = 0x04
+	kdbxVariantUint64  = 0x05
+	kdbxVariantBool    = 0x08
+	kdbxVariantInt32   = 0x0C
+	kdbxVariantInt64   = 0x0D
+	kdbxVariantString  = 0x18
+	kdbxVariantBytes   = 0x42
+)
+
+// UUIDs of ciphers and KDFs.
+var (
+	kdbxAES256   = kdbxUUID("31c1f2e6bf714350be5805216afc5aff")
+	kdbxChaCha20 = kdbxUUID("d6038a2b8b6f4cb5a524339a31dbb59a")
+	kdbxTwofish  = kdbxUUID("ad68f29f576f4bb9a36ad47af965346c")
+	kdbxAESKDF   = kdbxUUID("c9d9f39a628a4460bf740d08c18a4fea")
+	kdbxArgon2d  = kdbxUUID("ef636ddf8c29444b91f7a9a403e30a0c")
+	kdbxArgon2id = kdbxUUID("9e298b1956db4773b23dfc3ec6f0a1e6")
+)
+
+// kdbxHeader is outer header of KDBX 4.
+type kdbxHeader struct {
+	cipher     string
+	compressed bool
+	masterSeed []byte
+	iv         []byte
+	kdf        map[string]interface{}
+}
+
+// kdbxNode is element of XML. Protected values are already decrypted.
+type kdbxNode struct {
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*kdbxNode
+}
+
+// parseKDBX decrypts KeePass database KDBX 4 by password. Key files aren't supported.
+// Groups become folders, recycle bin and history of entries are skipped.
+func parseKDBX(data []byte, password string) (Result, error) {
+	header, size, err := readKDBXHeader(data)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if len(data) < size+2*sha256.Size {
+		return Result{}, fmt.Errorf("%w: truncated header", ErrBadExport)
+	}
+
+	if hash := sha256.Sum256(data[:size]); !hmac.Equal(hash[:], data[size:size+sha256.Size]) {
+		return Result{}, fmt.Errorf("%w: header checksum mismatch", ErrBadExport)
+	}
+
+	transformed, err := kdbxTransformKey(header.kdf, kdbxCompositeKey(password))
+	if err != nil {
+		return Result{}, err
+	}
+
+	encryptionKey, hmacKey := kdbxKeys(header.masterSeed, transformed)
+	if !hmac.Equal(kdbxHMAC(hmacKey, math.MaxUint64, data[:size]), data[size+sha256.Size:size+2*sha256.Size]) {
+		return Result{}, ErrWrongPassword
+	}
+
+	payload, err := readKDBXBlocks(data[size+2*sha256.Size:], hmacKey)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if payload, err = kdbxDecrypt(header, encryptionKey, payload); err != nil {
+		return Result{}, err
+	}
+
+	if header.compressed {
+		reader, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return Result{}, fmt.Errorf("%w: %v", ErrBadExport, err)
+		}
+
+		left := maxUnpackedSize
+		if payload, err = readUnpacked(reader, &left); errors.Is(err, ErrTooLarge) {
+			return Result{}, err
+		}
+		if err != nil {
+			return Result{}, fmt.Errorf("%w: %v", ErrBadExport, err)
+		}
+	}
+
+	stream, binaries, document, err := readKDBXInnerHeader(payload)
+	if err != nil {
+		return Result{}, err
+	}
+
+	root, err := parseKDBXXML(document, stream)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return convertKDBX(root, binaries), nil
+}
+
+// readKDBXHeader reads outer header. Returns header and its size.
+func readKDBXHeader(data []byte) (kdbxHeader, int, error) {
+	var header kdbxHeader
+
+	if len(data) < 12 || binary.LittleEndian.Uint32(data[0:4]) != kdbxSignature1 ||
+		binary.LittleEndian.Uint32(data[4:8]) != kdbxSignature2 {
+		return header, 0, fmt.Errorf("%w: it isn't KeePass database", ErrBadExport)
+	}
+
+	if version := binary.LittleEndian.Uint16(data[10:12]); version != kdbxVersion4 {
+		return header, 0, fmt.Errorf("%w: KDBX %d, save database as KDBX 4", ErrUnsupported, version)
+	}
+
+	offset := 12
+	for {
+		if len(data) < offset+5 {
+			return header, 0, fmt.Errorf("%w: truncated header", ErrBadExport)
+		}
+
+		id, size := data[offset], int(binary.LittleEndian.Uint32(data[offset+1:offset+5]))
+		offset += 5
+
+		if size < 0 || len(data) < offset+size {
+			return header, 0, fmt.Errorf("%w: truncated header", ErrBadExport)
+		}
+
+		value := data[offset : offset+size]
+		offset += size
+
+		switch id {
+		case kdbxEndOfHeader:
+			if header.masterSeed == nil || header.iv == nil || header.kdf == nil {
+				return header, 0, fmt.Errorf("%w: incomplete header", ErrBadExport)
+			}
+
+			return header, offset, nil
+		case kdbxCipherID:
+			header.cipher = string(value)
+		case kdbxCompression:
+			if len(value) != 4 || binary.LittleEndian.Uint32(value) > 1 {
+				return header, 0, fmt.Errorf("%w: compression", ErrUnsupported)
+			}
+			header.compressed = binary.LittleEndian.Uint32(value) == 1
+		case kdbxMasterSeed:
+			header.masterSeed = value
+		case kdbxEncryptionIV:
+			header.iv = value
+		case kdbxKDFParameters:
+			kdf, err := readVariantDictionary(value)
+			if err != nil {
+				return header, 0, err
+			}
+			header.kdf = kdf
+		}
+	}
+}
+
+// readVariantDictionary reads dictionary of typed values.
+func readVariantDictionary(data []byte) (map[string]interface{}, error) {
+	errBad := fmt.Errorf("%w: bad KDF parameters", ErrBadExport)
+
+	if len(data) < 2 || binary.LittleEndian.Uint16(data)&0xFF00 > kdbxVariantVersion&0xFF00 {
+		return nil, errBad
+	}
+
+	dictionary, offset := make(map[string]interface{}), 2
+	for {
+		if len(data) < offset+1 {
+			return nil, errBad
+		}
+
+		kind := data[offset]
+		offset++
+
+		if kind == kdbxVariantEnd {
+			return dictionary, nil
+		}
+
+		var fields [2][]byte
+		for i := range fields {
+			if len(data) < offset+4 {
+				return nil, errBad
+			}
+
+			size := int(binary.LittleEndian.Uint32(data[offset:]))
+			offset += 4
+
+			if size < 0 || len(data) < offset+size {
+				return nil, errBad
+			}
+
+			fields[i] = data[offset : offset+size]
+			offset += size
+		}
+
+		key, value := string(fields[0]), fields[1]
+
+		switch {
+		case kind == kdbxVariantUint32 && len(value) == 4:
+			dictionary[key] = uint64(binary.LittleEndian.Uint32(value))
+		case kind == kdbxVariantUint64 && len(value) == 8:
+			dictionary[key] = binary.LittleEndian.Uint64(value)
+		case kind == kdbxVariantInt32 && len(value) == 4:
+			dictionary[key] = int64(int32(binary.LittleEndian.Uint32(value)))
+		case kind == kdbxVariantInt64 && len(value) == 8:
+			dictionary[key] = int64(binary.LittleEndian.Uint64(value))
+		case kind == kdbxVariantBool && len(value) == 1:
+			dictionary[key] = value[0] != 0
+		case kind == kdbxVariantString:
+			dictionary[key] = string(value)
+		case kind == kdbxVariantBytes:
+			dictionary[key] = value
+		default:
+			return nil, errBad
+		}
+	}
+}
+
+// kdbxCompositeKey makes composite key of password.
+func kdbxCompositeKey(password string) []byte {
+	hash := sha256.Sum256([]byte(password))
+	composite := sha256.Sum256(hash[:])
+
+	return composite[:]
+}
+
+// kdbxTransformKey derives key of composite key by KDF of header: AES-KDF, Argon2d or Argon2id.
+func kdbxTransformKey(kdf map[string]interface{}, composite []byte) ([]byte, error) {
+	uuid, _ := kdf["$UUID"].([]byte)
+	salt, _ := kdf["S"].([]byte)
+
+	switch string(uuid) {
+	case kdbxAESKDF:
+		rounds, ok := kdf["R"].(uint64)
+		if !ok || len(salt) != 32 {
+			return nil, fmt.Errorf("%w: bad AES-KDF parameters", ErrBadExport)
+		}
+		if rounds > kdbxMaxAESRounds {
+			return nil, fmt.Errorf("%w: too many AES-KDF rounds", ErrUnsupported)
+		}
+
+		block, err := aes.NewCipher(salt)
+		if err != nil {
+			return nil, err
+		}
+
+		key := append([]byte(nil), composite...)
+		for i := uint64(0); i < rounds; i++ {
+			block.Encrypt(key[:16], key[:16])
+			block.Encrypt(key[16:], key[16:])
+		}
+
+		hash := sha256.Sum256(key)
+
+		return hash[:], nil
+	case kdbxArgon2d, kdbxArgon2id:
+		parallelism, _ := kdf["P"].(uint64)
+		memory, _ := kdf["M"].(uint64)
+		iterations, _ := kdf["I"].(uint64)
+		version, _ := kdf["V"].(uint64)
+		secret, _ := kdf["K"].([]byte)
+		data, _ := kdf["A"].([]byte)
+
+		if version != argon2Version {
+			return nil, fmt.Errorf("%w: Argon2 version %#x", ErrUnsupported, version)
+		}
+		if parallelism == 0 || parallelism > math.MaxUint8 || iterations == 0 || iterations > math.MaxUint32 ||
+			memory/1024 == 0 || memory/1024 > math.MaxUint32 || len(salt) == 0 {
+			return nil, fmt.Errorf("%w: bad Argon2 parameters", ErrBadExport)
+		}
+		// Limits of master key derivation, so export can't take all memory or time.
+		if iterations > pkg.KDFMaxTime || memory/1024 > pkg.KDFMaxMemory || parallelism > pkg.KDFMaxThreads {
+			return nil, fmt.Errorf("%w: too expensive Argon2 parameters", ErrUnsupported)
+		}
+
+		mode := argon2d
+		if string(uuid) == kdbxArgon2id {
+			mode = argon2id
+		}
+
+		return argon2Key(mode, composite, salt, secret, data, uint32(iterations), uint32(memory/1024), uint8(parallelism), 32), nil
+	default:
+		return nil, fmt.Errorf("%w: key derivation function", ErrUnsupported)
+	}
+}
+
+// kdbxKeys derives key of cipher and key of HMAC blocks.
+func kdbxKeys(masterSeed, transformed []byte) ([]byte, []byte) {
+	encryptionKey := sha256.Sum256(append(append([]byte(nil), masterSeed...), transformed...))
+	hmacKey := sha512.Sum512(append(append(append([]byte(nil), masterSeed...), transformed...), 0x01))
+
+	return encryptionKey[:], hmacKey[:]
+}
+
+// kdbxHMAC makes HMAC-SHA-256 of data with key of block with index.
+func kdbxHMAC(hmacKey []byte, index uint64, data ...[]byte) []byte {
+	var buffer [8]byte
+	binary.LittleEndian.PutUint64(buffer[:], index)
+
+	blockKey := sha512.Sum512(append(buffer[:], hmacKey...))
+
+	mac := hmac.New(sha256.New, blockKey[:])
+	for _, part := range data {
+		mac.Write(part)
+	}
+
+	return mac.Sum(nil)
+}
+
+// readKDBXBlocks reads HMAC blocks of encrypted payload: HMAC, size and data. Last block is empty.
+func readKDBXBlocks(data, hmacKey []byte) ([]byte, error) {
+	var payload []byte
+
+	for index := uint64(0); ; index++ {
+		if len(data) < sha256.Size+4 {
+			return nil, fmt.Errorf("%w: truncated payload", ErrBadExport)
+		}
+
+		mac, size := data[:sha256.Size], data[sha256.Size:sha256.Size+4]
+		length := int(binary.LittleEndian.Uint32(size))
+		data = data[sha256.Size+4:]
+
+		if length < 0 || len(data) < length {
+			return nil, fmt.Errorf("%w: truncated payload", ErrBadExport)
+		}
+
+		var buffer [8]byte
+		binary.LittleEndian.PutUint64(buffer[:], index)
+
+		if !hmac.Equal(mac, kdbxHMAC(hmacKey, index, buffer[:], size, data[:length])) {
+			return nil, fmt.Errorf("%w: block %d is corrupted", ErrBadExport, index)
+		}
+
+		if length == 0 {
+			return payload, nil
+		}
+
+		payload = append(payload, data[:length]...)
+		data = data[length:]
+	}
+}
+
+// kdbxDecrypt decrypts payload by cipher of header: AES-256 or Twofish in CBC mode or ChaCha20.
+func kdbxDecrypt(header kdbxHeader, key, payload []byte) ([]byte, error) {
+	var block cipher.Block
+
+	switch header.cipher {
+	case kdbxChaCha20:
+		stream, err := chacha20.NewUnauthenticatedCipher(key, header.iv)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadExport, err)
+		}
+
+		plain := make([]byte, len(payload))
+		stream.XORKeyStream(plain, payload)
+
+		return plain, nil
+	case kdbxAES256:
+		block, _ = aes.NewCipher(key)
+	case kdbxTwofish:
+		block, _ = twofish.NewCipher(key)
+	default:
+		return nil, fmt.Errorf("%w: cipher", ErrUnsupported)
+	}
+
+	if len(header.iv) != block.BlockSize() || len(payload) == 0 || len(payload)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("%w: bad encrypted payload", ErrBadExport)
+	}
+
+	plain := make([]byte, len(payload))
+	cipher.NewCBCDecrypter(block, header.iv).CryptBlocks(plain, payload)
+
+	// PKCS #7 padding.
+	padding := int(plain[len(plain)-1])
+	if padding == 0 || padding > block.BlockSize() ||
+		!bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("%w: bad padding", ErrBadExport)
+	}
+
+	return plain[:len(plain)-padding], nil
+}
+
+// readKDBXInnerHeader reads inner header of payload. Returns cipher of protected values, attachments and XML.
+func readKDBXInnerHeader(payload []byte) (cipher.Stream, [][]byte, []byte, error) {
+	var (
+		streamID  uint32
+		streamKey []byte
+		binaries  [][]byte
+	)
+
+	for {
+		if len(payload) < 5 {
+			return nil, nil, nil, fmt.Errorf("%w: truncated inner header", ErrBadExport)
+		}
+
+		id, size := payload[0], int(binary.LittleEndian.Uint32(payload[1:5]))
+		payload = payload[5:]
+
+		if size < 0 || len(payload) < size {
+			return nil, nil, nil, fmt.Errorf("%w: truncated inner header", ErrBadExport)
+		}
+
+		value := payload[:size]
+		payload = payload[size:]
+
+		switch id {
+		case kdbxInnerEnd:
+			if streamID != kdbxChaCha20Stream {
+				return nil, nil, nil, fmt.Errorf("%w: inner stream cipher", ErrUnsupported)
+			}
+
+			hash := sha512.Sum512(streamKey)
+			stream, err := chacha20.NewUnauthenticatedCipher(hash[:32], hash[32:44])
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+			return stream, binaries, payload, nil
+		case kdbxInnerStreamID:
+			if len(value) == 4 {
+				streamID = binary.LittleEndian.Uint32(value)
+			}
+		case kdbxInnerStreamKey:
+			streamKey = value
+		case kdbxInnerBinary:
+			// First byte is flags of attachment.
+			if len(value) == 0 {
+				return nil, nil, nil, fmt.Errorf("%w: bad attachment", ErrBadExport)
+			}
+			binaries = append(binaries, value[1:])
+		}
+	}
+}
+
+// parseKDBXXML parses XML to tree. Protected values are decrypted by stream in document order,
+// so all of them are decrypted, history too.
+func parseKDBXXML(document []byte, stream cipher.Stream) (*kdbxNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	root := &kdbxNode{}
+	path := []*kdbxNode{root}
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrBadExport, err)
+		}
+
+		current := path[len(path)-1]
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &kdbxNode{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, attr := range t.Attr {
+				node.attrs[attr.Name.Local] = attr.Value
+			}
+
+			current.children = append(current.children, node)
+			path = append(path, node)
+		case xml.CharData:
+			current.text += string(t)
+		case xml.EndElement:
+			if strings.EqualFold(current.attrs["Protected"], "true") {
+				value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(current.text))
+				if err != nil {
+					return nil, fmt.Errorf("%w: bad protected value", ErrBadExport)
+				}
+
+				stream.XORKeyStream(value, value)
+				current.text = string(value)
+			}
+
+			path = path[:len(path)-1]
+		}
+	}
+
+	return root, nil
+}
+
+// convertKDBX converts entries of tree. Root group isn't folder.
+func convertKDBX(document *kdbxNode, binaries [][]byte) Result {
+	var result Result
+
+	file := document.child("KeePassFile")
+	recycleBin := file.child("Meta").child("RecycleBinUUID").text
+	if !strings.EqualFold(file.child("Meta").child("RecycleBinEnabled").text, "true") {
+		recycleBin = ""
+	}
+
+	var walk func(group *kdbxNode, folder []string)
+	walk = func(group *kdbxNode, folder []string) {
+		for _, node := range group.children {
+			switch node.name {
+			case "Group":
+				if recycleBin != "" && node.child("UUID").text == recycleBin {
+					continue
+				}
+				walk(node, append(folder[:len(folder):len(folder)], folderName(node.child("Name").text)))
+			case "Entry":
+				result.addKDBXEntry(node, strings.Join(folder, "/"), binaries)
+			}
+		}
+	}
+
+	walk(file.child("Root").child("Group"), nil)
+
+	return result
+}
+
+// addKDBXEntry adds records of entry. Entry with username, password or URL is login,
+// entry with card number is card, else it's text.
+func (r *Result) addKDBXEntry(entry *kdbxNode, folder string, binaries [][]byte) {
+	values := make(map[string]string)
+	var fields []entity.CustomField
+
+	for _, node := range entry.children {
+		if node.name != "String" {
+			continue
+		}
+
+		key, value := node.child("Key").text, node.child("Value")
+		switch {
+		case key == "Title" || key == "UserName" || key == "Password" || key == "URL" || key == "Notes" ||
+			key == "otp" || strings.HasPrefix(key, "TimeOtp-"):
+			values[key] = value.text
+		case value.text != "":
+			fields = append(fields, entity.CustomField{
+				Name:   key,
+				Value:  value.text,
+				Hidden: strings.EqualFold(value.attrs["Protected"], "true"),
+			})
+		}
+	}
+
+	it := item{
+		name:   values["Title"],
+		folder: folder,
+		tags:   strings.FieldsFunc(entry.child("Tags").text, func(r rune) bool { return r == ';' || r == ',' }),
+	}
+
+	otp := keepassOTP(it.name, values)
+	attachments := entry.all("Binary")
+	card, rest := kdbxCard(fields)
+
+	switch {
+	case values["UserName"] != "" || values["Password"] != "" || values["URL"] != "":
+		r.addLogin(it, entity.LoginAndPassword{
+			Login:    values["UserName"],
+			Password: values["Password"],
+			URL:      values["URL"],
+			Notes:    values["Notes"],
+			Fields:   fields,
+		})
+	case card.CardNumber != "":
+		card.Notes = joinText(values["Notes"], fieldsText(rest))
+		r.addCard(it, card)
+	case values["Notes"] != "" || len(fields) > 0 || (otp == "" && len(attachments) == 0):
+		r.addText(it, joinText(values["Notes"], fieldsText(fields)))
+	}
+
+	r.addOTP(it, otp)
+
+	for _, node := range attachments {
+		name, ref := node.child("Key").text, node.child("Value").attrs["Ref"]
+
+		index, err := strconv.Atoi(ref)
+		if err != nil || index < 0 || index >= len(binaries) {
+			r.skip(it.name, fmt.Sprintf("attachment %s isn't found in database", name))
+			continue
+		}
+
+		r.addFile(it, name, binaries[index])
+	}
+}
+
+// kdbxCard gets card of fields, which exportKDBX writes for card records. Returns card and other fields.
+func kdbxCard(fields []entity.CustomField) (entity.CreditCard, []entity.CustomField) {
+	var (
+		card entity.CreditCard
+		rest []entity.CustomField
+	)
+
+	for _, field := range fields {
+		switch field.Name {
+		case kdbxCardNumber:
+			card.CardNumber = field.Value
+		case kdbxCardExpiration:
+			card.ExpirationDate = field.Value
+		case kdbxCardCVC:
+			card.CVCCode = field.Value
+		case kdbxCardholder:
+			card.Holder = field.Value
+		default:
+			rest = append(rest, field)
+		}
+	}
+
+	return card, rest
+}
+
+// keepassOTP gets key of one-time passwords: otpauth:// URI of KeePassXC or TimeOtp-* strings of KeePass.
+func keepassOTP(name string, values map[string]string) string {
+	if values["otp"] != "" {
+		return values["otp"]
+	}
+
+	secret := values["TimeOtp-Secret-Base32"]
+	if secret == "" {
+		return ""
+	}
+
+	query := url.Values{"secret": {secret}}
+	if length := values["TimeOtp-Length"]; length != "" {
+		query.Set("digits", length)
+	}
+	if period := values["TimeOtp-Period"]; period != "" {
+		query.Set("period", period)
+	}
+	if algorithm := values["TimeOtp-Algorithm"]; algorithm != "" {
+		// KeePass names algorithms HMAC-SHA-256, otpauth:// names them SHA256.
+		query.Set("algorithm", strings.ReplaceAll(strings.TrimPrefix(algorithm, "HMAC-"), "-", ""))
+	}
+
+	u := url.URL{Scheme: "otpauth", Host: "totp", Path: "/" + name, RawQuery: query.Encode()}
+
+	return u.String()
+}
+
+// child gets first child with name. Missing child is empty node, so paths can be chained.
+func (n *kdbxNode) child(name string) *kdbxNode {
+	if n != nil {
+		for _, child := range n.children {
+			if child.name == name {
+				return child
+			}
+		}
+	}
+
+	return &kdbxNode{}
+}
+
+// all gets children with name.
+func (n *kdbxNode) all(name string) []*kdbxNode {
+	var result []*kdbxNode
+	for _, child := range n.children {
+		if child.name == name {
+			result = append(result, child)
+		}
+	}
+
+	return result
+}
+
+// kdbxUUID decodes UUID in hex.
+func kdbxUUID(s string) string {
+	uuid, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(uuid)
+}
+
+// kdbxMaxAESRounds limits rounds of AES-KDF, it's about several seconds of work.
+const kdbxMaxAESRounds = 100_000_000
+
+// Argon2d parameters of exported databases.
+var (
+	kdbxExportIterations  uint64 = 2
+	kdbxExportMemory      uint64 = 64 << 20
+	kdbxExportParallelism uint32 = 2
+)
+
+// kdbxBlockSize is size of HMAC blocks of exported databases.
+const kdbxBlockSize = 1 << 20
+
+// Strings of card entries.
+const (
+	kdbxCardNumber     = "Card number"
+	kdbxCardExpiration = "Expiration"
+	kdbxCardCVC        = "CVC"
+	kdbxCardholder     = "Cardholder"
+)
+
+// kdbxGenerator is name of program, which wrote database.
+const kdbxGenerator = "GophKeeper"
+
+// kdbxEpoch is start of KDBX 4 time: seconds are counted from 0001-01-01.
+var kdbxEpoch = time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// exportKDBX writes records as KeePass database encrypted by password. Folders become groups,
+// OTP keys are "otp" strings like KeePassXC keeps them, cards are entries with card fields,
+// files are entries with attachment.
+func exportKDBX(w io.Writer, records []entity.Record, password string) ([]Skipped, error) {
+	var (
+		skipped  []Skipped
+		binaries [][]byte
+	)
+
+	root, err := kdbxGroupNode(kdbxGenerator)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*kdbxNode{"": root}
+	for _, record := range records {
+		entry := decodeExported(record)
+		if entry.skipped != nil {
+			skipped = append(skipped, *entry.skipped)
+			continue
+		}
+
+		group, err := kdbxGroupOf(groups, record.Folder)
+		if err != nil {
+			return nil, err
+		}
+
+		node, err := kdbxEntryNode(entry, len(binaries))
+		if err != nil {
+			return nil, err
+		}
+		if record.Type == entity.TypeFile {
+			binaries = append(binaries, record.Data)
+		}
+
+		group.children = append(group.children, node)
+	}
+
+	document := kdbxElement("KeePassFile",
+		kdbxElement("Meta",
+			kdbxText("Generator", kdbxGenerator),
+			kdbxText("DatabaseName", kdbxGenerator),
+			kdbxText("RecycleBinEnabled", "False"),
+		),
+		kdbxElement("Root", root),
+	)
+
+	return skipped, writeKDBX(w, password, document, binaries)
+}
+
+// kdbxGroupOf gets group of folder, missing groups of path are created.
+func kdbxGroupOf(groups map[string]*kdbxNode, folder string) (*kdbxNode, error) {
+	if group, ok := groups[folder]; ok {
+		return group, nil
+	}
+
+	parentFolder, name := "", folder
+	if i := strings.LastIndex(folder, "/"); i >= 0 {
+		parentFolder, name = folder[:i], folder[i+1:]
+	}
+
+	parent, err := kdbxGroupOf(groups, parentFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := kdbxGroupNode(name)
+	if err != nil {
+		return nil, err
+	}
+
+	parent.children = append(parent.children, group)
+	groups[folder] = group
+
+	return group, nil
+}
+
+// kdbxGroupNode makes empty group with name.
+func kdbxGroupNode(name string) (*kdbxNode, error) {
+	uuid, err := kdbxRandomUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	return kdbxElement("Group", kdbxText("UUID", uuid), kdbxText("Name", name)), nil
+}
+
+// kdbxEntryNode makes entry of record. Attachment of file record is binary with index.
+func kdbxEntryNode(entry exported, binary int) (*kdbxNode, error) {
+	uuid, err := kdbxRandomUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	record := entry.record
+	modified := record.UpdatedAt
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+
+	node := kdbxElement("Entry",
+		kdbxText("UUID", uuid),
+		kdbxText("Tags", strings.Join(record.Tags, ";")),
+		kdbxElement("Times",
+			kdbxText("CreationTime", kdbxTime(modified)),
+			kdbxText("LastModificationTime", kdbxTime(modified)),
+		),
+		kdbxString("Title", title(record), false),
+	)
+
+	switch {
+	case entry.login != nil:
+		node.children = append(node.children,
+			kdbxString("UserName", entry.login.Login, false),
+			kdbxString("Password", entry.login.Password, true),
+			kdbxString("URL", entry.login.URL, false),
+			kdbxString("Notes", entry.login.Notes, false),
+		)
+		for _, field := range entry.login.Fields {
+			node.children = append(node.children, kdbxString(field.Name, field.Value, field.Hidden))
+		}
+	case entry.text != nil:
+		node.children = append(node.children, kdbxString("Notes", entry.text.Text, false))
+	case entry.card != nil:
+		node.children = append(node.children,
+			kdbxString(kdbxCardNumber, entry.card.CardNumber, true),
+			kdbxString(kdbxCardExpiration, entry.card.ExpirationDate, false),
+			kdbxString(kdbxCardCVC, entry.card.CVCCode, true),
+			kdbxString(kdbxCardholder, entry.card.Holder, false),
+			kdbxString("Notes", entry.card.Notes, false),
+		)
+	case entry.otp != nil:
+		node.children = append(node.children, kdbxString("otp", entry.otp.URI, true))
+	default:
+		reference := &kdbxNode{name: "Value", attrs: map[string]string{"Ref": strconv.Itoa(binary)}}
+		node.children = append(node.children, kdbxElement("Binary", kdbxText("Key", record.Metadata), reference))
+	}
+
+	return node, nil
+}
+
+// writeKDBX writes KDBX 4 database encrypted by AES-256 with key derived by Argon2d.
+// Protected values of document are plain, they are encrypted by inner stream here.
+func writeKDBX(w io.Writer, password string, document *kdbxNode, binaries [][]byte) error {
+	salt, err := pkg.GenerateRandom(32)
+	if err != nil {
+		return err
+	}
+
+	streamKey, err := pkg.GenerateRandom(64)
+	if err != nil {
+		return err
+	}
+
+	hash := sha512.Sum512(streamKey)
+	stream, err := chacha20.NewUnauthenticatedCipher(hash[:32], hash[32:44])
+	if err != nil {
+		return err
+	}
+
+	var inner bytes.Buffer
+	writeTLV(&inner, kdbxInnerStreamID, binary.LittleEndian.AppendUint32(nil, kdbxChaCha20Stream))
+	writeTLV(&inner, kdbxInnerStreamKey, streamKey)
+	for _, content := range binaries {
+		writeTLV(&inner, kdbxInnerBinary, append([]byte{0}, content...))
+	}
+	writeTLV(&inner, kdbxInnerEnd, nil)
+
+	inner.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&inner)
+	if err = encodeKDBXNode(encoder, document, stream); err != nil {
+		return err
+	}
+	if err = encoder.Flush(); err != nil {
+		return err
+	}
+
+	kdf := map[string]interface{}{
+		"$UUID": []byte(kdbxArgon2d),
+		"S":     salt,
+		"P":     kdbxExportParallelism,
+		"M":     kdbxExportMemory,
+		"I":     kdbxExportIterations,
+		"V":     uint32(argon2Version),
+	}
+
+	return sealKDBX(w, password, kdbxAES256, kdf, inner.Bytes())
+}
+
+// sealKDBX writes header and payload of KDBX 4: inner header and XML, which are gzipped and encrypted by cipher.
+func sealKDBX(w io.Writer, password, cipherID string, kdf map[string]interface{}, inner []byte) error {
+	ivSize := aes.BlockSize
+	if cipherID == kdbxChaCha20 {
+		ivSize = chacha20.NonceSize
+	}
+
+	seed, err := pkg.GenerateRandom(32)
+	if err != nil {
+		return err
+	}
+
+	iv, err := pkg.GenerateRandom(ivSize)
+	if err != nil {
+		return err
+	}
+
+	parameters := writeVariantDictionary(kdf)
+
+	var header bytes.Buffer
+	header.Write(binary.LittleEndian.AppendUint32(nil, kdbxSignature1))
+	header.Write(binary.LittleEndian.AppendUint32(nil, kdbxSignature2))
+	header.Write(binary.LittleEndian.AppendUint32(nil, kdbxVersion4<<16))
+	writeTLV(&header, kdbxCipherID, []byte(cipherID))
+	writeTLV(&header, kdbxCompression, binary.LittleEndian.AppendUint32(nil, 1))
+	writeTLV(&header, kdbxMasterSeed, seed)
+	writeTLV(&header, kdbxEncryptionIV, iv)
+	writeTLV(&header, kdbxKDFParameters, parameters)
+	writeTLV(&header, kdbxEndOfHeader, []byte("\r\n\r\n"))
+
+	// Key is derived from written parameters, values are typed like readers get them.
+	written, err := readVariantDictionary(parameters)
+	if err != nil {
+		return err
+	}
+
+	transformed, err := kdbxTransformKey(written, kdbxCompositeKey(password))
+	if err != nil {
+		return err
+	}
+	encryptionKey, hmacKey := kdbxKeys(seed, transformed)
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err = zw.Write(inner); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+
+	payload, err := kdbxEncrypt(cipherID, encryptionKey, iv, compressed.Bytes())
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(header.Bytes())
+	file := append(header.Bytes(), hash[:]...)
+	file = append(file, kdbxHMAC(hmacKey, math.MaxUint64, header.Bytes())...)
+
+	// HMAC blocks, last block is empty.
+	for index := uint64(0); ; index++ {
+		size := len(payload)
+		if size > kdbxBlockSize {
+			size = kdbxBlockSize
+		}
+
+		prefix := binary.LittleEndian.AppendUint64(nil, index)
+		prefix = binary.LittleEndian.AppendUint32(prefix, uint32(size))
+
+		file = append(file, kdbxHMAC(hmacKey, index, prefix, payload[:size])...)
+		file = append(file, prefix[8:]...)
+		file = append(file, payload[:size]...)
+
+		if size == 0 {
+			break
+		}
+		payload = payload[size:]
+	}
+
+	_, err = w.Write(file)
+
+	return err
+}
+
+// kdbxEncrypt encrypts payload by cipher: AES-256 or Twofish in CBC mode with PKCS #7 padding or ChaCha20.
+func kdbxEncrypt(cipherID string, key, iv, plain []byte) ([]byte, error) {
+	var block cipher.Block
+
+	switch cipherID {
+	case kdbxChaCha20:
+		stream, err := chacha20.NewUnauthenticatedCipher(key, iv)
+		if err != nil {
+			return nil, err
+		}
+
+		payload := make([]byte, len(plain))
+		stream.XORKeyStream(payload, plain)
+
+		return payload, nil
+	case kdbxAES256:
+		block, _ = aes.NewCipher(key)
+	case kdbxTwofish:
+		block, _ = twofish.NewCipher(key)
+	default:
+		return nil, fmt.Errorf("%w: cipher", ErrUnsupported)
+	}
+
+	padding := block.BlockSize() - len(plain)%block.BlockSize()
+	padded := append(append([]byte(nil), plain...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	payload := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(payload, padded)
+
+	return payload, nil
+}
+
+// encodeKDBXNode encodes node to XML. Protected values are encrypted by stream in document order.
+func encodeKDBXNode(encoder *xml.Encoder, node *kdbxNode, stream cipher.Stream) error {
+	start := xml.StartElement{Name: xml.Name{Local: node.name}}
+
+	keys := make([]string, 0, len(node.attrs))
+	for key := range node.attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: key}, Value: node.attrs[key]})
+	}
+
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+
+	text := node.text
+	if node.attrs["Protected"] == "True" {
+		value := []byte(text)
+		stream.XORKeyStream(value, value)
+		text = base64.StdEncoding.EncodeToString(value)
+	}
+
+	if text != "" {
+		if err := encoder.EncodeToken(xml.CharData(text)); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range node.children {
+		if err := encodeKDBXNode(encoder, child, stream); err != nil {
+			return err
+		}
+	}
+
+	return encoder.EncodeToken(start.End())
+}
+
+// writeTLV writes field of header: ID, size and value.
+func writeTLV(buffer *bytes.Buffer, id byte, value []byte) {
+	buffer.WriteByte(id)
+	buffer.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(value))))
+	buffer.Write(value)
+}
+
+// writeVariantDictionary writes dictionary of uint32, uint64 and bytes values sorted by keys.
+func writeVariantDictionary(dictionary map[string]interface{}) []byte {
+	keys := make([]string, 0, len(dictionary))
+	for key := range dictionary {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buffer bytes.Buffer
+	buffer.Write(binary.LittleEndian.AppendUint16(nil, kdbxVariantVersion))
+
+	for _, key := range keys {
+		var (
+			kind byte
+			data []byte
+		)
+
+		switch value := dictionary[key].(type) {
+		case uint32:
+			kind, data = kdbxVariantUint32, binary.LittleEndian.AppendUint32(nil, value)
+		case uint64:
+			kind, data = kdbxVariantUint64, binary.LittleEndian.AppendUint64(nil, value)
+		case []byte:
+			kind, data = kdbxVariantBytes, value
+		default:
+			continue
+		}
+
+		buffer.WriteByte(kind)
+		buffer.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(key))))
+		buffer.WriteString(key)
+		buffer.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+		buffer.Write(data)
+	}
+	buffer.WriteByte(kdbxVariantEnd)
+
+	return buffer.Bytes()
+}
+
+// kdbxElement makes element with children.
+func kdbxElement(name string, children ...*kdbxNode) *kdbxNode {
+	return &kdbxNode{name: name, children: children}
+}
+
+// kdbxText makes element with text.
+func kdbxText(name, text string) *kdbxNode {
+	return &kdbxNode{name: name, text: text}
+}
+
+// kdbxString makes string field of entry, protected value is encrypted in database.
+func kdbxString(key, value string, protected bool) *kdbxNode {
+	node := kdbxText("Value", value)
+	if protected {
+		node.attrs = map[string]string{"Protected": "True"}
+	}
+
+	return kdbxElement("String", kdbxText("Key", key), node)
+}
+
+// kdbxRandomUUID generates UUID of group or entry in base64.
+func kdbxRandomUUID() (string, error) {
+	uuid, err := pkg.GenerateRandom(16)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(uuid), nil
+}
+
+// kdbxTime encodes time: seconds from kdbxEpoch in base64.
+func kdbxTime(t time.Time) string {
+	seconds := t.Unix() - kdbxEpoch.Unix()
+
+	return base64.StdEncoding.EncodeToString(binary.LittleEndian.AppendUint64(nil, uint64(seconds)))
+}
@@ -0,0 +1,656 @@
+package exchange
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/twofish"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+)
+
+// testKDBXDocument has groups, recycle bin, protected values, history, attachment and OTP.
+const testKDBXDocument = `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta>
+		<RecycleBinEnabled>True</RecycleBinEnabled>
+		<RecycleBinUUID>cmVjeWNsZWJpbnV1aWQxMg==</RecycleBinUUID>
+	</Meta>
+	<Root>
+		<Group>
+			<UUID>cm9vdGdyb3VwdXVpZDEyMw==</UUID>
+			<Name>Database</Name>
+			<Entry>
+				<Tags>web;mail</Tags>
+				<String><Key>Title</Key><Value>Mail</Value></String>
+				<String><Key>UserName</Key><Value>user</Value></String>
+				<String><Key>Password</Key><Value Protected="True">secret</Value></String>
+				<String><Key>URL</Key><Value>https://mail.example.com</Value></String>
+				<String><Key>PIN</Key><Value Protected="True">1234</Value></String>
+				<String><Key>otp</Key><Value Protected="True">otpauth://totp/Mail:user?secret=JBSWY3DPEHPK3PXP</Value></String>
+				<Binary><Key>key.pem</Key><Value Ref="0" /></Binary>
+				<History>
+					<Entry>
+						<String><Key>Password</Key><Value Protected="True">old secret</Value></String>
+					</Entry>
+				</History>
+			</Entry>
+			<Group>
+				<UUID>YmFua2dyb3VwdXVpZDEyMw==</UUID>
+				<Name>Banks</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>Note</Value></String>
+					<String><Key>Notes</Key><Value Protected="True">text of note</Value></String>
+				</Entry>
+			</Group>
+			<Group>
+				<UUID>cmVjeWNsZWJpbnV1aWQxMg==</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry>
+					<String><Key>Title</Key><Value>Deleted</Value></String>
+					<String><Key>Password</Key><Value Protected="True">deleted</Value></String>
+				</Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`
+
+// testKDBXSignature is signatures and version 4.1 of KDBX file.
+var testKDBXSignature = []byte{0x03, 0xD9, 0xA2, 0x9A, 0x67, 0xFB, 0x4B, 0xB5, 0x01, 0x00, 0x04, 0x00}
+
+// testKDBX makes KDBX 4 database with password. Encoder of tests doesn't use code of exporter,
+// so parser and exporter are checked by format, not by each other.
+// Protected values of document are plain, they are encrypted here.
+func testKDBX(t *testing.T, password, cipherID string, kdf map[string]interface{}, document string, binaries [][]byte) []byte {
+	t.Helper()
+
+	seed, iv, streamKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 16), bytes.Repeat([]byte{3}, 64)
+	if cipherID == kdbxChaCha20 {
+		iv = iv[:12]
+	}
+
+	// Header: cipher, gzip compression, master seed, IV, KDF parameters and end of header.
+	var header bytes.Buffer
+	header.Write(testKDBXSignature)
+	testTLV(&header, 2, []byte(cipherID))
+	testTLV(&header, 3, []byte{1, 0, 0, 0})
+	testTLV(&header, 4, seed)
+	testTLV(&header, 7, iv)
+	testTLV(&header, 11, testVariantDictionary(kdf))
+	testTLV(&header, 0, []byte("\r\n\r\n"))
+
+	encryptionKey, hmacKey := testKDBXKeys(t, password, seed, kdf)
+
+	hash := sha256.Sum256(header.Bytes())
+	file := append(append([]byte(nil), header.Bytes()...), hash[:]...)
+	file = append(file, testKDBXHMAC(hmacKey, math.MaxUint64, header.Bytes())...)
+
+	// Inner header: ChaCha20 stream of protected values, its key and attachments, then XML.
+	stream := testInnerStream(t, streamKey)
+
+	protected := regexp.MustCompile(`(<Value Protected="True">)([^<]*)(</Value>)`)
+	document = protected.ReplaceAllStringFunc(document, func(match string) string {
+		parts := protected.FindStringSubmatch(match)
+		value := []byte(parts[2])
+		stream.XORKeyStream(value, value)
+
+		return parts[1] + base64.StdEncoding.EncodeToString(value) + parts[3]
+	})
+
+	var inner bytes.Buffer
+	testTLV(&inner, 1, []byte{3, 0, 0, 0})
+	testTLV(&inner, 2, streamKey)
+	for _, binary := range binaries {
+		testTLV(&inner, 3, append([]byte{0}, binary...))
+	}
+	testTLV(&inner, 0, nil)
+	inner.WriteString(document)
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	_, err := zw.Write(inner.Bytes())
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	// Encryption.
+	plain := compressed.Bytes()
+	var payload []byte
+	if cipherID == kdbxChaCha20 {
+		stream, err := chacha20.NewUnauthenticatedCipher(encryptionKey, iv)
+		require.NoError(t, err)
+
+		payload = make([]byte, len(plain))
+		stream.XORKeyStream(payload, plain)
+	} else {
+		var block cipher.Block
+		if cipherID == kdbxTwofish {
+			block, _ = twofish.NewCipher(encryptionKey)
+		} else {
+			block, _ = aes.NewCipher(encryptionKey)
+		}
+
+		padding := block.BlockSize() - len(plain)%block.BlockSize()
+		plain = append(plain, bytes.Repeat([]byte{byte(padding)}, padding)...)
+		payload = make([]byte, len(plain))
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(payload, plain)
+	}
+
+	// HMAC blocks: payload in two blocks and empty last block.
+	blocks := [][]byte{payload[:len(payload)/2], payload[len(payload)/2:], nil}
+	for index, block := range blocks {
+		var prefix [12]byte
+		binary.LittleEndian.PutUint64(prefix[:8], uint64(index))
+		binary.LittleEndian.PutUint32(prefix[8:], uint32(len(block)))
+
+		file = append(file, testKDBXHMAC(hmacKey, uint64(index), prefix[:], block)...)
+		file = append(file, prefix[8:]...)
+		file = append(file, block...)
+	}
+
+	return file
+}
+
+// testKDBXFile is KDBX 4 database, which is read by reader of tests: XML with decrypted protected values
+// and attachments.
+type testKDBXFile struct {
+	root     *testXMLNode
+	binaries [][]byte
+}
+
+// openTestKDBX reads KDBX 4 database encrypted by AES-256. Reader of tests doesn't use code of parser,
+// checksums of header and blocks are checked by require.
+func openTestKDBX(t *testing.T, data []byte, password string) testKDBXFile {
+	t.Helper()
+
+	require.Greater(t, len(data), len(testKDBXSignature))
+	require.Equal(t, testKDBXSignature[:8], data[:8], "signatures")
+	require.Equal(t, uint16(4), binary.LittleEndian.Uint16(data[10:12]), "major version")
+
+	fields := make(map[byte][]byte)
+	offset := len(testKDBXSignature)
+	for id := byte(0xff); id != 0; {
+		id = data[offset]
+		size := int(binary.LittleEndian.Uint32(data[offset+1:]))
+		fields[id] = data[offset+5 : offset+5+size]
+		offset += 5 + size
+	}
+
+	header := data[:offset]
+	hash := sha256.Sum256(header)
+	require.Equal(t, hash[:], data[offset:offset+32], "SHA-256 of header")
+
+	encryptionKey, hmacKey := testKDBXKeys(t, password, fields[4], testReadVariantDictionary(t, fields[11]))
+	require.Equal(t, testKDBXHMAC(hmacKey, math.MaxUint64, header), data[offset+32:offset+64], "HMAC of header")
+
+	var payload []byte
+	rest := data[offset+64:]
+	for index := uint64(0); ; index++ {
+		size := int(binary.LittleEndian.Uint32(rest[32:36]))
+		block := rest[36 : 36+size]
+
+		prefix := append(binary.LittleEndian.AppendUint64(nil, index), rest[32:36]...)
+		require.Equal(t, testKDBXHMAC(hmacKey, index, prefix, block), rest[:32], "HMAC of block %d", index)
+
+		payload = append(payload, block...)
+		rest = rest[36+size:]
+
+		if size == 0 {
+			break
+		}
+	}
+
+	require.Equal(t, []byte(kdbxAES256), fields[2], "cipher")
+	block, err := aes.NewCipher(encryptionKey)
+	require.NoError(t, err)
+
+	plain := make([]byte, len(payload))
+	cipher.NewCBCDecrypter(block, fields[7]).CryptBlocks(plain, payload)
+	plain = plain[:len(plain)-int(plain[len(plain)-1])]
+
+	if binary.LittleEndian.Uint32(fields[3]) == 1 {
+		zr, err := gzip.NewReader(bytes.NewReader(plain))
+		require.NoError(t, err)
+		plain, err = io.ReadAll(zr)
+		require.NoError(t, err)
+	}
+
+	var (
+		file      testKDBXFile
+		streamKey []byte
+	)
+
+	for id := byte(0xff); id != 0; {
+		id = plain[0]
+		size := int(binary.LittleEndian.Uint32(plain[1:]))
+		value := plain[5 : 5+size]
+		plain = plain[5+size:]
+
+		switch id {
+		case 1:
+			require.Equal(t, []byte{3, 0, 0, 0}, value, "ChaCha20 stream of protected values")
+		case 2:
+			streamKey = value
+		case 3:
+			file.binaries = append(file.binaries, value[1:])
+		}
+	}
+
+	file.root = testReadXML(t, plain, testInnerStream(t, streamKey))
+
+	return file
+}
+
+// testKDBXEntry is entry of database: path of groups under root group, tags, strings,
+// keys of protected strings and attachments by names.
+type testKDBXEntry struct {
+	Folder    string
+	Tags      string
+	Strings   map[string]string
+	Protected []string
+	Binaries  map[string]string
+}
+
+// entries gets entries of all groups of database.
+func (f testKDBXFile) entries(t *testing.T) []testKDBXEntry {
+	var (
+		entries []testKDBXEntry
+		walk    func(group *testXMLNode, folder string)
+	)
+
+	walk = func(group *testXMLNode, folder string) {
+		for _, node := range group.children {
+			switch node.name {
+			case "Group":
+				walk(node, strings.TrimPrefix(folder+"/"+node.child("Name").text, "/"))
+			case "Entry":
+				entry := testKDBXEntry{Folder: folder, Tags: node.child("Tags").text, Strings: map[string]string{}}
+				for _, field := range node.children {
+					key, value := field.child("Key").text, field.child("Value")
+					switch field.name {
+					case "String":
+						entry.Strings[key] = value.text
+						if value.attrs["Protected"] == "True" {
+							entry.Protected = append(entry.Protected, key)
+						}
+					case "Binary":
+						ref, err := strconv.Atoi(value.attrs["Ref"])
+						require.NoError(t, err)
+						require.Less(t, ref, len(f.binaries))
+
+						if entry.Binaries == nil {
+							entry.Binaries = map[string]string{}
+						}
+						entry.Binaries[key] = string(f.binaries[ref])
+					}
+				}
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	// Root group is database itself, it isn't folder.
+	walk(f.root.child("Root").child("Group"), "")
+
+	return entries
+}
+
+// testXMLNode is element of XML, which is read by reader of tests.
+type testXMLNode struct {
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*testXMLNode
+}
+
+// child gets first child with name or empty node.
+func (n *testXMLNode) child(name string) *testXMLNode {
+	for _, child := range n.children {
+		if child.name == name {
+			return child
+		}
+	}
+
+	return &testXMLNode{}
+}
+
+// testReadXML reads XML to tree, protected values are decrypted by stream in document order.
+func testReadXML(t *testing.T, document []byte, stream cipher.Stream) *testXMLNode {
+	t.Helper()
+
+	root := &testXMLNode{}
+	stack := []*testXMLNode{root}
+
+	decoder := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		top := stack[len(stack)-1]
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &testXMLNode{name: token.Name.Local, attrs: map[string]string{}}
+			for _, attr := range token.Attr {
+				node.attrs[attr.Name.Local] = attr.Value
+			}
+			top.children = append(top.children, node)
+			stack = append(stack, node)
+		case xml.CharData:
+			top.text += string(token)
+		case xml.EndElement:
+			if top.attrs["Protected"] == "True" {
+				value, err := base64.StdEncoding.DecodeString(top.text)
+				require.NoError(t, err)
+				stream.XORKeyStream(value, value)
+				top.text = string(value)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	require.Len(t, root.children, 1)
+
+	return root.children[0]
+}
+
+// testKDBXKeys derives key of cipher and key of HMAC blocks by password and KDF parameters.
+func testKDBXKeys(t *testing.T, password string, seed []byte, kdf map[string]interface{}) ([]byte, []byte) {
+	t.Helper()
+
+	hashed := sha256.Sum256([]byte(password))
+	composite := sha256.Sum256(hashed[:])
+
+	uuid, _ := kdf["$UUID"].([]byte)
+	salt, _ := kdf["S"].([]byte)
+
+	var transformed []byte
+	switch string(uuid) {
+	case kdbxAESKDF:
+		block, err := aes.NewCipher(salt)
+		require.NoError(t, err)
+
+		key := composite
+		for i := uint64(0); i < testUint(kdf["R"]); i++ {
+			block.Encrypt(key[:16], key[:16])
+			block.Encrypt(key[16:], key[16:])
+		}
+
+		sum := sha256.Sum256(key[:])
+		transformed = sum[:]
+	case kdbxArgon2d:
+		// Argon2d itself is checked by test vectors of RFC 9106.
+		transformed = argon2Key(argon2d, composite[:], salt, nil, nil,
+			uint32(testUint(kdf["I"])), uint32(testUint(kdf["M"])/1024), uint8(testUint(kdf["P"])), 32)
+	default:
+		require.FailNow(t, "unknown KDF")
+	}
+
+	material := append(append([]byte(nil), seed...), transformed...)
+	encryptionKey := sha256.Sum256(material)
+	hmacKey := sha512.Sum512(append(material, 0x01))
+
+	return encryptionKey[:], hmacKey[:]
+}
+
+// testKDBXHMAC makes HMAC-SHA-256 of data with key of block with index.
+func testKDBXHMAC(hmacKey []byte, index uint64, data ...[]byte) []byte {
+	blockKey := sha512.Sum512(append(binary.LittleEndian.AppendUint64(nil, index), hmacKey...))
+
+	mac := hmac.New(sha256.New, blockKey[:])
+	for _, part := range data {
+		mac.Write(part)
+	}
+
+	return mac.Sum(nil)
+}
+
+// testInnerStream makes ChaCha20 stream of protected values by key of inner header.
+func testInnerStream(t *testing.T, streamKey []byte) cipher.Stream {
+	t.Helper()
+
+	hashed := sha512.Sum512(streamKey)
+	stream, err := chacha20.NewUnauthenticatedCipher(hashed[:32], hashed[32:44])
+	require.NoError(t, err)
+
+	return stream
+}
+
+// testTLV writes field of header.
+func testTLV(buffer *bytes.Buffer, id byte, value []byte) {
+	buffer.WriteByte(id)
+	buffer.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(value))))
+	buffer.Write(value)
+}
+
+// testVariantDictionary writes dictionary of uint64 and bytes values.
+func testVariantDictionary(dictionary map[string]interface{}) []byte {
+	var buffer bytes.Buffer
+	buffer.Write([]byte{0x00, 0x01})
+
+	for key, value := range dictionary {
+		var (
+			kind byte
+			data []byte
+		)
+
+		switch v := value.(type) {
+		case uint64:
+			kind, data = 0x05, binary.LittleEndian.AppendUint64(nil, v)
+		case []byte:
+			kind, data = 0x42, v
+		}
+
+		buffer.WriteByte(kind)
+		buffer.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(key))))
+		buffer.WriteString(key)
+		buffer.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(data))))
+		buffer.Write(data)
+	}
+	buffer.WriteByte(0x00)
+
+	return buffer.Bytes()
+}
+
+// testReadVariantDictionary reads dictionary of uint32, uint64 and bytes values.
+func testReadVariantDictionary(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+
+	require.Equal(t, []byte{0x00, 0x01}, data[:2], "version of dictionary")
+	data = data[2:]
+
+	dictionary := make(map[string]interface{})
+	for data[0] != 0x00 {
+		kind := data[0]
+		keySize := int(binary.LittleEndian.Uint32(data[1:]))
+		key := string(data[5 : 5+keySize])
+		data = data[5+keySize:]
+
+		valueSize := int(binary.LittleEndian.Uint32(data))
+		value := data[4 : 4+valueSize]
+		data = data[4+valueSize:]
+
+		switch kind {
+		case 0x04:
+			dictionary[key] = binary.LittleEndian.Uint32(value)
+		case 0x05:
+			dictionary[key] = binary.LittleEndian.Uint64(value)
+		case 0x42:
+			dictionary[key] = value
+		default:
+			require.FailNow(t, "unexpected type of variant", "%s: %#x", key, kind)
+		}
+	}
+
+	return dictionary
+}
+
+// testUint gets uint32 or uint64 value of dictionary.
+func testUint(value interface{}) uint64 {
+	switch v := value.(type) {
+	case uint32:
+		return uint64(v)
+	case uint64:
+		return v
+	default:
+		return 0
+	}
+}
+
+func TestParseKDBX(t *testing.T) {
+	argon2 := map[string]interface{}{
+		"$UUID": []byte(kdbxArgon2d),
+		"S":     bytes.Repeat([]byte{4}, 32),
+		"P":     uint64(2),
+		"M":     uint64(64 * 1024),
+		"I":     uint64(2),
+		"V":     uint64(argon2Version),
+	}
+	aesKDF := map[string]interface{}{
+		"$UUID": []byte(kdbxAESKDF),
+		"S":     bytes.Repeat([]byte{5}, 32),
+		"R":     uint64(100),
+	}
+
+	tc := []struct {
+		name   string
+		cipher string
+		kdf    map[string]interface{}
+	}{
+		{name: "AES-256 and Argon2d", cipher: kdbxAES256, kdf: argon2},
+		{name: "ChaCha20 and AES-KDF", cipher: kdbxChaCha20, kdf: aesKDF},
+		{name: "Twofish and AES-KDF", cipher: kdbxTwofish, kdf: aesKDF},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		data := testKDBX(t, "master", test.cipher, test.kdf, testKDBXDocument, [][]byte{[]byte("PEM")})
+
+		result, err := Parse(FormatKDBX, data, Options{Password: "master", Folder: "keepass"})
+		require.NoError(t, err)
+		require.Len(t, result.Records, 4)
+		assert.Empty(t, result.Skipped)
+
+		login := result.Records[0]
+		assert.Equal(t, entity.TypeLoginAndPassword, login.Type)
+		assert.Equal(t, "Mail", login.Name)
+		assert.Equal(t, "keepass", login.Folder)
+		assert.Equal(t, []string{"mail", "web"}, login.Tags)
+
+		payload, err := entity.DecodePayload(login)
+		require.NoError(t, err)
+		assert.Equal(t, &entity.LoginAndPassword{
+			Login:    "user",
+			Password: "secret",
+			URL:      "https://mail.example.com",
+			Fields:   []entity.CustomField{{Name: "PIN", Value: "1234", Hidden: true}},
+		}, payload)
+
+		otp := result.Records[1]
+		assert.Equal(t, entity.TypeOTP, otp.Type)
+		payload, err = entity.DecodePayload(otp)
+		require.NoError(t, err)
+		assert.Equal(t, "otpauth://totp/Mail:user?secret=JBSWY3DPEHPK3PXP", payload.(*entity.OTPData).URI)
+
+		file := result.Records[2]
+		assert.Equal(t, entity.TypeFile, file.Type)
+		assert.Equal(t, "key.pem", file.Metadata)
+		assert.Equal(t, []byte("PEM"), file.Data)
+
+		note := result.Records[3]
+		assert.Equal(t, entity.TypeText, note.Type)
+		assert.Equal(t, "keepass/Banks", note.Folder)
+		payload, err = entity.DecodePayload(note)
+		require.NoError(t, err)
+		assert.Equal(t, &entity.TextData{Text: "text of note"}, payload)
+
+		assert.Equal(t, map[entity.RecordType]int{
+			entity.TypeLoginAndPassword: 1,
+			entity.TypeOTP:              1,
+			entity.TypeFile:             1,
+			entity.TypeText:             1,
+		}, result.Summary())
+	}
+
+	t.Log("Wrong password")
+	data := testKDBX(t, "master", kdbxAES256, aesKDF, testKDBXDocument, nil)
+	_, err := Parse(FormatKDBX, data, Options{Password: "wrong"})
+	assert.ErrorIs(t, err, ErrWrongPassword)
+
+	t.Log("Corrupted payload")
+	data[len(data)-50] ^= 0xFF
+	_, err = Parse(FormatKDBX, data, Options{Password: "master"})
+	assert.ErrorIs(t, err, ErrBadExport)
+
+	t.Log("Not KeePass database")
+	_, err = Parse(FormatKDBX, []byte("not a database"), Options{Password: "master"})
+	assert.ErrorIs(t, err, ErrBadExport)
+
+	t.Log("KDBX 3")
+	data = testKDBX(t, "master", kdbxAES256, aesKDF, testKDBXDocument, nil)
+	data[10] = 3
+	_, err = Parse(FormatKDBX, data, Options{Password: "master"})
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	t.Log("Payload is larger than limit")
+	defer func(size int64) { maxUnpackedSize = size }(maxUnpackedSize)
+	maxUnpackedSize = 64
+	data = testKDBX(t, "master", kdbxAES256, aesKDF, testKDBXDocument, nil)
+	_, err = Parse(FormatKDBX, data, Options{Password: "master"})
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+func TestKDBXTransformKey_Limits(t *testing.T) {
+	composite := kdbxCompositeKey("master")
+	argon2 := func(memory, iterations, parallelism uint64) map[string]interface{} {
+		return map[string]interface{}{
+			"$UUID": []byte(kdbxArgon2d),
+			"S":     bytes.Repeat([]byte{4}, 32),
+			"P":     parallelism,
+			"M":     memory,
+			"I":     iterations,
+			"V":     uint64(argon2Version),
+		}
+	}
+
+	tc := []struct {
+		name string
+		kdf  map[string]interface{}
+	}{
+		{"Too much memory of Argon2", argon2(64<<30, 2, 2)},
+		{"Too many iterations of Argon2", argon2(64<<20, 1000, 2)},
+		{"Too many threads of Argon2", argon2(64<<20, 2, 64)},
+		{"Too many rounds of AES-KDF", map[string]interface{}{
+			"$UUID": []byte(kdbxAESKDF),
+			"S":     bytes.Repeat([]byte{5}, 32),
+			"R":     uint64(1) << 40,
+		}},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		_, err := kdbxTransformKey(test.kdf, composite)
+		assert.ErrorIs(t, err, ErrUnsupported)
+	}
+}
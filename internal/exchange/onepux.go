@@ -0,0 +1,338 @@
+package exchange
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+)
+
+// Categories of 1Password items.
+const (
+	onePUXLogin    = "001"
+	onePUXCard     = "002"
+	onePUXNote     = "003"
+	onePUXPassword = "005"
+	onePUXDocument = "006"
+)
+
+// 1PUX is zip with export.data JSON and attachments named files/<document ID>__<file name>.
+const (
+	onePUXData  = "export.data"
+	onePUXFiles = "files/"
+)
+
+type onePUXExport struct {
+	Accounts []struct {
+		Vaults []struct {
+			Attrs struct {
+				Name string `json:"name"`
+			} `json:"attrs"`
+			Items []onePUXItem `json:"items"`
+		} `json:"vaults"`
+	} `json:"accounts"`
+}
+
+type onePUXItem struct {
+	State        string `json:"state"`
+	CategoryUUID string `json:"categoryUuid"`
+	Details      struct {
+		LoginFields []struct {
+			Value       string `json:"value"`
+			Designation string `json:"designation"`
+		} `json:"loginFields"`
+		NotesPlain string `json:"notesPlain"`
+		Password   string `json:"password"`
+		Sections   []struct {
+			Fields []struct {
+				Title string                     `json:"title"`
+				ID    string                     `json:"id"`
+				Value map[string]json.RawMessage `json:"value"`
+			} `json:"fields"`
+		} `json:"sections"`
+		DocumentAttributes *onePUXFile `json:"documentAttributes"`
+	} `json:"details"`
+	Overview struct {
+		Title string   `json:"title"`
+		URL   string   `json:"url"`
+		Tags  []string `json:"tags"`
+	} `json:"overview"`
+}
+
+type onePUXFile struct {
+	FileName   string `json:"fileName"`
+	DocumentID string `json:"documentId"`
+}
+
+// onePUXField is field of item section with value converted to text.
+type onePUXField struct {
+	id, title, kind, value string
+	file                   *onePUXFile
+}
+
+// parse1PUX converts 1PUX export of 1Password. Vaults become folders, archived items are skipped.
+// Data and attachments together are unpacked up to maxUnpackedSize.
+func parse1PUX(data []byte) (Result, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrBadExport, err)
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	dataFile, ok := files[onePUXData]
+	if !ok {
+		return Result{}, fmt.Errorf("%w: no %s", ErrBadExport, onePUXData)
+	}
+
+	left := maxUnpackedSize
+
+	content, err := readZipFile(dataFile, &left)
+	if errors.Is(err, ErrTooLarge) {
+		return Result{}, err
+	}
+	if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrBadExport, err)
+	}
+
+	var export onePUXExport
+	if err = json.Unmarshal(content, &export); err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrBadExport, err)
+	}
+
+	var result Result
+	for _, account := range export.Accounts {
+		for _, vault := range account.Vaults {
+			for _, op := range vault.Items {
+				it := item{name: op.Overview.Title, folder: folderName(vault.Attrs.Name), tags: op.Overview.Tags}
+				if op.State == "archived" {
+					result.skip(it.name, "archived")
+					continue
+				}
+
+				result.add1PUXItem(it, op, func(file onePUXFile) ([]byte, error) {
+					return onePUXAttachment(files, file, &left)
+				})
+			}
+		}
+	}
+
+	if left < 0 {
+		return Result{}, ErrTooLarge
+	}
+
+	return result, nil
+}
+
+// add1PUXItem adds records of item. Attachment gets content of attached file.
+func (r *Result) add1PUXItem(it item, op onePUXItem, attachment func(onePUXFile) ([]byte, error)) {
+	var (
+		fields      []onePUXField
+		custom      []entity.CustomField
+		attachments []onePUXFile
+		otps        []string
+	)
+
+	for _, section := range op.Details.Sections {
+		for _, field := range section.Fields {
+			parsed := parse1PUXField(field.ID, field.Title, field.Value)
+
+			switch {
+			case parsed.file != nil:
+				attachments = append(attachments, *parsed.file)
+			case parsed.kind == "totp":
+				otps = append(otps, parsed.value)
+			case parsed.value != "":
+				fields = append(fields, parsed)
+			}
+		}
+	}
+
+	if op.Details.DocumentAttributes != nil {
+		attachments = append(attachments, *op.Details.DocumentAttributes)
+	}
+
+	var login entity.LoginAndPassword
+	for _, field := range op.Details.LoginFields {
+		switch field.Designation {
+		case "username":
+			login.Login = field.Value
+		case "password":
+			login.Password = field.Value
+		}
+	}
+
+	switch op.CategoryUUID {
+	case onePUXLogin, onePUXPassword:
+		if login.Password == "" {
+			login.Password = op.Details.Password
+		}
+		login.URL, login.Notes = op.Overview.URL, op.Details.NotesPlain
+
+		for _, field := range fields {
+			login.Fields = append(login.Fields, entity.CustomField{
+				Name:   field.title,
+				Value:  field.value,
+				Hidden: field.kind == "concealed",
+			})
+		}
+
+		r.addLogin(it, login)
+	case onePUXCard:
+		var card entity.CreditCard
+		for _, field := range fields {
+			switch field.id {
+			case "ccnum":
+				card.CardNumber = field.value
+			case "cvv":
+				card.CVCCode = field.value
+			case "expiry":
+				card.ExpirationDate = field.value
+			case "cardholder":
+				card.Holder = field.value
+			default:
+				custom = append(custom, entity.CustomField{Name: field.title, Value: field.value})
+			}
+		}
+		card.Notes = joinText(op.Details.NotesPlain, fieldsText(custom))
+
+		r.addCard(it, card)
+	case onePUXDocument:
+		if op.Details.NotesPlain != "" || len(fields) > 0 {
+			r.addText(it, joinText(op.Details.NotesPlain, onePUXFieldsText(fields)))
+		}
+	default:
+		if op.CategoryUUID != onePUXNote && login.Login+login.Password != "" {
+			custom = append(custom,
+				entity.CustomField{Name: "username", Value: login.Login},
+				entity.CustomField{Name: "password", Value: login.Password},
+			)
+		}
+
+		r.addText(it, joinText(onePUXFieldsText(fields), fieldsText(custom), op.Details.NotesPlain))
+	}
+
+	for _, value := range otps {
+		r.addOTP(it, value)
+	}
+
+	for _, file := range attachments {
+		content, err := attachment(file)
+		if err != nil {
+			r.skip(it.name, fmt.Sprintf("attachment %s: %v", file.FileName, err))
+			continue
+		}
+
+		r.addFile(it, file.FileName, content)
+	}
+}
+
+// parse1PUXField converts value of field to text. Value is object with one key, which is kind of value.
+func parse1PUXField(id, title string, value map[string]json.RawMessage) onePUXField {
+	field := onePUXField{id: id, title: title}
+	if field.title == "" {
+		field.title = id
+	}
+
+	for kind, raw := range value {
+		field.kind = kind
+
+		switch kind {
+		case "file":
+			var file onePUXFile
+			if json.Unmarshal(raw, &file) == nil && file.DocumentID != "" {
+				field.file = &file
+			}
+		case "monthYear":
+			// Month and year are number YYYYMM.
+			var date int
+			if json.Unmarshal(raw, &date) == nil && date > 0 {
+				field.value = cardExpiration(strconv.Itoa(date%100), strconv.Itoa(date/100))
+			}
+		case "email":
+			var email struct {
+				Address string `json:"email_address"`
+			}
+			if json.Unmarshal(raw, &email) == nil {
+				field.value = email.Address
+			}
+		default:
+			field.value = jsonText(raw)
+		}
+	}
+
+	return field
+}
+
+// jsonText converts JSON value to text: strings as is, numbers and booleans by JSON,
+// objects as "key: value" lines sorted by keys.
+func jsonText(raw json.RawMessage) string {
+	var value interface{}
+	if json.Unmarshal(raw, &value) != nil {
+		return ""
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		lines := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if text := fmt.Sprint(v[key]); v[key] != nil && text != "" {
+				lines = append(lines, key+": "+text)
+			}
+		}
+
+		return strings.Join(lines, "\n")
+	default:
+		return strings.TrimSpace(string(raw))
+	}
+}
+
+// onePUXFieldsText makes text of fields.
+func onePUXFieldsText(fields []onePUXField) string {
+	custom := make([]entity.CustomField, 0, len(fields))
+	for _, field := range fields {
+		custom = append(custom, entity.CustomField{Name: field.title, Value: field.value})
+	}
+
+	return fieldsText(custom)
+}
+
+// onePUXAttachment reads attached file from archive.
+func onePUXAttachment(files map[string]*zip.File, file onePUXFile, left *int64) ([]byte, error) {
+	zipped, ok := files[onePUXFiles+file.DocumentID+"__"+file.FileName]
+	if !ok {
+		return nil, errors.New("file isn't found in export")
+	}
+
+	return readZipFile(zipped, left)
+}
+
+// readZipFile reads file of zip archive up to left bytes, size in header isn't trusted.
+func readZipFile(file *zip.File, left *int64) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return readUnpacked(reader, left)
+}
@@ -0,0 +1,132 @@
+package exchange
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+)
+
+const test1PUXData = `{
+	"accounts": [{"vaults": [{"attrs": {"name": "Private"}, "items": [
+		{
+			"state": "active", "categoryUuid": "001",
+			"details": {
+				"loginFields": [
+					{"value": "user", "designation": "username"},
+					{"value": "secret", "designation": "password"}
+				],
+				"notesPlain": "notes",
+				"sections": [{"fields": [
+					{"title": "PIN", "id": "pin", "value": {"concealed": "1234"}},
+					{"title": "one-time password", "id": "otp", "value": {"totp": "otpauth://totp/Mail:user?secret=JBSWY3DPEHPK3PXP"}},
+					{"title": "key", "id": "file", "value": {"file": {"fileName": "key.pem", "documentId": "doc1"}}}
+				]}]
+			},
+			"overview": {"title": "Mail", "url": "https://mail.example.com", "tags": ["web"]}
+		},
+		{
+			"state": "active", "categoryUuid": "002",
+			"details": {"sections": [{"fields": [
+				{"title": "cardholder name", "id": "cardholder", "value": {"string": "John Doe"}},
+				{"title": "number", "id": "ccnum", "value": {"creditCardNumber": "4111111111111111"}},
+				{"title": "verification number", "id": "cvv", "value": {"concealed": "123"}},
+				{"title": "expiry date", "id": "expiry", "value": {"monthYear": 203003}},
+				{"title": "type", "id": "type", "value": {"creditCardType": "visa"}}
+			]}]},
+			"overview": {"title": "Visa"}
+		},
+		{
+			"state": "active", "categoryUuid": "006",
+			"details": {"documentAttributes": {"fileName": "scan.pdf", "documentId": "missing"}},
+			"overview": {"title": "Scan"}
+		},
+		{"state": "archived", "categoryUuid": "003", "details": {"notesPlain": "old"}, "overview": {"title": "Old"}}
+	]}]}]
+}`
+
+func test1PUX(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buffer bytes.Buffer
+	zw := zip.NewWriter(&buffer)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	return buffer.Bytes()
+}
+
+func TestParse1PUX(t *testing.T) {
+	data := test1PUX(t, map[string]string{
+		"export.attributes":    `{"version": 3}`,
+		"export.data":          test1PUXData,
+		"files/doc1__key.pem":  "PEM",
+		"files/other__key.pem": "other",
+	})
+
+	result, err := Parse(Format1PUX, data, Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Records, 4)
+
+	t.Log("Login with custom fields, OTP and attachment")
+	login := result.Records[0]
+	assert.Equal(t, "Private", login.Folder)
+	assert.Equal(t, []string{"web"}, login.Tags)
+	payload, err := entity.DecodePayload(login)
+	require.NoError(t, err)
+	assert.Equal(t, &entity.LoginAndPassword{
+		Login:    "user",
+		Password: "secret",
+		URL:      "https://mail.example.com",
+		Notes:    "notes",
+		Fields:   []entity.CustomField{{Name: "PIN", Value: "1234", Hidden: true}},
+	}, payload)
+
+	assert.Equal(t, entity.TypeOTP, result.Records[1].Type)
+	assert.Equal(t, entity.TypeFile, result.Records[2].Type)
+	assert.Equal(t, "key.pem", result.Records[2].Metadata)
+	assert.Equal(t, []byte("PEM"), result.Records[2].Data)
+
+	t.Log("Card")
+	payload, err = entity.DecodePayload(result.Records[3])
+	require.NoError(t, err)
+	assert.Equal(t, &entity.CreditCard{
+		CardNumber:     "4111111111111111",
+		ExpirationDate: "03/30",
+		CVCCode:        "123",
+		Holder:         "John Doe",
+		Notes:          "type: visa",
+	}, payload)
+
+	t.Log("Missing attachment and archived item are skipped")
+	assert.Equal(t, []Skipped{
+		{Name: "Scan", Reason: "attachment scan.pdf: file isn't found in export"},
+		{Name: "Old", Reason: "archived"},
+	}, result.Skipped)
+
+	t.Log("Not 1PUX")
+	_, err = Parse(Format1PUX, []byte("not zip"), Options{})
+	assert.ErrorIs(t, err, ErrBadExport)
+
+	_, err = Parse(Format1PUX, test1PUX(t, map[string]string{"other": ""}), Options{})
+	assert.ErrorIs(t, err, ErrBadExport)
+
+	t.Log("Data and attachments are larger than limit")
+	defer func(size int64) { maxUnpackedSize = size }(maxUnpackedSize)
+	maxUnpackedSize = int64(len(test1PUXData)) + 1
+	_, err = Parse(Format1PUX, data, Options{})
+	assert.ErrorIs(t, err, ErrTooLarge)
+
+	maxUnpackedSize = 16
+	_, err = Parse(Format1PUX, data, Options{})
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
@@ -0,0 +1,399 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Backup of server is tar.gz: tables of DB in JSON lines, files of file records and manifest,
+// which is the last entry, with sizes and SHA-256 checksums of all entries.
+const (
+	BackupFormat  = "gophkeeper-server-backup"
+	BackupVersion = 1
+
+	backupManifest = "manifest.json"
+	backupTablesTo = "tables/"
+	backupFilesTo  = "files/"
+)
+
+// BackupManifest describes backup of server. Missing are file records, which files weren't found,
+// while backup was made, e.g. they were deleted after snapshot of DB.
+type BackupManifest struct {
+	Format    string        `json:"format"`
+	Version   int           `json:"version"`
+	CreatedAt time.Time     `json:"created_at"`
+	Schema    int64         `json:"schema"`
+	Tables    []BackupEntry `json:"tables"`
+	Files     []BackupEntry `json:"files"`
+	Missing   []string      `json:"missing,omitempty"`
+}
+
+// BackupEntry is table or file of backup: name (of table or ID of file record), number of rows of table,
+// size and SHA-256 checksum of content.
+type BackupEntry struct {
+	Name   string `json:"name"`
+	Rows   int    `json:"rows,omitempty"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Backup writes backup of DB and files of file records, which are referenced by snapshot of DB.
+// Tables are copied from temporary files of dump.
+func Backup(ctx context.Context, db BackupStorager, files FileStorager, w io.Writer) (BackupManifest, error) {
+	dump, err := db.Dump(ctx)
+	if err != nil {
+		return BackupManifest{}, err
+	}
+	defer dump.Close()
+
+	manifest := BackupManifest{
+		Format:    BackupFormat,
+		Version:   BackupVersion,
+		CreatedAt: time.Now().UTC(),
+		Schema:    dump.Schema,
+		Tables:    make([]BackupEntry, 0, len(dump.Tables)),
+		Files:     make([]BackupEntry, 0, len(dump.Files)),
+	}
+
+	zw := gzip.NewWriter(w)
+	tw := tar.NewWriter(zw)
+
+	for _, table := range dump.Tables {
+		entry, err := backupTable(tw, table, manifest.CreatedAt)
+		if err != nil {
+			return BackupManifest{}, err
+		}
+
+		manifest.Tables = append(manifest.Tables, entry)
+	}
+
+	for _, recordID := range dump.Files {
+		entry, err := backupFile(ctx, tw, files, recordID, manifest.CreatedAt)
+		if errors.Is(err, ErrNotFound) {
+			log.Warnf("%s :: %s", "file of record isn't found in backup", recordID)
+
+			manifest.Missing = append(manifest.Missing, recordID)
+			continue
+		}
+		if err != nil {
+			return BackupManifest{}, err
+		}
+
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return BackupManifest{}, err
+	}
+
+	if _, err = writeBackupEntry(tw, backupManifest, manifest.CreatedAt, bytes.NewReader(data), int64(len(data))); err != nil {
+		return BackupManifest{}, err
+	}
+
+	if err = tw.Close(); err != nil {
+		return BackupManifest{}, err
+	}
+
+	return manifest, zw.Close()
+}
+
+// backupTable writes file of table with its rows from the start.
+func backupTable(tw *tar.Writer, table DumpTable, modified time.Time) (BackupEntry, error) {
+	info, err := table.File.Stat()
+	if err != nil {
+		return BackupEntry{}, err
+	}
+
+	if _, err = table.File.Seek(0, io.SeekStart); err != nil {
+		return BackupEntry{}, err
+	}
+
+	entry, err := writeBackupEntry(tw, backupTablesTo+table.Name+".jsonl", modified, table.File, info.Size())
+	if err != nil {
+		return BackupEntry{}, err
+	}
+	entry.Name, entry.Rows = table.Name, table.Rows
+
+	return entry, nil
+}
+
+// backupFile writes file of record. Size of file must be known for tar, so file is read twice:
+// to get size and checksum and to copy it.
+func backupFile(ctx context.Context, tw *tar.Writer, files FileStorager, recordID string, modified time.Time) (BackupEntry, error) {
+	file, err := files.OpenRecord(ctx, recordID)
+	if err != nil {
+		return BackupEntry{}, err
+	}
+
+	size, err := io.Copy(io.Discard, file)
+	file.Close()
+	if err != nil {
+		return BackupEntry{}, err
+	}
+
+	if file, err = files.OpenRecord(ctx, recordID); err != nil {
+		return BackupEntry{}, err
+	}
+	defer file.Close()
+
+	entry, err := writeBackupEntry(tw, backupFilesTo+recordID, modified, file, size)
+	if err != nil {
+		return BackupEntry{}, err
+	}
+	entry.Name = recordID
+
+	return entry, nil
+}
+
+// writeBackupEntry writes entry of tar with size and returns its size and checksum.
+func writeBackupEntry(tw *tar.Writer, name string, modified time.Time, r io.Reader, size int64) (BackupEntry, error) {
+	err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0o600,
+		Size:     size,
+		ModTime:  modified,
+	})
+	if err != nil {
+		return BackupEntry{}, err
+	}
+
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(tw, hash), io.LimitReader(r, size)); err != nil {
+		// Tar writer fails, if file is shorter than its size in header.
+		return BackupEntry{}, err
+	}
+
+	return BackupEntry{Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// VerifyBackup reads backup and checks that it has all tables and files of manifest with the same checksums.
+func VerifyBackup(r io.Reader) (BackupManifest, error) {
+	return readBackup(r, nil, nil)
+}
+
+// RestoreBackup restores backup into empty instance: files are written to file storage, tables are written
+// to temporary files, then rows are inserted into DB in one transaction. Backup is verified before,
+// so it's read twice. If DB isn't restored, written files are deleted.
+func RestoreBackup(ctx context.Context, db BackupStorager, files FileStorager, r io.ReadSeeker) (BackupManifest, error) {
+	manifest, err := VerifyBackup(r)
+	if err != nil {
+		return manifest, err
+	}
+
+	if err = db.CheckRestore(ctx, manifest.Schema); err != nil {
+		return manifest, err
+	}
+
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return manifest, err
+	}
+
+	dump := Dump{Schema: manifest.Schema}
+	defer dump.Close()
+
+	var written []string
+
+	_, err = readBackup(r, func(name string, rows io.Reader) error {
+		table, err := newDumpTable(name)
+		if err != nil {
+			return err
+		}
+		dump.Tables = append(dump.Tables, table)
+
+		_, err = io.Copy(table.File, rows)
+
+		return err
+	}, func(recordID string, content io.Reader) error {
+		if err := files.WriteRecord(ctx, recordID, content); err != nil {
+			return err
+		}
+		written = append(written, recordID)
+
+		return nil
+	})
+	if err == nil {
+		// Backup is verified, so numbers of rows are known from manifest.
+		for i, table := range dump.Tables {
+			for _, entry := range manifest.Tables {
+				if entry.Name == table.Name {
+					dump.Tables[i].Rows = entry.Rows
+				}
+			}
+		}
+
+		err = db.Load(ctx, dump)
+	}
+
+	if err != nil {
+		for _, recordID := range written {
+			if errDelete := files.DeleteRecord(ctx, recordID); errDelete != nil {
+				log.Warnf("%s :: %v", "delete restored file fault", errDelete)
+			}
+		}
+
+		return manifest, err
+	}
+
+	return manifest, nil
+}
+
+// readBackup reads entries of backup and checks them by manifest. Tables and files are passed to callbacks,
+// if they are set.
+func readBackup(
+	r io.Reader,
+	table func(name string, rows io.Reader) error,
+	file func(recordID string, content io.Reader) error,
+) (BackupManifest, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return BackupManifest{}, fmt.Errorf("%w: %v", ErrBadBackup, err)
+	}
+
+	var (
+		manifest *BackupManifest
+		read     = make(map[string]BackupEntry)
+	)
+
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return BackupManifest{}, fmt.Errorf("%w: %v", ErrBadBackup, err)
+		}
+		if manifest != nil {
+			return BackupManifest{}, fmt.Errorf("%w: entries after manifest", ErrBadBackup)
+		}
+
+		var (
+			hash    = sha256.New()
+			content = io.TeeReader(tr, hash)
+			key     string
+		)
+
+		// Tables are keyed by names and files by paths, so table can't be confused with file.
+		switch name := header.Name; {
+		case name == backupManifest:
+			manifest = new(BackupManifest)
+			if err = json.NewDecoder(content).Decode(manifest); err != nil {
+				return BackupManifest{}, fmt.Errorf("%w: manifest: %v", ErrBadBackup, err)
+			}
+			continue
+		case strings.HasPrefix(name, backupTablesTo) && strings.HasSuffix(name, ".jsonl"):
+			key = strings.TrimSuffix(strings.TrimPrefix(name, backupTablesTo), ".jsonl")
+			if _, ok := read[key]; ok {
+				return BackupManifest{}, fmt.Errorf("%w: duplicate entry %s", ErrBadBackup, name)
+			}
+
+			rows := new(rowCounter)
+			content = io.TeeReader(content, rows)
+			if table != nil {
+				if err = table(key, content); err != nil {
+					return BackupManifest{}, err
+				}
+			}
+			if _, err = io.Copy(io.Discard, content); err != nil {
+				return BackupManifest{}, fmt.Errorf("%w: %v", ErrBadBackup, err)
+			}
+			read[key] = BackupEntry{Name: key, Rows: rows.rows()}
+		case strings.HasPrefix(name, backupFilesTo) && fileRecordID.MatchString(strings.TrimPrefix(name, backupFilesTo)):
+			key = name
+			if _, ok := read[key]; ok {
+				return BackupManifest{}, fmt.Errorf("%w: duplicate entry %s", ErrBadBackup, name)
+			}
+			if file != nil {
+				if err = file(strings.TrimPrefix(name, backupFilesTo), content); err != nil {
+					return BackupManifest{}, err
+				}
+			}
+			read[key] = BackupEntry{Name: strings.TrimPrefix(name, backupFilesTo)}
+		default:
+			return BackupManifest{}, fmt.Errorf("%w: unknown entry %s", ErrBadBackup, name)
+		}
+
+		// Rest of content, which callback didn't read, is read for checksum.
+		if _, err = io.Copy(io.Discard, content); err != nil {
+			return BackupManifest{}, fmt.Errorf("%w: %v", ErrBadBackup, err)
+		}
+
+		entry := read[key]
+		entry.Size, entry.SHA256 = header.Size, hex.EncodeToString(hash.Sum(nil))
+		read[key] = entry
+	}
+
+	if manifest == nil {
+		return BackupManifest{}, fmt.Errorf("%w: no manifest", ErrBadBackup)
+	}
+
+	return *manifest, checkBackup(*manifest, read)
+}
+
+// rowCounter counts rows of JSON lines, which are written to it. Last row may be without line break.
+type rowCounter struct {
+	lines int
+	last  byte
+}
+
+func (c *rowCounter) Write(p []byte) (int, error) {
+	c.lines += bytes.Count(p, []byte{'\n'})
+	if len(p) != 0 {
+		c.last = p[len(p)-1]
+	}
+
+	return len(p), nil
+}
+
+// rows returns number of rows.
+func (c *rowCounter) rows() int {
+	if c.last != 0 && c.last != '\n' {
+		return c.lines + 1
+	}
+
+	return c.lines
+}
+
+// checkBackup compares entries, which are read, with manifest.
+func checkBackup(manifest BackupManifest, read map[string]BackupEntry) error {
+	if manifest.Format != BackupFormat {
+		return fmt.Errorf("%w: it isn't backup of server", ErrBadBackup)
+	}
+	if manifest.Version != BackupVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrBadBackup, manifest.Version)
+	}
+
+	expected := make(map[string]BackupEntry, len(manifest.Tables)+len(manifest.Files))
+	for _, entry := range manifest.Tables {
+		expected[entry.Name] = entry
+	}
+	for _, entry := range manifest.Files {
+		expected[backupFilesTo+entry.Name] = entry
+	}
+
+	if len(expected) != len(read) {
+		return fmt.Errorf("%w: %d entries instead of %d", ErrBadBackup, len(read), len(expected))
+	}
+
+	for key, entry := range expected {
+		if got, ok := read[key]; !ok || got != entry {
+			return fmt.Errorf("%w: %s doesn't match manifest", ErrBadBackup, entry.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testFileID    = "0b7e3f9a-3c1d-4e2b-9f6a-5d8c7b6a5e4f"
+	testMissingID = "1c8f4a0b-4d2e-4f3c-8a7b-6e9d8c7b6a5f"
+)
+
+// fakeBackupStorager keeps dump in memory. Loaded are tables without files and their rows, which are read from files.
+type fakeBackupStorager struct {
+	dump     Dump
+	loaded   []DumpTable
+	rows     map[string][]string
+	restore  error
+	loadFail error
+}
+
+func (f *fakeBackupStorager) Dump(context.Context) (Dump, error) {
+	return f.dump, nil
+}
+
+func (f *fakeBackupStorager) CheckRestore(_ context.Context, schema int64) error {
+	if f.restore != nil {
+		return f.restore
+	}
+	if schema != f.dump.Schema {
+		return ErrSchemaMismatch
+	}
+
+	return nil
+}
+
+func (f *fakeBackupStorager) Load(_ context.Context, dump Dump) error {
+	if f.loadFail != nil {
+		return f.loadFail
+	}
+
+	f.rows = make(map[string][]string, len(dump.Tables))
+	for _, table := range dump.Tables {
+		rows, err := readDumpTable(table)
+		if err != nil {
+			return err
+		}
+		f.rows[table.Name] = rows
+		table.File = nil
+		f.loaded = append(f.loaded, table)
+	}
+
+	return nil
+}
+
+// testDump returns dump with new files of tables, because backup removes them.
+func testDump(t *testing.T) Dump {
+	return Dump{
+		Schema: 12,
+		Tables: []DumpTable{
+			testDumpTable(t, "users", `{"user_id":"u1"}`, `{"user_id":"u2"}`),
+			testDumpTable(t, "users_data", `{"record_id":"`+testFileID+`"}`),
+			testDumpTable(t, "vaults"),
+		},
+		Files: []string{testFileID, testMissingID},
+	}
+}
+
+func testServerBackup(t *testing.T) ([]byte, BackupManifest) {
+	files := newFileStorage(t.TempDir())
+	require.NoError(t, files.WriteRecord(context.Background(), testFileID, strings.NewReader("file content")))
+
+	var buf bytes.Buffer
+	manifest, err := Backup(context.Background(), &fakeBackupStorager{dump: testDump(t)}, files, &buf)
+	require.NoError(t, err)
+
+	return buf.Bytes(), manifest
+}
+
+// rewriteBackup copies entries of backup, change can replace content of entry or drop it.
+// Extra entries are written before entries of backup.
+func rewriteBackup(t *testing.T, data []byte, change func(name string, content []byte) ([]byte, bool), extra ...string) []byte {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+
+	for _, name := range extra {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o600, Size: 4}))
+		_, err = tw.Write([]byte("root"))
+		require.NoError(t, err)
+	}
+
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(tr)
+		require.NoError(t, err)
+
+		content, keep := change(header.Name, content)
+		if !keep {
+			continue
+		}
+
+		header.Size = int64(len(content))
+		require.NoError(t, tw.WriteHeader(header))
+		_, err = tw.Write(content)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestBackup(t *testing.T) {
+	data, manifest := testServerBackup(t)
+
+	assert.Equal(t, BackupFormat, manifest.Format)
+	assert.Equal(t, int64(12), manifest.Schema)
+	assert.Len(t, manifest.Tables, 3)
+	assert.Equal(t, 2, manifest.Tables[0].Rows)
+	checksum := sha256.Sum256([]byte("file content"))
+	assert.Equal(t, []BackupEntry{{Name: testFileID, Size: 12, SHA256: hex.EncodeToString(checksum[:])}}, manifest.Files)
+	assert.Equal(t, []string{testMissingID}, manifest.Missing)
+
+	verified, err := VerifyBackup(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, manifest.Files, verified.Files)
+	assert.Equal(t, manifest.Tables, verified.Tables)
+}
+
+func TestVerifyBackup(t *testing.T) {
+	data, _ := testServerBackup(t)
+
+	tc := []struct {
+		name   string
+		backup []byte
+	}{
+		{
+			name: "Changed file",
+			backup: rewriteBackup(t, data, func(name string, content []byte) ([]byte, bool) {
+				if name == backupFilesTo+testFileID {
+					return []byte("file CONTENT"), true
+				}
+				return content, true
+			}),
+		},
+		{
+			name: "Dropped table",
+			backup: rewriteBackup(t, data, func(name string, content []byte) ([]byte, bool) {
+				return content, name != backupTablesTo+"users.jsonl"
+			}),
+		},
+		{
+			name: "Dropped manifest",
+			backup: rewriteBackup(t, data, func(name string, content []byte) ([]byte, bool) {
+				return content, name != backupManifest
+			}),
+		},
+		{
+			name: "File with path outside of storage",
+			backup: rewriteBackup(t, data, func(name string, content []byte) ([]byte, bool) {
+				return content, true
+			}, backupFilesTo+"../../etc/passwd"),
+		},
+		{
+			name: "Duplicate table",
+			backup: rewriteBackup(t, data, func(name string, content []byte) ([]byte, bool) {
+				return content, true
+			}, backupTablesTo+"users.jsonl", backupTablesTo+"users.jsonl"),
+		},
+		{
+			name:   "Truncated archive",
+			backup: data[:len(data)/2],
+		},
+		{
+			name:   "Not archive",
+			backup: []byte("not a backup"),
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		_, err := VerifyBackup(bytes.NewReader(test.backup))
+		assert.ErrorIs(t, err, ErrBadBackup)
+	}
+}
+
+func TestRestoreBackup(t *testing.T) {
+	data, _ := testServerBackup(t)
+
+	tc := []struct {
+		name  string
+		db    *fakeBackupStorager
+		valid func(db *fakeBackupStorager, files *fileStorage, err error)
+	}{
+		{
+			name: "Restore into empty instance",
+			db:   &fakeBackupStorager{dump: Dump{Schema: 12}},
+			valid: func(db *fakeBackupStorager, files *fileStorage, err error) {
+				require.NoError(t, err)
+				assert.Equal(t, []DumpTable{{Name: "users", Rows: 2}, {Name: "users_data", Rows: 1}, {Name: "vaults"}}, db.loaded)
+				assert.Equal(t, map[string][]string{
+					"users":      {`{"user_id":"u1"}`, `{"user_id":"u2"}`},
+					"users_data": {`{"record_id":"` + testFileID + `"}`},
+					"vaults":     nil,
+				}, db.rows)
+
+				f, err := files.OpenRecord(context.Background(), testFileID)
+				require.NoError(t, err)
+				defer f.Close()
+
+				content, err := io.ReadAll(f)
+				assert.NoError(t, err)
+				assert.Equal(t, "file content", string(content))
+			},
+		},
+		{
+			name: "Instance isn't empty",
+			db:   &fakeBackupStorager{dump: Dump{Schema: 12}, restore: ErrNotEmpty},
+			valid: func(db *fakeBackupStorager, files *fileStorage, err error) {
+				assert.ErrorIs(t, err, ErrNotEmpty)
+				assert.Nil(t, db.loaded)
+
+				_, err = files.OpenRecord(context.Background(), testFileID)
+				assert.ErrorIs(t, err, ErrNotFound)
+			},
+		},
+		{
+			name: "Files are deleted, if DB isn't restored",
+			db:   &fakeBackupStorager{dump: Dump{Schema: 12}, loadFail: ErrBadBackup},
+			valid: func(db *fakeBackupStorager, files *fileStorage, err error) {
+				assert.ErrorIs(t, err, ErrBadBackup)
+
+				_, err = files.OpenRecord(context.Background(), testFileID)
+				assert.ErrorIs(t, err, ErrNotFound)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		files := newFileStorage(t.TempDir())
+		_, err := RestoreBackup(context.Background(), test.db, files, bytes.NewReader(data))
+		test.valid(test.db, files, err)
+	}
+}
@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// backupTables are tables, which are dumped, in order of restore, so references of rows are valid.
+// Sessions aren't dumped, restore would bring back refresh tokens, which were revoked after backup,
+// so users log in again after restore.
+var backupTables = []string{
+	"users", "users_data", "record_versions", "record_tombstones", "vaults", "vault_members", "vault_records",
+}
+
+// backupSkippedTable is table of old backups, which isn't restored.
+const backupSkippedTable = "sessions"
+
+// Queries of backups. Rows are dumped and loaded as JSON, so Postgres converts values of columns by itself.
+var (
+	backupSchemaQuery = `SELECT version FROM schema_migrations`
+	backupFilesQuery  = `SELECT record_id FROM users_data WHERE record_type = $1 ORDER BY record_id`
+	backupEmptyQuery  = backupEmpty()
+)
+
+// backupEmpty makes query, which checks that all tables of backups are empty.
+func backupEmpty() string {
+	checks := make([]string, len(backupTables))
+	for i, table := range backupTables {
+		checks[i] = `EXISTS (SELECT 1 FROM ` + table + `)`
+	}
+
+	return `SELECT ` + strings.Join(checks, ` OR `)
+}
+
+// backupDumpQuery selects rows of table as JSON.
+func backupDumpQuery(table string) string {
+	return `SELECT row_to_json(t)::text FROM ` + table + ` t`
+}
+
+// backupLoadQuery inserts row of table from JSON.
+func backupLoadQuery(table string) string {
+	return `INSERT INTO ` + table + ` SELECT * FROM json_populate_record(NULL::` + table + `, $1)`
+}
+
+// Dump reads rows of all tables and IDs of file records in one read-only transaction with repeatable read,
+// so dump is consistent, while server works. Rows are written to temporary file of table, while they are read.
+func (s *dbStorage) Dump(ctx context.Context) (Dump, error) {
+	var dump Dump
+
+	options := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+	err := s.inTxWith(ctx, options, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, backupSchemaQuery).Scan(&dump.Schema); err != nil {
+			log.Infoln(err)
+
+			return ErrUnknown
+		}
+
+		for _, name := range backupTables {
+			table, err := newDumpTable(name)
+			if err != nil {
+				return err
+			}
+
+			// Table is added before error is checked, so its file is removed with dump.
+			table.Rows, err = dumpRows(ctx, tx, backupDumpQuery(name), table.File)
+			dump.Tables = append(dump.Tables, table)
+			if err != nil {
+				return err
+			}
+		}
+
+		files, err := queryStrings(ctx, tx, backupFilesQuery, entity.TypeFile)
+		if err != nil {
+			return err
+		}
+		dump.Files = files
+
+		return nil
+	})
+	if err != nil {
+		dump.Close()
+
+		return Dump{}, err
+	}
+
+	return dump, nil
+}
+
+// dumpRows writes rows of one text column to w in lines. Returns number of rows.
+func dumpRows(ctx context.Context, q querier, query string, w io.Writer) (int, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		log.Infoln(err)
+
+		return 0, ErrUnknown
+	}
+	defer rows.Close()
+
+	var (
+		bw    = bufio.NewWriter(w)
+		count int
+	)
+
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			log.Infoln(err)
+
+			return 0, ErrUnknown
+		}
+
+		if _, err = bw.WriteString(value + "\n"); err != nil {
+			return 0, err
+		}
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Infoln(err)
+
+		return 0, ErrUnknown
+	}
+
+	return count, bw.Flush()
+}
+
+// CheckRestore checks that DB is empty and its schema has version of dump.
+func (s *dbStorage) CheckRestore(ctx context.Context, schema int64) error {
+	return checkRestore(ctx, s.DB, schema)
+}
+
+// Load inserts rows of dump into empty DB in one transaction. Rows are read from files of tables one by one.
+func (s *dbStorage) Load(ctx context.Context, dump Dump) error {
+	tables := make(map[string]*os.File, len(dump.Tables))
+	for _, table := range dump.Tables {
+		if table.Name == backupSkippedTable {
+			log.Warnf("%s :: %d", "sessions of backup aren't restored", table.Rows)
+			continue
+		}
+		if !isBackupTable(table.Name) {
+			return ErrBadBackup
+		}
+
+		tables[table.Name] = table.File
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := checkRestore(ctx, tx, dump.Schema); err != nil {
+			return err
+		}
+
+		for _, table := range backupTables {
+			if file, ok := tables[table]; ok {
+				if err := loadRows(ctx, tx, table, file); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// loadRows inserts rows of table from file with rows in lines.
+func loadRows(ctx context.Context, tx *sql.Tx, table string, file *os.File) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(file)
+	for {
+		row, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) && row == "" {
+			return nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if _, err = tx.ExecContext(ctx, backupLoadQuery(table), strings.TrimSuffix(row, "\n")); err != nil {
+			log.Warnf("%s :: %v", "load row of "+table+" fault", err)
+
+			return ErrBadBackup
+		}
+	}
+}
+
+// newDumpTable creates temporary file for rows of table.
+func newDumpTable(name string) (DumpTable, error) {
+	file, err := os.CreateTemp("", "dump-"+name+"-*")
+	if err != nil {
+		return DumpTable{}, err
+	}
+
+	return DumpTable{Name: name, File: file}, nil
+}
+
+// Close removes temporary files of tables.
+func (d *Dump) Close() error {
+	var errs []error
+	for _, table := range d.Tables {
+		if table.File == nil {
+			continue
+		}
+
+		table.File.Close()
+		errs = append(errs, os.Remove(table.File.Name()))
+	}
+
+	return errors.Join(errs...)
+}
+
+// querier is DB or transaction.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// checkRestore checks that tables are empty and version of schema is the same.
+func checkRestore(ctx context.Context, q querier, schema int64) error {
+	var version int64
+	if err := q.QueryRowContext(ctx, backupSchemaQuery).Scan(&version); err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	if version != schema {
+		return ErrSchemaMismatch
+	}
+
+	var exists bool
+	if err := q.QueryRowContext(ctx, backupEmptyQuery).Scan(&exists); err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	if exists {
+		return ErrNotEmpty
+	}
+
+	return nil
+}
+
+// queryStrings selects one text column of rows.
+func queryStrings(ctx context.Context, q querier, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+	defer rows.Close()
+
+	var result []string
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+
+		result = append(result, value)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	return result, nil
+}
+
+// isBackupTable checks that table is dumped in backups.
+func isBackupTable(name string) bool {
+	for _, table := range backupTables {
+		if table == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDumpTable makes table of dump with rows in file.
+func testDumpTable(t *testing.T, name string, rows ...string) DumpTable {
+	file, err := os.Create(filepath.Join(t.TempDir(), name))
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+
+	for _, row := range rows {
+		_, err = file.WriteString(row + "\n")
+		require.NoError(t, err)
+	}
+
+	return DumpTable{Name: name, Rows: len(rows), File: file}
+}
+
+// readDumpTable reads rows of table from its file.
+func readDumpTable(table DumpTable) ([]string, error) {
+	if _, err := table.File.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var rows []string
+	scanner := bufio.NewScanner(table.File)
+	for scanner.Scan() {
+		rows = append(rows, scanner.Text())
+	}
+
+	return rows, scanner.Err()
+}
+
+func TestDBStorage_Dump(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Dump all tables and file records in one transaction",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(backupSchemaQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(12))
+				for _, table := range backupTables {
+					rows := sqlmock.NewRows([]string{"row_to_json"})
+					if table == "users" {
+						rows.AddRow(`{"user_id":"u1"}`)
+					}
+					mock.ExpectQuery(backupDumpQuery(table)).WillReturnRows(rows)
+				}
+				mock.ExpectQuery(backupFilesQuery).WithArgs(entity.TypeFile).WillReturnRows(
+					sqlmock.NewRows([]string{"record_id"}).AddRow("f1"),
+				)
+				mock.ExpectCommit()
+			},
+			func() {
+				dump, err := storage.Dump(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, int64(12), dump.Schema)
+				assert.Len(t, dump.Tables, len(backupTables))
+				for _, table := range dump.Tables {
+					assert.NotEqual(t, "sessions", table.Name)
+				}
+				assert.Equal(t, "users", dump.Tables[0].Name)
+				assert.Equal(t, 1, dump.Tables[0].Rows)
+				rows, err := readDumpTable(dump.Tables[0])
+				assert.NoError(t, err)
+				assert.Equal(t, []string{`{"user_id":"u1"}`}, rows)
+				assert.Equal(t, []string{"f1"}, dump.Files)
+				assert.NoError(t, mock.ExpectationsWereMet())
+
+				t.Log("Files of tables are removed by close")
+				name := dump.Tables[0].File.Name()
+				assert.NoError(t, dump.Close())
+				_, err = os.Stat(name)
+				assert.ErrorIs(t, err, os.ErrNotExist)
+			},
+		},
+		{
+			"Dump, but DB will return error",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(backupSchemaQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(12))
+				mock.ExpectQuery(backupDumpQuery("users")).WillReturnError(errors.New("some DB error"))
+				mock.ExpectRollback()
+			},
+			func() {
+				_, err := storage.Dump(context.Background())
+				assert.ErrorIs(t, err, ErrUnknown)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_Load(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	dump := Dump{Schema: 12, Tables: []DumpTable{
+		testDumpTable(t, "users_data", `{"record_id":"r1"}`),
+		testDumpTable(t, "users", `{"user_id":"u1"}`),
+	}}
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Load rows into empty DB in order of references",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(backupSchemaQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(12))
+				mock.ExpectQuery(backupEmptyQuery).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(backupLoadQuery("users")).WithArgs(`{"user_id":"u1"}`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(backupLoadQuery("users_data")).WithArgs(`{"record_id":"r1"}`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			func() {
+				assert.NoError(t, storage.Load(context.Background(), dump))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Load into DB, which isn't empty",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(backupSchemaQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(12))
+				mock.ExpectQuery(backupEmptyQuery).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			func() {
+				assert.ErrorIs(t, storage.Load(context.Background(), dump), ErrNotEmpty)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Load into DB with other schema",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(backupSchemaQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(13))
+				mock.ExpectRollback()
+			},
+			func() {
+				assert.ErrorIs(t, storage.Load(context.Background(), dump), ErrSchemaMismatch)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Load row, which DB rejects, rolls back all rows",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(backupSchemaQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(12))
+				mock.ExpectQuery(backupEmptyQuery).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(backupLoadQuery("users")).WithArgs(`{"user_id":"u1"}`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(backupLoadQuery("users_data")).WithArgs(`{"record_id":"r1"}`).WillReturnError(errors.New("some DB error"))
+				mock.ExpectRollback()
+			},
+			func() {
+				assert.ErrorIs(t, storage.Load(context.Background(), dump), ErrBadBackup)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Load old backup with sessions, sessions aren't restored",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(backupSchemaQuery).WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(12))
+				mock.ExpectQuery(backupEmptyQuery).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(backupLoadQuery("users")).WithArgs(`{"user_id":"u1"}`).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			func() {
+				assert.NoError(t, storage.Load(context.Background(), Dump{Schema: 12, Tables: []DumpTable{
+					testDumpTable(t, "users", `{"user_id":"u1"}`),
+					testDumpTable(t, "sessions", `{"token_hash":"revoked"}`),
+				}}))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Load unknown table",
+			func() {},
+			func() {
+				err := storage.Load(context.Background(), Dump{Tables: []DumpTable{{Name: "pg_authid"}}})
+				assert.ErrorIs(t, err, ErrBadBackup)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
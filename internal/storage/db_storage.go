@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"encoding/hex"
 	"errors"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/entity"
@@ -83,9 +85,14 @@ func (s *dbStorage) CreateUser(credentials entity.UserCredentials) error {
 
 	_, err = s.DB.ExecContext(
 		ctx,
-		`INSERT INTO users (login, password) VALUES ($1, $2)`,
+		`INSERT INTO users (login, password, kdf_salt, kdf_time, kdf_memory, kdf_threads, public_key) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
 		credentials.Login,
 		credentials.Password,
+		hex.EncodeToString(credentials.KDF.Salt),
+		credentials.KDF.Time,
+		credentials.KDF.Memory,
+		credentials.KDF.Threads,
+		hex.EncodeToString(credentials.PublicKey),
 	)
 	if err != nil {
 		log.Infoln(err)
@@ -96,8 +103,206 @@ func (s *dbStorage) CreateUser(credentials entity.UserCredentials) error {
 	return nil
 }
 
-// LoginUser check if credentials are valid. Returns userID.
-func (s *dbStorage) LoginUser(credentials entity.UserCredentials) (entity.UserID, error) {
+// GetUser gets user with password hash by login.
+func (s *dbStorage) GetUser(login string) (entity.User, error) {
+	user := entity.User{Login: login}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	row := s.DB.QueryRowContext(
+		ctx,
+		`SELECT user_id, password FROM users WHERE login = $1`,
+		login,
+	)
+
+	err := row.Scan(&user.ID, &user.PasswordHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Infoln(err)
+
+		return entity.User{}, ErrWrongCredentials
+	}
+
+	if err != nil || row.Err() != nil {
+		log.Infoln(err)
+
+		return entity.User{}, ErrUnknown
+	}
+
+	return user, nil
+}
+
+// UpdatePasswordHash replaces password hash of user.
+func (s *dbStorage) UpdatePasswordHash(userID entity.UserID, passwordHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := s.DB.ExecContext(
+		ctx,
+		`UPDATE users SET password = $1 WHERE user_id = $2`,
+		passwordHash,
+		userID,
+	)
+	if err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetKDFParams gets key derivation parameters of user.
+func (s *dbStorage) GetKDFParams(userID entity.UserID) (entity.KDFParams, error) {
+	var (
+		params  entity.KDFParams
+		hexSalt string
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	row := s.DB.QueryRowContext(
+		ctx,
+		`SELECT kdf_salt, kdf_time, kdf_memory, kdf_threads, kdf_legacy FROM users WHERE user_id = $1`,
+		userID,
+	)
+
+	err := row.Scan(&hexSalt, &params.Time, &params.Memory, &params.Threads, &params.Legacy)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Infoln(err)
+
+		return params, ErrWrongCredentials
+	}
+
+	if err != nil || row.Err() != nil {
+		log.Infoln(err)
+
+		return params, ErrUnknown
+	}
+
+	if hexSalt != "" {
+		if params.Salt, err = hex.DecodeString(hexSalt); err != nil {
+			log.Infoln(err)
+
+			return entity.KDFParams{}, ErrUnknown
+		}
+	}
+
+	return params, nil
+}
+
+// SetKDFParams saves key derivation parameters of legacy user, who starts upgrade of legacy key.
+// Parameters are saved only once, so records, which were already encrypted by new key, stay readable
+// after interrupted upgrade. User stays legacy until upgrade is finished.
+func (s *dbStorage) SetKDFParams(ctx context.Context, params entity.KDFParams) error {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in setting KDF params")
+		return ErrUnauthenticated
+	}
+
+	err := checkAffected(s.DB.ExecContext(
+		ctx,
+		`UPDATE users SET kdf_salt = $1, kdf_time = $2, kdf_memory = $3, kdf_threads = $4 WHERE user_id = $5 AND kdf_legacy AND kdf_salt = ''`,
+		hex.EncodeToString(params.Salt),
+		params.Time,
+		params.Memory,
+		params.Threads,
+		userID,
+	))
+	if errors.Is(err, ErrNotFound) {
+		return ErrConflict
+	}
+
+	return err
+}
+
+// FinishKDFUpgrade clears legacy mark of user, whose records are encrypted by key derived with saved parameters.
+// Previous versions of records are deleted, they are encrypted by legacy key, which anyone can compute.
+func (s *dbStorage) FinishKDFUpgrade(ctx context.Context, params entity.KDFParams) error {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in finishing KDF upgrade")
+		return ErrUnauthenticated
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		err := checkAffected(tx.ExecContext(
+			ctx,
+			`UPDATE users SET kdf_legacy = FALSE WHERE user_id = $1 AND kdf_legacy AND kdf_salt = $2 AND kdf_time = $3 AND kdf_memory = $4 AND kdf_threads = $5`,
+			userID,
+			hex.EncodeToString(params.Salt),
+			params.Time,
+			params.Memory,
+			params.Threads,
+		))
+		if errors.Is(err, ErrNotFound) {
+			return ErrConflict
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.ExecContext(
+			ctx,
+			`DELETE FROM record_versions WHERE record_id IN (SELECT record_id FROM users_data WHERE user_id = $1)`,
+			userID,
+		); err != nil {
+			log.Infoln(err)
+
+			return ErrUnknown
+		}
+
+		return nil
+	})
+}
+
+// CreateSession saves new session of user by refresh token hash. Expired sessions of user are removed.
+func (s *dbStorage) CreateSession(userID entity.UserID, tokenHash string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(
+			ctx,
+			`DELETE FROM sessions WHERE user_id = $1 AND expires_at <= now()`,
+			userID,
+		); err != nil {
+			log.Infoln(err)
+
+			return ErrUnknown
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO sessions (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+			userID,
+			tokenHash,
+			expiresAt,
+		); err != nil {
+			log.Infoln(err)
+
+			return ErrUnknown
+		}
+
+		return nil
+	})
+}
+
+// RotateSession replaces refresh token hash of not expired session and prolongs it. Returns user of session.
+func (s *dbStorage) RotateSession(tokenHash, newTokenHash string, expiresAt time.Time) (entity.UserID, error) {
 	var userID entity.UserID
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -105,30 +310,66 @@ func (s *dbStorage) LoginUser(credentials entity.UserCredentials) (entity.UserID
 
 	row := s.DB.QueryRowContext(
 		ctx,
-		`SELECT user_id FROM users WHERE login = $1 AND password = $2`,
-		credentials.Login,
-		credentials.Password,
+		`UPDATE sessions SET token_hash = $1, expires_at = $2 WHERE token_hash = $3 AND expires_at > now() RETURNING user_id`,
+		newTokenHash,
+		expiresAt,
+		tokenHash,
 	)
 
 	err := row.Scan(&userID)
 	if errors.Is(err, sql.ErrNoRows) {
 		log.Infoln(err)
 
-		return userID, ErrWrongCredentials
+		return "", ErrUnauthenticated
 	}
 
 	if err != nil || row.Err() != nil {
 		log.Infoln(err)
 
-		return userID, ErrUnknown
+		return "", ErrUnknown
 	}
 
 	return userID, nil
 }
 
+// DeleteSession deletes session by refresh token hash.
+func (s *dbStorage) DeleteSession(tokenHash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := checkAffected(s.DB.ExecContext(
+		ctx,
+		`DELETE FROM sessions WHERE token_hash = $1`,
+		tokenHash,
+	))
+	if errors.Is(err, ErrNotFound) {
+		return ErrUnauthenticated
+	}
+
+	return err
+}
+
+// DeleteSessions deletes all sessions of user.
+func (s *dbStorage) DeleteSessions(userID entity.UserID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := s.DB.ExecContext(
+		ctx,
+		`DELETE FROM sessions WHERE user_id = $1`,
+		userID,
+	); err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	return nil
+}
+
 // GetRecordsInfo gets all DB record from this user.
 func (s *dbStorage) GetRecordsInfo(ctx context.Context) ([]entity.Record, error) {
-	userID, ok := ctx.Value("userID").(entity.UserID)
+	userID, ok := userFromContext(ctx)
 	if !ok {
 		log.Println("Failed get userID from context in getting all records")
 		return nil, ErrUnauthenticated
@@ -136,7 +377,7 @@ func (s *dbStorage) GetRecordsInfo(ctx context.Context) ([]entity.Record, error)
 
 	rows, err := s.DB.QueryContext(
 		ctx,
-		`SELECT record_id, record_type, metadata FROM users_data WHERE user_id = $1`,
+		`SELECT record_id, record_type, metadata, name, tags, folder, blind_index, revision, updated_at FROM users_data WHERE user_id = $1`,
 		userID,
 	)
 	if err != nil {
@@ -149,13 +390,20 @@ func (s *dbStorage) GetRecordsInfo(ctx context.Context) ([]entity.Record, error)
 
 	result := make([]entity.Record, 0, 10)
 
-	var row entity.Record
 	for rows.Next() {
-		if err := rows.Scan(&row.ID, &row.Type, &row.Metadata); err != nil {
+		var (
+			row              entity.Record
+			tags, blindIndex string
+		)
+
+		if err := rows.Scan(
+			&row.ID, &row.Type, &row.Metadata, &row.Name, &tags, &row.Folder, &blindIndex, &row.Revision, &row.UpdatedAt,
+		); err != nil {
 			log.Infoln(err)
 
 			return nil, ErrUnknown
 		}
+		row.Tags, row.BlindIndex = decodeList(tags), decodeList(blindIndex)
 
 		result = append(result, row)
 	}
@@ -168,9 +416,112 @@ func (s *dbStorage) GetRecordsInfo(ctx context.Context) ([]entity.Record, error)
 	return result, nil
 }
 
+// recordsSortColumns are columns, which records list is sorted by.
+var recordsSortColumns = map[entity.RecordsSort]string{
+	entity.SortByName:      "lower(name)",
+	entity.SortByUpdatedAt: "updated_at",
+	entity.SortByType:      "record_type",
+}
+
+// ListRecords gets page of user records, which match query, without data. Total is counted with page,
+// so page after the last one has zero total.
+func (s *dbStorage) ListRecords(ctx context.Context, query entity.RecordsQuery) (entity.RecordsPage, error) {
+	page := entity.RecordsPage{Records: make([]entity.Record, 0, 10)}
+
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in listing records")
+		return page, ErrUnauthenticated
+	}
+
+	query = query.Normalize()
+	args := []interface{}{userID}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	conditions := []string{"user_id = $1"}
+	if query.Search != "" {
+		search := arg("%" + escapeLike(query.Search) + "%")
+		condition := "name ILIKE " + search + " OR metadata ILIKE " + search
+
+		// Encrypted metadata is found by hashes of all search words.
+		if len(query.BlindIndex) > 0 {
+			hashes := make([]string, 0, len(query.BlindIndex))
+			for _, hash := range query.BlindIndex {
+				hashes = append(hashes, "blind_index LIKE "+arg("%,"+escapeLike(hash)+",%"))
+			}
+			condition += " OR (" + strings.Join(hashes, " AND ") + ")"
+		}
+
+		conditions = append(conditions, "("+condition+")")
+	}
+	for _, tag := range query.Tags {
+		conditions = append(conditions, "tags LIKE "+arg("%,"+escapeLike(tag)+",%"))
+	}
+	if query.Folder != "" {
+		conditions = append(conditions, "(folder = "+arg(query.Folder)+" OR folder LIKE "+arg(escapeLike(query.Folder)+"/%")+")")
+	}
+	if len(query.Types) > 0 {
+		types := make([]string, 0, len(query.Types))
+		for _, recordType := range query.Types {
+			types = append(types, arg(recordType))
+		}
+		conditions = append(conditions, "record_type IN ("+strings.Join(types, ", ")+")")
+	}
+
+	order := " ASC"
+	if query.Descending {
+		order = " DESC"
+	}
+
+	rows, err := s.DB.QueryContext(
+		ctx,
+		`SELECT record_id, record_type, metadata, name, tags, folder, blind_index, revision, updated_at, COUNT(*) OVER () FROM users_data WHERE `+
+			strings.Join(conditions, " AND ")+
+			` ORDER BY `+recordsSortColumns[query.Sort]+order+`, record_id`+order+
+			` LIMIT `+arg(query.Limit)+` OFFSET `+arg(query.Offset),
+		args...,
+	)
+	if err != nil {
+		log.Infoln(err)
+
+		return page, ErrUnknown
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			record           entity.Record
+			tags, blindIndex string
+		)
+
+		if err := rows.Scan(
+			&record.ID, &record.Type, &record.Metadata, &record.Name, &tags, &record.Folder, &blindIndex,
+			&record.Revision, &record.UpdatedAt, &page.Total,
+		); err != nil {
+			log.Infoln(err)
+
+			return entity.RecordsPage{}, ErrUnknown
+		}
+		record.Tags, record.BlindIndex = decodeList(tags), decodeList(blindIndex)
+
+		page.Records = append(page.Records, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("Failed get rows in listing records:", err)
+		return entity.RecordsPage{}, ErrUnknown
+	}
+
+	return page, nil
+}
+
 // CreateRecord saves new record to DB, returns recordID.
 func (s *dbStorage) CreateRecord(ctx context.Context, record entity.Record) (string, error) {
-	userID, ok := ctx.Value("userID").(entity.UserID)
+	userID, ok := userFromContext(ctx)
 	if !ok {
 		log.Println("Failed get userID from context in getting all records")
 		return "", ErrUnauthenticated
@@ -178,20 +529,45 @@ func (s *dbStorage) CreateRecord(ctx context.Context, record entity.Record) (str
 
 	hexDataString := hex.EncodeToString(record.Data)
 
-	row := s.DB.QueryRowContext(
-		ctx,
-		`INSERT INTO users_data (user_id, record_type, metadata, encoded_data) VALUES ($1, $2, $3, $4) RETURNING record_id`,
-		userID,
-		record.Type,
-		record.Metadata,
-		hexDataString,
-	)
-
 	var recordID string
-	if err := row.Scan(&recordID); err != nil || row.Err() != nil {
-		log.Infoln(err)
 
-		return "", ErrUnknown
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		revision, err := nextRevision(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO users_data (user_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, revision) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING record_id`
+		args := []interface{}{
+			userID,
+			record.Type,
+			record.Metadata,
+			record.Name,
+			encodeList(entity.NormalizeTags(record.Tags)),
+			entity.NormalizeFolder(record.Folder),
+			encodeList(record.BlindIndex),
+			hexDataString,
+			revision,
+		}
+
+		// ID is set for file records, which files are written before records.
+		if record.ID != "" {
+			query = `INSERT INTO users_data (user_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, revision, record_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING record_id`
+			args = append(args, record.ID)
+		}
+
+		row := tx.QueryRowContext(ctx, query, args...)
+
+		if err = row.Scan(&recordID); err != nil || row.Err() != nil {
+			log.Infoln(err)
+
+			return ErrUnknown
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
 
 	return recordID, nil
@@ -201,7 +577,7 @@ func (s *dbStorage) CreateRecord(ctx context.Context, record entity.Record) (str
 func (s *dbStorage) GetRecord(ctx context.Context, recordID string) (entity.Record, error) {
 	record := entity.Record{}
 
-	userID, ok := ctx.Value("userID").(entity.UserID)
+	userID, ok := userFromContext(ctx)
 	if !ok {
 		log.Println("Failed get userID from context in getting all records")
 		return record, ErrUnauthenticated
@@ -209,13 +585,16 @@ func (s *dbStorage) GetRecord(ctx context.Context, recordID string) (entity.Reco
 
 	row := s.DB.QueryRowContext(
 		ctx,
-		`SELECT record_id, record_type, metadata, encoded_data FROM users_data WHERE record_id = $1 AND user_id = $2`,
+		`SELECT record_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, revision, updated_at FROM users_data WHERE record_id = $1 AND user_id = $2`,
 		recordID,
 		userID,
 	)
 
-	var hexDataString string
-	err := row.Scan(&record.ID, &record.Type, &record.Metadata, &hexDataString)
+	var hexDataString, tags, blindIndex string
+	err := row.Scan(
+		&record.ID, &record.Type, &record.Metadata, &record.Name, &tags, &record.Folder, &blindIndex,
+		&hexDataString, &record.Revision, &record.UpdatedAt,
+	)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		log.Infoln(err)
@@ -234,24 +613,459 @@ func (s *dbStorage) GetRecord(ctx context.Context, recordID string) (entity.Reco
 
 		return record, ErrUnknown
 	}
+	record.Tags, record.BlindIndex = decodeList(tags), decodeList(blindIndex)
 
 	return record, nil
 }
 
-// DeleteRecord deletes record from DB by ID.
-func (s *dbStorage) DeleteRecord(ctx context.Context, recordID string) error {
-	userID, ok := ctx.Value("userID").(entity.UserID)
+// DeleteRecord deletes record from DB by ID, if it wasn't changed after revision. Tombstone is left instead of record.
+func (s *dbStorage) DeleteRecord(ctx context.Context, recordID string, revision int64) error {
+	userID, ok := userFromContext(ctx)
 	if !ok {
 		log.Println("Failed get userID from context in getting all records")
 		return ErrUnauthenticated
 	}
 
-	result, err := s.DB.ExecContext(
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		newRevision, err := nextRevision(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if err = checkRevision(ctx, tx, recordID, userID, revision); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			`DELETE FROM users_data WHERE record_id = $1 AND user_id = $2`,
+			recordID,
+			userID,
+		)
+		if err = checkAffected(result, err); err != nil {
+			return err
+		}
+
+		result, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO record_tombstones (record_id, user_id, revision) VALUES ($1, $2, $3)`,
+			recordID,
+			userID,
+			newRevision,
+		)
+
+		return checkAffected(result, err)
+	})
+}
+
+// UpdateRecord replaces data and metadata of record, if it wasn't changed after record revision.
+// Previous version is saved to record history.
+func (s *dbStorage) UpdateRecord(ctx context.Context, record entity.Record) error {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in updating record")
+		return ErrUnauthenticated
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		revision, err := nextRevision(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if err = checkRevision(ctx, tx, record.ID, userID, record.Revision); err != nil {
+			return err
+		}
+
+		if err = archiveRecord(ctx, tx, record.ID, userID); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE users_data SET metadata = $1, name = $2, tags = $3, folder = $4, blind_index = $5, encoded_data = $6, version = version + 1, revision = $7, updated_at = now() WHERE record_id = $8 AND user_id = $9 AND record_type = $10`,
+			record.Metadata,
+			record.Name,
+			encodeList(entity.NormalizeTags(record.Tags)),
+			entity.NormalizeFolder(record.Folder),
+			encodeList(record.BlindIndex),
+			hex.EncodeToString(record.Data),
+			revision,
+			record.ID,
+			userID,
+			record.Type,
+		)
+
+		return checkAffected(result, err)
+	})
+}
+
+// GetRecordVersions gets previous versions of record, newest first.
+func (s *dbStorage) GetRecordVersions(ctx context.Context, recordID string) ([]entity.RecordVersion, error) {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in getting record versions")
+		return nil, ErrUnauthenticated
+	}
+
+	rows, err := s.DB.QueryContext(
+		ctx,
+		`SELECT v.version, v.metadata, v.replaced_at FROM record_versions v JOIN users_data d ON d.record_id = v.record_id WHERE v.record_id = $1 AND d.user_id = $2 ORDER BY v.version DESC`,
+		recordID,
+		userID,
+	)
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	defer rows.Close()
+
+	result := make([]entity.RecordVersion, 0, 10)
+
+	for rows.Next() {
+		version := entity.RecordVersion{RecordID: recordID}
+
+		if err := rows.Scan(&version.Version, &version.Metadata, &version.ReplacedAt); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+
+		result = append(result, version)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("Failed get rows in getting record versions:", err)
+		return nil, ErrUnknown
+	}
+
+	return result, nil
+}
+
+// RestoreRecordVersion makes previous version of record current, if record wasn't changed after revision.
+// Replaced version is saved to record history too.
+func (s *dbStorage) RestoreRecordVersion(ctx context.Context, recordID string, version int32, revision int64) error {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in restoring record version")
+		return ErrUnauthenticated
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		newRevision, err := nextRevision(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		if err = checkRevision(ctx, tx, recordID, userID, revision); err != nil {
+			return err
+		}
+
+		if err = archiveRecord(ctx, tx, recordID, userID); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			`UPDATE users_data SET metadata = v.metadata, name = v.name, tags = v.tags, folder = v.folder, blind_index = v.blind_index, encoded_data = v.encoded_data, version = users_data.version + 1, revision = $1, updated_at = now() FROM record_versions v WHERE users_data.record_id = $2 AND users_data.user_id = $3 AND v.record_id = users_data.record_id AND v.version = $4`,
+			newRevision,
+			recordID,
+			userID,
+			version,
+		)
+
+		return checkAffected(result, err)
+	})
+}
+
+// Changes of synchronisation are returned by pages, so response fits into gRPC message:
+// page has at most syncPageSize changes and is cut, when data of its records exceeds syncPageBytes.
+// Tests make pages smaller.
+var (
+	syncPageSize        = 500
+	syncPageBytes int64 = 1 << 20
+)
+
+// Sync gets records changed and deleted after revision. Changes are returned up to current revision of user,
+// changes committed later will be got by next synchronisation. If there are more changes than fit into page,
+// revision of the last change in page is returned with More, so client continues from it.
+// Every change has its own revision, so page is never cut inside revision.
+func (s *dbStorage) Sync(ctx context.Context, sinceRevision int64) (entity.RecordChanges, error) {
+	changes := entity.RecordChanges{}
+
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in syncing records")
+		return changes, ErrUnauthenticated
+	}
+
+	row := s.DB.QueryRowContext(
+		ctx,
+		`SELECT revision FROM users WHERE user_id = $1`,
+		userID,
+	)
+
+	err := row.Scan(&changes.Revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Infoln(err)
+
+		return changes, ErrUnauthenticated
+	}
+	if err != nil || row.Err() != nil {
+		log.Infoln(err)
+
+		return changes, ErrUnknown
+	}
+
+	// One more change is got, so it's known, whether page is the last one.
+	records, err := s.changedRecords(ctx, userID, sinceRevision, changes.Revision, syncPageSize+1)
+	if err != nil {
+		return entity.RecordChanges{}, err
+	}
+
+	deleted, err := s.tombstones(ctx, userID, sinceRevision, changes.Revision, syncPageSize+1)
+	if err != nil {
+		return entity.RecordChanges{}, err
+	}
+
+	return pageChanges(records, deleted, changes.Revision), nil
+}
+
+// pageChanges merges changed and deleted records by revisions into page.
+func pageChanges(records []entity.Record, deleted []entity.Tombstone, revision int64) entity.RecordChanges {
+	page := entity.RecordChanges{
+		Records:  make([]entity.Record, 0, len(records)),
+		Deleted:  make([]entity.Tombstone, 0, len(deleted)),
+		Revision: revision,
+	}
+
+	var size int64
+
+	for len(records) > 0 || len(deleted) > 0 {
+		full := len(page.Records)+len(page.Deleted) >= syncPageSize || size >= syncPageBytes
+		if full {
+			page.More = true
+			break
+		}
+
+		if len(deleted) == 0 || (len(records) > 0 && records[0].Revision < deleted[0].Revision) {
+			record := records[0]
+			records = records[1:]
+
+			size += int64(len(record.Data) + len(record.Metadata) + len(record.Name))
+			page.Records = append(page.Records, record)
+			page.Revision = record.Revision
+		} else {
+			tombstone := deleted[0]
+			deleted = deleted[1:]
+
+			page.Deleted = append(page.Deleted, tombstone)
+			page.Revision = tombstone.Revision
+		}
+	}
+
+	if !page.More {
+		page.Revision = revision
+	}
+
+	return page
+}
+
+// changedRecords gets at most limit records with data, which were changed in revisions range (from, to].
+func (s *dbStorage) changedRecords(ctx context.Context, userID entity.UserID, from, to int64, limit int) ([]entity.Record, error) {
+	rows, err := s.DB.QueryContext(
+		ctx,
+		`SELECT record_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, revision, updated_at FROM users_data WHERE user_id = $1 AND revision > $2 AND revision <= $3 ORDER BY revision LIMIT $4`,
+		userID,
+		from,
+		to,
+		limit,
+	)
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	defer rows.Close()
+
+	result := make([]entity.Record, 0, 10)
+
+	for rows.Next() {
+		var (
+			record                          entity.Record
+			hexDataString, tags, blindIndex string
+		)
+
+		if err := rows.Scan(
+			&record.ID, &record.Type, &record.Metadata, &record.Name, &tags, &record.Folder, &blindIndex,
+			&hexDataString, &record.Revision, &record.UpdatedAt,
+		); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+		record.Tags, record.BlindIndex = decodeList(tags), decodeList(blindIndex)
+
+		if record.Data, err = hex.DecodeString(hexDataString); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+
+		result = append(result, record)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("Failed get rows in getting changed records:", err)
+		return nil, ErrUnknown
+	}
+
+	return result, nil
+}
+
+// tombstones gets at most limit records, which were deleted in revisions range (from, to].
+func (s *dbStorage) tombstones(ctx context.Context, userID entity.UserID, from, to int64, limit int) ([]entity.Tombstone, error) {
+	rows, err := s.DB.QueryContext(
+		ctx,
+		`SELECT record_id, revision, deleted_at FROM record_tombstones WHERE user_id = $1 AND revision > $2 AND revision <= $3 ORDER BY revision LIMIT $4`,
+		userID,
+		from,
+		to,
+		limit,
+	)
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	defer rows.Close()
+
+	result := make([]entity.Tombstone, 0, 10)
+
+	for rows.Next() {
+		var tombstone entity.Tombstone
+
+		if err := rows.Scan(&tombstone.RecordID, &tombstone.Revision, &tombstone.DeletedAt); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+
+		result = append(result, tombstone)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Println("Failed get rows in getting tombstones:", err)
+		return nil, ErrUnknown
+	}
+
+	return result, nil
+}
+
+// nextRevision increments revision of user data and returns it. Row of user stays locked until transaction ends,
+// so changes of one user are made one by one and revisions are committed in order.
+func nextRevision(ctx context.Context, tx *sql.Tx, userID entity.UserID) (int64, error) {
+	row := tx.QueryRowContext(
+		ctx,
+		`UPDATE users SET revision = revision + 1 WHERE user_id = $1 RETURNING revision`,
+		userID,
+	)
+
+	var revision int64
+
+	err := row.Scan(&revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Infoln(err)
+
+		return 0, ErrUnauthenticated
+	}
+	if err != nil || row.Err() != nil {
+		log.Infoln(err)
+
+		return 0, ErrUnknown
+	}
+
+	return revision, nil
+}
+
+// checkRevision checks that record wasn't changed after revision, which client knows.
+// Zero revision isn't checked. Must be called after nextRevision, which locks changes of user.
+func checkRevision(ctx context.Context, tx *sql.Tx, recordID string, userID entity.UserID, revision int64) error {
+	row := tx.QueryRowContext(
 		ctx,
-		`DELETE FROM users_data WHERE record_id = $1 AND user_id = $2`,
+		`SELECT revision FROM users_data WHERE record_id = $1 AND user_id = $2`,
 		recordID,
 		userID,
 	)
+
+	var current int64
+
+	err := row.Scan(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil || row.Err() != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	if revision != 0 && revision != current {
+		return ErrConflict
+	}
+
+	return nil
+}
+
+// archiveRecord copies current version of record to record history.
+func archiveRecord(ctx context.Context, tx *sql.Tx, recordID string, userID entity.UserID) error {
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO record_versions (record_id, version, record_type, metadata, name, tags, folder, blind_index, encoded_data) SELECT record_id, version, record_type, metadata, name, tags, folder, blind_index, encoded_data FROM users_data WHERE record_id = $1 AND user_id = $2`,
+		recordID,
+		userID,
+	)
+
+	return checkAffected(result, err)
+}
+
+// encodeList joins tags or blind index hashes to stored form ",item1,item2,", so every item can be found by LIKE.
+func encodeList(items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+
+	return "," + strings.Join(items, ",") + ","
+}
+
+// decodeList splits stored tags or blind index hashes.
+func decodeList(items string) []string {
+	items = strings.Trim(items, ",")
+	if items == "" {
+		return nil
+	}
+
+	return strings.Split(items, ",")
+}
+
+// escapeLike escapes special characters of LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// userFromContext returns ID of authenticated user of request.
+func userFromContext(ctx context.Context) (entity.UserID, bool) {
+	identity, ok := entity.IdentityFromContext(ctx)
+
+	return identity.UserID, ok
+}
+
+// checkAffected converts result of query, which must change some rows, to storage error.
+func checkAffected(result sql.Result, err error) error {
 	if err != nil {
 		log.Infoln(err)
 
@@ -267,3 +1081,34 @@ func (s *dbStorage) DeleteRecord(ctx context.Context, recordID string) error {
 
 	return nil
 }
+
+// inTx runs f in transaction. Transaction is committed, if f returns nil, otherwise it's rolled back.
+func (s *dbStorage) inTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	return s.inTxWith(ctx, nil, f)
+}
+
+// inTxWith runs f in transaction with options like inTx.
+func (s *dbStorage) inTxWith(ctx context.Context, options *sql.TxOptions, f func(tx *sql.Tx) error) error {
+	tx, err := s.DB.BeginTx(ctx, options)
+	if err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	if err = f(tx); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			log.Infoln(errRollback)
+		}
+
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/config"
 	"github.com/bbt-t/lets-go-keep/internal/entity"
@@ -43,8 +44,8 @@ func TestDBStorage_CreateUser(t *testing.T) {
 					[]string{"count"}).AddRow(0),
 				)
 				mock.ExpectExec(
-					`INSERT INTO users (login, password) VALUES ($1, $2)`,
-				).WithArgs("my_login", "my_password").WillReturnResult(
+					`INSERT INTO users (login, password, kdf_salt, kdf_time, kdf_memory, kdf_threads, public_key) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+				).WithArgs("my_login", "my_password", "0102", 3, 65536, 4, "0a0b").WillReturnResult(
 					sqlmock.NewResult(0, 1),
 				)
 			},
@@ -52,6 +53,13 @@ func TestDBStorage_CreateUser(t *testing.T) {
 				err := storage.CreateUser(entity.UserCredentials{
 					Login:    "my_login",
 					Password: "my_password",
+					KDF: entity.KDFParams{
+						Salt:    []byte{0x01, 0x02},
+						Time:    3,
+						Memory:  65536,
+						Threads: 4,
+					},
+					PublicKey: []byte{0x0a, 0x0b},
 				})
 				assert.NoError(t, err)
 				assert.NoError(t, mock.ExpectationsWereMet())
@@ -64,8 +72,8 @@ func TestDBStorage_CreateUser(t *testing.T) {
 					`SELECT COUNT(*) FROM users WHERE login = $1`,
 				).WithArgs("my_login").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 				mock.ExpectExec(
-					`INSERT INTO users (login, password) VALUES ($1, $2)`,
-				).WithArgs("my_login", "my_password").WillReturnError(errors.New("some DB error"))
+					`INSERT INTO users (login, password, kdf_salt, kdf_time, kdf_memory, kdf_threads, public_key) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+				).WithArgs("my_login", "my_password", "", 0, 0, 0, "").WillReturnError(errors.New("some DB error"))
 			},
 			func() {
 				err := storage.CreateUser(entity.UserCredentials{
@@ -101,7 +109,7 @@ func TestDBStorage_CreateUser(t *testing.T) {
 	}
 }
 
-func TestDBStorage_LoginUser(t *testing.T) {
+func TestDBStorage_GetUser(t *testing.T) {
 	storage := newDBStorage(dbConnectionURL)
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	assert.NoError(t, err)
@@ -113,54 +121,50 @@ func TestDBStorage_LoginUser(t *testing.T) {
 		valid func()
 	}{
 		{
-			"Login user with good credentials",
+			"Get existing user",
 			func() {
 				mock.ExpectQuery(
-					`SELECT user_id FROM users WHERE login = $1 AND password = $2`,
-				).WithArgs("my_login", "my_password").WillReturnRows(
-					sqlmock.NewRows([]string{"user_id"}).AddRow("6584c88d-1bb4-4686-83be-925abb24fc20"))
+					`SELECT user_id, password FROM users WHERE login = $1`,
+				).WithArgs("my_login").WillReturnRows(
+					sqlmock.NewRows([]string{"user_id", "password"}).
+						AddRow("6584c88d-1bb4-4686-83be-925abb24fc20", "$argon2id$hash"))
 			},
 			func() {
-				userID, err := storage.LoginUser(entity.UserCredentials{
-					Login:    "my_login",
-					Password: "my_password",
-				})
+				user, err := storage.GetUser("my_login")
 				assert.NoError(t, err)
-				assert.Equal(t, entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"), userID)
+				assert.Equal(t, entity.User{
+					ID:           "6584c88d-1bb4-4686-83be-925abb24fc20",
+					Login:        "my_login",
+					PasswordHash: "$argon2id$hash",
+				}, user)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Login user with good credentials, but DB will return error",
+			"Get user, but DB will return error",
 			func() {
 				mock.ExpectQuery(
-					`SELECT user_id FROM users WHERE login = $1 AND password = $2`,
-				).WithArgs("my_login", "my_password").WillReturnError(errors.New("some DB error"))
+					`SELECT user_id, password FROM users WHERE login = $1`,
+				).WithArgs("my_login").WillReturnError(errors.New("some DB error"))
 			},
 			func() {
-				userID, err := storage.LoginUser(entity.UserCredentials{
-					Login:    "my_login",
-					Password: "my_password",
-				})
+				user, err := storage.GetUser("my_login")
 				assert.Equal(t, ErrUnknown, err)
-				assert.Equal(t, entity.UserID(""), userID)
+				assert.Empty(t, user)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Login user with bad credentials",
+			"Get not existing user",
 			func() {
 				mock.ExpectQuery(
-					`SELECT user_id FROM users WHERE login = $1 AND password = $2`,
-				).WithArgs("my_login", "my_password").WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+					`SELECT user_id, password FROM users WHERE login = $1`,
+				).WithArgs("my_login").WillReturnRows(sqlmock.NewRows([]string{"user_id", "password"}))
 			},
 			func() {
-				userID, err := storage.LoginUser(entity.UserCredentials{
-					Login:    "my_login",
-					Password: "my_password",
-				})
+				user, err := storage.GetUser("my_login")
 				assert.Equal(t, ErrWrongCredentials, err)
-				assert.Equal(t, entity.UserID(""), userID)
+				assert.Empty(t, user)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
@@ -173,7 +177,7 @@ func TestDBStorage_LoginUser(t *testing.T) {
 	}
 }
 
-func TestDBStorage_GetRecordsInfo(t *testing.T) {
+func TestDBStorage_UpdatePasswordHash(t *testing.T) {
 	storage := newDBStorage(dbConnectionURL)
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	assert.NoError(t, err)
@@ -185,65 +189,42 @@ func TestDBStorage_GetRecordsInfo(t *testing.T) {
 		valid func()
 	}{
 		{
-			"Get all info from unauthorized user",
-			func() {},
+			"Update hash of existing user",
 			func() {
-				records, err := storage.GetRecordsInfo(context.Background())
-				assert.Equal(t, ErrUnauthenticated, err)
-				assert.Empty(t, records)
+				mock.ExpectExec(
+					`UPDATE users SET password = $1 WHERE user_id = $2`,
+				).WithArgs("new_hash", "userID").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			func() {
+				err := storage.UpdatePasswordHash("userID", "new_hash")
+				assert.NoError(t, err)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Get all info from authorized user",
+			"Update hash of not existing user",
 			func() {
-				mock.ExpectQuery(
-					"SELECT record_id, record_type, metadata FROM users_data WHERE user_id = $1",
-				).WithArgs("6584c88d-1bb4-4686-83be-925abb24fc20").WillReturnRows(
-					sqlmock.NewRows([]string{"record_id", "record_type", "metadata"}).AddRow("1", entity.TypeLoginAndPassword, "login and password").AddRow("2", entity.TypeText, "custom text"))
+				mock.ExpectExec(
+					`UPDATE users SET password = $1 WHERE user_id = $2`,
+				).WithArgs("new_hash", "userID").WillReturnResult(sqlmock.NewResult(0, 0))
 			},
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID", entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
-				)
-				records, err := storage.GetRecordsInfo(ctx)
-				assert.NoError(t, err)
-
-				assert.Equal(t, []entity.Record{
-					{
-						ID:       "1",
-						Type:     entity.TypeLoginAndPassword,
-						Metadata: "login and password",
-					},
-					{
-						ID:       "2",
-						Type:     entity.TypeText,
-						Metadata: "custom text",
-					},
-				}, records)
-
+				err := storage.UpdatePasswordHash("userID", "new_hash")
+				assert.Equal(t, ErrNotFound, err)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Get all info from authorized user, but DB will return error",
+			"Update hash, but DB will return error",
 			func() {
-				mock.ExpectQuery(
-					"SELECT record_id, record_type, metadata FROM users_data WHERE user_id = $1",
-				).WithArgs(
-					"6584c88d-1bb4-4686-83be-925abb24fc20",
-				).WillReturnError(errors.New("some DB error"))
+				mock.ExpectExec(
+					`UPDATE users SET password = $1 WHERE user_id = $2`,
+				).WithArgs("new_hash", "userID").WillReturnError(errors.New("some DB error"))
 			},
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID",
-					entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
-				)
-				records, err := storage.GetRecordsInfo(ctx)
+				err := storage.UpdatePasswordHash("userID", "new_hash")
 				assert.Equal(t, ErrUnknown, err)
-				assert.Empty(t, records)
+				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 	}
@@ -255,7 +236,7 @@ func TestDBStorage_GetRecordsInfo(t *testing.T) {
 	}
 }
 
-func TestDBStorage_CreateRecord(t *testing.T) {
+func TestDBStorage_GetKDFParams(t *testing.T) {
 	storage := newDBStorage(dbConnectionURL)
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	assert.NoError(t, err)
@@ -267,68 +248,53 @@ func TestDBStorage_CreateRecord(t *testing.T) {
 		valid func()
 	}{
 		{
-			"Create record with unauthorized user",
-			func() {},
+			"Get KDF params of user",
 			func() {
-				recordID, err := storage.CreateRecord(context.Background(), entity.Record{})
-				assert.Equal(t, ErrUnauthenticated, err)
-				assert.Empty(t, recordID)
+				mock.ExpectQuery(
+					`SELECT kdf_salt, kdf_time, kdf_memory, kdf_threads, kdf_legacy FROM users WHERE user_id = $1`,
+				).WithArgs("6584c88d-1bb4-4686-83be-925abb24fc20").WillReturnRows(
+					sqlmock.NewRows([]string{"kdf_salt", "kdf_time", "kdf_memory", "kdf_threads", "kdf_legacy"}).
+						AddRow("0102", 3, 65536, 4, false))
+			},
+			func() {
+				params, err := storage.GetKDFParams("6584c88d-1bb4-4686-83be-925abb24fc20")
+				assert.NoError(t, err)
+				assert.Equal(t, entity.KDFParams{
+					Salt:    []byte{0x01, 0x02},
+					Time:    3,
+					Memory:  65536,
+					Threads: 4,
+				}, params)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Create record with authorized user",
+			"Get KDF params of legacy user",
 			func() {
 				mock.ExpectQuery(
-					"INSERT INTO users_data (user_id, record_type, metadata, encoded_data) VALUES ($1, $2, $3, $4) RETURNING record_id",
-				).WithArgs(
-					"6584c88d-1bb4-4686-83be-925abb24fc20",
-					entity.TypeText,
-					"my text",
-					hex.EncodeToString([]byte("hello!")),
-				).WillReturnRows(sqlmock.NewRows([]string{"record_id"}).AddRow("1"))
+					`SELECT kdf_salt, kdf_time, kdf_memory, kdf_threads, kdf_legacy FROM users WHERE user_id = $1`,
+				).WithArgs("6584c88d-1bb4-4686-83be-925abb24fc20").WillReturnRows(
+					sqlmock.NewRows([]string{"kdf_salt", "kdf_time", "kdf_memory", "kdf_threads", "kdf_legacy"}).
+						AddRow("", 0, 0, 0, true))
 			},
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID",
-					entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
-				)
-				recordID, err := storage.CreateRecord(ctx, entity.Record{
-					Metadata: "my text",
-					Type:     entity.TypeText,
-					Data:     []byte("hello!"),
-				})
+				params, err := storage.GetKDFParams("6584c88d-1bb4-4686-83be-925abb24fc20")
 				assert.NoError(t, err)
-				assert.Equal(t, "1", recordID)
+				assert.Equal(t, entity.KDFParams{Legacy: true}, params)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Create record with authorized user, but DB will return error",
+			"Get KDF params of non existed user",
 			func() {
 				mock.ExpectQuery(
-					"INSERT INTO users_data (user_id, record_type, metadata, encoded_data) VALUES ($1, $2, $3, $4) RETURNING record_id",
-				).WithArgs(
-					"6584c88d-1bb4-4686-83be-925abb24fc20",
-					entity.TypeText,
-					"my text",
-					hex.EncodeToString([]byte("hello!")),
-				).WillReturnError(errors.New("some DB error"))
+					`SELECT kdf_salt, kdf_time, kdf_memory, kdf_threads, kdf_legacy FROM users WHERE user_id = $1`,
+				).WithArgs("6584c88d-1bb4-4686-83be-925abb24fc20").WillReturnRows(
+					sqlmock.NewRows([]string{"kdf_salt", "kdf_time", "kdf_memory", "kdf_threads", "kdf_legacy"}))
 			},
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID",
-					entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
-				)
-				recordID, err := storage.CreateRecord(ctx, entity.Record{
-					Metadata: "my text",
-					Type:     entity.TypeText,
-					Data:     []byte("hello!"),
-				})
-				assert.Equal(t, ErrUnknown, err)
-				assert.Empty(t, recordID)
+				_, err := storage.GetKDFParams("6584c88d-1bb4-4686-83be-925abb24fc20")
+				assert.Equal(t, ErrWrongCredentials, err)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
@@ -341,93 +307,200 @@ func TestDBStorage_CreateRecord(t *testing.T) {
 	}
 }
 
-func TestDBStorage_GetRecord(t *testing.T) {
+func TestDBStorage_UpgradeKDF(t *testing.T) {
 	storage := newDBStorage(dbConnectionURL)
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	assert.NoError(t, err)
 	storage.DB = db
 
+	ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+	params := entity.KDFParams{Salt: []byte{0x01, 0x02}, Time: 3, Memory: 65536, Threads: 4}
+
+	set := `UPDATE users SET kdf_salt = $1, kdf_time = $2, kdf_memory = $3, kdf_threads = $4 WHERE user_id = $5 AND kdf_legacy AND kdf_salt = ''`
+	finish := `UPDATE users SET kdf_legacy = FALSE WHERE user_id = $1 AND kdf_legacy AND kdf_salt = $2 AND kdf_time = $3 AND kdf_memory = $4 AND kdf_threads = $5`
+	versions := `DELETE FROM record_versions WHERE record_id IN (SELECT record_id FROM users_data WHERE user_id = $1)`
+
 	tc := []struct {
 		name  string
 		mock  func()
 		valid func()
 	}{
 		{
-			"Get record with unauthorized user",
+			"Set KDF params of legacy user",
+			func() {
+				mock.ExpectExec(set).WithArgs("0102", 3, 65536, 4, "6584c88d-1bb4-4686-83be-925abb24fc20").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			func() {
+				assert.NoError(t, storage.SetKDFParams(ctx, params))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Set KDF params, which are already set",
+			func() {
+				mock.ExpectExec(set).WithArgs("0102", 3, 65536, 4, "6584c88d-1bb4-4686-83be-925abb24fc20").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			func() {
+				assert.Equal(t, ErrConflict, storage.SetKDFParams(ctx, params))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Finish KDF upgrade, versions of records are deleted",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(finish).WithArgs("6584c88d-1bb4-4686-83be-925abb24fc20", "0102", 3, 65536, 4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(versions).WithArgs("6584c88d-1bb4-4686-83be-925abb24fc20").
+					WillReturnResult(sqlmock.NewResult(0, 5))
+				mock.ExpectCommit()
+			},
+			func() {
+				assert.NoError(t, storage.FinishKDFUpgrade(ctx, params))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Finish KDF upgrade with other params",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(finish).WithArgs("6584c88d-1bb4-4686-83be-925abb24fc20", "0102", 3, 65536, 4).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			func() {
+				assert.Equal(t, ErrConflict, storage.FinishKDFUpgrade(ctx, params))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Upgrade KDF with unauthorized user",
 			func() {},
 			func() {
-				record, err := storage.GetRecord(context.Background(), "1")
-				assert.Equal(t, ErrUnauthenticated, err)
-				assert.Empty(t, record)
+				assert.Equal(t, ErrUnauthenticated, storage.SetKDFParams(context.Background(), params))
+				assert.Equal(t, ErrUnauthenticated, storage.FinishKDFUpgrade(context.Background(), params))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_CreateSession(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Create session",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectExec(
+					`DELETE FROM sessions WHERE user_id = $1 AND expires_at <= now()`,
+				).WithArgs("userID").WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(
+					`INSERT INTO sessions (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+				).WithArgs("userID", "hash", expiresAt).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			func() {
+				err := storage.CreateSession("userID", "hash", expiresAt)
+				assert.NoError(t, err)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Get record with authorized user",
+			"Create session, but DB will return error",
 			func() {
-				mock.ExpectQuery(
-					"SELECT record_id, record_type, metadata, encoded_data FROM users_data WHERE record_id = $1 AND user_id = $2",
-				).WithArgs(
-					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
-				).WillReturnRows(
-					sqlmock.NewRows([]string{"record_id", "record_type", "metadata", "encoded_data"}).AddRow("1", entity.TypeText, "my text", hex.EncodeToString([]byte("hello!"))))
+				mock.ExpectBegin()
+				mock.ExpectExec(
+					`DELETE FROM sessions WHERE user_id = $1 AND expires_at <= now()`,
+				).WithArgs("userID").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(
+					`INSERT INTO sessions (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`,
+				).WithArgs("userID", "hash", expiresAt).WillReturnError(errors.New("some DB error"))
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.CreateSession("userID", "hash", expiresAt)
+				assert.Equal(t, ErrUnknown, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
 			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_RotateSession(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	expiresAt := time.Now().Add(time.Hour)
+	query := `UPDATE sessions SET token_hash = $1, expires_at = $2 WHERE token_hash = $3 AND expires_at > now() RETURNING user_id`
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Rotate session",
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID",
-					entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
+				mock.ExpectQuery(query).WithArgs("new hash", expiresAt, "hash").WillReturnRows(
+					sqlmock.NewRows([]string{"user_id"}).AddRow("userID"),
 				)
-				record, err := storage.GetRecord(ctx, "1")
+			},
+			func() {
+				userID, err := storage.RotateSession("hash", "new hash", expiresAt)
 				assert.NoError(t, err)
-				assert.Equal(t, entity.Record{
-					ID:       "1",
-					Metadata: "my text",
-					Type:     entity.TypeText,
-					Data:     []byte("hello!"),
-				}, record)
+				assert.Equal(t, entity.UserID("userID"), userID)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Get non existed record with authorized user",
+			"Rotate expired or revoked session",
 			func() {
-				mock.ExpectQuery(
-					"SELECT record_id, record_type, metadata, encoded_data FROM users_data WHERE record_id = $1 AND user_id = $2",
-				).WithArgs(
-					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
-				).WillReturnRows(sqlmock.NewRows([]string{"record_id", "record_type", "metadata", "encoded_data"}))
+				mock.ExpectQuery(query).WithArgs("new hash", expiresAt, "hash").WillReturnRows(
+					sqlmock.NewRows([]string{"user_id"}),
+				)
 			},
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID",
-					entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
-				)
-				record, err := storage.GetRecord(ctx, "1")
-				assert.Equal(t, ErrNotFound, err)
-				assert.Empty(t, record)
+				userID, err := storage.RotateSession("hash", "new hash", expiresAt)
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.Empty(t, userID)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Get record with authorized user, but DB will return error",
+			"Rotate session, but DB will return error",
 			func() {
-				mock.ExpectQuery(
-					"SELECT record_id, record_type, metadata, encoded_data FROM users_data WHERE record_id = $1 AND user_id = $2",
-				).WithArgs(
-					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
-				).WillReturnError(errors.New("some DB error"))
+				mock.ExpectQuery(query).WithArgs("new hash", expiresAt, "hash").WillReturnError(errors.New("some DB error"))
 			},
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID",
-					entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
-				)
-				record, err := storage.GetRecord(ctx, "1")
+				_, err := storage.RotateSession("hash", "new hash", expiresAt)
 				assert.Equal(t, ErrUnknown, err)
-				assert.Empty(t, record)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
@@ -440,7 +513,7 @@ func TestDBStorage_GetRecord(t *testing.T) {
 	}
 }
 
-func TestDBStorage_DeleteRecord(t *testing.T) {
+func TestDBStorage_DeleteSessions(t *testing.T) {
 	storage := newDBStorage(dbConnectionURL)
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	assert.NoError(t, err)
@@ -452,71 +525,1115 @@ func TestDBStorage_DeleteRecord(t *testing.T) {
 		valid func()
 	}{
 		{
-			"Delete record with unauthorized user",
-			func() {},
+			"Delete session",
 			func() {
-				err := storage.DeleteRecord(context.Background(), "1")
-				assert.Equal(t, ErrUnauthenticated, err)
+				mock.ExpectExec(
+					`DELETE FROM sessions WHERE token_hash = $1`,
+				).WithArgs("hash").WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			func() {
+				assert.NoError(t, storage.DeleteSession("hash"))
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Delete record with authorized user",
+			"Delete not existing session",
 			func() {
 				mock.ExpectExec(
-					"DELETE FROM users_data WHERE record_id = $1 AND user_id = $2",
-				).WithArgs(
-					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
-				).WillReturnResult(sqlmock.NewResult(0, 1))
+					`DELETE FROM sessions WHERE token_hash = $1`,
+				).WithArgs("hash").WillReturnResult(sqlmock.NewResult(0, 0))
 			},
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID",
-					entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
-				)
-				err := storage.DeleteRecord(ctx, "1")
-				assert.NoError(t, err)
+				assert.Equal(t, ErrUnauthenticated, storage.DeleteSession("hash"))
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Delete record with authorized user, but DB will return error",
+			"Delete all sessions of user",
 			func() {
 				mock.ExpectExec(
-					"DELETE FROM users_data WHERE record_id = $1 AND user_id = $2",
-				).WithArgs(
-					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
-				).WillReturnError(errors.New("some DB error"))
+					`DELETE FROM sessions WHERE user_id = $1`,
+				).WithArgs("userID").WillReturnResult(sqlmock.NewResult(0, 3))
 			},
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID",
-					entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
-				)
-				err := storage.DeleteRecord(ctx, "1")
-				assert.Equal(t, ErrUnknown, err)
+				assert.NoError(t, storage.DeleteSessions("userID"))
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
 		{
-			"Delete non existed record with authorized user",
+			"Delete all sessions of user, but DB will return error",
 			func() {
 				mock.ExpectExec(
-					"DELETE FROM users_data WHERE record_id = $1 AND user_id = $2",
-				).WithArgs(
-					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
-				).WillReturnResult(sqlmock.NewResult(0, 0))
+					`DELETE FROM sessions WHERE user_id = $1`,
+				).WithArgs("userID").WillReturnError(errors.New("some DB error"))
 			},
 			func() {
-				ctx := context.WithValue(
-					context.Background(),
-					"userID",
-					entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20"),
-				)
-				err := storage.DeleteRecord(ctx, "1")
-				assert.Equal(t, ErrNotFound, err)
+				assert.Equal(t, ErrUnknown, storage.DeleteSessions("userID"))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_GetRecordsInfo(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	updatedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Get all info from unauthorized user",
+			func() {},
+			func() {
+				records, err := storage.GetRecordsInfo(context.Background())
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.Empty(t, records)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get all info from authorized user",
+			func() {
+				mock.ExpectQuery(
+					"SELECT record_id, record_type, metadata, name, tags, folder, blind_index, revision, updated_at FROM users_data WHERE user_id = $1",
+				).WithArgs("6584c88d-1bb4-4686-83be-925abb24fc20").WillReturnRows(
+					sqlmock.NewRows([]string{"record_id", "record_type", "metadata", "name", "tags", "folder", "blind_index", "revision", "updated_at"}).
+						AddRow("1", entity.TypeLoginAndPassword, "login and password", "Bank", ",finance,work,", "work/banks", "", 3, updatedAt).
+						AddRow("2", entity.TypeText, "gkm1:encrypted", "", "", "", ",1f,2e,", 5, updatedAt))
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+				records, err := storage.GetRecordsInfo(ctx)
+				assert.NoError(t, err)
+
+				assert.Equal(t, []entity.Record{
+					{
+						ID:        "1",
+						Type:      entity.TypeLoginAndPassword,
+						Metadata:  "login and password",
+						Name:      "Bank",
+						Tags:      []string{"finance", "work"},
+						Folder:    "work/banks",
+						Revision:  3,
+						UpdatedAt: updatedAt,
+					},
+					{
+						ID:         "2",
+						Type:       entity.TypeText,
+						Metadata:   "gkm1:encrypted",
+						BlindIndex: []string{"1f", "2e"},
+						Revision:   5,
+						UpdatedAt:  updatedAt,
+					},
+				}, records)
+
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get all info from authorized user, but DB will return error",
+			func() {
+				mock.ExpectQuery(
+					"SELECT record_id, record_type, metadata, name, tags, folder, blind_index, revision, updated_at FROM users_data WHERE user_id = $1",
+				).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnError(errors.New("some DB error"))
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+				records, err := storage.GetRecordsInfo(ctx)
+				assert.Equal(t, ErrUnknown, err)
+				assert.Empty(t, records)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_ListRecords(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+	updatedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"record_id", "record_type", "metadata", "name", "tags", "folder", "blind_index", "revision", "updated_at", "count"}
+	selectRecords := `SELECT record_id, record_type, metadata, name, tags, folder, blind_index, revision, updated_at, COUNT(*) OVER () FROM users_data WHERE `
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"List records with unauthorized user",
+			func() {},
+			func() {
+				page, err := storage.ListRecords(context.Background(), entity.RecordsQuery{})
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.Empty(t, page.Records)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"List first page of all records sorted by name",
+			func() {
+				mock.ExpectQuery(
+					selectRecords+`user_id = $1 ORDER BY lower(name) ASC, record_id ASC LIMIT $2 OFFSET $3`,
+				).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20", 100, 0,
+				).WillReturnRows(sqlmock.NewRows(columns).
+					AddRow("1", entity.TypeLoginAndPassword, "login", "Bank", ",finance,", "work", "", 3, updatedAt, 2).
+					AddRow("2", entity.TypeText, "text", "Note", "", "", "", 5, updatedAt, 2))
+			},
+			func() {
+				page, err := storage.ListRecords(ctx, entity.RecordsQuery{})
+				assert.NoError(t, err)
+				assert.Equal(t, entity.RecordsPage{
+					Records: []entity.Record{
+						{
+							ID:        "1",
+							Type:      entity.TypeLoginAndPassword,
+							Metadata:  "login",
+							Name:      "Bank",
+							Tags:      []string{"finance"},
+							Folder:    "work",
+							Revision:  3,
+							UpdatedAt: updatedAt,
+						},
+						{ID: "2", Type: entity.TypeText, Metadata: "text", Name: "Note", Revision: 5, UpdatedAt: updatedAt},
+					},
+					Total: 2,
+				}, page)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"List records with all filters",
+			func() {
+				mock.ExpectQuery(
+					selectRecords+`user_id = $1 AND (name ILIKE $2 OR metadata ILIKE $2 OR (blind_index LIKE $3 AND blind_index LIKE $4)) `+
+						`AND tags LIKE $5 AND tags LIKE $6 AND (folder = $7 OR folder LIKE $8) AND record_type IN ($9, $10) `+
+						`ORDER BY updated_at DESC, record_id DESC LIMIT $11 OFFSET $12`,
+				).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+					`%50\%\_off%`,
+					"%,1f,%",
+					"%,2e,%",
+					"%,finance,%",
+					"%,work,%",
+					"work/banks",
+					`work/banks/%`,
+					entity.TypeLoginAndPassword,
+					entity.TypeCreditCard,
+					1000,
+					20,
+				).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			func() {
+				page, err := storage.ListRecords(ctx, entity.RecordsQuery{
+					Search:     "50%_off",
+					BlindIndex: []string{"1f", "2e"},
+					Tags:       []string{"Work", "finance"},
+					Folder:     "/work/banks/",
+					Types:      []entity.RecordType{entity.TypeLoginAndPassword, entity.TypeCreditCard},
+					Sort:       entity.SortByUpdatedAt,
+					Descending: true,
+					Offset:     20,
+					Limit:      5000,
+				})
+				assert.NoError(t, err)
+				assert.Empty(t, page.Records)
+				assert.Zero(t, page.Total)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"List records, but DB will return error",
+			func() {
+				mock.ExpectQuery(
+					selectRecords+`user_id = $1 ORDER BY record_type ASC, record_id ASC LIMIT $2 OFFSET $3`,
+				).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20", 100, 0,
+				).WillReturnError(errors.New("some DB error"))
+			},
+			func() {
+				page, err := storage.ListRecords(ctx, entity.RecordsQuery{Sort: entity.SortByType})
+				assert.Equal(t, ErrUnknown, err)
+				assert.Empty(t, page.Records)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_CreateRecord(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	revision := `UPDATE users SET revision = revision + 1 WHERE user_id = $1 RETURNING revision`
+	insert := `INSERT INTO users_data (user_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, revision) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING record_id`
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Create record with unauthorized user",
+			func() {},
+			func() {
+				recordID, err := storage.CreateRecord(context.Background(), entity.Record{})
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.Empty(t, recordID)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Create record with authorized user",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(7))
+				mock.ExpectQuery(insert).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+					entity.TypeText,
+					"my text",
+					"Note",
+					",personal,",
+					"notes",
+					"",
+					hex.EncodeToString([]byte("hello!")),
+					7,
+				).WillReturnRows(sqlmock.NewRows([]string{"record_id"}).AddRow("1"))
+				mock.ExpectCommit()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+				recordID, err := storage.CreateRecord(ctx, entity.Record{
+					Metadata: "my text",
+					Type:     entity.TypeText,
+					Data:     []byte("hello!"),
+					Name:     "Note",
+					Tags:     []string{" Personal", "personal"},
+					Folder:   "/notes/",
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, "1", recordID)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Create file record with ID of written file",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(8))
+				mock.ExpectQuery(
+					`INSERT INTO users_data (user_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, revision, record_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING record_id`,
+				).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+					entity.TypeFile,
+					"file.txt",
+					"",
+					"",
+					"",
+					"",
+					"",
+					8,
+					"0f9d1e40-5b2a-4c1e-9a43-6f1f2d7e8a10",
+				).WillReturnRows(sqlmock.NewRows([]string{"record_id"}).AddRow("0f9d1e40-5b2a-4c1e-9a43-6f1f2d7e8a10"))
+				mock.ExpectCommit()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+				recordID, err := storage.CreateRecord(ctx, entity.Record{
+					ID:       "0f9d1e40-5b2a-4c1e-9a43-6f1f2d7e8a10",
+					Metadata: "file.txt",
+					Type:     entity.TypeFile,
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, "0f9d1e40-5b2a-4c1e-9a43-6f1f2d7e8a10", recordID)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Create record with authorized user, but DB will return error",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(7))
+				mock.ExpectQuery(insert).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+					entity.TypeText,
+					"my text",
+					"Note",
+					",personal,",
+					"notes",
+					"",
+					hex.EncodeToString([]byte("hello!")),
+					7,
+				).WillReturnError(errors.New("some DB error"))
+				mock.ExpectRollback()
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+				recordID, err := storage.CreateRecord(ctx, entity.Record{
+					Metadata: "my text",
+					Type:     entity.TypeText,
+					Data:     []byte("hello!"),
+					Name:     "Note",
+					Tags:     []string{" Personal", "personal"},
+					Folder:   "/notes/",
+				})
+				assert.Equal(t, ErrUnknown, err)
+				assert.Empty(t, recordID)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_GetRecord(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	updatedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"record_id", "record_type", "metadata", "name", "tags", "folder", "blind_index", "encoded_data", "revision", "updated_at"}
+	query := "SELECT record_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, revision, updated_at FROM users_data WHERE record_id = $1 AND user_id = $2"
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Get record with unauthorized user",
+			func() {},
+			func() {
+				record, err := storage.GetRecord(context.Background(), "1")
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.Empty(t, record)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get record with authorized user",
+			func() {
+				mock.ExpectQuery(query).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(
+					sqlmock.NewRows(columns).AddRow("1", entity.TypeText, "my text", "Note", ",personal,", "notes", "", hex.EncodeToString([]byte("hello!")), 4, updatedAt))
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+				record, err := storage.GetRecord(ctx, "1")
+				assert.NoError(t, err)
+				assert.Equal(t, entity.Record{
+					ID:        "1",
+					Metadata:  "my text",
+					Type:      entity.TypeText,
+					Data:      []byte("hello!"),
+					Revision:  4,
+					Name:      "Note",
+					Tags:      []string{"personal"},
+					Folder:    "notes",
+					UpdatedAt: updatedAt,
+				}, record)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get non existed record with authorized user",
+			func() {
+				mock.ExpectQuery(query).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows(columns))
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+				record, err := storage.GetRecord(ctx, "1")
+				assert.Equal(t, ErrNotFound, err)
+				assert.Empty(t, record)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get record with authorized user, but DB will return error",
+			func() {
+				mock.ExpectQuery(query).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnError(errors.New("some DB error"))
+			},
+			func() {
+				ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+				record, err := storage.GetRecord(ctx, "1")
+				assert.Equal(t, ErrUnknown, err)
+				assert.Empty(t, record)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_DeleteRecord(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+
+	revision := `UPDATE users SET revision = revision + 1 WHERE user_id = $1 RETURNING revision`
+	check := `SELECT revision FROM users_data WHERE record_id = $1 AND user_id = $2`
+	remove := `DELETE FROM users_data WHERE record_id = $1 AND user_id = $2`
+	tombstone := `INSERT INTO record_tombstones (record_id, user_id, revision) VALUES ($1, $2, $3)`
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Delete record with unauthorized user",
+			func() {},
+			func() {
+				err := storage.DeleteRecord(context.Background(), "1", 0)
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Delete record with authorized user, tombstone is left",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(8))
+				mock.ExpectQuery(check).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(5))
+				mock.ExpectExec(remove).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(tombstone).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20", 8,
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			func() {
+				err := storage.DeleteRecord(ctx, "1", 5)
+				assert.NoError(t, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Delete record changed on another device",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(8))
+				mock.ExpectQuery(check).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(6))
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.DeleteRecord(ctx, "1", 5)
+				assert.Equal(t, ErrConflict, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Delete record with authorized user, but DB will return error",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnError(errors.New("some DB error"))
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.DeleteRecord(ctx, "1", 0)
+				assert.Equal(t, ErrUnknown, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Delete non existed record with authorized user",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(8))
+				mock.ExpectQuery(check).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}))
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.DeleteRecord(ctx, "1", 0)
+				assert.Equal(t, ErrNotFound, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_UpdateRecord(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+	record := entity.Record{
+		ID:         "1",
+		Metadata:   "new metadata",
+		Type:       entity.TypeText,
+		Data:       []byte{0x01, 0x02},
+		Revision:   5,
+		Name:       "Bank",
+		Tags:       []string{"Work"},
+		Folder:     "work",
+		BlindIndex: []string{"1f"},
+	}
+
+	revision := `UPDATE users SET revision = revision + 1 WHERE user_id = $1 RETURNING revision`
+	check := `SELECT revision FROM users_data WHERE record_id = $1 AND user_id = $2`
+	archive := `INSERT INTO record_versions (record_id, version, record_type, metadata, name, tags, folder, blind_index, encoded_data) SELECT record_id, version, record_type, metadata, name, tags, folder, blind_index, encoded_data FROM users_data WHERE record_id = $1 AND user_id = $2`
+	update := `UPDATE users_data SET metadata = $1, name = $2, tags = $3, folder = $4, blind_index = $5, encoded_data = $6, version = version + 1, revision = $7, updated_at = now() WHERE record_id = $8 AND user_id = $9 AND record_type = $10`
+
+	expectRevisions := func(current int) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(revision).WithArgs(
+			"6584c88d-1bb4-4686-83be-925abb24fc20",
+		).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(8))
+
+		rows := sqlmock.NewRows([]string{"revision"})
+		if current > 0 {
+			rows.AddRow(current)
+		}
+
+		mock.ExpectQuery(check).WithArgs(
+			"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+		).WillReturnRows(rows)
+	}
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Update record with unauthorized user",
+			func() {},
+			func() {
+				err := storage.UpdateRecord(context.Background(), record)
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Update record with authorized user",
+			func() {
+				expectRevisions(5)
+				mock.ExpectExec(archive).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(update).WithArgs(
+					"new metadata", "Bank", ",work,", "work", ",1f,", "0102", 8, "1", "6584c88d-1bb4-4686-83be-925abb24fc20", entity.TypeText,
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			func() {
+				err := storage.UpdateRecord(ctx, record)
+				assert.NoError(t, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Update record changed on another device",
+			func() {
+				expectRevisions(6)
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.UpdateRecord(ctx, record)
+				assert.Equal(t, ErrConflict, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Update record without revision isn't checked",
+			func() {
+				expectRevisions(6)
+				mock.ExpectExec(archive).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(update).WithArgs(
+					"new metadata", "Bank", ",work,", "work", ",1f,", "0102", 8, "1", "6584c88d-1bb4-4686-83be-925abb24fc20", entity.TypeText,
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			func() {
+				unchecked := record
+				unchecked.Revision = 0
+
+				err := storage.UpdateRecord(ctx, unchecked)
+				assert.NoError(t, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Update not existing record",
+			func() {
+				expectRevisions(0)
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.UpdateRecord(ctx, record)
+				assert.Equal(t, ErrNotFound, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Update record with other type, history isn't changed",
+			func() {
+				expectRevisions(5)
+				mock.ExpectExec(archive).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(update).WithArgs(
+					"new metadata", "Bank", ",work,", "work", ",1f,", "0102", 8, "1", "6584c88d-1bb4-4686-83be-925abb24fc20", entity.TypeText,
+				).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.UpdateRecord(ctx, record)
+				assert.Equal(t, ErrNotFound, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Update record, but DB will return error",
+			func() {
+				expectRevisions(5)
+				mock.ExpectExec(archive).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnError(errors.New("some DB error"))
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.UpdateRecord(ctx, record)
+				assert.Equal(t, ErrUnknown, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_GetRecordVersions(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+	replacedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	query := `SELECT v.version, v.metadata, v.replaced_at FROM record_versions v JOIN users_data d ON d.record_id = v.record_id WHERE v.record_id = $1 AND d.user_id = $2 ORDER BY v.version DESC`
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Get versions with unauthorized user",
+			func() {},
+			func() {
+				versions, err := storage.GetRecordVersions(context.Background(), "1")
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.Empty(t, versions)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get versions with authorized user",
+			func() {
+				mock.ExpectQuery(query).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"version", "metadata", "replaced_at"}).
+					AddRow(2, "second", replacedAt).
+					AddRow(1, "first", replacedAt))
+			},
+			func() {
+				versions, err := storage.GetRecordVersions(ctx, "1")
+				assert.NoError(t, err)
+				assert.Equal(t, []entity.RecordVersion{
+					{RecordID: "1", Version: 2, Metadata: "second", ReplacedAt: replacedAt},
+					{RecordID: "1", Version: 1, Metadata: "first", ReplacedAt: replacedAt},
+				}, versions)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get versions, but DB will return error",
+			func() {
+				mock.ExpectQuery(query).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnError(errors.New("some DB error"))
+			},
+			func() {
+				versions, err := storage.GetRecordVersions(ctx, "1")
+				assert.Equal(t, ErrUnknown, err)
+				assert.Empty(t, versions)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_RestoreRecordVersion(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+
+	revision := `UPDATE users SET revision = revision + 1 WHERE user_id = $1 RETURNING revision`
+	archive := `INSERT INTO record_versions (record_id, version, record_type, metadata, name, tags, folder, blind_index, encoded_data) SELECT record_id, version, record_type, metadata, name, tags, folder, blind_index, encoded_data FROM users_data WHERE record_id = $1 AND user_id = $2`
+	check := `SELECT revision FROM users_data WHERE record_id = $1 AND user_id = $2`
+	restore := `UPDATE users_data SET metadata = v.metadata, name = v.name, tags = v.tags, folder = v.folder, blind_index = v.blind_index, encoded_data = v.encoded_data, version = users_data.version + 1, revision = $1, updated_at = now() FROM record_versions v WHERE users_data.record_id = $2 AND users_data.user_id = $3 AND v.record_id = users_data.record_id AND v.version = $4`
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Restore version with unauthorized user",
+			func() {},
+			func() {
+				err := storage.RestoreRecordVersion(context.Background(), "1", 1, 3)
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Restore existing version",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(8))
+				mock.ExpectQuery(check).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(3))
+				mock.ExpectExec(archive).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(restore).WithArgs(
+					8, "1", "6584c88d-1bb4-4686-83be-925abb24fc20", 1,
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			func() {
+				err := storage.RestoreRecordVersion(ctx, "1", 1, 3)
+				assert.NoError(t, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Restore not existing version",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(8))
+				mock.ExpectQuery(check).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(3))
+				mock.ExpectExec(archive).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(restore).WithArgs(
+					8, "1", "6584c88d-1bb4-4686-83be-925abb24fc20", 5,
+				).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.RestoreRecordVersion(ctx, "1", 5, 0)
+				assert.Equal(t, ErrNotFound, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Restore version of record changed after known revision",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(8))
+				mock.ExpectQuery(check).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(7))
+				mock.ExpectRollback()
+			},
+			func() {
+				err := storage.RestoreRecordVersion(ctx, "1", 1, 3)
+				assert.Equal(t, ErrConflict, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Restore version, but commit fails",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(revision).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(8))
+				mock.ExpectQuery(check).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(3))
+				mock.ExpectExec(archive).WithArgs(
+					"1", "6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(restore).WithArgs(
+					8, "1", "6584c88d-1bb4-4686-83be-925abb24fc20", 1,
+				).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit().WillReturnError(errors.New("some DB error"))
+			},
+			func() {
+				err := storage.RestoreRecordVersion(ctx, "1", 1, 3)
+				assert.Equal(t, ErrUnknown, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_Sync(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	ctx := entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID("6584c88d-1bb4-4686-83be-925abb24fc20")})
+	changedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	current := `SELECT revision FROM users WHERE user_id = $1`
+	changed := `SELECT record_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, revision, updated_at FROM users_data WHERE user_id = $1 AND revision > $2 AND revision <= $3 ORDER BY revision LIMIT $4`
+	deleted := `SELECT record_id, revision, deleted_at FROM record_tombstones WHERE user_id = $1 AND revision > $2 AND revision <= $3 ORDER BY revision LIMIT $4`
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Sync with unauthorized user",
+			func() {},
+			func() {
+				changes, err := storage.Sync(context.Background(), 0)
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.Empty(t, changes)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Sync changes up to current revision",
+			func() {
+				mock.ExpectQuery(current).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(9))
+				mock.ExpectQuery(changed).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20", 5, 9, 501,
+				).WillReturnRows(sqlmock.NewRows([]string{"record_id", "record_type", "metadata", "name", "tags", "folder", "blind_index", "encoded_data", "revision", "updated_at"}).
+					AddRow("1", entity.TypeText, "my text", "", "", "", "", hex.EncodeToString([]byte("hello!")), 6, changedAt).
+					AddRow("2", entity.TypeFile, "file", "", "", "", "", "", 8, changedAt))
+				mock.ExpectQuery(deleted).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20", 5, 9, 501,
+				).WillReturnRows(sqlmock.NewRows([]string{"record_id", "revision", "deleted_at"}).
+					AddRow("3", 9, changedAt))
+			},
+			func() {
+				changes, err := storage.Sync(ctx, 5)
+				assert.NoError(t, err)
+				assert.Equal(t, entity.RecordChanges{
+					Records: []entity.Record{
+						{ID: "1", Metadata: "my text", Type: entity.TypeText, Data: []byte("hello!"), Revision: 6, UpdatedAt: changedAt},
+						{ID: "2", Metadata: "file", Type: entity.TypeFile, Data: []byte{}, Revision: 8, UpdatedAt: changedAt},
+					},
+					Deleted:  []entity.Tombstone{{RecordID: "3", Revision: 9, DeletedAt: changedAt}},
+					Revision: 9,
+				}, changes)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Sync changes by pages",
+			func() {
+				syncPageSize = 2
+
+				mock.ExpectQuery(current).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(9))
+				mock.ExpectQuery(changed).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20", 5, 9, 3,
+				).WillReturnRows(sqlmock.NewRows([]string{"record_id", "record_type", "metadata", "name", "tags", "folder", "blind_index", "encoded_data", "revision", "updated_at"}).
+					AddRow("1", entity.TypeText, "my text", "", "", "", "", hex.EncodeToString([]byte("hello!")), 6, changedAt).
+					AddRow("2", entity.TypeFile, "file", "", "", "", "", "", 8, changedAt))
+				mock.ExpectQuery(deleted).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20", 5, 9, 3,
+				).WillReturnRows(sqlmock.NewRows([]string{"record_id", "revision", "deleted_at"}).
+					AddRow("3", 7, changedAt).
+					AddRow("4", 9, changedAt))
+			},
+			func() {
+				defer func() { syncPageSize = 500 }()
+
+				changes, err := storage.Sync(ctx, 5)
+				assert.NoError(t, err)
+				assert.Equal(t, entity.RecordChanges{
+					Records:  []entity.Record{{ID: "1", Metadata: "my text", Type: entity.TypeText, Data: []byte("hello!"), Revision: 6, UpdatedAt: changedAt}},
+					Deleted:  []entity.Tombstone{{RecordID: "3", Revision: 7, DeletedAt: changedAt}},
+					Revision: 7,
+					More:     true,
+				}, changes)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Sync changes, which data exceeds page",
+			func() {
+				syncPageBytes = 4
+
+				mock.ExpectQuery(current).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(9))
+				mock.ExpectQuery(changed).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20", 5, 9, 501,
+				).WillReturnRows(sqlmock.NewRows([]string{"record_id", "record_type", "metadata", "name", "tags", "folder", "blind_index", "encoded_data", "revision", "updated_at"}).
+					AddRow("1", entity.TypeText, "my text", "", "", "", "", hex.EncodeToString([]byte("hello!")), 6, changedAt))
+				mock.ExpectQuery(deleted).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20", 5, 9, 501,
+				).WillReturnRows(sqlmock.NewRows([]string{"record_id", "revision", "deleted_at"}).
+					AddRow("3", 7, changedAt))
+			},
+			func() {
+				defer func() { syncPageBytes = 1 << 20 }()
+
+				changes, err := storage.Sync(ctx, 5)
+				assert.NoError(t, err)
+				assert.Equal(t, entity.RecordChanges{
+					Records:  []entity.Record{{ID: "1", Metadata: "my text", Type: entity.TypeText, Data: []byte("hello!"), Revision: 6, UpdatedAt: changedAt}},
+					Deleted:  []entity.Tombstone{},
+					Revision: 6,
+					More:     true,
+				}, changes)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Sync user, who doesn't exist",
+			func() {
+				mock.ExpectQuery(current).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}))
+			},
+			func() {
+				changes, err := storage.Sync(ctx, 0)
+				assert.Equal(t, ErrUnauthenticated, err)
+				assert.Empty(t, changes)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Sync, but DB will return error",
+			func() {
+				mock.ExpectQuery(current).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20",
+				).WillReturnRows(sqlmock.NewRows([]string{"revision"}).AddRow(9))
+				mock.ExpectQuery(changed).WithArgs(
+					"6584c88d-1bb4-4686-83be-925abb24fc20", 0, 9, 501,
+				).WillReturnError(errors.New("some DB error"))
+			},
+			func() {
+				changes, err := storage.Sync(ctx, 0)
+				assert.Equal(t, ErrUnknown, err)
+				assert.Empty(t, changes)
 				assert.NoError(t, mock.ExpectationsWereMet())
 			},
 		},
@@ -0,0 +1,558 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GetPublicKey gets public key of user by login, so vault key can be wrapped for this user.
+// User on legacy key has no public key: key derived from legacy key is known to anyone.
+func (s *dbStorage) GetPublicKey(ctx context.Context, login string) ([]byte, error) {
+	if _, ok := userFromContext(ctx); !ok {
+		log.Println("Failed get userID from context in getting public key")
+		return nil, ErrUnauthenticated
+	}
+
+	row := s.DB.QueryRowContext(
+		ctx,
+		`SELECT public_key, kdf_legacy FROM users WHERE login = $1`,
+		login,
+	)
+
+	var (
+		hexKey string
+		legacy bool
+	)
+
+	err := row.Scan(&hexKey, &legacy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil || row.Err() != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	if hexKey == "" || legacy {
+		return nil, ErrNoPublicKey
+	}
+
+	key, err := hex.DecodeString(hexKey)
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	return key, nil
+}
+
+// SetPublicKey sets public key of user, who has no one yet, e.g. user was registered before keys.
+// Public key, which is set, can't be replaced by another one. User on legacy key can't set public key.
+func (s *dbStorage) SetPublicKey(ctx context.Context, publicKey []byte) error {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in setting public key")
+		return ErrUnauthenticated
+	}
+
+	err := checkAffected(s.DB.ExecContext(
+		ctx,
+		`UPDATE users SET public_key = $1 WHERE user_id = $2 AND NOT kdf_legacy AND public_key IN ('', $1)`,
+		hex.EncodeToString(publicKey),
+		userID,
+	))
+	if errors.Is(err, ErrNotFound) {
+		return ErrConflict
+	}
+
+	return err
+}
+
+// CreateVault creates shared vault, user becomes its owner. Vault key must be wrapped for owner.
+func (s *dbStorage) CreateVault(ctx context.Context, vault entity.Vault) (string, error) {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in creating vault")
+		return "", ErrUnauthenticated
+	}
+
+	var vaultID string
+
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		row := tx.QueryRowContext(
+			ctx,
+			`INSERT INTO vaults (name, owner_id) VALUES ($1, $2) RETURNING vault_id`,
+			vault.Name,
+			userID,
+		)
+
+		if err := row.Scan(&vaultID); err != nil || row.Err() != nil {
+			log.Infoln(err)
+
+			return ErrUnknown
+		}
+
+		result, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO vault_members (vault_id, user_id, role, wrapped_key) VALUES ($1, $2, $3, $4)`,
+			vaultID,
+			userID,
+			entity.RoleOwner,
+			hex.EncodeToString(vault.WrappedKey),
+		)
+
+		return checkAffected(result, err)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return vaultID, nil
+}
+
+// GetVaults gets vaults, which user is member of, with vault keys wrapped for user.
+func (s *dbStorage) GetVaults(ctx context.Context) ([]entity.Vault, error) {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in getting vaults")
+		return nil, ErrUnauthenticated
+	}
+
+	rows, err := s.DB.QueryContext(
+		ctx,
+		`SELECT v.vault_id, v.name, m.role, m.wrapped_key FROM vaults v JOIN vault_members m ON m.vault_id = v.vault_id WHERE m.user_id = $1 ORDER BY lower(v.name), v.vault_id`,
+		userID,
+	)
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	defer rows.Close()
+
+	result := make([]entity.Vault, 0, 10)
+
+	for rows.Next() {
+		var (
+			vault      entity.Vault
+			wrappedKey string
+		)
+
+		if err = rows.Scan(&vault.ID, &vault.Name, &vault.Role, &wrappedKey); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+
+		if vault.WrappedKey, err = hex.DecodeString(wrappedKey); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+
+		result = append(result, vault)
+	}
+
+	if rows.Err() != nil {
+		log.Println("Failed get rows in getting vaults:", rows.Err())
+		return nil, ErrUnknown
+	}
+
+	return result, nil
+}
+
+// GetVaultMembers gets members of vault, owner first. Every member can see them.
+func (s *dbStorage) GetVaultMembers(ctx context.Context, vaultID string) ([]entity.VaultMember, error) {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in getting vault members")
+		return nil, ErrUnauthenticated
+	}
+
+	if err := s.checkVaultRole(ctx, vaultID, userID, entity.RoleRead); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.QueryContext(
+		ctx,
+		`SELECT u.login, m.role, m.key_mac FROM vault_members m JOIN users u ON u.user_id = m.user_id WHERE m.vault_id = $1 ORDER BY m.role DESC, u.login`,
+		vaultID,
+	)
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	defer rows.Close()
+
+	result := make([]entity.VaultMember, 0, 10)
+
+	for rows.Next() {
+		var (
+			member = entity.VaultMember{VaultID: vaultID}
+			keyMAC string
+		)
+
+		if err = rows.Scan(&member.Login, &member.Role, &keyMAC); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+
+		if member.KeyMAC, err = hex.DecodeString(keyMAC); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+
+		result = append(result, member)
+	}
+
+	if rows.Err() != nil {
+		log.Println("Failed get rows in getting vault members:", rows.Err())
+		return nil, ErrUnknown
+	}
+
+	return result, nil
+}
+
+// AddVaultMember adds user to vault or changes role and wrapped key of member. Only owner can do it,
+// owner role can't be given or taken.
+func (s *dbStorage) AddVaultMember(ctx context.Context, member entity.VaultMember) error {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in adding vault member")
+		return ErrUnauthenticated
+	}
+
+	if member.Role == entity.RoleOwner {
+		return ErrForbidden
+	}
+
+	if err := s.checkVaultRole(ctx, member.VaultID, userID, entity.RoleOwner); err != nil {
+		return err
+	}
+
+	return checkAffected(s.DB.ExecContext(
+		ctx,
+		`INSERT INTO vault_members (vault_id, user_id, role, wrapped_key, key_mac) SELECT $1, user_id, $2, $3, $4 FROM users WHERE login = $5 `+
+			`ON CONFLICT (vault_id, user_id) DO UPDATE SET role = EXCLUDED.role, wrapped_key = EXCLUDED.wrapped_key, key_mac = EXCLUDED.key_mac WHERE vault_members.role <> $6`,
+		member.VaultID,
+		member.Role,
+		hex.EncodeToString(member.WrappedKey),
+		hex.EncodeToString(member.KeyMAC),
+		member.Login,
+		entity.RoleOwner,
+	))
+}
+
+// RevokeVaultMember removes user from vault and replaces vault key in one serializable transaction:
+// wrapped keys of all other members and all records are replaced. If members or records of vault
+// were changed, while new key was made, nothing is changed and ErrConflict is returned.
+// Only owner can do it, owner can't be removed.
+func (s *dbStorage) RevokeVaultMember(ctx context.Context, rotation entity.VaultRotation) error {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in revoking vault member")
+		return ErrUnauthenticated
+	}
+
+	if err := s.checkVaultRole(ctx, rotation.VaultID, userID, entity.RoleOwner); err != nil {
+		return err
+	}
+
+	options := &sql.TxOptions{Isolation: sql.LevelSerializable}
+
+	return s.inTxWith(ctx, options, func(tx *sql.Tx) error {
+		if err := checkAffected(tx.ExecContext(
+			ctx,
+			`DELETE FROM vault_members WHERE vault_id = $1 AND role <> $2 AND user_id = (SELECT user_id FROM users WHERE login = $3)`,
+			rotation.VaultID,
+			entity.RoleOwner,
+			rotation.Revoked,
+		)); err != nil {
+			return err
+		}
+
+		for _, member := range rotation.Members {
+			err := checkAffected(tx.ExecContext(
+				ctx,
+				`UPDATE vault_members SET wrapped_key = $1 WHERE vault_id = $2 AND user_id = (SELECT user_id FROM users WHERE login = $3)`,
+				hex.EncodeToString(member.WrappedKey),
+				rotation.VaultID,
+				member.Login,
+			))
+			if errors.Is(err, ErrNotFound) {
+				return ErrConflict
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, record := range rotation.Records {
+			err := checkAffected(tx.ExecContext(
+				ctx,
+				`UPDATE vault_records SET metadata = $1, name = $2, tags = $3, folder = $4, blind_index = $5, encoded_data = $6, updated_at = now() WHERE record_id = $7 AND vault_id = $8`,
+				record.Metadata,
+				record.Name,
+				encodeList(entity.NormalizeTags(record.Tags)),
+				entity.NormalizeFolder(record.Folder),
+				encodeList(record.BlindIndex),
+				hex.EncodeToString(record.Data),
+				record.ID,
+				rotation.VaultID,
+			))
+			if errors.Is(err, ErrNotFound) {
+				return ErrConflict
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// Members and records, which were added after new key was made, would keep old key.
+		var members, records int
+
+		row := tx.QueryRowContext(
+			ctx,
+			`SELECT (SELECT count(*) FROM vault_members WHERE vault_id = $1), (SELECT count(*) FROM vault_records WHERE vault_id = $1)`,
+			rotation.VaultID,
+		)
+		if err := row.Scan(&members, &records); err != nil || row.Err() != nil {
+			log.Infoln(err)
+
+			return ErrUnknown
+		}
+
+		if members != len(rotation.Members) || records != len(rotation.Records) {
+			return ErrConflict
+		}
+
+		return nil
+	})
+}
+
+// GetVaultRecords gets all records of vault without data.
+func (s *dbStorage) GetVaultRecords(ctx context.Context, vaultID string) ([]entity.Record, error) {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in getting vault records")
+		return nil, ErrUnauthenticated
+	}
+
+	if err := s.checkVaultRole(ctx, vaultID, userID, entity.RoleRead); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.DB.QueryContext(
+		ctx,
+		`SELECT record_id, record_type, metadata, name, tags, folder, blind_index, updated_at FROM vault_records WHERE vault_id = $1`,
+		vaultID,
+	)
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	defer rows.Close()
+
+	result := make([]entity.Record, 0, 10)
+
+	for rows.Next() {
+		var (
+			row              = entity.Record{VaultID: vaultID}
+			tags, blindIndex string
+		)
+
+		if err = rows.Scan(
+			&row.ID, &row.Type, &row.Metadata, &row.Name, &tags, &row.Folder, &blindIndex, &row.UpdatedAt,
+		); err != nil {
+			log.Infoln(err)
+
+			return nil, ErrUnknown
+		}
+		row.Tags, row.BlindIndex = decodeList(tags), decodeList(blindIndex)
+
+		result = append(result, row)
+	}
+
+	if rows.Err() != nil {
+		log.Println("Failed get rows in getting vault records:", rows.Err())
+		return nil, ErrUnknown
+	}
+
+	return result, nil
+}
+
+// GetVaultRecord gets record of vault with data.
+func (s *dbStorage) GetVaultRecord(ctx context.Context, vaultID, recordID string) (entity.Record, error) {
+	record := entity.Record{VaultID: vaultID}
+
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in getting vault record")
+		return record, ErrUnauthenticated
+	}
+
+	if err := s.checkVaultRole(ctx, vaultID, userID, entity.RoleRead); err != nil {
+		return record, err
+	}
+
+	row := s.DB.QueryRowContext(
+		ctx,
+		`SELECT record_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, updated_at FROM vault_records WHERE record_id = $1 AND vault_id = $2`,
+		recordID,
+		vaultID,
+	)
+
+	var hexDataString, tags, blindIndex string
+	err := row.Scan(
+		&record.ID, &record.Type, &record.Metadata, &record.Name, &tags, &record.Folder, &blindIndex,
+		&hexDataString, &record.UpdatedAt,
+	)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Infoln(err)
+
+		return record, ErrNotFound
+	}
+	if err != nil || row.Err() != nil {
+		log.Infoln(err)
+
+		return record, ErrUnknown
+	}
+
+	record.Data, err = hex.DecodeString(hexDataString)
+	if err != nil {
+		log.Infoln(err)
+
+		return record, ErrUnknown
+	}
+	record.Tags, record.BlindIndex = decodeList(tags), decodeList(blindIndex)
+
+	return record, nil
+}
+
+// CreateVaultRecord creates record in vault of record. User must be able to write to vault.
+func (s *dbStorage) CreateVaultRecord(ctx context.Context, record entity.Record) (string, error) {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in creating vault record")
+		return "", ErrUnauthenticated
+	}
+
+	if err := s.checkVaultRole(ctx, record.VaultID, userID, entity.RoleReadWrite); err != nil {
+		return "", err
+	}
+
+	row := s.DB.QueryRowContext(
+		ctx,
+		`INSERT INTO vault_records (vault_id, created_by, record_type, metadata, name, tags, folder, blind_index, encoded_data) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING record_id`,
+		record.VaultID,
+		userID,
+		record.Type,
+		record.Metadata,
+		record.Name,
+		encodeList(entity.NormalizeTags(record.Tags)),
+		entity.NormalizeFolder(record.Folder),
+		encodeList(record.BlindIndex),
+		hex.EncodeToString(record.Data),
+	)
+
+	var recordID string
+	if err := row.Scan(&recordID); err != nil || row.Err() != nil {
+		log.Infoln(err)
+
+		return "", ErrUnknown
+	}
+
+	return recordID, nil
+}
+
+// UpdateVaultRecord replaces data and metadata of vault record. User must be able to write to vault.
+func (s *dbStorage) UpdateVaultRecord(ctx context.Context, record entity.Record) error {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in updating vault record")
+		return ErrUnauthenticated
+	}
+
+	if err := s.checkVaultRole(ctx, record.VaultID, userID, entity.RoleReadWrite); err != nil {
+		return err
+	}
+
+	return checkAffected(s.DB.ExecContext(
+		ctx,
+		`UPDATE vault_records SET metadata = $1, name = $2, tags = $3, folder = $4, blind_index = $5, encoded_data = $6, updated_at = now() WHERE record_id = $7 AND vault_id = $8 AND record_type = $9`,
+		record.Metadata,
+		record.Name,
+		encodeList(entity.NormalizeTags(record.Tags)),
+		entity.NormalizeFolder(record.Folder),
+		encodeList(record.BlindIndex),
+		hex.EncodeToString(record.Data),
+		record.ID,
+		record.VaultID,
+		record.Type,
+	))
+}
+
+// DeleteVaultRecord deletes record of vault. User must be able to write to vault.
+func (s *dbStorage) DeleteVaultRecord(ctx context.Context, vaultID, recordID string) error {
+	userID, ok := userFromContext(ctx)
+	if !ok {
+		log.Println("Failed get userID from context in deleting vault record")
+		return ErrUnauthenticated
+	}
+
+	if err := s.checkVaultRole(ctx, vaultID, userID, entity.RoleReadWrite); err != nil {
+		return err
+	}
+
+	return checkAffected(s.DB.ExecContext(
+		ctx,
+		`DELETE FROM vault_records WHERE record_id = $1 AND vault_id = $2`,
+		recordID,
+		vaultID,
+	))
+}
+
+// checkVaultRole checks that user is member of vault with role not lower than needed.
+// Vault of other users isn't found, so its existence isn't revealed.
+func (s *dbStorage) checkVaultRole(ctx context.Context, vaultID string, userID entity.UserID, need entity.VaultRole) error {
+	row := s.DB.QueryRowContext(
+		ctx,
+		`SELECT role FROM vault_members WHERE vault_id = $1 AND user_id = $2`,
+		vaultID,
+		userID,
+	)
+
+	var role entity.VaultRole
+
+	err := row.Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil || row.Err() != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	if role < need {
+		return ErrForbidden
+	}
+
+	return nil
+}
@@ -0,0 +1,585 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	vaultUserID = "6584c88d-1bb4-4686-83be-925abb24fc20"
+	vaultID     = "0f2b5c8e-4a1d-4c3b-9e7f-1a2b3c4d5e6f"
+	roleQuery   = `SELECT role FROM vault_members WHERE vault_id = $1 AND user_id = $2`
+)
+
+func vaultContext() context.Context {
+	return entity.ContextWithIdentity(context.Background(), entity.Identity{UserID: entity.UserID(vaultUserID)})
+}
+
+func TestDBStorage_GetPublicKey(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	query := `SELECT public_key, kdf_legacy FROM users WHERE login = $1`
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Get public key with unauthorized user",
+			func() {},
+			func() {
+				_, err := storage.GetPublicKey(context.Background(), "bob")
+				assert.Equal(t, ErrUnauthenticated, err)
+			},
+		},
+		{
+			"Get public key of user",
+			func() {
+				mock.ExpectQuery(query).WithArgs("bob").
+					WillReturnRows(sqlmock.NewRows([]string{"public_key", "kdf_legacy"}).AddRow("0a0b", false))
+			},
+			func() {
+				key, err := storage.GetPublicKey(vaultContext(), "bob")
+				assert.NoError(t, err)
+				assert.Equal(t, []byte{0x0a, 0x0b}, key)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get public key of user, who was registered before keys",
+			func() {
+				mock.ExpectQuery(query).WithArgs("old").
+					WillReturnRows(sqlmock.NewRows([]string{"public_key", "kdf_legacy"}).AddRow("", false))
+			},
+			func() {
+				_, err := storage.GetPublicKey(vaultContext(), "old")
+				assert.Equal(t, ErrNoPublicKey, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get public key of user on legacy key",
+			func() {
+				mock.ExpectQuery(query).WithArgs("legacy").
+					WillReturnRows(sqlmock.NewRows([]string{"public_key", "kdf_legacy"}).AddRow("0a0b", true))
+			},
+			func() {
+				_, err := storage.GetPublicKey(vaultContext(), "legacy")
+				assert.Equal(t, ErrNoPublicKey, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get public key of unknown user",
+			func() {
+				mock.ExpectQuery(query).WithArgs("nobody").
+					WillReturnRows(sqlmock.NewRows([]string{"public_key", "kdf_legacy"}))
+			},
+			func() {
+				_, err := storage.GetPublicKey(vaultContext(), "nobody")
+				assert.Equal(t, ErrNotFound, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_SetPublicKey(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	query := `UPDATE users SET public_key = $1 WHERE user_id = $2 AND NOT kdf_legacy AND public_key IN ('', $1)`
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Set public key with unauthorized user",
+			func() {},
+			func() {
+				assert.Equal(t, ErrUnauthenticated, storage.SetPublicKey(context.Background(), []byte{0x0a}))
+			},
+		},
+		{
+			"Set public key of user, who has no one or the same",
+			func() {
+				mock.ExpectExec(query).WithArgs("0a0b", vaultUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			func() {
+				assert.NoError(t, storage.SetPublicKey(vaultContext(), []byte{0x0a, 0x0b}))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Set public key of user, who has another one or is on legacy key",
+			func() {
+				mock.ExpectExec(query).WithArgs("0c", vaultUserID).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			func() {
+				assert.Equal(t, ErrConflict, storage.SetPublicKey(vaultContext(), []byte{0x0c}))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_Vaults(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Create vault",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO vaults (name, owner_id) VALUES ($1, $2) RETURNING vault_id`).
+					WithArgs("Team", vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"vault_id"}).AddRow(vaultID))
+				mock.ExpectExec(`INSERT INTO vault_members (vault_id, user_id, role, wrapped_key) VALUES ($1, $2, $3, $4)`).
+					WithArgs(vaultID, vaultUserID, entity.RoleOwner, "0102").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			func() {
+				id, err := storage.CreateVault(vaultContext(), entity.Vault{Name: "Team", WrappedKey: []byte{0x01, 0x02}})
+				assert.NoError(t, err)
+				assert.Equal(t, vaultID, id)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Create vault, but DB will return error",
+			func() {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`INSERT INTO vaults (name, owner_id) VALUES ($1, $2) RETURNING vault_id`).
+					WithArgs("Team", vaultUserID).
+					WillReturnError(errors.New("some DB error"))
+				mock.ExpectRollback()
+			},
+			func() {
+				id, err := storage.CreateVault(vaultContext(), entity.Vault{Name: "Team"})
+				assert.Equal(t, ErrUnknown, err)
+				assert.Empty(t, id)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get vaults of user",
+			func() {
+				mock.ExpectQuery(
+					`SELECT v.vault_id, v.name, m.role, m.wrapped_key FROM vaults v JOIN vault_members m ON m.vault_id = v.vault_id WHERE m.user_id = $1 ORDER BY lower(v.name), v.vault_id`,
+				).WithArgs(vaultUserID).WillReturnRows(
+					sqlmock.NewRows([]string{"vault_id", "name", "role", "wrapped_key"}).
+						AddRow(vaultID, "Team", entity.RoleReadWrite, "0102"),
+				)
+			},
+			func() {
+				vaults, err := storage.GetVaults(vaultContext())
+				assert.NoError(t, err)
+				assert.Equal(t, []entity.Vault{{
+					ID:         vaultID,
+					Name:       "Team",
+					Role:       entity.RoleReadWrite,
+					WrappedKey: []byte{0x01, 0x02},
+				}}, vaults)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get vaults with unauthorized user",
+			func() {},
+			func() {
+				_, err := storage.GetVaults(context.Background())
+				assert.Equal(t, ErrUnauthenticated, err)
+			},
+		},
+		{
+			"Get members of vault",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(entity.RoleRead))
+				mock.ExpectQuery(
+					`SELECT u.login, m.role, m.key_mac FROM vault_members m JOIN users u ON u.user_id = m.user_id WHERE m.vault_id = $1 ORDER BY m.role DESC, u.login`,
+				).WithArgs(vaultID).WillReturnRows(
+					sqlmock.NewRows([]string{"login", "role", "key_mac"}).
+						AddRow("alice", entity.RoleOwner, "").
+						AddRow("bob", entity.RoleRead, "0c"),
+				)
+			},
+			func() {
+				members, err := storage.GetVaultMembers(vaultContext(), vaultID)
+				assert.NoError(t, err)
+				assert.Equal(t, []entity.VaultMember{
+					{VaultID: vaultID, Login: "alice", Role: entity.RoleOwner, KeyMAC: []byte{}},
+					{VaultID: vaultID, Login: "bob", Role: entity.RoleRead, KeyMAC: []byte{0x0c}},
+				}, members)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get members of vault of other users",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}))
+			},
+			func() {
+				_, err := storage.GetVaultMembers(vaultContext(), vaultID)
+				assert.Equal(t, ErrNotFound, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_VaultMembers(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	add := `INSERT INTO vault_members (vault_id, user_id, role, wrapped_key, key_mac) SELECT $1, user_id, $2, $3, $4 FROM users WHERE login = $5 ` +
+		`ON CONFLICT (vault_id, user_id) DO UPDATE SET role = EXCLUDED.role, wrapped_key = EXCLUDED.wrapped_key, key_mac = EXCLUDED.key_mac WHERE vault_members.role <> $6`
+	remove := `DELETE FROM vault_members WHERE vault_id = $1 AND role <> $2 AND user_id = (SELECT user_id FROM users WHERE login = $3)`
+	rewrap := `UPDATE vault_members SET wrapped_key = $1 WHERE vault_id = $2 AND user_id = (SELECT user_id FROM users WHERE login = $3)`
+	reencrypt := `UPDATE vault_records SET metadata = $1, name = $2, tags = $3, folder = $4, blind_index = $5, encoded_data = $6, updated_at = now() WHERE record_id = $7 AND vault_id = $8`
+	count := `SELECT (SELECT count(*) FROM vault_members WHERE vault_id = $1), (SELECT count(*) FROM vault_records WHERE vault_id = $1)`
+	bob := entity.VaultMember{VaultID: vaultID, Login: "bob", Role: entity.RoleReadWrite, WrappedKey: []byte{0x03}, KeyMAC: []byte{0x0c}}
+	rotation := entity.VaultRotation{
+		VaultID: vaultID,
+		Revoked: "bob",
+		Members: []entity.VaultMember{{Login: "alice", WrappedKey: []byte{0x04}}},
+		Records: []entity.Record{{ID: "1", Type: entity.TypeText, Metadata: "m", Name: "n", Tags: []string{"t"}, Data: []byte{0x05}}},
+	}
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Owner invites member",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(entity.RoleOwner))
+				mock.ExpectExec(add).WithArgs(vaultID, entity.RoleReadWrite, "03", "0c", "bob", entity.RoleOwner).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			func() {
+				assert.NoError(t, storage.AddVaultMember(vaultContext(), bob))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Owner invites unknown user",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(entity.RoleOwner))
+				mock.ExpectExec(add).WithArgs(vaultID, entity.RoleReadWrite, "03", "0c", "bob", entity.RoleOwner).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			func() {
+				assert.Equal(t, ErrNotFound, storage.AddVaultMember(vaultContext(), bob))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Writer can't invite members",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(entity.RoleReadWrite))
+			},
+			func() {
+				assert.Equal(t, ErrForbidden, storage.AddVaultMember(vaultContext(), bob))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Owner role can't be given",
+			func() {},
+			func() {
+				member := bob
+				member.Role = entity.RoleOwner
+				assert.Equal(t, ErrForbidden, storage.AddVaultMember(vaultContext(), member))
+			},
+		},
+		{
+			"Owner revokes member, vault key is replaced",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(entity.RoleOwner))
+				mock.ExpectBegin()
+				mock.ExpectExec(remove).WithArgs(vaultID, entity.RoleOwner, "bob").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(rewrap).WithArgs("04", vaultID, "alice").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(reencrypt).WithArgs("m", "n", ",t,", "", "", "05", "1", vaultID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(count).WithArgs(vaultID).
+					WillReturnRows(sqlmock.NewRows([]string{"members", "records"}).AddRow(1, 1))
+				mock.ExpectCommit()
+			},
+			func() {
+				assert.NoError(t, storage.RevokeVaultMember(vaultContext(), rotation))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Owner revokes member, but record was added to vault meanwhile",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(entity.RoleOwner))
+				mock.ExpectBegin()
+				mock.ExpectExec(remove).WithArgs(vaultID, entity.RoleOwner, "bob").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(rewrap).WithArgs("04", vaultID, "alice").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(reencrypt).WithArgs("m", "n", ",t,", "", "", "05", "1", vaultID).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(count).WithArgs(vaultID).
+					WillReturnRows(sqlmock.NewRows([]string{"members", "records"}).AddRow(1, 2))
+				mock.ExpectRollback()
+			},
+			func() {
+				assert.Equal(t, ErrConflict, storage.RevokeVaultMember(vaultContext(), rotation))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Owner revokes member, but other member left meanwhile",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(entity.RoleOwner))
+				mock.ExpectBegin()
+				mock.ExpectExec(remove).WithArgs(vaultID, entity.RoleOwner, "bob").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(rewrap).WithArgs("04", vaultID, "alice").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			func() {
+				assert.Equal(t, ErrConflict, storage.RevokeVaultMember(vaultContext(), rotation))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Owner revokes user, who isn't member",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(entity.RoleOwner))
+				mock.ExpectBegin()
+				mock.ExpectExec(remove).WithArgs(vaultID, entity.RoleOwner, "bob").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+			func() {
+				assert.Equal(t, ErrNotFound, storage.RevokeVaultMember(vaultContext(), rotation))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Reader can't revoke members",
+			func() {
+				mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+					WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(entity.RoleRead))
+			},
+			func() {
+				assert.Equal(t, ErrForbidden, storage.RevokeVaultMember(vaultContext(), rotation))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestDBStorage_VaultRecords(t *testing.T) {
+	storage := newDBStorage(dbConnectionURL)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	assert.NoError(t, err)
+	storage.DB = db
+
+	updatedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	record := entity.Record{
+		ID:       "1",
+		VaultID:  vaultID,
+		Type:     entity.TypeText,
+		Metadata: "shared",
+		Name:     "Wi-Fi",
+		Tags:     []string{"Office"},
+		Data:     []byte{0x01, 0x02},
+	}
+	expectRole := func(role entity.VaultRole) {
+		mock.ExpectQuery(roleQuery).WithArgs(vaultID, vaultUserID).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+	}
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Get records of vault",
+			func() {
+				expectRole(entity.RoleRead)
+				mock.ExpectQuery(
+					`SELECT record_id, record_type, metadata, name, tags, folder, blind_index, updated_at FROM vault_records WHERE vault_id = $1`,
+				).WithArgs(vaultID).WillReturnRows(
+					sqlmock.NewRows([]string{"record_id", "record_type", "metadata", "name", "tags", "folder", "blind_index", "updated_at"}).
+						AddRow("1", entity.TypeText, "shared", "Wi-Fi", ",office,", "", "", updatedAt),
+				)
+			},
+			func() {
+				records, err := storage.GetVaultRecords(vaultContext(), vaultID)
+				assert.NoError(t, err)
+				assert.Equal(t, []entity.Record{{
+					ID:        "1",
+					VaultID:   vaultID,
+					Type:      entity.TypeText,
+					Metadata:  "shared",
+					Name:      "Wi-Fi",
+					Tags:      []string{"office"},
+					UpdatedAt: updatedAt,
+				}}, records)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Get record of vault",
+			func() {
+				expectRole(entity.RoleRead)
+				mock.ExpectQuery(
+					`SELECT record_id, record_type, metadata, name, tags, folder, blind_index, encoded_data, updated_at FROM vault_records WHERE record_id = $1 AND vault_id = $2`,
+				).WithArgs("1", vaultID).WillReturnRows(
+					sqlmock.NewRows([]string{"record_id", "record_type", "metadata", "name", "tags", "folder", "blind_index", "encoded_data", "updated_at"}).
+						AddRow("1", entity.TypeText, "shared", "Wi-Fi", "", "", "", "0102", updatedAt),
+				)
+			},
+			func() {
+				got, err := storage.GetVaultRecord(vaultContext(), vaultID, "1")
+				assert.NoError(t, err)
+				assert.Equal(t, []byte{0x01, 0x02}, got.Data)
+				assert.Equal(t, vaultID, got.VaultID)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Writer creates record",
+			func() {
+				expectRole(entity.RoleReadWrite)
+				mock.ExpectQuery(
+					`INSERT INTO vault_records (vault_id, created_by, record_type, metadata, name, tags, folder, blind_index, encoded_data) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING record_id`,
+				).WithArgs(vaultID, vaultUserID, entity.TypeText, "shared", "Wi-Fi", ",office,", "", "", "0102").
+					WillReturnRows(sqlmock.NewRows([]string{"record_id"}).AddRow("1"))
+			},
+			func() {
+				id, err := storage.CreateVaultRecord(vaultContext(), record)
+				assert.NoError(t, err)
+				assert.Equal(t, "1", id)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Reader can't create records",
+			func() {
+				expectRole(entity.RoleRead)
+			},
+			func() {
+				_, err := storage.CreateVaultRecord(vaultContext(), record)
+				assert.Equal(t, ErrForbidden, err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Writer updates record",
+			func() {
+				expectRole(entity.RoleOwner)
+				mock.ExpectExec(
+					`UPDATE vault_records SET metadata = $1, name = $2, tags = $3, folder = $4, blind_index = $5, encoded_data = $6, updated_at = now() WHERE record_id = $7 AND vault_id = $8 AND record_type = $9`,
+				).WithArgs("shared", "Wi-Fi", ",office,", "", "", "0102", "1", vaultID, entity.TypeText).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			func() {
+				assert.NoError(t, storage.UpdateVaultRecord(vaultContext(), record))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Writer deletes not existing record",
+			func() {
+				expectRole(entity.RoleReadWrite)
+				mock.ExpectExec(`DELETE FROM vault_records WHERE record_id = $1 AND vault_id = $2`).
+					WithArgs("2", vaultID).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+			func() {
+				assert.Equal(t, ErrNotFound, storage.DeleteVaultRecord(vaultContext(), vaultID, "2"))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+		{
+			"Reader can't delete records",
+			func() {
+				expectRole(entity.RoleRead)
+			},
+			func() {
+				assert.Equal(t, ErrForbidden, storage.DeleteVaultRecord(vaultContext(), vaultID, "1"))
+				assert.NoError(t, mock.ExpectationsWereMet())
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
@@ -8,5 +8,22 @@ var (
 	ErrWrongCredentials = errors.New("wrong login or password")
 	ErrLoginExists      = errors.New("this login already exists")
 	ErrNotFound         = errors.New("not found record with such id")
+	ErrNotSupported     = errors.New("operation isn't supported for this record type")
+	ErrConflict         = errors.New("record was changed on another device")
+	ErrForbidden        = errors.New("not enough rights for this vault")
+	ErrNoPublicKey      = errors.New("user has no public key")
 	ErrUnknown          = errors.New("internal server error")
 )
+
+// Errors for backups of server.
+var (
+	ErrBadBackup      = errors.New("backup is corrupted")
+	ErrNotEmpty       = errors.New("storage isn't empty, backup is restored only into empty instance")
+	ErrSchemaMismatch = errors.New("schema version of backup differs from schema of storage")
+)
+
+// Errors for file storages.
+var (
+	ErrBadStorageURL = errors.New("wrong URL of file storage")
+	ErrBadRecordID   = errors.New("id of file record isn't UUID")
+)
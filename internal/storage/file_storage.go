@@ -1,55 +1,91 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/bbt-t/lets-go-keep/internal/entity"
+	"github.com/bbt-t/lets-go-keep/pkg"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// Files of records are kept in subdirectories by the first characters of IDs (shards),
+// so directories don't grow too much. Files are written to temporary files in the same shard,
+// which are renamed after data is synced, so file of record is either complete or missing.
+const (
+	fileShardLength = 2
+	fileTempPrefix  = ".tmp-"
+)
+
+// fileRecordID is ID of file record (UUID), only such IDs are names of files, so IDs can't escape directory.
+var fileRecordID = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// newFileRecordID returns random UUID (version 4) for file record, so file can be written before record.
+func newFileRecordID() (string, error) {
+	id, err := pkg.GenerateRandom(16)
+	if err != nil {
+		return "", err
+	}
+
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:]), nil
+}
+
+// renameFile renames synced temporary file to file of record, tests replace it to simulate crash.
+var renameFile = os.Rename
+
 // fileStorage keeps records on disk.
 type fileStorage struct {
 	directory string
 }
 
-// newFileStorage returns new file storage.
+// newFileStorage returns new file storage. Temporary files, which are left by crash, are deleted,
+// files of old layout without shards are moved to shards.
 func newFileStorage(directory string) *fileStorage {
-	if err := os.Mkdir(directory, os.ModePerm); err != nil && !os.IsExist(err) {
+	if err := os.MkdirAll(directory, 0o700); err != nil {
 		log.Fatalln("Failed open directory for file storage")
 
 		return nil
 	}
 
-	return &fileStorage{directory: directory}
+	storage := &fileStorage{directory: directory}
+	storage.recover()
+
+	return storage
 }
 
 // GetRecord reads file with record data.
 func (storage *fileStorage) GetRecord(ctx context.Context, recordID string) (entity.Record, error) {
+	return getFileRecord(ctx, storage, recordID)
+}
+
+// getFileRecord reads whole data of file record, metadata of record is got from context.
+func getFileRecord(ctx context.Context, storage FileStorager, recordID string) (entity.Record, error) {
 	metadata, ok := ctx.Value("recordMetadata").(string)
 	if !ok {
 		log.Println("Failed get record metadata from context in getting file record")
 		return entity.Record{}, ErrUnknown
 	}
 
-	file, err := os.Open(storage.directory + "/" + recordID)
-	if errors.Is(err, os.ErrNotExist) {
-		log.Infoln(err)
-
-		return entity.Record{}, ErrNotFound
-	}
+	file, err := storage.OpenRecord(ctx, recordID)
 	if err != nil {
-		log.Infoln(err)
-
-		return entity.Record{}, ErrUnknown
+		return entity.Record{}, err
 	}
+	defer file.Close()
 
 	data, errReadAll := io.ReadAll(file)
 	if errReadAll != nil {
-		log.Infoln(err)
+		log.Infoln(errReadAll)
 
 		return entity.Record{}, ErrUnknown
 	}
@@ -62,34 +98,184 @@ func (storage *fileStorage) GetRecord(ctx context.Context, recordID string) (ent
 	}, nil
 }
 
+// OpenRecord opens file with record data for reading by chunks. Caller must close it.
+func (storage *fileStorage) OpenRecord(_ context.Context, recordID string) (io.ReadCloser, error) {
+	filename, ok := storage.filename(recordID)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Infoln(err)
+
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	return file, nil
+}
+
 // DeleteRecord deletes file with record data.
 func (storage *fileStorage) DeleteRecord(_ context.Context, recordID string) error {
-	filename := storage.directory + "/" + recordID
-	if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+	filename, ok := storage.filename(recordID)
+	if !ok {
 		return ErrNotFound
 	}
 
-	if err := os.RemoveAll(filename); err != nil {
+	err := os.Remove(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
 		log.Infoln(err)
 
 		return ErrUnknown
 	}
 
+	syncDirectory(filepath.Dir(filename))
+
 	return nil
 }
 
 // CreateRecord creates new file with record data.
-func (storage *fileStorage) CreateRecord(_ context.Context, record entity.Record) (string, error) {
-	file, err := os.Create(storage.directory + "/" + record.ID)
+func (storage *fileStorage) CreateRecord(ctx context.Context, record entity.Record) (string, error) {
+	if err := storage.WriteRecord(ctx, record.ID, bytes.NewReader(record.Data)); err != nil {
+		return "", err
+	}
+
+	return record.ID, nil
+}
+
+// WriteRecord copies record data from reader chunk by chunk to temporary file, syncs it and renames it
+// to file of record, so file of record is replaced only by complete data. Temporary file is deleted on errors.
+func (storage *fileStorage) WriteRecord(_ context.Context, recordID string, r io.Reader) error {
+	filename, ok := storage.filename(recordID)
+	if !ok {
+		return ErrBadRecordID
+	}
+
+	shard := filepath.Dir(filename)
+	if err := os.MkdirAll(shard, 0o700); err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	file, err := os.CreateTemp(shard, fileTempPrefix+"*")
 	if err != nil {
 		log.Infoln(err)
 
-		return "", ErrUnknown
+		return ErrUnknown
 	}
 
-	if _, err := file.Write(record.Data); err != nil {
-		return "", ErrUnknown
+	_, err = io.Copy(file, r)
+	if err == nil {
+		err = file.Sync()
+	}
+	if errClose := file.Close(); err == nil {
+		err = errClose
+	}
+	if err == nil {
+		err = renameFile(file.Name(), filename)
 	}
 
-	return record.ID, nil
+	if err != nil {
+		log.Infoln(err)
+
+		if errRemove := os.Remove(file.Name()); errRemove != nil {
+			log.Infoln(errRemove)
+		}
+
+		return ErrUnknown
+	}
+
+	syncDirectory(shard)
+
+	return nil
+}
+
+// filename is path of file of record in its shard, if ID is UUID.
+func (storage *fileStorage) filename(recordID string) (string, bool) {
+	if !fileRecordID.MatchString(recordID) {
+		return "", false
+	}
+
+	recordID = strings.ToLower(recordID)
+
+	return filepath.Join(storage.directory, recordID[:fileShardLength], recordID), true
+}
+
+// recover deletes temporary files and moves files of records from root of directory to shards.
+// Errors are only logged, files are kept.
+func (storage *fileStorage) recover() {
+	entries, err := os.ReadDir(storage.directory)
+	if err != nil {
+		log.Warnf("%s :: %v", "read file storage fault", err)
+
+		return
+	}
+
+	for _, entry := range entries {
+		name := filepath.Join(storage.directory, entry.Name())
+
+		switch {
+		case entry.IsDir():
+			removeTempFiles(name)
+		case strings.HasPrefix(entry.Name(), fileTempPrefix):
+			removeFile(name)
+		case fileRecordID.MatchString(entry.Name()):
+			filename, _ := storage.filename(entry.Name())
+			if err = os.MkdirAll(filepath.Dir(filename), 0o700); err == nil {
+				err = os.Rename(name, filename)
+			}
+			if err != nil {
+				log.Warnf("%s :: %v", "move file to shard fault", err)
+			}
+		}
+	}
+}
+
+// removeTempFiles deletes temporary files of shard.
+func removeTempFiles(shard string) {
+	entries, err := os.ReadDir(shard)
+	if err != nil {
+		log.Warnf("%s :: %v", "read shard of file storage fault", err)
+
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), fileTempPrefix) {
+			removeFile(filepath.Join(shard, entry.Name()))
+		}
+	}
+}
+
+// removeFile deletes file, which is left by crash.
+func removeFile(name string) {
+	log.Warnf("%s :: %s", "delete temporary file of file storage", name)
+
+	if err := os.Remove(name); err != nil {
+		log.Warnf("%s :: %v", "delete temporary file fault", err)
+	}
+}
+
+// syncDirectory syncs directory, so renames and deletions of its files survive crash.
+func syncDirectory(directory string) {
+	dir, err := os.Open(directory)
+	if err != nil {
+		log.Infoln(err)
+
+		return
+	}
+	defer dir.Close()
+
+	if err = dir.Sync(); err != nil {
+		log.Infoln(err)
+	}
 }
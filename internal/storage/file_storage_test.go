@@ -2,7 +2,11 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bbt-t/lets-go-keep/internal/config"
@@ -13,6 +17,16 @@ import (
 
 var filesDirectory = config.NewServerConfig().FilesDirectory
 
+const (
+	testRecordID = "3f2504e0-4f89-41d3-9a0c-0305e82c3301"
+	testOtherID  = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+)
+
+var (
+	testRecordFile = filepath.Join(filesDirectory, "3f", testRecordID)
+	testOtherFile  = filepath.Join(filesDirectory, "7c", testOtherID)
+)
+
 func TestNewFileStorage(t *testing.T) {
 	assert.NotPanics(t, func() {
 		newFileStorage(filesDirectory)
@@ -31,16 +45,16 @@ func TestFileStorage_CreateRecord(t *testing.T) {
 			"Create file record",
 			func() {
 				id, err := storage.CreateRecord(context.Background(), entity.Record{
-					ID:   "1",
+					ID:   testRecordID,
 					Type: entity.TypeFile,
 					Data: []byte("text"),
 				})
 				assert.NoError(t, err)
-				assert.Equal(t, "1", id)
+				assert.Equal(t, testRecordID, id)
 			},
 			func() {
 				assert.DirExists(t, filesDirectory)
-				assert.FileExists(t, filesDirectory+"/1")
+				assert.FileExists(t, testRecordFile)
 			},
 		},
 	}
@@ -66,20 +80,20 @@ func TestFileStorage_GetRecord(t *testing.T) {
 			"Get existed file record",
 			func() {
 				id, err := storage.CreateRecord(context.Background(), entity.Record{
-					ID:   "1",
+					ID:   testRecordID,
 					Type: entity.TypeFile,
 					Data: []byte("text"),
 				})
 				assert.NoError(t, err)
-				assert.Equal(t, "1", id)
+				assert.Equal(t, testRecordID, id)
 			},
 			func() {
 				ctx := context.WithValue(context.Background(), "recordMetadata", "file.txt")
-				record, err := storage.GetRecord(ctx, "1")
+				record, err := storage.GetRecord(ctx, testRecordID)
 				assert.NoError(t, err)
 
 				assert.Equal(t, entity.Record{
-					ID:       "1",
+					ID:       testRecordID,
 					Metadata: "file.txt",
 					Type:     entity.TypeFile,
 					Data:     []byte("text"),
@@ -91,15 +105,15 @@ func TestFileStorage_GetRecord(t *testing.T) {
 			"Get existed file record, but don't provide record metadata",
 			func() {
 				id, err := storage.CreateRecord(context.Background(), entity.Record{
-					ID:   "1",
+					ID:   testRecordID,
 					Type: entity.TypeFile,
 					Data: []byte("text"),
 				})
 				assert.NoError(t, err)
-				assert.Equal(t, "1", id)
+				assert.Equal(t, testRecordID, id)
 			},
 			func() {
-				record, err := storage.GetRecord(context.Background(), "1")
+				record, err := storage.GetRecord(context.Background(), testRecordID)
 				assert.Equal(t, ErrUnknown, err)
 				assert.Empty(t, record)
 			},
@@ -108,16 +122,16 @@ func TestFileStorage_GetRecord(t *testing.T) {
 			"Get non existed file record",
 			func() {
 				id, err := storage.CreateRecord(context.Background(), entity.Record{
-					ID:   "1",
+					ID:   testRecordID,
 					Type: entity.TypeFile,
 					Data: []byte("text"),
 				})
 				assert.NoError(t, err)
-				assert.Equal(t, "1", id)
+				assert.Equal(t, testRecordID, id)
 			},
 			func() {
 				ctx := context.WithValue(context.Background(), "recordMetadata", "file.txt")
-				record, err := storage.GetRecord(ctx, "2")
+				record, err := storage.GetRecord(ctx, testOtherID)
 				assert.Equal(t, ErrNotFound, err)
 				assert.Empty(t, record)
 			},
@@ -145,34 +159,89 @@ func TestFileStorage_DeleteRecord(t *testing.T) {
 			"Delete existed file record",
 			func() {
 				id, err := storage.CreateRecord(context.Background(), entity.Record{
-					ID:   "1",
+					ID:   testRecordID,
 					Type: entity.TypeFile,
 					Data: []byte("text"),
 				})
 				assert.NoError(t, err)
-				assert.Equal(t, "1", id)
+				assert.Equal(t, testRecordID, id)
 			},
 			func() {
-				err := storage.DeleteRecord(context.Background(), "1")
+				err := storage.DeleteRecord(context.Background(), testRecordID)
 				assert.NoError(t, err)
-				assert.NoFileExists(t, filesDirectory+"/1")
+				assert.NoFileExists(t, testRecordFile)
 			},
 		},
 		{
 			"Delete non existed file record",
 			func() {
 				id, err := storage.CreateRecord(context.Background(), entity.Record{
-					ID:   "1",
+					ID:   testRecordID,
 					Type: entity.TypeFile,
 					Data: []byte("text"),
 				})
 				assert.NoError(t, err)
-				assert.Equal(t, "1", id)
+				assert.Equal(t, testRecordID, id)
 			},
 			func() {
-				err := storage.DeleteRecord(context.Background(), "2")
+				err := storage.DeleteRecord(context.Background(), testOtherID)
 				assert.Equal(t, ErrNotFound, err)
-				assert.NoFileExists(t, filesDirectory+"/2")
+				assert.NoFileExists(t, testOtherFile)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.prepare()
+		test.valid()
+	}
+
+	assert.NoError(t, os.RemoveAll(filesDirectory))
+}
+
+// failingReader returns error after data is read.
+type failingReader struct {
+	data io.Reader
+}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	n, err := r.data.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, errors.New("connection lost")
+	}
+
+	return n, err
+}
+
+func TestFileStorage_WriteRecord(t *testing.T) {
+	storage := newFileStorage(filesDirectory)
+
+	tc := []struct {
+		name    string
+		prepare func()
+		valid   func()
+	}{
+		{
+			"Write file record from reader",
+			func() {
+				err := storage.WriteRecord(context.Background(), testRecordID, strings.NewReader(strings.Repeat("text", 1<<16)))
+				assert.NoError(t, err)
+			},
+			func() {
+				data, err := os.ReadFile(testRecordFile)
+				assert.NoError(t, err)
+				assert.Equal(t, strings.Repeat("text", 1<<16), string(data))
+			},
+		},
+		{
+			"Write file record from broken reader",
+			func() {
+				err := storage.WriteRecord(context.Background(), testOtherID, failingReader{strings.NewReader("text")})
+				assert.Equal(t, ErrUnknown, err)
+			},
+			func() {
+				assert.NoFileExists(t, testOtherFile)
 			},
 		},
 	}
@@ -185,3 +254,175 @@ func TestFileStorage_DeleteRecord(t *testing.T) {
 
 	assert.NoError(t, os.RemoveAll(filesDirectory))
 }
+
+func TestFileStorage_OpenRecord(t *testing.T) {
+	storage := newFileStorage(filesDirectory)
+
+	tc := []struct {
+		name    string
+		prepare func()
+		valid   func()
+	}{
+		{
+			"Open existed file record",
+			func() {
+				err := storage.WriteRecord(context.Background(), testRecordID, strings.NewReader("text"))
+				assert.NoError(t, err)
+			},
+			func() {
+				file, err := storage.OpenRecord(context.Background(), testRecordID)
+				assert.NoError(t, err)
+
+				data, err := io.ReadAll(file)
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("text"), data)
+				assert.NoError(t, file.Close())
+			},
+		},
+		{
+			"Open non existed file record",
+			func() {},
+			func() {
+				file, err := storage.OpenRecord(context.Background(), testOtherID)
+				assert.Equal(t, ErrNotFound, err)
+				assert.Nil(t, file)
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+		test.prepare()
+		test.valid()
+	}
+
+	assert.NoError(t, os.RemoveAll(filesDirectory))
+}
+
+func TestFileStorage_RecordID(t *testing.T) {
+	directory := filepath.Join(t.TempDir(), "files")
+	storage := newFileStorage(directory)
+
+	tc := []struct {
+		name string
+		id   string
+	}{
+		{"ID with traversal", "../../outside"},
+		{"ID with traversal in UUID form", "../3f2504e0-4f89-41d3-9a0c-0305e82c3301"},
+		{"ID isn't UUID", "1"},
+		{"Empty ID", ""},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		err := storage.WriteRecord(context.Background(), test.id, strings.NewReader("text"))
+		assert.ErrorIs(t, err, ErrBadRecordID)
+
+		_, err = storage.OpenRecord(context.Background(), test.id)
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.ErrorIs(t, storage.DeleteRecord(context.Background(), test.id), ErrNotFound)
+	}
+
+	assert.NoFileExists(t, filepath.Join(directory, "..", "outside"))
+
+	// IDs in upper case are the same records.
+	assert.NoError(t, storage.WriteRecord(context.Background(), strings.ToUpper(testRecordID), strings.NewReader("text")))
+	assert.FileExists(t, filepath.Join(directory, "3f", testRecordID))
+}
+
+func TestFileStorage_InterruptedWrite(t *testing.T) {
+	directory := t.TempDir()
+	storage := newFileStorage(directory)
+	filename := filepath.Join(directory, "3f", testRecordID)
+
+	tc := []struct {
+		name  string
+		write func() error
+	}{
+		{
+			"Connection is lost while file is written",
+			func() error {
+				return storage.WriteRecord(context.Background(), testRecordID, failingReader{strings.NewReader("new text")})
+			},
+		},
+		{
+			"Server stops before file is renamed",
+			func() error {
+				renameFile = func(string, string) error { return errors.New("crash") }
+				defer func() { renameFile = os.Rename }()
+
+				return storage.WriteRecord(context.Background(), testRecordID, strings.NewReader("new text"))
+			},
+		},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		assert.NoError(t, storage.WriteRecord(context.Background(), testRecordID, strings.NewReader("old text")))
+		assert.ErrorIs(t, test.write(), ErrUnknown)
+
+		// File of record keeps previous data, temporary file is deleted.
+		data, err := os.ReadFile(filename)
+		assert.NoError(t, err)
+		assert.Equal(t, "old text", string(data))
+
+		entries, err := os.ReadDir(filepath.Dir(filename))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	}
+}
+
+func TestFileStorage_Recover(t *testing.T) {
+	directory := t.TempDir()
+
+	// Files, which are left by crash, and file of record of old layout without shards.
+	assert.NoError(t, os.MkdirAll(filepath.Join(directory, "3f"), 0o700))
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, "3f", fileTempPrefix+"123"), []byte("part"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, fileTempPrefix+"456"), []byte("part"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, testOtherID), []byte("text"), 0o600))
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, "README"), []byte("keep"), 0o600))
+
+	storage := newFileStorage(directory)
+
+	assert.NoFileExists(t, filepath.Join(directory, "3f", fileTempPrefix+"123"))
+	assert.NoFileExists(t, filepath.Join(directory, fileTempPrefix+"456"))
+	assert.NoFileExists(t, filepath.Join(directory, testOtherID))
+	assert.FileExists(t, filepath.Join(directory, "README"))
+
+	file, err := storage.OpenRecord(context.Background(), testOtherID)
+	assert.NoError(t, err)
+
+	data, err := io.ReadAll(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "text", string(data))
+	assert.NoError(t, file.Close())
+}
+
+func TestFileStorage_ClosesFiles(t *testing.T) {
+	descriptors := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skip("open files can't be counted:", err)
+		}
+
+		return len(entries)
+	}
+
+	storage := newFileStorage(t.TempDir())
+	ctx := context.WithValue(context.Background(), "recordMetadata", "file.txt")
+
+	before := descriptors()
+	for i := 0; i < 100; i++ {
+		_, err := storage.CreateRecord(ctx, entity.Record{ID: testRecordID, Type: entity.TypeFile, Data: []byte("text")})
+		assert.NoError(t, err)
+
+		_, err = storage.GetRecord(ctx, testRecordID)
+		assert.NoError(t, err)
+
+		assert.NoError(t, storage.DeleteRecord(ctx, testRecordID))
+	}
+
+	assert.Equal(t, before, descriptors())
+}
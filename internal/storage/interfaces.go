@@ -2,18 +2,42 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/entity"
 )
 
+// DataBaseStorage interface for DB storage, which can be migrated.
 type DataBaseStorage interface {
 	MigrateUP()
-	CreateUser(credentials entity.UserCredentials) error
-	LoginUser(credentials entity.UserCredentials) (entity.UserID, error)
-	GetRecordsInfo(ctx context.Context) ([]entity.Record, error)
-	CreateRecord(ctx context.Context, record entity.Record) (string, error)
-	GetRecord(ctx context.Context, recordID string) (entity.Record, error)
-	DeleteRecord(ctx context.Context, recordID string) error
+	RecordStorager
+	BackupStorager
+}
+
+// BackupStorager interface for DB storage, which can be dumped and restored from dump.
+type BackupStorager interface {
+	Dump(ctx context.Context) (Dump, error)
+	CheckRestore(ctx context.Context, schema int64) error
+	Load(ctx context.Context, dump Dump) error
+}
+
+// Dump is consistent snapshot of DB: version of schema, rows of tables in JSON and IDs of file records.
+// Rows are kept in temporary files, so dump isn't kept in memory. Dump must be closed.
+type Dump struct {
+	Schema int64
+	Tables []DumpTable
+	Files  []string
+}
+
+// DumpTable is table with number of its rows and file with rows in JSON lines.
+type DumpTable struct {
+	Name string
+	Rows int
+	File *os.File
 }
 
 // NewDBStorage connects to DB (interface).
@@ -28,6 +52,8 @@ type FileStorager interface {
 	GetRecord(ctx context.Context, recordID string) (entity.Record, error)
 	CreateRecord(ctx context.Context, record entity.Record) (string, error)
 	DeleteRecord(ctx context.Context, recordID string) error
+	WriteRecord(ctx context.Context, recordID string, r io.Reader) error
+	OpenRecord(ctx context.Context, recordID string) (io.ReadCloser, error)
 }
 
 // NewFileStorage returns new file storage (interface).
@@ -35,12 +61,76 @@ func NewFileStorage(directory string) FileStorager {
 	return newFileStorage(directory)
 }
 
-// Storager interface for storage, which can storage only text data.
+// NewFileStorageByURL returns file storage selected by scheme of URL: local directory for path
+// or file:// URL, S3-compatible storage for s3:// (HTTPS) and s3+http:// URLs (see newS3Storage).
+func NewFileStorageByURL(storageURL string) (FileStorager, error) {
+	parsed, err := url.Parse(storageURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadStorageURL, err)
+	}
+
+	switch parsed.Scheme {
+	case "":
+		return newFileStorage(storageURL), nil
+	case "file":
+		return newFileStorage(parsed.Path), nil
+	case "s3", "s3+http":
+		storage, err := newS3Storage(parsed)
+		if err != nil {
+			return nil, err
+		}
+
+		return storage, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown scheme %s", ErrBadStorageURL, parsed.Scheme)
+	}
+}
+
+// RecordStorager interface for storage, which can storage only text data.
+type RecordStorager interface {
+	CreateUser(credentials entity.UserCredentials) error
+	GetUser(login string) (entity.User, error)
+	UpdatePasswordHash(userID entity.UserID, passwordHash string) error
+	GetKDFParams(userID entity.UserID) (entity.KDFParams, error)
+	SetKDFParams(ctx context.Context, params entity.KDFParams) error
+	FinishKDFUpgrade(ctx context.Context, params entity.KDFParams) error
+	CreateSession(userID entity.UserID, tokenHash string, expiresAt time.Time) error
+	RotateSession(tokenHash, newTokenHash string, expiresAt time.Time) (entity.UserID, error)
+	DeleteSession(tokenHash string) error
+	DeleteSessions(userID entity.UserID) error
+	GetRecordsInfo(ctx context.Context) ([]entity.Record, error)
+	ListRecords(ctx context.Context, query entity.RecordsQuery) (entity.RecordsPage, error)
+	GetRecord(ctx context.Context, recordID string) (entity.Record, error)
+	CreateRecord(ctx context.Context, record entity.Record) (string, error)
+	UpdateRecord(ctx context.Context, record entity.Record) error
+	DeleteRecord(ctx context.Context, recordID string, revision int64) error
+	GetRecordVersions(ctx context.Context, recordID string) ([]entity.RecordVersion, error)
+	RestoreRecordVersion(ctx context.Context, recordID string, version int32, revision int64) error
+	Sync(ctx context.Context, sinceRevision int64) (entity.RecordChanges, error)
+	VaultStorager
+}
+
+// VaultStorager interface for storage of shared vaults and their records.
+type VaultStorager interface {
+	GetPublicKey(ctx context.Context, login string) ([]byte, error)
+	SetPublicKey(ctx context.Context, publicKey []byte) error
+	CreateVault(ctx context.Context, vault entity.Vault) (string, error)
+	GetVaults(ctx context.Context) ([]entity.Vault, error)
+	GetVaultMembers(ctx context.Context, vaultID string) ([]entity.VaultMember, error)
+	AddVaultMember(ctx context.Context, member entity.VaultMember) error
+	RevokeVaultMember(ctx context.Context, rotation entity.VaultRotation) error
+	GetVaultRecords(ctx context.Context, vaultID string) ([]entity.Record, error)
+	GetVaultRecord(ctx context.Context, vaultID, recordID string) (entity.Record, error)
+	CreateVaultRecord(ctx context.Context, record entity.Record) (string, error)
+	UpdateVaultRecord(ctx context.Context, record entity.Record) error
+	DeleteVaultRecord(ctx context.Context, vaultID, recordID string) error
+}
+
+// Storager interface for storage, which can storage text data and stream files by chunks.
 //
 //go:generate mockery --name Storager
 type Storager interface {
-	CreateUser(credentials entity.UserCredentials) error
-	LoginUser(credentials entity.UserCredentials) (entity.UserID, error)
-	GetRecordsInfo(ctx context.Context) ([]entity.Record, error)
-	FileStorager
+	RecordStorager
+	CreateFileRecord(ctx context.Context, record entity.Record, r io.Reader) (string, error)
+	GetFileRecord(ctx context.Context, recordID string) (entity.Record, io.ReadCloser, error)
 }
@@ -4,8 +4,10 @@ package mocks
 
 import (
 	context "context"
+	io "io"
 
 	entity "github.com/bbt-t/lets-go-keep/internal/entity"
+
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -76,6 +78,46 @@ func (_m *FileStorager) GetRecord(ctx context.Context, recordID string) (entity.
 	return r0, r1
 }
 
+// OpenRecord provides a mock function with given fields: ctx, recordID
+func (_m *FileStorager) OpenRecord(ctx context.Context, recordID string) (io.ReadCloser, error) {
+	ret := _m.Called(ctx, recordID)
+
+	var r0 io.ReadCloser
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (io.ReadCloser, error)); ok {
+		return rf(ctx, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) io.ReadCloser); ok {
+		r0 = rf(ctx, recordID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(io.ReadCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WriteRecord provides a mock function with given fields: ctx, recordID, r
+func (_m *FileStorager) WriteRecord(ctx context.Context, recordID string, r io.Reader) error {
+	ret := _m.Called(ctx, recordID, r)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, io.Reader) error); ok {
+		r0 = rf(ctx, recordID, r)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewFileStorager interface {
 	mock.TestingT
 	Cleanup(func())
@@ -4,9 +4,13 @@ package mocks
 
 import (
 	context "context"
+	io "io"
 
 	entity "github.com/bbt-t/lets-go-keep/internal/entity"
+
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Storager is an autogenerated mock type for the Storager type
@@ -14,6 +18,44 @@ type Storager struct {
 	mock.Mock
 }
 
+// AddVaultMember provides a mock function with given fields: ctx, member
+func (_m *Storager) AddVaultMember(ctx context.Context, member entity.VaultMember) error {
+	ret := _m.Called(ctx, member)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.VaultMember) error); ok {
+		r0 = rf(ctx, member)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CreateFileRecord provides a mock function with given fields: ctx, record, r
+func (_m *Storager) CreateFileRecord(ctx context.Context, record entity.Record, r io.Reader) (string, error) {
+	ret := _m.Called(ctx, record, r)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record, io.Reader) (string, error)); ok {
+		return rf(ctx, record, r)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record, io.Reader) string); ok {
+		r0 = rf(ctx, record, r)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Record, io.Reader) error); ok {
+		r1 = rf(ctx, record, r)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateRecord provides a mock function with given fields: ctx, record
 func (_m *Storager) CreateRecord(ctx context.Context, record entity.Record) (string, error) {
 	ret := _m.Called(ctx, record)
@@ -38,6 +80,20 @@ func (_m *Storager) CreateRecord(ctx context.Context, record entity.Record) (str
 	return r0, r1
 }
 
+// CreateSession provides a mock function with given fields: userID, tokenHash, expiresAt
+func (_m *Storager) CreateSession(userID entity.UserID, tokenHash string, expiresAt time.Time) error {
+	ret := _m.Called(userID, tokenHash, expiresAt)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.UserID, string, time.Time) error); ok {
+		r0 = rf(userID, tokenHash, expiresAt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // CreateUser provides a mock function with given fields: credentials
 func (_m *Storager) CreateUser(credentials entity.UserCredentials) error {
 	ret := _m.Called(credentials)
@@ -52,13 +108,75 @@ func (_m *Storager) CreateUser(credentials entity.UserCredentials) error {
 	return r0
 }
 
-// DeleteRecord provides a mock function with given fields: ctx, recordID
-func (_m *Storager) DeleteRecord(ctx context.Context, recordID string) error {
-	ret := _m.Called(ctx, recordID)
+// CreateVault provides a mock function with given fields: ctx, vault
+func (_m *Storager) CreateVault(ctx context.Context, vault entity.Vault) (string, error) {
+	ret := _m.Called(ctx, vault)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Vault) (string, error)); ok {
+		return rf(ctx, vault)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Vault) string); ok {
+		r0 = rf(ctx, vault)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Vault) error); ok {
+		r1 = rf(ctx, vault)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateVaultRecord provides a mock function with given fields: ctx, record
+func (_m *Storager) CreateVaultRecord(ctx context.Context, record entity.Record) (string, error) {
+	ret := _m.Called(ctx, record)
+
+	var r0 string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) (string, error)); ok {
+		return rf(ctx, record)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) string); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.Record) error); ok {
+		r1 = rf(ctx, record)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteRecord provides a mock function with given fields: ctx, recordID, revision
+func (_m *Storager) DeleteRecord(ctx context.Context, recordID string, revision int64) error {
+	ret := _m.Called(ctx, recordID, revision)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
-		r0 = rf(ctx, recordID)
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(ctx, recordID, revision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteSession provides a mock function with given fields: tokenHash
+func (_m *Storager) DeleteSession(tokenHash string) error {
+	ret := _m.Called(tokenHash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(tokenHash)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -66,6 +184,131 @@ func (_m *Storager) DeleteRecord(ctx context.Context, recordID string) error {
 	return r0
 }
 
+// DeleteSessions provides a mock function with given fields: userID
+func (_m *Storager) DeleteSessions(userID entity.UserID) error {
+	ret := _m.Called(userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.UserID) error); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteVaultRecord provides a mock function with given fields: ctx, vaultID, recordID
+func (_m *Storager) DeleteVaultRecord(ctx context.Context, vaultID string, recordID string) error {
+	ret := _m.Called(ctx, vaultID, recordID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(ctx, vaultID, recordID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FinishKDFUpgrade provides a mock function with given fields: ctx, params
+func (_m *Storager) FinishKDFUpgrade(ctx context.Context, params entity.KDFParams) error {
+	ret := _m.Called(ctx, params)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.KDFParams) error); ok {
+		r0 = rf(ctx, params)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetFileRecord provides a mock function with given fields: ctx, recordID
+func (_m *Storager) GetFileRecord(ctx context.Context, recordID string) (entity.Record, io.ReadCloser, error) {
+	ret := _m.Called(ctx, recordID)
+
+	var r0 entity.Record
+	var r1 io.ReadCloser
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (entity.Record, io.ReadCloser, error)); ok {
+		return rf(ctx, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) entity.Record); ok {
+		r0 = rf(ctx, recordID)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) io.ReadCloser); ok {
+		r1 = rf(ctx, recordID)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(io.ReadCloser)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, string) error); ok {
+		r2 = rf(ctx, recordID)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetKDFParams provides a mock function with given fields: userID
+func (_m *Storager) GetKDFParams(userID entity.UserID) (entity.KDFParams, error) {
+	ret := _m.Called(userID)
+
+	var r0 entity.KDFParams
+	var r1 error
+	if rf, ok := ret.Get(0).(func(entity.UserID) (entity.KDFParams, error)); ok {
+		return rf(userID)
+	}
+	if rf, ok := ret.Get(0).(func(entity.UserID) entity.KDFParams); ok {
+		r0 = rf(userID)
+	} else {
+		r0 = ret.Get(0).(entity.KDFParams)
+	}
+
+	if rf, ok := ret.Get(1).(func(entity.UserID) error); ok {
+		r1 = rf(userID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPublicKey provides a mock function with given fields: ctx, login
+func (_m *Storager) GetPublicKey(ctx context.Context, login string) ([]byte, error) {
+	ret := _m.Called(ctx, login)
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]byte, error)); ok {
+		return rf(ctx, login)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, login)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRecord provides a mock function with given fields: ctx, recordID
 func (_m *Storager) GetRecord(ctx context.Context, recordID string) (entity.Record, error) {
 	ret := _m.Called(ctx, recordID)
@@ -90,6 +333,32 @@ func (_m *Storager) GetRecord(ctx context.Context, recordID string) (entity.Reco
 	return r0, r1
 }
 
+// GetRecordVersions provides a mock function with given fields: ctx, recordID
+func (_m *Storager) GetRecordVersions(ctx context.Context, recordID string) ([]entity.RecordVersion, error) {
+	ret := _m.Called(ctx, recordID)
+
+	var r0 []entity.RecordVersion
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]entity.RecordVersion, error)); ok {
+		return rf(ctx, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []entity.RecordVersion); ok {
+		r0 = rf(ctx, recordID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.RecordVersion)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRecordsInfo provides a mock function with given fields: ctx
 func (_m *Storager) GetRecordsInfo(ctx context.Context) ([]entity.Record, error) {
 	ret := _m.Called(ctx)
@@ -116,23 +385,201 @@ func (_m *Storager) GetRecordsInfo(ctx context.Context) ([]entity.Record, error)
 	return r0, r1
 }
 
-// LoginUser provides a mock function with given fields: credentials
-func (_m *Storager) LoginUser(credentials entity.UserCredentials) (entity.UserID, error) {
-	ret := _m.Called(credentials)
+// GetUser provides a mock function with given fields: login
+func (_m *Storager) GetUser(login string) (entity.User, error) {
+	ret := _m.Called(login)
+
+	var r0 entity.User
+	var r1 error
+	if rf, ok := ret.Get(0).(func(string) (entity.User, error)); ok {
+		return rf(login)
+	}
+	if rf, ok := ret.Get(0).(func(string) entity.User); ok {
+		r0 = rf(login)
+	} else {
+		r0 = ret.Get(0).(entity.User)
+	}
+
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(login)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultMembers provides a mock function with given fields: ctx, vaultID
+func (_m *Storager) GetVaultMembers(ctx context.Context, vaultID string) ([]entity.VaultMember, error) {
+	ret := _m.Called(ctx, vaultID)
+
+	var r0 []entity.VaultMember
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]entity.VaultMember, error)); ok {
+		return rf(ctx, vaultID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []entity.VaultMember); ok {
+		r0 = rf(ctx, vaultID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.VaultMember)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, vaultID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultRecord provides a mock function with given fields: ctx, vaultID, recordID
+func (_m *Storager) GetVaultRecord(ctx context.Context, vaultID string, recordID string) (entity.Record, error) {
+	ret := _m.Called(ctx, vaultID, recordID)
+
+	var r0 entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) (entity.Record, error)); ok {
+		return rf(ctx, vaultID, recordID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) entity.Record); ok {
+		r0 = rf(ctx, vaultID, recordID)
+	} else {
+		r0 = ret.Get(0).(entity.Record)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(ctx, vaultID, recordID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaultRecords provides a mock function with given fields: ctx, vaultID
+func (_m *Storager) GetVaultRecords(ctx context.Context, vaultID string) ([]entity.Record, error) {
+	ret := _m.Called(ctx, vaultID)
+
+	var r0 []entity.Record
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]entity.Record, error)); ok {
+		return rf(ctx, vaultID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []entity.Record); ok {
+		r0 = rf(ctx, vaultID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Record)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, vaultID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetVaults provides a mock function with given fields: ctx
+func (_m *Storager) GetVaults(ctx context.Context) ([]entity.Vault, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []entity.Vault
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]entity.Vault, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []entity.Vault); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]entity.Vault)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListRecords provides a mock function with given fields: ctx, query
+func (_m *Storager) ListRecords(ctx context.Context, query entity.RecordsQuery) (entity.RecordsPage, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 entity.RecordsPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.RecordsQuery) (entity.RecordsPage, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, entity.RecordsQuery) entity.RecordsPage); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Get(0).(entity.RecordsPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, entity.RecordsQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RestoreRecordVersion provides a mock function with given fields: ctx, recordID, version, revision
+func (_m *Storager) RestoreRecordVersion(ctx context.Context, recordID string, version int32, revision int64) error {
+	ret := _m.Called(ctx, recordID, version, revision)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int32, int64) error); ok {
+		r0 = rf(ctx, recordID, version, revision)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RevokeVaultMember provides a mock function with given fields: ctx, rotation
+func (_m *Storager) RevokeVaultMember(ctx context.Context, rotation entity.VaultRotation) error {
+	ret := _m.Called(ctx, rotation)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.VaultRotation) error); ok {
+		r0 = rf(ctx, rotation)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RotateSession provides a mock function with given fields: tokenHash, newTokenHash, expiresAt
+func (_m *Storager) RotateSession(tokenHash string, newTokenHash string, expiresAt time.Time) (entity.UserID, error) {
+	ret := _m.Called(tokenHash, newTokenHash, expiresAt)
 
 	var r0 entity.UserID
 	var r1 error
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) (entity.UserID, error)); ok {
-		return rf(credentials)
+	if rf, ok := ret.Get(0).(func(string, string, time.Time) (entity.UserID, error)); ok {
+		return rf(tokenHash, newTokenHash, expiresAt)
 	}
-	if rf, ok := ret.Get(0).(func(entity.UserCredentials) entity.UserID); ok {
-		r0 = rf(credentials)
+	if rf, ok := ret.Get(0).(func(string, string, time.Time) entity.UserID); ok {
+		r0 = rf(tokenHash, newTokenHash, expiresAt)
 	} else {
 		r0 = ret.Get(0).(entity.UserID)
 	}
 
-	if rf, ok := ret.Get(1).(func(entity.UserCredentials) error); ok {
-		r1 = rf(credentials)
+	if rf, ok := ret.Get(1).(func(string, string, time.Time) error); ok {
+		r1 = rf(tokenHash, newTokenHash, expiresAt)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -140,6 +587,100 @@ func (_m *Storager) LoginUser(credentials entity.UserCredentials) (entity.UserID
 	return r0, r1
 }
 
+// SetKDFParams provides a mock function with given fields: ctx, params
+func (_m *Storager) SetKDFParams(ctx context.Context, params entity.KDFParams) error {
+	ret := _m.Called(ctx, params)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.KDFParams) error); ok {
+		r0 = rf(ctx, params)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetPublicKey provides a mock function with given fields: ctx, publicKey
+func (_m *Storager) SetPublicKey(ctx context.Context, publicKey []byte) error {
+	ret := _m.Called(ctx, publicKey)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []byte) error); ok {
+		r0 = rf(ctx, publicKey)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Sync provides a mock function with given fields: ctx, sinceRevision
+func (_m *Storager) Sync(ctx context.Context, sinceRevision int64) (entity.RecordChanges, error) {
+	ret := _m.Called(ctx, sinceRevision)
+
+	var r0 entity.RecordChanges
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64) (entity.RecordChanges, error)); ok {
+		return rf(ctx, sinceRevision)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int64) entity.RecordChanges); ok {
+		r0 = rf(ctx, sinceRevision)
+	} else {
+		r0 = ret.Get(0).(entity.RecordChanges)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int64) error); ok {
+		r1 = rf(ctx, sinceRevision)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdatePasswordHash provides a mock function with given fields: userID, passwordHash
+func (_m *Storager) UpdatePasswordHash(userID entity.UserID, passwordHash string) error {
+	ret := _m.Called(userID, passwordHash)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(entity.UserID, string) error); ok {
+		r0 = rf(userID, passwordHash)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateRecord provides a mock function with given fields: ctx, record
+func (_m *Storager) UpdateRecord(ctx context.Context, record entity.Record) error {
+	ret := _m.Called(ctx, record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) error); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateVaultRecord provides a mock function with given fields: ctx, record
+func (_m *Storager) UpdateVaultRecord(ctx context.Context, record entity.Record) error {
+	ret := _m.Called(ctx, record)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, entity.Record) error); ok {
+		r0 = rf(ctx, record)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 type mockConstructorTestingTNewStorager interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,433 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Settings of S3 storage.
+const (
+	s3DefaultRegion   = "us-east-1"
+	s3DefaultPartSize = 16 << 20
+	// s3MinPartSize is the least size of part of multipart upload, except the last part, which S3 accepts.
+	s3MinPartSize = 5 << 20
+	s3MaxParts    = 10000
+
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3TimeFormat      = "20060102T150405Z"
+	s3DateFormat      = "20060102"
+	s3EmptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3ContentHash     = "X-Amz-Content-Sha256"
+	s3Date            = "X-Amz-Date"
+	s3MultipartUpload = "uploads"
+)
+
+// Timeouts of connections to S3. Whole request isn't limited, because big parts are uploaded slowly,
+// but stalled endpoint fails request even without deadline of context.
+const (
+	s3DialTimeout           = 10 * time.Second
+	s3TLSHandshakeTimeout   = 10 * time.Second
+	s3ResponseHeaderTimeout = 60 * time.Second
+	s3IdleConnTimeout       = 90 * time.Second
+)
+
+// s3Storage keeps files of records as objects of bucket of S3-compatible storage (AWS S3, MinIO, etc.),
+// so several replicas of server share them. Objects are addressed by path: endpoint/bucket/prefix/ID.
+// Files, which are bigger than part size, are uploaded by parts.
+type s3Storage struct {
+	client    *http.Client
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	region    string
+	accessKey string
+	secretKey string
+	partSize  int64
+	now       func() time.Time
+}
+
+// newS3Storage returns storage by URL: s3://ACCESS_KEY:SECRET_KEY@host[:port]/bucket[/prefix]?region=R&part_size=N.
+// Scheme s3 uses HTTPS, scheme s3+http uses HTTP (e.g. for local MinIO). If keys aren't in URL,
+// they are got from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+func newS3Storage(storageURL *url.URL) (*s3Storage, error) {
+	storage := &s3Storage{
+		client:    newS3Client(),
+		endpoint:  &url.URL{Scheme: "https", Host: storageURL.Host},
+		region:    s3DefaultRegion,
+		accessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		partSize:  s3DefaultPartSize,
+		now:       time.Now,
+	}
+
+	if storageURL.Scheme == "s3+http" {
+		storage.endpoint.Scheme = "http"
+	}
+
+	if storageURL.User != nil {
+		storage.accessKey = storageURL.User.Username()
+		storage.secretKey, _ = storageURL.User.Password()
+	}
+
+	bucket, prefix, _ := strings.Cut(strings.Trim(storageURL.Path, "/"), "/")
+	if storageURL.Host == "" || bucket == "" {
+		return nil, fmt.Errorf("%w: host and bucket are required in %s", ErrBadStorageURL, storageURL.Redacted())
+	}
+	storage.bucket, storage.prefix = bucket, prefix
+
+	query := storageURL.Query()
+	if region := query.Get("region"); region != "" {
+		storage.region = region
+	}
+
+	if partSize := query.Get("part_size"); partSize != "" {
+		size, err := strconv.ParseInt(partSize, 10, 64)
+		if err != nil || size < s3MinPartSize {
+			return nil, fmt.Errorf("%w: part size must be at least %d bytes", ErrBadStorageURL, s3MinPartSize)
+		}
+		storage.partSize = size
+	}
+
+	return storage, nil
+}
+
+// newS3Client returns HTTP client with timeouts of connecting and waiting for response.
+func newS3Client() *http.Client {
+	dialer := &net.Dialer{Timeout: s3DialTimeout, KeepAlive: 30 * time.Second}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   s3TLSHandshakeTimeout,
+			ResponseHeaderTimeout: s3ResponseHeaderTimeout,
+			ExpectContinueTimeout: time.Second,
+			IdleConnTimeout:       s3IdleConnTimeout,
+			MaxIdleConnsPerHost:   16,
+		},
+	}
+}
+
+// GetRecord reads object with record data.
+func (storage *s3Storage) GetRecord(ctx context.Context, recordID string) (entity.Record, error) {
+	return getFileRecord(ctx, storage, recordID)
+}
+
+// OpenRecord opens object with record data for reading by chunks. Caller must close it.
+func (storage *s3Storage) OpenRecord(ctx context.Context, recordID string) (io.ReadCloser, error) {
+	resp, err := storage.do(ctx, http.MethodGet, recordID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+// DeleteRecord deletes object with record data. S3 deletes missing objects without errors,
+// so object is checked before.
+func (storage *s3Storage) DeleteRecord(ctx context.Context, recordID string) error {
+	resp, err := storage.do(ctx, http.MethodHead, recordID, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	resp, err = storage.do(ctx, http.MethodDelete, recordID, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// CreateRecord creates new object with record data.
+func (storage *s3Storage) CreateRecord(ctx context.Context, record entity.Record) (string, error) {
+	if err := storage.WriteRecord(ctx, record.ID, bytes.NewReader(record.Data)); err != nil {
+		return "", err
+	}
+
+	return record.ID, nil
+}
+
+// WriteRecord uploads record data from reader. Data, which fits into one part, is uploaded
+// by one request, bigger data is uploaded by parts, so only one part is kept in memory.
+// Object appears in bucket only, when all data is uploaded.
+func (storage *s3Storage) WriteRecord(ctx context.Context, recordID string, r io.Reader) error {
+	part, err := readPart(r, storage.partSize)
+	if err != nil {
+		return err
+	}
+
+	if int64(len(part)) < storage.partSize {
+		resp, err := storage.do(ctx, http.MethodPut, recordID, nil, part)
+		if err != nil {
+			return err
+		}
+
+		return resp.Body.Close()
+	}
+
+	return storage.writeMultipart(ctx, recordID, part, r)
+}
+
+// s3Upload is started multipart upload.
+type s3Upload struct {
+	UploadID string `xml:"UploadId"`
+}
+
+// s3CompletedPart is uploaded part of multipart upload.
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// s3CompleteUpload is list of uploaded parts, which make object.
+type s3CompleteUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+// s3Error is error of S3 API.
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// writeMultipart uploads data by parts, first part is already read. Upload is aborted, if it fails,
+// so storage doesn't keep parts.
+func (storage *s3Storage) writeMultipart(ctx context.Context, recordID string, part []byte, r io.Reader) error {
+	resp, err := storage.do(ctx, http.MethodPost, recordID, url.Values{s3MultipartUpload: {""}}, nil)
+	if err != nil {
+		return err
+	}
+
+	var upload s3Upload
+	err = xml.NewDecoder(resp.Body).Decode(&upload)
+	resp.Body.Close()
+	if err != nil || upload.UploadID == "" {
+		log.Infoln("start multipart upload:", err)
+
+		return ErrUnknown
+	}
+
+	var complete s3CompleteUpload
+	for number := 1; len(part) > 0; number++ {
+		if number > s3MaxParts {
+			log.Infoln("too many parts of multipart upload of", recordID)
+			err = ErrUnknown
+			break
+		}
+
+		resp, err = storage.do(ctx, http.MethodPut, recordID, url.Values{
+			"partNumber": {strconv.Itoa(number)},
+			"uploadId":   {upload.UploadID},
+		}, part)
+		if err != nil {
+			break
+		}
+		resp.Body.Close()
+
+		complete.Parts = append(complete.Parts, s3CompletedPart{PartNumber: number, ETag: resp.Header.Get("ETag")})
+
+		if int64(len(part)) < storage.partSize {
+			break
+		}
+		if part, err = readPart(r, storage.partSize); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = storage.completeMultipart(ctx, recordID, upload.UploadID, complete)
+	}
+
+	if err != nil {
+		resp, errAbort := storage.do(ctx, http.MethodDelete, recordID, url.Values{"uploadId": {upload.UploadID}}, nil)
+		if errAbort == nil {
+			errAbort = resp.Body.Close()
+		}
+		if errAbort != nil {
+			log.Warnf("%s :: %v", "abort multipart upload fault", errAbort)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// completeMultipart makes object of uploaded parts. S3 can report error of completion with status 200,
+// so body is checked.
+func (storage *s3Storage) completeMultipart(ctx context.Context, recordID, uploadID string, complete s3CompleteUpload) error {
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	resp, err := storage.do(ctx, http.MethodPost, recordID, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	result, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Infoln(err)
+
+		return ErrUnknown
+	}
+
+	var failure s3Error
+	if xml.Unmarshal(result, &failure) == nil {
+		log.Infoln("complete multipart upload:", failure.Code, failure.Message)
+
+		return ErrUnknown
+	}
+
+	return nil
+}
+
+// readPart reads part of data, it's shorter than size only at the end of data.
+func readPart(r io.Reader, size int64) ([]byte, error) {
+	part, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	return part, nil
+}
+
+// do sends signed request about object of record and checks status of response. Missing object is ErrNotFound.
+// Caller must close body of response.
+func (storage *s3Storage) do(ctx context.Context, method, recordID string, query url.Values, body []byte) (*http.Response, error) {
+	key := recordID
+	if storage.prefix != "" {
+		key = storage.prefix + "/" + recordID
+	}
+
+	target := *storage.endpoint
+	target.Path = "/" + storage.bucket + "/" + key
+	target.RawQuery = s3Query(query)
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+	if body == nil {
+		req.Body, req.ContentLength = http.NoBody, 0
+	}
+
+	storage.sign(req, body)
+
+	resp, err := storage.client.Do(req)
+	if err != nil {
+		log.Infoln(err)
+
+		return nil, ErrUnknown
+	}
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	var failure s3Error
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&failure)
+	log.Infoln("S3", method, key, resp.Status, failure.Code, failure.Message)
+
+	return nil, ErrUnknown
+}
+
+// sign signs request by AWS Signature Version 4. Host and all headers of request are signed.
+func (storage *s3Storage) sign(req *http.Request, body []byte) {
+	now := storage.now().UTC()
+
+	payload := s3EmptyPayload
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payload = hex.EncodeToString(sum[:])
+	}
+
+	req.Header.Set(s3ContentHash, payload)
+	req.Header.Set(s3Date, now.Format(s3TimeFormat))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payload,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(s3DateFormat), storage.region, "s3", "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, now.Format(s3TimeFormat), scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + storage.secretKey)
+	for _, part := range []string{now.Format(s3DateFormat), storage.region, "s3", "aws4_request"} {
+		key = s3HMAC(key, part)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, storage.accessKey, scope, signedHeaders, hex.EncodeToString(s3HMAC(key, stringToSign))))
+}
+
+// s3HMAC makes HMAC-SHA256 of data.
+func s3HMAC(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+// s3Query encodes query in canonical form of signature: sorted by keys, spaces are %20.
+func s3Query(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
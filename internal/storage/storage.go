@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/entity"
 
@@ -11,12 +14,12 @@ import (
 
 // Storage struct which saves to DB and file storage.
 type Storage struct {
-	DBStorage   Storager
+	DBStorage   RecordStorager
 	FileStorage FileStorager
 }
 
 // NewStorage returns new storage.
-func NewStorage(DBStorage Storager, fileStorage FileStorager) *Storage {
+func NewStorage(DBStorage RecordStorager, fileStorage FileStorager) *Storage {
 	return &Storage{
 		DBStorage:   DBStorage,
 		FileStorage: fileStorage,
@@ -28,9 +31,49 @@ func (s *Storage) CreateUser(credentials entity.UserCredentials) error {
 	return s.DBStorage.CreateUser(credentials)
 }
 
-// LoginUser check user login using DB storage.
-func (s *Storage) LoginUser(credentials entity.UserCredentials) (entity.UserID, error) {
-	return s.DBStorage.LoginUser(credentials)
+// GetUser gets user by login from DB storage.
+func (s *Storage) GetUser(login string) (entity.User, error) {
+	return s.DBStorage.GetUser(login)
+}
+
+// UpdatePasswordHash replaces password hash of user in DB storage.
+func (s *Storage) UpdatePasswordHash(userID entity.UserID, passwordHash string) error {
+	return s.DBStorage.UpdatePasswordHash(userID, passwordHash)
+}
+
+// GetKDFParams gets key derivation parameters of user from DB storage.
+func (s *Storage) GetKDFParams(userID entity.UserID) (entity.KDFParams, error) {
+	return s.DBStorage.GetKDFParams(userID)
+}
+
+// SetKDFParams saves key derivation parameters of legacy user to DB storage.
+func (s *Storage) SetKDFParams(ctx context.Context, params entity.KDFParams) error {
+	return s.DBStorage.SetKDFParams(ctx, params)
+}
+
+// FinishKDFUpgrade clears legacy mark of user in DB storage.
+func (s *Storage) FinishKDFUpgrade(ctx context.Context, params entity.KDFParams) error {
+	return s.DBStorage.FinishKDFUpgrade(ctx, params)
+}
+
+// CreateSession saves new session of user to DB storage.
+func (s *Storage) CreateSession(userID entity.UserID, tokenHash string, expiresAt time.Time) error {
+	return s.DBStorage.CreateSession(userID, tokenHash, expiresAt)
+}
+
+// RotateSession replaces refresh token hash of session in DB storage.
+func (s *Storage) RotateSession(tokenHash, newTokenHash string, expiresAt time.Time) (entity.UserID, error) {
+	return s.DBStorage.RotateSession(tokenHash, newTokenHash, expiresAt)
+}
+
+// DeleteSession deletes session by refresh token hash from DB storage.
+func (s *Storage) DeleteSession(tokenHash string) error {
+	return s.DBStorage.DeleteSession(tokenHash)
+}
+
+// DeleteSessions deletes all sessions of user from DB storage.
+func (s *Storage) DeleteSessions(userID entity.UserID) error {
+	return s.DBStorage.DeleteSessions(userID)
 }
 
 // GetRecordsInfo gets all records from user from DB storage.
@@ -38,14 +81,20 @@ func (s *Storage) GetRecordsInfo(ctx context.Context) ([]entity.Record, error) {
 	return s.DBStorage.GetRecordsInfo(ctx)
 }
 
-// CreateRecord creates record, saves to DB. If record type is file, saves to file storage too.
-func (s *Storage) CreateRecord(ctx context.Context, record entity.Record) (string, error) {
-	data := record.Data
+// ListRecords gets page of user records, which match query, from DB storage.
+func (s *Storage) ListRecords(ctx context.Context, query entity.RecordsQuery) (entity.RecordsPage, error) {
+	return s.DBStorage.ListRecords(ctx, query)
+}
 
+// CreateRecord creates record, saves to DB. If record type is file, its data is saved to file storage
+// before record is saved to DB, so DB never has file record without file.
+func (s *Storage) CreateRecord(ctx context.Context, record entity.Record) (string, error) {
 	if record.Type == entity.TypeFile {
-		record.Data = nil
+		return s.CreateFileRecord(ctx, record, bytes.NewReader(record.Data))
 	}
 
+	record.ID = ""
+
 	id, err := s.DBStorage.CreateRecord(ctx, record)
 	if err != nil {
 		log.Infoln(err)
@@ -53,19 +102,38 @@ func (s *Storage) CreateRecord(ctx context.Context, record entity.Record) (strin
 		return "", err
 	}
 
-	if record.Type == entity.TypeFile {
-		record.ID = id
-		record.Data = data
-		_, err = s.FileStorage.CreateRecord(ctx, record)
-		return "", err
+	return id, nil
+}
+
+// UpdateRecord updates record in DB storage. Only labels of file records can be updated,
+// new file must be uploaded instead.
+func (s *Storage) UpdateRecord(ctx context.Context, record entity.Record) error {
+	if record.Type == entity.TypeFile && len(record.Data) != 0 {
+		return ErrNotSupported
 	}
 
-	return id, nil
+	return s.DBStorage.UpdateRecord(ctx, record)
+}
+
+// GetRecordVersions gets previous versions of record from DB storage.
+func (s *Storage) GetRecordVersions(ctx context.Context, recordID string) ([]entity.RecordVersion, error) {
+	return s.DBStorage.GetRecordVersions(ctx, recordID)
 }
 
-// DeleteRecord deletes record from DB storage. If record type is file, deletes from file storage too.
-func (s *Storage) DeleteRecord(ctx context.Context, recordID string) error {
-	err := s.DBStorage.DeleteRecord(ctx, recordID)
+// RestoreRecordVersion restores previous version of record in DB storage, if record wasn't changed after revision.
+func (s *Storage) RestoreRecordVersion(ctx context.Context, recordID string, version int32, revision int64) error {
+	return s.DBStorage.RestoreRecordVersion(ctx, recordID, version, revision)
+}
+
+// Sync gets records changed and deleted after revision from DB storage.
+func (s *Storage) Sync(ctx context.Context, sinceRevision int64) (entity.RecordChanges, error) {
+	return s.DBStorage.Sync(ctx, sinceRevision)
+}
+
+// DeleteRecord deletes record from DB storage, if it wasn't changed after revision.
+// If record type is file, deletes from file storage too.
+func (s *Storage) DeleteRecord(ctx context.Context, recordID string, revision int64) error {
+	err := s.DBStorage.DeleteRecord(ctx, recordID, revision)
 	if err != nil {
 		log.Infoln(err)
 
@@ -80,7 +148,7 @@ func (s *Storage) DeleteRecord(ctx context.Context, recordID string) error {
 	return nil
 }
 
-// GetRecord gets record from DB or file storage.
+// GetRecord gets record from DB storage. Data of file records isn't read, it must be got by GetFileRecord.
 func (s *Storage) GetRecord(ctx context.Context, recordID string) (entity.Record, error) {
 	record, err := s.DBStorage.GetRecord(ctx, recordID)
 	if err != nil {
@@ -89,10 +157,138 @@ func (s *Storage) GetRecord(ctx context.Context, recordID string) (entity.Record
 		return record, err
 	}
 
+	return record, nil
+}
+
+// CreateFileRecord writes data from reader to file storage chunk by chunk, then creates record in DB storage
+// with the same ID. So snapshot of DB (e.g. backup) doesn't have records, which files aren't written yet.
+// File is deleted, if record isn't created.
+func (s *Storage) CreateFileRecord(ctx context.Context, record entity.Record, r io.Reader) (string, error) {
+	id, err := newFileRecordID()
+	if err != nil {
+		log.Warnf("%s :: %v", "generate file record ID fault", err)
+
+		return "", ErrUnknown
+	}
+
+	if err = s.FileStorage.WriteRecord(ctx, id, r); err != nil {
+		log.Warnf("%s :: %v", "write file record fault", err)
+
+		return "", err
+	}
+
+	record.ID, record.Type, record.Data = id, entity.TypeFile, nil
+
+	if id, err = s.DBStorage.CreateRecord(ctx, record); err != nil {
+		log.Infoln(err)
+
+		if errDelete := s.FileStorage.DeleteRecord(ctx, record.ID); errDelete != nil {
+			log.Warnf("%s :: %v", "delete file of unfinished record fault", errDelete)
+		}
+
+		return "", err
+	}
+
+	return id, nil
+}
+
+// GetFileRecord gets record info from DB storage and opens reader with its data.
+// If record type isn't file, reader returns data stored in DB.
+func (s *Storage) GetFileRecord(ctx context.Context, recordID string) (entity.Record, io.ReadCloser, error) {
+	record, err := s.DBStorage.GetRecord(ctx, recordID)
+	if err != nil {
+		log.Infoln(err)
+
+		return record, nil, err
+	}
+
+	if record.Type != entity.TypeFile {
+		data := record.Data
+		record.Data = nil
+
+		return record, io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	file, err := s.FileStorage.OpenRecord(ctx, recordID)
+	if err != nil {
+		log.Infoln(err)
+
+		return entity.Record{}, nil, err
+	}
+
+	return record, file, nil
+}
+
+// GetPublicKey gets public key of user by login from DB storage.
+func (s *Storage) GetPublicKey(ctx context.Context, login string) ([]byte, error) {
+	return s.DBStorage.GetPublicKey(ctx, login)
+}
+
+// SetPublicKey sets public key of user in DB storage.
+func (s *Storage) SetPublicKey(ctx context.Context, publicKey []byte) error {
+	return s.DBStorage.SetPublicKey(ctx, publicKey)
+}
+
+// CreateVault creates shared vault in DB storage.
+func (s *Storage) CreateVault(ctx context.Context, vault entity.Vault) (string, error) {
+	return s.DBStorage.CreateVault(ctx, vault)
+}
+
+// GetVaults gets vaults of user from DB storage.
+func (s *Storage) GetVaults(ctx context.Context) ([]entity.Vault, error) {
+	return s.DBStorage.GetVaults(ctx)
+}
+
+// GetVaultMembers gets members of vault from DB storage.
+func (s *Storage) GetVaultMembers(ctx context.Context, vaultID string) ([]entity.VaultMember, error) {
+	return s.DBStorage.GetVaultMembers(ctx, vaultID)
+}
+
+// AddVaultMember adds member to vault in DB storage.
+func (s *Storage) AddVaultMember(ctx context.Context, member entity.VaultMember) error {
+	return s.DBStorage.AddVaultMember(ctx, member)
+}
+
+// RevokeVaultMember removes member from vault and replaces vault key in DB storage. Files can't be shared.
+func (s *Storage) RevokeVaultMember(ctx context.Context, rotation entity.VaultRotation) error {
+	for _, record := range rotation.Records {
+		if record.Type == entity.TypeFile {
+			return ErrNotSupported
+		}
+	}
+
+	return s.DBStorage.RevokeVaultMember(ctx, rotation)
+}
+
+// GetVaultRecords gets records of vault from DB storage.
+func (s *Storage) GetVaultRecords(ctx context.Context, vaultID string) ([]entity.Record, error) {
+	return s.DBStorage.GetVaultRecords(ctx, vaultID)
+}
+
+// GetVaultRecord gets record of vault with data from DB storage.
+func (s *Storage) GetVaultRecord(ctx context.Context, vaultID, recordID string) (entity.Record, error) {
+	return s.DBStorage.GetVaultRecord(ctx, vaultID, recordID)
+}
+
+// CreateVaultRecord creates record of vault in DB storage. Files can't be shared.
+func (s *Storage) CreateVaultRecord(ctx context.Context, record entity.Record) (string, error) {
 	if record.Type == entity.TypeFile {
-		ctx = context.WithValue(ctx, "recordMetadata", record.Metadata)
-		return s.FileStorage.GetRecord(ctx, recordID)
+		return "", ErrNotSupported
 	}
 
-	return record, nil
+	return s.DBStorage.CreateVaultRecord(ctx, record)
+}
+
+// UpdateVaultRecord updates record of vault in DB storage.
+func (s *Storage) UpdateVaultRecord(ctx context.Context, record entity.Record) error {
+	if record.Type == entity.TypeFile {
+		return ErrNotSupported
+	}
+
+	return s.DBStorage.UpdateVaultRecord(ctx, record)
+}
+
+// DeleteVaultRecord deletes record of vault from DB storage.
+func (s *Storage) DeleteVaultRecord(ctx context.Context, vaultID, recordID string) error {
+	return s.DBStorage.DeleteVaultRecord(ctx, vaultID, recordID)
 }
@@ -1,8 +1,12 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bbt-t/lets-go-keep/internal/entity"
 	"github.com/bbt-t/lets-go-keep/internal/storage/mocks"
@@ -78,38 +82,73 @@ func TestStorage_GetRecordsInfo(t *testing.T) {
 	}
 }
 
-func TestStorage_LoginUser(t *testing.T) {
+func TestStorage_GetUser(t *testing.T) {
 	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
 	storage := NewStorage(db, file)
 
-	tc := []struct {
-		name  string
-		mock  func()
-		valid func()
-	}{
-		{
-			"Login user",
-			func() {
-				db.On(
-					"LoginUser",
-					mock.AnythingOfType("entity.UserCredentials"),
-				).Return(entity.UserID(""), nil)
-			},
-			func() {
-				_, _ = storage.LoginUser(entity.UserCredentials{
-					Login:    "login",
-					Password: "password",
-				})
-				db.AssertExpectations(t)
-				file.AssertExpectations(t)
-			},
-		},
-	}
-	for _, test := range tc {
-		t.Log(test.name)
-		test.mock()
-		test.valid()
-	}
+	db.On("GetUser", "login").Return(entity.User{ID: "userID", Login: "login"}, nil).Once()
+
+	user, err := storage.GetUser("login")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.UserID("userID"), user.ID)
+	db.AssertExpectations(t)
+	file.AssertExpectations(t)
+}
+
+func TestStorage_UpdatePasswordHash(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+
+	db.On("UpdatePasswordHash", entity.UserID("userID"), "hash").Return(nil).Once()
+
+	assert.NoError(t, storage.UpdatePasswordHash("userID", "hash"))
+	db.AssertExpectations(t)
+	file.AssertExpectations(t)
+}
+
+func TestStorage_GetKDFParams(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+
+	db.On("GetKDFParams", entity.UserID("userID")).Return(entity.KDFParams{Time: 3}, nil).Once()
+
+	params, err := storage.GetKDFParams("userID")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.KDFParams{Time: 3}, params)
+	db.AssertExpectations(t)
+}
+
+func TestStorage_UpgradeKDF(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+
+	db.On("SetKDFParams", context.Background(), entity.KDFParams{Time: 3, Legacy: true}).Return(nil).Once()
+	db.On("FinishKDFUpgrade", context.Background(), entity.KDFParams{Time: 3}).Return(ErrConflict).Once()
+
+	assert.NoError(t, storage.SetKDFParams(context.Background(), entity.KDFParams{Time: 3, Legacy: true}))
+	assert.Equal(t, ErrConflict, storage.FinishKDFUpgrade(context.Background(), entity.KDFParams{Time: 3}))
+	db.AssertExpectations(t)
+}
+
+func TestStorage_Sessions(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+	expiresAt := time.Now().Add(time.Hour)
+
+	db.On("CreateSession", entity.UserID("userID"), "hash", expiresAt).Return(nil).Once()
+	db.On("RotateSession", "hash", "new hash", expiresAt).Return(entity.UserID("userID"), nil).Once()
+	db.On("DeleteSession", "new hash").Return(nil).Once()
+	db.On("DeleteSessions", entity.UserID("userID")).Return(nil).Once()
+
+	assert.NoError(t, storage.CreateSession("userID", "hash", expiresAt))
+
+	userID, err := storage.RotateSession("hash", "new hash", expiresAt)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.UserID("userID"), userID)
+
+	assert.NoError(t, storage.DeleteSession("new hash"))
+	assert.NoError(t, storage.DeleteSessions("userID"))
+	db.AssertExpectations(t)
 }
 
 func TestStorage_CreateRecord(t *testing.T) {
@@ -128,7 +167,7 @@ func TestStorage_CreateRecord(t *testing.T) {
 					"CreateRecord",
 					context.Background(),
 					mock.AnythingOfType("entity.Record"),
-				).Return("", nil)
+				).Return("", nil).Once()
 			},
 			func() {
 				_, _ = storage.CreateRecord(context.Background(), entity.Record{
@@ -144,24 +183,28 @@ func TestStorage_CreateRecord(t *testing.T) {
 		{
 			"Create file record",
 			func() {
-				db.On(
-					"CreateRecord",
-					context.Background(),
-					mock.AnythingOfType("entity.Record"),
-				).Return("", nil)
 				file.On(
+					"WriteRecord",
+					context.Background(),
+					mock.MatchedBy(fileRecordID.MatchString),
+					mock.AnythingOfType("*bytes.Reader"),
+				).Return(nil).Once()
+				db.On(
 					"CreateRecord",
 					context.Background(),
-					mock.AnythingOfType("entity.Record"),
-				).Return("", nil)
+					mock.MatchedBy(func(record entity.Record) bool {
+						return fileRecordID.MatchString(record.ID) && record.Type == entity.TypeFile && record.Data == nil
+					}),
+				).Return(func(_ context.Context, record entity.Record) string { return record.ID }, nil).Once()
 			},
 			func() {
-				_, _ = storage.CreateRecord(context.Background(), entity.Record{
-					ID:       "",
-					Metadata: "",
-					Type:     entity.TypeFile,
-					Data:     nil,
+				id, err := storage.CreateRecord(context.Background(), entity.Record{
+					ID:   "client ID",
+					Type: entity.TypeFile,
+					Data: []byte("text"),
 				})
+				assert.NoError(t, err)
+				assert.Regexp(t, fileRecordID, id)
 				db.AssertExpectations(t)
 				file.AssertExpectations(t)
 			},
@@ -190,15 +233,12 @@ func TestStorage_GetRecord(t *testing.T) {
 					"GetRecord",
 					context.Background(),
 					"",
-				).Return(entity.Record{Type: entity.TypeFile}, nil)
-				file.On(
-					"GetRecord",
-					mock.AnythingOfType("*context.valueCtx"),
-					"",
-				).Return(entity.Record{}, nil)
+				).Return(entity.Record{Type: entity.TypeFile}, nil).Once()
 			},
 			func() {
-				_, _ = storage.GetRecord(context.Background(), "")
+				record, err := storage.GetRecord(context.Background(), "")
+				assert.NoError(t, err)
+				assert.Equal(t, entity.Record{Type: entity.TypeFile}, record)
 				db.AssertExpectations(t)
 				file.AssertExpectations(t)
 			},
@@ -232,11 +272,11 @@ func TestStorage_DeleteRecord(t *testing.T) {
 		{
 			"Delete file record",
 			func() {
-				db.On("DeleteRecord", context.Background(), "").Return(nil)
+				db.On("DeleteRecord", context.Background(), "", int64(3)).Return(nil)
 				file.On("DeleteRecord", context.Background(), "").Return(nil)
 			},
 			func() {
-				_ = storage.DeleteRecord(context.Background(), "")
+				_ = storage.DeleteRecord(context.Background(), "", 3)
 				db.AssertExpectations(t)
 				file.AssertExpectations(t)
 			},
@@ -244,10 +284,179 @@ func TestStorage_DeleteRecord(t *testing.T) {
 		{
 			"Delete text record",
 			func() {
-				db.On("DeleteRecord", context.Background(), "").Return(entity.Record{}, nil)
+				db.On("DeleteRecord", context.Background(), "", int64(0)).Return(nil)
+			},
+			func() {
+				_ = storage.DeleteRecord(context.Background(), "", 0)
+			},
+		},
+	}
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestStorage_CreateFileRecord(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Create file record, file is written before record",
+			func() {
+				var written string
+
+				file.On(
+					"WriteRecord",
+					context.Background(),
+					mock.MatchedBy(fileRecordID.MatchString),
+					mock.AnythingOfType("*strings.Reader"),
+				).Run(func(args mock.Arguments) {
+					written = args.String(1)
+				}).Return(nil).Once()
+				db.On(
+					"CreateRecord",
+					context.Background(),
+					mock.MatchedBy(func(record entity.Record) bool {
+						return record.ID == written && record.Metadata == "file.txt" && record.Type == entity.TypeFile
+					}),
+				).Return(func(_ context.Context, record entity.Record) string { return record.ID }, nil).Once()
+			},
+			func() {
+				id, err := storage.CreateFileRecord(
+					context.Background(),
+					entity.Record{Metadata: "file.txt"},
+					strings.NewReader("text"),
+				)
+				assert.NoError(t, err)
+				assert.Regexp(t, fileRecordID, id)
+				db.AssertExpectations(t)
+				file.AssertExpectations(t)
+			},
+		},
+		{
+			"Create file record, but DB will return error, so file is deleted",
+			func() {
+				var written string
+
+				file.On(
+					"WriteRecord",
+					context.Background(),
+					mock.MatchedBy(fileRecordID.MatchString),
+					mock.AnythingOfType("*strings.Reader"),
+				).Run(func(args mock.Arguments) {
+					written = args.String(1)
+				}).Return(nil).Once()
+				db.On("CreateRecord", context.Background(), mock.AnythingOfType("entity.Record")).
+					Return("", ErrUnknown).Once()
+				file.On("DeleteRecord", context.Background(), mock.MatchedBy(func(recordID string) bool {
+					return recordID == written
+				})).Return(nil).Once()
+			},
+			func() {
+				id, err := storage.CreateFileRecord(
+					context.Background(),
+					entity.Record{Metadata: "file.txt"},
+					strings.NewReader("text"),
+				)
+				assert.Equal(t, ErrUnknown, err)
+				assert.Empty(t, id)
+				db.AssertExpectations(t)
+				file.AssertExpectations(t)
+			},
+		},
+		{
+			"Create file record, but file storage will return error",
+			func() {
+				file.On(
+					"WriteRecord",
+					context.Background(),
+					mock.MatchedBy(fileRecordID.MatchString),
+					mock.AnythingOfType("*strings.Reader"),
+				).Return(ErrUnknown).Once()
+			},
+			func() {
+				id, err := storage.CreateFileRecord(
+					context.Background(),
+					entity.Record{Metadata: "file.txt"},
+					strings.NewReader("text"),
+				)
+				assert.Equal(t, ErrUnknown, err)
+				assert.Empty(t, id)
+				db.AssertExpectations(t)
+				file.AssertExpectations(t)
+			},
+		},
+	}
+	for _, test := range tc {
+		t.Log(test.name)
+		test.mock()
+		test.valid()
+	}
+}
+
+func TestStorage_GetFileRecord(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+
+	tc := []struct {
+		name  string
+		mock  func()
+		valid func()
+	}{
+		{
+			"Get file record",
+			func() {
+				db.On("GetRecord", context.Background(), "1").
+					Return(entity.Record{ID: "1", Type: entity.TypeFile}, nil).Once()
+				file.On("OpenRecord", context.Background(), "1").
+					Return(io.NopCloser(strings.NewReader("text")), nil).Once()
+			},
+			func() {
+				record, reader, err := storage.GetFileRecord(context.Background(), "1")
+				assert.NoError(t, err)
+				assert.Equal(t, entity.Record{ID: "1", Type: entity.TypeFile}, record)
+
+				data, err := io.ReadAll(reader)
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("text"), data)
+				db.AssertExpectations(t)
+				file.AssertExpectations(t)
+			},
+		},
+		{
+			"Get text record",
+			func() {
+				db.On("GetRecord", context.Background(), "2").
+					Return(entity.Record{ID: "2", Type: entity.TypeText, Data: []byte("text")}, nil).Once()
+			},
+			func() {
+				record, reader, err := storage.GetFileRecord(context.Background(), "2")
+				assert.NoError(t, err)
+				assert.Equal(t, entity.Record{ID: "2", Type: entity.TypeText}, record)
+
+				var buf bytes.Buffer
+				_, err = buf.ReadFrom(reader)
+				assert.NoError(t, err)
+				assert.Equal(t, "text", buf.String())
+			},
+		},
+		{
+			"Get non existed record",
+			func() {
+				db.On("GetRecord", context.Background(), "3").
+					Return(entity.Record{}, ErrNotFound).Once()
 			},
 			func() {
-				_ = storage.DeleteRecord(context.Background(), "")
+				_, reader, err := storage.GetFileRecord(context.Background(), "3")
+				assert.Equal(t, ErrNotFound, err)
+				assert.Nil(t, reader)
 			},
 		},
 	}
@@ -257,3 +466,87 @@ func TestStorage_DeleteRecord(t *testing.T) {
 		test.valid()
 	}
 }
+
+func TestStorage_UpdateRecord(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+
+	record := entity.Record{ID: "1", Type: entity.TypeText, Data: []byte("data")}
+	db.On("UpdateRecord", context.Background(), record).Return(nil).Once()
+
+	assert.NoError(t, storage.UpdateRecord(context.Background(), record))
+
+	labels := entity.Record{ID: "2", Type: entity.TypeFile, Metadata: "gkm1:00"}
+	db.On("UpdateRecord", context.Background(), labels).Return(nil).Once()
+
+	assert.NoError(t, storage.UpdateRecord(context.Background(), labels))
+
+	err := storage.UpdateRecord(context.Background(), entity.Record{ID: "2", Type: entity.TypeFile, Data: []byte("file")})
+	assert.Equal(t, ErrNotSupported, err)
+
+	db.AssertExpectations(t)
+	file.AssertExpectations(t)
+}
+
+func TestStorage_RecordVersions(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+
+	db.On("GetRecordVersions", context.Background(), "1").
+		Return([]entity.RecordVersion{{RecordID: "1", Version: 1}}, nil).Once()
+	db.On("RestoreRecordVersion", context.Background(), "1", int32(1), int64(3)).Return(nil).Once()
+
+	versions, err := storage.GetRecordVersions(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Len(t, versions, 1)
+
+	assert.NoError(t, storage.RestoreRecordVersion(context.Background(), "1", 1, 3))
+
+	db.AssertExpectations(t)
+	file.AssertExpectations(t)
+}
+
+func TestStorage_Sync(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+
+	changes := entity.RecordChanges{
+		Records:  []entity.Record{{ID: "1", Revision: 4}},
+		Deleted:  []entity.Tombstone{{RecordID: "2", Revision: 5}},
+		Revision: 5,
+	}
+	db.On("Sync", context.Background(), int64(3)).Return(changes, nil).Once()
+
+	got, err := storage.Sync(context.Background(), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, changes, got)
+
+	db.AssertExpectations(t)
+	file.AssertExpectations(t)
+}
+
+func TestStorage_VaultRecords(t *testing.T) {
+	db, file := mocks.NewStorager(t), mocks.NewFileStorager(t)
+	storage := NewStorage(db, file)
+
+	record := entity.Record{VaultID: "vault", Type: entity.TypeText, Data: []byte("shared")}
+	db.On("CreateVaultRecord", context.Background(), record).Return("1", nil).Once()
+	db.On("DeleteVaultRecord", context.Background(), "vault", "1").Return(nil).Once()
+
+	id, err := storage.CreateVaultRecord(context.Background(), record)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", id)
+
+	t.Log("Files can't be shared")
+	_, err = storage.CreateVaultRecord(context.Background(), entity.Record{VaultID: "vault", Type: entity.TypeFile})
+	assert.Equal(t, ErrNotSupported, err)
+	assert.Equal(t, ErrNotSupported, storage.UpdateVaultRecord(context.Background(), entity.Record{Type: entity.TypeFile}))
+
+	rotation := entity.VaultRotation{VaultID: "vault", Revoked: "bob", Records: []entity.Record{{Type: entity.TypeFile}}}
+	assert.Equal(t, ErrNotSupported, storage.RevokeVaultMember(context.Background(), rotation))
+
+	assert.NoError(t, storage.DeleteVaultRecord(context.Background(), "vault", "1"))
+
+	db.AssertExpectations(t)
+	file.AssertExpectations(t)
+}
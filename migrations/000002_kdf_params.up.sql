@@ -0,0 +1,5 @@
+ALTER TABLE users
+    ADD COLUMN kdf_salt VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN kdf_time INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN kdf_memory INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN kdf_threads INTEGER NOT NULL DEFAULT 0;
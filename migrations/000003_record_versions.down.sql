@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS record_versions;
+
+ALTER TABLE users_data
+    DROP COLUMN IF EXISTS version;
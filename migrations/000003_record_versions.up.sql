@@ -0,0 +1,13 @@
+ALTER TABLE users_data
+    ALTER COLUMN encoded_data TYPE TEXT,
+    ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE record_versions (
+                        record_id UUID NOT NULL REFERENCES users_data (record_id) ON DELETE CASCADE,
+                        version INTEGER NOT NULL,
+                        record_type INTEGER,
+                        metadata VARCHAR(256),
+                        encoded_data TEXT,
+                        replaced_at TIMESTAMP NOT NULL DEFAULT now(),
+                        PRIMARY KEY (record_id, version)
+);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS record_tombstones;
+
+DROP INDEX IF EXISTS users_data_revision_idx;
+
+ALTER TABLE users_data
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS revision;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS revision;
@@ -0,0 +1,20 @@
+ALTER TABLE users
+    ADD COLUMN revision BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE users_data
+    ADD COLUMN revision BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT now();
+
+UPDATE users SET revision = 1;
+UPDATE users_data SET revision = 1;
+
+CREATE INDEX users_data_revision_idx ON users_data (user_id, revision);
+
+CREATE TABLE record_tombstones (
+                        record_id UUID PRIMARY KEY,
+                        user_id VARCHAR(256) NOT NULL,
+                        revision BIGINT NOT NULL,
+                        deleted_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX record_tombstones_revision_idx ON record_tombstones (user_id, revision);
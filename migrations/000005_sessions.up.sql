@@ -0,0 +1,9 @@
+CREATE TABLE sessions (
+                        session_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                        user_id VARCHAR(256) NOT NULL,
+                        token_hash VARCHAR(64) NOT NULL UNIQUE,
+                        created_at TIMESTAMP NOT NULL DEFAULT now(),
+                        expires_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX sessions_user_idx ON sessions (user_id);
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS users_data_name_idx;
+DROP INDEX IF EXISTS users_data_folder_idx;
+
+ALTER TABLE record_versions
+    DROP COLUMN IF EXISTS folder,
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS name;
+
+ALTER TABLE users_data
+    DROP COLUMN IF EXISTS folder,
+    DROP COLUMN IF EXISTS tags,
+    DROP COLUMN IF EXISTS name;
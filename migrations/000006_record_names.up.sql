@@ -0,0 +1,15 @@
+ALTER TABLE users_data
+    ADD COLUMN name VARCHAR(256) NOT NULL DEFAULT '',
+    ADD COLUMN tags TEXT NOT NULL DEFAULT '',
+    ADD COLUMN folder VARCHAR(1024) NOT NULL DEFAULT '';
+
+ALTER TABLE record_versions
+    ADD COLUMN name VARCHAR(256) NOT NULL DEFAULT '',
+    ADD COLUMN tags TEXT NOT NULL DEFAULT '',
+    ADD COLUMN folder VARCHAR(1024) NOT NULL DEFAULT '';
+
+UPDATE users_data SET name = COALESCE(metadata, '');
+UPDATE record_versions SET name = COALESCE(metadata, '');
+
+CREATE INDEX users_data_folder_idx ON users_data (user_id, folder);
+CREATE INDEX users_data_name_idx ON users_data (user_id, lower(name));
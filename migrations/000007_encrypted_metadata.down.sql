@@ -0,0 +1,9 @@
+ALTER TABLE record_versions
+    DROP COLUMN IF EXISTS blind_index,
+    ALTER COLUMN name TYPE VARCHAR(256),
+    ALTER COLUMN metadata TYPE VARCHAR(256);
+
+ALTER TABLE users_data
+    DROP COLUMN IF EXISTS blind_index,
+    ALTER COLUMN name TYPE VARCHAR(256),
+    ALTER COLUMN metadata TYPE VARCHAR(256);
@@ -0,0 +1,9 @@
+ALTER TABLE users_data
+    ALTER COLUMN metadata TYPE TEXT,
+    ALTER COLUMN name TYPE TEXT,
+    ADD COLUMN blind_index TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE record_versions
+    ALTER COLUMN metadata TYPE TEXT,
+    ALTER COLUMN name TYPE TEXT,
+    ADD COLUMN blind_index TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS vault_records;
+DROP TABLE IF EXISTS vault_members;
+DROP TABLE IF EXISTS vaults;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS public_key;
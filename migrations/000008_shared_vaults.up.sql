@@ -0,0 +1,35 @@
+ALTER TABLE users
+    ADD COLUMN public_key VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE TABLE vaults (
+                        vault_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                        name VARCHAR(256) NOT NULL,
+                        owner_id UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+                        created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE TABLE vault_members (
+                        vault_id UUID NOT NULL REFERENCES vaults (vault_id) ON DELETE CASCADE,
+                        user_id UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+                        role INTEGER NOT NULL,
+                        wrapped_key TEXT NOT NULL,
+                        PRIMARY KEY (vault_id, user_id)
+);
+
+CREATE INDEX vault_members_user_idx ON vault_members (user_id);
+
+CREATE TABLE vault_records (
+                        record_id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+                        vault_id UUID NOT NULL REFERENCES vaults (vault_id) ON DELETE CASCADE,
+                        created_by UUID NOT NULL,
+                        record_type INTEGER NOT NULL,
+                        metadata TEXT NOT NULL DEFAULT '',
+                        name TEXT NOT NULL DEFAULT '',
+                        tags TEXT NOT NULL DEFAULT '',
+                        folder VARCHAR(1024) NOT NULL DEFAULT '',
+                        blind_index TEXT NOT NULL DEFAULT '',
+                        encoded_data TEXT NOT NULL DEFAULT '',
+                        updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX vault_records_vault_idx ON vault_records (vault_id);
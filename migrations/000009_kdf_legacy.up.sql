@@ -0,0 +1,4 @@
+ALTER TABLE users
+    ADD COLUMN kdf_legacy BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE users SET kdf_legacy = TRUE WHERE kdf_salt = '';
@@ -0,0 +1,2 @@
+ALTER TABLE vault_members
+    DROP COLUMN IF EXISTS key_mac;
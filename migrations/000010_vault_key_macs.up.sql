@@ -0,0 +1,2 @@
+ALTER TABLE vault_members
+    ADD COLUMN key_mac TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+-- Public keys of users on legacy key are derived from legacy key, they aren't restored.
@@ -0,0 +1 @@
+UPDATE users SET public_key = '' WHERE kdf_legacy;
@@ -0,0 +1,317 @@
+package pkg
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Envelope is versioned format of encrypted data, which can be encrypted and decrypted by chunks.
+//
+// Header: magic "GKE" | version (1 byte) | chunk size (uint32) | random stream ID (16 bytes).
+// Chunk:  final flag (1 byte) | nonce (12 bytes) | ciphertext length (uint32) | ciphertext with tag.
+//
+// Every chunk is sealed by AES-GCM with header, chunk index and final flag as additional data,
+// so reordered, truncated or mixed from other envelopes chunks are detected.
+const (
+	EnvelopeVersion   = 1
+	EnvelopeChunkSize = 64 * 1024
+
+	envelopeMagic         = "GKE"
+	envelopeHeaderSize    = len(envelopeMagic) + 1 + 4 + 16
+	envelopeChunkHeader   = 1 + 12 + 4
+	envelopeMaxChunkSize  = 16 * 1024 * 1024
+	envelopeChunkFinal    = byte(1)
+	envelopeChunkNotFinal = byte(0)
+)
+
+// Errors for envelope format.
+var (
+	ErrNotEnvelope       = errors.New("data isn't in envelope format")
+	ErrEnvelopeVersion   = errors.New("unsupported envelope version")
+	ErrEnvelopeCorrupted = errors.New("envelope is corrupted")
+	ErrEnvelopeTruncated = errors.New("envelope is truncated")
+)
+
+// IsEnvelope checks if data starts with envelope header.
+func IsEnvelope(data []byte) bool {
+	return len(data) >= envelopeHeaderSize && string(data[:len(envelopeMagic)]) == envelopeMagic
+}
+
+// EncryptBytes encrypts data into envelope.
+func EncryptBytes(key, data []byte) ([]byte, error) {
+	reader, err := NewEncryptReader(bytes.NewReader(data), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(reader)
+}
+
+// DecryptBytes decrypts whole envelope.
+func DecryptBytes(key, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writer, err := NewDecryptWriter(&buf, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = writer.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err = writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// additionalData binds chunk to envelope header, its position and final flag.
+func additionalData(header []byte, index uint64, flag byte) []byte {
+	ad := make([]byte, 0, len(header)+8+1)
+	ad = append(ad, header...)
+	ad = binary.BigEndian.AppendUint64(ad, index)
+
+	return append(ad, flag)
+}
+
+// encryptReader reads plain data from source and returns it encrypted in envelope format.
+type encryptReader struct {
+	src    *bufio.Reader
+	aead   cipher.AEAD
+	header []byte
+	plain  []byte
+	out    []byte
+	index  uint64
+	done   bool
+}
+
+// NewEncryptReader returns reader, which encrypts data from r into envelope by chunks.
+func NewEncryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	streamID, err := GenerateRandom(16)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, envelopeHeaderSize)
+	header = append(header, envelopeMagic...)
+	header = append(header, EnvelopeVersion)
+	header = binary.BigEndian.AppendUint32(header, EnvelopeChunkSize)
+	header = append(header, streamID...)
+
+	return &encryptReader{
+		src:    bufio.NewReaderSize(r, EnvelopeChunkSize),
+		aead:   aead,
+		header: header,
+		plain:  make([]byte, EnvelopeChunkSize),
+		out:    append([]byte(nil), header...),
+	}, nil
+}
+
+// Read implementation of io.Reader interface.
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.sealNext(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+
+	return n, nil
+}
+
+// sealNext reads next chunk of plain data and encrypts it.
+func (r *encryptReader) sealNext() error {
+	n, err := io.ReadFull(r.src, r.plain)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+
+	flag := envelopeChunkNotFinal
+	if _, errPeek := r.src.Peek(1); errors.Is(errPeek, io.EOF) {
+		flag = envelopeChunkFinal
+	} else if errPeek != nil {
+		return errPeek
+	}
+
+	nonce, err := GenerateRandom(r.aead.NonceSize())
+	if err != nil {
+		return err
+	}
+
+	sealed := r.aead.Seal(nil, nonce, r.plain[:n], additionalData(r.header, r.index, flag))
+
+	r.out = append(r.out[:0], flag)
+	r.out = append(r.out, nonce...)
+	r.out = binary.BigEndian.AppendUint32(r.out, uint32(len(sealed)))
+	r.out = append(r.out, sealed...)
+
+	r.index++
+	r.done = flag == envelopeChunkFinal
+
+	return nil
+}
+
+// decryptWriter decrypts envelope written to it and writes plain data to destination.
+type decryptWriter struct {
+	dst       io.Writer
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int
+	buf       []byte
+	index     uint64
+	finished  bool
+	err       error
+}
+
+// NewDecryptWriter returns writer, which decrypts envelope by chunks and writes plain data to w.
+// Close must be called after all data is written to check that envelope isn't truncated.
+func NewDecryptWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptWriter{
+		dst:  w,
+		aead: aead,
+	}, nil
+}
+
+// Write implementation of io.Writer interface.
+func (w *decryptWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.buf = append(w.buf, p...)
+
+	if err := w.openAvailable(); err != nil {
+		w.err = err
+
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Close checks that final chunk was received. Returns first error of writing, if it was.
+func (w *decryptWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if !w.finished {
+		w.err = ErrEnvelopeTruncated
+	}
+
+	return w.err
+}
+
+// openAvailable decrypts all complete chunks from buffer.
+func (w *decryptWriter) openAvailable() error {
+	if w.header == nil {
+		if len(w.buf) < envelopeHeaderSize {
+			return nil
+		}
+
+		if err := w.readHeader(w.buf[:envelopeHeaderSize]); err != nil {
+			return err
+		}
+		w.buf = w.buf[envelopeHeaderSize:]
+	}
+
+	for len(w.buf) > 0 {
+		if w.finished {
+			return ErrEnvelopeCorrupted
+		}
+
+		if len(w.buf) < envelopeChunkHeader {
+			return nil
+		}
+
+		flag := w.buf[0]
+		nonce := w.buf[1 : 1+w.aead.NonceSize()]
+		size := int(binary.BigEndian.Uint32(w.buf[1+w.aead.NonceSize() : envelopeChunkHeader]))
+
+		if flag > envelopeChunkFinal || size > w.chunkSize+w.aead.Overhead() {
+			return ErrEnvelopeCorrupted
+		}
+
+		if len(w.buf) < envelopeChunkHeader+size {
+			return nil
+		}
+
+		plain, err := w.aead.Open(
+			nil,
+			nonce,
+			w.buf[envelopeChunkHeader:envelopeChunkHeader+size],
+			additionalData(w.header, w.index, flag),
+		)
+		if err != nil {
+			log.Infoln(err)
+
+			return ErrEnvelopeCorrupted
+		}
+
+		if _, err = w.dst.Write(plain); err != nil {
+			return err
+		}
+
+		w.buf = w.buf[envelopeChunkHeader+size:]
+		w.index++
+		w.finished = flag == envelopeChunkFinal
+	}
+
+	return nil
+}
+
+// readHeader validates envelope header.
+func (w *decryptWriter) readHeader(header []byte) error {
+	if string(header[:len(envelopeMagic)]) != envelopeMagic {
+		return ErrNotEnvelope
+	}
+
+	if header[len(envelopeMagic)] != EnvelopeVersion {
+		return ErrEnvelopeVersion
+	}
+
+	chunkSize := binary.BigEndian.Uint32(header[len(envelopeMagic)+1:])
+	if chunkSize == 0 || chunkSize > envelopeMaxChunkSize {
+		return ErrEnvelopeCorrupted
+	}
+
+	w.header = append([]byte(nil), header...)
+	w.chunkSize = int(chunkSize)
+
+	return nil
+}
+
+// newAEAD returns AES-GCM for key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
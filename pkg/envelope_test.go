@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var envelopeKey = bytes.Repeat([]byte{0x42}, 32)
+
+// chunkOffsets returns offsets of all chunks in envelope.
+func chunkOffsets(t *testing.T, envelope []byte) []int {
+	var offsets []int
+
+	for offset := envelopeHeaderSize; offset < len(envelope); {
+		offsets = append(offsets, offset)
+		size := int(binary.BigEndian.Uint32(envelope[offset+13 : offset+envelopeChunkHeader]))
+		offset += envelopeChunkHeader + size
+	}
+
+	assert.NotEmpty(t, offsets)
+
+	return offsets
+}
+
+func TestEnvelope(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789"), EnvelopeChunkSize/2)
+
+	tc := []struct {
+		name   string
+		data   []byte
+		chunks int
+	}{
+		{"Empty data", []byte{}, 1},
+		{"Small data", []byte("hello!"), 1},
+		{"Exactly one chunk", bytes.Repeat([]byte("a"), EnvelopeChunkSize), 1},
+		{"Several chunks", big, 5},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		encrypted, err := EncryptBytes(envelopeKey, test.data)
+		assert.NoError(t, err)
+		assert.True(t, IsEnvelope(encrypted))
+		assert.Len(t, chunkOffsets(t, encrypted), test.chunks)
+
+		decrypted, err := DecryptBytes(envelopeKey, encrypted)
+		assert.NoError(t, err)
+		assert.Equal(t, len(test.data), len(decrypted))
+		assert.True(t, bytes.Equal(test.data, decrypted))
+	}
+}
+
+func TestEnvelope_Stream(t *testing.T) {
+	data := bytes.Repeat([]byte("stream"), 3*EnvelopeChunkSize)
+
+	reader, err := NewEncryptReader(bytes.NewReader(data), envelopeKey)
+	assert.NoError(t, err)
+
+	var plain bytes.Buffer
+
+	writer, err := NewDecryptWriter(&plain, envelopeKey)
+	assert.NoError(t, err)
+
+	// Small buffer splits chunks between writes.
+	_, err = io.CopyBuffer(struct{ io.Writer }{writer}, struct{ io.Reader }{reader}, make([]byte, 1000))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	assert.Equal(t, data, plain.Bytes())
+}
+
+func TestEnvelope_Tampering(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), EnvelopeChunkSize/2)
+
+	encrypted, err := EncryptBytes(envelopeKey, data)
+	assert.NoError(t, err)
+
+	offsets := chunkOffsets(t, encrypted)
+
+	reordered := append([]byte(nil), encrypted[:offsets[0]]...)
+	reordered = append(reordered, encrypted[offsets[1]:offsets[2]]...)
+	reordered = append(reordered, encrypted[offsets[0]:offsets[1]]...)
+	reordered = append(reordered, encrypted[offsets[2]:]...)
+
+	flipped := append([]byte(nil), encrypted...)
+	flipped[len(flipped)-1] ^= 0x01
+
+	otherEnvelope, err := EncryptBytes(envelopeKey, data)
+	assert.NoError(t, err)
+
+	mixed := append([]byte(nil), encrypted[:offsets[1]]...)
+	mixed = append(mixed, otherEnvelope[offsets[1]:]...)
+
+	otherKey := bytes.Repeat([]byte{0x24}, 32)
+
+	tc := []struct {
+		name string
+		key  []byte
+		data []byte
+		want error
+	}{
+		{"Truncated after chunk", envelopeKey, encrypted[:offsets[len(offsets)-1]], ErrEnvelopeTruncated},
+		{"Truncated inside chunk", envelopeKey, encrypted[:len(encrypted)-10], ErrEnvelopeTruncated},
+		{"Only header", envelopeKey, encrypted[:envelopeHeaderSize], ErrEnvelopeTruncated},
+		{"Reordered chunks", envelopeKey, reordered, ErrEnvelopeCorrupted},
+		{"Flipped bit", envelopeKey, flipped, ErrEnvelopeCorrupted},
+		{"Chunks from other envelope", envelopeKey, mixed, ErrEnvelopeCorrupted},
+		{"Data after final chunk", envelopeKey, append(append([]byte(nil), encrypted...), 0x00), ErrEnvelopeCorrupted},
+		{"Wrong key", otherKey, encrypted, ErrEnvelopeCorrupted},
+		{"Not envelope", envelopeKey, bytes.Repeat([]byte{0x01}, 100), ErrNotEnvelope},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		decrypted, err := DecryptBytes(test.key, test.data)
+		assert.Equal(t, test.want, err)
+		assert.Empty(t, decrypted)
+	}
+}
+
+func TestEnvelope_Version(t *testing.T) {
+	encrypted, err := EncryptBytes(envelopeKey, []byte("hello!"))
+	assert.NoError(t, err)
+
+	encrypted[len(envelopeMagic)] = EnvelopeVersion + 1
+
+	_, err = DecryptBytes(envelopeKey, encrypted)
+	assert.Equal(t, ErrEnvelopeVersion, err)
+}
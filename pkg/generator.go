@@ -0,0 +1,225 @@
+package pkg
+
+import (
+	"bufio"
+	"crypto/rand"
+	_ "embed" // Wordlist of passphrases.
+	"errors"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Character classes of generated passwords.
+const (
+	PasswordLower   = "abcdefghijklmnopqrstuvwxyz"
+	PasswordUpper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	PasswordDigits  = "0123456789"
+	PasswordSymbols = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+
+	// passwordAmbiguous are characters, which are easy to confuse.
+	passwordAmbiguous = "0O1lI|"
+)
+
+// Limits of generator policies.
+const (
+	passwordMaxLength   = 1024
+	passphraseMaxWords  = 64
+	passphraseMinWords  = 3
+	passwordMinLength   = 8
+	passphraseWordsSize = 7776
+)
+
+// ErrBadGeneratorPolicy means that policy can't generate strong enough password.
+var ErrBadGeneratorPolicy = errors.New("bad password generator policy")
+
+// effWordlist is EFF large wordlist for diceware passphrases (CC BY 3.0, Electronic Frontier Foundation):
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed eff_large_wordlist.txt
+var effWordlist string
+
+// Words of wordlist, parsed on first use.
+var (
+	passphraseWordsOnce sync.Once
+	passphraseWordsList []string
+)
+
+// passphraseWords returns parsed words of wordlist.
+func passphraseWords() []string {
+	passphraseWordsOnce.Do(func() {
+		passphraseWordsList = make([]string, 0, passphraseWordsSize)
+
+		scanner := bufio.NewScanner(strings.NewReader(effWordlist))
+		for scanner.Scan() {
+			// Line is dice rolls and word separated by tab.
+			if fields := strings.Fields(scanner.Text()); len(fields) == 2 {
+				passphraseWordsList = append(passphraseWordsList, fields[1])
+			}
+		}
+	})
+
+	return passphraseWordsList
+}
+
+// PasswordPolicy is policy of generated password: its length and character classes.
+// Password contains at least one character of every chosen class.
+type PasswordPolicy struct {
+	Length           int
+	Lower            bool
+	Upper            bool
+	Digits           bool
+	Symbols          bool
+	ExcludeAmbiguous bool
+}
+
+// DefaultPasswordPolicy is policy of 20 characters of all classes.
+var DefaultPasswordPolicy = PasswordPolicy{
+	Length:  20,
+	Lower:   true,
+	Upper:   true,
+	Digits:  true,
+	Symbols: true,
+}
+
+// PassphrasePolicy is policy of diceware passphrase: number of words from wordlist and their separator.
+type PassphrasePolicy struct {
+	Words      int
+	Separator  string
+	Capitalize bool
+}
+
+// DefaultPassphrasePolicy is policy of 6 words separated by hyphens (about 77 bits of entropy).
+var DefaultPassphrasePolicy = PassphrasePolicy{
+	Words:     6,
+	Separator: "-",
+}
+
+// GeneratePassword generates random password by policy.
+func GeneratePassword(policy PasswordPolicy) (string, error) {
+	classes := policy.classes()
+	if len(classes) == 0 || policy.Length < passwordMinLength || policy.Length > passwordMaxLength {
+		return "", ErrBadGeneratorPolicy
+	}
+
+	alphabet := strings.Join(classes, "")
+	password := make([]byte, 0, policy.Length)
+
+	// One character of every class, then the rest from whole alphabet, then shuffle.
+	for _, class := range classes {
+		c, err := randomChar(class)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	for len(password) < policy.Length {
+		c, err := randomChar(alphabet)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+// Entropy estimates entropy of password generated by policy in bits.
+// Requirement of one character per class is neglected, it reduces entropy a little.
+func (p PasswordPolicy) Entropy() float64 {
+	return float64(p.Length) * math.Log2(float64(len(strings.Join(p.classes(), ""))))
+}
+
+// classes returns characters of chosen classes.
+func (p PasswordPolicy) classes() []string {
+	var classes []string
+
+	for _, class := range []struct {
+		chosen     bool
+		characters string
+	}{
+		{p.Lower, PasswordLower},
+		{p.Upper, PasswordUpper},
+		{p.Digits, PasswordDigits},
+		{p.Symbols, PasswordSymbols},
+	} {
+		if !class.chosen {
+			continue
+		}
+
+		characters := class.characters
+		if p.ExcludeAmbiguous {
+			characters = strings.Map(func(r rune) rune {
+				if strings.ContainsRune(passwordAmbiguous, r) {
+					return -1
+				}
+				return r
+			}, characters)
+		}
+
+		classes = append(classes, characters)
+	}
+
+	return classes
+}
+
+// GeneratePassphrase generates diceware passphrase by policy.
+func GeneratePassphrase(policy PassphrasePolicy) (string, error) {
+	if policy.Words < passphraseMinWords || policy.Words > passphraseMaxWords {
+		return "", ErrBadGeneratorPolicy
+	}
+
+	words := passphraseWords()
+	passphrase := make([]string, 0, policy.Words)
+
+	for i := 0; i < policy.Words; i++ {
+		n, err := randomInt(len(words))
+		if err != nil {
+			return "", err
+		}
+
+		word := words[n]
+		if policy.Capitalize {
+			word = string(unicode.ToUpper(rune(word[0]))) + word[1:]
+		}
+		passphrase = append(passphrase, word)
+	}
+
+	return strings.Join(passphrase, policy.Separator), nil
+}
+
+// Entropy estimates entropy of passphrase generated by policy in bits.
+func (p PassphrasePolicy) Entropy() float64 {
+	return float64(p.Words) * math.Log2(float64(len(passphraseWords())))
+}
+
+// randomChar returns uniformly chosen character of alphabet.
+func randomChar(alphabet string) (byte, error) {
+	n, err := randomInt(len(alphabet))
+	if err != nil {
+		return 0, err
+	}
+
+	return alphabet[n], nil
+}
+
+// randomInt returns uniformly chosen number in [0, max) from crypto random source.
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(n.Int64()), nil
+}
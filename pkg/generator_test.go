@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	tc := []struct {
+		name    string
+		policy  PasswordPolicy
+		entropy float64
+		want    error
+	}{
+		{"Default policy", DefaultPasswordPolicy, 128, nil},
+		{"Only digits", PasswordPolicy{Length: 10, Digits: true}, 33, nil},
+		{"Without ambiguous", PasswordPolicy{Length: 12, Lower: true, Upper: true, Digits: true, ExcludeAmbiguous: true}, 69, nil},
+		{"Without classes", PasswordPolicy{Length: 20}, 0, ErrBadGeneratorPolicy},
+		{"Too short", PasswordPolicy{Length: 4, Lower: true}, 0, ErrBadGeneratorPolicy},
+		{"Too long", PasswordPolicy{Length: 2048, Lower: true}, 0, ErrBadGeneratorPolicy},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		password, err := GeneratePassword(test.policy)
+		assert.ErrorIs(t, err, test.want)
+		if test.want != nil {
+			continue
+		}
+
+		assert.Len(t, password, test.policy.Length)
+		assert.GreaterOrEqual(t, test.policy.Entropy(), test.entropy)
+
+		for _, class := range test.policy.classes() {
+			assert.True(t, strings.ContainsAny(password, class), "%q has no character of %q", password, class)
+		}
+
+		for _, c := range password {
+			assert.True(t, strings.ContainsRune(strings.Join(test.policy.classes(), ""), c))
+			if test.policy.ExcludeAmbiguous {
+				assert.False(t, strings.ContainsRune(passwordAmbiguous, c))
+			}
+		}
+
+		other, err := GeneratePassword(test.policy)
+		assert.NoError(t, err)
+		assert.NotEqual(t, password, other)
+	}
+}
+
+func TestGeneratePassphrase(t *testing.T) {
+	assert.Len(t, passphraseWords(), passphraseWordsSize)
+
+	passphrase, err := GeneratePassphrase(DefaultPassphrasePolicy)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, strings.Count(passphrase, "-"), DefaultPassphrasePolicy.Words-1)
+
+	tc := []struct {
+		name    string
+		policy  PassphrasePolicy
+		entropy float64
+		want    error
+	}{
+		{"Six words", PassphrasePolicy{Words: 6, Separator: "_"}, 77, nil},
+		{"Capitalized", PassphrasePolicy{Words: 4, Separator: " ", Capitalize: true}, 51, nil},
+		{"Too few words", PassphrasePolicy{Words: 2}, 0, ErrBadGeneratorPolicy},
+		{"Too many words", PassphrasePolicy{Words: 100}, 0, ErrBadGeneratorPolicy},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		passphrase, err = GeneratePassphrase(test.policy)
+		assert.ErrorIs(t, err, test.want)
+		if test.want != nil {
+			continue
+		}
+
+		assert.GreaterOrEqual(t, test.policy.Entropy(), test.entropy)
+
+		words := strings.Split(passphrase, test.policy.Separator)
+		assert.Len(t, words, test.policy.Words)
+
+		for _, word := range words {
+			assert.NotEmpty(t, word)
+			if test.policy.Capitalize {
+				assert.Equal(t, strings.ToUpper(word[:1]), word[:1])
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"errors"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Default Argon2id parameters for new users.
+const (
+	KDFTime    = 3
+	KDFMemory  = 64 * 1024
+	KDFThreads = 4
+	KDFKeySize = 32
+	KDFSalt    = 16
+)
+
+// Limits of Argon2id parameters, which client accepts from server. Maximums limit imports too.
+// Salt is stored by server in hex, so it can't be longer than kdfMaxSalt.
+const (
+	kdfMaxSalt    = 32
+	kdfMinTime    = 1
+	KDFMaxTime    = 16
+	kdfMinMemory  = 16 * 1024
+	KDFMaxMemory  = 1024 * 1024
+	KDFMaxThreads = 16
+)
+
+// ErrBadKDFParams means that key derivation parameters are too weak or too expensive.
+var ErrBadKDFParams = errors.New("bad key derivation parameters")
+
+// NewKDFParams returns default key derivation parameters with new random salt.
+func NewKDFParams() (entity.KDFParams, error) {
+	salt, err := GenerateRandom(KDFSalt)
+	if err != nil {
+		return entity.KDFParams{}, err
+	}
+
+	return entity.KDFParams{
+		Salt:    salt,
+		Time:    KDFTime,
+		Memory:  KDFMemory,
+		Threads: KDFThreads,
+	}, nil
+}
+
+// ValidateKDFParams checks that parameters are in allowed limits.
+func ValidateKDFParams(params entity.KDFParams) error {
+	switch {
+	case len(params.Salt) < KDFSalt || len(params.Salt) > kdfMaxSalt:
+		return ErrBadKDFParams
+	case params.Time < kdfMinTime || params.Time > KDFMaxTime:
+		return ErrBadKDFParams
+	case params.Memory < kdfMinMemory || params.Memory > KDFMaxMemory:
+		return ErrBadKDFParams
+	case params.Threads == 0 || params.Threads > KDFMaxThreads:
+		return ErrBadKDFParams
+	}
+
+	return nil
+}
+
+// DeriveKey derives encryption key from master key using Argon2id.
+func DeriveKey(masterKey []byte, params entity.KDFParams) ([]byte, error) {
+	if err := ValidateKDFParams(params); err != nil {
+		return nil, err
+	}
+
+	return argon2.IDKey(masterKey, params.Salt, params.Time, params.Memory, params.Threads, KDFKeySize), nil
+}
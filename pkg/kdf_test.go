@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKDFParams(t *testing.T) {
+	first, err := NewKDFParams()
+	assert.NoError(t, err)
+	assert.NoError(t, ValidateKDFParams(first))
+
+	second, err := NewKDFParams()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.Salt, second.Salt)
+}
+
+func TestDeriveKey(t *testing.T) {
+	params := entity.KDFParams{
+		Salt:    bytes.Repeat([]byte{0x01}, KDFSalt),
+		Time:    1,
+		Memory:  kdfMinMemory,
+		Threads: 1,
+	}
+
+	key, err := DeriveKey([]byte("master key"), params)
+	assert.NoError(t, err)
+	assert.Len(t, key, KDFKeySize)
+
+	same, err := DeriveKey([]byte("master key"), params)
+	assert.NoError(t, err)
+	assert.Equal(t, key, same)
+
+	otherKey, err := DeriveKey([]byte("other master key"), params)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, otherKey)
+
+	params.Salt = bytes.Repeat([]byte{0x02}, KDFSalt)
+	otherSalt, err := DeriveKey([]byte("master key"), params)
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, otherSalt)
+}
+
+func TestValidateKDFParams(t *testing.T) {
+	good := entity.KDFParams{
+		Salt:    bytes.Repeat([]byte{0x01}, KDFSalt),
+		Time:    KDFTime,
+		Memory:  KDFMemory,
+		Threads: KDFThreads,
+	}
+
+	tc := []struct {
+		name   string
+		modify func(params *entity.KDFParams)
+		want   error
+	}{
+		{"Default params", func(params *entity.KDFParams) {}, nil},
+		{"Short salt", func(params *entity.KDFParams) { params.Salt = params.Salt[:8] }, ErrBadKDFParams},
+		{"Long salt", func(params *entity.KDFParams) { params.Salt = bytes.Repeat([]byte{0x01}, 33) }, ErrBadKDFParams},
+		{"Zero time", func(params *entity.KDFParams) { params.Time = 0 }, ErrBadKDFParams},
+		{"Too little memory", func(params *entity.KDFParams) { params.Memory = 1024 }, ErrBadKDFParams},
+		{"Too much memory", func(params *entity.KDFParams) { params.Memory = 8 * 1024 * 1024 }, ErrBadKDFParams},
+		{"Zero threads", func(params *entity.KDFParams) { params.Threads = 0 }, ErrBadKDFParams},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		params := good
+		test.modify(&params)
+
+		assert.Equal(t, test.want, ValidateKDFParams(params))
+	}
+}
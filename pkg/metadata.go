@@ -0,0 +1,212 @@
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// encryptedMetadataPrefix starts metadata encrypted by client. Metadata without it is stored in clear.
+const encryptedMetadataPrefix = "gkm1:"
+
+// encryptedLabelPrefix starts tag or name of folder encrypted by client.
+const encryptedLabelPrefix = "gkl1:"
+
+// Contexts separate keys of label encryption from record key.
+var (
+	labelKeyContext   = []byte("gophkeeper label key")
+	labelNonceContext = []byte("gophkeeper label nonce")
+)
+
+// blindIndexSize is size of word hash in bytes.
+const blindIndexSize = 16
+
+// blindIndexContext separates key of blind index from record key.
+var blindIndexContext = []byte("gophkeeper blind index")
+
+// Errors of metadata encryption.
+var (
+	ErrBadEncryptedMetadata = errors.New("encrypted metadata is malformed")
+	ErrPlainMetadata        = errors.New("metadata isn't encrypted")
+)
+
+// IsEncryptedMetadata checks if metadata was encrypted by client.
+func IsEncryptedMetadata(metadata string) bool {
+	return strings.HasPrefix(metadata, encryptedMetadataPrefix)
+}
+
+// EncryptMetadata encrypts metadata into envelope, so server sees only opaque text. Empty metadata stays empty.
+func EncryptMetadata(key []byte, metadata string) (string, error) {
+	if metadata == "" {
+		return "", nil
+	}
+
+	encrypted, err := EncryptBytes(key, []byte(metadata))
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedMetadataPrefix + base64.RawStdEncoding.EncodeToString(encrypted), nil
+}
+
+// DecryptMetadata decrypts metadata encrypted by EncryptMetadata. Empty metadata stays empty,
+// metadata in clear is ErrPlainMetadata, so server can't substitute it.
+func DecryptMetadata(key []byte, metadata string) (string, error) {
+	if metadata == "" {
+		return "", nil
+	}
+	if !IsEncryptedMetadata(metadata) {
+		return "", ErrPlainMetadata
+	}
+
+	encrypted, err := base64.RawStdEncoding.DecodeString(metadata[len(encryptedMetadataPrefix):])
+	if err != nil {
+		return "", ErrBadEncryptedMetadata
+	}
+
+	decrypted, err := DecryptBytes(key, encrypted)
+	if err != nil {
+		return "", err
+	}
+
+	return string(decrypted), nil
+}
+
+// IsEncryptedLabel checks if tag or folder was encrypted by client.
+func IsEncryptedLabel(label string) bool {
+	return strings.HasPrefix(label, encryptedLabelPrefix)
+}
+
+// EncryptLabel encrypts tag or name of folder. Nonce is keyed hash of label, so equal labels have equal
+// ciphertexts and server can filter records by them, but can't read them. Ciphertext is in lowercase hex,
+// so normalization of tags and folders on server keeps it. Empty label stays empty.
+func EncryptLabel(key []byte, label string) (string, error) {
+	if label == "" {
+		return "", nil
+	}
+
+	aead, err := newAEAD(labelKey(key, labelKeyContext))
+	if err != nil {
+		return "", err
+	}
+
+	nonce := labelNonce(key, label, aead.NonceSize())
+	sealed := aead.Seal(nonce, nonce, []byte(label), nil)
+
+	return encryptedLabelPrefix + hex.EncodeToString(sealed), nil
+}
+
+// DecryptLabel decrypts label encrypted by EncryptLabel. Empty label stays empty, label in clear is ErrPlainMetadata.
+func DecryptLabel(key []byte, label string) (string, error) {
+	if label == "" {
+		return "", nil
+	}
+	if !IsEncryptedLabel(label) {
+		return "", ErrPlainMetadata
+	}
+
+	sealed, err := hex.DecodeString(label[len(encryptedLabelPrefix):])
+	if err != nil {
+		return "", ErrBadEncryptedMetadata
+	}
+
+	aead, err := newAEAD(labelKey(key, labelKeyContext))
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrBadEncryptedMetadata
+	}
+
+	nonce := sealed[:aead.NonceSize()]
+	plain, err := aead.Open(nil, nonce, sealed[aead.NonceSize():], nil)
+	if err != nil || !hmac.Equal(nonce, labelNonce(key, string(plain), aead.NonceSize())) {
+		return "", ErrEnvelopeCorrupted
+	}
+
+	return string(plain), nil
+}
+
+// EncryptFolder encrypts every name of folder path, so subfolders stay subfolders on server.
+func EncryptFolder(key []byte, folder string) (string, error) {
+	return mapFolder(folder, func(name string) (string, error) { return EncryptLabel(key, name) })
+}
+
+// DecryptFolder decrypts folder path encrypted by EncryptFolder.
+func DecryptFolder(key []byte, folder string) (string, error) {
+	return mapFolder(folder, func(name string) (string, error) { return DecryptLabel(key, name) })
+}
+
+// mapFolder converts every name of folder path.
+func mapFolder(folder string, convert func(name string) (string, error)) (string, error) {
+	if folder == "" {
+		return "", nil
+	}
+
+	names := strings.Split(folder, "/")
+	for i, name := range names {
+		converted, err := convert(name)
+		if err != nil {
+			return "", err
+		}
+
+		names[i] = converted
+	}
+
+	return strings.Join(names, "/"), nil
+}
+
+// labelKey derives key for context of label encryption.
+func labelKey(key, context []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(context)
+
+	return mac.Sum(nil)
+}
+
+// labelNonce is keyed hash of label.
+func labelNonce(key []byte, label string, size int) []byte {
+	mac := hmac.New(sha256.New, labelKey(key, labelNonceContext))
+	mac.Write([]byte(label))
+
+	return mac.Sum(nil)[:size]
+}
+
+// BlindIndex gets keyed hashes of words of text, sorted and without repeats. Words are compared case-insensitively,
+// so server can find record by whole word without knowing it. Hashes don't reveal words, but equal words of
+// different records have equal hashes.
+func BlindIndex(key []byte, text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return nil
+	}
+
+	indexKey := hmac.New(sha256.New, key)
+	indexKey.Write(blindIndexContext)
+
+	mac := hmac.New(sha256.New, indexKey.Sum(nil))
+	seen := make(map[string]bool, len(words))
+	hashes := make([]string, 0, len(words))
+
+	for _, word := range words {
+		mac.Reset()
+		mac.Write([]byte(word))
+
+		hash := hex.EncodeToString(mac.Sum(nil)[:blindIndexSize])
+		if !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+
+	sort.Strings(hashes)
+
+	return hashes
+}
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptMetadata(t *testing.T) {
+	key, otherKey := bytes.Repeat([]byte{0x01}, 32), bytes.Repeat([]byte{0x02}, 32)
+
+	encrypted, err := EncryptMetadata(key, "/home/bob/passport.pdf")
+	assert.NoError(t, err)
+	assert.True(t, IsEncryptedMetadata(encrypted))
+	assert.NotContains(t, encrypted, "passport")
+
+	again, err := EncryptMetadata(key, "/home/bob/passport.pdf")
+	assert.NoError(t, err)
+	assert.NotEqual(t, encrypted, again)
+
+	decrypted, err := DecryptMetadata(key, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "/home/bob/passport.pdf", decrypted)
+
+	_, err = DecryptMetadata(otherKey, encrypted)
+	assert.ErrorIs(t, err, ErrEnvelopeCorrupted)
+
+	_, err = DecryptMetadata(key, encrypted[:len(encrypted)-1]+"!")
+	assert.ErrorIs(t, err, ErrBadEncryptedMetadata)
+
+	empty, err := EncryptMetadata(key, "")
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = DecryptMetadata(key, "plain metadata")
+	assert.ErrorIs(t, err, ErrPlainMetadata)
+
+	empty, err = DecryptMetadata(key, "")
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestEncryptLabel(t *testing.T) {
+	key, otherKey := bytes.Repeat([]byte{0x01}, 32), bytes.Repeat([]byte{0x02}, 32)
+
+	encrypted, err := EncryptLabel(key, "Bank")
+	assert.NoError(t, err)
+	assert.True(t, IsEncryptedLabel(encrypted))
+	assert.NotContains(t, strings.ToLower(encrypted), "bank")
+	assert.Equal(t, strings.ToLower(encrypted), encrypted, "server lowercases tags")
+
+	again, err := EncryptLabel(key, "Bank")
+	assert.NoError(t, err)
+	assert.Equal(t, encrypted, again, "equal labels are found by server")
+
+	decrypted, err := DecryptLabel(key, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bank", decrypted)
+
+	_, err = DecryptLabel(otherKey, encrypted)
+	assert.ErrorIs(t, err, ErrEnvelopeCorrupted)
+
+	_, err = DecryptLabel(key, encrypted+"zz")
+	assert.ErrorIs(t, err, ErrBadEncryptedMetadata)
+
+	_, err = DecryptLabel(key, "bank")
+	assert.ErrorIs(t, err, ErrPlainMetadata)
+
+	folder, err := EncryptFolder(key, "work/banks")
+	assert.NoError(t, err)
+	parent, err := EncryptFolder(key, "work")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(folder, parent+"/"), "subfolder stays subfolder")
+
+	decrypted, err = DecryptFolder(key, folder)
+	assert.NoError(t, err)
+	assert.Equal(t, "work/banks", decrypted)
+
+	_, err = DecryptFolder(key, parent+"/banks")
+	assert.ErrorIs(t, err, ErrPlainMetadata)
+}
+
+func TestBlindIndex(t *testing.T) {
+	key, otherKey := bytes.Repeat([]byte{0x01}, 32), bytes.Repeat([]byte{0x02}, 32)
+
+	index := BlindIndex(key, "My Bank, main account; bank")
+	assert.Len(t, index, 4)
+	assert.Subset(t, index, BlindIndex(key, "BANK account"))
+	assert.NotContains(t, strings.Join(index, ","), "bank")
+	assert.NotEqual(t, index, BlindIndex(otherKey, "My Bank, main account; bank"))
+	assert.Nil(t, BlindIndex(key, " ,; "))
+}
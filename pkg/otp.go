@@ -0,0 +1,199 @@
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Types of one-time password keys.
+const (
+	OTPTypeTOTP = "totp" // RFC 6238, time-based.
+	OTPTypeHOTP = "hotp" // RFC 4226, counter-based.
+)
+
+// Hash algorithms of one-time password keys.
+const (
+	OTPAlgorithmSHA1   = "SHA1"
+	OTPAlgorithmSHA256 = "SHA256"
+	OTPAlgorithmSHA512 = "SHA512"
+)
+
+// Default parameters of one-time password keys.
+const (
+	OTPDigits = 6
+	OTPPeriod = 30 * time.Second
+
+	otpScheme    = "otpauth"
+	otpMinDigits = 6
+	otpMaxDigits = 8
+)
+
+// ErrBadOTPURI means that otpauth URI is malformed or has unsupported parameters.
+var ErrBadOTPURI = errors.New("otpauth URI is malformed or unsupported")
+
+// OTPKey is key of one-time passwords from otpauth:// URI.
+type OTPKey struct {
+	Type      string
+	Issuer    string
+	Account   string
+	Secret    []byte
+	Algorithm string
+	Digits    int
+	// Period is lifetime of TOTP code.
+	Period time.Duration
+	// Counter is counter of next HOTP code.
+	Counter uint64
+}
+
+// ParseOTPURI parses otpauth://TYPE/LABEL?PARAMETERS URI (Google Authenticator key URI format).
+func ParseOTPURI(uri string) (OTPKey, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil || u.Scheme != otpScheme {
+		return OTPKey{}, ErrBadOTPURI
+	}
+
+	query := u.Query()
+	key := OTPKey{
+		Type:      strings.ToLower(u.Host),
+		Issuer:    query.Get("issuer"),
+		Algorithm: OTPAlgorithmSHA1,
+		Digits:    OTPDigits,
+		Period:    OTPPeriod,
+	}
+
+	// Label is "issuer:account" or "account".
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, ok := strings.Cut(label, ":"); ok {
+		if key.Issuer == "" {
+			key.Issuer = issuer
+		}
+		label = account
+	}
+	key.Account = strings.TrimSpace(label)
+
+	secret := strings.ToUpper(strings.ReplaceAll(query.Get("secret"), " ", ""))
+	key.Secret, err = base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.TrimRight(secret, "="))
+	if err != nil || len(key.Secret) == 0 {
+		return OTPKey{}, fmt.Errorf("%w: bad secret", ErrBadOTPURI)
+	}
+
+	if algorithm := query.Get("algorithm"); algorithm != "" {
+		key.Algorithm = strings.ToUpper(algorithm)
+	}
+	if digits := query.Get("digits"); digits != "" {
+		if key.Digits, err = strconv.Atoi(digits); err != nil {
+			return OTPKey{}, fmt.Errorf("%w: bad digits", ErrBadOTPURI)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		seconds, err := strconv.Atoi(period)
+		if err != nil || seconds <= 0 {
+			return OTPKey{}, fmt.Errorf("%w: bad period", ErrBadOTPURI)
+		}
+		key.Period = time.Duration(seconds) * time.Second
+	}
+
+	switch key.Type {
+	case OTPTypeTOTP:
+	case OTPTypeHOTP:
+		if key.Counter, err = strconv.ParseUint(query.Get("counter"), 10, 64); err != nil {
+			return OTPKey{}, fmt.Errorf("%w: bad counter", ErrBadOTPURI)
+		}
+	default:
+		return OTPKey{}, fmt.Errorf("%w: unknown type %q", ErrBadOTPURI, key.Type)
+	}
+
+	if key.hash() == nil {
+		return OTPKey{}, fmt.Errorf("%w: unknown algorithm %q", ErrBadOTPURI, key.Algorithm)
+	}
+	if key.Digits < otpMinDigits || key.Digits > otpMaxDigits {
+		return OTPKey{}, fmt.Errorf("%w: bad digits", ErrBadOTPURI)
+	}
+
+	return key, nil
+}
+
+// URI returns otpauth:// URI of key, it's used to save HOTP key with new counter.
+func (k OTPKey) URI() string {
+	query := url.Values{}
+	query.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret))
+	query.Set("algorithm", k.Algorithm)
+	query.Set("digits", strconv.Itoa(k.Digits))
+
+	if k.Issuer != "" {
+		query.Set("issuer", k.Issuer)
+	}
+	if k.Type == OTPTypeHOTP {
+		query.Set("counter", strconv.FormatUint(k.Counter, 10))
+	} else {
+		query.Set("period", strconv.Itoa(int(k.Period/time.Second)))
+	}
+
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + k.Account
+	}
+
+	u := url.URL{Scheme: otpScheme, Host: k.Type, Path: "/" + label, RawQuery: query.Encode()}
+
+	return u.String()
+}
+
+// Code returns current code of key. For TOTP it's code at time t and time remaining until it expires,
+// for HOTP it's code of current counter and zero duration.
+func (k OTPKey) Code(t time.Time) (string, time.Duration) {
+	if k.Type == OTPTypeHOTP {
+		return k.HOTP(k.Counter), 0
+	}
+
+	step := t.Unix() / int64(k.Period/time.Second)
+	expiresAt := time.Unix((step+1)*int64(k.Period/time.Second), 0)
+
+	return k.HOTP(uint64(step)), expiresAt.Sub(t)
+}
+
+// HOTP returns code of counter (RFC 4226).
+func (k OTPKey) HOTP(counter uint64) string {
+	message := make([]byte, 8)
+	binary.BigEndian.PutUint64(message, counter)
+
+	mac := hmac.New(k.hash(), k.Secret)
+	mac.Write(message)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation.
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < k.Digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", k.Digits, code%modulo)
+}
+
+// hash returns hash function of key algorithm or nil, if algorithm is unknown.
+func (k OTPKey) hash() func() hash.Hash {
+	switch k.Algorithm {
+	case OTPAlgorithmSHA1:
+		return sha1.New
+	case OTPAlgorithmSHA256:
+		return sha256.New
+	case OTPAlgorithmSHA512:
+		return sha512.New
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// otpSecret encodes test secret of RFC to base32.
+func otpSecret(secret string) string {
+	return base32.StdEncoding.EncodeToString([]byte(secret))
+}
+
+func TestParseOTPURI(t *testing.T) {
+	secret := otpSecret("12345678901234567890")
+
+	tc := []struct {
+		name string
+		uri  string
+		key  OTPKey
+		want error
+	}{
+		{
+			name: "TOTP with defaults",
+			uri:  "otpauth://totp/Example:alice@example.com?secret=" + secret + "&issuer=Example",
+			key: OTPKey{
+				Type: OTPTypeTOTP, Issuer: "Example", Account: "alice@example.com", Secret: []byte("12345678901234567890"),
+				Algorithm: OTPAlgorithmSHA1, Digits: 6, Period: 30 * time.Second,
+			},
+		},
+		{
+			name: "TOTP with parameters and issuer in label",
+			uri:  "otpauth://totp/Bank:bob?secret=" + secret + "&algorithm=sha256&digits=8&period=60",
+			key: OTPKey{
+				Type: OTPTypeTOTP, Issuer: "Bank", Account: "bob", Secret: []byte("12345678901234567890"),
+				Algorithm: OTPAlgorithmSHA256, Digits: 8, Period: time.Minute,
+			},
+		},
+		{
+			name: "HOTP",
+			uri:  "otpauth://hotp/bob?secret=" + secret + "&counter=5",
+			key: OTPKey{
+				Type: OTPTypeHOTP, Account: "bob", Secret: []byte("12345678901234567890"),
+				Algorithm: OTPAlgorithmSHA1, Digits: 6, Period: 30 * time.Second, Counter: 5,
+			},
+		},
+		{name: "Other scheme", uri: "https://example.com", want: ErrBadOTPURI},
+		{name: "Unknown type", uri: "otpauth://motp/bob?secret=" + secret, want: ErrBadOTPURI},
+		{name: "Without secret", uri: "otpauth://totp/bob", want: ErrBadOTPURI},
+		{name: "Bad secret", uri: "otpauth://totp/bob?secret=1!", want: ErrBadOTPURI},
+		{name: "HOTP without counter", uri: "otpauth://hotp/bob?secret=" + secret, want: ErrBadOTPURI},
+		{name: "Unknown algorithm", uri: "otpauth://totp/bob?algorithm=MD5&secret=" + secret, want: ErrBadOTPURI},
+		{name: "Too many digits", uri: "otpauth://totp/bob?digits=12&secret=" + secret, want: ErrBadOTPURI},
+		{name: "Bad period", uri: "otpauth://totp/bob?period=0&secret=" + secret, want: ErrBadOTPURI},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		key, err := ParseOTPURI(test.uri)
+		assert.ErrorIs(t, err, test.want)
+		assert.Equal(t, test.key, key)
+
+		if test.want != nil {
+			continue
+		}
+
+		parsed, err := ParseOTPURI(key.URI())
+		assert.NoError(t, err)
+		assert.Equal(t, key, parsed)
+	}
+}
+
+func TestOTPKey_HOTP(t *testing.T) {
+	// Test values of RFC 4226, appendix D.
+	key := OTPKey{Type: OTPTypeHOTP, Secret: []byte("12345678901234567890"), Algorithm: OTPAlgorithmSHA1, Digits: 6}
+	codes := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range codes {
+		assert.Equal(t, code, key.HOTP(uint64(counter)))
+	}
+
+	key.Counter = 3
+	code, remaining := key.Code(time.Now())
+	assert.Equal(t, "969429", code)
+	assert.Zero(t, remaining)
+}
+
+func TestOTPKey_Code(t *testing.T) {
+	// Test values of RFC 6238, appendix B.
+	secrets := map[string][]byte{
+		OTPAlgorithmSHA1:   []byte("12345678901234567890"),
+		OTPAlgorithmSHA256: []byte("12345678901234567890123456789012"),
+		OTPAlgorithmSHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+
+	tc := []struct {
+		time      int64
+		algorithm string
+		code      string
+	}{
+		{59, OTPAlgorithmSHA1, "94287082"},
+		{59, OTPAlgorithmSHA256, "46119246"},
+		{59, OTPAlgorithmSHA512, "90693936"},
+		{1111111109, OTPAlgorithmSHA1, "07081804"},
+		{1111111111, OTPAlgorithmSHA256, "67062674"},
+		{1234567890, OTPAlgorithmSHA512, "93441116"},
+		{2000000000, OTPAlgorithmSHA1, "69279037"},
+	}
+
+	for _, test := range tc {
+		key := OTPKey{
+			Type:      OTPTypeTOTP,
+			Secret:    secrets[test.algorithm],
+			Algorithm: test.algorithm,
+			Digits:    8,
+			Period:    OTPPeriod,
+		}
+
+		code, remaining := key.Code(time.Unix(test.time, 0))
+		assert.Equal(t, test.code, code, "%s at %d", test.algorithm, test.time)
+		assert.Equal(t, time.Duration(30-test.time%30)*time.Second, remaining)
+	}
+}
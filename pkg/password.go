@@ -0,0 +1,227 @@
+package pkg
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Names of supported password hashing algorithms.
+const (
+	PasswordArgon2id = "argon2id"
+	PasswordBcrypt   = "bcrypt"
+)
+
+// Default parameters of password hashing.
+const (
+	PasswordArgon2Time    = 3
+	PasswordArgon2Memory  = 64 * 1024
+	PasswordArgon2Threads = 4
+	PasswordBcryptCost    = 12
+
+	passwordSaltSize = 16
+	passwordHashSize = 32
+)
+
+// Errors of password hashing.
+var (
+	ErrUnknownPasswordHasher = errors.New("unknown password hashing algorithm")
+	ErrBadPasswordHash       = errors.New("password hash is malformed or unsupported")
+)
+
+// PasswordHasher hashes passwords and verifies them against stored hashes.
+// Stored hash is encoded with algorithm and its parameters, so parameters can be changed any time.
+type PasswordHasher interface {
+	// Hash returns encoded hash of password with new random salt.
+	Hash(password string) (string, error)
+	// Verify checks password against encoded hash made by any supported algorithm.
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash reports if encoded hash was made by other algorithm or with other parameters.
+	NeedsRehash(encoded string) bool
+}
+
+// NewPasswordHasher returns hasher for algorithm with default parameters. Empty algorithm means argon2id.
+func NewPasswordHasher(algorithm string) (PasswordHasher, error) {
+	switch algorithm {
+	case "", PasswordArgon2id:
+		return NewArgon2idHasher(PasswordArgon2Time, PasswordArgon2Memory, PasswordArgon2Threads), nil
+	case PasswordBcrypt:
+		return NewBcryptHasher(PasswordBcryptCost), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPasswordHasher, algorithm)
+	}
+}
+
+// NewArgon2idHasher returns hasher, which makes new hashes by Argon2id with given parameters.
+func NewArgon2idHasher(time, memory uint32, threads uint8) PasswordHasher {
+	return &passwordHasher{
+		algorithm: PasswordArgon2id,
+		argon2: argon2Params{
+			time:    time,
+			memory:  memory,
+			threads: threads,
+		},
+	}
+}
+
+// NewBcryptHasher returns hasher, which makes new hashes by bcrypt with given cost.
+func NewBcryptHasher(cost int) PasswordHasher {
+	return &passwordHasher{
+		algorithm:  PasswordBcrypt,
+		bcryptCost: cost,
+	}
+}
+
+// argon2Params are parameters of Argon2id password hash.
+type argon2Params struct {
+	time, memory uint32
+	threads      uint8
+}
+
+// passwordHasher makes hashes by chosen algorithm and verifies hashes of all supported algorithms.
+type passwordHasher struct {
+	algorithm  string
+	argon2     argon2Params
+	bcryptCost int
+}
+
+// Hash implementation of PasswordHasher interface.
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.algorithm == PasswordBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.bcryptCost)
+		if err != nil {
+			return "", err
+		}
+
+		return string(hash), nil
+	}
+
+	salt, err := GenerateRandom(passwordSaltSize)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeArgon2id(h.argon2, salt, argon2.IDKey(
+		[]byte(password), salt, h.argon2.time, h.argon2.memory, h.argon2.threads, passwordHashSize,
+	)), nil
+}
+
+// Verify implementation of PasswordHasher interface.
+func (h *passwordHasher) Verify(encoded, password string) (bool, error) {
+	if isBcryptHash(encoded) {
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, ErrBadPasswordHash
+		}
+
+		return true, nil
+	}
+
+	params, salt, hash, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(hash)))
+
+	return subtle.ConstantTimeCompare(hash, other) == 1, nil
+}
+
+// NeedsRehash implementation of PasswordHasher interface.
+func (h *passwordHasher) NeedsRehash(encoded string) bool {
+	if h.algorithm == PasswordBcrypt {
+		if !isBcryptHash(encoded) {
+			return true
+		}
+
+		cost, err := bcrypt.Cost([]byte(encoded))
+
+		return err != nil || cost != h.bcryptCost
+	}
+
+	params, _, hash, err := decodeArgon2id(encoded)
+
+	return err != nil || params != h.argon2 || len(hash) != passwordHashSize
+}
+
+// isBcryptHash checks if hash is in modular crypt format of bcrypt.
+func isBcryptHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+// encodeArgon2id encodes hash in PHC string format: $argon2id$v=19$m=65536,t=3,p=4$salt$hash.
+func encodeArgon2id(params argon2Params, salt, hash []byte) string {
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		PasswordArgon2id,
+		argon2.Version,
+		params.memory,
+		params.time,
+		params.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	)
+}
+
+// decodeArgon2id parses hash in PHC string format.
+func decodeArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var (
+		params  argon2Params
+		version int
+	)
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != PasswordArgon2id {
+		return params, nil, nil, ErrBadPasswordHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrBadPasswordHash
+	}
+
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads)
+	if err != nil || params.time == 0 || params.memory == 0 || params.threads == 0 {
+		return params, nil, nil, ErrBadPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return params, nil, nil, ErrBadPasswordHash
+	}
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash) == 0 {
+		return params, nil, nil, ErrBadPasswordHash
+	}
+
+	return params, salt, hash, nil
+}
+
+// IsLegacyPasswordHash checks if hash was made by PasswordHash.
+func IsLegacyPasswordHash(encoded string) bool {
+	if len(encoded) != 2*32 {
+		return false
+	}
+
+	_, err := hex.DecodeString(encoded)
+
+	return err == nil
+}
+
+// VerifyLegacyPasswordHash checks credentials against hash made by PasswordHash.
+func VerifyLegacyPasswordHash(encoded string, credentials entity.UserCredentials) bool {
+	return subtle.ConstantTimeCompare([]byte(encoded), []byte(PasswordHash(credentials))) == 1
+}
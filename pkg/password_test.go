@@ -0,0 +1,115 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-keep/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestNewPasswordHasher(t *testing.T) {
+	tc := []struct {
+		name      string
+		algorithm string
+		prefix    string
+		want      error
+	}{
+		{"Default algorithm", "", "$argon2id$v=19$m=65536,t=3,p=4$", nil},
+		{"Argon2id", PasswordArgon2id, "$argon2id$v=19$m=65536,t=3,p=4$", nil},
+		{"Bcrypt", PasswordBcrypt, "$2a$12$", nil},
+		{"Unknown algorithm", "md5", "", ErrUnknownPasswordHasher},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		hasher, err := NewPasswordHasher(test.algorithm)
+		assert.ErrorIs(t, err, test.want)
+		if test.want != nil {
+			continue
+		}
+
+		hash, err := hasher.Hash("password")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hash, test.prefix), hash)
+	}
+}
+
+func TestPasswordHasher(t *testing.T) {
+	hashers := map[string]PasswordHasher{
+		"Argon2id": NewArgon2idHasher(1, 1024, 1),
+		"Bcrypt":   NewBcryptHasher(bcrypt.MinCost),
+	}
+
+	for name, hasher := range hashers {
+		t.Log(name)
+
+		hash, err := hasher.Hash("password")
+		assert.NoError(t, err)
+		assert.False(t, hasher.NeedsRehash(hash))
+
+		other, err := hasher.Hash("password")
+		assert.NoError(t, err)
+		assert.NotEqual(t, hash, other)
+
+		valid, err := hasher.Verify(hash, "password")
+		assert.NoError(t, err)
+		assert.True(t, valid)
+
+		valid, err = hasher.Verify(hash, "wrong")
+		assert.NoError(t, err)
+		assert.False(t, valid)
+	}
+}
+
+func TestPasswordHasher_Verify(t *testing.T) {
+	argon2Hash, err := NewArgon2idHasher(1, 1024, 1).Hash("password")
+	assert.NoError(t, err)
+
+	bcryptHash, err := NewBcryptHasher(bcrypt.MinCost).Hash("password")
+	assert.NoError(t, err)
+
+	hasher := NewArgon2idHasher(2, 2048, 1)
+
+	tc := []struct {
+		name   string
+		hash   string
+		valid  bool
+		rehash bool
+		want   error
+	}{
+		{"Hash with other parameters", argon2Hash, true, true, nil},
+		{"Hash of other algorithm", bcryptHash, true, true, nil},
+		{"Malformed argon2id hash", "$argon2id$v=19$m=1024,t=1,p=1$salt", false, true, ErrBadPasswordHash},
+		{"Bad version", strings.Replace(argon2Hash, "v=19", "v=16", 1), false, true, ErrBadPasswordHash},
+		{"Bad bcrypt hash", "$2a$04$broken", false, true, ErrBadPasswordHash},
+		{"Unknown hash", "plain password", false, true, ErrBadPasswordHash},
+	}
+
+	for _, test := range tc {
+		t.Log(test.name)
+
+		valid, err := hasher.Verify(test.hash, "password")
+		assert.Equal(t, test.want, err)
+		assert.Equal(t, test.valid, valid)
+		assert.Equal(t, test.rehash, hasher.NeedsRehash(test.hash))
+	}
+}
+
+func TestLegacyPasswordHash(t *testing.T) {
+	credentials := entity.UserCredentials{Login: "admin", Password: "password"}
+	hash := "749f09bade8aca755660eeb17792da880218d4fbdc4e25fbec279d7fe9f65d70"
+
+	assert.Equal(t, hash, PasswordHash(credentials))
+	assert.True(t, IsLegacyPasswordHash(hash))
+	assert.True(t, VerifyLegacyPasswordHash(hash, credentials))
+
+	credentials.Password = "wrong"
+	assert.False(t, VerifyLegacyPasswordHash(hash, credentials))
+
+	assert.False(t, IsLegacyPasswordHash("$2a$04$broken"))
+	assert.False(t, IsLegacyPasswordHash(strings.Repeat("z", 64)))
+}
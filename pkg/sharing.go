@@ -0,0 +1,179 @@
+package pkg
+
+import (
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Wrapped key format: ephemeral X25519 public key (32 bytes) | envelope with key.
+//
+// Key of envelope is derived by HKDF from X25519 shared secret of ephemeral key and recipient key,
+// both public keys are HKDF salt, so wrapped key can be opened only by recipient.
+const (
+	VaultKeySize     = 32
+	PublicKeySize    = 32
+	wrappedKeyPrefix = PublicKeySize
+	fingerprintSize  = 16
+)
+
+// Contexts separate keys derived from record key and shared secrets.
+var (
+	keyPairContext   = []byte("gophkeeper x25519 key pair")
+	wrapKeyContext   = []byte("gophkeeper vault key wrap")
+	memberKeyContext = []byte("gophkeeper vault member key")
+)
+
+// Errors of key sharing.
+var (
+	ErrBadPublicKey  = errors.New("public key is malformed")
+	ErrBadWrappedKey = errors.New("wrapped key is malformed")
+)
+
+// DeriveKeyPair derives X25519 key pair of user from record key, so private key needn't be stored anywhere.
+func DeriveKeyPair(key []byte) (*ecdh.PrivateKey, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(keyPairContext)
+
+	return ecdh.X25519().NewPrivateKey(mac.Sum(nil))
+}
+
+// PublicKey gets X25519 public key of user, which is derived from record key.
+func PublicKey(key []byte) ([]byte, error) {
+	private, err := DeriveKeyPair(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return private.PublicKey().Bytes(), nil
+}
+
+// KeyFingerprint is short hash of public key in groups of hex digits, which users compare
+// by another channel, so server can't substitute public key of user.
+func KeyFingerprint(publicKey []byte) string {
+	sum := sha256.Sum256(publicKey)
+	digits := hex.EncodeToString(sum[:fingerprintSize])
+
+	groups := make([]string, 0, len(digits)/4)
+	for i := 0; i < len(digits); i += 4 {
+		groups = append(groups, digits[i:i+4])
+	}
+
+	return strings.Join(groups, " ")
+}
+
+// CheckFingerprint reports if fingerprint, which is typed by user, is fingerprint of public key.
+// Spaces, colons and case are ignored.
+func CheckFingerprint(publicKey []byte, fingerprint string) bool {
+	typed := strings.ToLower(strings.NewReplacer(" ", "", ":", "").Replace(fingerprint))
+	expected := strings.ReplaceAll(KeyFingerprint(publicKey), " ", "")
+
+	return hmac.Equal([]byte(typed), []byte(expected))
+}
+
+// MemberKeyMAC authenticates public key of vault member by record key of owner, so key, which was checked
+// by fingerprint once, is recognized later. Every field is prefixed by its length.
+func MemberKeyMAC(key []byte, vaultID, login string, publicKey []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(memberKeyContext)
+
+	for _, field := range [][]byte{[]byte(vaultID), []byte(login), publicKey} {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(field)))
+		mac.Write(size[:])
+		mac.Write(field)
+	}
+
+	return mac.Sum(nil)
+}
+
+// CheckMemberKeyMAC reports if public key of vault member is authenticated by MemberKeyMAC.
+func CheckMemberKeyMAC(key []byte, vaultID, login string, publicKey, keyMAC []byte) bool {
+	return hmac.Equal(MemberKeyMAC(key, vaultID, login, publicKey), keyMAC)
+}
+
+// NewVaultKey generates random symmetric key of shared vault.
+func NewVaultKey() ([]byte, error) {
+	return GenerateRandom(VaultKeySize)
+}
+
+// WrapKey encrypts vault key for user with public key, so only this user can decrypt it.
+func WrapKey(publicKey, vaultKey []byte) ([]byte, error) {
+	recipient, err := ecdh.X25519().NewPublicKey(publicKey)
+	if err != nil {
+		return nil, ErrBadPublicKey
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return nil, ErrBadPublicKey
+	}
+
+	wrapKey, err := sharedKey(secret, ephemeral.PublicKey().Bytes(), publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := EncryptBytes(wrapKey, vaultKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ephemeral.PublicKey().Bytes(), sealed...), nil
+}
+
+// UnwrapKey decrypts vault key wrapped for user with record key.
+func UnwrapKey(key, wrapped []byte) ([]byte, error) {
+	if len(wrapped) <= wrappedKeyPrefix {
+		return nil, ErrBadWrappedKey
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(wrapped[:wrappedKeyPrefix])
+	if err != nil {
+		return nil, ErrBadWrappedKey
+	}
+
+	private, err := DeriveKeyPair(key)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := private.ECDH(ephemeral)
+	if err != nil {
+		return nil, ErrBadWrappedKey
+	}
+
+	wrapKey, err := sharedKey(secret, ephemeral.Bytes(), private.PublicKey().Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return DecryptBytes(wrapKey, wrapped[wrappedKeyPrefix:])
+}
+
+// sharedKey derives key, which encrypts wrapped key, from X25519 shared secret and public keys.
+func sharedKey(secret, ephemeral, recipient []byte) ([]byte, error) {
+	salt := make([]byte, 0, len(ephemeral)+len(recipient))
+	salt = append(salt, ephemeral...)
+	salt = append(salt, recipient...)
+
+	key := make([]byte, VaultKeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, wrapKeyContext), key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
@@ -0,0 +1,74 @@
+package pkg
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrapKey(t *testing.T) {
+	aliceKey, bobKey := bytes.Repeat([]byte{0x01}, 32), bytes.Repeat([]byte{0x02}, 32)
+
+	alicePublic, err := PublicKey(aliceKey)
+	assert.NoError(t, err)
+	assert.Len(t, alicePublic, 32)
+
+	again, err := PublicKey(aliceKey)
+	assert.NoError(t, err)
+	assert.Equal(t, alicePublic, again)
+
+	vaultKey, err := NewVaultKey()
+	assert.NoError(t, err)
+	assert.Len(t, vaultKey, VaultKeySize)
+
+	wrapped, err := WrapKey(alicePublic, vaultKey)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(wrapped), string(vaultKey))
+
+	unwrapped, err := UnwrapKey(aliceKey, wrapped)
+	assert.NoError(t, err)
+	assert.Equal(t, vaultKey, unwrapped)
+
+	_, err = UnwrapKey(bobKey, wrapped)
+	assert.ErrorIs(t, err, ErrEnvelopeCorrupted)
+
+	_, err = UnwrapKey(aliceKey, wrapped[:20])
+	assert.ErrorIs(t, err, ErrBadWrappedKey)
+
+	_, err = WrapKey([]byte("short"), vaultKey)
+	assert.ErrorIs(t, err, ErrBadPublicKey)
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	alicePublic, err := PublicKey(bytes.Repeat([]byte{0x01}, 32))
+	assert.NoError(t, err)
+	bobPublic, err := PublicKey(bytes.Repeat([]byte{0x02}, 32))
+	assert.NoError(t, err)
+
+	fingerprint := KeyFingerprint(alicePublic)
+	assert.Len(t, fingerprint, 39)
+	assert.NotEqual(t, fingerprint, KeyFingerprint(bobPublic))
+
+	assert.True(t, CheckFingerprint(alicePublic, fingerprint))
+	assert.True(t, CheckFingerprint(alicePublic, strings.ToUpper(strings.ReplaceAll(fingerprint, " ", ":"))))
+	assert.False(t, CheckFingerprint(bobPublic, fingerprint))
+	assert.False(t, CheckFingerprint(alicePublic, ""))
+}
+
+func TestMemberKeyMAC(t *testing.T) {
+	ownerKey, otherKey := bytes.Repeat([]byte{0x01}, 32), bytes.Repeat([]byte{0x02}, 32)
+	bobPublic, err := PublicKey(otherKey)
+	assert.NoError(t, err)
+
+	keyMAC := MemberKeyMAC(ownerKey, "vaultID", "bob", bobPublic)
+	assert.True(t, CheckMemberKeyMAC(ownerKey, "vaultID", "bob", bobPublic, keyMAC))
+
+	assert.False(t, CheckMemberKeyMAC(otherKey, "vaultID", "bob", bobPublic, keyMAC))
+	assert.False(t, CheckMemberKeyMAC(ownerKey, "otherID", "bob", bobPublic, keyMAC))
+	assert.False(t, CheckMemberKeyMAC(ownerKey, "vaultID", "eve", bobPublic, keyMAC))
+	assert.False(t, CheckMemberKeyMAC(ownerKey, "vaultI", "Dbob", bobPublic, keyMAC))
+	assert.False(t, CheckMemberKeyMAC(ownerKey, "vaultID", "bob", bobPublic[1:], keyMAC))
+	assert.False(t, CheckMemberKeyMAC(ownerKey, "vaultID", "bob", bobPublic, nil))
+}
@@ -0,0 +1,186 @@
+package pkg
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// handshake runs TLS handshake between client and server configs over loopback connection.
+func handshake(t *testing.T, serverCfg, clientCfg *tls.Config) (errServer, errClient error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return err, err
+	}
+	defer listener.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		serverConn, err := listener.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		defer serverConn.Close()
+
+		server := tls.Server(serverConn, serverCfg)
+		err = server.Handshake()
+		if err == nil {
+			// Client verifies server certificate first, server error comes after client finished.
+			_, err = server.Read(make([]byte, 1))
+		}
+		done <- err
+	}()
+
+	clientConn, err := net.Dial("tcp", listener.Addr().String())
+	if !assert.NoError(t, err) {
+		return err, err
+	}
+	defer clientConn.Close()
+
+	client := tls.Client(clientConn, clientCfg)
+	errClient = client.Handshake()
+	if errClient == nil {
+		_, errClient = client.Write([]byte{1})
+	}
+
+	return <-done, errClient
+}
+
+func TestEnsureSelfSignedCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "certs", "server.crt"), filepath.Join(dir, "certs", "server.key")
+
+	created, err := EnsureSelfSignedCert(certFile, keyFile, []string{"localhost", "127.0.0.1"})
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	info, err := os.Stat(keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	first, err := os.ReadFile(certFile)
+	assert.NoError(t, err)
+
+	created, err = EnsureSelfSignedCert(certFile, keyFile, []string{"localhost"})
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	second, err := os.ReadFile(certFile)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+}
+
+func TestTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	serverCert, serverKey := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	clientCert, clientKey := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	otherCert, otherKey := filepath.Join(dir, "other.crt"), filepath.Join(dir, "other.key")
+
+	for _, pair := range [][2]string{{serverCert, serverKey}, {clientCert, clientKey}, {otherCert, otherKey}} {
+		_, err := EnsureSelfSignedCert(pair[0], pair[1], []string{"localhost"})
+		assert.NoError(t, err)
+	}
+
+	tests := []struct {
+		name         string
+		clientCAFile string
+		caFile       string
+		certFile     string
+		keyFile      string
+		serverName   string
+		valid        bool
+	}{
+		{
+			name:       "TLS",
+			caFile:     serverCert,
+			serverName: "localhost",
+			valid:      true,
+		},
+		{
+			name:       "Unknown server certificate",
+			caFile:     otherCert,
+			serverName: "localhost",
+			valid:      false,
+		},
+		{
+			name:       "Wrong server name",
+			caFile:     serverCert,
+			serverName: "example.com",
+			valid:      false,
+		},
+		{
+			name:         "Mutual TLS",
+			clientCAFile: clientCert,
+			caFile:       serverCert,
+			certFile:     clientCert,
+			keyFile:      clientKey,
+			serverName:   "localhost",
+			valid:        true,
+		},
+		{
+			name:         "Mutual TLS without client certificate",
+			clientCAFile: clientCert,
+			caFile:       serverCert,
+			serverName:   "localhost",
+			valid:        false,
+		},
+		{
+			name:         "Mutual TLS with unknown client certificate",
+			clientCAFile: clientCert,
+			caFile:       serverCert,
+			certFile:     otherCert,
+			keyFile:      otherKey,
+			serverName:   "localhost",
+			valid:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverCfg, err := NewServerTLSConfig(serverCert, serverKey, tt.clientCAFile)
+			assert.NoError(t, err)
+
+			clientCfg, err := NewClientTLSConfig(tt.caFile, tt.certFile, tt.keyFile, tt.serverName)
+			assert.NoError(t, err)
+
+			errServer, errClient := handshake(t, serverCfg, clientCfg)
+			if tt.valid {
+				assert.NoError(t, errServer)
+				assert.NoError(t, errClient)
+			} else {
+				assert.True(t, errServer != nil || errClient != nil)
+			}
+		})
+	}
+}
+
+func TestNewClientTLSConfig_BadBundle(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "ca.crt")
+	assert.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0o600))
+
+	_, err := NewClientTLSConfig(bundle, "", "", "")
+	assert.ErrorIs(t, err, ErrNoCertificates)
+
+	_, err = NewServerTLSConfig("missing.crt", "missing.key", "")
+	assert.Error(t, err)
+}
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSelfSignedCert([]string{"localhost", "::1"}, time.Hour)
+	assert.NoError(t, err)
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+	if !assert.NotNil(t, cert.Leaf) {
+		return
+	}
+	assert.Equal(t, []string{"localhost"}, cert.Leaf.DNSNames)
+	assert.Len(t, cert.Leaf.IPAddresses, 1)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), cert.Leaf.NotAfter, time.Minute)
+}
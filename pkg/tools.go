@@ -24,6 +24,8 @@ func GenerateRandom(size int) ([]byte, error) {
 }
 
 // PasswordHash make encryption string.
+//
+// Deprecated: unsalted hash, used only to verify passwords of old users. Use PasswordHasher instead.
 func PasswordHash(credentials entity.UserCredentials) string {
 	sha := sha256.New()
 	sha.Write([]byte(credentials.Login + credentials.Password))
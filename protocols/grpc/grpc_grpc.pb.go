@@ -20,12 +20,35 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Gophkeeper_Register_FullMethodName       = "/gophkeeper.Gophkeeper/Register"
-	Gophkeeper_Login_FullMethodName          = "/gophkeeper.Gophkeeper/Login"
-	Gophkeeper_GetRecordsInfo_FullMethodName = "/gophkeeper.Gophkeeper/GetRecordsInfo"
-	Gophkeeper_GetRecord_FullMethodName      = "/gophkeeper.Gophkeeper/GetRecord"
-	Gophkeeper_CreateRecord_FullMethodName   = "/gophkeeper.Gophkeeper/CreateRecord"
-	Gophkeeper_DeleteRecord_FullMethodName   = "/gophkeeper.Gophkeeper/DeleteRecord"
+	Gophkeeper_Register_FullMethodName             = "/gophkeeper.Gophkeeper/Register"
+	Gophkeeper_Login_FullMethodName                = "/gophkeeper.Gophkeeper/Login"
+	Gophkeeper_GetRecordsInfo_FullMethodName       = "/gophkeeper.Gophkeeper/GetRecordsInfo"
+	Gophkeeper_ListRecords_FullMethodName          = "/gophkeeper.Gophkeeper/ListRecords"
+	Gophkeeper_GetRecord_FullMethodName            = "/gophkeeper.Gophkeeper/GetRecord"
+	Gophkeeper_CreateRecord_FullMethodName         = "/gophkeeper.Gophkeeper/CreateRecord"
+	Gophkeeper_DeleteRecord_FullMethodName         = "/gophkeeper.Gophkeeper/DeleteRecord"
+	Gophkeeper_UploadFile_FullMethodName           = "/gophkeeper.Gophkeeper/UploadFile"
+	Gophkeeper_DownloadFile_FullMethodName         = "/gophkeeper.Gophkeeper/DownloadFile"
+	Gophkeeper_UpdateRecord_FullMethodName         = "/gophkeeper.Gophkeeper/UpdateRecord"
+	Gophkeeper_GetRecordVersions_FullMethodName    = "/gophkeeper.Gophkeeper/GetRecordVersions"
+	Gophkeeper_RestoreRecordVersion_FullMethodName = "/gophkeeper.Gophkeeper/RestoreRecordVersion"
+	Gophkeeper_Sync_FullMethodName                 = "/gophkeeper.Gophkeeper/Sync"
+	Gophkeeper_RefreshSession_FullMethodName       = "/gophkeeper.Gophkeeper/RefreshSession"
+	Gophkeeper_Logout_FullMethodName               = "/gophkeeper.Gophkeeper/Logout"
+	Gophkeeper_RevokeSessions_FullMethodName       = "/gophkeeper.Gophkeeper/RevokeSessions"
+	Gophkeeper_UpgradeKDF_FullMethodName           = "/gophkeeper.Gophkeeper/UpgradeKDF"
+	Gophkeeper_GetPublicKey_FullMethodName         = "/gophkeeper.Gophkeeper/GetPublicKey"
+	Gophkeeper_SetPublicKey_FullMethodName         = "/gophkeeper.Gophkeeper/SetPublicKey"
+	Gophkeeper_CreateVault_FullMethodName          = "/gophkeeper.Gophkeeper/CreateVault"
+	Gophkeeper_GetVaults_FullMethodName            = "/gophkeeper.Gophkeeper/GetVaults"
+	Gophkeeper_GetVaultMembers_FullMethodName      = "/gophkeeper.Gophkeeper/GetVaultMembers"
+	Gophkeeper_InviteMember_FullMethodName         = "/gophkeeper.Gophkeeper/InviteMember"
+	Gophkeeper_RevokeMember_FullMethodName         = "/gophkeeper.Gophkeeper/RevokeMember"
+	Gophkeeper_GetVaultRecords_FullMethodName      = "/gophkeeper.Gophkeeper/GetVaultRecords"
+	Gophkeeper_GetVaultRecord_FullMethodName       = "/gophkeeper.Gophkeeper/GetVaultRecord"
+	Gophkeeper_CreateVaultRecord_FullMethodName    = "/gophkeeper.Gophkeeper/CreateVaultRecord"
+	Gophkeeper_UpdateVaultRecord_FullMethodName    = "/gophkeeper.Gophkeeper/UpdateVaultRecord"
+	Gophkeeper_DeleteVaultRecord_FullMethodName    = "/gophkeeper.Gophkeeper/DeleteVaultRecord"
 )
 
 // GophkeeperClient is the client API for Gophkeeper service.
@@ -35,9 +58,32 @@ type GophkeeperClient interface {
 	Register(ctx context.Context, in *UserCredentials, opts ...grpc.CallOption) (*Session, error)
 	Login(ctx context.Context, in *UserCredentials, opts ...grpc.CallOption) (*Session, error)
 	GetRecordsInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*RecordsList, error)
+	ListRecords(ctx context.Context, in *ListRecordsRequest, opts ...grpc.CallOption) (*RecordsPage, error)
 	GetRecord(ctx context.Context, in *RecordID, opts ...grpc.CallOption) (*Record, error)
-	CreateRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	CreateRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*RecordID, error)
 	DeleteRecord(ctx context.Context, in *RecordID, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	UploadFile(ctx context.Context, opts ...grpc.CallOption) (Gophkeeper_UploadFileClient, error)
+	DownloadFile(ctx context.Context, in *RecordID, opts ...grpc.CallOption) (Gophkeeper_DownloadFileClient, error)
+	UpdateRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetRecordVersions(ctx context.Context, in *RecordID, opts ...grpc.CallOption) (*RecordVersionsList, error)
+	RestoreRecordVersion(ctx context.Context, in *RecordVersion, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*RecordChanges, error)
+	RefreshSession(ctx context.Context, in *RefreshToken, opts ...grpc.CallOption) (*Session, error)
+	Logout(ctx context.Context, in *RefreshToken, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	RevokeSessions(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	UpgradeKDF(ctx context.Context, in *KDFParams, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetPublicKey(ctx context.Context, in *UserLogin, opts ...grpc.CallOption) (*PublicKey, error)
+	SetPublicKey(ctx context.Context, in *PublicKey, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	CreateVault(ctx context.Context, in *Vault, opts ...grpc.CallOption) (*Vault, error)
+	GetVaults(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VaultsList, error)
+	GetVaultMembers(ctx context.Context, in *Vault, opts ...grpc.CallOption) (*VaultMembersList, error)
+	InviteMember(ctx context.Context, in *VaultMember, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	RevokeMember(ctx context.Context, in *VaultRotation, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	GetVaultRecords(ctx context.Context, in *Vault, opts ...grpc.CallOption) (*RecordsList, error)
+	GetVaultRecord(ctx context.Context, in *VaultRecordID, opts ...grpc.CallOption) (*Record, error)
+	CreateVaultRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*RecordID, error)
+	UpdateVaultRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	DeleteVaultRecord(ctx context.Context, in *VaultRecordID, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type gophkeeperClient struct {
@@ -75,6 +121,15 @@ func (c *gophkeeperClient) GetRecordsInfo(ctx context.Context, in *emptypb.Empty
 	return out, nil
 }
 
+func (c *gophkeeperClient) ListRecords(ctx context.Context, in *ListRecordsRequest, opts ...grpc.CallOption) (*RecordsPage, error) {
+	out := new(RecordsPage)
+	err := c.cc.Invoke(ctx, Gophkeeper_ListRecords_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *gophkeeperClient) GetRecord(ctx context.Context, in *RecordID, opts ...grpc.CallOption) (*Record, error) {
 	out := new(Record)
 	err := c.cc.Invoke(ctx, Gophkeeper_GetRecord_FullMethodName, in, out, opts...)
@@ -84,8 +139,8 @@ func (c *gophkeeperClient) GetRecord(ctx context.Context, in *RecordID, opts ...
 	return out, nil
 }
 
-func (c *gophkeeperClient) CreateRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*emptypb.Empty, error) {
-	out := new(emptypb.Empty)
+func (c *gophkeeperClient) CreateRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*RecordID, error) {
+	out := new(RecordID)
 	err := c.cc.Invoke(ctx, Gophkeeper_CreateRecord_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
@@ -102,6 +157,252 @@ func (c *gophkeeperClient) DeleteRecord(ctx context.Context, in *RecordID, opts
 	return out, nil
 }
 
+func (c *gophkeeperClient) UploadFile(ctx context.Context, opts ...grpc.CallOption) (Gophkeeper_UploadFileClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Gophkeeper_ServiceDesc.Streams[0], Gophkeeper_UploadFile_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gophkeeperUploadFileClient{stream}
+	return x, nil
+}
+
+type Gophkeeper_UploadFileClient interface {
+	Send(*FileChunk) error
+	CloseAndRecv() (*RecordID, error)
+	grpc.ClientStream
+}
+
+type gophkeeperUploadFileClient struct {
+	grpc.ClientStream
+}
+
+func (x *gophkeeperUploadFileClient) Send(m *FileChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *gophkeeperUploadFileClient) CloseAndRecv() (*RecordID, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(RecordID)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gophkeeperClient) DownloadFile(ctx context.Context, in *RecordID, opts ...grpc.CallOption) (Gophkeeper_DownloadFileClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Gophkeeper_ServiceDesc.Streams[1], Gophkeeper_DownloadFile_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &gophkeeperDownloadFileClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Gophkeeper_DownloadFileClient interface {
+	Recv() (*FileChunk, error)
+	grpc.ClientStream
+}
+
+type gophkeeperDownloadFileClient struct {
+	grpc.ClientStream
+}
+
+func (x *gophkeeperDownloadFileClient) Recv() (*FileChunk, error) {
+	m := new(FileChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *gophkeeperClient) UpdateRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_UpdateRecord_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) GetRecordVersions(ctx context.Context, in *RecordID, opts ...grpc.CallOption) (*RecordVersionsList, error) {
+	out := new(RecordVersionsList)
+	err := c.cc.Invoke(ctx, Gophkeeper_GetRecordVersions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) RestoreRecordVersion(ctx context.Context, in *RecordVersion, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_RestoreRecordVersion_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) Sync(ctx context.Context, in *SyncRequest, opts ...grpc.CallOption) (*RecordChanges, error) {
+	out := new(RecordChanges)
+	err := c.cc.Invoke(ctx, Gophkeeper_Sync_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) RefreshSession(ctx context.Context, in *RefreshToken, opts ...grpc.CallOption) (*Session, error) {
+	out := new(Session)
+	err := c.cc.Invoke(ctx, Gophkeeper_RefreshSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) Logout(ctx context.Context, in *RefreshToken, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_Logout_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) RevokeSessions(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_RevokeSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) UpgradeKDF(ctx context.Context, in *KDFParams, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_UpgradeKDF_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) GetPublicKey(ctx context.Context, in *UserLogin, opts ...grpc.CallOption) (*PublicKey, error) {
+	out := new(PublicKey)
+	err := c.cc.Invoke(ctx, Gophkeeper_GetPublicKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) SetPublicKey(ctx context.Context, in *PublicKey, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_SetPublicKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) CreateVault(ctx context.Context, in *Vault, opts ...grpc.CallOption) (*Vault, error) {
+	out := new(Vault)
+	err := c.cc.Invoke(ctx, Gophkeeper_CreateVault_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) GetVaults(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*VaultsList, error) {
+	out := new(VaultsList)
+	err := c.cc.Invoke(ctx, Gophkeeper_GetVaults_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) GetVaultMembers(ctx context.Context, in *Vault, opts ...grpc.CallOption) (*VaultMembersList, error) {
+	out := new(VaultMembersList)
+	err := c.cc.Invoke(ctx, Gophkeeper_GetVaultMembers_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) InviteMember(ctx context.Context, in *VaultMember, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_InviteMember_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) RevokeMember(ctx context.Context, in *VaultRotation, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_RevokeMember_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) GetVaultRecords(ctx context.Context, in *Vault, opts ...grpc.CallOption) (*RecordsList, error) {
+	out := new(RecordsList)
+	err := c.cc.Invoke(ctx, Gophkeeper_GetVaultRecords_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) GetVaultRecord(ctx context.Context, in *VaultRecordID, opts ...grpc.CallOption) (*Record, error) {
+	out := new(Record)
+	err := c.cc.Invoke(ctx, Gophkeeper_GetVaultRecord_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) CreateVaultRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*RecordID, error) {
+	out := new(RecordID)
+	err := c.cc.Invoke(ctx, Gophkeeper_CreateVaultRecord_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) UpdateVaultRecord(ctx context.Context, in *Record, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_UpdateVaultRecord_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gophkeeperClient) DeleteVaultRecord(ctx context.Context, in *VaultRecordID, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Gophkeeper_DeleteVaultRecord_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GophkeeperServer is the server API for Gophkeeper service.
 // All implementations must embed UnimplementedGophkeeperServer
 // for forward compatibility
@@ -109,9 +410,32 @@ type GophkeeperServer interface {
 	Register(context.Context, *UserCredentials) (*Session, error)
 	Login(context.Context, *UserCredentials) (*Session, error)
 	GetRecordsInfo(context.Context, *emptypb.Empty) (*RecordsList, error)
+	ListRecords(context.Context, *ListRecordsRequest) (*RecordsPage, error)
 	GetRecord(context.Context, *RecordID) (*Record, error)
-	CreateRecord(context.Context, *Record) (*emptypb.Empty, error)
+	CreateRecord(context.Context, *Record) (*RecordID, error)
 	DeleteRecord(context.Context, *RecordID) (*emptypb.Empty, error)
+	UploadFile(Gophkeeper_UploadFileServer) error
+	DownloadFile(*RecordID, Gophkeeper_DownloadFileServer) error
+	UpdateRecord(context.Context, *Record) (*emptypb.Empty, error)
+	GetRecordVersions(context.Context, *RecordID) (*RecordVersionsList, error)
+	RestoreRecordVersion(context.Context, *RecordVersion) (*emptypb.Empty, error)
+	Sync(context.Context, *SyncRequest) (*RecordChanges, error)
+	RefreshSession(context.Context, *RefreshToken) (*Session, error)
+	Logout(context.Context, *RefreshToken) (*emptypb.Empty, error)
+	RevokeSessions(context.Context, *emptypb.Empty) (*emptypb.Empty, error)
+	UpgradeKDF(context.Context, *KDFParams) (*emptypb.Empty, error)
+	GetPublicKey(context.Context, *UserLogin) (*PublicKey, error)
+	SetPublicKey(context.Context, *PublicKey) (*emptypb.Empty, error)
+	CreateVault(context.Context, *Vault) (*Vault, error)
+	GetVaults(context.Context, *emptypb.Empty) (*VaultsList, error)
+	GetVaultMembers(context.Context, *Vault) (*VaultMembersList, error)
+	InviteMember(context.Context, *VaultMember) (*emptypb.Empty, error)
+	RevokeMember(context.Context, *VaultRotation) (*emptypb.Empty, error)
+	GetVaultRecords(context.Context, *Vault) (*RecordsList, error)
+	GetVaultRecord(context.Context, *VaultRecordID) (*Record, error)
+	CreateVaultRecord(context.Context, *Record) (*RecordID, error)
+	UpdateVaultRecord(context.Context, *Record) (*emptypb.Empty, error)
+	DeleteVaultRecord(context.Context, *VaultRecordID) (*emptypb.Empty, error)
 	mustEmbedUnimplementedGophkeeperServer()
 }
 
@@ -128,15 +452,84 @@ func (UnimplementedGophkeeperServer) Login(context.Context, *UserCredentials) (*
 func (UnimplementedGophkeeperServer) GetRecordsInfo(context.Context, *emptypb.Empty) (*RecordsList, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRecordsInfo not implemented")
 }
+func (UnimplementedGophkeeperServer) ListRecords(context.Context, *ListRecordsRequest) (*RecordsPage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRecords not implemented")
+}
 func (UnimplementedGophkeeperServer) GetRecord(context.Context, *RecordID) (*Record, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRecord not implemented")
 }
-func (UnimplementedGophkeeperServer) CreateRecord(context.Context, *Record) (*emptypb.Empty, error) {
+func (UnimplementedGophkeeperServer) CreateRecord(context.Context, *Record) (*RecordID, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateRecord not implemented")
 }
 func (UnimplementedGophkeeperServer) DeleteRecord(context.Context, *RecordID) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteRecord not implemented")
 }
+func (UnimplementedGophkeeperServer) UploadFile(Gophkeeper_UploadFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadFile not implemented")
+}
+func (UnimplementedGophkeeperServer) DownloadFile(*RecordID, Gophkeeper_DownloadFileServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadFile not implemented")
+}
+func (UnimplementedGophkeeperServer) UpdateRecord(context.Context, *Record) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRecord not implemented")
+}
+func (UnimplementedGophkeeperServer) GetRecordVersions(context.Context, *RecordID) (*RecordVersionsList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecordVersions not implemented")
+}
+func (UnimplementedGophkeeperServer) RestoreRecordVersion(context.Context, *RecordVersion) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreRecordVersion not implemented")
+}
+func (UnimplementedGophkeeperServer) Sync(context.Context, *SyncRequest) (*RecordChanges, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sync not implemented")
+}
+func (UnimplementedGophkeeperServer) RefreshSession(context.Context, *RefreshToken) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshSession not implemented")
+}
+func (UnimplementedGophkeeperServer) Logout(context.Context, *RefreshToken) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
+}
+func (UnimplementedGophkeeperServer) RevokeSessions(context.Context, *emptypb.Empty) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeSessions not implemented")
+}
+func (UnimplementedGophkeeperServer) UpgradeKDF(context.Context, *KDFParams) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpgradeKDF not implemented")
+}
+func (UnimplementedGophkeeperServer) GetPublicKey(context.Context, *UserLogin) (*PublicKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPublicKey not implemented")
+}
+func (UnimplementedGophkeeperServer) SetPublicKey(context.Context, *PublicKey) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPublicKey not implemented")
+}
+func (UnimplementedGophkeeperServer) CreateVault(context.Context, *Vault) (*Vault, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateVault not implemented")
+}
+func (UnimplementedGophkeeperServer) GetVaults(context.Context, *emptypb.Empty) (*VaultsList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVaults not implemented")
+}
+func (UnimplementedGophkeeperServer) GetVaultMembers(context.Context, *Vault) (*VaultMembersList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVaultMembers not implemented")
+}
+func (UnimplementedGophkeeperServer) InviteMember(context.Context, *VaultMember) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InviteMember not implemented")
+}
+func (UnimplementedGophkeeperServer) RevokeMember(context.Context, *VaultRotation) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeMember not implemented")
+}
+func (UnimplementedGophkeeperServer) GetVaultRecords(context.Context, *Vault) (*RecordsList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVaultRecords not implemented")
+}
+func (UnimplementedGophkeeperServer) GetVaultRecord(context.Context, *VaultRecordID) (*Record, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVaultRecord not implemented")
+}
+func (UnimplementedGophkeeperServer) CreateVaultRecord(context.Context, *Record) (*RecordID, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateVaultRecord not implemented")
+}
+func (UnimplementedGophkeeperServer) UpdateVaultRecord(context.Context, *Record) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateVaultRecord not implemented")
+}
+func (UnimplementedGophkeeperServer) DeleteVaultRecord(context.Context, *VaultRecordID) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteVaultRecord not implemented")
+}
 func (UnimplementedGophkeeperServer) mustEmbedUnimplementedGophkeeperServer() {}
 
 // UnsafeGophkeeperServer may be embedded to opt out of forward compatibility for this service.
@@ -204,6 +597,24 @@ func _Gophkeeper_GetRecordsInfo_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Gophkeeper_ListRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRecordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).ListRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_ListRecords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).ListRecords(ctx, req.(*ListRecordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Gophkeeper_GetRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RecordID)
 	if err := dec(in); err != nil {
@@ -258,38 +669,540 @@ func _Gophkeeper_DeleteRecord_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
-// Gophkeeper_ServiceDesc is the grpc.ServiceDesc for Gophkeeper service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var Gophkeeper_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "gophkeeper.Gophkeeper",
-	HandlerType: (*GophkeeperServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Register",
-			Handler:    _Gophkeeper_Register_Handler,
-		},
-		{
-			MethodName: "Login",
-			Handler:    _Gophkeeper_Login_Handler,
-		},
-		{
-			MethodName: "GetRecordsInfo",
-			Handler:    _Gophkeeper_GetRecordsInfo_Handler,
-		},
-		{
-			MethodName: "GetRecord",
-			Handler:    _Gophkeeper_GetRecord_Handler,
-		},
-		{
-			MethodName: "CreateRecord",
-			Handler:    _Gophkeeper_CreateRecord_Handler,
-		},
-		{
-			MethodName: "DeleteRecord",
-			Handler:    _Gophkeeper_DeleteRecord_Handler,
+func _Gophkeeper_UploadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GophkeeperServer).UploadFile(&gophkeeperUploadFileServer{stream})
+}
+
+type Gophkeeper_UploadFileServer interface {
+	SendAndClose(*RecordID) error
+	Recv() (*FileChunk, error)
+	grpc.ServerStream
+}
+
+type gophkeeperUploadFileServer struct {
+	grpc.ServerStream
+}
+
+func (x *gophkeeperUploadFileServer) SendAndClose(m *RecordID) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *gophkeeperUploadFileServer) Recv() (*FileChunk, error) {
+	m := new(FileChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Gophkeeper_DownloadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RecordID)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GophkeeperServer).DownloadFile(m, &gophkeeperDownloadFileServer{stream})
+}
+
+type Gophkeeper_DownloadFileServer interface {
+	Send(*FileChunk) error
+	grpc.ServerStream
+}
+
+type gophkeeperDownloadFileServer struct {
+	grpc.ServerStream
+}
+
+func (x *gophkeeperDownloadFileServer) Send(m *FileChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Gophkeeper_UpdateRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Record)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).UpdateRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_UpdateRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).UpdateRecord(ctx, req.(*Record))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_GetRecordVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).GetRecordVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_GetRecordVersions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).GetRecordVersions(ctx, req.(*RecordID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_RestoreRecordVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RecordVersion)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).RestoreRecordVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_RestoreRecordVersion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).RestoreRecordVersion(ctx, req.(*RecordVersion))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_Sync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).Sync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_Sync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).Sync(ctx, req.(*SyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_RefreshSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshToken)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).RefreshSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_RefreshSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).RefreshSession(ctx, req.(*RefreshToken))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshToken)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_Logout_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).Logout(ctx, req.(*RefreshToken))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_RevokeSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).RevokeSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_RevokeSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).RevokeSessions(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_UpgradeKDF_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(KDFParams)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).UpgradeKDF(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_UpgradeKDF_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).UpgradeKDF(ctx, req.(*KDFParams))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_GetPublicKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserLogin)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).GetPublicKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_GetPublicKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).GetPublicKey(ctx, req.(*UserLogin))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_SetPublicKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublicKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).SetPublicKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_SetPublicKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).SetPublicKey(ctx, req.(*PublicKey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_CreateVault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Vault)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).CreateVault(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_CreateVault_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).CreateVault(ctx, req.(*Vault))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_GetVaults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).GetVaults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_GetVaults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).GetVaults(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_GetVaultMembers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Vault)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).GetVaultMembers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_GetVaultMembers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).GetVaultMembers(ctx, req.(*Vault))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_InviteMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VaultMember)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).InviteMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_InviteMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).InviteMember(ctx, req.(*VaultMember))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_RevokeMember_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VaultRotation)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).RevokeMember(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_RevokeMember_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).RevokeMember(ctx, req.(*VaultRotation))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_GetVaultRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Vault)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).GetVaultRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_GetVaultRecords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).GetVaultRecords(ctx, req.(*Vault))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_GetVaultRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VaultRecordID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).GetVaultRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_GetVaultRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).GetVaultRecord(ctx, req.(*VaultRecordID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_CreateVaultRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Record)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).CreateVaultRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_CreateVaultRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).CreateVaultRecord(ctx, req.(*Record))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_UpdateVaultRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Record)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).UpdateVaultRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_UpdateVaultRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).UpdateVaultRecord(ctx, req.(*Record))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Gophkeeper_DeleteVaultRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VaultRecordID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GophkeeperServer).DeleteVaultRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Gophkeeper_DeleteVaultRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GophkeeperServer).DeleteVaultRecord(ctx, req.(*VaultRecordID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Gophkeeper_ServiceDesc is the grpc.ServiceDesc for Gophkeeper service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Gophkeeper_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gophkeeper.Gophkeeper",
+	HandlerType: (*GophkeeperServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _Gophkeeper_Register_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _Gophkeeper_Login_Handler,
+		},
+		{
+			MethodName: "GetRecordsInfo",
+			Handler:    _Gophkeeper_GetRecordsInfo_Handler,
+		},
+		{
+			MethodName: "ListRecords",
+			Handler:    _Gophkeeper_ListRecords_Handler,
+		},
+		{
+			MethodName: "GetRecord",
+			Handler:    _Gophkeeper_GetRecord_Handler,
+		},
+		{
+			MethodName: "CreateRecord",
+			Handler:    _Gophkeeper_CreateRecord_Handler,
+		},
+		{
+			MethodName: "DeleteRecord",
+			Handler:    _Gophkeeper_DeleteRecord_Handler,
+		},
+		{
+			MethodName: "UpdateRecord",
+			Handler:    _Gophkeeper_UpdateRecord_Handler,
+		},
+		{
+			MethodName: "GetRecordVersions",
+			Handler:    _Gophkeeper_GetRecordVersions_Handler,
+		},
+		{
+			MethodName: "RestoreRecordVersion",
+			Handler:    _Gophkeeper_RestoreRecordVersion_Handler,
+		},
+		{
+			MethodName: "Sync",
+			Handler:    _Gophkeeper_Sync_Handler,
+		},
+		{
+			MethodName: "RefreshSession",
+			Handler:    _Gophkeeper_RefreshSession_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _Gophkeeper_Logout_Handler,
+		},
+		{
+			MethodName: "RevokeSessions",
+			Handler:    _Gophkeeper_RevokeSessions_Handler,
+		},
+		{
+			MethodName: "UpgradeKDF",
+			Handler:    _Gophkeeper_UpgradeKDF_Handler,
+		},
+		{
+			MethodName: "GetPublicKey",
+			Handler:    _Gophkeeper_GetPublicKey_Handler,
+		},
+		{
+			MethodName: "SetPublicKey",
+			Handler:    _Gophkeeper_SetPublicKey_Handler,
+		},
+		{
+			MethodName: "CreateVault",
+			Handler:    _Gophkeeper_CreateVault_Handler,
+		},
+		{
+			MethodName: "GetVaults",
+			Handler:    _Gophkeeper_GetVaults_Handler,
+		},
+		{
+			MethodName: "GetVaultMembers",
+			Handler:    _Gophkeeper_GetVaultMembers_Handler,
+		},
+		{
+			MethodName: "InviteMember",
+			Handler:    _Gophkeeper_InviteMember_Handler,
+		},
+		{
+			MethodName: "RevokeMember",
+			Handler:    _Gophkeeper_RevokeMember_Handler,
+		},
+		{
+			MethodName: "GetVaultRecords",
+			Handler:    _Gophkeeper_GetVaultRecords_Handler,
+		},
+		{
+			MethodName: "GetVaultRecord",
+			Handler:    _Gophkeeper_GetVaultRecord_Handler,
+		},
+		{
+			MethodName: "CreateVaultRecord",
+			Handler:    _Gophkeeper_CreateVaultRecord_Handler,
+		},
+		{
+			MethodName: "UpdateVaultRecord",
+			Handler:    _Gophkeeper_UpdateVaultRecord_Handler,
+		},
+		{
+			MethodName: "DeleteVaultRecord",
+			Handler:    _Gophkeeper_DeleteVaultRecord_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadFile",
+			Handler:       _Gophkeeper_UploadFile_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadFile",
+			Handler:       _Gophkeeper_DownloadFile_Handler,
+			ServerStreams: true,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "protocols/grpc/grpc.proto",
 }